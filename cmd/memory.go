@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/DotNetAge/mindx/internal/client/render"
 	"github.com/DotNetAge/mindx/pkg/rpc"
@@ -45,20 +49,28 @@ type memoryRecord struct {
 
 var memoryQueryCmd = &cobra.Command{
 	Use:   "query",
-	Short: "Semantic search of long-term memory",
-	Args:  cobra.MinimumNArgs(1),
+	Short: "Search long-term memory by meaning, by exact words, or both",
+	Long: `Search long-term memory. The default hybrid mode fuses semantic
+(vector) search with lexical (BM25) search, so exact identifiers such as
+ticket numbers, function names and error codes are found as well as
+related wording. --mode vector or --mode lexical uses one of them alone.`,
+	Args: cobra.MinimumNArgs(1),
 	Example: `  mindx memory query "project architecture"
-  mindx memory query "API design" --limit 20 --min-score 0.5`,
+  mindx memory query "API design" --limit 20 --min-score 0.5
+  mindx memory query "PROJ-1234" --mode lexical`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		limit, _ := cmd.Flags().GetInt("limit")
 		minScore, _ := cmd.Flags().GetFloat64("min-score")
+		mode, _ := cmd.Flags().GetString("mode")
 		jsonOut, _ := cmd.Flags().GetBool("json")
 		cl, err := rpc.Dial(daemonAddr)
 		if err != nil {
 			return err
 		}
 		defer func() { _ = cl.Close() }()
-		result, err := cl.MemoryQuery(args[0], limit, minScore)
+		result, err := cl.MemoryQueryBy(rpc.MemoryQueryParams{
+			Query: args[0], Limit: limit, MinScore: minScore, Mode: mode,
+		})
 		if err != nil {
 			return err
 		}
@@ -93,23 +105,46 @@ var memoryQueryCmd = &cobra.Command{
 // ── memory store ──────────────────────────────────────────────
 
 var memoryStoreCmd = &cobra.Command{
-	Use:     "store",
-	Short:   "Store content in long-term memory",
-	Example: `  mindx memory store --content "The API uses REST over HTTP" --title "API Design" --source "chat"`,
+	Use:   "store",
+	Short: "Store content in long-term memory",
+	Long: `Store content in long-term memory.
+
+--scope decides which agents can recall it: global (every agent), project
+(agents working in --project, default the current directory), agent (only
+--agent) or session (only --session). Without --scope the memory is global,
+or belongs to --agent or --project when given.`,
+	Example: `  mindx memory store --content "The API uses REST over HTTP" --title "API Design" --source "chat"
+  mindx memory store --content "Deploys freeze on Fridays" --scope project
+  mindx memory store --content "Prefers table output" --scope agent --agent backend-engineer`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		content, _ := cmd.Flags().GetString("content")
 		title, _ := cmd.Flags().GetString("title")
 		description, _ := cmd.Flags().GetString("description")
 		source, _ := cmd.Flags().GetString("source")
+		scope, _ := cmd.Flags().GetString("scope")
+		agentName, _ := cmd.Flags().GetString("agent")
+		projectDir, _ := cmd.Flags().GetString("project")
+		sessionID, _ := cmd.Flags().GetString("session")
 		if content == "" {
 			return fmt.Errorf("--content is required")
 		}
+		if scope == "project" && projectDir == "" {
+			projectDir, _ = os.Getwd()
+		}
+		if projectDir != "" {
+			if abs, err := filepath.Abs(projectDir); err == nil {
+				projectDir = abs
+			}
+		}
 		cl, err := rpc.Dial(daemonAddr)
 		if err != nil {
 			return err
 		}
 		defer func() { _ = cl.Close() }()
-		result, err := cl.MemoryStore(content, title, description, source)
+		result, err := cl.MemoryStoreBy(rpc.MemoryStoreParams{
+			Content: content, Title: title, Description: description, Source: source,
+			Scope: scope, AgentName: agentName, ProjectDir: projectDir, SessionID: sessionID,
+		})
 		if err != nil {
 			return err
 		}
@@ -272,16 +307,22 @@ var memoryGetChunksCmd = &cobra.Command{
 // ── memory count ──────────────────────────────────────────────
 
 var memoryCountCmd = &cobra.Command{
-	Use:     "count",
-	Short:   "Count total memory records",
-	Example: `  mindx memory count`,
+	Use:   "count",
+	Short: "Count memory records, optionally of one agent, project or session",
+	Example: `  mindx memory count
+  mindx memory count --agent coder --project-dir /work/api`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		agent, _ := cmd.Flags().GetString("agent")
+		projectDir, _ := cmd.Flags().GetString("project-dir")
+		sessionID, _ := cmd.Flags().GetString("session-id")
 		cl, err := rpc.Dial(daemonAddr)
 		if err != nil {
 			return err
 		}
 		defer func() { _ = cl.Close() }()
-		result, err := cl.MemoryCount()
+		result, err := cl.MemoryCountBy(rpc.MemoryCountParams{
+			AgentName: agent, ProjectDir: projectDir, SessionID: sessionID,
+		})
 		if err != nil {
 			return err
 		}
@@ -299,16 +340,226 @@ var memoryCountCmd = &cobra.Command{
 	},
 }
 
+// ── memory consolidate ────────────────────────────────────────
+
+var memoryConsolidateCmd = &cobra.Command{
+	Use:   "consolidate",
+	Short: "Merge near-duplicate memories and archive decayed ones now",
+	Long: `Run a memory consolidation now under the memory_consolidation policy in
+mindx.json, whether or not the background job is enabled. Clusters of
+similar memories are merged by the default model; merged and pruned
+memories are moved to the archive (see "mindx memory archived").`,
+	Example: `  mindx memory consolidate --dry-run
+  mindx memory consolidate`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		jsonOut, _ := cmd.Flags().GetBool("json")
+		cl, err := rpc.Dial(daemonAddr)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = cl.Close() }()
+		result, err := cl.MemoryConsolidate(context.Background(), dryRun)
+		if err != nil {
+			return err
+		}
+
+		var report rpc.MemoryConsolidateResult
+		if jsonOut || json.Unmarshal(result, &report) != nil {
+			fmt.Println(string(result))
+			return nil
+		}
+
+		verb := "Merged"
+		if report.DryRun {
+			verb = "Would merge"
+		}
+		for _, c := range report.Clusters {
+			target := c.ID
+			if target == "" {
+				target = c.Summary
+			}
+			fmt.Printf("%s %d memories into %s\n", verb, len(c.MergedFrom), target)
+		}
+		if report.DryRun {
+			fmt.Printf("Scanned %d memories: would merge %d cluster(s) and archive %d decayed memory(ies)\n",
+				report.Scanned, len(report.Clusters), len(report.Pruned))
+			return nil
+		}
+		fmt.Printf("Scanned %d memories: merged %d cluster(s), archived %d decayed memory(ies)\n",
+			report.Scanned, len(report.Clusters), len(report.Pruned))
+		return nil
+	},
+}
+
+// ── memory archived ───────────────────────────────────────────
+
+var memoryArchivedCmd = &cobra.Command{
+	Use:   "archived",
+	Short: "List memories moved to the archive by consolidation",
+	Example: `  mindx memory archived
+  mindx memory archived --limit 100 --json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		limit, _ := cmd.Flags().GetInt("limit")
+		jsonOut, _ := cmd.Flags().GetBool("json")
+		cl, err := rpc.Dial(daemonAddr)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = cl.Close() }()
+		result, err := cl.MemoryArchived(limit)
+		if err != nil {
+			return err
+		}
+
+		var list rpc.MemoryArchivedResult
+		if jsonOut || json.Unmarshal(result, &list) != nil {
+			fmt.Println(string(result))
+			return nil
+		}
+		if len(list.Chunks) == 0 {
+			fmt.Println("No archived memories.")
+			return nil
+		}
+
+		table := render.NewTable([]string{"ID", "Agent", "Reason", "Merged Into", "Summary", "Archived"}, 140)
+		for _, c := range list.Chunks {
+			table.AddRow([]string{
+				c.ID,
+				c.AgentName,
+				c.Reason,
+				c.MergedInto,
+				c.Summary,
+				time.UnixMilli(c.ArchivedAt).Format("2006-01-02 15:04"),
+			})
+		}
+		fmt.Println(table.Render())
+		fmt.Printf("\n%d archived memory(ies)\n", list.Count)
+		return nil
+	},
+}
+
+// ── memory restore ────────────────────────────────────────────
+
+var memoryRestoreCmd = &cobra.Command{
+	Use:     "restore",
+	Short:   "Store an archived memory again",
+	Example: `  mindx memory restore --id "3f2a..."`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, _ := cmd.Flags().GetString("id")
+		if id == "" {
+			return fmt.Errorf("--id is required")
+		}
+		cl, err := rpc.Dial(daemonAddr)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = cl.Close() }()
+		result, err := cl.MemoryRestore(id)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(result))
+		return nil
+	},
+}
+
+// ── memory reembed ────────────────────────────────────────────
+
+var memoryReembedCmd = &cobra.Command{
+	Use:   "reembed",
+	Short: "Rebuild memory and knowledge-base vectors with the configured embedder",
+	Long: `Re-embed the long-term memory and the knowledge base with the embedder
+configured in mindx.json. Run it after switching embedders: vectors of
+different embedders cannot be mixed, so the daemon starts each embedder
+with stores of its own and memory search stays empty until the migration
+has copied the memories over.
+
+The daemon migrates the memory in the background and requeues every
+indexed knowledge-base file for re-indexing, which calls the default model
+again. The command follows the progress until the memory is done; with
+--detach it returns at once, and --status reports the progress later.`,
+	Example: `  mindx memory reembed
+  mindx memory reembed --detach
+  mindx memory reembed --status --json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		statusOnly, _ := cmd.Flags().GetBool("status")
+		detach, _ := cmd.Flags().GetBool("detach")
+		jsonOut, _ := cmd.Flags().GetBool("json")
+		cl, err := rpc.Dial(daemonAddr)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = cl.Close() }()
+
+		var result json.RawMessage
+		if statusOnly {
+			result, err = cl.MemoryReembedStatus()
+		} else {
+			result, err = cl.MemoryReembed()
+		}
+		if err != nil {
+			return err
+		}
+
+		var st rpc.MemoryReembedStatus
+		if jsonOut || json.Unmarshal(result, &st) != nil {
+			fmt.Println(string(result))
+			return nil
+		}
+		if !statusOnly && !detach {
+			for st.Running {
+				fmt.Printf("\rMemory: %d/%d  Knowledge base: %d/%d remaining ", st.MemoryDone, st.MemoryTotal, st.KBRemaining, st.KBQueued)
+				time.Sleep(time.Second)
+				if result, err = cl.MemoryReembedStatus(); err != nil {
+					return err
+				}
+				if err := json.Unmarshal(result, &st); err != nil {
+					return err
+				}
+			}
+			fmt.Println()
+		}
+		printReembedStatus(st)
+		if st.Error != "" {
+			return fmt.Errorf("reembed failed: %s", st.Error)
+		}
+		return nil
+	},
+}
+
+func printReembedStatus(st rpc.MemoryReembedStatus) {
+	switch {
+	case st.Running:
+		fmt.Printf("Re-embedding: %d/%d memories done\n", st.MemoryDone, st.MemoryTotal)
+	case st.StartedAt == 0:
+		fmt.Println("No re-embedding has run since the daemon started.")
+	default:
+		fmt.Printf("Re-embedded %d of %d memories\n", st.MemoryStored, st.MemoryTotal)
+	}
+	if st.KBQueued > 0 {
+		fmt.Printf("Knowledge base: %d of %d queued entries still waiting to be re-indexed\n", st.KBRemaining, st.KBQueued)
+	}
+	if st.Pending && !st.Running {
+		fmt.Println("Memories of the previous embedder still await migration; run 'mindx memory reembed'.")
+	}
+}
+
 // ── init subcommands ──────────────────────────────────────────
 
 func init() {
 	memoryQueryCmd.Flags().Int("limit", 10, "Maximum number of results")
 	memoryQueryCmd.Flags().Float64("min-score", 0, "Minimum similarity score (0.0 to 1.0)")
+	memoryQueryCmd.Flags().String("mode", "hybrid", "Search mode: vector, lexical or hybrid")
 	memoryQueryCmd.Flags().Bool("json", false, "Output raw JSON")
 	memoryStoreCmd.Flags().String("content", "", "Content to store (required)")
 	memoryStoreCmd.Flags().String("title", "", "Title/summary")
 	memoryStoreCmd.Flags().String("description", "", "Description")
 	memoryStoreCmd.Flags().String("source", "", "Source identifier")
+	memoryStoreCmd.Flags().String("scope", "", "Memory scope: global, project, agent or session")
+	memoryStoreCmd.Flags().String("agent", "", "Agent the memory belongs to")
+	memoryStoreCmd.Flags().String("project", "", "Project directory the memory belongs to (--scope project defaults to the current directory)")
+	memoryStoreCmd.Flags().String("session", "", "Session the memory belongs to")
 	memoryDeleteCmd.Flags().String("id", "", "Memory record ID to delete (required)")
 	memoryChunksCmd.Flags().Int("page", 1, "Page number")
 	memoryChunksCmd.Flags().Int("page-size", 20, "Page size")
@@ -316,6 +567,17 @@ func init() {
 	memoryChunksCmd.Flags().Bool("json", false, "Output raw JSON")
 	memoryGetChunksCmd.Flags().String("doc-id", "", "Document ID (required)")
 	memoryGetChunksCmd.Flags().Bool("json", false, "Output raw JSON")
+	memoryCountCmd.Flags().String("agent", "", "Only count memories of this agent")
+	memoryCountCmd.Flags().String("project-dir", "", "Only count memories of this project directory")
+	memoryCountCmd.Flags().String("session-id", "", "Only count memories of this session")
+	memoryConsolidateCmd.Flags().Bool("dry-run", false, "Report what would be merged and archived without changing anything")
+	memoryConsolidateCmd.Flags().Bool("json", false, "Output raw JSON")
+	memoryArchivedCmd.Flags().Int("limit", 50, "Maximum number of archived memories")
+	memoryArchivedCmd.Flags().Bool("json", false, "Output raw JSON")
+	memoryRestoreCmd.Flags().String("id", "", "Archived memory ID to restore (required)")
+	memoryReembedCmd.Flags().Bool("status", false, "Show the progress of the running or last re-embedding")
+	memoryReembedCmd.Flags().Bool("detach", false, "Start the re-embedding and return without following it")
+	memoryReembedCmd.Flags().Bool("json", false, "Output raw JSON")
 
 	memoryCmd.AddCommand(memoryQueryCmd)
 	memoryCmd.AddCommand(memoryStoreCmd)
@@ -323,4 +585,8 @@ func init() {
 	memoryCmd.AddCommand(memoryChunksCmd)
 	memoryCmd.AddCommand(memoryGetChunksCmd)
 	memoryCmd.AddCommand(memoryCountCmd)
+	memoryCmd.AddCommand(memoryConsolidateCmd)
+	memoryCmd.AddCommand(memoryArchivedCmd)
+	memoryCmd.AddCommand(memoryRestoreCmd)
+	memoryCmd.AddCommand(memoryReembedCmd)
 }
@@ -20,6 +20,7 @@ import (
 var queryFlags struct {
 	limit    int
 	minScore float64
+	mode     string
 }
 
 var queryCmd = &cobra.Command{
@@ -28,12 +29,14 @@ var queryCmd = &cobra.Command{
 	Long: `Search the MindX long-term memory store and return matching records.
 
 Requires an embedder model to be configured (see 'mindx doctor').
-Searches by semantic similarity to the provided search terms.
+By default fuses semantic similarity with exact-word (BM25) matching;
+--mode vector or --mode lexical uses one of them alone.
 
 Examples:
   mindx query "project architecture"
   mindx query -n 20 "API design decisions"
-  mindx query --min-score 0.5 "database schema"`,
+  mindx query --min-score 0.5 "database schema"
+  mindx query --mode lexical "ERR_CONN_RESET"`,
 	Args: cobra.MinimumNArgs(1),
 	RunE: runQuery,
 }
@@ -43,11 +46,17 @@ var queryJSON bool
 func init() {
 	queryCmd.Flags().IntVarP(&queryFlags.limit, "limit", "n", 10, "Maximum number of results to return")
 	queryCmd.Flags().Float64Var(&queryFlags.minScore, "min-score", 0, "Minimum similarity score (0.0 to 1.0)")
+	queryCmd.Flags().StringVar(&queryFlags.mode, "mode", "hybrid", "Search mode: vector, lexical or hybrid")
 	queryCmd.Flags().BoolVar(&queryJSON, "json", false, "Output raw JSON")
 	rootCmd.AddCommand(queryCmd)
 }
 
 func runQuery(cmd *cobra.Command, args []string) error {
+	mode, err := memory.ParseRetrieveMode(queryFlags.mode)
+	if err != nil {
+		return fmt.Errorf("invalid --mode %q: want vector, lexical or hybrid", queryFlags.mode)
+	}
+
 	workspaceDir := core.DefaultUserPrefsDir()
 
 	if !core.WorkspaceExists(workspaceDir) {
@@ -62,17 +71,9 @@ func runQuery(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no embedder model configured — run 'mindx doctor' to set one up")
 	}
 
-	modelPath := cfg.EmbedderModelPath(workspaceDir)
-	if _, statErr := os.Stat(modelPath); statErr != nil {
-		return fmt.Errorf("embedder model file not found at %s\nRun 'mindx doctor' to download it", modelPath)
-	}
-
-	emb, err := memory.NewEmbedderFromConfig(modelPath)
+	emb, embID, err := core.NewEmbedder(cfg, workspaceDir, core.NewCredentialStore(workspaceDir))
 	if err != nil {
-		return fmt.Errorf("cannot create embedder: %w", err)
-	}
-	if emb == nil {
-		return fmt.Errorf("embedder model could not be loaded from %s", modelPath)
+		return fmt.Errorf("cannot create embedder: %w\nRun 'mindx doctor' to set one up", err)
 	}
 
 	memDir := filepath.Join(workspaceDir, "memory")
@@ -94,10 +95,11 @@ func runQuery(cmd *cobra.Command, args []string) error {
 	})
 
 	mem, err := memory.NewRAGMemoryFromConfig(memory.MemoryConfig{
-		AgentName: "_shared",
-		MemoryDir: memDir,
-		Embedder:  emb,
-		Logger:    queryLogger,
+		AgentName:  "_shared",
+		MemoryDir:  memDir,
+		Embedder:   emb,
+		EmbedderID: embID,
+		Logger:     queryLogger,
 	})
 	if err != nil {
 		return fmt.Errorf("cannot open memory store: %w", err)
@@ -118,7 +120,7 @@ func runQuery(cmd *cobra.Command, args []string) error {
 	}
 
 	start := time.Now()
-	records, err := mem.Retrieve(context.Background(), query, opts...)
+	records, err := mem.RetrieveWithMode(context.Background(), query, mode, opts...)
 	elapsed := time.Since(start)
 	if err != nil {
 		return fmt.Errorf("memory query failed: %w", err)
@@ -3,6 +3,8 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/DotNetAge/mindx/internal/client/render"
 	"github.com/DotNetAge/mindx/pkg/rpc"
@@ -21,6 +23,8 @@ All operations require the daemon to be running (mindx start).
 Examples:
   mindx schedule list
   mindx schedule add --agent writer --content "Daily standup" --cron "0 0 9 * * *"
+  mindx schedule add --agent writer --content "Launch post" --at "2026-11-01 09:00"
+  mindx schedule history a1b2c3d4
   mindx schedule delete --id a1b2c3d4`,
 	PersistentPreRunE: requireDaemon,
 }
@@ -37,10 +41,79 @@ type scheduleEntry struct {
 	SessionID string `json:"session_id,omitempty"`
 	Content   string `json:"content"`
 	CronExpr  string `json:"cron_expr"`
+	Trigger   string `json:"trigger,omitempty"`
+	RunAt     string `json:"run_at,omitempty"`
+	Every     string `json:"every,omitempty"`
+	Timezone  string `json:"timezone,omitempty"`
+	MaxRuns   int    `json:"max_runs,omitempty"`
 	Enabled   bool   `json:"enabled"`
+	RetiredAt string `json:"retired_at,omitempty"`
 	CreatedAt string `json:"created_at"`
 }
 
+// describe renders the entry's trigger as a short human-readable string.
+func (e scheduleEntry) describe() string {
+	var s string
+	switch e.Trigger {
+	case "at":
+		s = "at " + e.RunAt
+	case "every":
+		s = "every " + e.Every
+	default:
+		s = e.CronExpr
+	}
+	if e.Timezone != "" {
+		s += " (" + e.Timezone + ")"
+	}
+	if e.MaxRuns > 0 {
+		s += fmt.Sprintf(" ×%d", e.MaxRuns)
+	}
+	return s
+}
+
+type scheduleRun struct {
+	RunID      string    `json:"run_id"`
+	EntryID    string    `json:"entry_id"`
+	Agent      string    `json:"agent"`
+	SessionID  string    `json:"session_id,omitempty"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	Attempts   int       `json:"attempts,omitempty"`
+	CatchUp    bool      `json:"catch_up,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+	Usage      struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+	Output       string `json:"output,omitempty"`
+	MessageStart int    `json:"message_start"`
+	MessageEnd   int    `json:"message_end"`
+	Steps        []struct {
+		Agent        string `json:"agent"`
+		Branch       string `json:"branch,omitempty"`
+		SessionID    string `json:"session_id,omitempty"`
+		Status       string `json:"status"`
+		Error        string `json:"error,omitempty"`
+		DurationMs   int64  `json:"duration_ms"`
+		Output       string `json:"output,omitempty"`
+		MessageStart int    `json:"message_start"`
+	} `json:"steps,omitempty"`
+	Deliveries []struct {
+		Type   string `json:"type"`
+		Target string `json:"target,omitempty"`
+		Status string `json:"status"`
+		Error  string `json:"error,omitempty"`
+	} `json:"deliveries,omitempty"`
+}
+
+type scheduleRunGetResponse struct {
+	Run          scheduleRun        `json:"run"`
+	Messages     []map[string]any   `json:"messages"`
+	StepMessages [][]map[string]any `json:"step_messages,omitempty"`
+}
+
 // ── schedule list ─────────────────────────────────────────────
 
 var scheduleListCmd = &cobra.Command{
@@ -76,13 +149,16 @@ var scheduleListCmd = &cobra.Command{
 			return nil
 		}
 
-		table := render.NewTable([]string{"ID", "Agent", "Cron", "Enabled", "Created"}, 100)
+		table := render.NewTable([]string{"ID", "Agent", "Schedule", "Enabled", "Created"}, 100)
 		for _, e := range entries {
 			enabled := "yes"
 			if !e.Enabled {
 				enabled = "no"
 			}
-			table.AddRow([]string{e.ID, e.Agent, e.CronExpr, enabled, e.CreatedAt})
+			if e.RetiredAt != "" {
+				enabled = "retired"
+			}
+			table.AddRow([]string{e.ID, e.Agent, e.describe(), enabled, e.CreatedAt})
 		}
 		fmt.Println(table.Render())
 		fmt.Printf("\n%d scheduled task(s)\n", len(entries))
@@ -96,7 +172,17 @@ var scheduleAddCmd = &cobra.Command{
 	Use:   "add",
 	Short: "Add a new scheduled task",
 	Example: `  mindx schedule add --agent writer --content "Daily standup" --cron "0 0 9 * * *"
-  mindx schedule add --agent writer --content "Blog post" --cron "0 0 9 * * 1" --session-id "task-abc123" --project-dir /path/to/project`,
+  mindx schedule add --agent writer --content "Blog post" --cron "0 0 9 * * 1" --session-id "task-abc123" --project-dir /path/to/project
+  mindx schedule add --agent finance --content "Month-end report" --cron "0 0 17 LW * *" --timezone Asia/Shanghai
+  mindx schedule add --agent writer --content "Launch post" --at "2026-11-01 09:00"
+  mindx schedule add --agent monitor --content "Check the build" --every 90m --max-runs 8
+  mindx schedule add --agent crawler --content "Sync feeds" --every 15m --timeout 10m --retries 3 --overlap queue
+  mindx schedule add --agent writer --content "Daily digest" --cron "0 0 8 * * *" --catch-up once
+  mindx schedule add --agent researcher --content "Research AI news" --cron "0 0 7 * * *" \
+    --on-success 'content-creator:Write a post from this research: {{output}}' \
+    --on-failure 'notifier:Research failed: {{error}}'
+  mindx schedule add --agent analyst --content "Sales summary" --cron "0 0 18 * * 1-5" \
+    --deliver-file "~/reports/sales-{{date}}.md" --deliver-webhook https://hooks.example.com/sales --deliver-memory`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		agent, _ := cmd.Flags().GetString("agent")
 		content, _ := cmd.Flags().GetString("content")
@@ -104,6 +190,24 @@ var scheduleAddCmd = &cobra.Command{
 		sessionID, _ := cmd.Flags().GetString("session-id")
 		projectDir, _ := cmd.Flags().GetString("project-dir")
 		enabled, _ := cmd.Flags().GetBool("enabled")
+		at, _ := cmd.Flags().GetString("at")
+		every, _ := cmd.Flags().GetString("every")
+		timezone, _ := cmd.Flags().GetString("timezone")
+		start, _ := cmd.Flags().GetString("start")
+		end, _ := cmd.Flags().GetString("end")
+		maxRuns, _ := cmd.Flags().GetInt("max-runs")
+		timeout, _ := cmd.Flags().GetString("timeout")
+		retries, _ := cmd.Flags().GetInt("retries")
+		backoff, _ := cmd.Flags().GetString("retry-backoff")
+		overlap, _ := cmd.Flags().GetString("overlap")
+		catchUp, _ := cmd.Flags().GetString("catch-up")
+		catchUpMax, _ := cmd.Flags().GetInt("catch-up-max")
+		onSuccessFlag, _ := cmd.Flags().GetString("on-success")
+		onFailureFlag, _ := cmd.Flags().GetString("on-failure")
+		deliverFiles, _ := cmd.Flags().GetStringArray("deliver-file")
+		deliverWebhooks, _ := cmd.Flags().GetStringArray("deliver-webhook")
+		deliverKeys, _ := cmd.Flags().GetStringArray("deliver-kv")
+		deliverMemory, _ := cmd.Flags().GetBool("deliver-memory")
 
 		if agent == "" {
 			return fmt.Errorf("--agent is required")
@@ -111,8 +215,45 @@ var scheduleAddCmd = &cobra.Command{
 		if content == "" {
 			return fmt.Errorf("--content is required")
 		}
-		if cron == "" {
-			return fmt.Errorf("--cron is required")
+		trigger := ""
+		switch {
+		case at != "" && (cron != "" || every != ""), cron != "" && every != "":
+			return fmt.Errorf("--cron, --at and --every are mutually exclusive")
+		case at != "":
+			trigger = "at"
+		case every != "":
+			trigger = "every"
+		case cron != "":
+			trigger = "cron"
+		default:
+			return fmt.Errorf("one of --cron, --at or --every is required")
+		}
+
+		onSuccess, err := parseChainFlag("--on-success", onSuccessFlag)
+		if err != nil {
+			return err
+		}
+		onFailure, err := parseChainFlag("--on-failure", onFailureFlag)
+		if err != nil {
+			return err
+		}
+
+		var sinks []map[string]any
+		for _, path := range deliverFiles {
+			sinks = append(sinks, map[string]any{"type": "file", "path": path, "append": true})
+		}
+		for _, url := range deliverWebhooks {
+			sinks = append(sinks, map[string]any{"type": "webhook", "url": url})
+		}
+		for _, key := range deliverKeys {
+			sinks = append(sinks, map[string]any{"type": "kv", "key": key})
+		}
+		if deliverMemory {
+			sinks = append(sinks, map[string]any{"type": "memory"})
+		}
+		var sinksJSON json.RawMessage
+		if len(sinks) > 0 {
+			sinksJSON, _ = json.Marshal(sinks)
 		}
 
 		cl, err := rpc.Dial(daemonAddr)
@@ -128,6 +269,23 @@ var scheduleAddCmd = &cobra.Command{
 			SessionID:  sessionID,
 			ProjectDir: projectDir,
 			Enabled:    enabled,
+			Trigger:    trigger,
+			RunAt:      at,
+			Every:      every,
+			Timezone:   timezone,
+			StartAt:    start,
+			EndAt:      end,
+			MaxRuns:    maxRuns,
+
+			Timeout:      timeout,
+			MaxRetries:   retries,
+			RetryBackoff: backoff,
+			Overlap:      overlap,
+			CatchUp:      catchUp,
+			CatchUpMax:   catchUpMax,
+			OnSuccess:    onSuccess,
+			OnFailure:    onFailure,
+			Sinks:        sinksJSON,
 		})
 		if err != nil {
 			return err
@@ -145,6 +303,29 @@ var scheduleAddCmd = &cobra.Command{
 	},
 }
 
+// parseChainFlag turns an --on-success / --on-failure value into a step
+// object. It accepts "agent:content" or a full JSON step.
+func parseChainFlag(name, value string) (json.RawMessage, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if strings.HasPrefix(value, "{") {
+		if !json.Valid([]byte(value)) {
+			return nil, fmt.Errorf("%s: invalid JSON", name)
+		}
+		return json.RawMessage(value), nil
+	}
+	agent, content, ok := strings.Cut(value, ":")
+	if !ok || strings.TrimSpace(agent) == "" || strings.TrimSpace(content) == "" {
+		return nil, fmt.Errorf("%s: want \"agent:content\" or a JSON step", name)
+	}
+	return json.Marshal(map[string]string{
+		"agent":   strings.TrimPrefix(strings.TrimSpace(agent), "@"),
+		"content": strings.TrimSpace(content),
+	})
+}
+
 // ── schedule delete ───────────────────────────────────────────
 
 var scheduleDeleteCmd = &cobra.Command{
@@ -172,19 +353,198 @@ var scheduleDeleteCmd = &cobra.Command{
 	},
 }
 
+// ── schedule history ──────────────────────────────────────────
+
+var scheduleHistoryCmd = &cobra.Command{
+	Use:   "history <id>",
+	Short: "Show the run history of a scheduled task",
+	Example: `  mindx schedule history a1b2c3d4
+  mindx schedule history a1b2c3d4 --limit 5
+  mindx schedule history a1b2c3d4 --run 9f8e7d6c`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runID, _ := cmd.Flags().GetString("run")
+		limit, _ := cmd.Flags().GetInt("limit")
+		jsonOut, _ := cmd.Flags().GetBool("json")
+
+		cl, err := rpc.Dial(daemonAddr)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = cl.Close() }()
+
+		if runID != "" {
+			result, err := cl.ScheduleRunGet(runID)
+			if err != nil {
+				return err
+			}
+			if jsonOut {
+				fmt.Println(string(result))
+				return nil
+			}
+			var resp scheduleRunGetResponse
+			if err := json.Unmarshal(result, &resp); err != nil {
+				fmt.Println(string(result))
+				return nil
+			}
+			printScheduleRun(resp)
+			return nil
+		}
+
+		result, err := cl.ScheduleRuns(args[0], limit)
+		if err != nil {
+			return err
+		}
+		if jsonOut {
+			fmt.Println(string(result))
+			return nil
+		}
+
+		var runs []scheduleRun
+		if err := json.Unmarshal(result, &runs); err != nil {
+			fmt.Println(string(result))
+			return nil
+		}
+		if len(runs) == 0 {
+			fmt.Println("No runs recorded.")
+			return nil
+		}
+
+		table := render.NewTable([]string{"Run ID", "Started", "Duration", "Status", "Tokens", "Output"}, 120)
+		for _, r := range runs {
+			status := r.Status
+			if r.Attempts > 1 {
+				status += fmt.Sprintf(" (%d attempts)", r.Attempts)
+			}
+			if r.CatchUp {
+				status += " [catch-up]"
+			}
+			if r.Error != "" {
+				status += ": " + r.Error
+			}
+			table.AddRow([]string{
+				r.RunID,
+				r.StartedAt.Local().Format("2006-01-02 15:04:05"),
+				(time.Duration(r.DurationMs) * time.Millisecond).String(),
+				status,
+				fmt.Sprintf("%d", r.Usage.TotalTokens),
+				r.Output,
+			})
+		}
+		fmt.Println(table.Render())
+		fmt.Printf("\n%d run(s)\n", len(runs))
+		return nil
+	},
+}
+
+// printScheduleRun prints one run record followed by its transcript.
+func printScheduleRun(resp scheduleRunGetResponse) {
+	r := resp.Run
+	fmt.Printf("Run:      %s (task %s, @%s)\n", r.RunID, r.EntryID, r.Agent)
+	fmt.Printf("Status:   %s\n", r.Status)
+	if r.Attempts > 1 {
+		fmt.Printf("Attempts: %d\n", r.Attempts)
+	}
+	if r.Error != "" {
+		fmt.Printf("Error:    %s\n", r.Error)
+	}
+	fmt.Printf("Started:  %s\n", r.StartedAt.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("Duration: %s\n", time.Duration(r.DurationMs)*time.Millisecond)
+	fmt.Printf("Tokens:   %d (in:%d out:%d)\n", r.Usage.TotalTokens, r.Usage.PromptTokens, r.Usage.CompletionTokens)
+	if r.SessionID != "" {
+		fmt.Printf("Session:  %s (messages %d-%d)\n", r.SessionID, r.MessageStart, r.MessageEnd)
+	}
+
+	for _, dl := range r.Deliveries {
+		line := fmt.Sprintf("Delivery: %s", dl.Type)
+		if dl.Target != "" {
+			line += " → " + dl.Target
+		}
+		line += " [" + dl.Status + "]"
+		if dl.Error != "" {
+			line += " " + dl.Error
+		}
+		fmt.Println(line)
+	}
+
+	if len(r.Steps) > 1 {
+		fmt.Println()
+		table := render.NewTable([]string{"Step", "Agent", "Branch", "Status", "Duration", "Session"}, 120)
+		for i, step := range r.Steps {
+			status := step.Status
+			if step.Error != "" {
+				status += ": " + step.Error
+			}
+			table.AddRow([]string{
+				fmt.Sprintf("%d", i+1), "@" + strings.TrimPrefix(step.Agent, "@"), step.Branch, status,
+				(time.Duration(step.DurationMs) * time.Millisecond).String(), step.SessionID,
+			})
+		}
+		fmt.Println(table.Render())
+
+		for i, msgs := range resp.StepMessages {
+			if len(msgs) == 0 || i >= len(r.Steps) {
+				continue
+			}
+			fmt.Printf("\nStep %d (@%s):\n", i+1, strings.TrimPrefix(r.Steps[i].Agent, "@"))
+			printRunMessages(msgs, r.Steps[i].MessageStart)
+		}
+	} else if len(resp.Messages) > 0 {
+		fmt.Println()
+		printRunMessages(resp.Messages, r.MessageStart)
+	}
+
+	if r.Output != "" {
+		fmt.Printf("\nOutput:\n%s\n", r.Output)
+	}
+}
+
+// printRunMessages prints a transcript slice numbered from offset+1.
+func printRunMessages(msgs []map[string]any, offset int) {
+	table := render.NewTable([]string{"#", "Role", "Content"}, 120)
+	for i, msg := range msgs {
+		role, _ := msg["role"].(string)
+		content, _ := msg["content"].(string)
+		table.AddRow([]string{fmt.Sprintf("%d", offset+i+1), role, content})
+	}
+	fmt.Println(table.Render())
+}
+
 // ── init subcommands ──────────────────────────────────────────
 
 func init() {
 	scheduleAddCmd.Flags().String("agent", "", "Target agent name (e.g. writer)")
 	scheduleAddCmd.Flags().String("content", "", "Prompt content to send to the agent")
-	scheduleAddCmd.Flags().String("cron", "", "6-field cron expression")
+	scheduleAddCmd.Flags().String("cron", "", "6-field cron expression (day-of-month accepts L and LW)")
+	scheduleAddCmd.Flags().String("at", "", "Run once at this time (e.g. \"2026-11-01 09:00\")")
+	scheduleAddCmd.Flags().String("every", "", "Run at a fixed interval (e.g. 90m, 24h)")
+	scheduleAddCmd.Flags().String("timezone", "", "IANA time zone for the schedule (default: daemon local)")
+	scheduleAddCmd.Flags().String("start", "", "Do not run before this time")
+	scheduleAddCmd.Flags().String("end", "", "Do not run after this time")
+	scheduleAddCmd.Flags().Int("max-runs", 0, "Retire the task after this many runs (0 = unlimited)")
+	scheduleAddCmd.Flags().String("timeout", "", "Per-attempt timeout (default 5m)")
+	scheduleAddCmd.Flags().Int("retries", 0, "Retry a failed run this many times")
+	scheduleAddCmd.Flags().String("retry-backoff", "", "Delay before the first retry, doubled each time (default 30s)")
+	scheduleAddCmd.Flags().String("overlap", "", "When a run is still in progress: skip (default), queue or allow")
+	scheduleAddCmd.Flags().String("catch-up", "", "Runs missed while the daemon was down: none (default), once or all")
+	scheduleAddCmd.Flags().Int("catch-up-max", 0, "Most missed runs to replay with --catch-up all (default 10)")
+	scheduleAddCmd.Flags().String("on-success", "", "Follow-up step after a successful run: \"agent:content\" or JSON ({{output}} injects the answer)")
+	scheduleAddCmd.Flags().String("on-failure", "", "Follow-up step after a failed run: \"agent:content\" or JSON")
+	scheduleAddCmd.Flags().StringArray("deliver-file", nil, "Append each run's output to this file (repeatable; {{date}} expands)")
+	scheduleAddCmd.Flags().StringArray("deliver-webhook", nil, "POST each run's result as JSON to this URL (repeatable)")
+	scheduleAddCmd.Flags().StringArray("deliver-kv", nil, "Append each run's output to this KV key (repeatable)")
+	scheduleAddCmd.Flags().Bool("deliver-memory", false, "Store each run's output in long-term memory")
 	scheduleAddCmd.Flags().String("session-id", "", "Session UUID or graph task ID to link")
 	scheduleAddCmd.Flags().String("project-dir", "", "Project working directory")
 	scheduleAddCmd.Flags().Bool("enabled", true, "Enable the schedule immediately")
 	scheduleDeleteCmd.Flags().String("id", "", "Schedule entry ID to delete")
 	scheduleListCmd.Flags().Bool("json", false, "Output raw JSON")
+	scheduleHistoryCmd.Flags().String("run", "", "Show one run with its transcript")
+	scheduleHistoryCmd.Flags().Int("limit", 20, "Maximum number of runs to list (0 = all)")
+	scheduleHistoryCmd.Flags().Bool("json", false, "Output raw JSON")
 
 	scheduleCmd.AddCommand(scheduleListCmd)
 	scheduleCmd.AddCommand(scheduleAddCmd)
 	scheduleCmd.AddCommand(scheduleDeleteCmd)
+	scheduleCmd.AddCommand(scheduleHistoryCmd)
 }
@@ -1,8 +1,13 @@
 package cmd
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/DotNetAge/mindx/internal/client/render"
@@ -23,6 +28,8 @@ Examples:
   mindx session create --agent "my-agent" --project-dir "/path/to/project"
   mindx session list
   mindx session get --session-id "01ABCDEF..."
+  mindx session search "decided sqlite"
+  mindx session export --session-id "01ABCDEF..." --format markdown
   mindx session delete --session-id "01ABCDEF..."`,
 	PersistentPreRunE: requireDaemon,
 }
@@ -34,13 +41,24 @@ func init() {
 // ── response types (aligned with RPC) ─────────────────────────
 
 type sessionInfo struct {
-	SessionID      string    `json:"session_id"`
-	AgentName      string    `json:"agent_name,omitempty"`
-	Title          string    `json:"title,omitempty"`
-	ProjectDir     string    `json:"project_dir,omitempty"`
-	SessionDir     string    `json:"session_dir,omitempty"`
-	LastActivityAt time.Time `json:"last_activity_at"`
-	CreatedAt      time.Time `json:"created_at"`
+	SessionID        string    `json:"session_id"`
+	AgentName        string    `json:"agent_name,omitempty"`
+	Title            string    `json:"title,omitempty"`
+	ProjectDir       string    `json:"project_dir,omitempty"`
+	SessionDir       string    `json:"session_dir,omitempty"`
+	LastActivityAt   time.Time `json:"last_activity_at"`
+	CreatedAt        time.Time `json:"created_at"`
+	ParentSessionID  string    `json:"parent_session_id,omitempty"`
+	ForkMessageIndex int       `json:"fork_message_index,omitempty"`
+}
+
+type sessionSearchHit struct {
+	SessionID string `json:"session_id"`
+	AgentName string `json:"agent_name"`
+	Offset    int    `json:"offset"`
+	Role      string `json:"role"`
+	Timestamp int64  `json:"timestamp"`
+	Snippet   string `json:"snippet"`
 }
 
 type sessionGetResponse struct {
@@ -91,16 +109,23 @@ var sessionListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all sessions (optionally filtered by agent)",
 	Example: `  mindx session list
-  mindx session list --agent "notes"`,
+  mindx session list --agent "notes"
+  mindx session list --tree`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		agent, _ := cmd.Flags().GetString("agent")
 		jsonOut, _ := cmd.Flags().GetBool("json")
+		tree, _ := cmd.Flags().GetBool("tree")
 		cl, err := rpc.Dial(daemonAddr)
 		if err != nil {
 			return err
 		}
 		defer func() { _ = cl.Close() }()
-		result, err := cl.SessionList(agent)
+		var result json.RawMessage
+		if tree {
+			result, err = cl.SessionListLineage(agent)
+		} else {
+			result, err = cl.SessionList(agent)
+		}
 		if err != nil {
 			return err
 		}
@@ -122,6 +147,12 @@ var sessionListCmd = &cobra.Command{
 			return nil
 		}
 
+		if tree {
+			fmt.Println(renderSessionTree(sessions))
+			fmt.Printf("\n%d session(s)\n", len(sessions))
+			return nil
+		}
+
 		table := render.NewTable([]string{"Session ID", "Agent", "Title", "Created"}, 100)
 		for _, s := range sessions {
 			table.AddRow([]string{
@@ -137,6 +168,64 @@ var sessionListCmd = &cobra.Command{
 	},
 }
 
+// renderSessionTree renders sessions as a fork tree: each fork is listed
+// under the session it was forked from, oldest first. A fork whose parent
+// is not listed (deleted, or another agent's) is shown as a root.
+func renderSessionTree(sessions []sessionInfo) string {
+	listed := make(map[string]bool, len(sessions))
+	for _, s := range sessions {
+		listed[s.SessionID] = true
+	}
+	children := make(map[string][]sessionInfo)
+	var roots []sessionInfo
+	for _, s := range sessions {
+		if s.ParentSessionID != "" && listed[s.ParentSessionID] {
+			children[s.ParentSessionID] = append(children[s.ParentSessionID], s)
+		} else {
+			roots = append(roots, s)
+		}
+	}
+	byCreated := func(list []sessionInfo) {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	}
+	byCreated(roots)
+
+	table := render.NewTable([]string{"Session ID", "Agent", "Title", "Forked At", "Created"}, 120)
+	var walk func(s sessionInfo, prefix, branch string)
+	walk = func(s sessionInfo, prefix, branch string) {
+		forkedAt := ""
+		if s.ParentSessionID != "" {
+			forkedAt = fmt.Sprintf("#%d", s.ForkMessageIndex)
+		}
+		table.AddRow([]string{
+			prefix + branch + s.SessionID,
+			s.AgentName,
+			s.Title,
+			forkedAt,
+			s.CreatedAt.Format("2006-01-02 15:04"),
+		})
+		switch branch {
+		case "├─ ":
+			prefix += "│  "
+		case "└─ ":
+			prefix += "   "
+		}
+		kids := children[s.SessionID]
+		byCreated(kids)
+		for i, c := range kids {
+			next := "├─ "
+			if i == len(kids)-1 {
+				next = "└─ "
+			}
+			walk(c, prefix, next)
+		}
+	}
+	for _, r := range roots {
+		walk(r, "", "")
+	}
+	return table.Render()
+}
+
 // ── session get ───────────────────────────────────────────────
 
 var sessionGetCmd = &cobra.Command{
@@ -208,6 +297,489 @@ var sessionGetCmd = &cobra.Command{
 	},
 }
 
+// ── session fork ──────────────────────────────────────────────
+
+var sessionForkCmd = &cobra.Command{
+	Use:   "fork",
+	Short: "Fork a session from one of its messages",
+	Long: `Creates a new session holding a copy of the session's history up to and
+including message --at (0-based, counting compacted messages; the "#"
+column of "mindx session search"). The original session is left untouched,
+so a different direction can be tried from that point.
+
+The fork inherits the agent, project directory and tracked file changes
+(with their backups), and records its parent; see "mindx session list --tree".`,
+	Example: `  mindx session fork --session-id "01ABCDEFGHJK..." --at 7`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, _ := cmd.Flags().GetString("session-id")
+		at, _ := cmd.Flags().GetInt("at")
+		jsonOut, _ := cmd.Flags().GetBool("json")
+		if id == "" {
+			return fmt.Errorf("--session-id is required")
+		}
+		if !cmd.Flags().Changed("at") {
+			return fmt.Errorf("--at is required")
+		}
+		cl, err := rpc.Dial(daemonAddr)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = cl.Close() }()
+		result, err := cl.SessionFork(id, at)
+		if err != nil {
+			return err
+		}
+
+		var resp struct {
+			SessionID    string `json:"session_id"`
+			MessageCount int    `json:"message_count"`
+		}
+		if jsonOut || json.Unmarshal(result, &resp) != nil || resp.SessionID == "" {
+			fmt.Println(string(result))
+			return nil
+		}
+		fmt.Printf("Session forked: %s (%d message(s) from %s)\n", resp.SessionID, resp.MessageCount, id)
+		return nil
+	},
+}
+
+// ── session export ────────────────────────────────────────────
+
+var sessionExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export a session as a bundle, Markdown transcript or fine-tuning JSONL",
+	Long: `Exports a session in one of three formats:
+
+  bundle    self-contained .mindx-session archive with messages, metadata,
+            compaction cursor, tracked file versions and token usage;
+            restore it with "mindx session import"
+  markdown  human-readable transcript
+  openai    one line of OpenAI chat fine-tuning JSONL (user and assistant
+            turns only); append several sessions with ">>" to build a dataset
+
+A bundle is written to <session-id>.mindx-session unless --output is given;
+the text formats go to stdout.`,
+	Example: `  mindx session export --session-id "01ABCDEFGHJK..."
+  mindx session export --session-id "01ABCDEFGHJK..." --format markdown -o review.md
+  mindx session export --session-id "01ABCDEFGHJK..." --format openai >> dataset.jsonl`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, _ := cmd.Flags().GetString("session-id")
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+		if id == "" {
+			return fmt.Errorf("--session-id is required")
+		}
+		cl, err := rpc.Dial(daemonAddr)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = cl.Close() }()
+		result, err := cl.SessionExport(id, format)
+		if err != nil {
+			return err
+		}
+
+		var resp rpc.SessionExportResult
+		if err := json.Unmarshal(result, &resp); err != nil {
+			return fmt.Errorf("decode export: %w", err)
+		}
+		data := []byte(resp.Data)
+		if resp.Encoding == "base64" {
+			if data, err = base64.StdEncoding.DecodeString(resp.Data); err != nil {
+				return fmt.Errorf("decode export: %w", err)
+			}
+			if output == "" {
+				output = resp.Filename
+			}
+		}
+		if output == "" {
+			_, err := os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			return fmt.Errorf("write %s: %w", output, err)
+		}
+		fmt.Fprintf(os.Stderr, "Session %s exported to %s\n", id, output)
+		return nil
+	},
+}
+
+// ── session import ────────────────────────────────────────────
+
+var sessionImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a session bundle as a new session",
+	Long: `Restores a .mindx-session bundle made by "mindx session export" as a new
+session with its own ID. Messages, metadata, compaction cursor, tracked
+file versions and token usage are restored; imported usage is kept for
+reporting but does not count against budgets.
+
+--agent and --project-dir override the bundle's own. Tracked file paths
+under the original project directory are moved to the new one.`,
+	Example: `  mindx session import 01ABCDEFGHJK.mindx-session
+  mindx session import review.mindx-session --project-dir ~/src/api`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		agent, _ := cmd.Flags().GetString("agent")
+		projectDir, _ := cmd.Flags().GetString("project-dir")
+		jsonOut, _ := cmd.Flags().GetBool("json")
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		if projectDir != "" {
+			if abs, err := filepath.Abs(projectDir); err == nil {
+				projectDir = abs
+			}
+		}
+		cl, err := rpc.Dial(daemonAddr)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = cl.Close() }()
+		result, err := cl.SessionImport(rpc.SessionImportParams{
+			Data:       base64.StdEncoding.EncodeToString(data),
+			Agent:      agent,
+			ProjectDir: projectDir,
+		})
+		if err != nil {
+			return err
+		}
+
+		var resp struct {
+			SessionID         string `json:"session_id"`
+			OriginalSessionID string `json:"original_session_id"`
+			AgentName         string `json:"agent_name"`
+			Messages          int    `json:"messages"`
+			TrackedFiles      int    `json:"tracked_files"`
+		}
+		if jsonOut || json.Unmarshal(result, &resp) != nil || resp.SessionID == "" {
+			fmt.Println(string(result))
+			return nil
+		}
+		fmt.Printf("Session imported: %s (agent %s, %d message(s), %d tracked file(s); was %s)\n",
+			resp.SessionID, resp.AgentName, resp.Messages, resp.TrackedFiles, resp.OriginalSessionID)
+		return nil
+	},
+}
+
+// ── session star ──────────────────────────────────────────────
+
+var sessionStarCmd = &cobra.Command{
+	Use:     "star",
+	Short:   "Star a session so retention keeps it",
+	Example: `  mindx session star --session-id "01ABCDEFGHJK..."`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setSessionStarred(cmd, true)
+	},
+}
+
+var sessionUnstarCmd = &cobra.Command{
+	Use:     "unstar",
+	Short:   "Remove a session's star",
+	Example: `  mindx session unstar --session-id "01ABCDEFGHJK..."`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setSessionStarred(cmd, false)
+	},
+}
+
+func setSessionStarred(cmd *cobra.Command, starred bool) error {
+	id, _ := cmd.Flags().GetString("session-id")
+	if id == "" {
+		return fmt.Errorf("--session-id is required")
+	}
+	cl, err := rpc.Dial(daemonAddr)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = cl.Close() }()
+	if _, err := cl.SessionStar(id, starred); err != nil {
+		return err
+	}
+	if starred {
+		fmt.Printf("Session starred: %s\n", id)
+	} else {
+		fmt.Printf("Session unstarred: %s\n", id)
+	}
+	return nil
+}
+
+// ── session share ─────────────────────────────────────────────
+
+var sessionShareCmd = &cobra.Command{
+	Use:   "share",
+	Short: "Create a read-only link to a session",
+	Long: `Creates an expiring, token-protected link to a read-only page of the
+session's messages, tool calls and file diffs, served by the daemon's WebUI
+server (port 1313). Anyone who can reach that port and has the link can read
+the session until it expires or is revoked with "mindx session unshare".
+
+The link uses http://localhost:1313; pass --base-url with the host name a
+teammate reaches this machine under.`,
+	Example: `  mindx session share --session-id "01ABCDEFGHJK..."
+  mindx session share --session-id "01ABCDEFGHJK..." --expires 72h --base-url http://devbox.lan:1313`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, _ := cmd.Flags().GetString("session-id")
+		expires, _ := cmd.Flags().GetDuration("expires")
+		baseURL, _ := cmd.Flags().GetString("base-url")
+		jsonOut, _ := cmd.Flags().GetBool("json")
+		if id == "" {
+			return fmt.Errorf("--session-id is required")
+		}
+		if expires < time.Second {
+			return fmt.Errorf("--expires must be at least 1s")
+		}
+		cl, err := rpc.Dial(daemonAddr)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = cl.Close() }()
+		result, err := cl.SessionShare(rpc.SessionShareParams{
+			SessionID: id, TTL: int(expires.Seconds()), BaseURL: baseURL,
+		})
+		if err != nil {
+			return err
+		}
+
+		var resp rpc.SessionShareResult
+		if jsonOut || json.Unmarshal(result, &resp) != nil || resp.URL == "" {
+			fmt.Println(string(result))
+			return nil
+		}
+		fmt.Println(resp.URL)
+		fmt.Printf("Expires %s; revoke with: mindx session unshare --token %s\n",
+			time.Unix(resp.ExpiresAt, 0).Format("2006-01-02 15:04"), resp.Token)
+		return nil
+	},
+}
+
+// ── session unshare ───────────────────────────────────────────
+
+var sessionUnshareCmd = &cobra.Command{
+	Use:   "unshare",
+	Short: "Revoke session share links",
+	Long: `Revokes the share link with --token, or every share link of the session
+with --session-id.`,
+	Example: `  mindx session unshare --token "Xb3k..."
+  mindx session unshare --session-id "01ABCDEFGHJK..."`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		token, _ := cmd.Flags().GetString("token")
+		id, _ := cmd.Flags().GetString("session-id")
+		if token == "" && id == "" {
+			return fmt.Errorf("--token or --session-id is required")
+		}
+		if i := strings.LastIndex(token, "/share/"); i >= 0 {
+			token = token[i+len("/share/"):] // accept the whole link
+		}
+		cl, err := rpc.Dial(daemonAddr)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = cl.Close() }()
+		result, err := cl.SessionUnshare(rpc.SessionUnshareParams{Token: token, SessionID: id})
+		if err != nil {
+			return err
+		}
+		var resp struct {
+			Revoked int `json:"revoked"`
+		}
+		_ = json.Unmarshal(result, &resp)
+		fmt.Printf("Share link(s) revoked: %d\n", resp.Revoked)
+		return nil
+	},
+}
+
+// ── session gc ────────────────────────────────────────────────
+
+var sessionGCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Archive or delete old sessions by the retention policy",
+	Long: `Applies the session retention policy ("session_retention" in mindx.json)
+now. The daemon also applies it in the background, by default once a day.
+
+A session is collected once it has been inactive for more than max_age_days,
+or once its agent has max_per_agent more recently active sessions. Starred
+sessions and sessions with a request in flight are kept. Collected
+sessions are archived as bundles under ~/.mindx/data/session_archive
+(restore with "mindx session import") or, with action "delete", deleted.
+
+--dry-run only reports what would be collected and the space reclaimed.
+The flags override the configured policy for this run.`,
+	Example: `  mindx session gc --dry-run
+  mindx session gc --max-age-days 90 --dry-run
+  mindx session gc --max-per-agent 20 --action delete`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var params rpc.SessionGCParams
+		params.DryRun, _ = cmd.Flags().GetBool("dry-run")
+		params.MaxAgeDays, _ = cmd.Flags().GetInt("max-age-days")
+		params.MaxPerAgent, _ = cmd.Flags().GetInt("max-per-agent")
+		params.Action, _ = cmd.Flags().GetString("action")
+		jsonOut, _ := cmd.Flags().GetBool("json")
+
+		cl, err := rpc.Dial(daemonAddr)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = cl.Close() }()
+		result, err := cl.SessionGC(params)
+		if err != nil {
+			return err
+		}
+
+		var report struct {
+			DryRun      bool   `json:"dry_run"`
+			Action      string `json:"action"`
+			Scanned     int    `json:"scanned"`
+			KeptStarred int    `json:"kept_starred"`
+			KeptActive  int    `json:"kept_active"`
+			Sessions    []struct {
+				SessionID      string    `json:"session_id"`
+				AgentName      string    `json:"agent_name"`
+				LastActivityAt time.Time `json:"last_activity_at"`
+				Reason         string    `json:"reason"`
+				Bytes          int64     `json:"bytes"`
+				Error          string    `json:"error"`
+			} `json:"sessions"`
+			ReclaimedBytes int64 `json:"reclaimed_bytes"`
+			ArchivedBytes  int64 `json:"archived_bytes"`
+		}
+		if jsonOut || json.Unmarshal(result, &report) != nil {
+			fmt.Println(string(result))
+			return nil
+		}
+
+		if len(report.Sessions) > 0 {
+			table := render.NewTable([]string{"Session ID", "Agent", "Last Active", "Reason", "Size", "Error"}, 120)
+			for _, s := range report.Sessions {
+				table.AddRow([]string{
+					s.SessionID,
+					s.AgentName,
+					s.LastActivityAt.Local().Format("2006-01-02 15:04"),
+					s.Reason,
+					formatByteSize(s.Bytes),
+					s.Error,
+				})
+			}
+			fmt.Println(table.Render())
+			fmt.Println()
+		}
+
+		verb := map[string]string{"archive": "archived", "delete": "deleted"}[report.Action]
+		if report.DryRun {
+			fmt.Printf("Dry run: %d of %d session(s) would be %s, reclaiming %s\n",
+				len(report.Sessions), report.Scanned, verb, formatByteSize(report.ReclaimedBytes))
+		} else {
+			fmt.Printf("%d of %d session(s) %s, reclaimed %s", len(report.Sessions), report.Scanned, verb, formatByteSize(report.ReclaimedBytes))
+			if report.ArchivedBytes > 0 {
+				fmt.Printf(" (archives: %s)", formatByteSize(report.ArchivedBytes))
+			}
+			fmt.Println()
+		}
+		if report.KeptStarred > 0 || report.KeptActive > 0 {
+			fmt.Printf("Kept: %d starred, %d in use\n", report.KeptStarred, report.KeptActive)
+		}
+		return nil
+	},
+}
+
+// formatByteSize renders n bytes with a binary unit, e.g. "1.5 MiB".
+func formatByteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// ── session search ────────────────────────────────────────────
+
+var sessionSearchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Full-text search over the messages of all sessions",
+	Long: `Searches the messages of every session, across all agents. A message
+matches when it contains every word of the query; Chinese, Japanese and
+Korean text is matched by character pairs, so no spaces are needed.
+
+Each hit shows the session, the message offset (its 0-based position in
+the session's full history, including compacted messages) and a snippet
+around the match.`,
+	Example: `  mindx session search "decided sqlite"
+  mindx session search "数据库 选型" --agent coder --role assistant
+  mindx session search retention --project-dir /work/api --since 2026-09-01 --until 2026-10-01`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		agent, _ := cmd.Flags().GetString("agent")
+		projectDir, _ := cmd.Flags().GetString("project-dir")
+		since, _ := cmd.Flags().GetString("since")
+		until, _ := cmd.Flags().GetString("until")
+		roles, _ := cmd.Flags().GetString("role")
+		limit, _ := cmd.Flags().GetInt("limit")
+		jsonOut, _ := cmd.Flags().GetBool("json")
+
+		params := rpc.SessionSearchParams{
+			Query:      strings.Join(args, " "),
+			Agent:      agent,
+			ProjectDir: projectDir,
+			Since:      since,
+			Until:      until,
+			Limit:      limit,
+		}
+		if roles != "" {
+			params.Roles = splitComma(roles)
+		}
+		if params.ProjectDir != "" {
+			if abs, err := filepath.Abs(params.ProjectDir); err == nil {
+				params.ProjectDir = abs
+			}
+		}
+
+		cl, err := rpc.Dial(daemonAddr)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = cl.Close() }()
+		result, err := cl.SessionSearch(params)
+		if err != nil {
+			return err
+		}
+
+		if jsonOut {
+			fmt.Println(string(result))
+			return nil
+		}
+
+		var hits []sessionSearchHit
+		if err := json.Unmarshal(result, &hits); err != nil {
+			fmt.Println(string(result))
+			return nil
+		}
+		if len(hits) == 0 {
+			fmt.Println("No matching messages.")
+			return nil
+		}
+
+		table := render.NewTable([]string{"Session ID", "Agent", "#", "Role", "Time", "Snippet"}, 140)
+		for _, h := range hits {
+			table.AddRow([]string{
+				h.SessionID,
+				h.AgentName,
+				fmt.Sprintf("%d", h.Offset),
+				h.Role,
+				time.UnixMilli(h.Timestamp).Format("2006-01-02 15:04"),
+				h.Snippet,
+			})
+		}
+		fmt.Println(table.Render())
+		fmt.Printf("\n%d match(es)\n", len(hits))
+		return nil
+	},
+}
+
 // ── session delete ────────────────────────────────────────────
 
 var sessionDeleteCmd = &cobra.Command{
@@ -391,8 +963,38 @@ func init() {
 	sessionCreateCmd.Flags().String("project-dir", "", "Project directory for file indexing")
 	sessionListCmd.Flags().String("agent", "", "Filter by agent name")
 	sessionListCmd.Flags().Bool("json", false, "Output raw JSON")
+	sessionListCmd.Flags().Bool("tree", false, "Show forks nested under the session they were forked from")
+	sessionForkCmd.Flags().String("session-id", "", "Session ID (required)")
+	sessionForkCmd.Flags().Int("at", 0, "Index of the last message to keep (required)")
+	sessionForkCmd.Flags().Bool("json", false, "Output raw JSON")
+	sessionExportCmd.Flags().String("session-id", "", "Session ID (required)")
+	sessionExportCmd.Flags().String("format", "bundle", "Export format: bundle, markdown or openai")
+	sessionExportCmd.Flags().StringP("output", "o", "", "Write to this file (default: <session-id>.mindx-session for bundles, stdout otherwise)")
+	sessionImportCmd.Flags().String("agent", "", "Import under this agent instead of the bundle's")
+	sessionImportCmd.Flags().String("project-dir", "", "Project directory of the imported session")
+	sessionImportCmd.Flags().Bool("json", false, "Output raw JSON")
+	sessionStarCmd.Flags().String("session-id", "", "Session ID (required)")
+	sessionUnstarCmd.Flags().String("session-id", "", "Session ID (required)")
+	sessionShareCmd.Flags().String("session-id", "", "Session ID (required)")
+	sessionShareCmd.Flags().Duration("expires", 24*time.Hour, "How long the link stays valid (at most 720h)")
+	sessionShareCmd.Flags().String("base-url", "", "Origin of the link instead of http://localhost:1313")
+	sessionShareCmd.Flags().Bool("json", false, "Output raw JSON")
+	sessionUnshareCmd.Flags().String("token", "", "Share token or link to revoke")
+	sessionUnshareCmd.Flags().String("session-id", "", "Revoke every share link of this session")
+	sessionGCCmd.Flags().Bool("dry-run", false, "Only report what would be collected")
+	sessionGCCmd.Flags().Int("max-age-days", 0, "Collect sessions inactive for more than this many days")
+	sessionGCCmd.Flags().Int("max-per-agent", 0, "Keep only this many most recently active sessions per agent")
+	sessionGCCmd.Flags().String("action", "", "What to do with collected sessions: archive or delete")
+	sessionGCCmd.Flags().Bool("json", false, "Output raw JSON")
 	sessionGetCmd.Flags().String("session-id", "", "Session ID (required)")
 	sessionGetCmd.Flags().Bool("json", false, "Output raw JSON")
+	sessionSearchCmd.Flags().String("agent", "", "Only search sessions of this agent")
+	sessionSearchCmd.Flags().String("project-dir", "", "Only search sessions in this project directory (or below)")
+	sessionSearchCmd.Flags().String("since", "", "Only messages at or after this date (YYYY-MM-DD or RFC 3339)")
+	sessionSearchCmd.Flags().String("until", "", "Only messages before this date (YYYY-MM-DD or RFC 3339)")
+	sessionSearchCmd.Flags().String("role", "", "Comma-separated message roles, e.g. user,assistant")
+	sessionSearchCmd.Flags().Int("limit", 20, "Maximum number of matches")
+	sessionSearchCmd.Flags().Bool("json", false, "Output raw JSON")
 	sessionDeleteCmd.Flags().String("session-id", "", "Session ID (required)")
 	sessionMetaCmd.Flags().String("session-id", "", "Session ID (required)")
 	sessionContextCmd.Flags().String("session-id", "", "Session ID (required)")
@@ -405,6 +1007,15 @@ func init() {
 	sessionCmd.AddCommand(sessionCreateCmd)
 	sessionCmd.AddCommand(sessionListCmd)
 	sessionCmd.AddCommand(sessionGetCmd)
+	sessionCmd.AddCommand(sessionSearchCmd)
+	sessionCmd.AddCommand(sessionForkCmd)
+	sessionCmd.AddCommand(sessionExportCmd)
+	sessionCmd.AddCommand(sessionImportCmd)
+	sessionCmd.AddCommand(sessionStarCmd)
+	sessionCmd.AddCommand(sessionUnstarCmd)
+	sessionCmd.AddCommand(sessionShareCmd)
+	sessionCmd.AddCommand(sessionUnshareCmd)
+	sessionCmd.AddCommand(sessionGCCmd)
 	sessionCmd.AddCommand(sessionDeleteCmd)
 	sessionCmd.AddCommand(sessionMetaCmd)
 	sessionCmd.AddCommand(sessionContextCmd)
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/DotNetAge/mindx/pkg/rpc"
+	"github.com/spf13/cobra"
+)
+
+// ── storage parent ────────────────────────────────────────────
+
+var storageCmd = &cobra.Command{
+	Use:   "storage",
+	Short: "Encryption of stored data (requires daemon)",
+	Long: `Manage encryption at rest of sessions, token usage, file versions and
+memory.
+
+Encryption is turned on with "storage": {"encrypt": true} in mindx.json and
+takes effect when the daemon restarts. The data keys are kept in the
+credential store (the macOS keychain, or ~/.mindx/settings/.credentials).
+
+All operations require the daemon to be running (mindx start).
+
+Examples:
+  mindx storage rekey`,
+	PersistentPreRunE: requireDaemon,
+}
+
+func init() {
+	rootCmd.AddCommand(storageCmd)
+}
+
+// ── storage rekey ─────────────────────────────────────────────
+
+var storageRekeyCmd = &cobra.Command{
+	Use:   "rekey",
+	Short: "Rotate the data key and re-encrypt all stored data",
+	Long: `Generates a new data key and rewrites every store with it: sessions,
+the session search index, token usage, file versions, retention archives
+and memory. Old keys are retired once everything has been rewritten: no
+longer used to encrypt, but kept to decrypt. If the rekey fails it can
+be run again.
+
+Run it after turning encryption on, to encrypt data written before, and
+after turning it off, to decrypt everything. It refuses to run while
+another mindx process, such as a local TUI, uses the data.
+
+Memory text is sealed in the vector store; the vectors themselves and
+the agent, project and session fields used to filter them are not.
+Backups under a session's backup/ directory and bundles written by
+session export are not encrypted either.`,
+	Example: `  mindx storage rekey`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jsonOut, _ := cmd.Flags().GetBool("json")
+
+		cl, err := rpc.Dial(daemonAddr)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = cl.Close() }()
+		result, err := cl.StorageRekey(context.Background())
+		if err != nil {
+			return err
+		}
+
+		var report struct {
+			Encrypted   bool           `json:"encrypted"`
+			PrimaryKey  string         `json:"primary_key"`
+			RetiredKeys []string       `json:"retired_keys"`
+			Rewritten   map[string]int `json:"rewritten"`
+		}
+		if jsonOut || json.Unmarshal(result, &report) != nil {
+			fmt.Println(string(result))
+			return nil
+		}
+
+		stores := make([]string, 0, len(report.Rewritten))
+		for name := range report.Rewritten {
+			stores = append(stores, name)
+		}
+		sort.Strings(stores)
+		for _, name := range stores {
+			fmt.Printf("  %-14s %d rewritten\n", name, report.Rewritten[name])
+		}
+		if report.Encrypted {
+			fmt.Printf("Storage encrypted with key %s", report.PrimaryKey)
+		} else {
+			fmt.Print("Storage decrypted")
+		}
+		if len(report.RetiredKeys) > 0 {
+			fmt.Printf("; retired %s", strings.Join(report.RetiredKeys, ", "))
+		}
+		fmt.Println()
+		return nil
+	},
+}
+
+func init() {
+	storageRekeyCmd.Flags().Bool("json", false, "Output raw JSON")
+
+	storageCmd.AddCommand(storageRekeyCmd)
+}
@@ -3,6 +3,10 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/DotNetAge/mindx/internal/client/render"
@@ -24,7 +28,8 @@ Examples:
   mindx token monthly --year 2026 --month 6
   mindx token by-model --model gpt-4o --year 2026 --month 6
   mindx token total
-  mindx token session --session-id "abc123"`,
+  mindx token session --session-id "abc123"
+  mindx token export --since 2026-06-01 --until 2026-07-01 --group-by project_dir,agent`,
 	PersistentPreRunE: requireDaemon,
 }
 
@@ -265,6 +270,193 @@ var tokenSessionCmd = &cobra.Command{
 	},
 }
 
+// ── token export ──────────────────────────────────────────────
+
+var tokenExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export token usage records and a chargeback summary",
+	Long: `Export token usage records over a date range as CSV, JSONL or columnar
+JSON. Records are fetched from the daemon page by page and written as they
+arrive. --until is exclusive; both default to open bounds.
+
+--group-by selects the dimensions of the chargeback summary: project_dir,
+agent, model, source and session. The summary is printed to stderr after
+the records, or alone with --summary.
+
+Usage that came with imported sessions was spent elsewhere and is left out
+unless --include-imported is given.`,
+	Example: `  mindx token export --since 2026-06-01 --until 2026-07-01 > june.csv
+  mindx token export --since 2026-06-01 --format jsonl --output usage.jsonl
+  mindx token export --since 2026-06-01 --until 2026-07-01 --group-by project_dir,agent --summary`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		since, _ := cmd.Flags().GetString("since")
+		until, _ := cmd.Flags().GetString("until")
+		format, _ := cmd.Flags().GetString("format")
+		groupBy, _ := cmd.Flags().GetStringSlice("group-by")
+		output, _ := cmd.Flags().GetString("output")
+		summaryOnly, _ := cmd.Flags().GetBool("summary")
+		pageSize, _ := cmd.Flags().GetInt("page-size")
+		jsonOut, _ := cmd.Flags().GetBool("json")
+		includeImported, _ := cmd.Flags().GetBool("include-imported")
+
+		cl, err := rpc.Dial(daemonAddr)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = cl.Close() }()
+
+		params := rpc.TokenUsageExportParams{
+			Since: since, Until: until, Format: format, GroupBy: groupBy, Limit: pageSize,
+			IncludeImported: includeImported,
+		}
+		if summaryOnly {
+			params.Limit = 1
+		}
+
+		var out io.Writer = os.Stdout
+		if output != "" && !summaryOnly {
+			f, err := os.Create(output)
+			if err != nil {
+				return fmt.Errorf("create output: %w", err)
+			}
+			defer func() { _ = f.Close() }()
+			out = f
+		}
+
+		var summary json.RawMessage
+		var columnar *tokenColumnar
+		for {
+			result, err := cl.TokenUsageExport(params)
+			if err != nil {
+				return err
+			}
+			var page struct {
+				Format     string          `json:"format"`
+				HasMore    bool            `json:"has_more"`
+				NextOffset int             `json:"next_offset"`
+				Cursor     string          `json:"cursor"`
+				Data       json.RawMessage `json:"data"`
+				Summary    json.RawMessage `json:"summary"`
+			}
+			if err := json.Unmarshal(result, &page); err != nil {
+				return fmt.Errorf("decode export page: %w", err)
+			}
+			if params.Cursor == "" {
+				summary = page.Summary
+				params.Cursor = page.Cursor
+			}
+			if summaryOnly {
+				break
+			}
+
+			if page.Format == "columnar" {
+				var c tokenColumnar
+				if err := json.Unmarshal(page.Data, &c); err != nil {
+					return fmt.Errorf("decode columnar page: %w", err)
+				}
+				if columnar == nil {
+					columnar = &c
+				} else {
+					columnar.append(c)
+				}
+			} else {
+				var text string
+				if err := json.Unmarshal(page.Data, &text); err != nil {
+					return fmt.Errorf("decode export page: %w", err)
+				}
+				if _, err := io.WriteString(out, text); err != nil {
+					return fmt.Errorf("write output: %w", err)
+				}
+			}
+
+			if !page.HasMore {
+				break
+			}
+			params.Offset = page.NextOffset
+		}
+
+		if columnar != nil {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(columnar); err != nil {
+				return fmt.Errorf("write output: %w", err)
+			}
+		}
+
+		// The summary goes to stdout when it is all we print, to stderr
+		// otherwise so that it never mixes with exported records.
+		summaryOut := io.Writer(os.Stderr)
+		if summaryOnly {
+			summaryOut = os.Stdout
+		}
+		if jsonOut {
+			_, _ = fmt.Fprintln(summaryOut, string(summary))
+			return nil
+		}
+		return printChargeback(summaryOut, summary, groupBy)
+	},
+}
+
+// tokenColumnar is a columnar export page; pages are merged column by
+// column before the document is written.
+type tokenColumnar struct {
+	Schema   []map[string]string      `json:"schema"`
+	RowCount int                      `json:"row_count"`
+	Columns  map[string][]interface{} `json:"columns"`
+}
+
+func (c *tokenColumnar) append(next tokenColumnar) {
+	for name, values := range next.Columns {
+		c.Columns[name] = append(c.Columns[name], values...)
+	}
+	c.RowCount += next.RowCount
+}
+
+func printChargeback(w io.Writer, raw json.RawMessage, groupBy []string) error {
+	var lines []struct {
+		Group       map[string]string  `json:"group"`
+		Requests    int                `json:"requests"`
+		TotalTokens int                `json:"total_tokens"`
+		Cost        map[string]float64 `json:"cost_by_currency"`
+		Unpriced    int                `json:"unpriced_requests"`
+	}
+	if err := json.Unmarshal(raw, &lines); err != nil {
+		_, _ = fmt.Fprintln(w, string(raw))
+		return nil
+	}
+
+	headers := make([]string, 0, len(groupBy)+4)
+	for _, g := range groupBy {
+		headers = append(headers, strings.TrimSpace(g))
+	}
+	headers = append(headers, "Requests", "Tokens", "Cost", "Unpriced")
+	table := render.NewTable(headers, 120)
+	for _, l := range lines {
+		row := make([]string, 0, len(headers))
+		for _, g := range groupBy {
+			row = append(row, l.Group[strings.TrimSpace(g)])
+		}
+		currencies := make([]string, 0, len(l.Cost))
+		for cur := range l.Cost {
+			currencies = append(currencies, cur)
+		}
+		sort.Strings(currencies)
+		costs := make([]string, 0, len(currencies))
+		for _, cur := range currencies {
+			costs = append(costs, fmt.Sprintf("%.4f %s", l.Cost[cur], cur))
+		}
+		row = append(row,
+			fmt.Sprintf("%d", l.Requests),
+			fmt.Sprintf("%d", l.TotalTokens),
+			strings.Join(costs, ", "),
+			fmt.Sprintf("%d", l.Unpriced),
+		)
+		table.AddRow(row)
+	}
+	_, _ = fmt.Fprintln(w, table.Render())
+	return nil
+}
+
 // ── init subcommands ──────────────────────────────────────────
 
 func init() {
@@ -280,9 +472,20 @@ func init() {
 	tokenSessionCmd.Flags().String("session-id", "", "Session ID (required)")
 	tokenSessionCmd.Flags().Bool("json", false, "Output raw JSON")
 
+	tokenExportCmd.Flags().String("since", "", "Start date YYYY-MM-DD, inclusive")
+	tokenExportCmd.Flags().String("until", "", "End date YYYY-MM-DD, exclusive")
+	tokenExportCmd.Flags().String("format", "csv", "Record format: csv, jsonl or columnar")
+	tokenExportCmd.Flags().StringSlice("group-by", nil, "Chargeback dimensions: project_dir, agent, model, source, session")
+	tokenExportCmd.Flags().StringP("output", "o", "", "Write records to a file instead of stdout")
+	tokenExportCmd.Flags().Bool("summary", false, "Print only the chargeback summary")
+	tokenExportCmd.Flags().Int("page-size", 0, "Records per RPC page (default: server default)")
+	tokenExportCmd.Flags().Bool("json", false, "Output the chargeback summary as raw JSON")
+	tokenExportCmd.Flags().Bool("include-imported", false, "Include usage that came with imported sessions")
+
 	tokenCmd.AddCommand(tokenOverviewCmd)
 	tokenCmd.AddCommand(tokenMonthlyCmd)
 	tokenCmd.AddCommand(tokenByModelCmd)
 	tokenCmd.AddCommand(tokenTotalCmd)
 	tokenCmd.AddCommand(tokenSessionCmd)
+	tokenCmd.AddCommand(tokenExportCmd)
 }
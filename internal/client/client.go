@@ -32,6 +32,7 @@ import (
 	clientmsg "github.com/DotNetAge/mindx/internal/client/msg"
 	appcore "github.com/DotNetAge/mindx/internal/core"
 	"github.com/DotNetAge/mindx/internal/i18n"
+	"github.com/DotNetAge/mindx/pkg/execctx"
 )
 
 const (
@@ -1200,7 +1201,8 @@ func (m *rootModel) handleSend(e clientmsg.UserSendMsg) (tea.Model, tea.Cmd) {
 			return
 		}
 
-		ask := rt.Ask(agentName, e.Text, s).WithContext(ctx)
+		askCtx := execctx.WithSessionID(execctx.WithProjectDir(ctx, sessionMeta.ProjectDir), sessionID)
+		ask := rt.Ask(agentName, e.Text, s).WithContext(askCtx)
 
 		ask.OnContent(func(c string) {
 			m.program.Send(clientmsg.ContentDeltaMsg{SessionID: sessionID, Content: c})
@@ -4,13 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/DotNetAge/gort/pkg/gateway"
 	"github.com/DotNetAge/mindx/internal/i18n"
 	"github.com/DotNetAge/mindx/pkg/scheduler"
 	"github.com/google/uuid"
-	"github.com/robfig/cron/v3"
 )
 
 // SchedulerDeps holds external dependencies for scheduler commands.
@@ -68,40 +69,54 @@ func handleJobAdd(ctx *gateway.CommandContext) (any, error) {
 		return nil, errors.New(i18n.T("cmd.scheduler.usage") + "\n" + i18n.T("cmd.scheduler.example"))
 	}
 
-	agent, sessionID, content, cronExpr, projectDir, err := parseJobAddArgs(argsStr)
+	entry, err := parseJobAddArgs(argsStr)
 	if err != nil {
 		return nil, err
 	}
+	entry.ID = generateID()
+	entry.Enabled = true
 
-	parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	if _, err := parser.Parse(cronExpr); err != nil {
-		return nil, fmt.Errorf(i18n.T("cmd.scheduler.invalid.cron"), err)
-	}
-
-	entry := &scheduler.ScheduleEntry{
-		ID:         generateID(),
-		Agent:      agent,
-		SessionID:  sessionID,
-		ProjectDir: projectDir,
-		Content:    content,
-		CronExpr:   cronExpr,
-		Enabled:    true,
+	if err := entry.Validate(); err != nil {
+		return nil, fmt.Errorf(i18n.T("cmd.scheduler.invalid.schedule"), err)
 	}
 
 	if err := schedulerDeps.SchedulerDB().Save(context.Background(), entry); err != nil {
+		if errors.Is(err, scheduler.ErrNotApplied) {
+			return nil, fmt.Errorf(i18n.T("cmd.scheduler.not.applied"), err)
+		}
 		return nil, fmt.Errorf(i18n.T("cmd.scheduler.save.failed"), err)
 	}
 
-	sessInfo := sessionID
+	sessInfo := entry.SessionID
 	if sessInfo == "" || sessInfo == "new" {
 		sessInfo = "(auto)"
 	}
-	dirInfo := projectDir
+	dirInfo := entry.ProjectDir
 	if dirInfo == "" {
 		dirInfo = "(daemon default)"
 	}
 	return fmt.Sprintf(i18n.T("cmd.scheduler.job.created")+"\n  ID: %s\n  "+i18n.T("cmd.scheduler.job.target")+": @%s\n  Session: %s\n  "+i18n.T("cmd.scheduler.job.projectdir")+": %s\n  "+i18n.T("cmd.scheduler.job.content")+": %s\n  "+i18n.T("cmd.scheduler.job.schedule")+": %s",
-		entry.ID, entry.Agent, sessInfo, dirInfo, truncateString(entry.Content, 50), entry.CronExpr), nil
+		entry.ID, entry.Agent, sessInfo, dirInfo, truncateString(entry.Content, 50), describeSchedule(entry)), nil
+}
+
+// describeSchedule renders an entry's trigger for list and confirmation output.
+func describeSchedule(entry *scheduler.ScheduleEntry) string {
+	var s string
+	switch entry.TriggerKind() {
+	case scheduler.TriggerAt:
+		s = "at " + entry.RunAt.Format("2006-01-02 15:04:05")
+	case scheduler.TriggerEvery:
+		s = "every " + entry.Every
+	default:
+		s = entry.CronExpr
+	}
+	if entry.Timezone != "" {
+		s += " (" + entry.Timezone + ")"
+	}
+	if entry.MaxRuns > 0 {
+		s += fmt.Sprintf(" ×%d", entry.MaxRuns)
+	}
+	return s
 }
 
 func handleJobList(ctx *gateway.CommandContext) (any, error) {
@@ -125,6 +140,8 @@ func handleJobList(ctx *gateway.CommandContext) (any, error) {
 		status := i18n.T("cmd.scheduler.status.disabled")
 		if entry.Enabled {
 			status = i18n.T("cmd.scheduler.status.enabled")
+		} else if !entry.RetiredAt.IsZero() {
+			status = i18n.T("cmd.scheduler.status.retired")
 		}
 		sessDisplay := entry.SessionID
 		if sessDisplay == "" || sessDisplay == "new" {
@@ -140,7 +157,7 @@ func handleJobList(ctx *gateway.CommandContext) (any, error) {
 			sessDisplay,
 			dirDisplay,
 			truncateString(entry.Content, 30),
-			entry.CronExpr,
+			describeSchedule(&entry),
 			status,
 			fmt.Sprintf("%d/%d", entry.SuccessCnt, entry.FailureCnt),
 		})
@@ -233,62 +250,98 @@ func splitArgs(s string) []string {
 	return parts
 }
 
-func parseJobAddArgs(argsStr string) (agent string, sessionID string, content string, cronExpr string, projectDir string, err error) {
+// jobAddOptions lists the key=value options accepted by /job-add. Times are
+// parsed after all options are collected so tz= applies regardless of order.
+var jobAddOptions = []string{"expr", "at", "every", "tz", "start", "end", "max", "dir", "project"}
+
+func parseJobAddArgs(argsStr string) (*scheduler.ScheduleEntry, error) {
 	parts := splitArgs(argsStr)
-	var agentIdx, exprIdx, dirIdx = -1, -1, -1
-	var exprValue, dirValue string
+	agentIdx := -1
+	used := make(map[int]bool)
+	opts := make(map[string]string)
+	entry := &scheduler.ScheduleEntry{}
 
 	for i, part := range parts {
 		if strings.HasPrefix(part, "@") && agentIdx == -1 {
 			agentIdx = i
-			agent = strings.TrimPrefix(part, "@")
-		} else if strings.HasPrefix(part, "expr=") {
-			exprIdx = i
-			exprValue = strings.TrimPrefix(part, "expr=")
-			exprValue = strings.Trim(exprValue, "\"'")
-		} else if (strings.HasPrefix(part, "dir=") || strings.HasPrefix(part, "project=")) && dirIdx == -1 {
-			dirIdx = i
-			if strings.HasPrefix(part, "dir=") {
-				dirValue = strings.TrimPrefix(part, "dir=")
-			} else {
-				dirValue = strings.TrimPrefix(part, "project=")
+			used[i] = true
+			entry.Agent = strings.TrimPrefix(part, "@")
+			continue
+		}
+		for _, key := range jobAddOptions {
+			if v, ok := strings.CutPrefix(part, key+"="); ok {
+				if _, seen := opts[key]; !seen {
+					opts[key] = strings.Trim(v, "\"'")
+				}
+				used[i] = true
+				break
 			}
-			dirValue = strings.Trim(dirValue, "\"'")
 		}
 	}
 
-	if agent == "" {
-		return "", "", "", "", "", errors.New(i18n.T("cmd.scheduler.missing.agent") + "\n" + i18n.T("cmd.scheduler.add.example"))
+	if entry.Agent == "" {
+		return nil, errors.New(i18n.T("cmd.scheduler.missing.agent") + "\n" + i18n.T("cmd.scheduler.add.example"))
 	}
 
-	if exprValue == "" {
-		return "", "", "", "", "", errors.New(i18n.T("cmd.scheduler.missing.cron") + "\n" + i18n.T("cmd.scheduler.add.example"))
+	entry.CronExpr = opts["expr"]
+	entry.Every = opts["every"]
+	entry.Timezone = opts["tz"]
+	entry.ProjectDir = opts["dir"]
+	if entry.ProjectDir == "" {
+		entry.ProjectDir = opts["project"]
+	}
+	switch {
+	case entry.CronExpr != "":
+		entry.Trigger = scheduler.TriggerCron
+	case opts["at"] != "":
+		entry.Trigger = scheduler.TriggerAt
+	case entry.Every != "":
+		entry.Trigger = scheduler.TriggerEvery
+	default:
+		return nil, errors.New(i18n.T("cmd.scheduler.missing.cron") + "\n" + i18n.T("cmd.scheduler.add.example"))
+	}
+	for key, dst := range map[string]*time.Time{"at": &entry.RunAt, "start": &entry.StartAt, "end": &entry.EndAt} {
+		if opts[key] == "" {
+			continue
+		}
+		t, err := scheduler.ParseTime(opts[key], entry.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf(i18n.T("cmd.scheduler.invalid.schedule"), err)
+		}
+		*dst = t
+	}
+	if v := opts["max"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf(i18n.T("cmd.scheduler.invalid.schedule"), err)
+		}
+		entry.MaxRuns = n
 	}
 
 	var contentParts []string
 	for i, part := range parts {
-		if i == agentIdx || i == exprIdx || i == dirIdx {
+		if used[i] {
 			continue
 		}
 		contentParts = append(contentParts, part)
 	}
 
 	if len(contentParts) == 0 {
-		return "", "", "", "", "", fmt.Errorf(i18n.T("cmd.scheduler.missing.content"), agent)
+		return nil, fmt.Errorf(i18n.T("cmd.scheduler.missing.content"), entry.Agent)
 	}
 
-	sessionID = contentParts[0]
-	content = strings.Join(contentParts[1:], " ")
-	if content == "" {
-		content = sessionID
-		sessionID = "new"
+	entry.SessionID = contentParts[0]
+	entry.Content = strings.Join(contentParts[1:], " ")
+	if entry.Content == "" {
+		entry.Content = entry.SessionID
+		entry.SessionID = "new"
 	}
 
-	if sessionID == "" {
-		sessionID = "new"
+	if entry.SessionID == "" {
+		entry.SessionID = "new"
 	}
 
-	return agent, sessionID, content, exprValue, dirValue, nil
+	return entry, nil
 }
 
 func truncateString(s string, maxLen int) string {
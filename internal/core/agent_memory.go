@@ -0,0 +1,104 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/DotNetAge/mindx/pkg/memory"
+	"gopkg.in/yaml.v3"
+)
+
+// agentMemoryFrontMatter is the part of an agent file's front-matter that
+// mindx reads itself; goharness parses the rest.
+//
+//	memory:
+//	  read: [global, project, agent, session]
+//	  write: [agent]
+type agentMemoryFrontMatter struct {
+	Name   string `yaml:"name"`
+	Memory *struct {
+		Read  *[]memory.Scope `yaml:"read"`
+		Write *[]memory.Scope `yaml:"write"`
+	} `yaml:"memory"`
+}
+
+// privateMemoryPolicy applies to an agent whose memory declaration cannot
+// be read: it sees and keeps only its own memories.
+var privateMemoryPolicy = memory.ScopePolicy{
+	Read:  []memory.Scope{memory.ScopeAgent, memory.ScopeSession},
+	Write: []memory.Scope{memory.ScopeAgent},
+}
+
+// LoadAgentMemoryPolicy returns the memory scopes the agent name declares
+// in its file under dir, {name}.md or the file whose front-matter names
+// it. An agent without a declaration, or without a file, gets
+// memory.DefaultScopePolicy; a declaration leaving out read or write
+// keeps the default for it, and an empty list grants nothing.
+func LoadAgentMemoryPolicy(dir, name string) (memory.ScopePolicy, error) {
+	fm, err := readAgentFrontMatter(filepath.Join(dir, strings.ToLower(name)+".md"))
+	if err != nil && !os.IsNotExist(err) {
+		return memory.ScopePolicy{}, err
+	}
+	if fm == nil || fm.Name != name {
+		fm = nil
+		paths, _ := filepath.Glob(filepath.Join(dir, "*.md"))
+		for _, path := range paths {
+			if other, err := readAgentFrontMatter(path); err == nil && other != nil && other.Name == name {
+				fm = other
+				break
+			}
+		}
+	}
+
+	policy := memory.DefaultScopePolicy
+	if fm == nil || fm.Memory == nil {
+		return policy, nil
+	}
+	if fm.Memory.Read != nil {
+		policy.Read = *fm.Memory.Read
+	}
+	if fm.Memory.Write != nil {
+		policy.Write = *fm.Memory.Write
+	}
+	if err := policy.Validate(); err != nil {
+		return memory.ScopePolicy{}, fmt.Errorf("agent %s: %w", name, err)
+	}
+	return policy, nil
+}
+
+// readAgentFrontMatter parses the YAML front-matter of the agent file at
+// path; a file without one yields nil.
+func readAgentFrontMatter(path string) (*agentMemoryFrontMatter, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	if !bytes.HasPrefix(data, []byte("---\n")) {
+		return nil, nil
+	}
+	end := bytes.Index(data[4:], []byte("\n---"))
+	if end < 0 {
+		return nil, nil
+	}
+	var fm agentMemoryFrontMatter
+	if err := yaml.Unmarshal(data[4:4+end], &fm); err != nil {
+		return nil, fmt.Errorf("parse front-matter of %s: %w", path, err)
+	}
+	return &fm, nil
+}
+
+// MemoryPolicy returns the memory scopes the agent may read and write. An
+// unreadable declaration is logged and limits the agent to its own
+// memories.
+func (a *App) MemoryPolicy(agentName string) memory.ScopePolicy {
+	policy, err := LoadAgentMemoryPolicy(a.settings.AgentsDir(), agentName)
+	if err != nil {
+		a.logger.Warn("invalid agent memory scopes, limiting agent to its own memories", "agent", agentName, "error", err)
+		return privateMemoryPolicy
+	}
+	return policy
+}
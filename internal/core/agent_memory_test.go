@@ -0,0 +1,53 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadAgentMemoryPolicy(t *testing.T) {
+	dir := t.TempDir()
+	write := func(file, frontMatter string) {
+		t.Helper()
+		data := "---\n" + frontMatter + "---\n\n我是测试智能体。\n"
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("backend-engineer.md", "name: backend-engineer\nrole: 后端\n")
+	write("marketing-director.md", "name: marketing-director\nmemory:\n  read: [global, agent, session]\n  write: [agent]\n")
+	write("auditor.md", "name: auditor\nmemory:\n  write: []\n")
+	write("renamed.md", "name: Writer\nmemory:\n  read: [project]\n")
+	write("broken.md", "name: broken\nmemory:\n  read: [team]\n")
+
+	tests := []struct {
+		name  string
+		read  string
+		write string
+	}{
+		{"backend-engineer", "[global project agent session]", "[agent]"},
+		{"marketing-director", "[global agent session]", "[agent]"},
+		{"auditor", "[global project agent session]", "[]"},
+		{"Writer", "[project]", "[agent]"},
+		{"missing", "[global project agent session]", "[agent]"},
+	}
+	for _, tt := range tests {
+		p, err := LoadAgentMemoryPolicy(dir, tt.name)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := fmt.Sprint(p.Read); got != tt.read {
+			t.Errorf("%s read = %s, want %s", tt.name, got, tt.read)
+		}
+		if got := fmt.Sprint(p.Write); got != tt.write {
+			t.Errorf("%s write = %s, want %s", tt.name, got, tt.write)
+		}
+	}
+
+	if _, err := LoadAgentMemoryPolicy(dir, "broken"); err == nil {
+		t.Error("an unknown scope should fail")
+	}
+}
@@ -42,6 +42,9 @@ type App struct {
 	mindxConfig *MindxConfig
 	credStore   CredentialStore
 	logger      logging.Logger
+	// storageDir is the Settings.StorageDir shared with other processes
+	// for storage keys; empty when there is none.
+	storageDir string
 
 	// Registries (shared across all agents)
 	agents      *config.AgentRegistry
@@ -62,7 +65,8 @@ type App struct {
 	permissionRuleStore *MindxPermissionRuleStore
 
 	// Optional components
-	embedder goragcore.Embedder
+	embedder   goragcore.Embedder
+	embedderID string
 
 	// Knowledge graph indexer (injected by Daemon after initialization)
 	graphIndexer *goragindexer.GraphIndexer
@@ -91,6 +95,9 @@ type App struct {
 	// TokenUsageStore for persistent LLM token usage records
 	tokenUsageStore *mindxses.FileTokenUsageStore
 
+	// Per-model-family token estimators
+	tokenizers *TokenizerRegistry
+
 	// skillsPromptOverride, if set, overrides the default skills catalog prompt
 	// section in the agent system prompt. Set via SetSkillsPromptOverride().
 	skillsPromptOverride func(skills []*skill.Skill) string
@@ -156,6 +163,19 @@ func DefaultApp(mindxConfig *MindxConfig) (*App, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to load model costs: %w", err)
 	}
+	if err := costs.LoadPricingFile(settings.PricingFile()); err != nil {
+		logger.Warn("Failed to load price schedules", "file", settings.PricingFile(), "error", err)
+	}
+
+	tokenizerCfg, err := LoadTokenizerConfig(settings.ModelsFile())
+	if err != nil {
+		logger.Warn("Failed to load tokenizer config", "file", settings.ModelsFile(), "error", err)
+	}
+	calibration, err := mindxses.NewTokenCalibration(settings.TokenCalibrationFile())
+	if err != nil {
+		logger.Warn("Failed to load token calibration", "file", settings.TokenCalibrationFile(), "error", err)
+	}
+	tokenizers := NewTokenizerRegistry(tokenizerCfg, settings.TokenizersDir(), calibration, logger)
 
 	logger.Info("Loading rules", "file", settings.DataRulesFile())
 	rulesReg, err := rules.NewFileRuleRegistry(settings.DataRulesFile())
@@ -176,25 +196,25 @@ func DefaultApp(mindxConfig *MindxConfig) (*App, error) {
 	}
 
 	credStore := NewCredentialStore(settings.UserPreferences())
+	if err := InitStorage(credStore, mindxConfig.StorageEncrypted(), settings.StorageDir()); err != nil {
+		logger.Warn("Failed to load storage keys, encrypted data is unreadable", "error", err)
+	}
 
 	// Create embedder if configured for semantic memory support
-	var emb goragcore.Embedder
-	if mindxConfig != nil && mindxConfig.HasEmbedder() {
-		modelPath := mindxConfig.EmbedderModelPath(settings.UserPreferences())
-		var embErr error
-		emb, embErr = memory.NewEmbedderFromConfig(modelPath)
-		if embErr != nil {
-			logger.Warn("Failed to create embedder, memory disabled", "error", embErr, "model", modelPath)
-		}
+	emb, embID, embErr := NewEmbedder(mindxConfig, settings.UserPreferences(), credStore)
+	if embErr != nil {
+		logger.Warn("Failed to create embedder, memory disabled", "error", embErr)
+		emb, embID = nil, ""
 	}
 
 	// Create permission rule store (nil-safe: if mindxConfig is nil, returns no-op store)
 	permStore := NewMindxPermissionRuleStore(mindxConfig)
 
-	return &App{
+	app := &App{
 		settings:            settings,
 		mindxConfig:         mindxConfig,
 		credStore:           credStore,
+		storageDir:          settings.StorageDir(),
 		logger:              logger,
 		agents:              agentsReg,
 		models:              models,
@@ -206,10 +226,19 @@ func DefaultApp(mindxConfig *MindxConfig) (*App, error) {
 		sessDB:              sessDB,
 		runtimeCache:        make(map[string]*agents.Runtime),
 		embedder:            emb,
+		embedderID:          embID,
 		permissionRuleStore: permStore,
 		tokenUsageStore:     mindxses.NewFileTokenUsageStore(settings.DataDir()),
+		tokenizers:          tokenizers,
 		providerConfigs:     providers,
-	}, nil
+	}
+	if sessDB != nil {
+		sessDB.SetTokenEstimatorResolver(app.TokenEstimatorForAgent)
+		if err := sessDB.EnableSearch(settings.SessionSearchDir()); err != nil {
+			logger.Warn("Failed to open session search index", "error", err)
+		}
+	}
+	return app, nil
 }
 
 func resolveCurrentAgentName(cfg *MindxConfig, agents *config.AgentRegistry, logger logging.Logger) string {
@@ -240,6 +269,12 @@ func (a *App) Embedder() goragcore.Embedder {
 	return a.embedder
 }
 
+// EmbedderID identifies the configured embedder's vectors (see
+// memory.EmbedderConfig.ID); it is empty without an embedder.
+func (a *App) EmbedderID() string {
+	return a.embedderID
+}
+
 // SetGraphIndexer injects the knowledge graph indexer for knowledge base tools.
 func (a *App) SetGraphIndexer(gi *goragindexer.GraphIndexer) {
 	a.graphIndexer = gi
@@ -312,6 +347,12 @@ func (a *App) ResolveDefaultModel() *config.ModelConfig {
 	if modelName == "" {
 		return nil
 	}
+	return a.ResolveModel(modelName)
+}
+
+// ResolveModel 返回解析后的 modelName 模型配置，包含从 Provider 继承的参数
+// 和从 CredentialStore 解析的 API 密钥；模型不存在时返回 nil。
+func (a *App) ResolveModel(modelName string) *config.ModelConfig {
 	modelCfg := a.Models().Get(modelName)
 	if modelCfg == nil {
 		return nil
@@ -351,6 +392,10 @@ func (a *App) SetTestDir(tmpDir string) error {
 	if err != nil {
 		return err
 	}
+	sessDB.SetTokenEstimatorResolver(a.TokenEstimatorForAgent)
+	if err := sessDB.EnableSearch(filepath.Join(tmpDir, "session_search")); err != nil {
+		return err
+	}
 	a.sessDB = sessDB
 	return nil
 }
@@ -514,16 +559,29 @@ func (a *App) resolveModelName(agentModelName string) (string, *config.ModelConf
 
 // createRuntime builds an agents.Runtime for the given agent name with all registries and services.
 func (a *App) createRuntime(agentName string) (*agents.Runtime, error) {
-	a.logger.Info("createRuntime: start", "agent", agentName)
+	return a.createRuntimeWithModel(agentName, "")
+}
+
+// createRuntimeWithModel is createRuntime with an optional model override;
+// an empty modelName uses the configured model.
+func (a *App) createRuntimeWithModel(agentName, modelName string) (*agents.Runtime, error) {
+	a.logger.Info("createRuntime: start", "agent", agentName, "model_override", modelName)
 
 	agent := a.Agents().Get(agentName)
 	if agent == nil {
 		return nil, fmt.Errorf("agent %q not found", agentName)
 	}
 
-	_, modelCfg, err := a.resolveModelName(agent.Model)
-	if err != nil {
-		return nil, fmt.Errorf("agent %q: %w", agent.Name, err)
+	var modelCfg *config.ModelConfig
+	if modelName != "" {
+		if modelCfg = a.Models().Get(modelName); modelCfg == nil {
+			return nil, fmt.Errorf("agent %q: model %q not found", agent.Name, modelName)
+		}
+	} else {
+		var err error
+		if _, modelCfg, err = a.resolveModelName(agent.Model); err != nil {
+			return nil, fmt.Errorf("agent %q: %w", agent.Name, err)
+		}
 	}
 	resolvedModel := *modelCfg
 
@@ -658,10 +716,11 @@ func (a *App) createRuntime(agentName string) (*agents.Runtime, error) {
 		} else {
 			a.logger.Info("createRuntime: creating shared memory", "agent", agentName)
 			ltMem, ltErr := memory.NewRAGMemoryFromConfig(memory.MemoryConfig{
-				AgentName: "_shared",
-				MemoryDir: filepath.Join(a.settings.UserPreferences(), "memory"),
-				Embedder:  a.embedder,
-				Logger:    a.logger,
+				AgentName:  "_shared",
+				MemoryDir:  filepath.Join(a.settings.UserPreferences(), "memory"),
+				Embedder:   a.embedder,
+				EmbedderID: a.embedderID,
+				Logger:     a.logger,
 			})
 			if ltErr != nil {
 				a.logger.Warn("Failed to create long-term memory", "agent", agent.Name, "error", ltErr)
@@ -699,21 +758,33 @@ func (a *App) createRuntime(agentName string) (*agents.Runtime, error) {
 		}
 	}
 
+	// Wrap the built-in file and shell tools so they work in the project
+	// directory carried by the execution context (see pkg/execctx) rather
+	// than the daemon's process-wide CWD. Runtimes are shared between
+	// interactive and scheduled asks, so the directory must travel with
+	// each call instead of being set via os.Chdir.
+	for _, name := range mindxtools.WorkDirTools {
+		if t, ok := rt.ToolRegistry().Get(name); ok {
+			if err := rt.RegisterTool(mindxtools.WithWorkDir(t)); err != nil {
+				a.logger.Warn("createRuntime: 包装工具工作目录失败", "agent", agentName, "tool", name, "error", err)
+			}
+		}
+	}
+
 	// Register MemorySearch tool whenever long-term memory is available.
-	// This gives the LLM a tool to actively recall past conversation summaries.
+	// This gives the LLM a tool to actively recall past conversation summaries,
+	// limited to the memory scopes the agent's front-matter lets it read.
 	if mem := a.LongTermMemory(); mem != nil {
-		ms := tools.NewMemorySearch(mem)
-		if ms != nil {
-			if err := rt.RegisterTool(ms); err != nil {
-				a.logger.Warn("createRuntime: 注册 MemorySearch 失败", "agent", agentName, "error", err)
-			} else {
-				a.logger.Info("createRuntime: MemorySearch 注册成功", "agent", agentName)
-			}
+		ms := mindxtools.NewMemorySearch(mem, agentName, a.MemoryPolicy(agentName))
+		if err := rt.RegisterTool(ms); err != nil {
+			a.logger.Warn("createRuntime: 注册 MemorySearch 失败", "agent", agentName, "error", err)
+		} else {
+			a.logger.Info("createRuntime: MemorySearch 注册成功", "agent", agentName)
 		}
 	}
 
 	// Register knowledge base tools whenever the graph indexer is available.
-	// Each tool resolves projectDir at runtime from the session/cwd.
+	// Each tool resolves projectDir at runtime from the execution context/cwd.
 	if a.graphIndexer != nil {
 		qs := mindxtools.NewQuickSearch(a.graphIndexer)
 		if err := rt.RegisterTool(qs); err != nil {
@@ -935,10 +1006,11 @@ func (a *App) NewSessionFromMeta() *session.Session {
 
 	if a.embedder != nil {
 		sessRAG, ragErr := memory.NewRAGMemoryFromConfig(memory.MemoryConfig{
-			AgentName: agentName,
-			MemoryDir: filepath.Join(a.settings.UserPreferences(), "memory"),
-			Embedder:  a.embedder,
-			Logger:    a.logger,
+			AgentName:  agentName,
+			MemoryDir:  filepath.Join(a.settings.UserPreferences(), "memory"),
+			Embedder:   a.embedder,
+			EmbedderID: a.embedderID,
+			Logger:     a.logger,
 		})
 		if ragErr != nil {
 			a.logger.Warn("failed to create session RAG memory, compaction summaries will use in-memory fallback", "error", ragErr)
@@ -947,7 +1019,7 @@ func (a *App) NewSessionFromMeta() *session.Session {
 			if a.currentSessionMeta != nil {
 				projectDir = a.currentSessionMeta.ProjectDir
 			}
-			opts = append(opts, session.WithMemory(mindxses.NewRAGMemoryAdapter(sessRAG, agentName, projectDir)))
+			opts = append(opts, session.WithMemory(mindxses.NewRAGMemoryAdapter(sessRAG, agentName, projectDir, a.MemoryPolicy(agentName))))
 
 			agent := a.Agents().Get(agentName)
 			if agent != nil {
@@ -0,0 +1,347 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/DotNetAge/goharness/agents"
+	goharnesssession "github.com/DotNetAge/goharness/session"
+	mindxses "github.com/DotNetAge/mindx/pkg/session"
+)
+
+// BudgetScope selects which token usage counts toward a budget.
+type BudgetScope string
+
+const (
+	// BudgetScopeGlobal counts every record. It is the default.
+	BudgetScopeGlobal BudgetScope = "global"
+	// BudgetScopeProvider counts records of one provider (Target).
+	BudgetScopeProvider BudgetScope = "provider"
+	// BudgetScopeModel counts records of one model (Target).
+	BudgetScopeModel BudgetScope = "model"
+	// BudgetScopeAgent counts records of one agent (Target).
+	BudgetScopeAgent BudgetScope = "agent"
+	// BudgetScopeProject counts records of sessions whose project
+	// directory is Target.
+	BudgetScopeProject BudgetScope = "project"
+)
+
+// BudgetPeriod is the window a budget's limit applies to, in local time.
+type BudgetPeriod string
+
+const (
+	BudgetDaily   BudgetPeriod = "daily"
+	BudgetMonthly BudgetPeriod = "monthly"
+)
+
+// BudgetUnit is what a budget's limit is measured in.
+type BudgetUnit string
+
+const (
+	// BudgetUSD limits cost as computed from the CostRegistry at each
+	// record's timestamp. Models without a USD price cost nothing, as in
+	// token.usage reports.
+	BudgetUSD BudgetUnit = "usd"
+	// BudgetTokens limits total tokens.
+	BudgetTokens BudgetUnit = "tokens"
+)
+
+// BudgetAction is what happens once a budget is exceeded.
+type BudgetAction string
+
+const (
+	// BudgetWarn lets the request run and only notifies. It is the default.
+	BudgetWarn BudgetAction = "warn"
+	// BudgetDowngrade runs the request on DowngradeModel instead.
+	BudgetDowngrade BudgetAction = "downgrade"
+	// BudgetBlock refuses the request, and stops a running one at its
+	// next turn.
+	BudgetBlock BudgetAction = "block"
+)
+
+// Budget is a spending cap stored in mindx.json under "budgets".
+type Budget struct {
+	Name           string       `json:"name"`
+	Scope          BudgetScope  `json:"scope,omitempty"`
+	Target         string       `json:"target,omitempty"`
+	Period         BudgetPeriod `json:"period"`
+	Unit           BudgetUnit   `json:"unit"`
+	Limit          float64      `json:"limit"`
+	Action         BudgetAction `json:"action,omitempty"`
+	DowngradeModel string       `json:"downgrade_model,omitempty"`
+}
+
+// ScopeKind returns the effective scope, defaulting to BudgetScopeGlobal.
+func (b Budget) ScopeKind() BudgetScope {
+	if b.Scope == "" {
+		return BudgetScopeGlobal
+	}
+	return b.Scope
+}
+
+// ActionKind returns the effective action, defaulting to BudgetWarn.
+func (b Budget) ActionKind() BudgetAction {
+	if b.Action == "" {
+		return BudgetWarn
+	}
+	return b.Action
+}
+
+// Validate checks that the budget is complete.
+func (b Budget) Validate() error {
+	switch b.ScopeKind() {
+	case BudgetScopeGlobal:
+	case BudgetScopeProvider, BudgetScopeModel, BudgetScopeAgent, BudgetScopeProject:
+		if b.Target == "" {
+			return fmt.Errorf("budget %q: target is required for scope %q", b.Name, b.Scope)
+		}
+	default:
+		return fmt.Errorf("budget %q: unknown scope %q", b.Name, b.Scope)
+	}
+	if b.Period != BudgetDaily && b.Period != BudgetMonthly {
+		return fmt.Errorf("budget %q: period must be daily or monthly", b.Name)
+	}
+	if b.Unit != BudgetUSD && b.Unit != BudgetTokens {
+		return fmt.Errorf("budget %q: unit must be usd or tokens", b.Name)
+	}
+	if b.Limit <= 0 {
+		return fmt.Errorf("budget %q: limit must be positive", b.Name)
+	}
+	switch b.ActionKind() {
+	case BudgetWarn, BudgetBlock:
+	case BudgetDowngrade:
+		if b.DowngradeModel == "" {
+			return fmt.Errorf("budget %q: downgrade_model is required for downgrade", b.Name)
+		}
+	default:
+		return fmt.Errorf("budget %q: unknown action %q", b.Name, b.Action)
+	}
+	return nil
+}
+
+// PeriodStart returns the start of the budget period containing now.
+func (b Budget) PeriodStart(now time.Time) time.Time {
+	y, m, d := now.Date()
+	if b.Period == BudgetMonthly {
+		return time.Date(y, m, 1, 0, 0, 0, 0, now.Location())
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+}
+
+// BudgetSubject describes the request a budget check is made for.
+type BudgetSubject struct {
+	Provider   string
+	Model      string
+	Agent      string
+	ProjectDir string
+}
+
+// Applies reports whether requests for subject count toward the budget.
+func (b Budget) Applies(subject BudgetSubject) bool {
+	switch b.ScopeKind() {
+	case BudgetScopeProvider:
+		return subject.Provider == b.Target
+	case BudgetScopeModel:
+		return subject.Model == b.Target
+	case BudgetScopeAgent:
+		return subject.Agent == b.Target
+	case BudgetScopeProject:
+		return subject.ProjectDir != "" && sameDirectory(subject.ProjectDir, b.Target)
+	}
+	return true
+}
+
+// filter narrows a usage query to the budget's scope and period. Project
+// scope cannot be expressed as a filter and is applied per record.
+func (b Budget) filter(now time.Time) goharnesssession.TokenUsageFilter {
+	f := goharnesssession.TokenUsageFilter{Since: b.PeriodStart(now)}
+	switch b.ScopeKind() {
+	case BudgetScopeProvider:
+		f.ProviderName = b.Target
+	case BudgetScopeModel:
+		f.ModelName = b.Target
+	case BudgetScopeAgent:
+		f.AgentName = b.Target
+	}
+	return f
+}
+
+// Spent sums the records counting toward the budget, in its unit.
+// projectOf maps a session ID to its project directory and is only used
+// for project-scoped budgets.
+func (b Budget) Spent(records []goharnesssession.TokenUsageRecord, costs *CostRegistry, projectOf func(sessionID string) string) float64 {
+	var spent float64
+	for _, r := range records {
+		if b.ScopeKind() == BudgetScopeProject {
+			if dir := projectOf(r.SessionID); dir == "" || !sameDirectory(dir, b.Target) {
+				continue
+			}
+		}
+		if b.Unit == BudgetTokens {
+			spent += float64(r.TotalTokens)
+			continue
+		}
+		if costs == nil {
+			continue
+		}
+		if mc, ok := costs.PriceAt(r.ModelName, r.ProviderName, r.Timestamp); ok && mc.CurrencyCode() == "USD" {
+			spent += CalculateCost(mc, int64(r.PromptTokens), int64(r.CompletionTokens), int64(r.CachedTokens))
+		}
+	}
+	return spent
+}
+
+// BudgetStatus is the state of one budget at check time.
+type BudgetStatus struct {
+	Budget      Budget    `json:"budget"`
+	Spent       float64   `json:"spent"`
+	PeriodStart time.Time `json:"period_start"`
+	Exceeded    bool      `json:"exceeded"`
+}
+
+// BudgetDecision is the outcome of checking all budgets for a request.
+// Action is the strictest action of the exceeded budgets (block, then
+// downgrade, then warn), or empty when none is exceeded. Model is the
+// model to downgrade to when Action is BudgetDowngrade.
+type BudgetDecision struct {
+	Action   BudgetAction   `json:"action,omitempty"`
+	Model    string         `json:"model,omitempty"`
+	Exceeded []BudgetStatus `json:"exceeded,omitempty"`
+}
+
+// Blocked reports whether the request must not run.
+func (d *BudgetDecision) Blocked() bool {
+	return d != nil && d.Action == BudgetBlock
+}
+
+// decide folds the exceeded budgets into a decision. A downgrade to the
+// model the subject already uses cannot help and is reported as a warning.
+func decide(subject BudgetSubject, statuses []BudgetStatus) *BudgetDecision {
+	d := &BudgetDecision{}
+	rank := map[BudgetAction]int{"": 0, BudgetWarn: 1, BudgetDowngrade: 2, BudgetBlock: 3}
+	for _, st := range statuses {
+		if !st.Exceeded {
+			continue
+		}
+		d.Exceeded = append(d.Exceeded, st)
+		action := st.Budget.ActionKind()
+		if action == BudgetDowngrade && st.Budget.DowngradeModel == subject.Model {
+			action = BudgetWarn
+		}
+		if rank[action] > rank[d.Action] {
+			d.Action = action
+			d.Model = ""
+			if action == BudgetDowngrade {
+				d.Model = st.Budget.DowngradeModel
+			}
+		}
+	}
+	return d
+}
+
+// Budgets returns the budgets configured in mindx.json.
+func (a *App) Budgets() []Budget {
+	if a.mindxConfig == nil {
+		return nil
+	}
+	return a.mindxConfig.Budgets
+}
+
+// BudgetSubject resolves the model, provider and project directory a
+// request by agentName in sessionID (or projectDir, when given) runs with.
+func (a *App) BudgetSubject(agentName, sessionID, projectDir string) BudgetSubject {
+	subject := BudgetSubject{Agent: agentName, ProjectDir: projectDir}
+	if agent := a.Agents().Get(agentName); agent != nil {
+		if name, modelCfg, err := a.resolveModelName(agent.Model); err == nil {
+			subject.Model = name
+			subject.Provider = modelCfg.Provider
+		}
+	}
+	if subject.ProjectDir == "" && sessionID != "" && a.sessDB != nil {
+		if meta, err := a.sessDB.GetMeta(context.Background(), sessionID); err == nil && meta != nil {
+			subject.ProjectDir = meta.ProjectDir
+		}
+	}
+	return subject
+}
+
+// CheckBudgets evaluates every configured budget that applies to subject
+// against the usage recorded in its current period.
+func (a *App) CheckBudgets(ctx context.Context, subject BudgetSubject) (*BudgetDecision, error) {
+	budgets := a.Budgets()
+	if len(budgets) == 0 || a.tokenUsageStore == nil {
+		return &BudgetDecision{}, nil
+	}
+
+	now := time.Now()
+	projects := make(map[string]string)
+	projectOf := func(sessionID string) string {
+		if dir, ok := projects[sessionID]; ok {
+			return dir
+		}
+		dir := ""
+		if a.sessDB != nil {
+			if meta, err := a.sessDB.GetMeta(ctx, sessionID); err == nil && meta != nil {
+				dir = meta.ProjectDir
+			}
+		}
+		projects[sessionID] = dir
+		return dir
+	}
+
+	var statuses []BudgetStatus
+	for _, b := range budgets {
+		if b.Validate() != nil || !b.Applies(subject) {
+			continue
+		}
+		found, err := a.tokenUsageStore.QueryWithSource(ctx, b.filter(now))
+		if err != nil {
+			return nil, fmt.Errorf("budget %q: query usage: %w", b.Name, err)
+		}
+		records := make([]goharnesssession.TokenUsageRecord, 0, len(found))
+		for _, r := range found {
+			if r.Source != mindxses.UsageSourceImport {
+				records = append(records, r.TokenUsageRecord)
+			}
+		}
+		spent := b.Spent(records, a.costs, projectOf)
+		statuses = append(statuses, BudgetStatus{
+			Budget:      b,
+			Spent:       spent,
+			PeriodStart: b.PeriodStart(now),
+			Exceeded:    spent >= b.Limit,
+		})
+	}
+	return decide(subject, statuses), nil
+}
+
+// ResolveRuntimeForModel returns (or creates and caches) a Runtime for the
+// given agent that runs on modelName instead of the configured model. It
+// is used to downgrade requests once a budget is exceeded.
+func (a *App) ResolveRuntimeForModel(name, modelName string) (*agents.Runtime, error) {
+	if modelName == "" {
+		return a.ResolveRuntime(name)
+	}
+	if name == "" {
+		name = a.CurrentAgentName()
+	}
+	key := name + "@" + modelName
+
+	a.runtimeMu.RLock()
+	if cached, ok := a.runtimeCache[key]; ok {
+		a.runtimeMu.RUnlock()
+		return cached, nil
+	}
+	a.runtimeMu.RUnlock()
+
+	rt, err := a.createRuntimeWithModel(name, modelName)
+	if err != nil {
+		return nil, err
+	}
+
+	a.runtimeMu.Lock()
+	a.runtimeCache[key] = rt
+	a.runtimeMu.Unlock()
+	return rt, nil
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	goharnesssession "github.com/DotNetAge/goharness/session"
+)
+
+func TestBudget_Validate(t *testing.T) {
+	valid := Budget{Name: "daily", Period: BudgetDaily, Unit: BudgetUSD, Limit: 5}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	cases := map[string]Budget{
+		"missing target":   {Name: "m", Scope: BudgetScopeModel, Period: BudgetDaily, Unit: BudgetUSD, Limit: 1},
+		"bad period":       {Name: "p", Period: "weekly", Unit: BudgetUSD, Limit: 1},
+		"bad unit":         {Name: "u", Period: BudgetDaily, Unit: "eur", Limit: 1},
+		"zero limit":       {Name: "z", Period: BudgetDaily, Unit: BudgetTokens},
+		"downgrade w/o to": {Name: "d", Period: BudgetDaily, Unit: BudgetUSD, Limit: 1, Action: BudgetDowngrade},
+		"unknown action":   {Name: "a", Period: BudgetDaily, Unit: BudgetUSD, Limit: 1, Action: "panic"},
+		"unknown scope":    {Name: "s", Scope: "team", Target: "x", Period: BudgetDaily, Unit: BudgetUSD, Limit: 1},
+	}
+	for name, b := range cases {
+		if err := b.Validate(); err == nil {
+			t.Errorf("%s: Validate should fail", name)
+		}
+	}
+}
+
+func TestBudget_PeriodStart(t *testing.T) {
+	now := time.Date(2026, 10, 17, 15, 30, 0, 0, time.UTC)
+	daily := Budget{Period: BudgetDaily}
+	if got, want := daily.PeriodStart(now), time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("daily PeriodStart = %v, want %v", got, want)
+	}
+	monthly := Budget{Period: BudgetMonthly}
+	if got, want := monthly.PeriodStart(now), time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("monthly PeriodStart = %v, want %v", got, want)
+	}
+}
+
+func TestBudget_Spent(t *testing.T) {
+	costs := NewCostRegistry()
+	costs.Set("gpt", ModelCost{CostPer1MIn: 1, CostPer1MOut: 2})
+
+	records := []goharnesssession.TokenUsageRecord{
+		{SessionID: "a", ModelName: "gpt", PromptTokens: 1_000_000, CompletionTokens: 500_000, TotalTokens: 1_500_000},
+		{SessionID: "b", ModelName: "gpt", PromptTokens: 1_000_000, TotalTokens: 1_000_000},
+		{SessionID: "a", ModelName: "local", PromptTokens: 100, TotalTokens: 100},
+	}
+	projects := map[string]string{"a": "/work/proj", "b": "/work/other"}
+	projectOf := func(id string) string { return projects[id] }
+
+	usd := Budget{Unit: BudgetUSD}
+	if got := usd.Spent(records, costs, projectOf); got != 3 {
+		t.Errorf("usd Spent = %v, want 3 (unpriced models cost nothing)", got)
+	}
+	tokens := Budget{Unit: BudgetTokens, Scope: BudgetScopeProject, Target: "/work/proj"}
+	if got := tokens.Spent(records, costs, projectOf); got != 1_500_100 {
+		t.Errorf("project tokens Spent = %v, want 1500100", got)
+	}
+}
+
+func TestDecide(t *testing.T) {
+	subject := BudgetSubject{Model: "big"}
+	warn := BudgetStatus{Budget: Budget{Name: "w"}, Exceeded: true}
+	down := BudgetStatus{Budget: Budget{Name: "d", Action: BudgetDowngrade, DowngradeModel: "small"}, Exceeded: true}
+	block := BudgetStatus{Budget: Budget{Name: "b", Action: BudgetBlock}, Exceeded: true}
+	under := BudgetStatus{Budget: Budget{Name: "u", Action: BudgetBlock}}
+
+	if d := decide(subject, []BudgetStatus{under}); d.Action != "" || len(d.Exceeded) != 0 {
+		t.Errorf("nothing exceeded: got %+v", d)
+	}
+	if d := decide(subject, []BudgetStatus{warn, down}); d.Action != BudgetDowngrade || d.Model != "small" {
+		t.Errorf("warn+downgrade: got %+v", d)
+	}
+	if d := decide(subject, []BudgetStatus{down, block, warn}); !d.Blocked() || len(d.Exceeded) != 3 {
+		t.Errorf("block should win: got %+v", d)
+	}
+	if d := decide(BudgetSubject{Model: "small"}, []BudgetStatus{down}); d.Action != BudgetWarn {
+		t.Errorf("downgrade to current model should warn: got %+v", d)
+	}
+}
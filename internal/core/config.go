@@ -10,6 +10,7 @@ import (
 
 	"github.com/DotNetAge/goharness/rule"
 	"github.com/DotNetAge/mindx/internal/i18n"
+	"github.com/DotNetAge/mindx/pkg/memory"
 )
 
 type DaemonConfig struct {
@@ -66,6 +67,25 @@ type MindxConfig struct {
 	// overwritten on update.
 	AgentSkillChecksums map[string]string `json:"agent_skill_checksums,omitempty"`
 
+	// Budgets are daily or monthly spending caps checked before each
+	// interactive or scheduled request and after each of its turns.
+	Budgets []Budget `json:"budgets,omitempty"`
+
+	// SessionRetention decides which old sessions the daemon archives or
+	// deletes. Nil keeps every session.
+	SessionRetention *SessionRetention `json:"session_retention,omitempty"`
+
+	// MemoryConsolidation configures merging, decay and pruning of the
+	// shared memory. Nil never consolidates.
+	MemoryConsolidation *MemoryConsolidation `json:"memory_consolidation,omitempty"`
+
+	// Storage configures encryption of mindx data at rest.
+	Storage *StorageConfig `json:"storage,omitempty"`
+
+	// Embedder selects an embedder other than the local ONNX model named by
+	// EmbedderModel. Nil uses that model.
+	Embedder *EmbedderSettings `json:"embedder,omitempty"`
+
 	filePath string `json:"-"`
 }
 
@@ -79,8 +99,12 @@ func (c *MindxConfig) EmbedderModelPath(workspaceDir string) string {
 	return filepath.Join(workspaceDir, "data", "models", c.EmbedderModel)
 }
 
-// HasEmbedder 报告是否已配置 Embedder 模型（Memory 可用）。
+// HasEmbedder 报告是否已配置 Embedder（Memory 可用）：本地 ONNX 模型需
+// 配置 EmbedderModel，其余 provider 由 Embedder 配置。
 func (c *MindxConfig) HasEmbedder() bool {
+	if c.embedderProvider() != memory.EmbedderONNX {
+		return true
+	}
 	return c.EmbedderModel != ""
 }
 
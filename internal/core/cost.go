@@ -3,45 +3,176 @@ package core
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
+// DefaultCurrency is the currency of prices that do not name one.
+const DefaultCurrency = "USD"
+
 type ModelCost struct {
 	CostPer1MIn        float64 `yaml:"cost_per_1m_in"`
 	CostPer1MOut       float64 `yaml:"cost_per_1m_out"`
 	CostPer1MInCached  float64 `yaml:"cost_per_1m_in_cached"`
 	CostPer1MOutCached float64 `yaml:"cost_per_1m_out_cached"`
+	Currency           string  `yaml:"currency,omitempty"`
+}
+
+// CurrencyCode returns the price's upper-case ISO currency, defaulting to
+// DefaultCurrency.
+func (c ModelCost) CurrencyCode() string {
+	if c.Currency == "" {
+		return DefaultCurrency
+	}
+	return strings.ToUpper(c.Currency)
 }
 
+// PricePoint is a price that takes effect at EffectiveFrom and stays in
+// force until the next point for the same model and provider. An empty
+// Provider applies to the model under any provider.
+type PricePoint struct {
+	Model         string    `yaml:"model"`
+	Provider      string    `yaml:"provider,omitempty"`
+	EffectiveFrom time.Time `yaml:"effective_from"`
+	ModelCost     `yaml:",inline"`
+}
+
+type priceKey struct {
+	model    string
+	provider string
+}
+
+// CostRegistry resolves model prices. Each model has a base price (from
+// models.yml) plus an optional schedule of dated price points (from
+// pricing.yml), optionally per provider. The price of a token usage record
+// is the one in force at its timestamp, so a price change never rewrites
+// past reports; the base price applies before the first point.
 type CostRegistry struct {
-	costs map[string]ModelCost
+	mu        sync.RWMutex
+	costs     map[string]ModelCost
+	schedules map[priceKey][]PricePoint // sorted by EffectiveFrom
 }
 
 func NewCostRegistry() *CostRegistry {
 	return &CostRegistry{
-		costs: make(map[string]ModelCost),
+		costs:     make(map[string]ModelCost),
+		schedules: make(map[priceKey][]PricePoint),
 	}
 }
 
+// Set replaces the base price of a model.
 func (r *CostRegistry) Set(modelName string, cost ModelCost) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.costs[modelName] = cost
 }
 
+// AddPrice adds a dated price point, replacing any point for the same
+// model, provider and effective time.
+func (r *CostRegistry) AddPrice(p PricePoint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := priceKey{model: p.Model, provider: p.Provider}
+	points := r.schedules[key]
+	for i := range points {
+		if points[i].EffectiveFrom.Equal(p.EffectiveFrom) {
+			points[i] = p
+			return
+		}
+	}
+	points = append(points, p)
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].EffectiveFrom.Before(points[j].EffectiveFrom)
+	})
+	r.schedules[key] = points
+}
+
+// Prices returns every dated price point, ordered by model, provider and
+// effective time.
+func (r *CostRegistry) Prices() []PricePoint {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out []PricePoint
+	for _, points := range r.schedules {
+		out = append(out, points...)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Model != out[j].Model {
+			return out[i].Model < out[j].Model
+		}
+		if out[i].Provider != out[j].Provider {
+			return out[i].Provider < out[j].Provider
+		}
+		return out[i].EffectiveFrom.Before(out[j].EffectiveFrom)
+	})
+	return out
+}
+
+// Get returns the price of a model in force now, under any provider.
 func (r *CostRegistry) Get(modelName string) (ModelCost, bool) {
+	return r.PriceAt(modelName, "", time.Now())
+}
+
+// PriceAt returns the price of a model in force at the given time. A
+// provider-specific schedule takes precedence over the provider-agnostic
+// one, which takes precedence over the base price.
+func (r *CostRegistry) PriceAt(modelName, provider string, at time.Time) (ModelCost, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if provider != "" {
+		if c, ok := priceIn(r.schedules[priceKey{model: modelName, provider: provider}], at); ok {
+			return c, true
+		}
+	}
+	if c, ok := priceIn(r.schedules[priceKey{model: modelName}], at); ok {
+		return c, true
+	}
 	c, ok := r.costs[modelName]
 	return c, ok
 }
 
+// priceIn returns the last point of a sorted schedule effective at t.
+func priceIn(points []PricePoint, t time.Time) (ModelCost, bool) {
+	i := sort.Search(len(points), func(i int) bool {
+		return points[i].EffectiveFrom.After(t)
+	})
+	if i == 0 {
+		return ModelCost{}, false
+	}
+	return points[i-1].ModelCost, true
+}
+
 type NamedCost struct {
 	Name string
 	ModelCost
 }
 
+// List returns the current price of every model with a base price or a
+// price schedule.
 func (r *CostRegistry) List() []NamedCost {
-	result := make([]NamedCost, 0, len(r.costs))
-	for k, v := range r.costs {
-		result = append(result, NamedCost{Name: k, ModelCost: v})
+	r.mu.RLock()
+	names := make(map[string]bool, len(r.costs))
+	for k := range r.costs {
+		names[k] = true
+	}
+	for k := range r.schedules {
+		names[k.model] = true
+	}
+	r.mu.RUnlock()
+
+	result := make([]NamedCost, 0, len(names))
+	for name := range names {
+		if c, ok := r.Get(name); ok {
+			result = append(result, NamedCost{Name: name, ModelCost: c})
+		}
 	}
 	return result
 }
@@ -78,6 +209,59 @@ func LoadCostsFromModelsFile(path string) (*CostRegistry, error) {
 	return reg, nil
 }
 
+// pricingFile is the layout of pricing.yml:
+//
+//	prices:
+//	  - model: gpt-4o
+//	    provider: openai          # optional
+//	    effective_from: 2026-01-01
+//	    currency: USD             # optional, default USD
+//	    cost_per_1m_in: 2.5
+//	    cost_per_1m_out: 10
+type pricingFile struct {
+	Prices []PricePoint `yaml:"prices"`
+}
+
+// LoadPricingFile adds the price schedules in path to the registry. A
+// missing file is not an error.
+func (r *CostRegistry) LoadPricingFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read pricing file: %w", err)
+	}
+
+	var parsed pricingFile
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		return fmt.Errorf("failed to parse pricing file: %w", err)
+	}
+	for _, p := range parsed.Prices {
+		if p.Model == "" || p.EffectiveFrom.IsZero() {
+			return fmt.Errorf("pricing file: every price needs model and effective_from")
+		}
+		r.AddPrice(p)
+	}
+	return nil
+}
+
+// SavePricingFile writes every price schedule of the registry to path.
+func (r *CostRegistry) SavePricingFile(path string) error {
+	data, err := yaml.Marshal(pricingFile{Prices: r.Prices()})
+	if err != nil {
+		return fmt.Errorf("failed to marshal pricing file: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create settings dir: %w", err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write pricing file: %w", err)
+	}
+	return os.Rename(tmpPath, path)
+}
+
 // DefaultModelCost returns a moderate pricing for unknown models.
 func DefaultModelCost() ModelCost {
 	return ModelCost{
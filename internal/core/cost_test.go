@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestCostRegistry_SetGet(t *testing.T) {
@@ -140,3 +141,80 @@ models:
 		t.Error("free-model should not be registered (all costs are zero)")
 	}
 }
+
+func TestCostRegistry_PriceAt(t *testing.T) {
+	reg := NewCostRegistry()
+	reg.Set("gpt", ModelCost{CostPer1MIn: 10})
+
+	jan := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	jun := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	reg.AddPrice(PricePoint{Model: "gpt", EffectiveFrom: jun, ModelCost: ModelCost{CostPer1MIn: 5}})
+	reg.AddPrice(PricePoint{Model: "gpt", EffectiveFrom: jan, ModelCost: ModelCost{CostPer1MIn: 8}})
+	reg.AddPrice(PricePoint{Model: "gpt", Provider: "azure", EffectiveFrom: jan, ModelCost: ModelCost{CostPer1MIn: 60, Currency: "cny"}})
+
+	tests := []struct {
+		name     string
+		provider string
+		at       time.Time
+		want     float64
+	}{
+		{"before any schedule uses base price", "", jan.AddDate(0, 0, -1), 10},
+		{"first price point", "", jan.AddDate(0, 1, 0), 8},
+		{"later price point", "openai", jun.AddDate(0, 0, 1), 5},
+		{"provider-specific schedule wins", "azure", jun.AddDate(0, 0, 1), 60},
+	}
+	for _, tt := range tests {
+		got, ok := reg.PriceAt("gpt", tt.provider, tt.at)
+		if !ok || got.CostPer1MIn != tt.want {
+			t.Errorf("%s: PriceAt = %v (ok=%v), want %v", tt.name, got.CostPer1MIn, ok, tt.want)
+		}
+	}
+
+	if c, _ := reg.PriceAt("gpt", "azure", jun); c.CurrencyCode() != "CNY" {
+		t.Errorf("CurrencyCode = %q, want CNY", c.CurrencyCode())
+	}
+	if c, _ := reg.Get("gpt"); c.CostPer1MIn != 5 || c.CurrencyCode() != DefaultCurrency {
+		t.Errorf("Get should return the current price in USD, got %+v", c)
+	}
+}
+
+func TestCostRegistry_PricingFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pricing.yml")
+	yml := `
+prices:
+  - model: qwen
+    effective_from: 2026-03-01
+    currency: CNY
+    cost_per_1m_in: 2
+    cost_per_1m_out: 8
+  - model: qwen
+    provider: openrouter
+    effective_from: 2026-03-01T00:00:00Z
+    cost_per_1m_in: 0.3
+`
+	if err := os.WriteFile(path, []byte(yml), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	reg := NewCostRegistry()
+	if err := reg.LoadPricingFile(path); err != nil {
+		t.Fatalf("LoadPricingFile failed: %v", err)
+	}
+	if c, ok := reg.PriceAt("qwen", "openrouter", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)); !ok || c.CostPer1MIn != 0.3 {
+		t.Errorf("openrouter price = %+v (ok=%v), want 0.3", c, ok)
+	}
+	if _, ok := reg.PriceAt("qwen", "", time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)); ok {
+		t.Error("no price should be in force before the first effective date")
+	}
+
+	if err := reg.SavePricingFile(path); err != nil {
+		t.Fatalf("SavePricingFile failed: %v", err)
+	}
+	again := NewCostRegistry()
+	if err := again.LoadPricingFile(path); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if got := len(again.Prices()); got != 2 {
+		t.Errorf("round trip kept %d prices, want 2", got)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -40,6 +41,11 @@ func ResolveAPIKey(store CredentialStore, ref string) string {
 	return ""
 }
 
+// keychainItemNotFound is the exit status of `security` for a missing
+// item (errSecItemNotFound). A missing key reads as "", as it does from
+// the file store.
+const keychainItemNotFound = 44
+
 type macKeychainStore struct {
 	service string
 }
@@ -47,6 +53,10 @@ type macKeychainStore struct {
 func (s *macKeychainStore) Get(key string) (string, error) {
 	out, err := exec.Command("security", "find-generic-password",
 		"-s", s.service, "-a", key, "-w").Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == keychainItemNotFound {
+		return "", nil
+	}
 	if err != nil {
 		return "", fmt.Errorf("keychain get %q: %w", key, err)
 	}
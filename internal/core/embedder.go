@@ -0,0 +1,78 @@
+package core
+
+import (
+	"fmt"
+	"os"
+
+	goragcore "github.com/DotNetAge/gorag/v2/core"
+	"github.com/DotNetAge/mindx/pkg/memory"
+)
+
+// EmbedderSettings selects the embedder of memory and the knowledge base,
+// stored in mindx.json under "embedder". Without it the local ONNX model
+// named by embedder_model is used. Switching embedders leaves the stored
+// vectors behind; `mindx memory reembed` rebuilds them.
+type EmbedderSettings struct {
+	// Provider is "onnx" (the default), "openai" for an OpenAI-compatible
+	// /embeddings endpoint, or "hash" for the deterministic test embedder.
+	Provider string `json:"provider,omitempty"`
+	// Family is the ONNX model family of embedder_model. Defaults to
+	// "chinese-clip".
+	Family string `json:"family,omitempty"`
+	// BaseURL is the endpoint root, e.g. http://localhost:11434/v1.
+	BaseURL string `json:"base_url,omitempty"`
+	// Model is the model name sent to the endpoint.
+	Model string `json:"model,omitempty"`
+	// APIKey names the credential holding the endpoint's API key.
+	APIKey string `json:"api_key,omitempty"`
+	// Dim is the vector dimension. The endpoint is probed when it is zero;
+	// the hash embedder defaults to 256.
+	Dim int `json:"dim,omitempty"`
+}
+
+func (c *MindxConfig) embedderProvider() string {
+	if c.Embedder == nil || c.Embedder.Provider == "" {
+		return memory.EmbedderONNX
+	}
+	return c.Embedder.Provider
+}
+
+// EmbedderConfig returns the embedder configuration, with the API key
+// still a credential name.
+func (c *MindxConfig) EmbedderConfig(workspaceDir string) memory.EmbedderConfig {
+	cfg := memory.EmbedderConfig{
+		Provider:  c.embedderProvider(),
+		ModelPath: c.EmbedderModelPath(workspaceDir),
+	}
+	if e := c.Embedder; e != nil {
+		cfg.Family = e.Family
+		cfg.BaseURL = e.BaseURL
+		cfg.Model = e.Model
+		cfg.APIKey = e.APIKey
+		cfg.Dim = e.Dim
+	}
+	return cfg
+}
+
+// NewEmbedder creates the configured embedder, resolving its API key from
+// creds. A config without an embedder yields nil.
+func NewEmbedder(c *MindxConfig, workspaceDir string, creds CredentialStore) (goragcore.Embedder, string, error) {
+	if c == nil || !c.HasEmbedder() {
+		return nil, "", nil
+	}
+	cfg := c.EmbedderConfig(workspaceDir)
+	if cfg.Provider == memory.EmbedderONNX {
+		if _, err := os.Stat(cfg.ModelPath); err != nil {
+			return nil, "", fmt.Errorf("embedder model file not found at %s", cfg.ModelPath)
+		}
+	}
+	id := cfg.ID()
+	if cfg.APIKey != "" {
+		cfg.APIKey = ResolveAPIKey(creds, cfg.APIKey)
+	}
+	emb, err := memory.NewEmbedder(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	return emb, id, nil
+}
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DotNetAge/mindx/pkg/memory"
+)
+
+func TestMindxConfigHasEmbedder(t *testing.T) {
+	if (&MindxConfig{}).HasEmbedder() {
+		t.Error("empty config has no embedder")
+	}
+	if !(&MindxConfig{EmbedderModel: "model_q4.onnx"}).HasEmbedder() {
+		t.Error("embedder_model configures the ONNX embedder")
+	}
+	if (&MindxConfig{Embedder: &EmbedderSettings{Family: "chinese-clip"}}).HasEmbedder() {
+		t.Error("the ONNX provider needs embedder_model")
+	}
+	if !(&MindxConfig{Embedder: &EmbedderSettings{Provider: "hash"}}).HasEmbedder() {
+		t.Error("the hash provider needs no model")
+	}
+}
+
+func TestMindxConfigEmbedderConfig(t *testing.T) {
+	c := &MindxConfig{
+		EmbedderModel: "model_q4.onnx",
+		Embedder:      &EmbedderSettings{Provider: "openai", BaseURL: "http://localhost:11434/v1", Model: "bge-m3", APIKey: "embedder", Dim: 1024},
+	}
+	cfg := c.EmbedderConfig("/ws")
+	if cfg.Provider != memory.EmbedderOpenAI || cfg.BaseURL != "http://localhost:11434/v1" || cfg.Model != "bge-m3" || cfg.APIKey != "embedder" || cfg.Dim != 1024 {
+		t.Errorf("EmbedderConfig = %+v", cfg)
+	}
+	if cfg.ModelPath != filepath.Join("/ws", "data", "models", "model_q4.onnx") {
+		t.Errorf("ModelPath = %q", cfg.ModelPath)
+	}
+	if got := (&MindxConfig{EmbedderModel: "m.onnx"}).EmbedderConfig("/ws").Provider; got != memory.EmbedderONNX {
+		t.Errorf("default provider = %q, want onnx", got)
+	}
+}
+
+func TestNewEmbedder(t *testing.T) {
+	emb, id, err := NewEmbedder(&MindxConfig{}, t.TempDir(), memCredentialStore{})
+	if emb != nil || id != "" || err != nil {
+		t.Fatalf("no embedder configured: %v, %q, %v", emb, id, err)
+	}
+
+	c := &MindxConfig{Embedder: &EmbedderSettings{Provider: "hash", Dim: 64}}
+	emb, id, err = NewEmbedder(c, t.TempDir(), memCredentialStore{})
+	if err != nil || emb.Dim() != 64 || id != c.EmbedderConfig("").ID() {
+		t.Fatalf("hash embedder: %v, %q, %v", emb, id, err)
+	}
+
+	_, _, err = NewEmbedder(&MindxConfig{EmbedderModel: "missing.onnx"}, t.TempDir(), memCredentialStore{})
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("missing model file: err = %v", err)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+
+	"github.com/DotNetAge/mindx/pkg/storage"
 )
 
 type FileVersionStore struct{}
@@ -48,7 +50,7 @@ func (s *FileVersionStore) GetInitial(sessionDir, filePath string) (string, erro
 		return "", fmt.Errorf("no versions found")
 	}
 	sort.Strings(entries)
-	data, err := os.ReadFile(entries[0])
+	data, err := storage.ReadFile(entries[0])
 	return string(data), err
 }
 
@@ -59,7 +61,7 @@ func (s *FileVersionStore) GetLatest(sessionDir, filePath string) (string, error
 		return "", fmt.Errorf("no versions found")
 	}
 	sort.Strings(entries)
-	data, err := os.ReadFile(entries[len(entries)-1])
+	data, err := storage.ReadFile(entries[len(entries)-1])
 	return string(data), err
 }
 
@@ -79,7 +81,7 @@ func (s *FileVersionStore) ListFiles(sessionDir string) ([]string, error) {
 			continue
 		}
 		pathFile := filepath.Join(fd, e.Name(), ".path")
-		data, err := os.ReadFile(pathFile)
+		data, err := storage.ReadFile(pathFile)
 		if err != nil {
 			continue
 		}
@@ -105,16 +107,53 @@ func (s *FileVersionStore) CountChanges(sessionDir, filePath string) (additions,
 	return add, del, nil
 }
 
+// Reseal rewrites the recorded versions of a session, and their .path
+// files, in the current storage form and reports how many files changed.
+func (s *FileVersionStore) Reseal(sessionDir string) (int, error) {
+	var paths []string
+	err := filepath.WalkDir(s.filesDir(sessionDir), func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.Type().IsRegular() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, path := range paths {
+		changed, err := storage.ResealFile(path)
+		if err != nil {
+			return n, err
+		}
+		if changed {
+			n++
+		}
+	}
+	return n, nil
+}
+
 func copyFile(src, dst string) error {
 	data, err := os.ReadFile(src)
 	if err != nil {
 		return err
 	}
+	if data, err = storage.Seal(data); err != nil {
+		return err
+	}
 	if err := os.WriteFile(dst, data, 0644); err != nil {
 		return err
 	}
 	pathFile := filepath.Join(filepath.Dir(dst), ".path")
-	_ = os.WriteFile(pathFile, []byte(src), 0644)
+	if name, err := storage.Seal([]byte(src)); err == nil {
+		_ = os.WriteFile(pathFile, name, 0644)
+	}
 	return nil
 }
 
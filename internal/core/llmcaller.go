@@ -36,6 +36,8 @@ type LLMCaller interface {
 	// Call 执行 LLM 调用，messages 为连续的用户消息序列。
 	// 返回 LLMResult 或在出错时返回 error。
 	Call(messages ...string) (LLMResult, error)
+	// CallContext 同 Call，ctx 取消时中止调用。
+	CallContext(ctx context.Context, messages ...string) (LLMResult, error)
 }
 
 // llmCaller 是 Executable 的默认实现，封装了一次 LLM 调用的完整配置。
@@ -65,7 +67,12 @@ func NewCaller(cfg *harness.ModelConfig, systemPrompt string) LLMCaller {
 //
 // 返回的 LLMResult 包含响应文本和 Token 使用统计（如果 LLM 返回了 usage 数据）。
 func (b *llmCaller) Call(messages ...string) (LLMResult, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 4*time.Minute)
+	return b.CallContext(context.Background(), messages...)
+}
+
+// CallContext 执行一次 LLM 非流式调用，超时在 ctx 之上另加 4 分钟上限。
+func (b *llmCaller) CallContext(ctx context.Context, messages ...string) (LLMResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 4*time.Minute)
 	defer cancel()
 
 	chatMsgs := []chatcore.Message{
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/DotNetAge/mindx/pkg/memory"
+)
+
+// MemoryConsolidation is the memory consolidation policy stored in
+// mindx.json under "memory_consolidation". When enabled the daemon
+// periodically merges near-duplicate memories through the default model
+// and archives memories whose decayed score has fallen below PruneBelow.
+// The decay settings also rank memory search results.
+type MemoryConsolidation struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// IntervalHours is how often the daemon consolidates. Defaults to 24.
+	IntervalHours int `json:"interval_hours,omitempty"`
+	// Similarity is the least search score at which two memories are
+	// merged. Defaults to 0.9.
+	Similarity float64 `json:"similarity,omitempty"`
+	// MaxCluster caps how many memories merge into one. Defaults to 8.
+	MaxCluster int `json:"max_cluster,omitempty"`
+	// HalfLifeDays is the age at which a memory's decayed score halves.
+	// Defaults to 30; a negative value turns decay off.
+	HalfLifeDays int `json:"half_life_days,omitempty"`
+	// DecayWeight is the share of the decayed score in search ranking.
+	// Defaults to 0.2.
+	DecayWeight *float64 `json:"decay_weight,omitempty"`
+	// PruneBelow archives memories whose decayed score is below it. Zero
+	// prunes nothing.
+	PruneBelow float64 `json:"prune_below,omitempty"`
+}
+
+// Interval returns how often the daemon consolidates.
+func (c MemoryConsolidation) Interval() time.Duration {
+	if c.IntervalHours <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(c.IntervalHours) * time.Hour
+}
+
+// Decay returns the decay memories are ranked and pruned by.
+func (c MemoryConsolidation) Decay() memory.Decay {
+	d := memory.DefaultDecay
+	switch {
+	case c.HalfLifeDays < 0:
+		d.HalfLife = 0
+	case c.HalfLifeDays > 0:
+		d.HalfLife = time.Duration(c.HalfLifeDays) * 24 * time.Hour
+	}
+	if c.DecayWeight != nil {
+		d.Weight = *c.DecayWeight
+	}
+	return d
+}
+
+// Options returns the options of a consolidation run under c.
+func (c MemoryConsolidation) Options() memory.ConsolidateOptions {
+	return memory.ConsolidateOptions{
+		Similarity: c.Similarity,
+		MaxCluster: c.MaxCluster,
+		PruneBelow: c.PruneBelow,
+	}
+}
+
+// Validate reports whether the policy is well-formed.
+func (c MemoryConsolidation) Validate() error {
+	if c.Similarity < 0 || c.Similarity > 1 {
+		return fmt.Errorf("memory consolidation: similarity must be within [0, 1]")
+	}
+	if c.DecayWeight != nil && (*c.DecayWeight < 0 || *c.DecayWeight > 1) {
+		return fmt.Errorf("memory consolidation: decay_weight must be within [0, 1]")
+	}
+	if c.PruneBelow < 0 || c.MaxCluster < 0 {
+		return fmt.Errorf("memory consolidation: limits must not be negative")
+	}
+	return nil
+}
+
+// MemoryConsolidation returns the configured consolidation policy; the
+// zero policy never consolidates and ranks with memory.DefaultDecay.
+func (a *App) MemoryConsolidation() MemoryConsolidation {
+	if a.mindxConfig == nil || a.mindxConfig.MemoryConsolidation == nil {
+		return MemoryConsolidation{}
+	}
+	return *a.mindxConfig.MemoryConsolidation
+}
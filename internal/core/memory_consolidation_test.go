@@ -0,0 +1,38 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DotNetAge/mindx/pkg/memory"
+)
+
+func TestMemoryConsolidationDecay(t *testing.T) {
+	if got := (MemoryConsolidation{}).Decay(); got != memory.DefaultDecay {
+		t.Errorf("default decay = %+v, want %+v", got, memory.DefaultDecay)
+	}
+	weight := 0.5
+	got := MemoryConsolidation{HalfLifeDays: 7, DecayWeight: &weight}.Decay()
+	if got.HalfLife != 7*24*time.Hour || got.Weight != 0.5 {
+		t.Errorf("decay = %+v", got)
+	}
+	if got := (MemoryConsolidation{HalfLifeDays: -1}).Decay(); got.Enabled() {
+		t.Errorf("negative half-life should turn decay off: %+v", got)
+	}
+}
+
+func TestMemoryConsolidationValidate(t *testing.T) {
+	if err := (MemoryConsolidation{Enabled: true, Similarity: 0.85, PruneBelow: 0.05}).Validate(); err != nil {
+		t.Errorf("valid policy rejected: %v", err)
+	}
+	weight := 2.0
+	for _, c := range []MemoryConsolidation{
+		{Similarity: 1.5},
+		{PruneBelow: -1},
+		{DecayWeight: &weight},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("%+v accepted", c)
+		}
+	}
+}
@@ -4,3 +4,7 @@ package core
 // 使用 fmt.Sprintf(PROMPT_TRANSLATE, "中文") 可指定目标语言。
 const PROMPT_TRANSLATE = `You are a professional translator. Translate the following content into %s accurately and naturally. Preserve the original formatting, code blocks, and special characters. Only output the translated result, no explanations or notes.`
 const PROMPT_OPTIMIZE_USERINPUT = `You are a professional input optimizer. Expand, complete, and refine the following user input by removing noise, filling in missing context, and clarifying ambiguous terms — making it easier for an LLM to understand and respond accurately. Only output the optimized result, no explanations or notes.`
+
+// PROMPT_MEMORY_CONSOLIDATE 是记忆整理提示词，用于把多条相近的记忆合并为一条。
+// 输出首行为标题，空一行后为合并后的记忆正文。
+const PROMPT_MEMORY_CONSOLIDATE = `You merge near-duplicate memories of an AI assistant into one. The input is a numbered list of memories, each with a title and body. Write a single memory that keeps every distinct fact, decision, preference and identifier (paths, names, versions, commands) from all of them, drops repetition, and prefers the newest memory where they conflict. Use the language of the memories. Output the title on the first line, then a blank line, then the merged memory. No explanations or notes.`
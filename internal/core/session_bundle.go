@@ -0,0 +1,569 @@
+package core
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	goharnesssession "github.com/DotNetAge/goharness/session"
+	mindxses "github.com/DotNetAge/mindx/pkg/session"
+	"github.com/DotNetAge/mindx/pkg/storage"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// Session export formats.
+const (
+	// SessionFormatBundle is a self-contained gzipped tarball carrying
+	// everything needed to recreate the session on another machine.
+	SessionFormatBundle = "bundle"
+	// SessionFormatMarkdown is a human-readable transcript.
+	SessionFormatMarkdown = "markdown"
+	// SessionFormatOpenAI is one line of OpenAI chat fine-tuning JSONL.
+	SessionFormatOpenAI = "openai"
+)
+
+// SessionBundleExt is the file extension of a session bundle.
+const SessionBundleExt = ".mindx-session"
+
+// A session bundle is a tar.gz with this layout:
+//
+//	manifest.json       sessionManifest
+//	meta.json           the session's SessionInfo, compaction cursor included
+//	messages.jsonl      every message, one JSON object per line
+//	token_usage.jsonl   the session's token usage records, with source
+//	modify_files.yml    tracked modified files
+//	backup/<name>.bak   pre-modification backups of tracked files
+//	files/<hash>/v<N>   FileVersionStore versions, with files/<hash>/.path
+const (
+	bundleFormatName = "mindx-session"
+	bundleVersion    = 1
+
+	bundleManifest   = "manifest.json"
+	bundleMeta       = "meta.json"
+	bundleMessages   = "messages.jsonl"
+	bundleUsage      = "token_usage.jsonl"
+	bundleModify     = "modify_files.yml"
+	bundleBackupDir  = "backup"
+	bundleVersionDir = "files"
+
+	// maxBundleSize bounds the uncompressed size of an imported bundle.
+	maxBundleSize = 1 << 30
+)
+
+type sessionManifest struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	SessionID  string    `json:"session_id"`
+	AgentName  string    `json:"agent_name"`
+	ProjectDir string    `json:"project_dir,omitempty"`
+	ExportedAt time.Time `json:"exported_at"`
+	Messages   int       `json:"messages"`
+}
+
+// SessionBundler exports sessions to and imports them from portable
+// formats. It reads and writes through the session, token usage and file
+// version stores.
+type SessionBundler struct {
+	Sessions *mindxses.FileSessionStore
+	Usage    *mindxses.FileTokenUsageStore
+	Versions *FileVersionStore
+}
+
+// SessionBundler returns a bundler over the app's stores.
+func (a *App) SessionBundler() *SessionBundler {
+	return &SessionBundler{Sessions: a.sessDB, Usage: a.tokenUsageStore, Versions: a.versions}
+}
+
+// ExportFileName is the conventional file name for an export of
+// sessionID in format.
+func ExportFileName(sessionID, format string) string {
+	switch format {
+	case SessionFormatMarkdown:
+		return sessionID + ".md"
+	case SessionFormatOpenAI:
+		return sessionID + ".jsonl"
+	default:
+		return sessionID + SessionBundleExt
+	}
+}
+
+// Export writes sessionID to w in format.
+func (b *SessionBundler) Export(ctx context.Context, sessionID, format string, w io.Writer) error {
+	if b.Sessions == nil {
+		return fmt.Errorf("session store not available")
+	}
+	meta, err := b.Sessions.GetMeta(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("session %q: %w", sessionID, err)
+	}
+	msgs, err := b.Sessions.Get(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("read session %q: %w", sessionID, err)
+	}
+
+	switch format {
+	case "", SessionFormatBundle:
+		return b.writeBundle(ctx, meta, msgs, w)
+	case SessionFormatMarkdown:
+		return writeMarkdownTranscript(meta, msgs, w)
+	case SessionFormatOpenAI:
+		return writeOpenAIChat(msgs, w)
+	default:
+		return fmt.Errorf("unknown export format %q (want %s, %s or %s)", format, SessionFormatBundle, SessionFormatMarkdown, SessionFormatOpenAI)
+	}
+}
+
+func (b *SessionBundler) writeBundle(ctx context.Context, meta *goharnesssession.SessionInfo, msgs []goharnesssession.Message, w io.Writer) error {
+	dir, err := b.Sessions.ResolveSessionDir(meta.SessionID)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	add := func(name string, data []byte) error {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: now}); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	manifest, _ := json.MarshalIndent(sessionManifest{
+		Format:     bundleFormatName,
+		Version:    bundleVersion,
+		SessionID:  meta.SessionID,
+		AgentName:  meta.AgentName,
+		ProjectDir: meta.ProjectDir,
+		ExportedAt: now,
+		Messages:   len(msgs),
+	}, "", "  ")
+	if err := add(bundleManifest, manifest); err != nil {
+		return fmt.Errorf("write bundle: %w", err)
+	}
+	metaData, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal session meta: %w", err)
+	}
+	if err := add(bundleMeta, metaData); err != nil {
+		return fmt.Errorf("write bundle: %w", err)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, m := range msgs {
+		if err := enc.Encode(m); err != nil {
+			return fmt.Errorf("marshal message: %w", err)
+		}
+	}
+	if err := add(bundleMessages, buf.Bytes()); err != nil {
+		return fmt.Errorf("write bundle: %w", err)
+	}
+
+	if b.Usage != nil {
+		records, err := b.Usage.QueryWithSource(ctx, goharnesssession.TokenUsageFilter{SessionID: meta.SessionID})
+		if err != nil {
+			return fmt.Errorf("query token usage: %w", err)
+		}
+		buf.Reset()
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return fmt.Errorf("marshal token usage: %w", err)
+			}
+		}
+		if err := add(bundleUsage, buf.Bytes()); err != nil {
+			return fmt.Errorf("write bundle: %w", err)
+		}
+	}
+
+	// Exported bundles are plaintext: files sealed at rest are opened here.
+	if data, err := storage.ReadFile(filepath.Join(dir, bundleModify)); err == nil {
+		if err := add(bundleModify, data); err != nil {
+			return fmt.Errorf("write bundle: %w", err)
+		}
+	}
+	for _, sub := range []string{bundleBackupDir, bundleVersionDir} {
+		root := filepath.Join(dir, sub)
+		err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+			if err != nil || !info.Mode().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return nil
+			}
+			data, err := storage.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			return add(filepath.ToSlash(rel), data)
+		})
+		if err != nil {
+			return fmt.Errorf("write bundle: %w", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("write bundle: %w", err)
+	}
+	return gz.Close()
+}
+
+// BundleImportOptions adjust where an imported session lands. Empty fields
+// keep the bundle's values.
+type BundleImportOptions struct {
+	Agent string
+	// ProjectDir relocates the session. Tracked file paths under the
+	// original project directory are rewritten to lie under this one.
+	ProjectDir string
+}
+
+// BundleImportResult describes an imported session.
+type BundleImportResult struct {
+	SessionID         string `json:"session_id"`
+	OriginalSessionID string `json:"original_session_id"`
+	AgentName         string `json:"agent_name"`
+	ProjectDir        string `json:"project_dir"`
+	Messages          int    `json:"messages"`
+	TrackedFiles      int    `json:"tracked_files"`
+	UsageRecords      int    `json:"usage_records"`
+}
+
+// Import recreates the session in a bundle read from r as a new session.
+// Its token usage is recorded with source "import", which budgets do not
+// count: it was spent where the session was exported from.
+func (b *SessionBundler) Import(ctx context.Context, r io.Reader, opts BundleImportOptions) (*BundleImportResult, error) {
+	if b.Sessions == nil {
+		return nil, fmt.Errorf("session store not available")
+	}
+	// Retention archives are sealed at rest while encryption is on.
+	data, err := io.ReadAll(io.LimitReader(r, maxBundleSize))
+	if err != nil {
+		return nil, fmt.Errorf("read session bundle: %w", err)
+	}
+	if data, err = storage.Open(data); err != nil {
+		return nil, fmt.Errorf("open session bundle: %w", err)
+	}
+	entries, err := readBundle(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest sessionManifest
+	if err := json.Unmarshal(entries[bundleManifest], &manifest); err != nil || manifest.Format != bundleFormatName {
+		return nil, fmt.Errorf("not a session bundle: missing or invalid %s", bundleManifest)
+	}
+	if manifest.Version > bundleVersion {
+		return nil, fmt.Errorf("session bundle version %d is newer than supported (%d)", manifest.Version, bundleVersion)
+	}
+	var meta goharnesssession.SessionInfo
+	if err := json.Unmarshal(entries[bundleMeta], &meta); err != nil {
+		return nil, fmt.Errorf("parse bundle %s: %w", bundleMeta, err)
+	}
+	msgs, err := decodeJSONL[goharnesssession.Message](entries[bundleMessages])
+	if err != nil {
+		return nil, fmt.Errorf("parse bundle %s: %w", bundleMessages, err)
+	}
+	usage, err := decodeJSONL[mindxses.TokenUsageRecordWithSource](entries[bundleUsage])
+	if err != nil {
+		return nil, fmt.Errorf("parse bundle %s: %w", bundleUsage, err)
+	}
+
+	agent := firstNonEmpty(opts.Agent, meta.AgentName, manifest.AgentName)
+	if agent == "" {
+		return nil, fmt.Errorf("session bundle names no agent; pass one explicitly")
+	}
+	oldProject := meta.ProjectDir
+	projectDir := firstNonEmpty(opts.ProjectDir, oldProject)
+	relocate := func(p string) string {
+		if oldProject == "" || projectDir == oldProject {
+			return p
+		}
+		rel, err := filepath.Rel(oldProject, p)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return p
+		}
+		return filepath.Join(projectDir, rel)
+	}
+
+	var sessOpts []goharnesssession.SessionOption
+	if projectDir != "" {
+		sessOpts = append(sessOpts, goharnesssession.WithProjectDirOption(projectDir))
+	}
+	info, err := b.Sessions.Create(ctx, agent, sessOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("create session: %w", err)
+	}
+	dir := info.SessionDir
+	ok := false
+	defer func() {
+		if !ok {
+			_ = b.Sessions.DeleteSession(context.Background(), info.SessionID)
+		}
+	}()
+
+	cursor := meta.Cursor
+	if cursor > len(msgs) {
+		cursor = 0
+	}
+	if err := b.Sessions.UpdateMessages(ctx, info.SessionID, cursor, msgs); err != nil {
+		return nil, fmt.Errorf("write messages: %w", err)
+	}
+	newMeta, err := b.Sessions.GetMeta(ctx, info.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	newMeta.Title = meta.Title
+	newMeta.Sponsor = meta.Sponsor
+	if !meta.CreatedAt.IsZero() {
+		newMeta.CreatedAt = meta.CreatedAt
+	}
+	newMeta.LastActivityAt = time.Now()
+	newMeta.MessageCount = len(msgs)
+	if err := mindxses.SaveSessionMeta(dir, newMeta); err != nil {
+		return nil, fmt.Errorf("save session meta: %w", err)
+	}
+
+	result := &BundleImportResult{
+		SessionID:         info.SessionID,
+		OriginalSessionID: meta.SessionID,
+		AgentName:         agent,
+		ProjectDir:        projectDir,
+		Messages:          len(msgs),
+	}
+	if result.TrackedFiles, err = b.restoreTrackedFiles(info.SessionID, dir, entries, relocate); err != nil {
+		return nil, err
+	}
+
+	if b.Usage != nil {
+		for _, r := range usage {
+			// A fresh ID keeps the copy apart from the original when the
+			// bundle is imported back where it was exported.
+			rec := r.TokenUsageRecord
+			rec.ID = uuid.New().String()
+			rec.SessionID = info.SessionID
+			if rec.AgentName == meta.AgentName {
+				rec.AgentName = agent
+			}
+			if err := b.Usage.AppendWithSource(ctx, rec, string(mindxses.UsageSourceImport)); err != nil {
+				return nil, fmt.Errorf("record token usage: %w", err)
+			}
+			result.UsageRecords++
+		}
+	}
+	ok = true
+	return result, nil
+}
+
+// restoreTrackedFiles writes the tracked file list, backups and file
+// versions of a bundle into the session directory, relocating file paths.
+// It returns the number of tracked files.
+func (b *SessionBundler) restoreTrackedFiles(sessionID, dir string, entries map[string][]byte, relocate func(string) string) (int, error) {
+	var tracked []string
+	if data, ok := entries[bundleModify]; ok {
+		if err := yaml.Unmarshal(data, &tracked); err != nil {
+			return 0, fmt.Errorf("parse bundle %s: %w", bundleModify, err)
+		}
+		for i := range tracked {
+			tracked[i] = relocate(tracked[i])
+		}
+		if err := b.Sessions.SaveModifyFiles(sessionID, tracked); err != nil {
+			return 0, fmt.Errorf("save tracked files: %w", err)
+		}
+	}
+
+	versions := make(map[string]map[string][]byte) // hash dir -> file -> data
+	for name, data := range entries {
+		parts := strings.Split(name, "/")
+		switch {
+		case len(parts) == 2 && parts[0] == bundleBackupDir:
+			if err := writeBundleFile(filepath.Join(dir, bundleBackupDir, parts[1]), data); err != nil {
+				return 0, err
+			}
+		case len(parts) == 3 && parts[0] == bundleVersionDir:
+			if versions[parts[1]] == nil {
+				versions[parts[1]] = make(map[string][]byte)
+			}
+			versions[parts[1]][parts[2]] = data
+		}
+	}
+	for hash, files := range versions {
+		target := filepath.Join(dir, bundleVersionDir, hash)
+		if p, ok := files[".path"]; ok && b.Versions != nil {
+			newPath := relocate(string(p))
+			files[".path"] = []byte(newPath)
+			target = b.Versions.fileDir(dir, newPath)
+		}
+		for name, data := range files {
+			sealed, err := storage.Seal(data)
+			if err != nil {
+				return 0, fmt.Errorf("restore %s: %w", name, err)
+			}
+			if err := writeBundleFile(filepath.Join(target, name), sealed); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(tracked), nil
+}
+
+func writeBundleFile(p string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return fmt.Errorf("restore %s: %w", filepath.Base(p), err)
+	}
+	if err := os.WriteFile(p, data, 0644); err != nil {
+		return fmt.Errorf("restore %s: %w", filepath.Base(p), err)
+	}
+	return nil
+}
+
+// readBundle reads every regular file of a bundle into memory, keyed by
+// its slash-separated path. Entry names that are absolute or climb out of
+// the bundle are rejected.
+func readBundle(r io.Reader) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a session bundle: %w", err)
+	}
+	defer func() { _ = gz.Close() }()
+	tr := tar.NewReader(io.LimitReader(gz, maxBundleSize))
+
+	entries := make(map[string][]byte)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read session bundle: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(hdr.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") || strings.Contains(name, "\\") {
+			return nil, fmt.Errorf("session bundle has unsafe entry %q", hdr.Name)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("read session bundle: %w", err)
+		}
+		entries[name] = data
+	}
+}
+
+func decodeJSONL[T any](data []byte) ([]T, error) {
+	var out []T
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var v T
+		if err := dec.Decode(&v); err != nil {
+			if errors.Is(err, io.EOF) {
+				return out, nil
+			}
+			return nil, err
+		}
+		out = append(out, v)
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// writeMarkdownTranscript renders a session as Markdown. Each message is a
+// section headed by its index, the number session.fork takes.
+func writeMarkdownTranscript(meta *goharnesssession.SessionInfo, msgs []goharnesssession.Message, w io.Writer) error {
+	var b strings.Builder
+	title := meta.Title
+	if title == "" {
+		title = meta.SessionID
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	fmt.Fprintf(&b, "- Session: `%s`\n", meta.SessionID)
+	fmt.Fprintf(&b, "- Agent: %s\n", meta.AgentName)
+	if meta.ProjectDir != "" {
+		fmt.Fprintf(&b, "- Project: `%s`\n", meta.ProjectDir)
+	}
+	if !meta.CreatedAt.IsZero() {
+		fmt.Fprintf(&b, "- Created: %s\n", meta.CreatedAt.Format(time.RFC3339))
+	}
+	fmt.Fprintf(&b, "- Messages: %d\n", len(msgs))
+	if meta.Cursor > 0 {
+		fmt.Fprintf(&b, "- Compacted: messages before #%d are outside the active context window\n", meta.Cursor)
+	}
+
+	for i, m := range msgs {
+		fmt.Fprintf(&b, "\n---\n\n## #%d %s", i, m.Role)
+		if m.Timestamp > 0 {
+			fmt.Fprintf(&b, " · %s", time.UnixMilli(m.Timestamp).Format("2006-01-02 15:04:05"))
+		}
+		b.WriteString("\n\n")
+		if m.ReasoningContent != "" {
+			fmt.Fprintf(&b, "<details><summary>Reasoning</summary>\n\n%s\n\n</details>\n\n", strings.TrimSpace(m.ReasoningContent))
+		}
+		content := strings.TrimSpace(m.Content)
+		if m.Role == "tool" {
+			fence := "```"
+			for strings.Contains(content, fence) {
+				fence += "`"
+			}
+			fmt.Fprintf(&b, "%s\n%s\n%s\n", fence, content, fence)
+		} else {
+			b.WriteString(content + "\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// openAIChatMessage is a message of the OpenAI chat fine-tuning format.
+type openAIChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// writeOpenAIChat writes a session as one line of OpenAI chat fine-tuning
+// JSONL: {"messages": [...]}. Only user and assistant text is kept, and
+// the example is cut after its last assistant message, since tool calls
+// cannot be reconstructed from the stored messages.
+func writeOpenAIChat(msgs []goharnesssession.Message, w io.Writer) error {
+	var out []openAIChatMessage
+	last := -1
+	for _, m := range msgs {
+		if (m.Role != "user" && m.Role != "assistant") || strings.TrimSpace(m.Content) == "" {
+			continue
+		}
+		out = append(out, openAIChatMessage{Role: m.Role, Content: m.Content})
+		if m.Role == "assistant" {
+			last = len(out) - 1
+		}
+	}
+	if last < 0 {
+		return fmt.Errorf("session has no assistant reply to export")
+	}
+	line, err := json.Marshal(struct {
+		Messages []openAIChatMessage `json:"messages"`
+	}{out[:last+1]})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
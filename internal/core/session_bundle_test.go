@@ -0,0 +1,171 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	goharnesssession "github.com/DotNetAge/goharness/session"
+	mindxses "github.com/DotNetAge/mindx/pkg/session"
+)
+
+func newTestBundler(t *testing.T) *SessionBundler {
+	t.Helper()
+	dir := t.TempDir()
+	sessions, err := mindxses.NewFileSessionStore(filepath.Join(dir, "sessions"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &SessionBundler{
+		Sessions: sessions,
+		Usage:    mindxses.NewFileTokenUsageStore(filepath.Join(dir, "data")),
+		Versions: NewFileVersionStore(),
+	}
+}
+
+// seedBundleSession creates a session in /work/api with three messages, a
+// tracked file with a backup and two recorded versions, and one usage
+// record.
+func seedBundleSession(t *testing.T, b *SessionBundler) string {
+	t.Helper()
+	ctx := context.Background()
+	info, err := b.Sessions.Create(ctx, "coder", goharnesssession.WithProjectDirOption("/work/api"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := []goharnesssession.Message{
+		{Role: "user", Content: "Rename the queue package", Timestamp: 1000},
+		{Role: "assistant", Content: "Done, see `queue.go`.", ReasoningContent: "simple rename", Timestamp: 2000},
+		{Role: "tool", Content: "ok", Timestamp: 3000},
+	}
+	for _, m := range msgs {
+		if err := b.Sessions.Append(ctx, info.SessionID, "coder", "", m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Sessions.SetCursor(ctx, info.SessionID, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	tracked := "/work/api/queue.go"
+	if err := b.Sessions.SaveModifyFiles(info.SessionID, []string{tracked}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeBundleFile(filepath.Join(info.SessionDir, "backup", "queue.go.bak"), []byte("package old\n")); err != nil {
+		t.Fatal(err)
+	}
+	vdir := b.Versions.fileDir(info.SessionDir, tracked)
+	for name, data := range map[string]string{"v1": "package old\n", "v2": "package queue\n", ".path": tracked} {
+		if err := writeBundleFile(filepath.Join(vdir, name), []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := b.Usage.AppendWithSource(ctx, goharnesssession.TokenUsageRecord{
+		ID: "u1", SessionID: info.SessionID, AgentName: "coder", ModelName: "m",
+		PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120, Timestamp: time.Now(),
+	}, string(mindxses.UsageSourceChat)); err != nil {
+		t.Fatal(err)
+	}
+	return info.SessionID
+}
+
+func TestSessionBundleRoundTrip(t *testing.T) {
+	src := newTestBundler(t)
+	id := seedBundleSession(t, src)
+	ctx := context.Background()
+
+	var bundle bytes.Buffer
+	if err := src.Export(ctx, id, SessionFormatBundle, &bundle); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	dst := newTestBundler(t)
+	res, err := dst.Import(ctx, &bundle, BundleImportOptions{ProjectDir: "/home/me/api"})
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if res.OriginalSessionID != id || res.AgentName != "coder" || res.Messages != 3 || res.TrackedFiles != 1 || res.UsageRecords != 1 {
+		t.Errorf("result = %+v", res)
+	}
+
+	msgs, _ := dst.Sessions.Get(ctx, res.SessionID)
+	if len(msgs) != 3 || msgs[1].ReasoningContent != "simple rename" {
+		t.Errorf("imported messages = %+v", msgs)
+	}
+	meta, _ := dst.Sessions.GetMeta(ctx, res.SessionID)
+	if meta.ProjectDir != "/home/me/api" || meta.Cursor != 1 || meta.MessageCount != 3 {
+		t.Errorf("imported meta = %+v", meta)
+	}
+
+	relocated := "/home/me/api/queue.go"
+	if files, _ := dst.Sessions.GetModifyFiles(res.SessionID); len(files) != 1 || files[0] != relocated {
+		t.Errorf("tracked files = %v, want [%s]", files, relocated)
+	}
+	dir, _ := dst.Sessions.ResolveSessionDir(res.SessionID)
+	if data, err := os.ReadFile(filepath.Join(dir, "backup", "queue.go.bak")); err != nil || string(data) != "package old\n" {
+		t.Errorf("backup = %q, %v", data, err)
+	}
+	if latest, err := dst.Versions.GetLatest(dir, relocated); err != nil || latest != "package queue\n" {
+		t.Errorf("latest version = %q, %v", latest, err)
+	}
+	if initial, err := dst.Versions.GetInitial(dir, relocated); err != nil || initial != "package old\n" {
+		t.Errorf("initial version = %q, %v", initial, err)
+	}
+
+	usage, _ := dst.Usage.QueryWithSource(ctx, goharnesssession.TokenUsageFilter{SessionID: res.SessionID})
+	if len(usage) != 1 || usage[0].TotalTokens != 120 || usage[0].Source != mindxses.UsageSourceImport {
+		t.Errorf("imported usage = %+v", usage)
+	}
+	if len(usage) == 1 && usage[0].ID == "u1" {
+		t.Error("imported usage kept the original record ID")
+	}
+}
+
+func TestSessionBundleRejectsInvalidInput(t *testing.T) {
+	if _, err := newTestBundler(t).Import(context.Background(), strings.NewReader("not a bundle"), BundleImportOptions{}); err == nil {
+		t.Error("Import of garbage should fail")
+	}
+	if _, err := readBundle(&bytes.Buffer{}); err == nil {
+		t.Error("readBundle of empty input should fail")
+	}
+}
+
+func TestSessionExportText(t *testing.T) {
+	b := newTestBundler(t)
+	id := seedBundleSession(t, b)
+	ctx := context.Background()
+
+	var md bytes.Buffer
+	if err := b.Export(ctx, id, SessionFormatMarkdown, &md); err != nil {
+		t.Fatalf("Export markdown: %v", err)
+	}
+	for _, want := range []string{"# Rename the queue package", "## #1 assistant", "<details><summary>Reasoning</summary>", "```\nok\n```", "before #1"} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("markdown missing %q:\n%s", want, md.String())
+		}
+	}
+
+	var jsonl bytes.Buffer
+	if err := b.Export(ctx, id, SessionFormatOpenAI, &jsonl); err != nil {
+		t.Fatalf("Export openai: %v", err)
+	}
+	var example struct {
+		Messages []openAIChatMessage `json:"messages"`
+	}
+	if err := json.Unmarshal(jsonl.Bytes(), &example); err != nil {
+		t.Fatalf("openai line: %v", err)
+	}
+	if len(example.Messages) != 2 || example.Messages[1].Role != "assistant" {
+		t.Errorf("openai messages = %+v", example.Messages)
+	}
+
+	if err := b.Export(ctx, id, "pdf", &bytes.Buffer{}); err == nil {
+		t.Error("unknown format should fail")
+	}
+}
@@ -0,0 +1,310 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	mindxses "github.com/DotNetAge/mindx/pkg/session"
+	"github.com/DotNetAge/mindx/pkg/storage"
+)
+
+// RetentionAction is what happens to a session a retention policy
+// collects.
+type RetentionAction string
+
+const (
+	// RetentionArchive writes the session to a bundle under the archive
+	// directory before deleting it. It is the default.
+	RetentionArchive RetentionAction = "archive"
+	// RetentionDelete deletes the session outright.
+	RetentionDelete RetentionAction = "delete"
+)
+
+// Reasons a session is collected.
+const (
+	RetentionReasonMaxAge      = "max_age"
+	RetentionReasonMaxPerAgent = "max_per_agent"
+)
+
+// SessionRetention is the retention policy stored in mindx.json under
+// "session_retention". A session is collected once it has been inactive
+// for more than MaxAgeDays, or once its agent has MaxPerAgent more
+// recently active sessions. A zero limit is off.
+type SessionRetention struct {
+	MaxAgeDays  int `json:"max_age_days,omitempty"`
+	MaxPerAgent int `json:"max_per_agent,omitempty"`
+	// KeepStarred exempts starred sessions, which then also do not count
+	// toward MaxPerAgent. Unset means true.
+	KeepStarred *bool           `json:"keep_starred,omitempty"`
+	Action      RetentionAction `json:"action,omitempty"`
+	// IntervalHours is how often the daemon applies the policy. Defaults
+	// to 24.
+	IntervalHours int `json:"interval_hours,omitempty"`
+}
+
+// Enabled reports whether the policy has any limit set.
+func (r SessionRetention) Enabled() bool {
+	return r.MaxAgeDays > 0 || r.MaxPerAgent > 0
+}
+
+// ActionKind returns the effective action, defaulting to RetentionArchive.
+func (r SessionRetention) ActionKind() RetentionAction {
+	if r.Action == "" {
+		return RetentionArchive
+	}
+	return r.Action
+}
+
+// KeepsStarred reports whether starred sessions are exempt.
+func (r SessionRetention) KeepsStarred() bool {
+	return r.KeepStarred == nil || *r.KeepStarred
+}
+
+// Interval returns how often the daemon applies the policy.
+func (r SessionRetention) Interval() time.Duration {
+	if r.IntervalHours <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(r.IntervalHours) * time.Hour
+}
+
+// Validate reports whether the policy is well-formed.
+func (r SessionRetention) Validate() error {
+	if r.MaxAgeDays < 0 || r.MaxPerAgent < 0 {
+		return fmt.Errorf("session retention: limits must not be negative")
+	}
+	switch r.ActionKind() {
+	case RetentionArchive, RetentionDelete:
+	default:
+		return fmt.Errorf("session retention: unknown action %q", r.Action)
+	}
+	return nil
+}
+
+// SessionRetention returns the configured retention policy; the zero
+// policy keeps everything.
+func (a *App) SessionRetention() SessionRetention {
+	if a.mindxConfig == nil || a.mindxConfig.SessionRetention == nil {
+		return SessionRetention{}
+	}
+	return *a.mindxConfig.SessionRetention
+}
+
+// CollectedSession is a session a retention run collected, or would
+// collect on a dry run.
+type CollectedSession struct {
+	SessionID      string    `json:"session_id"`
+	AgentName      string    `json:"agent_name"`
+	ProjectDir     string    `json:"project_dir,omitempty"`
+	Title          string    `json:"title,omitempty"`
+	LastActivityAt time.Time `json:"last_activity_at"`
+	Reason         string    `json:"reason"`
+	// Bytes is the size of the session directory, tracked file versions
+	// included.
+	Bytes       int64  `json:"bytes"`
+	ArchivePath string `json:"archive_path,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// RetentionReport is the outcome of a retention run.
+type RetentionReport struct {
+	DryRun      bool               `json:"dry_run"`
+	Action      RetentionAction    `json:"action"`
+	Scanned     int                `json:"scanned"`
+	KeptStarred int                `json:"kept_starred"`
+	KeptActive  int                `json:"kept_active"`
+	Sessions    []CollectedSession `json:"sessions"`
+	// ReclaimedBytes is the size of the removed session directories;
+	// ArchivedBytes is the size of the bundles written in their place.
+	ReclaimedBytes int64 `json:"reclaimed_bytes"`
+	ArchivedBytes  int64 `json:"archived_bytes,omitempty"`
+}
+
+// SessionJanitor applies retention policies to the session store.
+type SessionJanitor struct {
+	Bundler *SessionBundler
+	// ArchiveDir receives <agent>/<session-id>.mindx-session bundles.
+	ArchiveDir string
+	// Active reports sessions that are in use; they are never collected.
+	Active func(sessionID string) bool
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// SessionJanitor returns a janitor over the app's stores that archives to
+// the settings' session archive directory.
+func (a *App) SessionJanitor() *SessionJanitor {
+	return &SessionJanitor{Bundler: a.SessionBundler(), ArchiveDir: a.settings.SessionArchiveDir()}
+}
+
+// Run applies policy. On a dry run nothing is changed and the report
+// lists what would be collected. A session that fails to archive or
+// delete is reported with its error and left in place.
+func (j *SessionJanitor) Run(ctx context.Context, policy SessionRetention, dryRun bool) (*RetentionReport, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	report := &RetentionReport{DryRun: dryRun, Action: policy.ActionKind(), Sessions: []CollectedSession{}}
+	if !policy.Enabled() {
+		return report, nil
+	}
+
+	store := j.Bundler.Sessions
+	sessions, err := store.ListSessions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+	// Most recently active first, so ranks count from the newest.
+	sort.SliceStable(sessions, func(a, b int) bool {
+		return sessions[a].LastActivityAt.After(sessions[b].LastActivityAt)
+	})
+	report.Scanned = len(sessions)
+
+	now := time.Now()
+	if j.Now != nil {
+		now = j.Now()
+	}
+	cutoff := now.AddDate(0, 0, -policy.MaxAgeDays)
+	rank := make(map[string]int)
+
+	for _, s := range sessions {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		dir, err := store.ResolveSessionDir(s.SessionID)
+		if err != nil {
+			continue
+		}
+		if policy.KeepsStarred() && mindxses.LoadSessionStarred(dir) {
+			report.KeptStarred++
+			continue
+		}
+		rank[s.AgentName]++
+
+		var reason string
+		switch {
+		case policy.MaxPerAgent > 0 && rank[s.AgentName] > policy.MaxPerAgent:
+			reason = RetentionReasonMaxPerAgent
+		case policy.MaxAgeDays > 0 && s.LastActivityAt.Before(cutoff):
+			reason = RetentionReasonMaxAge
+		default:
+			continue
+		}
+		if j.Active != nil && j.Active(s.SessionID) {
+			report.KeptActive++
+			continue
+		}
+
+		c := CollectedSession{
+			SessionID:      s.SessionID,
+			AgentName:      s.AgentName,
+			ProjectDir:     s.ProjectDir,
+			Title:          s.Title,
+			LastActivityAt: s.LastActivityAt,
+			Reason:         reason,
+			Bytes:          dirSize(dir),
+		}
+		if !dryRun {
+			if err := j.collect(ctx, report.Action, &c); err != nil {
+				c.Error = err.Error()
+				report.Sessions = append(report.Sessions, c)
+				continue
+			}
+			if c.ArchivePath != "" {
+				if info, err := os.Stat(c.ArchivePath); err == nil {
+					report.ArchivedBytes += info.Size()
+				}
+			}
+		}
+		report.ReclaimedBytes += c.Bytes
+		report.Sessions = append(report.Sessions, c)
+	}
+	return report, nil
+}
+
+// collect archives (when action asks for it) and then deletes c's session.
+func (j *SessionJanitor) collect(ctx context.Context, action RetentionAction, c *CollectedSession) error {
+	if action == RetentionArchive {
+		path := filepath.Join(j.ArchiveDir, c.AgentName, c.SessionID+SessionBundleExt)
+		if err := j.archive(ctx, c.SessionID, path); err != nil {
+			return fmt.Errorf("archive: %w", err)
+		}
+		c.ArchivePath = path
+	}
+	if err := j.Bundler.Sessions.DeleteSession(ctx, c.SessionID); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	return nil
+}
+
+// archive writes the session's bundle to path, replacing it atomically.
+// Unlike an exported bundle, an archive stays in the data directory, so
+// it is sealed like the session it replaces while encryption is on;
+// Import opens it.
+func (j *SessionJanitor) archive(ctx context.Context, sessionID, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := j.Bundler.Export(ctx, sessionID, SessionFormatBundle, &buf); err != nil {
+		return err
+	}
+	data, err := storage.Seal(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".archive-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// resealArchives rewrites the archived bundles under dir in the current
+// storage form.
+func resealArchives(dir string) (int, error) {
+	n := 0
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.Mode().IsRegular() || !strings.HasSuffix(p, SessionBundleExt) {
+			return nil
+		}
+		changed, err := storage.ResealFile(p)
+		if changed {
+			n++
+		}
+		return err
+	})
+	return n, err
+}
+
+// dirSize returns the total size of the regular files under dir.
+func dirSize(dir string) int64 {
+	var n int64
+	_ = filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			n += info.Size()
+		}
+		return nil
+	})
+	return n
+}
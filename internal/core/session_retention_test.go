@@ -0,0 +1,222 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	goharnesssession "github.com/DotNetAge/goharness/session"
+	mindxses "github.com/DotNetAge/mindx/pkg/session"
+	"github.com/DotNetAge/mindx/pkg/storage"
+)
+
+// seedRetention creates one session of agent "coder" per age, last active
+// that many days before now, and returns their IDs in the same order.
+func seedRetention(t *testing.T, store *mindxses.FileSessionStore, now time.Time, ages ...int) []string {
+	t.Helper()
+	ctx := context.Background()
+	ids := make([]string, len(ages))
+	for i, age := range ages {
+		info, err := store.Create(ctx, "coder")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Append(ctx, info.SessionID, "coder", "", goharnesssession.Message{Role: "user", Content: "hello"}); err != nil {
+			t.Fatal(err)
+		}
+		setLastActivity(t, store, info.SessionID, now.AddDate(0, 0, -age))
+		ids[i] = info.SessionID
+	}
+	return ids
+}
+
+// setLastActivity backdates a session. SaveSessionMeta always stamps the
+// current time, so meta.json is edited in place.
+func setLastActivity(t *testing.T, store *mindxses.FileSessionStore, sessionID string, at time.Time) {
+	t.Helper()
+	dir, err := store.ResolveSessionDir(sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := mindxses.LoadSessionMeta(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "meta.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = []byte(strings.ReplaceAll(string(data), meta.UpdatedAt.Format(time.RFC3339Nano), at.Format(time.RFC3339Nano)))
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// collectedIDs lists the collected sessions as "<index in ids>:<reason>".
+func collectedIDs(r *RetentionReport, ids []string) string {
+	out := make([]string, len(r.Sessions))
+	for i, s := range r.Sessions {
+		for n, id := range ids {
+			if id == s.SessionID {
+				out[i] = strconv.Itoa(n) + ":" + s.Reason
+			}
+		}
+	}
+	sort.Strings(out)
+	return strings.Join(out, ",")
+}
+
+func TestSessionJanitor(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	newJanitor := func(t *testing.T) (*SessionJanitor, []string) {
+		b := newTestBundler(t)
+		ids := seedRetention(t, b.Sessions, now, 1, 5, 40, 100)
+		return &SessionJanitor{Bundler: b, ArchiveDir: t.TempDir(), Now: func() time.Time { return now }}, ids
+	}
+	keep := false
+
+	tests := []struct {
+		name   string
+		policy SessionRetention
+		star   []int
+		active []int
+		want   string
+	}{
+		{"off", SessionRetention{}, nil, nil, ""},
+		{"max age", SessionRetention{MaxAgeDays: 30}, nil, nil, "2:max_age,3:max_age"},
+		{"max per agent", SessionRetention{MaxPerAgent: 2}, nil, nil, "2:max_per_agent,3:max_per_agent"},
+		{"both", SessionRetention{MaxAgeDays: 3, MaxPerAgent: 3}, nil, nil, "1:max_age,2:max_age,3:max_per_agent"},
+		{"starred kept and not ranked", SessionRetention{MaxPerAgent: 2}, []int{0}, nil, "3:max_per_agent"},
+		{"starred not kept", SessionRetention{MaxPerAgent: 2, KeepStarred: &keep}, []int{2}, nil, "2:max_per_agent,3:max_per_agent"},
+		{"active kept", SessionRetention{MaxAgeDays: 30}, nil, []int{3}, "2:max_age"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j, ids := newJanitor(t)
+			for _, i := range tt.star {
+				if err := j.Bundler.Sessions.SetStarred(ids[i], true); err != nil {
+					t.Fatal(err)
+				}
+			}
+			j.Active = func(id string) bool {
+				for _, i := range tt.active {
+					if ids[i] == id {
+						return true
+					}
+				}
+				return false
+			}
+			report, err := j.Run(context.Background(), tt.policy, true)
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if got := collectedIDs(report, ids); got != tt.want {
+				t.Errorf("collected = %q, want %q", got, tt.want)
+			}
+			if len(report.Sessions) > 0 && report.ReclaimedBytes <= 0 {
+				t.Errorf("reclaimed bytes = %d", report.ReclaimedBytes)
+			}
+			if sessions, _ := j.Bundler.Sessions.ListSessions(context.Background()); len(sessions) != 4 {
+				t.Errorf("dry run left %d sessions, want 4", len(sessions))
+			}
+		})
+	}
+}
+
+func TestSessionJanitorArchivesAndDeletes(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	b := newTestBundler(t)
+	ids := seedRetention(t, b.Sessions, now, 1, 100)
+	j := &SessionJanitor{Bundler: b, ArchiveDir: t.TempDir(), Now: func() time.Time { return now }}
+
+	report, err := j.Run(ctx, SessionRetention{MaxAgeDays: 30}, false)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(report.Sessions) != 1 || report.Sessions[0].Error != "" {
+		t.Fatalf("report = %+v", report)
+	}
+	archived := report.Sessions[0].ArchivePath
+	if archived != filepath.Join(j.ArchiveDir, "coder", ids[1]+SessionBundleExt) || report.ArchivedBytes <= 0 {
+		t.Errorf("archive = %q, %d bytes", archived, report.ArchivedBytes)
+	}
+	if _, err := b.Sessions.ResolveSessionDir(ids[1]); err == nil {
+		t.Error("collected session still exists")
+	}
+
+	f, err := os.Open(archived)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	res, err := b.Import(ctx, f, BundleImportOptions{})
+	if err != nil || res.OriginalSessionID != ids[1] || res.Messages != 1 {
+		t.Errorf("restore archive = %+v, %v", res, err)
+	}
+
+	report, err = j.Run(ctx, SessionRetention{MaxPerAgent: 1, Action: RetentionDelete}, false)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(report.Sessions) != 1 || report.Sessions[0].ArchivePath != "" {
+		t.Errorf("delete report = %+v", report)
+	}
+
+	if _, err := j.Run(ctx, SessionRetention{MaxAgeDays: 1, Action: "shred"}, true); err == nil {
+		t.Error("unknown action should fail")
+	}
+}
+
+func TestSessionJanitorSealsArchives(t *testing.T) {
+	key, err := storage.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring, err := storage.NewKeyring("k1", map[string][]byte{"k1": key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage.Use(ring, true)
+	t.Cleanup(func() { storage.Use(nil, false) })
+
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	b := newTestBundler(t)
+	ids := seedRetention(t, b.Sessions, now, 100)
+	j := &SessionJanitor{Bundler: b, ArchiveDir: t.TempDir(), Now: func() time.Time { return now }}
+
+	report, err := j.Run(ctx, SessionRetention{MaxAgeDays: 30}, false)
+	if err != nil || len(report.Sessions) != 1 {
+		t.Fatalf("Run = %+v, %v", report, err)
+	}
+	archived := report.Sessions[0].ArchivePath
+	data, err := os.ReadFile(archived)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !storage.IsSealed(data) {
+		t.Fatal("archive is plaintext while encryption is on")
+	}
+	res, err := b.Import(ctx, bytes.NewReader(data), BundleImportOptions{})
+	if err != nil || res.OriginalSessionID != ids[0] {
+		t.Errorf("restore sealed archive = %+v, %v", res, err)
+	}
+
+	// A rekey to plaintext rewrites the archive.
+	storage.Use(ring, false)
+	if n, err := resealArchives(j.ArchiveDir); err != nil || n != 1 {
+		t.Fatalf("resealArchives = %d, %v", n, err)
+	}
+	if data, _ := os.ReadFile(archived); storage.IsSealed(data) {
+		t.Error("archive still sealed after resealing to plaintext")
+	}
+}
@@ -26,6 +26,24 @@ func (s *Settings) ModelsFile() string {
 	return filepath.Join(s.UserPreferences(), "settings", "models.yml")
 }
 
+// PricingFile holds dated, per-provider model price schedules that take
+// precedence over the base prices in models.yml.
+func (s *Settings) PricingFile() string {
+	return filepath.Join(s.UserPreferences(), "settings", "pricing.yml")
+}
+
+// TokenizersDir holds tiktoken-format BPE vocabularies, one
+// <encoding>.tiktoken file per encoding named in models.yml.
+func (s *Settings) TokenizersDir() string {
+	return filepath.Join(s.UserPreferences(), "settings", "tokenizers")
+}
+
+// TokenCalibrationFile stores the learned per-family token estimate
+// correction factors.
+func (s *Settings) TokenCalibrationFile() string {
+	return filepath.Join(s.DataDir(), "token_calibration.json")
+}
+
 func (s *Settings) ProvidersFile() string {
 	return filepath.Join(s.UserPreferences(), "settings", "providers.yml")
 }
@@ -60,6 +78,22 @@ func (s *Settings) SessionsDir() string {
 	return filepath.Join(s.UserPreferences(), "sessions")
 }
 
+// SessionSearchDir holds the full-text index over session messages.
+func (s *Settings) SessionSearchDir() string {
+	return filepath.Join(s.DataDir(), "session_search")
+}
+
+// SessionArchiveDir holds the bundles of sessions archived by retention.
+func (s *Settings) SessionArchiveDir() string {
+	return filepath.Join(s.DataDir(), "session_archive")
+}
+
+// StorageDir holds what the processes sharing the data directory need
+// to agree on storage keys: the published primary key and their leases.
+func (s *Settings) StorageDir() string {
+	return filepath.Join(s.DataDir(), "storage")
+}
+
 func (s *Settings) SchedulesDir() string {
 	return filepath.Join(s.DataDir(), "schedules")
 }
@@ -0,0 +1,297 @@
+package core
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DotNetAge/mindx/pkg/storage"
+)
+
+// storageKeyringCredential is the CredentialStore key holding the storage
+// data keys, as JSON: {"primary": id, "keys": {id: base64 key},
+// "retired": [id]}.
+const storageKeyringCredential = "storage.keyring"
+
+// StorageConfig is stored in mindx.json under "storage".
+type StorageConfig struct {
+	// Encrypt seals session, token usage, file version and memory data at
+	// rest with AES-256-GCM. Data written before it was turned on stays
+	// readable; `mindx storage rekey` seals it.
+	Encrypt bool `json:"encrypt,omitempty"`
+}
+
+// StorageEncrypted reports whether encryption at rest is configured. It
+// is safe on a nil config.
+func (c *MindxConfig) StorageEncrypted() bool {
+	return c != nil && c.Storage != nil && c.Storage.Encrypt
+}
+
+// StorageEncrypted reports whether encryption at rest is configured.
+func (a *App) StorageEncrypted() bool {
+	return a.mindxConfig.StorageEncrypted()
+}
+
+type storedKeyring struct {
+	Primary string            `json:"primary"`
+	Keys    map[string]string `json:"keys"`
+	// Retired lists the keys a rekey has moved all data off. They are
+	// kept to decrypt what processes still on an older keyring write.
+	Retired []string `json:"retired,omitempty"`
+}
+
+func loadStorageKeys(creds CredentialStore) (*storedKeyring, error) {
+	raw, err := creds.Get(storageKeyringCredential)
+	if err != nil {
+		return nil, fmt.Errorf("read storage keys: %w", err)
+	}
+	k := &storedKeyring{Keys: map[string]string{}}
+	if raw == "" {
+		return k, nil
+	}
+	if err := json.Unmarshal([]byte(raw), k); err != nil {
+		return nil, fmt.Errorf("parse storage keys: %w", err)
+	}
+	if k.Keys == nil {
+		k.Keys = map[string]string{}
+	}
+	return k, nil
+}
+
+func (k *storedKeyring) save(creds CredentialStore) error {
+	if len(k.Keys) == 0 {
+		return creds.Set(storageKeyringCredential, "")
+	}
+	data, err := json.Marshal(k)
+	if err != nil {
+		return err
+	}
+	if err := creds.Set(storageKeyringCredential, string(data)); err != nil {
+		return fmt.Errorf("save storage keys: %w", err)
+	}
+	return nil
+}
+
+// ring returns the keyring, or nil when there are no keys.
+func (k *storedKeyring) ring() (*storage.Keyring, error) {
+	if len(k.Keys) == 0 {
+		return nil, nil
+	}
+	keys := make(map[string][]byte, len(k.Keys))
+	for id, enc := range k.Keys {
+		key, err := base64.StdEncoding.DecodeString(enc)
+		if err != nil {
+			return nil, fmt.Errorf("storage key %q: %w", id, err)
+		}
+		keys[id] = key
+	}
+	return storage.NewKeyring(k.Primary, keys)
+}
+
+// rotate adds a new key and makes it the primary one.
+func (k *storedKeyring) rotate(now time.Time) error {
+	key, err := storage.GenerateKey()
+	if err != nil {
+		return err
+	}
+	base := "k" + now.UTC().Format("20060102T150405")
+	id := base
+	for n := 2; k.Keys[id] != ""; n++ {
+		id = base + "-" + strconv.Itoa(n)
+	}
+	k.Keys[id] = base64.StdEncoding.EncodeToString(key)
+	k.Primary = id
+	return nil
+}
+
+// InitStorage installs the process keyring from creds. With encrypt set
+// and no key yet, a first key is generated. Without encrypt, existing keys
+// are still loaded so data sealed earlier stays readable.
+//
+// With a storage directory dir, shared by the processes using the data,
+// the process attaches to it and follows the key rotations another
+// process publishes there, reloading the keyring from creds.
+func InitStorage(creds CredentialStore, encrypt bool, dir string) error {
+	k, err := loadStorageKeys(creds)
+	if err != nil {
+		storage.Use(nil, false)
+		return err
+	}
+	if encrypt && len(k.Keys) == 0 {
+		if err := k.rotate(time.Now()); err != nil {
+			return err
+		}
+		if err := k.save(creds); err != nil {
+			return err
+		}
+	}
+	ring, err := k.ring()
+	if err != nil {
+		storage.Use(nil, false)
+		return err
+	}
+	storage.Use(ring, encrypt)
+	if dir == "" {
+		return nil
+	}
+	storage.Watch(dir, func() (*storage.Keyring, error) {
+		k, err := loadStorageKeys(creds)
+		if err != nil {
+			return nil, err
+		}
+		return k.ring()
+	})
+	return storage.Attach(dir)
+}
+
+// Resealer is a store outside the App, such as the daemon's memory, that
+// a storage rekey rewrites too.
+type Resealer interface {
+	Reseal(ctx context.Context) (int, error)
+}
+
+// StorageRekeyReport is the outcome of a storage rekey.
+type StorageRekeyReport struct {
+	Encrypted   bool     `json:"encrypted"`
+	PrimaryKey  string   `json:"primary_key,omitempty"`
+	RetiredKeys []string `json:"retired_keys,omitempty"`
+	// Rewritten counts rewritten files per store; for memory it counts
+	// re-stored memories.
+	Rewritten map[string]int `json:"rewritten"`
+}
+
+// rekeyMu keeps rekeys from overlapping.
+var rekeyMu sync.Mutex
+
+// RekeyStorage rotates to a new data key and rewrites every store with
+// it. With encryption off it instead rewrites every store in plaintext.
+// Once every store has been rewritten the old keys are marked retired but
+// kept, to decrypt what a process still sealing with them writes; after a
+// failure they stay as they were and the rekey can be run again.
+//
+// Other processes attached to the data, such as a TUI, would keep
+// writing with the keyring they loaded, so the rekey refuses to run while
+// any is attached. The new primary key is published for them all the
+// same.
+func (a *App) RekeyStorage(ctx context.Context, extra map[string]Resealer) (*StorageRekeyReport, error) {
+	if !rekeyMu.TryLock() {
+		return nil, fmt.Errorf("a storage rekey is already running")
+	}
+	defer rekeyMu.Unlock()
+
+	if a.storageDir != "" {
+		others, err := storage.Attached(a.storageDir)
+		if err != nil {
+			return nil, err
+		}
+		if len(others) > 0 {
+			return nil, fmt.Errorf("other mindx processes use the data (pids %v); close them and run the rekey again", others)
+		}
+	}
+
+	encrypt := a.StorageEncrypted()
+	k, err := loadStorageKeys(a.credStore)
+	if err != nil {
+		return nil, err
+	}
+	if encrypt {
+		if err := k.rotate(time.Now()); err != nil {
+			return nil, err
+		}
+		if err := k.save(a.credStore); err != nil {
+			return nil, err
+		}
+	}
+	ring, err := k.ring()
+	if err != nil {
+		return nil, err
+	}
+	storage.Use(ring, encrypt)
+	if a.storageDir != "" && encrypt {
+		if err := storage.Publish(a.storageDir, k.Primary); err != nil {
+			return nil, fmt.Errorf("publish storage key: %w", err)
+		}
+	}
+
+	report := &StorageRekeyReport{Encrypted: encrypt, Rewritten: map[string]int{}}
+	if err := a.resealStores(ctx, extra, report.Rewritten); err != nil {
+		return report, err
+	}
+
+	retired := map[string]bool{}
+	for _, id := range k.Retired {
+		retired[id] = true
+	}
+	for id := range k.Keys {
+		if (!encrypt || id != k.Primary) && !retired[id] {
+			report.RetiredKeys = append(report.RetiredKeys, id)
+			k.Retired = append(k.Retired, id)
+		}
+	}
+	sort.Strings(report.RetiredKeys)
+	sort.Strings(k.Retired)
+	if len(report.RetiredKeys) > 0 {
+		if err := k.save(a.credStore); err != nil {
+			return report, err
+		}
+	}
+	if encrypt {
+		report.PrimaryKey = k.Primary
+	}
+	return report, nil
+}
+
+func (a *App) resealStores(ctx context.Context, extra map[string]Resealer, counts map[string]int) error {
+	if a.sessDB != nil {
+		n, err := a.sessDB.Reseal()
+		counts["sessions"] = n
+		if err != nil {
+			return fmt.Errorf("reseal sessions: %w", err)
+		}
+		sessions, err := a.sessDB.ListSessions(ctx)
+		if err != nil {
+			return fmt.Errorf("reseal file versions: %w", err)
+		}
+		for _, s := range sessions {
+			dir, err := a.sessDB.ResolveSessionDir(s.SessionID)
+			if err != nil {
+				continue
+			}
+			n, err := a.versions.Reseal(dir)
+			counts["file_versions"] += n
+			if err != nil {
+				return fmt.Errorf("reseal file versions of %s: %w", s.SessionID, err)
+			}
+		}
+	}
+	if a.tokenUsageStore != nil {
+		n, err := a.tokenUsageStore.Reseal()
+		counts["token_usage"] = n
+		if err != nil {
+			return fmt.Errorf("reseal token usage: %w", err)
+		}
+	}
+	if a.settings != nil {
+		n, err := resealArchives(a.settings.SessionArchiveDir())
+		counts["session_archives"] = n
+		if err != nil {
+			return fmt.Errorf("reseal session archives: %w", err)
+		}
+	}
+	for name, r := range extra {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := r.Reseal(ctx)
+		counts[name] = n
+		if err != nil {
+			return fmt.Errorf("reseal %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/DotNetAge/mindx/pkg/storage"
+)
+
+type memCredentialStore map[string]string
+
+func (m memCredentialStore) Get(key string) (string, error) { return m[key], nil }
+func (m memCredentialStore) Set(key, value string) error {
+	m[key] = value
+	return nil
+}
+
+func TestRekeyStorage(t *testing.T) {
+	t.Cleanup(func() { storage.Use(nil, false) })
+	ctx := context.Background()
+	creds := memCredentialStore{}
+	cfg := &MindxConfig{Storage: &StorageConfig{Encrypt: true}}
+
+	if err := InitStorage(creds, true, ""); err != nil {
+		t.Fatalf("InitStorage: %v", err)
+	}
+	first := storage.Active().Primary()
+
+	b := newTestBundler(t)
+	id := seedBundleSession(t, b)
+	app := &App{mindxConfig: cfg, credStore: creds, sessDB: b.Sessions, tokenUsageStore: b.Usage, versions: b.Versions}
+	dir, _ := b.Sessions.ResolveSessionDir(id)
+	latest := filepath.Join(b.Versions.fileDir(dir, "/work/api/queue.go"), "v2")
+
+	report, err := app.RekeyStorage(ctx, nil)
+	if err != nil {
+		t.Fatalf("RekeyStorage: %v", err)
+	}
+	if !report.Encrypted || report.PrimaryKey == first || len(report.RetiredKeys) != 1 || report.RetiredKeys[0] != first {
+		t.Errorf("report = %+v", report)
+	}
+	if k, _ := loadStorageKeys(creds); k.Keys[first] == "" || len(k.Retired) != 1 || k.Retired[0] != first {
+		t.Errorf("retired key not kept to decrypt: %+v", k)
+	}
+	if report.Rewritten["sessions"] == 0 || report.Rewritten["file_versions"] != 3 || report.Rewritten["token_usage"] != 1 {
+		t.Errorf("rewritten = %v", report.Rewritten)
+	}
+	if data, _ := os.ReadFile(latest); !storage.IsSealed(data) {
+		t.Errorf("version not sealed: %q", data)
+	}
+	if got, err := b.Versions.GetLatest(dir, "/work/api/queue.go"); err != nil || got != "package queue\n" {
+		t.Errorf("GetLatest = %q, %v", got, err)
+	}
+	if msgs, _ := b.Sessions.Get(ctx, id); len(msgs) != 3 {
+		t.Errorf("messages after rekey = %d", len(msgs))
+	}
+
+	// A fresh process finds the rotated key in the credential store.
+	storage.Use(nil, false)
+	if err := InitStorage(creds, true, ""); err != nil || storage.Active().Primary() != report.PrimaryKey {
+		t.Fatalf("InitStorage after rekey: %v", err)
+	}
+
+	cfg.Storage.Encrypt = false
+	report, err = app.RekeyStorage(ctx, nil)
+	if err != nil {
+		t.Fatalf("RekeyStorage to plaintext: %v", err)
+	}
+	if report.Encrypted || report.PrimaryKey != "" || len(report.RetiredKeys) != 1 {
+		t.Errorf("plaintext report = %+v", report)
+	}
+	if k, _ := loadStorageKeys(creds); len(k.Keys) != 2 || len(k.Retired) != 2 {
+		t.Errorf("keyring after decrypting = %+v, want both keys kept as retired", k)
+	}
+	if data, _ := os.ReadFile(latest); string(data) != "package queue\n" {
+		t.Errorf("version after decrypting = %q", data)
+	}
+	if msgs, _ := b.Sessions.Get(ctx, id); len(msgs) != 3 {
+		t.Errorf("messages after decrypting = %d", len(msgs))
+	}
+}
+
+func TestRekeyStorageRefusesAttachedProcesses(t *testing.T) {
+	t.Cleanup(func() {
+		storage.Use(nil, false)
+		storage.Watch("", nil)
+	})
+	creds := memCredentialStore{}
+	dir := t.TempDir()
+	if err := InitStorage(creds, true, dir); err != nil {
+		t.Fatalf("InitStorage: %v", err)
+	}
+	app := &App{mindxConfig: &MindxConfig{Storage: &StorageConfig{Encrypt: true}}, credStore: creds, storageDir: dir}
+
+	// A TUI attached to the same data.
+	lease := filepath.Join(dir, "clients", strconv.Itoa(os.Getpid()+1))
+	if err := os.WriteFile(lease, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	first := storage.Active().Primary()
+	if _, err := app.RekeyStorage(context.Background(), nil); err == nil {
+		t.Fatal("RekeyStorage should refuse while another process is attached")
+	}
+	if storage.Active().Primary() != first {
+		t.Error("a refused rekey rotated the key")
+	}
+
+	if err := os.Remove(lease); err != nil {
+		t.Fatal(err)
+	}
+	report, err := app.RekeyStorage(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("RekeyStorage: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "primary")); strings.TrimSpace(string(data)) != report.PrimaryKey {
+		t.Errorf("published primary = %q, want %s", data, report.PrimaryKey)
+	}
+}
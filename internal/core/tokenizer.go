@@ -0,0 +1,212 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/DotNetAge/mindx/pkg/logging"
+	mindxses "github.com/DotNetAge/mindx/pkg/session"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// EncodingHeuristic is the built-in script-aware estimator. It is the
+	// default for models no family matches.
+	EncodingHeuristic = "heuristic"
+	// EncodingChars is the legacy ~4 bytes per token estimator.
+	EncodingChars = "chars"
+)
+
+// TokenizerFamily selects a tokenizer for models whose name starts with
+// one of Match. Any other Encoding names an external vocabulary: no
+// vocabulary ships with mindx, so the tiktoken-format file
+// <settings>/tokenizers/<encoding>.tiktoken has to be put in place (e.g.
+// cl100k_base or o200k_base, downloaded from the tiktoken releases) for
+// the built-in BPE tokenizer to count with it. Without the file the
+// heuristic estimator is used.
+type TokenizerFamily struct {
+	Name     string   `yaml:"name"`
+	Match    []string `yaml:"match"`
+	Encoding string   `yaml:"encoding"`
+}
+
+// TokenizerConfig is the "tokenizers" section of models.yml:
+//
+//	tokenizers:
+//	  default: heuristic        # optional
+//	  calibrate: true           # learn a correction factor per family
+//	  families:
+//	    - name: gpt-4o
+//	      match: [gpt-4o, o1, o3]
+//	      encoding: o200k_base
+//	    - name: qwen
+//	      match: [qwen]
+//	      encoding: cl100k_base
+type TokenizerConfig struct {
+	Default   string            `yaml:"default,omitempty"`
+	Calibrate bool              `yaml:"calibrate,omitempty"`
+	Families  []TokenizerFamily `yaml:"families,omitempty"`
+}
+
+// LoadTokenizerConfig reads the tokenizers section of models.yml. A
+// missing file or section yields the zero config.
+func LoadTokenizerConfig(path string) (TokenizerConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return TokenizerConfig{}, nil
+		}
+		return TokenizerConfig{}, fmt.Errorf("failed to read models file for tokenizers: %w", err)
+	}
+	var parsed struct {
+		Tokenizers TokenizerConfig `yaml:"tokenizers"`
+	}
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		return TokenizerConfig{}, fmt.Errorf("failed to parse tokenizers: %w", err)
+	}
+	return parsed.Tokenizers, nil
+}
+
+// TokenizerRegistry maps models to token estimators by family, loading
+// BPE vocabularies on first use, and optionally scales every estimate by
+// the family's learned calibration factor.
+type TokenizerRegistry struct {
+	cfg         TokenizerConfig
+	dir         string
+	calibration *mindxses.TokenCalibration
+	logger      logging.Logger
+
+	mu        sync.Mutex
+	encodings map[string]mindxses.TokenEstimator
+}
+
+// NewTokenizerRegistry creates a registry that looks up vocabularies in
+// dir. calibration may be nil, which disables calibration.
+func NewTokenizerRegistry(cfg TokenizerConfig, dir string, calibration *mindxses.TokenCalibration, logger logging.Logger) *TokenizerRegistry {
+	return &TokenizerRegistry{
+		cfg:         cfg,
+		dir:         dir,
+		calibration: calibration,
+		logger:      logger,
+		encodings:   make(map[string]mindxses.TokenEstimator),
+	}
+}
+
+// Family returns the family of a model: the one with the longest matching
+// name prefix, or a "default" family using the default encoding.
+func (r *TokenizerRegistry) Family(model string) TokenizerFamily {
+	model = strings.ToLower(model)
+	best, bestLen := -1, 0
+	for i, f := range r.cfg.Families {
+		for _, m := range f.Match {
+			m = strings.ToLower(m)
+			if m != "" && strings.HasPrefix(model, m) && len(m) > bestLen {
+				best, bestLen = i, len(m)
+			}
+		}
+	}
+	if best >= 0 {
+		f := r.cfg.Families[best]
+		if f.Name == "" {
+			f.Name = f.Match[0]
+		}
+		return f
+	}
+	return TokenizerFamily{Name: "default", Encoding: r.cfg.Default}
+}
+
+// Calibrating reports whether estimates are calibrated from usage.
+func (r *TokenizerRegistry) Calibrating() bool {
+	return r.cfg.Calibrate && r.calibration != nil
+}
+
+// Base returns the uncalibrated estimator for a model.
+func (r *TokenizerRegistry) Base(model string) mindxses.TokenEstimator {
+	return r.encoding(r.Family(model).Encoding)
+}
+
+// For returns the estimator for a model, calibrated when calibration is
+// enabled.
+func (r *TokenizerRegistry) For(model string) mindxses.TokenEstimator {
+	family := r.Family(model)
+	est := r.encoding(family.Encoding)
+	if r.Calibrating() {
+		return r.calibration.Estimator(family.Name, est)
+	}
+	return est
+}
+
+// Observe feeds one calibration sample for a model: text the base
+// estimator put at estimated tokens was billed as actual prompt tokens.
+func (r *TokenizerRegistry) Observe(model string, estimated, actual int) {
+	if !r.Calibrating() {
+		return
+	}
+	if err := r.calibration.Observe(r.Family(model).Name, estimated, actual); err != nil && r.logger != nil {
+		r.logger.Warn("token calibration: failed to save", "error", err)
+	}
+}
+
+// Calibration returns the calibration store, or nil when disabled.
+func (r *TokenizerRegistry) Calibration() *mindxses.TokenCalibration {
+	return r.calibration
+}
+
+func (r *TokenizerRegistry) encoding(name string) mindxses.TokenEstimator {
+	switch name {
+	case "", EncodingHeuristic:
+		return mindxses.NewHeuristicEstimator()
+	case EncodingChars:
+		return mindxses.NewTokenEstimator()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if est, ok := r.encodings[name]; ok {
+		return est
+	}
+	path := filepath.Join(r.dir, name+".tiktoken")
+	var est mindxses.TokenEstimator
+	enc, err := mindxses.LoadBPEFile(name, path)
+	if err != nil {
+		if r.logger != nil {
+			r.logger.Warn("tokenizer: vocabulary unavailable, using heuristic estimator",
+				"encoding", name, "file", path, "error", err)
+		}
+		est = mindxses.NewHeuristicEstimator()
+	} else {
+		est = enc
+	}
+	r.encodings[name] = est
+	return est
+}
+
+// Tokenizers returns the tokenizer registry.
+func (a *App) Tokenizers() *TokenizerRegistry {
+	return a.tokenizers
+}
+
+// AgentModel returns the name of the model an agent runs on, or "".
+func (a *App) AgentModel(agentName string) string {
+	agent := a.Agents().Get(agentName)
+	if agent == nil {
+		return ""
+	}
+	name, _, err := a.resolveModelName(agent.Model)
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
+// TokenEstimatorForAgent returns the estimator for the model an agent runs
+// on. It is installed as the session store's estimator resolver.
+func (a *App) TokenEstimatorForAgent(agentName string) mindxses.TokenEstimator {
+	if a.tokenizers == nil {
+		return nil
+	}
+	return a.tokenizers.For(a.AgentModel(agentName))
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	mindxses "github.com/DotNetAge/mindx/pkg/session"
+)
+
+func TestLoadTokenizerConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "models.yml")
+	data := `models:
+  - name: qwen-max
+tokenizers:
+  calibrate: true
+  families:
+    - name: gpt-4o
+      match: [gpt-4o, o3]
+      encoding: o200k_base
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadTokenizerConfig(path)
+	if err != nil {
+		t.Fatalf("LoadTokenizerConfig: %v", err)
+	}
+	if !cfg.Calibrate || len(cfg.Families) != 1 || cfg.Families[0].Encoding != "o200k_base" {
+		t.Errorf("cfg = %+v", cfg)
+	}
+
+	if cfg, err := LoadTokenizerConfig(filepath.Join(t.TempDir(), "missing.yml")); err != nil || len(cfg.Families) != 0 {
+		t.Errorf("missing file: cfg = %+v, err = %v", cfg, err)
+	}
+}
+
+func TestTokenizerRegistryFamilies(t *testing.T) {
+	dir := t.TempDir()
+	var vocab strings.Builder
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&vocab, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), i)
+	}
+	fmt.Fprintf(&vocab, "%s 256\n", base64.StdEncoding.EncodeToString([]byte("ab")))
+	if err := os.WriteFile(filepath.Join(dir, "tiny.tiktoken"), []byte(vocab.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reg := NewTokenizerRegistry(TokenizerConfig{
+		Families: []TokenizerFamily{
+			{Name: "qwen", Match: []string{"qwen"}, Encoding: "tiny"},
+			{Name: "qwen-long", Match: []string{"qwen-long"}, Encoding: EncodingChars},
+			{Name: "broken", Match: []string{"broken"}, Encoding: "missing"},
+		},
+	}, dir, nil, nil)
+
+	if f := reg.Family("Qwen-Max"); f.Name != "qwen" {
+		t.Errorf("Family(Qwen-Max) = %q, want qwen", f.Name)
+	}
+	if f := reg.Family("qwen-long-1"); f.Name != "qwen-long" {
+		t.Errorf("Family(qwen-long-1) = %q, want longest match qwen-long", f.Name)
+	}
+	if f := reg.Family("gpt-4o"); f.Name != "default" {
+		t.Errorf("Family(gpt-4o) = %q, want default", f.Name)
+	}
+
+	if got := reg.For("qwen-max").Estimate("abab"); got != 2 {
+		t.Errorf("bpe Estimate = %d, want 2", got)
+	}
+	if got := reg.For("qwen-long").Estimate("abcdefgh"); got != 2 {
+		t.Errorf("chars Estimate = %d, want 2", got)
+	}
+	// A missing vocabulary falls back to the heuristic estimator.
+	if got, want := reg.For("broken").Estimate("今天天气"), mindxses.NewHeuristicEstimator().Estimate("今天天气"); got != want {
+		t.Errorf("fallback Estimate = %d, want %d", got, want)
+	}
+	if reg.Calibrating() {
+		t.Error("registry without calibration store should not calibrate")
+	}
+}
+
+func TestTokenizerRegistryCalibrates(t *testing.T) {
+	cal, err := mindxses.NewTokenCalibration(filepath.Join(t.TempDir(), "cal.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := NewTokenizerRegistry(TokenizerConfig{
+		Calibrate: true,
+		Default:   EncodingChars,
+	}, t.TempDir(), cal, nil)
+
+	for i := 0; i < 3; i++ {
+		reg.Observe("any-model", 1000, 1500)
+	}
+	text := strings.Repeat("x", 400)
+	if got := reg.Base("any-model").Estimate(text); got != 100 {
+		t.Errorf("Base Estimate = %d, want 100", got)
+	}
+	if got := reg.For("any-model").Estimate(text); got != 150 {
+		t.Errorf("calibrated Estimate = %d, want 150", got)
+	}
+}
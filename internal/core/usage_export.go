@@ -0,0 +1,208 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	goharnesssession "github.com/DotNetAge/goharness/session"
+	mindxses "github.com/DotNetAge/mindx/pkg/session"
+)
+
+// UsageDimension is a record attribute token usage can be grouped by in a
+// chargeback report.
+type UsageDimension string
+
+const (
+	UsageByProject UsageDimension = "project_dir"
+	UsageByAgent   UsageDimension = "agent"
+	UsageByModel   UsageDimension = "model"
+	UsageBySource  UsageDimension = "source"
+	UsageBySession UsageDimension = "session"
+)
+
+// ParseUsageDimensions validates group-by names, dropping duplicates and
+// blanks while keeping their order.
+func ParseUsageDimensions(names []string) ([]UsageDimension, error) {
+	seen := make(map[UsageDimension]bool, len(names))
+	dims := make([]UsageDimension, 0, len(names))
+	for _, name := range names {
+		d := UsageDimension(strings.TrimSpace(name))
+		if d == "" || seen[d] {
+			continue
+		}
+		switch d {
+		case UsageByProject, UsageByAgent, UsageByModel, UsageBySource, UsageBySession:
+		default:
+			return nil, fmt.Errorf("unknown group-by dimension %q (allowed: project_dir, agent, model, source, session)", name)
+		}
+		seen[d] = true
+		dims = append(dims, d)
+	}
+	return dims, nil
+}
+
+// UsageRow is one token usage record enriched for export: the project
+// directory of its session and its cost at the price in force at its
+// timestamp. Cost and Currency are zero for models without a price.
+type UsageRow struct {
+	ID               string    `json:"id"`
+	Timestamp        time.Time `json:"timestamp"`
+	SessionID        string    `json:"session_id"`
+	ConversationID   string    `json:"conversation_id"`
+	ProjectDir       string    `json:"project_dir"`
+	Agent            string    `json:"agent"`
+	Model            string    `json:"model"`
+	Provider         string    `json:"provider"`
+	Source           string    `json:"source"`
+	PromptTokens     int       `json:"input_tokens"`
+	CompletionTokens int       `json:"output_tokens"`
+	CachedTokens     int       `json:"cached_tokens"`
+	TotalTokens      int       `json:"total_tokens"`
+	Cost             float64   `json:"cost"`
+	Currency         string    `json:"currency"`
+}
+
+// Dimension returns the row's value for a group-by dimension.
+func (r UsageRow) Dimension(d UsageDimension) string {
+	switch d {
+	case UsageByProject:
+		return r.ProjectDir
+	case UsageByAgent:
+		return r.Agent
+	case UsageByModel:
+		return r.Model
+	case UsageBySource:
+		return r.Source
+	case UsageBySession:
+		return r.SessionID
+	}
+	return ""
+}
+
+// NewUsageRow prices a record with costs at its timestamp. TotalTokens is
+// the chargeable count, prompt + completion - cached, as in token.usage
+// reports.
+func NewUsageRow(r goharnesssession.TokenUsageRecord, source, projectDir string, costs *CostRegistry) UsageRow {
+	total := r.PromptTokens + r.CompletionTokens - r.CachedTokens
+	if total < 0 {
+		total = 0
+	}
+	row := UsageRow{
+		ID:               r.ID,
+		Timestamp:        r.Timestamp,
+		SessionID:        r.SessionID,
+		ConversationID:   r.ConversationID,
+		ProjectDir:       projectDir,
+		Agent:            r.AgentName,
+		Model:            r.ModelName,
+		Provider:         r.ProviderName,
+		Source:           source,
+		PromptTokens:     r.PromptTokens,
+		CompletionTokens: r.CompletionTokens,
+		CachedTokens:     r.CachedTokens,
+		TotalTokens:      total,
+	}
+	if costs != nil {
+		if mc, ok := costs.PriceAt(r.ModelName, r.ProviderName, r.Timestamp); ok {
+			row.Cost = CalculateCost(mc, int64(r.PromptTokens), int64(r.CompletionTokens), int64(r.CachedTokens))
+			row.Currency = mc.CurrencyCode()
+		}
+	}
+	return row
+}
+
+// UsageRows returns the token usage recorded in [since, until) as export
+// rows, oldest first. A zero bound is open. Usage that came with imported
+// sessions is left out unless includeImported is set.
+func (a *App) UsageRows(ctx context.Context, since, until time.Time, includeImported bool) ([]UsageRow, error) {
+	if a.tokenUsageStore == nil {
+		return nil, nil
+	}
+	records, err := a.tokenUsageStore.QueryWithSource(ctx, goharnesssession.TokenUsageFilter{Since: since, Until: until})
+	if err != nil {
+		return nil, fmt.Errorf("query token usage: %w", err)
+	}
+
+	projects := make(map[string]string)
+	rows := make([]UsageRow, 0, len(records))
+	for _, r := range records {
+		if r.Source == mindxses.UsageSourceImport && !includeImported {
+			continue
+		}
+		dir, ok := projects[r.SessionID]
+		if !ok && r.SessionID != "" && a.sessDB != nil {
+			if meta, err := a.sessDB.GetMeta(ctx, r.SessionID); err == nil && meta != nil {
+				dir = meta.ProjectDir
+			}
+			projects[r.SessionID] = dir
+		}
+		rows = append(rows, NewUsageRow(r.TokenUsageRecord, string(r.Source), dir, a.costs))
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].Timestamp.Before(rows[j].Timestamp)
+	})
+	return rows, nil
+}
+
+// ChargebackLine is the usage of one group in a chargeback report. Cost
+// is summed per currency; Unpriced counts requests whose model has no
+// price and so add no cost.
+type ChargebackLine struct {
+	Group        map[string]string  `json:"group"`
+	Requests     int                `json:"requests"`
+	InputTokens  int                `json:"input_tokens"`
+	OutputTokens int                `json:"output_tokens"`
+	CachedTokens int                `json:"cached_tokens"`
+	TotalTokens  int                `json:"total_tokens"`
+	Cost         map[string]float64 `json:"cost_by_currency"`
+	Unpriced     int                `json:"unpriced_requests,omitempty"`
+}
+
+// Chargeback groups rows by the given dimensions, ordered by group
+// values. With no dimensions it returns a single line for all rows.
+func Chargeback(rows []UsageRow, dims []UsageDimension) []ChargebackLine {
+	index := make(map[string]int)
+	var lines []ChargebackLine
+	for _, r := range rows {
+		values := make([]string, len(dims))
+		for i, d := range dims {
+			values[i] = r.Dimension(d)
+		}
+		key := strings.Join(values, "\x00")
+		i, ok := index[key]
+		if !ok {
+			group := make(map[string]string, len(dims))
+			for j, d := range dims {
+				group[string(d)] = values[j]
+			}
+			i = len(lines)
+			index[key] = i
+			lines = append(lines, ChargebackLine{Group: group, Cost: map[string]float64{}})
+		}
+		line := &lines[i]
+		line.Requests++
+		line.InputTokens += r.PromptTokens
+		line.OutputTokens += r.CompletionTokens
+		line.CachedTokens += r.CachedTokens
+		line.TotalTokens += r.TotalTokens
+		if r.Currency == "" {
+			line.Unpriced++
+		} else {
+			line.Cost[r.Currency] += r.Cost
+		}
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		for _, d := range dims {
+			a, b := lines[i].Group[string(d)], lines[j].Group[string(d)]
+			if a != b {
+				return a < b
+			}
+		}
+		return false
+	})
+	return lines
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	goharnesssession "github.com/DotNetAge/goharness/session"
+)
+
+func TestParseUsageDimensions(t *testing.T) {
+	dims, err := ParseUsageDimensions([]string{"agent", " project_dir ", "", "agent"})
+	if err != nil {
+		t.Fatalf("ParseUsageDimensions: %v", err)
+	}
+	if len(dims) != 2 || dims[0] != UsageByAgent || dims[1] != UsageByProject {
+		t.Errorf("dims = %v, want [agent project_dir]", dims)
+	}
+
+	if _, err := ParseUsageDimensions([]string{"team"}); err == nil {
+		t.Error("unknown dimension should fail")
+	}
+}
+
+func TestNewUsageRow_PricesAtTimestamp(t *testing.T) {
+	reg := NewCostRegistry()
+	reg.Set("gpt", ModelCost{CostPer1MIn: 1, CostPer1MOut: 2})
+	change := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	reg.AddPrice(PricePoint{Model: "gpt", EffectiveFrom: change, ModelCost: ModelCost{CostPer1MIn: 10, CostPer1MOut: 20, Currency: "eur"}})
+
+	rec := goharnesssession.TokenUsageRecord{
+		ModelName: "gpt", AgentName: "coder", SessionID: "s1",
+		PromptTokens: 1_000_000, CompletionTokens: 1_000_000, CachedTokens: 500_000,
+	}
+
+	rec.Timestamp = change.Add(-time.Hour)
+	before := NewUsageRow(rec, "chat", "/p", reg)
+	if math.Abs(before.Cost-2.5) > 1e-9 || before.Currency != "USD" {
+		t.Errorf("before change: cost = %v %s, want 2.5 USD", before.Cost, before.Currency)
+	}
+	if before.TotalTokens != 1_500_000 || before.ProjectDir != "/p" || before.Agent != "coder" {
+		t.Errorf("before change: row = %+v", before)
+	}
+
+	rec.Timestamp = change
+	after := NewUsageRow(rec, "chat", "/p", reg)
+	if math.Abs(after.Cost-25) > 1e-9 || after.Currency != "EUR" {
+		t.Errorf("after change: cost = %v %s, want 25 EUR", after.Cost, after.Currency)
+	}
+
+	rec.ModelName = "unknown"
+	if row := NewUsageRow(rec, "chat", "", reg); row.Cost != 0 || row.Currency != "" {
+		t.Errorf("unpriced model: cost = %v %q, want 0", row.Cost, row.Currency)
+	}
+}
+
+func TestChargeback(t *testing.T) {
+	rows := []UsageRow{
+		{ProjectDir: "/b", Agent: "coder", TotalTokens: 10, PromptTokens: 8, CompletionTokens: 2, Cost: 1, Currency: "USD"},
+		{ProjectDir: "/a", Agent: "coder", TotalTokens: 20, Cost: 2, Currency: "USD"},
+		{ProjectDir: "/b", Agent: "coder", TotalTokens: 5, Cost: 3, Currency: "EUR"},
+		{ProjectDir: "/b", Agent: "writer", TotalTokens: 7},
+	}
+
+	lines := Chargeback(rows, []UsageDimension{UsageByProject, UsageByAgent})
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3", len(lines))
+	}
+	if lines[0].Group["project_dir"] != "/a" || lines[1].Group["agent"] != "coder" || lines[2].Group["agent"] != "writer" {
+		t.Errorf("lines not ordered by group: %+v", lines)
+	}
+	b := lines[1]
+	if b.Requests != 2 || b.TotalTokens != 15 || b.InputTokens != 8 {
+		t.Errorf("/b coder = %+v", b)
+	}
+	if b.Cost["USD"] != 1 || b.Cost["EUR"] != 3 {
+		t.Errorf("/b coder cost = %v, want 1 USD and 3 EUR", b.Cost)
+	}
+	if lines[2].Unpriced != 1 || len(lines[2].Cost) != 0 {
+		t.Errorf("/b writer = %+v, want one unpriced request", lines[2])
+	}
+
+	all := Chargeback(rows, nil)
+	if len(all) != 1 || all[0].Requests != 4 || all[0].TotalTokens != 42 {
+		t.Errorf("ungrouped = %+v, want one line of 4 requests", all)
+	}
+}
@@ -11,7 +11,7 @@ import (
 )
 
 // askEventHandlers groups the callback functions for common AskBuilder event
-// types that are shared between defaultHandler and executeScheduleCommand.
+// types that are shared between defaultHandler and executeScheduleStep.
 // A zero-value (nil) field means the event is omitted from the builder.
 type askEventHandlers struct {
 	Thinking           func(chunk string)
@@ -243,7 +243,7 @@ func newClientAskHandlers(
 	}
 }
 
-// ── Factory: broadcast event handlers (used by executeScheduleCommand) ──
+// ── Factory: broadcast event handlers (used by executeScheduleStep) ──
 
 // newBroadcastAskHandlers creates event handlers that broadcast AskBuilder
 // events to all connected clients via d.broadcastScheduleEvent.
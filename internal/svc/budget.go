@@ -0,0 +1,121 @@
+package svc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/DotNetAge/goharness/agents"
+	goharnesssession "github.com/DotNetAge/goharness/session"
+	"github.com/DotNetAge/mindx/internal/core"
+	"github.com/DotNetAge/mindx/internal/i18n"
+)
+
+// checkBudget evaluates the configured budgets for a request and
+// broadcasts budget_exceeded when any of them is over its limit. phase is
+// "start" before a request runs, "turn" after each of its LLM calls and
+// "memory" before a memory consolidation call.
+// Evaluation errors are logged and never block the request.
+func (d *Daemon) checkBudget(ctx context.Context, subject core.BudgetSubject, sessionID, phase string) *core.BudgetDecision {
+	decision, err := d.app.CheckBudgets(ctx, subject)
+	if err != nil {
+		d.logger.Warn("budget check failed", "session_id", sessionID, "error", err)
+		return &core.BudgetDecision{}
+	}
+	if len(decision.Exceeded) == 0 {
+		return decision
+	}
+
+	d.logger.Warn("budget exceeded",
+		"session_id", sessionID,
+		"agent", subject.Agent,
+		"model", subject.Model,
+		"phase", phase,
+		"action", decision.Action,
+		"budgets", budgetNames(decision),
+	)
+	if d.gw != nil {
+		d.gw.BroadcastNotification("budget_exceeded", map[string]any{
+			"session_id":  sessionID,
+			"agent":       subject.Agent,
+			"model":       subject.Model,
+			"provider":    subject.Provider,
+			"project_dir": subject.ProjectDir,
+			"phase":       phase,
+			"action":      decision.Action,
+			"downgrade":   decision.Model,
+			"budgets":     decision.Exceeded,
+		})
+	}
+	return decision
+}
+
+// applyBudget runs the start-of-request budget check for subject. It
+// returns the runtime and subject to run with — rt itself, or a runtime on
+// the downgrade model — plus the decision, which seeds budgetTurnGuard. A
+// blocking budget yields an error.
+func (d *Daemon) applyBudget(ctx context.Context, rt *agents.Runtime, subject core.BudgetSubject, sessionID string) (*agents.Runtime, core.BudgetSubject, *core.BudgetDecision, error) {
+	decision := d.checkBudget(ctx, subject, sessionID, "start")
+	switch {
+	case decision.Blocked():
+		return rt, subject, decision, fmt.Errorf(i18n.T("svc.event.budget.blocked"), budgetNames(decision))
+	case decision.Action == core.BudgetDowngrade:
+		downgraded, err := d.app.ResolveRuntimeForModel(subject.Agent, decision.Model)
+		if err != nil {
+			d.logger.Warn("budget downgrade failed, keeping current model",
+				"agent", subject.Agent, "model", decision.Model, "error", err)
+			return rt, subject, decision, nil
+		}
+		d.logger.Info("budget: downgraded request",
+			"session_id", sessionID, "agent", subject.Agent,
+			"from", subject.Model, "to", decision.Model)
+		subject.Model = decision.Model
+		if modelCfg := d.app.Models().Get(decision.Model); modelCfg != nil {
+			subject.Provider = modelCfg.Provider
+		}
+		return downgraded, subject, decision, nil
+	}
+	return rt, subject, decision, nil
+}
+
+// budgetTurnGuard returns a TokenUsageRecorded handler that re-checks the
+// budgets after every LLM call of a running request and cancels it once a
+// blocking budget is exceeded. A breach is broadcast at most once per
+// request, including one already reported by the start check. next, when
+// non-nil, is called first.
+func (d *Daemon) budgetTurnGuard(subject core.BudgetSubject, sessionID string, start *core.BudgetDecision, cancel context.CancelFunc, next func(goharnesssession.TokenUsageRecord)) func(goharnesssession.TokenUsageRecord) {
+	if len(d.app.Budgets()) == 0 {
+		return next
+	}
+	var reported atomic.Bool
+	reported.Store(start != nil && len(start.Exceeded) > 0)
+	return func(record goharnesssession.TokenUsageRecord) {
+		if next != nil {
+			next(record)
+		}
+		var decision *core.BudgetDecision
+		if reported.Load() {
+			var err error
+			if decision, err = d.app.CheckBudgets(context.Background(), subject); err != nil {
+				return
+			}
+		} else {
+			decision = d.checkBudget(context.Background(), subject, sessionID, "turn")
+			reported.Store(len(decision.Exceeded) > 0)
+		}
+		if decision.Blocked() {
+			d.logger.Warn("budget exceeded mid-request, cancelling",
+				"session_id", sessionID, "budgets", budgetNames(decision))
+			cancel()
+		}
+	}
+}
+
+func budgetNames(decision *core.BudgetDecision) string {
+	names := make([]string, 0, len(decision.Exceeded))
+	for _, st := range decision.Exceeded {
+		names = append(names, fmt.Sprintf("%q", st.Budget.Name))
+	}
+	return strings.Join(names, ", ")
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/DotNetAge/mindx/internal/i18n"
 	"github.com/DotNetAge/mindx/internal/mcp"
 	"github.com/DotNetAge/mindx/internal/update"
+	"github.com/DotNetAge/mindx/pkg/execctx"
 	"github.com/DotNetAge/mindx/pkg/indexing"
 	"github.com/DotNetAge/mindx/pkg/logging"
 	"github.com/DotNetAge/mindx/pkg/memory"
@@ -54,6 +55,12 @@ type Daemon struct {
 	schedulerDB  *scheduler.FileSchedulerStore
 	sharedMemory *memory.RAGMemory
 
+	// reembed tracks the running or last re-embedding migration.
+	reembed reembedJob
+
+	// usageExports holds the rows of token usage exports in progress.
+	usageExports usageExportCache
+
 	// knowledge-graph indexer (GraphIndexer)
 	graphIndexer    *goragindexer.GraphIndexer
 	graphIndexerErr error // init failure reason, exposed in KB handler errors
@@ -68,7 +75,6 @@ type Daemon struct {
 	addr          string
 	wsPath        string
 	logger        logging.Logger
-	execMu        sync.Mutex
 	clientCancels sync.Map
 
 	// activeSessions tracks live sessions by sessionID for FileModifyHook
@@ -224,11 +230,14 @@ func NewDaemon(app *core.App, addr, wsPath string, runtimeFS fs.FS) *Daemon {
 
 		// ── Shared Memory（对话记忆）─────────────────────────────
 		// Memory 仅为对话服务，基于 SemanticIndexer
+		decay := app.MemoryConsolidation().Decay()
 		sharedMem, memErr := memory.NewRAGMemoryFromConfig(memory.MemoryConfig{
-			AgentName: "_shared",
-			MemoryDir: filepath.Join(app.Settings().UserPreferences(), "memory"),
-			Embedder:  emb,
-			Logger:    logger,
+			AgentName:  "_shared",
+			MemoryDir:  filepath.Join(app.Settings().UserPreferences(), "memory"),
+			Embedder:   emb,
+			EmbedderID: app.EmbedderID(),
+			Logger:     logger,
+			Decay:      &decay,
 		})
 		if memErr != nil {
 			logger.Warn("failed to create shared RAG memory", "error", memErr)
@@ -318,6 +327,7 @@ func NewDaemon(app *core.App, addr, wsPath string, runtimeFS fs.FS) *Daemon {
 			}
 			d.gw.BroadcastNotification(method, info)
 		})
+		d.registerScheduleSinks()
 	}
 
 	// Initialize global KV store (bbolt)
@@ -551,6 +561,12 @@ func (d *Daemon) Start(ctx context.Context) error {
 	// ── 自动升级检查（启动时 + 每日一次） ─────────────────
 	go d.autoUpdateLoop(ctx)
 
+	// ── Session 保留策略：归档或删除过期 Session ─────────────
+	go d.sessionRetentionLoop(ctx)
+
+	// ── 记忆整理：合并相近记忆，归档衰减的记忆 ─────────────
+	go d.memoryConsolidationLoop(ctx)
+
 	// ── Hot-reload: watch agents/skills directories for file changes ──
 	d.hotReload = NewHotReloadWatcher(d.app, d.logger)
 	go func() {
@@ -568,6 +584,8 @@ func (d *Daemon) Start(ctx context.Context) error {
 	d.webServer.HandleFunc("/api/health", d.handleHealth)
 	// Register file download handler for binary file access.
 	d.webServer.HandleFunc("/api/fs/download", d.handleFSDownload)
+	// Register read-only shared session pages (session.share links).
+	d.webServer.HandleFunc(sessionSharePath, d.handleSessionShareView)
 
 	if err := d.webServer.Start(ctx); err != nil {
 		d.logger.Warn("WebUI server failed to start", "error", err)
@@ -1020,17 +1038,6 @@ func (d *Daemon) defaultHandler(msg *gateway.Message) {
 		return
 	}
 
-	// Wire FileModifyHook to look up sessions from the active sessions map.
-	// This enables file backup before Write/FileEdit tools execute.
-	rt.WithFileModifyTracker(func(sessionID string) (action.TrackFunc, bool) {
-		val, ok := d.activeSessions.Load(sessionID)
-		if !ok {
-			return nil, false
-		}
-		sess := val.(*goharnesssession.Session)
-		return sess.TrackModify, true
-	})
-
 	// NOTE: Old GrantCache / execution.resume non-blocking permission flow has
 	// been removed. Permission resumption now flows through the
 	// PermissionAllow / PermissionDeny magic words (see runtime.resolvePermissionMagicWord),
@@ -1044,6 +1051,25 @@ func (d *Daemon) defaultHandler(msg *gateway.Message) {
 		resolvedAgentName = d.app.CurrentAgentName()
 	}
 
+	// ── Budget check: block, or swap in the downgrade model's runtime ──
+	budgetSubject := d.app.BudgetSubject(resolvedAgentName, sessionID, "")
+	rt, budgetSubject, budgetDecision, err := d.applyBudget(context.Background(), rt, budgetSubject, sessionID)
+	if err != nil {
+		d.sendEvent(msg.ClientID, sessionID, gateway.RespError, i18n.T("svc.event.budget.exceeded"), err.Error())
+		return
+	}
+
+	// Wire FileModifyHook to look up sessions from the active sessions map.
+	// This enables file backup before Write/FileEdit tools execute.
+	rt.WithFileModifyTracker(func(sessionID string) (action.TrackFunc, bool) {
+		val, ok := d.activeSessions.Load(sessionID)
+		if !ok {
+			return nil, false
+		}
+		sess := val.(*goharnesssession.Session)
+		return sess.TrackModify, true
+	})
+
 	d.logger.Info("request start",
 		"client_id", msg.ClientID,
 		"session_id", sessionID,
@@ -1074,14 +1100,14 @@ func (d *Daemon) defaultHandler(msg *gateway.Message) {
 			goharnesssession.WithSummarizer(goharnesssession.NewLLMSummarizer(*modelCfg)),
 		)
 	}
+	// 会话的 project_dir：用于记忆元数据，并随 ctx 传给工具
+	projectDir := ""
+	if meta, metaErr := d.app.SessDB().GetMeta(context.Background(), sessionID); metaErr == nil && meta != nil {
+		projectDir = meta.ProjectDir
+	}
 	// 绑定 RAG 记忆存储，使压缩摘要持久化到 RAG indexer（浏览器可读）
 	if d.sharedMemory != nil {
-		// 获取会话的 project_dir 用于存储元数据
-		projectDir := ""
-		if meta, metaErr := d.app.SessDB().GetMeta(context.Background(), sessionID); metaErr == nil && meta != nil {
-			projectDir = meta.ProjectDir
-		}
-		sessOpts = append(sessOpts, goharnesssession.WithMemory(mindxses.NewRAGMemoryAdapter(d.sharedMemory, resolvedAgentName, projectDir)))
+		sessOpts = append(sessOpts, goharnesssession.WithMemory(mindxses.NewRAGMemoryAdapter(d.sharedMemory, resolvedAgentName, projectDir, d.app.MemoryPolicy(resolvedAgentName))))
 	}
 	s, err := goharnesssession.Load(context.Background(), sessionID, resolvedAgentName, d.app.SessDB(), d.logger, sessOpts...)
 	if err != nil {
@@ -1149,9 +1175,14 @@ func (d *Daemon) defaultHandler(msg *gateway.Message) {
 
 		// ── Build common event handlers via factory ──
 		emitter := newClientAskHandlers(d, gw, clientID, sid, withAgent, s, func() string { return currentAgentName })
+		emitter.TokenUsageRecorded = d.budgetTurnGuard(budgetSubject, sid, budgetDecision, cancel, emitter.TokenUsageRecorded)
+		emitter.TokenUsageRecorded = d.tokenCalibrationGuard(s, emitter.TokenUsageRecorded)
 
+		// As for scheduled runs, the tools resolve paths against the
+		// session's project rather than the daemon's working directory.
+		turnCtx := execctx.WithSessionID(execctx.WithProjectDir(ctx, projectDir), sid)
 		builder := rt.Ask(resolvedAgentName, content, s).
-			WithContext(ctx).
+			WithContext(turnCtx).
 			OnEvent(func(ev events.ReactEvent) {
 				currentAgentName = ev.AgentName
 			}).
@@ -1245,18 +1276,20 @@ func (d *Daemon) defaultHandler(msg *gateway.Message) {
 					}
 				}
 			}
+			data := map[string]any{
+				"window_tokens":        usage.WindowTokens,
+				"max_window_size":      usage.MaxWindowSize,
+				"usage_ratio":          usage.UsageRatio,
+				"message_count":        usage.MessageCount,
+				"cursor":               usage.Cursor,
+				"active_message_count": usage.ActiveMessageCount,
+				"total_actual_tokens":  usage.TotalActualTokens,
+				"total_cost":           usage.TotalCost,
+			}
+			d.applyWindowEstimate(data, s, float64(usage.MaxWindowSize))
 			d.gw.BroadcastNotification("context_usage", map[string]any{
 				"session_id": sid,
-				"data": map[string]any{
-					"window_tokens":        usage.WindowTokens,
-					"max_window_size":      usage.MaxWindowSize,
-					"usage_ratio":          usage.UsageRatio,
-					"message_count":        usage.MessageCount,
-					"cursor":               usage.Cursor,
-					"active_message_count": usage.ActiveMessageCount,
-					"total_actual_tokens":  usage.TotalActualTokens,
-					"total_cost":           usage.TotalCost,
-				},
+				"data":       data,
 			})
 		}
 
@@ -1338,9 +1371,25 @@ func (d *Daemon) resolveSessionID(clientProvided string, commandProvided string)
 // Scheduler Command Execution
 // ---------------------------------------------------------------------------
 
-func (d *Daemon) executeScheduleCommand(ctx context.Context, agent string, sessionID string, content string, projectDir string) error {
+// executeScheduleCommand runs a scheduled entry together with its
+// on_success / on_failure follow-ups, recorded as one logical run.
+func (d *Daemon) executeScheduleCommand(ctx context.Context, req scheduler.RunRequest) (*scheduler.RunResult, error) {
+	return scheduler.RunChain(ctx, req, d.executeScheduleStep)
+}
+
+// executeScheduleStep runs a single step of a scheduled run in its session.
+func (d *Daemon) executeScheduleStep(ctx context.Context, req scheduler.RunRequest) (*scheduler.RunResult, error) {
+	agent, content, projectDir := req.Agent, req.Content, req.ProjectDir
+	sessionID := req.SessionID
+	if sessionID == "" || sessionID == "new" {
+		sessionID = generateSessionID()
+	}
+
 	d.logger.Info("scheduled task: execution started",
 		"agent", agent,
+		"entry_id", req.EntryID,
+		"run_id", req.RunID,
+		"attempt", req.Attempt,
 		"session_id", sessionID,
 		"project_dir", projectDir,
 		"content_preview", truncate(content, 100),
@@ -1351,7 +1400,7 @@ func (d *Daemon) executeScheduleCommand(ctx context.Context, agent string, sessi
 		d.logger.Error("scheduled task: failed to resolve runtime", err,
 			"agent", agent,
 		)
-		return fmt.Errorf("resolve runtime for %q: %w", agent, err)
+		return nil, fmt.Errorf("resolve runtime for %q: %w", agent, err)
 	}
 
 	targetDir := projectDir
@@ -1365,37 +1414,50 @@ func (d *Daemon) executeScheduleCommand(ctx context.Context, agent string, sessi
 		}
 	}
 
-	s, err := goharnesssession.Load(context.Background(), sessionID, agent, d.app.SessDB(), d.logger)
+	// ── Budget check: a blocking budget fails the run before it starts ──
+	budgetSubject := d.app.BudgetSubject(agent, sessionID, targetDir)
+	rt, budgetSubject, budgetDecision, err := d.applyBudget(ctx, rt, budgetSubject, sessionID)
 	if err != nil {
-		return fmt.Errorf("scheduled task: load session %q: %w", sessionID, err)
+		return nil, fmt.Errorf("scheduled task: %w", err)
 	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	// Build AskBuilder with common event handlers (via factory).
-	emitter := newBroadcastAskHandlers(d, sessionID, agent)
-	ask := wireAskEvents(rt.Ask(agent, content, s).WithContext(ctx), emitter)
+	s, err := goharnesssession.Load(context.Background(), sessionID, agent, d.app.SessDB(), d.logger)
+	if err != nil {
+		return nil, fmt.Errorf("scheduled task: load session %q: %w", sessionID, err)
+	}
 
-	// ── Optional chdir for project directory ──
-	if targetDir != "" {
-		d.execMu.Lock()
-		originalCWD, _ := os.Getwd()
+	result := &scheduler.RunResult{
+		SessionID:    sessionID,
+		MessageStart: len(s.All()),
+	}
 
-		if err := os.Chdir(targetDir); err != nil {
-			d.execMu.Unlock()
-			d.logger.Warn("scheduled task: failed to chdir to project dir, using current dir",
-				"project_dir", targetDir, "error", err)
-		} else {
-			_ = os.Setenv("MINDX_PROJECT_DIR", targetDir)
-			_ = os.Setenv("MINDX_SESSION_ID", sessionID)
-			defer func() {
-				if restoreErr := os.Chdir(originalCWD); restoreErr != nil {
-					d.logger.Warn("scheduled task: failed to restore cwd", "original", originalCWD, "error", restoreErr)
-				}
-				_ = os.Unsetenv("MINDX_PROJECT_DIR")
-				_ = os.Unsetenv("MINDX_SESSION_ID")
-				d.execMu.Unlock()
-			}()
+	// Build AskBuilder with common event handlers (via factory), capturing
+	// the final answer and token usage for the run history.
+	emitter := newBroadcastAskHandlers(d, sessionID, agent)
+	broadcastAnswer := emitter.Answer
+	emitter.Answer = func(answer string) {
+		result.Output = answer
+		broadcastAnswer(answer)
+	}
+	broadcastSummary := emitter.ExecutionSummary
+	emitter.ExecutionSummary = func(data events.ExecutionSummaryData) {
+		result.Usage = scheduler.RunUsage{
+			PromptTokens:     data.TokensUsed.PromptTokens,
+			CompletionTokens: data.TokensUsed.CompletionTokens,
+			CachedTokens:     data.TokensUsed.CachedTokens,
+			ReasoningTokens:  data.TokensUsed.ReasoningTokens,
+			TotalTokens:      data.TokensUsed.TotalTokens,
 		}
+		broadcastSummary(data)
 	}
+	emitter.TokenUsageRecorded = d.budgetTurnGuard(budgetSubject, sessionID, budgetDecision, cancel, emitter.TokenUsageRecorded)
+	// The project directory and session travel with the context so the
+	// file and shell tools resolve paths per execution; concurrent runs in
+	// different projects never touch the process-wide CWD or environment.
+	ctx = execctx.WithSessionID(execctx.WithProjectDir(ctx, targetDir), sessionID)
+	ask := wireAskEvents(rt.Ask(agent, content, s).WithContext(ctx), emitter)
 
 	d.logger.Info("scheduled task: calling Runtime.Ask()",
 		"session_id", sessionID, "agent", agent)
@@ -1403,13 +1465,15 @@ func (d *Daemon) executeScheduleCommand(ctx context.Context, agent string, sessi
 	d.logger.Info("scheduled task: Runtime.Ask() returned",
 		"session_id", sessionID, "error", err)
 
+	result.MessageEnd = len(s.All())
+
 	if err != nil {
-		return fmt.Errorf("execute scheduled message for @%s (session: %s): %w", agent, sessionID, err)
+		return result, fmt.Errorf("execute scheduled message for @%s (session: %s): %w", agent, sessionID, err)
 	}
 
 	d.logger.Info("scheduled task: execution completed successfully",
 		"session_id", sessionID, "agent", agent)
-	return nil
+	return result, nil
 }
 
 func (d *Daemon) restoreSessionEnvironment(sessionID string) *goharnesssession.SessionInfo {
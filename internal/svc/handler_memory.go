@@ -3,6 +3,7 @@ package svc
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -10,6 +11,7 @@ import (
 
 	goharnessmemory "github.com/DotNetAge/goharness/memory"
 	goragcore "github.com/DotNetAge/gorag/v2/core"
+	"github.com/DotNetAge/mindx/pkg/memory"
 	"github.com/DotNetAge/mindx/pkg/rpc"
 )
 
@@ -35,6 +37,10 @@ func (d *Daemon) handleMemoryQuery(_ context.Context, params json.RawMessage) (a
 	if p.Query == "" {
 		return nil, fmt.Errorf("query is required")
 	}
+	mode, err := memory.ParseRetrieveMode(p.Mode)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mode %q: want vector, lexical or hybrid", p.Mode)
+	}
 
 	mem := d.sharedMemory
 	if mem == nil {
@@ -49,7 +55,7 @@ func (d *Daemon) handleMemoryQuery(_ context.Context, params json.RawMessage) (a
 		opts = append(opts, goharnessmemory.WithMinScore(p.MinScore))
 	}
 
-	chunks, err := mem.Retrieve(context.Background(), p.Query, opts...)
+	chunks, err := mem.RetrieveWithMode(context.Background(), p.Query, mode, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("memory query failed: %w", err)
 	}
@@ -68,6 +74,13 @@ func (d *Daemon) handleMemoryStore(_ context.Context, params json.RawMessage) (a
 	if p.Content == "" {
 		return nil, fmt.Errorf("content is required")
 	}
+	var scope memory.Scope
+	if p.Scope != "" {
+		var err error
+		if scope, err = memory.ParseScope(p.Scope); err != nil {
+			return nil, fmt.Errorf("invalid scope %q: want global, project, agent or session", p.Scope)
+		}
+	}
 
 	mem := d.sharedMemory
 	if mem == nil {
@@ -75,12 +88,15 @@ func (d *Daemon) handleMemoryStore(_ context.Context, params json.RawMessage) (a
 	}
 
 	chunk := goharnessmemory.MemoryChunk{
-		Summary:   p.Title,
-		Content:   p.Content,
-		Timestamp: time.Now(),
+		Summary:    p.Title,
+		Content:    p.Content,
+		AgentName:  p.AgentName,
+		ProjectDir: p.ProjectDir,
+		SessionID:  p.SessionID,
+		Timestamp:  time.Now(),
 	}
 
-	id, err := mem.Store(context.Background(), chunk)
+	id, err := mem.StoreScoped(context.Background(), chunk, scope)
 	if err != nil {
 		return nil, fmt.Errorf("memory store failed: %w", err)
 	}
@@ -143,6 +159,11 @@ func (d *Daemon) handleMemoryChunks(_ context.Context, params json.RawMessage) (
 
 	chunks := make([]rpc.ChunkItem, 0, len(hits))
 	for _, h := range hits {
+		// Sealed memory metadata is shown as stored when it cannot be
+		// opened.
+		if opened, err := memory.OpenHit(h); err == nil {
+			h = opened
+		}
 		parentID, _ := h.Metadata["parent_id"].(string)
 		mimeType, _ := h.Metadata["mime_type"].(string)
 		chunks = append(chunks, rpc.ChunkItem{
@@ -248,35 +269,40 @@ func (d *Daemon) handleMemoryGetChunks(_ context.Context, params json.RawMessage
 }
 
 // ---------------------------------------------------------------------------
-// memory.count — 获取 RAG 索引中的分块总数
+// memory.count — 获取 RAG 索引中的分块总数，可按 agent / project / session 范围统计
 // ---------------------------------------------------------------------------
 
-func (d *Daemon) handleMemoryCount(_ context.Context, _ json.RawMessage) (any, error) {
+func (d *Daemon) handleMemoryCount(ctx context.Context, params json.RawMessage) (any, error) {
+	var p rpc.MemoryCountParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+
 	mem := d.sharedMemory
 	if mem == nil {
 		return nil, fmt.Errorf("memory service not available (embedder not configured)")
 	}
 
-	indexer := mem.Semantic()
-	if indexer == nil {
-		return nil, fmt.Errorf("indexer not initialized")
-	}
-
-	count, err := indexer.Count(context.Background())
+	count, err := mem.Count(ctx, memory.MemoryFilter{
+		AgentName:  p.AgentName,
+		ProjectDir: p.ProjectDir,
+		SessionID:  p.SessionID,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("memory count failed: %w", err)
 	}
 
-	d.logger.Info("memory.count called", "count", count)
+	d.logger.Info("memory.count called", "count", count,
+		"agent_name", p.AgentName, "project_dir", p.ProjectDir, "session_id", p.SessionID)
 
 	return rpc.MemoryCountResult{Count: count}, nil
 }
 
 // ---------------------------------------------------------------------------
-// memory.list_by_session — 按会话 ID 列出所有 MemoryChunk（分页，按时间倒序）
+// memory.list_by_session — 按会话 ID 列出所有 MemoryChunk（按时间倒序）
 // ---------------------------------------------------------------------------
 
-func (d *Daemon) handleMemoryListBySession(_ context.Context, params json.RawMessage) (any, error) {
+func (d *Daemon) handleMemoryListBySession(ctx context.Context, params json.RawMessage) (any, error) {
 	var p rpc.MemoryListBySessionParams
 	if err := unmarshalParams(params, &p); err != nil {
 		return nil, err
@@ -290,50 +316,25 @@ func (d *Daemon) handleMemoryListBySession(_ context.Context, params json.RawMes
 		return nil, fmt.Errorf("memory service not available (embedder not configured)")
 	}
 
-	indexer := mem.Semantic()
-	if indexer == nil {
-		return nil, fmt.Errorf("indexer not initialized")
+	// 元数据索引按 session_id 定位，已按时间倒序（最新在前）
+	chunks, err := mem.List(ctx, memory.MemoryFilter{SessionID: p.SessionID}, 0)
+	if err != nil {
+		return nil, fmt.Errorf("list chunks failed: %w", err)
 	}
-
-	// 分页遍历所有 chunk，按 session_id 过滤
-	var matched []rpc.MemoryChunkItem
-	const pageSize = 200
-	offset := 0
-	for {
-		hits, err := indexer.List(context.Background(), offset, pageSize)
-		if err != nil {
-			return nil, fmt.Errorf("list chunks failed: %w", err)
-		}
-		if len(hits) == 0 {
-			break
-		}
-		for _, hit := range hits {
-			sessionID, _ := hit.Metadata["session_id"].(string)
-			if sessionID != p.SessionID {
-				continue
-			}
-			chunk := chunkHitToMemoryItem(hit)
-			if chunk != nil {
-				matched = append(matched, *chunk)
-			}
-		}
-		if len(hits) < pageSize {
-			break
+	matched := make([]rpc.MemoryChunkItem, 0, len(chunks))
+	for _, c := range chunks {
+		item := rpc.MemoryChunkItem{
+			ID:        c.ID,
+			Summary:   c.Summary,
+			Content:   c.Content,
+			SessionID: c.SessionID,
+			AgentName: c.AgentName,
+			Tags:      c.Tags,
 		}
-		offset += pageSize
-	}
-
-	// 按时间倒序（最新在前）
-	// The indexer may return hits in insertion order;
-	// we sort by timestamp descending.
-	// Since we don't import sort in this file, we'll
-	// do a simple slice sort inline.
-	for i := 0; i < len(matched); i++ {
-		for j := i + 1; j < len(matched); j++ {
-			if matched[i].Timestamp < matched[j].Timestamp {
-				matched[i], matched[j] = matched[j], matched[i]
-			}
+		if !c.Timestamp.IsZero() {
+			item.Timestamp = c.Timestamp.UnixMilli()
 		}
+		matched = append(matched, item)
 	}
 
 	d.logger.Info("memory.list_by_session called",
@@ -346,54 +347,6 @@ func (d *Daemon) handleMemoryListBySession(_ context.Context, params json.RawMes
 	}, nil
 }
 
-// chunkHitToMemoryItem converts a gorag Hit to a MemoryChunkItem.
-func chunkHitToMemoryItem(hit goragcore.Hit) *rpc.MemoryChunkItem {
-	item := &rpc.MemoryChunkItem{
-		ID:      hit.ID,
-		Content: hit.Content,
-	}
-
-	if hit.Metadata != nil {
-		if a, ok := hit.Metadata["agent_name"].(string); ok {
-			item.AgentName = a
-		}
-		if s, ok := hit.Metadata["session_id"].(string); ok {
-			item.SessionID = s
-		}
-		if s, ok := hit.Metadata["summary"].(string); ok && s != "" {
-			item.Summary = s
-		} else {
-			item.Summary = hit.Title
-		}
-		if t, ok := hit.Metadata["tags"]; ok {
-			switch v := t.(type) {
-			case []string:
-				item.Tags = v
-			case []any:
-				for _, tag := range v {
-					if s, ok := tag.(string); ok {
-						item.Tags = append(item.Tags, s)
-					}
-				}
-			}
-		}
-		if ts, ok := hit.Metadata["timestamp"]; ok {
-			switch v := ts.(type) {
-			case float64:
-				item.Timestamp = int64(v)
-			case int64:
-				item.Timestamp = v
-			}
-		}
-	}
-
-	if item.Summary == "" {
-		item.Summary = hit.Title
-	}
-
-	return item
-}
-
 // ---------------------------------------------------------------------------
 // memory.update — 更新一条 MemoryChunk
 // ---------------------------------------------------------------------------
@@ -459,6 +412,10 @@ func (d *Daemon) handleMemoryUpdate(_ context.Context, params json.RawMessage) (
 
 // hitToMemoryChunk converts a gorag Hit to a goharness MemoryChunk for update purposes.
 func hitToMemoryChunk(hit goragcore.Hit) *goharnessmemory.MemoryChunk {
+	hit, err := memory.OpenHit(hit)
+	if err != nil {
+		return nil
+	}
 	chunk := &goharnessmemory.MemoryChunk{
 		ID:      hit.ID,
 		Content: hit.Content,
@@ -512,3 +469,138 @@ func hitToMemoryChunk(hit goragcore.Hit) *goharnessmemory.MemoryChunk {
 
 	return chunk
 }
+
+// ---------------------------------------------------------------------------
+// memory.consolidate — 立即整理记忆（合并相近记忆，归档衰减的记忆）
+// ---------------------------------------------------------------------------
+
+// handleMemoryConsolidate runs a consolidation under the configured
+// policy now, whether or not the background job is enabled.
+func (d *Daemon) handleMemoryConsolidate(ctx context.Context, params json.RawMessage) (any, error) {
+	var p rpc.MemoryConsolidateParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	report, err := d.consolidateMemory(ctx, d.app.MemoryConsolidation(), p.DryRun)
+	if err != nil {
+		return nil, fmt.Errorf("memory consolidate failed: %w", err)
+	}
+
+	result := rpc.MemoryConsolidateResult{
+		Scanned:  report.Scanned,
+		Clusters: make([]rpc.MemoryClusterItem, 0, len(report.Clusters)),
+		Pruned:   report.Pruned,
+		DryRun:   report.DryRun,
+	}
+	if result.Pruned == nil {
+		result.Pruned = []string{}
+	}
+	for _, c := range report.Clusters {
+		result.Clusters = append(result.Clusters, rpc.MemoryClusterItem{
+			ID:         c.ID,
+			AgentName:  c.AgentName,
+			ProjectDir: c.ProjectDir,
+			Summary:    c.Summary,
+			MergedFrom: c.MergedFrom,
+		})
+	}
+
+	d.logger.Info("memory.consolidate called",
+		"dry_run", p.DryRun,
+		"clusters", len(result.Clusters),
+		"pruned", len(result.Pruned))
+
+	return result, nil
+}
+
+// ---------------------------------------------------------------------------
+// memory.archived / memory.restore — 查看与恢复归档的记忆
+// ---------------------------------------------------------------------------
+
+func (d *Daemon) handleMemoryArchived(_ context.Context, params json.RawMessage) (any, error) {
+	var p rpc.MemoryArchivedParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Limit <= 0 {
+		p.Limit = 50
+	}
+
+	mem := d.sharedMemory
+	if mem == nil {
+		return nil, fmt.Errorf("memory service not available (embedder not configured)")
+	}
+
+	archived, err := mem.Archived(p.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("list archived memories failed: %w", err)
+	}
+	items := make([]rpc.MemoryArchivedItem, 0, len(archived))
+	for _, a := range archived {
+		item := rpc.MemoryArchivedItem{
+			ID:         a.ID,
+			AgentName:  a.AgentName,
+			SessionID:  a.SessionID,
+			ProjectDir: a.ProjectDir,
+			Summary:    a.Summary,
+			Content:    a.Content,
+			Tags:       a.Tags,
+			Importance: a.Importance,
+			MergedFrom: a.MergedFrom,
+			Reason:     a.Reason,
+			MergedInto: a.MergedInto,
+			ArchivedAt: a.ArchivedAt.UnixMilli(),
+		}
+		if !a.Timestamp.IsZero() {
+			item.Timestamp = a.Timestamp.UnixMilli()
+		}
+		items = append(items, item)
+	}
+
+	return rpc.MemoryArchivedResult{Chunks: items, Count: len(items)}, nil
+}
+
+func (d *Daemon) handleMemoryRestore(ctx context.Context, params json.RawMessage) (any, error) {
+	var p rpc.MemoryRestoreParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.ID == "" {
+		return nil, fmt.Errorf("id is required")
+	}
+
+	mem := d.sharedMemory
+	if mem == nil {
+		return nil, fmt.Errorf("memory service not available (embedder not configured)")
+	}
+
+	restored, err := mem.Restore(ctx, p.ID)
+	if err != nil {
+		if errors.Is(err, goharnessmemory.ErrMemoryNotFound) {
+			return nil, fmt.Errorf("memory %q is not archived", p.ID)
+		}
+		return nil, fmt.Errorf("memory restore failed: %w", err)
+	}
+
+	d.logger.Info("memory.restore called", "id", p.ID, "reason", restored.Reason)
+
+	return map[string]string{"status": "ok", "id": p.ID, "merged_into": restored.MergedInto}, nil
+}
+
+// ---------------------------------------------------------------------------
+// memory.reembed / memory.reembed_status — 以当前 embedder 重建记忆与知识库向量
+// ---------------------------------------------------------------------------
+
+func (d *Daemon) handleMemoryReembed(ctx context.Context, _ json.RawMessage) (any, error) {
+	status, err := d.startReembed(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("memory reembed: %w", err)
+	}
+	d.logger.Info("memory.reembed started", "kb_queued", status.KBQueued, "pending", status.Pending)
+	return status, nil
+}
+
+func (d *Daemon) handleMemoryReembedStatus(ctx context.Context, _ json.RawMessage) (any, error) {
+	return d.reembedStatus(ctx), nil
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 
 	goharnessconfig "github.com/DotNetAge/goharness/config"
 	"github.com/DotNetAge/mindx/internal/core"
@@ -396,14 +397,19 @@ func (d *Daemon) handleModelUpdate(_ context.Context, params json.RawMessage) (a
 	}
 
 	if p.CostPer1MIn != nil || p.CostPer1MOut != nil {
-		mc := core.ModelCost{}
+		// A price change takes effect now: it is recorded as a dated price
+		// point so records made under the old price keep their cost.
+		mc, _ := d.app.Costs().Get(p.Name)
 		if p.CostPer1MIn != nil {
 			mc.CostPer1MIn = *p.CostPer1MIn
 		}
 		if p.CostPer1MOut != nil {
 			mc.CostPer1MOut = *p.CostPer1MOut
 		}
-		d.app.Costs().Set(p.Name, mc)
+		d.app.Costs().AddPrice(core.PricePoint{Model: p.Name, EffectiveFrom: time.Now(), ModelCost: mc})
+		if err := d.app.Costs().SavePricingFile(d.app.Settings().PricingFile()); err != nil {
+			d.logger.Warn("failed to save price schedule", "model", p.Name, "error", err)
+		}
 	}
 
 	return map[string]any{
@@ -17,8 +17,16 @@ func (r *RPCHandlerRegistry) handlers() map[string]gateway.MethodHandler {
 		"session.list":               r.daemon.handleSessionList,
 		"session.get":                r.daemon.handleSessionGet,
 		"session.meta":               r.daemon.handleSessionMeta,
+		"session.search":             r.daemon.handleSessionSearch,
 		"session.create":             r.daemon.handleSessionCreate,
 		"session.delete":             r.daemon.handleSessionDelete,
+		"session.fork":               r.daemon.handleSessionFork,
+		"session.export":             r.daemon.handleSessionExport,
+		"session.import":             r.daemon.handleSessionImport,
+		"session.star":               r.daemon.handleSessionStar,
+		"session.gc":                 r.daemon.handleSessionGC,
+		"session.share":              r.daemon.handleSessionShare,
+		"session.unshare":            r.daemon.handleSessionUnshare,
 		"session.confirm_files":      r.daemon.handleSessionConfirmFiles,
 		"session.rollback_files":     r.daemon.handleSessionRollbackFiles,
 		"session.context":            r.daemon.handleSessionContext,
@@ -33,6 +41,11 @@ func (r *RPCHandlerRegistry) handlers() map[string]gateway.MethodHandler {
 		"memory.chunks":              r.daemon.handleMemoryChunks,
 		"memory.get_chunks":          r.daemon.handleMemoryGetChunks,
 		"memory.count":               r.daemon.handleMemoryCount,
+		"memory.consolidate":         r.daemon.handleMemoryConsolidate,
+		"memory.archived":            r.daemon.handleMemoryArchived,
+		"memory.restore":             r.daemon.handleMemoryRestore,
+		"memory.reembed":             r.daemon.handleMemoryReembed,
+		"memory.reembed_status":      r.daemon.handleMemoryReembedStatus,
 		"agent.list":                 r.daemon.handleAgentList,
 		"agent.get":                  r.daemon.handleAgentGet,
 		"agent.create":               r.daemon.handleAgentCreate,
@@ -77,9 +90,13 @@ func (r *RPCHandlerRegistry) handlers() map[string]gateway.MethodHandler {
 		"token.usage.total":          r.daemon.handleTokenUsageTotal,
 		"token.usage.session":        r.daemon.handleTokenUsageSession,
 		"token.usage.session.detail": r.daemon.handleTokenUsageSessionDetail,
+		"token.usage.export":         r.daemon.handleTokenUsageExport,
+		"storage.rekey":              r.daemon.handleStorageRekey,
 		"schedule.list":              r.daemon.handleScheduleList,
 		"schedule.add":               r.daemon.handleScheduleAdd,
 		"schedule.del":               r.daemon.handleScheduleDelete,
+		"schedule.runs":              r.daemon.handleScheduleRuns,
+		"schedule.run.get":           r.daemon.handleScheduleRunGet,
 		"log.read":                   r.daemon.handleLogRead,
 		"log.clear":                  r.daemon.handleLogClear,
 		"log.count":                  r.daemon.handleLogCount,
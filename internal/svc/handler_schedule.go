@@ -3,6 +3,7 @@ package svc
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -49,9 +50,6 @@ func (d *Daemon) handleScheduleAdd(_ context.Context, params json.RawMessage) (a
 	if p.Content == "" {
 		return nil, fmt.Errorf("content is required")
 	}
-	if p.CronExpr == "" {
-		return nil, fmt.Errorf("cron_expr is required")
-	}
 
 	entry := &scheduler.ScheduleEntry{
 		ID:         uuid.NewString()[:8],
@@ -61,17 +59,94 @@ func (d *Daemon) handleScheduleAdd(_ context.Context, params json.RawMessage) (a
 		Content:    p.Content,
 		CronExpr:   p.CronExpr,
 		Enabled:    true,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+		Trigger:    scheduler.TriggerType(p.Trigger),
+		Every:      p.Every,
+		Timezone:   p.Timezone,
+		MaxRuns:    p.MaxRuns,
+
+		Timeout:      p.Timeout,
+		MaxRetries:   p.MaxRetries,
+		RetryBackoff: p.RetryBackoff,
+		Overlap:      scheduler.OverlapPolicy(p.Overlap),
+		CatchUp:      scheduler.CatchUpPolicy(p.CatchUp),
+		CatchUpMax:   p.CatchUpMax,
+
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := applyScheduleTimes(entry, p); err != nil {
+		return nil, err
+	}
+	for branch, raw := range map[string]json.RawMessage{"on_success": p.OnSuccess, "on_failure": p.OnFailure} {
+		if len(raw) == 0 {
+			continue
+		}
+		var step scheduler.ChainStep
+		if err := json.Unmarshal(raw, &step); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", branch, err)
+		}
+		if branch == "on_success" {
+			entry.OnSuccess = &step
+		} else {
+			entry.OnFailure = &step
+		}
+	}
+	if len(p.Sinks) > 0 {
+		if err := json.Unmarshal(p.Sinks, &entry.Sinks); err != nil {
+			return nil, fmt.Errorf("invalid sinks: %w", err)
+		}
+	}
+	if entry.Trigger == "" && entry.CronExpr == "" {
+		switch {
+		case !entry.RunAt.IsZero():
+			entry.Trigger = scheduler.TriggerAt
+		case entry.Every != "":
+			entry.Trigger = scheduler.TriggerEvery
+		default:
+			return nil, fmt.Errorf("cron_expr, run_at or every is required")
+		}
+	}
+	if err := entry.Validate(); err != nil {
+		return nil, err
+	}
+	if entry.Exhausted(time.Now()) {
+		return nil, fmt.Errorf("schedule never fires: trigger is already exhausted")
 	}
 
 	if err := d.schedulerDB.Save(context.Background(), entry); err != nil {
+		if errors.Is(err, scheduler.ErrNotApplied) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("save schedule failed: %w", err)
 	}
 
 	return entry, nil
 }
 
+// applyScheduleTimes parses the string timestamps of p into entry, using
+// the entry's time zone for values without an explicit offset.
+func applyScheduleTimes(entry *scheduler.ScheduleEntry, p rpc.ScheduleAddParams) error {
+	for _, f := range []struct {
+		name  string
+		value string
+		dst   *time.Time
+	}{
+		{"run_at", p.RunAt, &entry.RunAt},
+		{"start_at", p.StartAt, &entry.StartAt},
+		{"end_at", p.EndAt, &entry.EndAt},
+	} {
+		if f.value == "" {
+			continue
+		}
+		t, err := scheduler.ParseTime(f.value, entry.Timezone)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", f.name, err)
+		}
+		*f.dst = t
+	}
+	return nil
+}
+
 func (d *Daemon) handleScheduleDelete(_ context.Context, params json.RawMessage) (any, error) {
 	if d.schedulerDB == nil {
 		return nil, fmt.Errorf("scheduler not available")
@@ -92,3 +167,79 @@ func (d *Daemon) handleScheduleDelete(_ context.Context, params json.RawMessage)
 
 	return map[string]string{"status": "deleted", "id": p.ID}, nil
 }
+
+func (d *Daemon) handleScheduleRuns(_ context.Context, params json.RawMessage) (any, error) {
+	if d.schedulerDB == nil {
+		return nil, fmt.Errorf("scheduler not available")
+	}
+
+	var p rpc.ScheduleRunsParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.ID == "" {
+		return nil, fmt.Errorf("schedule id is required")
+	}
+
+	runs, err := d.schedulerDB.Runs().List(p.ID, p.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("list schedule runs failed: %w", err)
+	}
+	return runs, nil
+}
+
+// handleScheduleRunGet returns one run record together with the session
+// messages the run produced (its transcript).
+func (d *Daemon) handleScheduleRunGet(_ context.Context, params json.RawMessage) (any, error) {
+	if d.schedulerDB == nil {
+		return nil, fmt.Errorf("scheduler not available")
+	}
+
+	var p rpc.ScheduleRunGetParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.RunID == "" {
+		return nil, fmt.Errorf("run_id is required")
+	}
+
+	rec, err := d.schedulerDB.Runs().Get(p.RunID)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := d.runTranscript(rec.SessionID, rec.MessageStart, rec.MessageEnd)
+	if err != nil {
+		return nil, err
+	}
+	resp := map[string]any{
+		"run":      rec,
+		"messages": messages,
+	}
+
+	// Chained runs span several sessions; return each step's transcript.
+	if len(rec.Steps) > 1 {
+		steps := make([][]map[string]any, len(rec.Steps))
+		for i, step := range rec.Steps {
+			if steps[i], err = d.runTranscript(step.SessionID, step.MessageStart, step.MessageEnd); err != nil {
+				return nil, err
+			}
+		}
+		resp["step_messages"] = steps
+	}
+	return resp, nil
+}
+
+// runTranscript returns messages [start, end) of a session, enriched for display.
+func (d *Daemon) runTranscript(sessionID string, start, end int) ([]map[string]any, error) {
+	if sessionID == "" || end <= start {
+		return []map[string]any{}, nil
+	}
+	sess, err := d.getOrLoadSession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("get session %q failed: %w", sessionID, err)
+	}
+	all := sess.All()
+	start, end = min(start, len(all)), min(end, len(all))
+	return d.enrichMessages(all[start:end]), nil
+}
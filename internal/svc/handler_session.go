@@ -1,12 +1,16 @@
 package svc
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	goharnesssession "github.com/DotNetAge/goharness/session"
+	"github.com/DotNetAge/mindx/internal/core"
 	"github.com/DotNetAge/mindx/pkg/rpc"
 	mindxses "github.com/DotNetAge/mindx/pkg/session"
 )
@@ -37,9 +41,35 @@ func (d *Daemon) handleSessionList(_ context.Context, params json.RawMessage) (a
 		sessions = filtered
 	}
 
+	if p.Lineage {
+		return withLineage(sessions, sessDB.Lineages()), nil
+	}
 	return sessions, nil
 }
 
+// withLineage adds the fork lineage kept beside SessionInfo in meta.json
+// (parent_session_id, fork_message_index, child_session_ids) to each
+// session of a listing.
+func withLineage(sessions []goharnesssession.SessionInfo, lineages map[string]mindxses.SessionLineage) []map[string]any {
+	out := make([]map[string]any, len(sessions))
+	for i := range sessions {
+		data, _ := json.Marshal(sessions[i])
+		_ = json.Unmarshal(data, &out[i])
+		l, ok := lineages[sessions[i].SessionID]
+		if !ok {
+			continue
+		}
+		if l.ParentID != "" {
+			out[i]["parent_session_id"] = l.ParentID
+			out[i]["fork_message_index"] = l.ForkIndex
+		}
+		if len(l.Children) > 0 {
+			out[i]["child_session_ids"] = l.Children
+		}
+	}
+	return out
+}
+
 func (d *Daemon) handleSessionGet(_ context.Context, params json.RawMessage) (any, error) {
 	var p rpc.SessionGetParams
 	if err := unmarshalParams(params, &p); err != nil {
@@ -149,6 +179,215 @@ func (d *Daemon) handleSessionMeta(_ context.Context, params json.RawMessage) (a
 	return meta, nil
 }
 
+// handleSessionFork copies a session up to a message into a new session of
+// the same agent and project. Forks are exempt from the one session per
+// (agent, project_dir) rule of session.create: branching is their point.
+func (d *Daemon) handleSessionFork(ctx context.Context, params json.RawMessage) (any, error) {
+	var p rpc.SessionForkParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.SessionID == "" {
+		return nil, fmt.Errorf("session_id is required")
+	}
+
+	sessDB := d.app.SessDB()
+	if sessDB == nil {
+		return nil, fmt.Errorf("session store not available")
+	}
+
+	info, err := sessDB.Fork(ctx, p.SessionID, p.AtMessageIndex)
+	if err != nil {
+		return nil, fmt.Errorf("fork session %q failed: %w", p.SessionID, err)
+	}
+
+	d.logger.Info("session forked",
+		"session_id", info.SessionID,
+		"parent_session_id", p.SessionID,
+		"at_message_index", p.AtMessageIndex,
+	)
+
+	return map[string]any{
+		"session_id":         info.SessionID,
+		"parent_session_id":  p.SessionID,
+		"fork_message_index": p.AtMessageIndex,
+		"agent_name":         info.AgentName,
+		"project_dir":        info.ProjectDir,
+		"session_dir":        info.SessionDir,
+		"message_count":      info.MessageCount,
+	}, nil
+}
+
+func (d *Daemon) handleSessionSearch(ctx context.Context, params json.RawMessage) (any, error) {
+	var p rpc.SessionSearchParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(p.Query) == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+
+	sessDB := d.app.SessDB()
+	if sessDB == nil {
+		return nil, fmt.Errorf("session store not available")
+	}
+
+	since, err := parseExportDate(p.Since)
+	if err != nil {
+		return nil, fmt.Errorf("invalid since %q: %w", p.Since, err)
+	}
+	until, err := parseExportDate(p.Until)
+	if err != nil {
+		return nil, fmt.Errorf("invalid until %q: %w", p.Until, err)
+	}
+
+	hits, err := sessDB.Search(ctx, mindxses.SearchQuery{
+		Text:       p.Query,
+		Agent:      p.Agent,
+		ProjectDir: p.ProjectDir,
+		Since:      since,
+		Until:      until,
+		Roles:      p.Roles,
+		Limit:      p.Limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("search sessions failed: %w", err)
+	}
+	return hits, nil
+}
+
+// handleSessionExport renders a session as a bundle, a Markdown transcript
+// or OpenAI chat JSONL. The binary bundle is returned base64-encoded.
+func (d *Daemon) handleSessionExport(ctx context.Context, params json.RawMessage) (any, error) {
+	var p rpc.SessionExportParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.SessionID == "" {
+		return nil, fmt.Errorf("session_id is required")
+	}
+	if p.Format == "" {
+		p.Format = core.SessionFormatBundle
+	}
+	if d.app.SessDB() == nil {
+		return nil, fmt.Errorf("session store not available")
+	}
+
+	var buf bytes.Buffer
+	if err := d.app.SessionBundler().Export(ctx, p.SessionID, p.Format, &buf); err != nil {
+		return nil, fmt.Errorf("export session %q failed: %w", p.SessionID, err)
+	}
+
+	res := rpc.SessionExportResult{
+		Format:   p.Format,
+		Filename: core.ExportFileName(p.SessionID, p.Format),
+		Encoding: "utf-8",
+		Data:     buf.String(),
+	}
+	if p.Format == core.SessionFormatBundle {
+		res.Encoding = "base64"
+		res.Data = base64.StdEncoding.EncodeToString(buf.Bytes())
+	}
+	return res, nil
+}
+
+// handleSessionImport restores a session bundle as a new session. Like
+// session.fork it is not subject to the one-session-per-project rule of
+// session.create.
+func (d *Daemon) handleSessionImport(ctx context.Context, params json.RawMessage) (any, error) {
+	var p rpc.SessionImportParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Data == "" {
+		return nil, fmt.Errorf("data is required")
+	}
+	data, err := base64.StdEncoding.DecodeString(p.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data: %w", err)
+	}
+	if d.app.SessDB() == nil {
+		return nil, fmt.Errorf("session store not available")
+	}
+
+	res, err := d.app.SessionBundler().Import(ctx, bytes.NewReader(data), core.BundleImportOptions{
+		Agent:      p.Agent,
+		ProjectDir: p.ProjectDir,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("import session failed: %w", err)
+	}
+
+	d.logger.Info("session imported",
+		"session_id", res.SessionID,
+		"original_session_id", res.OriginalSessionID,
+		"agent", res.AgentName,
+		"messages", res.Messages,
+	)
+	return res, nil
+}
+
+func (d *Daemon) handleSessionStar(_ context.Context, params json.RawMessage) (any, error) {
+	var p rpc.SessionStarParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.SessionID == "" {
+		return nil, fmt.Errorf("session_id is required")
+	}
+
+	sessDB := d.app.SessDB()
+	if sessDB == nil {
+		return nil, fmt.Errorf("session store not available")
+	}
+	if err := sessDB.SetStarred(p.SessionID, p.Starred); err != nil {
+		return nil, fmt.Errorf("star session %q failed: %w", p.SessionID, err)
+	}
+	return map[string]any{
+		"session_id": p.SessionID,
+		"starred":    p.Starred,
+	}, nil
+}
+
+// handleSessionGC applies the session retention policy now, or with
+// dry_run reports what it would collect and how much space that frees.
+func (d *Daemon) handleSessionGC(ctx context.Context, params json.RawMessage) (any, error) {
+	var p rpc.SessionGCParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+	if d.app.SessDB() == nil {
+		return nil, fmt.Errorf("session store not available")
+	}
+
+	policy := d.app.SessionRetention()
+	if p.MaxAgeDays > 0 {
+		policy.MaxAgeDays = p.MaxAgeDays
+	}
+	if p.MaxPerAgent > 0 {
+		policy.MaxPerAgent = p.MaxPerAgent
+	}
+	if p.Action != "" {
+		policy.Action = core.RetentionAction(p.Action)
+	}
+	if !policy.Enabled() {
+		return nil, fmt.Errorf("no session retention policy: set session_retention in mindx.json or pass max_age_days / max_per_agent")
+	}
+
+	report, err := d.sessionJanitor().Run(ctx, policy, p.DryRun)
+	if err != nil {
+		return nil, fmt.Errorf("session gc failed: %w", err)
+	}
+	if !p.DryRun {
+		d.logger.Info("session gc complete",
+			"action", report.Action,
+			"collected", len(report.Sessions),
+			"reclaimed_bytes", report.ReclaimedBytes,
+		)
+	}
+	return report, nil
+}
+
 func (d *Daemon) handleSessionDelete(_ context.Context, params json.RawMessage) (any, error) {
 	var p rpc.SessionDeleteParams
 	if err := unmarshalParams(params, &p); err != nil {
@@ -351,7 +590,8 @@ func (d *Daemon) handleSessionRollbackFiles(_ context.Context, params json.RawMe
 }
 
 // handleSessionContext returns the current context window usage for a session.
-// The calculation is consistent with GoHarness's MicroCompact method (estimateWindowTokensV2).
+// window_tokens is estimated with the tokenizer of the agent's model (see
+// applyWindowEstimate); the other fields come from GoHarness.
 // It uses the session's maxWindowSize (if set) or falls back to the default model's context_length.
 func (d *Daemon) handleSessionContext(_ context.Context, params json.RawMessage) (any, error) {
 	var p rpc.SessionContextParams
@@ -385,7 +625,7 @@ func (d *Daemon) handleSessionContext(_ context.Context, params json.RawMessage)
 		}
 	}
 
-	return map[string]any{
+	result := map[string]any{
 		"session_id":           p.SessionID,
 		"window_tokens":        usage.WindowTokens,
 		"max_window_size":      usage.MaxWindowSize,
@@ -395,7 +635,9 @@ func (d *Daemon) handleSessionContext(_ context.Context, params json.RawMessage)
 		"active_message_count": usage.ActiveMessageCount,
 		"total_actual_tokens":  usage.TotalActualTokens,
 		"total_cost":           usage.TotalCost,
-	}, nil
+	}
+	d.applyWindowEstimate(result, sess, float64(usage.MaxWindowSize))
+	return result, nil
 }
 
 func (d *Daemon) handleSessionTruncate(ctx context.Context, params json.RawMessage) (any, error) {
@@ -502,7 +744,7 @@ func (d *Daemon) handleSessionCompact(ctx context.Context, params json.RawMessag
 
 	// 绑定 RAG 记忆存储，使压缩摘要持久化到 RAG indexer（浏览器可读）
 	if d.sharedMemory != nil {
-		sess.SetMemory(mindxses.NewRAGMemoryAdapter(d.sharedMemory, sess.AgentName(), sess.ProjectDir()))
+		sess.SetMemory(mindxses.NewRAGMemoryAdapter(d.sharedMemory, sess.AgentName(), sess.ProjectDir(), d.app.MemoryPolicy(sess.AgentName())))
 	}
 
 	d.logger.Info("session.compact: triggered",
@@ -626,7 +868,7 @@ func (d *Daemon) handleSessionCompact(ctx context.Context, params json.RawMessag
 		"usage_ratio", usage.UsageRatio,
 	)
 
-	return map[string]any{
+	result := map[string]any{
 		"session_id":           p.SessionID,
 		"mode":                 p.Mode,
 		"window_tokens":        usage.WindowTokens,
@@ -637,5 +879,7 @@ func (d *Daemon) handleSessionCompact(ctx context.Context, params json.RawMessag
 		"active_message_count": usage.ActiveMessageCount,
 		"total_actual_tokens":  usage.TotalActualTokens,
 		"total_cost":           usage.TotalCost,
-	}, nil
+	}
+	d.applyWindowEstimate(result, sess, float64(usage.MaxWindowSize))
+	return result, nil
 }
@@ -0,0 +1,434 @@
+package svc
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	goharnesssession "github.com/DotNetAge/goharness/session"
+	"github.com/DotNetAge/mindx/pkg/rpc"
+	"go.etcd.io/bbolt"
+)
+
+// ---------------------------------------------------------------------------
+// Session sharing: read-only replay links served by the WebUI server
+//
+//	GET /share/<token>
+//
+// Share tokens live in their own bucket of the KV store, so kv.clear and
+// kv.list never touch them. Each entry is a kvItem keyed by the token
+// whose value is a sessionShare; expired entries are dropped when they
+// are looked up and whenever a new link is created.
+// ---------------------------------------------------------------------------
+
+const (
+	sessionShareBucket = "session_shares"
+	sessionSharePath   = "/share/"
+
+	defaultShareTTL = 24 * time.Hour
+	maxShareTTL     = 30 * 24 * time.Hour
+)
+
+// sessionShare is the value stored for a share token.
+type sessionShare struct {
+	SessionID string `json:"session_id"`
+}
+
+func (d *Daemon) handleSessionShare(_ context.Context, params json.RawMessage) (any, error) {
+	var p rpc.SessionShareParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.SessionID == "" {
+		return nil, fmt.Errorf("session_id is required")
+	}
+	ttl := time.Duration(p.TTL) * time.Second
+	switch {
+	case p.TTL < 0:
+		return nil, fmt.Errorf("ttl must not be negative")
+	case ttl == 0:
+		ttl = defaultShareTTL
+	case ttl > maxShareTTL:
+		return nil, fmt.Errorf("ttl must be at most %d seconds (30 days)", int(maxShareTTL.Seconds()))
+	}
+
+	sessDB := d.app.SessDB()
+	if sessDB == nil {
+		return nil, fmt.Errorf("session store not available")
+	}
+	if _, err := sessDB.GetSessionMeta(p.SessionID); err != nil {
+		return nil, fmt.Errorf("session %q: %w", p.SessionID, err)
+	}
+	if d.kvStore == nil {
+		return nil, fmt.Errorf("kvstore not initialized")
+	}
+
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("generate share token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	now := time.Now()
+	item, err := json.Marshal(kvItem{
+		Key:       token,
+		Value:     sessionShare{SessionID: p.SessionID},
+		CreatedAt: now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = d.kvStore.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(sessionShareBucket))
+		if err != nil {
+			return err
+		}
+		if err := deleteShares(b, "", func(it *kvItem, _ string) bool {
+			return it.ExpiresAt > 0 && now.Unix() > it.ExpiresAt
+		}); err != nil {
+			return err
+		}
+		return b.Put([]byte(token), item)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("store share token: %w", err)
+	}
+
+	base := strings.TrimRight(p.BaseURL, "/")
+	if base == "" {
+		base = d.webServer.URL()
+	}
+	d.logger.Info("session shared", "session_id", p.SessionID, "expires_in", ttl.String())
+	return rpc.SessionShareResult{
+		SessionID: p.SessionID,
+		Token:     token,
+		URL:       base + sessionSharePath + token,
+		ExpiresAt: now.Add(ttl).Unix(),
+	}, nil
+}
+
+func (d *Daemon) handleSessionUnshare(_ context.Context, params json.RawMessage) (any, error) {
+	var p rpc.SessionUnshareParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Token == "" && p.SessionID == "" {
+		return nil, fmt.Errorf("token or session_id is required")
+	}
+	if d.kvStore == nil {
+		return nil, fmt.Errorf("kvstore not initialized")
+	}
+
+	revoked := 0
+	err := d.kvStore.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(sessionShareBucket))
+		if b == nil {
+			return nil
+		}
+		return deleteShares(b, p.Token, func(_ *kvItem, sessionID string) bool {
+			// A token and a session ID together must both match.
+			if p.SessionID != "" && sessionID != p.SessionID {
+				return false
+			}
+			revoked++
+			return true
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("revoke share: %w", err)
+	}
+	if revoked == 0 {
+		return nil, fmt.Errorf("no matching share link")
+	}
+
+	d.logger.Info("session unshared", "session_id", p.SessionID, "revoked", revoked)
+	return map[string]any{"status": "ok", "revoked": revoked}, nil
+}
+
+// deleteShares deletes the share entries of b for which match returns
+// true. With a token only that entry is considered.
+func deleteShares(b *bbolt.Bucket, token string, match func(item *kvItem, sessionID string) bool) error {
+	consider := func(k, v []byte) bool {
+		item := decodeKVItem(string(k), v)
+		return match(item, shareSessionID(item))
+	}
+	if token != "" {
+		v := b.Get([]byte(token))
+		if v == nil || !consider([]byte(token), v) {
+			return nil
+		}
+		return b.Delete([]byte(token))
+	}
+
+	var doomed [][]byte
+	if err := b.ForEach(func(k, v []byte) error {
+		if consider(k, v) {
+			doomed = append(doomed, append([]byte(nil), k...))
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, k := range doomed {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shareSessionID returns the session a share entry points at.
+func shareSessionID(item *kvItem) string {
+	data, _ := json.Marshal(item.Value)
+	var s sessionShare
+	_ = json.Unmarshal(data, &s)
+	return s.SessionID
+}
+
+// lookupShare resolves a share token to its session ID. Expired tokens
+// are deleted and reported as not found.
+func (d *Daemon) lookupShare(token string) (string, bool) {
+	if d.kvStore == nil || token == "" {
+		return "", false
+	}
+	var item *kvItem
+	_ = d.kvStore.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(sessionShareBucket))
+		if b == nil {
+			return nil
+		}
+		if v := b.Get([]byte(token)); v != nil {
+			item = decodeKVItem(token, v)
+		}
+		return nil
+	})
+	if item == nil {
+		return "", false
+	}
+	if item.ExpiresAt > 0 && time.Now().Unix() > item.ExpiresAt {
+		_ = d.kvStore.Update(func(tx *bbolt.Tx) error {
+			if b := tx.Bucket([]byte(sessionShareBucket)); b != nil {
+				return b.Delete([]byte(token))
+			}
+			return nil
+		})
+		return "", false
+	}
+	id := shareSessionID(item)
+	return id, id != ""
+}
+
+// handleSessionShareView renders a shared session as a read-only page.
+// Unknown, revoked and expired tokens all get the same 404.
+func (d *Daemon) handleSessionShareView(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'")
+
+	token := strings.TrimPrefix(r.URL.Path, sessionSharePath)
+	if strings.Contains(token, "/") {
+		token = ""
+	}
+	sessionID, ok := d.lookupShare(token)
+	sessDB := d.app.SessDB()
+	if !ok || sessDB == nil {
+		http.Error(w, "this share link does not exist or has expired", http.StatusNotFound)
+		return
+	}
+
+	meta, err := sessDB.GetMeta(r.Context(), sessionID)
+	if err != nil {
+		http.Error(w, "this share link does not exist or has expired", http.StatusNotFound)
+		return
+	}
+	msgs, err := sessDB.Get(r.Context(), sessionID)
+	if err != nil {
+		d.logger.Error("share view: read session failed", err, "session_id", sessionID)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := shareTemplate.Execute(&buf, d.buildSharePage(meta, msgs)); err != nil {
+		d.logger.Error("share view: render failed", err, "session_id", sessionID)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(buf.Bytes())
+}
+
+type sharePage struct {
+	Title     string
+	Agent     string
+	CreatedAt string
+	Messages  []shareMessage
+	Diffs     []shareDiff
+}
+
+type shareMessage struct {
+	Index     int
+	Role      string
+	Time      string
+	Reasoning string
+	Content   string
+	ToolName  string // tool results: the name of the tool that produced them
+	ToolCalls []shareToolCall
+}
+
+type shareToolCall struct {
+	Name      string
+	Arguments string
+}
+
+type shareDiff struct {
+	Path      string
+	Additions int
+	Deletions int
+	Lines     []shareDiffLine
+}
+
+type shareDiffLine struct {
+	Kind string // "add", "del", "hunk" or ""
+	Text string
+}
+
+// buildSharePage collects everything the share page shows: every message,
+// compacted ones included, the tool calls and their results, and the
+// diffs of files changed in the session. Project paths are shown relative
+// to the project directory so the page does not expose the local layout.
+func (d *Daemon) buildSharePage(meta *goharnesssession.SessionInfo, msgs []goharnesssession.Message) sharePage {
+	page := sharePage{Title: meta.Title, Agent: meta.AgentName}
+	if page.Title == "" {
+		page.Title = meta.SessionID
+	}
+	if !meta.CreatedAt.IsZero() {
+		page.CreatedAt = meta.CreatedAt.Format("2006-01-02 15:04")
+	}
+
+	toolNames := map[string]string{}
+	for i, m := range msgs {
+		sm := shareMessage{Index: i, Role: m.Role, Reasoning: strings.TrimSpace(m.ReasoningContent), Content: m.Content}
+		if m.Timestamp > 0 {
+			sm.Time = time.UnixMilli(m.Timestamp).Format("2006-01-02 15:04:05")
+		}
+		for _, tc := range m.ToolCalls {
+			toolNames[tc.ID] = tc.Name
+			args := tc.Arguments
+			var pretty bytes.Buffer
+			if json.Indent(&pretty, []byte(args), "", "  ") == nil {
+				args = pretty.String()
+			}
+			sm.ToolCalls = append(sm.ToolCalls, shareToolCall{Name: tc.Name, Arguments: args})
+		}
+		if m.Role == "tool" {
+			sm.ToolName = toolNames[m.ToolCallID]
+		}
+		page.Messages = append(page.Messages, sm)
+	}
+
+	sessionDir, err := d.app.SessDB().ResolveSessionDir(meta.SessionID)
+	versions := d.app.FileVersions()
+	if err != nil || versions == nil {
+		return page
+	}
+	files, _ := versions.ListFiles(sessionDir)
+	for _, f := range files {
+		before, err1 := versions.GetInitial(sessionDir, f)
+		after, err2 := versions.GetLatest(sessionDir, f)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		sd := shareDiff{Path: f}
+		if meta.ProjectDir != "" {
+			if rel, err := filepath.Rel(meta.ProjectDir, f); err == nil && !strings.HasPrefix(rel, "..") {
+				sd.Path = rel
+			}
+		}
+		sd.Additions, sd.Deletions = countDiffLines(before, after)
+		for _, line := range splitLines(buildUnifiedDiff(f, before, after)) {
+			kind := ""
+			switch {
+			case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+				continue
+			case strings.HasPrefix(line, "@@"):
+				kind = "hunk"
+			case strings.HasPrefix(line, "+"):
+				kind = "add"
+			case strings.HasPrefix(line, "-"):
+				kind = "del"
+			}
+			sd.Lines = append(sd.Lines, shareDiffLine{Kind: kind, Text: line})
+		}
+		page.Diffs = append(page.Diffs, sd)
+	}
+	return page
+}
+
+var shareTemplate = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex, nofollow">
+<title>{{.Title}} · MindX</title>
+<style>
+body { font: 14px/1.5 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #1f2328; background: #f6f8fa; margin: 0; }
+main { max-width: 960px; margin: 0 auto; padding: 24px 16px 64px; }
+header p { color: #59636e; margin: 4px 0 0; }
+.msg, .diff { background: #fff; border: 1px solid #d1d9e0; border-radius: 6px; margin: 16px 0; }
+.msg h2, .diff h3 { font-size: 13px; margin: 0; padding: 8px 12px; border-bottom: 1px solid #d1d9e0; background: #f6f8fa; }
+.msg h2 span, .diff h3 span { color: #59636e; font-weight: normal; }
+.user h2 { background: #ddf4ff; }
+.tool h2 { background: #fff8c5; }
+pre { margin: 0; padding: 12px; white-space: pre-wrap; word-break: break-word; font: 12px/1.45 ui-monospace, SFMono-Regular, Menlo, monospace; }
+.content { padding: 12px; white-space: pre-wrap; word-break: break-word; }
+details { border-top: 1px dashed #d1d9e0; }
+summary { cursor: pointer; padding: 6px 12px; color: #59636e; }
+.diff pre { padding: 0; white-space: pre; overflow-x: auto; }
+.diff pre div { padding: 0 12px; }
+.add { background: #dafbe1; } .del { background: #ffebe9; } .hunk { color: #59636e; background: #ddf4ff; }
+footer { color: #59636e; font-size: 12px; text-align: center; }
+</style>
+</head>
+<body>
+<main>
+<header>
+<h1>{{.Title}}</h1>
+<p>Agent {{.Agent}}{{if .CreatedAt}} · {{.CreatedAt}}{{end}} · {{len .Messages}} messages · read-only shared view</p>
+</header>
+{{range .Messages}}
+<section class="msg {{.Role}}" id="m{{.Index}}">
+<h2>#{{.Index}} {{.Role}}{{if .ToolName}} · {{.ToolName}}{{end}}{{if .Time}} <span>{{.Time}}</span>{{end}}</h2>
+{{if .Reasoning}}<details><summary>Reasoning</summary><pre>{{.Reasoning}}</pre></details>{{end}}
+{{if eq .Role "tool"}}{{if .Content}}<pre>{{.Content}}</pre>{{end}}{{else if .Content}}<div class="content">{{.Content}}</div>{{end}}
+{{range .ToolCalls}}<details><summary>Tool call: {{.Name}}</summary><pre>{{.Arguments}}</pre></details>{{end}}
+</section>
+{{end}}
+{{if .Diffs}}<h2>Changed files</h2>{{end}}
+{{range .Diffs}}
+<section class="diff">
+<h3>{{.Path}} <span>+{{.Additions}} −{{.Deletions}}</span></h3>
+<pre>{{range .Lines}}<div class="{{.Kind}}">{{.Text}}</div>{{end}}</pre>
+</section>
+{{end}}
+<footer>Shared from MindX</footer>
+</main>
+</body>
+</html>
+`))
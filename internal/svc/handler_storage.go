@@ -0,0 +1,29 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/DotNetAge/mindx/internal/core"
+)
+
+// handleStorageRekey rotates the storage data key and rewrites sessions,
+// token usage, file versions and memory with it; with encryption off it
+// rewrites them in plaintext.
+func (d *Daemon) handleStorageRekey(ctx context.Context, _ json.RawMessage) (any, error) {
+	extra := map[string]core.Resealer{}
+	if d.sharedMemory != nil {
+		extra["memory"] = d.sharedMemory
+	}
+	report, err := d.app.RekeyStorage(ctx, extra)
+	if err != nil {
+		return nil, fmt.Errorf("storage rekey failed: %w", err)
+	}
+	d.logger.Info("storage rekey complete",
+		"encrypted", report.Encrypted,
+		"primary_key", report.PrimaryKey,
+		"retired_keys", len(report.RetiredKeys),
+	)
+	return report, nil
+}
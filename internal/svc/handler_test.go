@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
@@ -14,6 +16,7 @@ import (
 	"github.com/DotNetAge/goharness/logging"
 	goharnesssession "github.com/DotNetAge/goharness/session"
 	"github.com/DotNetAge/mindx/internal/core"
+	"github.com/DotNetAge/mindx/pkg/rpc"
 	mindxses "github.com/DotNetAge/mindx/pkg/session"
 )
 
@@ -169,6 +172,183 @@ func TestHandleSessionList_NilParams(t *testing.T) {
 	}
 }
 
+func TestHandleSessionFork_OK(t *testing.T) {
+	d, cleanup := newTestDaemon(t)
+	defer cleanup()
+
+	sessionID := mustCreateSession(t, d.app.SessDB(), "agent-alpha")
+
+	params, _ := json.Marshal(map[string]any{"session_id": sessionID, "at_message_index": 0})
+	result, err := d.handleSessionFork(context.Background(), params)
+	if err != nil {
+		t.Fatalf("handleSessionFork error = %v", err)
+	}
+	fork, ok := result.(map[string]any)
+	if !ok || fork["parent_session_id"] != sessionID || fork["session_id"] == sessionID {
+		t.Fatalf("unexpected fork result: %v", result)
+	}
+
+	params, _ = json.Marshal(map[string]any{"agent": "agent-alpha", "lineage": true})
+	result, err = d.handleSessionList(context.Background(), params)
+	if err != nil {
+		t.Fatalf("handleSessionList error = %v", err)
+	}
+	sessions, ok := result.([]map[string]any)
+	if !ok || len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions with lineage, got %v", result)
+	}
+	for _, s := range sessions {
+		if s["session_id"] == fork["session_id"] && s["parent_session_id"] != sessionID {
+			t.Errorf("fork listed without parent: %v", s)
+		}
+	}
+}
+
+func TestHandleSessionFork_OutOfRange(t *testing.T) {
+	d, cleanup := newTestDaemon(t)
+	defer cleanup()
+
+	sessionID := mustCreateSession(t, d.app.SessDB(), "agent-alpha")
+
+	params, _ := json.Marshal(map[string]any{"session_id": sessionID, "at_message_index": 5})
+	if _, err := d.handleSessionFork(context.Background(), params); err == nil {
+		t.Fatal("expected error for message index past the end")
+	}
+}
+
+func TestHandleSessionExportImport_RoundTrip(t *testing.T) {
+	d, cleanup := newTestDaemon(t)
+	defer cleanup()
+
+	sessionID := mustCreateSession(t, d.app.SessDB(), "agent-alpha")
+
+	params, _ := json.Marshal(map[string]any{"session_id": sessionID})
+	result, err := d.handleSessionExport(context.Background(), params)
+	if err != nil {
+		t.Fatalf("handleSessionExport error = %v", err)
+	}
+	export, ok := result.(rpc.SessionExportResult)
+	if !ok || export.Format != "bundle" || export.Encoding != "base64" || !strings.HasSuffix(export.Filename, ".mindx-session") {
+		t.Fatalf("unexpected export result: %v", result)
+	}
+
+	params, _ = json.Marshal(map[string]any{"data": export.Data, "agent": "agent-beta"})
+	result, err = d.handleSessionImport(context.Background(), params)
+	if err != nil {
+		t.Fatalf("handleSessionImport error = %v", err)
+	}
+	imported, ok := result.(*core.BundleImportResult)
+	if !ok || imported.OriginalSessionID != sessionID || imported.AgentName != "agent-beta" || imported.Messages != 1 {
+		t.Fatalf("unexpected import result: %v", result)
+	}
+
+	params, _ = json.Marshal(map[string]any{"session_id": sessionID, "format": "markdown"})
+	result, err = d.handleSessionExport(context.Background(), params)
+	if err != nil {
+		t.Fatalf("handleSessionExport markdown error = %v", err)
+	}
+	if md := result.(rpc.SessionExportResult); md.Encoding != "utf-8" || !strings.Contains(md.Data, "init") {
+		t.Errorf("unexpected markdown export: %+v", md)
+	}
+}
+
+func TestHandleSessionGC_DryRun(t *testing.T) {
+	d, cleanup := newTestDaemon(t)
+	defer cleanup()
+
+	if _, err := d.handleSessionGC(context.Background(), json.RawMessage(`{"dry_run":true}`)); err == nil {
+		t.Fatal("expected error without a retention policy")
+	}
+
+	older := mustCreateSession(t, d.app.SessDB(), "agent-alpha")
+	time.Sleep(10 * time.Millisecond)
+	mustCreateSession(t, d.app.SessDB(), "agent-alpha")
+
+	params, _ := json.Marshal(map[string]any{"dry_run": true, "max_per_agent": 1})
+	result, err := d.handleSessionGC(context.Background(), params)
+	if err != nil {
+		t.Fatalf("handleSessionGC error = %v", err)
+	}
+	report, ok := result.(*core.RetentionReport)
+	if !ok || len(report.Sessions) != 1 || report.Sessions[0].SessionID != older || report.ReclaimedBytes <= 0 {
+		t.Fatalf("unexpected gc report: %+v", result)
+	}
+
+	params, _ = json.Marshal(map[string]any{"session_id": older, "starred": true})
+	if _, err := d.handleSessionStar(context.Background(), params); err != nil {
+		t.Fatalf("handleSessionStar error = %v", err)
+	}
+	params, _ = json.Marshal(map[string]any{"dry_run": true, "max_per_agent": 1})
+	result, _ = d.handleSessionGC(context.Background(), params)
+	if report := result.(*core.RetentionReport); len(report.Sessions) != 0 || report.KeptStarred != 1 {
+		t.Errorf("starred session collected: %+v", report)
+	}
+}
+
+func TestHandleSessionShare_ViewAndRevoke(t *testing.T) {
+	d, cleanup := newTestDaemon(t)
+	defer cleanup()
+	if d.kvStore == nil {
+		t.Skip("kvstore not available")
+	}
+
+	sessionID := mustCreateSession(t, d.app.SessDB(), "agent-alpha")
+	view := func(token string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		d.handleSessionShareView(rec, httptest.NewRequest(http.MethodGet, sessionSharePath+token, nil))
+		return rec
+	}
+
+	params, _ := json.Marshal(map[string]any{"session_id": sessionID, "ttl": 60, "base_url": "http://devbox:1313/"})
+	result, err := d.handleSessionShare(context.Background(), params)
+	if err != nil {
+		t.Fatalf("handleSessionShare error = %v", err)
+	}
+	share := result.(rpc.SessionShareResult)
+	if share.URL != "http://devbox:1313/share/"+share.Token || share.ExpiresAt <= time.Now().Unix() {
+		t.Fatalf("unexpected share result: %+v", share)
+	}
+
+	rec := view(share.Token)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "init") {
+		t.Fatalf("share view = %d %q", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("share view should not be cached")
+	}
+	if rec := view("not-a-token"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown token = %d, want 404", rec.Code)
+	}
+
+	params, _ = json.Marshal(map[string]any{"session_id": sessionID})
+	if _, err := d.handleSessionUnshare(context.Background(), params); err != nil {
+		t.Fatalf("handleSessionUnshare error = %v", err)
+	}
+	if rec := view(share.Token); rec.Code != http.StatusNotFound {
+		t.Errorf("revoked token = %d, want 404", rec.Code)
+	}
+	if _, err := d.handleSessionUnshare(context.Background(), params); err == nil {
+		t.Error("expected error revoking a session without links")
+	}
+}
+
+func TestHandleSessionShare_Validation(t *testing.T) {
+	d, cleanup := newTestDaemon(t)
+	defer cleanup()
+
+	if _, err := d.handleSessionShare(context.Background(), json.RawMessage(`{}`)); err == nil {
+		t.Error("expected error without session_id")
+	}
+	sessionID := mustCreateSession(t, d.app.SessDB(), "agent-alpha")
+	params, _ := json.Marshal(map[string]any{"session_id": sessionID, "ttl": 31 * 24 * 3600})
+	if _, err := d.handleSessionShare(context.Background(), params); err == nil {
+		t.Error("expected error for a ttl over 30 days")
+	}
+	if _, err := d.handleSessionUnshare(context.Background(), json.RawMessage(`{}`)); err == nil {
+		t.Error("expected error without token or session_id")
+	}
+}
+
 // ==========================================================================
 // Session RPC Handlers — handleSessionGet
 // ==========================================================================
@@ -308,6 +488,17 @@ func TestHandleMemoryQuery_MissingQuery(t *testing.T) {
 	}
 }
 
+func TestHandleMemoryQuery_InvalidMode(t *testing.T) {
+	d, cleanup := newTestDaemon(t)
+	defer cleanup()
+
+	params, _ := json.Marshal(map[string]string{"query": "PROJ-1234", "mode": "bm25"})
+	_, err := d.handleMemoryQuery(context.Background(), params)
+	if err == nil || !strings.Contains(err.Error(), "invalid mode") {
+		t.Fatalf("err = %v, want invalid mode", err)
+	}
+}
+
 func TestHandleMemoryQuery_InvalidJSON(t *testing.T) {
 	d, cleanup := newTestDaemon(t)
 	defer cleanup()
@@ -340,6 +531,17 @@ func TestHandleMemoryStore_MissingContent(t *testing.T) {
 	}
 }
 
+func TestHandleMemoryStore_InvalidScope(t *testing.T) {
+	d, cleanup := newTestDaemon(t)
+	defer cleanup()
+
+	params, _ := json.Marshal(map[string]string{"content": "hello", "scope": "team"})
+	_, err := d.handleMemoryStore(context.Background(), params)
+	if err == nil || !strings.Contains(err.Error(), "invalid scope") {
+		t.Fatalf("err = %v, want invalid scope", err)
+	}
+}
+
 func TestHandleMemoryStore_InvalidJSON(t *testing.T) {
 	d, cleanup := newTestDaemon(t)
 	defer cleanup()
@@ -375,6 +577,67 @@ func TestHandleMemoryDelete_NilMemory(t *testing.T) {
 	}
 }
 
+func TestHandleMemoryCount_NilMemory(t *testing.T) {
+	d, cleanup := newTestDaemon(t)
+	defer cleanup()
+
+	params, _ := json.Marshal(map[string]string{"agent_name": "coder"})
+	if _, err := d.handleMemoryCount(context.Background(), params); err == nil {
+		t.Fatal("expected error when sharedMemory is nil")
+	}
+	if _, err := d.handleMemoryCount(context.Background(), json.RawMessage("{invalid")); err == nil {
+		t.Fatal("expected error for invalid JSON")
+	}
+}
+
+func TestHandleMemoryConsolidate_NilMemory(t *testing.T) {
+	d, cleanup := newTestDaemon(t)
+	defer cleanup()
+
+	params, _ := json.Marshal(map[string]bool{"dry_run": true})
+	if _, err := d.handleMemoryConsolidate(context.Background(), params); err == nil {
+		t.Fatal("expected error when sharedMemory is nil")
+	}
+	if _, err := d.handleMemoryArchived(context.Background(), nil); err == nil {
+		t.Fatal("expected error when sharedMemory is nil")
+	}
+	if _, err := d.handleMemoryRestore(context.Background(), json.RawMessage(`{}`)); err == nil || !strings.Contains(err.Error(), "id is required") {
+		t.Fatalf("restore without id: err = %v", err)
+	}
+}
+
+func TestHandleMemoryReembed_NilMemory(t *testing.T) {
+	d, cleanup := newTestDaemon(t)
+	defer cleanup()
+
+	if _, err := d.handleMemoryReembed(context.Background(), nil); err == nil {
+		t.Fatal("expected error when memory and knowledge base are unavailable")
+	}
+	result, err := d.handleMemoryReembedStatus(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("reembed_status: %v", err)
+	}
+	if st := result.(rpc.MemoryReembedStatus); st.Running || st.Pending {
+		t.Fatalf("status = %+v, want idle", st)
+	}
+}
+
+func TestSplitMergedMemory(t *testing.T) {
+	tests := []struct {
+		text, summary, content string
+	}{
+		{"Build setup\n\nUse make build; Go 1.23.", "Build setup", "Use make build; Go 1.23."},
+		{"# Build setup\nUse make build.\n", "Build setup", "Use make build."},
+		{"  only a title  ", "only a title", ""},
+	}
+	for _, tt := range tests {
+		summary, content := splitMergedMemory(tt.text)
+		if summary != tt.summary || content != tt.content {
+			t.Errorf("splitMergedMemory(%q) = %q, %q; want %q, %q", tt.text, summary, content, tt.summary, tt.content)
+		}
+	}
+}
+
 func TestHandleMemoryDelete_MissingID(t *testing.T) {
 	d, cleanup := newTestDaemon(t)
 	defer cleanup()
@@ -1079,3 +1342,115 @@ func TestHandleProviderDelete_InvalidJSON(t *testing.T) {
 		t.Fatal("expected error for invalid JSON")
 	}
 }
+
+// ==========================================================================
+// Token usage export
+// ==========================================================================
+
+func TestHandleTokenUsageExport_PagesFromSnapshot(t *testing.T) {
+	d, cleanup := newTestDaemon(t)
+	defer cleanup()
+
+	store := d.app.TokenUsageStore()
+	if store == nil {
+		t.Skip("no token usage store")
+	}
+	appendUsage := func(id string) {
+		t.Helper()
+		if err := store.Append(context.Background(), goharnesssession.TokenUsageRecord{
+			ID: id, SessionID: "s1", ModelName: "m", PromptTokens: 10, Timestamp: time.Now(),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []string{"u1", "u2", "u3"} {
+		appendUsage(id)
+	}
+
+	export := func(p rpc.TokenUsageExportParams) (map[string]any, error) {
+		params, _ := json.Marshal(p)
+		res, err := d.handleTokenUsageExport(context.Background(), params)
+		if err != nil {
+			return nil, err
+		}
+		return res.(map[string]any), nil
+	}
+
+	first, err := export(rpc.TokenUsageExportParams{Format: "jsonl", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor, _ := first["cursor"].(string)
+	if first["count"] != 2 || first["has_more"] != true || cursor == "" {
+		t.Fatalf("first page = %+v", first)
+	}
+
+	// Records appended mid-export do not shift or extend it.
+	appendUsage("u4")
+	second, err := export(rpc.TokenUsageExportParams{Format: "jsonl", Limit: 2, Offset: 2, Cursor: cursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second["count"] != 1 || second["has_more"] != false || second["total"] != 3 {
+		t.Errorf("second page = %+v", second)
+	}
+	if _, ok := second["summary"]; ok {
+		t.Error("a continued page repeats the summary")
+	}
+
+	if _, err := export(rpc.TokenUsageExportParams{Format: "jsonl", Offset: 2, Cursor: cursor}); err == nil {
+		t.Error("a finished export's cursor should be gone")
+	}
+}
+
+func TestUsageExportCacheEvictsOldest(t *testing.T) {
+	var c usageExportCache
+	first := c.put(nil)
+	second := c.put(nil)
+	// Reading the first export keeps it over the second.
+	time.Sleep(time.Millisecond)
+	if _, ok := c.get(first); !ok {
+		t.Fatal("first export missing")
+	}
+	for i := 2; i < maxUsageExports+1; i++ {
+		c.put(nil)
+	}
+	if len(c.exports) != maxUsageExports {
+		t.Errorf("exports = %d, want %d", len(c.exports), maxUsageExports)
+	}
+	if _, ok := c.get(second); ok {
+		t.Error("least recently read export not evicted")
+	}
+	if _, ok := c.get(first); !ok {
+		t.Error("recently read export evicted")
+	}
+}
+
+func TestCurrencyTotalsPut(t *testing.T) {
+	single := currencyTotals{}
+	single.add("USD", 0.5)
+	single.add("USD", 0.25)
+	out := map[string]any{}
+	single.put(out, "total_cost")
+	if out["total_cost"] != 0.75 || out["currency"] != "USD" {
+		t.Errorf("single currency = %+v", out)
+	}
+
+	mixed := currencyTotals{}
+	mixed.add("USD", 1)
+	mixed.add("CNY", 7)
+	out = map[string]any{}
+	mixed.put(out, "total_cost")
+	if _, ok := out["total_cost"]; ok {
+		t.Errorf("mixed currencies summed into total_cost: %+v", out)
+	}
+	if byCur := out["cost_by_currency"].(map[string]float64); byCur["USD"] != 1 || byCur["CNY"] != 7 {
+		t.Errorf("cost_by_currency = %v", byCur)
+	}
+
+	out = map[string]any{}
+	currencyTotals{}.put(out, "cost")
+	if out["cost"] != 0.0 {
+		t.Errorf("no cost = %+v", out)
+	}
+}
@@ -0,0 +1,317 @@
+package svc
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DotNetAge/mindx/internal/core"
+	"github.com/DotNetAge/mindx/pkg/rpc"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultExportLimit = 5000
+	maxExportLimit     = 50000
+
+	// usageExportTTL is how long an unfinished export keeps its rows
+	// between two pages.
+	usageExportTTL = 10 * time.Minute
+	// maxUsageExports caps the exports in progress; starting one more
+	// evicts the least recently read.
+	maxUsageExports = 8
+)
+
+// usageExportCache holds the rows of the exports in progress, so that an
+// export queries and sorts the usage once and serves every page from
+// that snapshot. At most maxUsageExports are kept. Its zero value is
+// ready to use.
+type usageExportCache struct {
+	mu      sync.Mutex
+	exports map[string]*usageExport
+}
+
+type usageExport struct {
+	rows    []core.UsageRow
+	expires time.Time
+}
+
+// put stores the rows of a new export and returns its cursor.
+func (c *usageExportCache) put(rows []core.UsageRow) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweep()
+	if c.exports == nil {
+		c.exports = make(map[string]*usageExport)
+	}
+	for len(c.exports) >= maxUsageExports {
+		c.evict()
+	}
+	id := uuid.New().String()
+	c.exports[id] = &usageExport{rows: rows, expires: time.Now().Add(usageExportTTL)}
+	return id
+}
+
+// get returns the rows of the export with cursor id and extends its
+// lifetime.
+func (c *usageExportCache) get(id string) ([]core.UsageRow, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweep()
+	e, ok := c.exports[id]
+	if !ok {
+		return nil, false
+	}
+	e.expires = time.Now().Add(usageExportTTL)
+	return e.rows, true
+}
+
+// drop forgets a finished export.
+func (c *usageExportCache) drop(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.exports, id)
+}
+
+// sweep drops the exports whose client went away. Caller holds mu.
+func (c *usageExportCache) sweep() {
+	now := time.Now()
+	for id, e := range c.exports {
+		if now.After(e.expires) {
+			delete(c.exports, id)
+		}
+	}
+}
+
+// evict drops the export read least recently. Caller holds mu.
+func (c *usageExportCache) evict() {
+	oldest := ""
+	for id, e := range c.exports {
+		if oldest == "" || e.expires.Before(c.exports[oldest].expires) {
+			oldest = id
+		}
+	}
+	delete(c.exports, oldest)
+}
+
+// exportColumns are the columns of every export format, in order.
+var exportColumns = []struct {
+	name string
+	typ  string
+}{
+	{"id", "string"},
+	{"timestamp", "timestamp"},
+	{"session_id", "string"},
+	{"conversation_id", "string"},
+	{"project_dir", "string"},
+	{"agent", "string"},
+	{"model", "string"},
+	{"provider", "string"},
+	{"source", "string"},
+	{"input_tokens", "int64"},
+	{"output_tokens", "int64"},
+	{"cached_tokens", "int64"},
+	{"total_tokens", "int64"},
+	{"cost", "double"},
+	{"currency", "string"},
+}
+
+// handleTokenUsageExport returns one page of the token usage recorded in a
+// date range, rendered as csv, jsonl or columnar JSON, and — on the first
+// page — a chargeback summary grouped by the requested dimensions. Clients
+// stream a large export by requesting pages from next_offset, passing
+// back the cursor of the first page, until has_more is false. The rows are
+// queried once, by the first page; later pages read them from
+// d.usageExports.
+func (d *Daemon) handleTokenUsageExport(ctx context.Context, params json.RawMessage) (any, error) {
+	var p rpc.TokenUsageExportParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Format == "" {
+		p.Format = "csv"
+	}
+	if p.Format != "csv" && p.Format != "jsonl" && p.Format != "columnar" {
+		return nil, fmt.Errorf("unknown format %q (allowed: csv, jsonl, columnar)", p.Format)
+	}
+	dims, err := core.ParseUsageDimensions(p.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+	since, err := parseExportDate(p.Since)
+	if err != nil {
+		return nil, fmt.Errorf("invalid since: %w", err)
+	}
+	until, err := parseExportDate(p.Until)
+	if err != nil {
+		return nil, fmt.Errorf("invalid until: %w", err)
+	}
+	if !since.IsZero() && !until.IsZero() && !until.After(since) {
+		return nil, fmt.Errorf("until must be after since")
+	}
+	if p.Offset < 0 {
+		return nil, fmt.Errorf("offset must not be negative")
+	}
+	limit := p.Limit
+	if limit <= 0 {
+		limit = defaultExportLimit
+	}
+	if limit > maxExportLimit {
+		limit = maxExportLimit
+	}
+
+	var rows []core.UsageRow
+	if p.Cursor != "" {
+		var ok bool
+		if rows, ok = d.usageExports.get(p.Cursor); !ok {
+			return nil, fmt.Errorf("export cursor %q is unknown or expired; restart the export", p.Cursor)
+		}
+	} else if rows, err = d.app.UsageRows(ctx, since, until, p.IncludeImported); err != nil {
+		return nil, err
+	}
+
+	start := min(p.Offset, len(rows))
+	end := min(start+limit, len(rows))
+	page := rows[start:end]
+
+	var data any
+	switch p.Format {
+	case "csv":
+		data, err = renderUsageCSV(page, start == 0)
+	case "jsonl":
+		data, err = renderUsageJSONL(page)
+	case "columnar":
+		data = renderUsageColumnar(page)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("render %s: %w", p.Format, err)
+	}
+
+	result := map[string]any{
+		"format":      p.Format,
+		"offset":      start,
+		"count":       len(page),
+		"total":       len(rows),
+		"has_more":    end < len(rows),
+		"next_offset": end,
+		"data":        data,
+	}
+	switch {
+	case p.Cursor == "" && end < len(rows):
+		result["cursor"] = d.usageExports.put(rows)
+	case p.Cursor != "" && end >= len(rows):
+		d.usageExports.drop(p.Cursor)
+	}
+	if p.Cursor == "" {
+		result["group_by"] = dims
+		result["summary"] = roundedChargeback(core.Chargeback(rows, dims))
+	}
+	return result, nil
+}
+
+// parseExportDate parses a local YYYY-MM-DD date or an RFC 3339 time. An
+// empty string is an open bound.
+func parseExportDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func exportValues(r core.UsageRow) []any {
+	return []any{
+		r.ID,
+		r.Timestamp.Format(time.RFC3339),
+		r.SessionID,
+		r.ConversationID,
+		r.ProjectDir,
+		r.Agent,
+		r.Model,
+		r.Provider,
+		r.Source,
+		r.PromptTokens,
+		r.CompletionTokens,
+		r.CachedTokens,
+		r.TotalTokens,
+		r.Cost,
+		r.Currency,
+	}
+}
+
+func renderUsageCSV(rows []core.UsageRow, header bool) (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if header {
+		names := make([]string, len(exportColumns))
+		for i, c := range exportColumns {
+			names[i] = c.name
+		}
+		if err := w.Write(names); err != nil {
+			return "", err
+		}
+	}
+	record := make([]string, len(exportColumns))
+	for _, r := range rows {
+		for i, v := range exportValues(r) {
+			switch v := v.(type) {
+			case float64:
+				record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+			default:
+				record[i] = fmt.Sprint(v)
+			}
+		}
+		if err := w.Write(record); err != nil {
+			return "", err
+		}
+	}
+	w.Flush()
+	return buf.String(), w.Error()
+}
+
+func renderUsageJSONL(rows []core.UsageRow) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range rows {
+		if err := enc.Encode(r); err != nil {
+			return "", err
+		}
+	}
+	return buf.String(), nil
+}
+
+// renderUsageColumnar lays rows out column by column, Parquet style: a
+// schema, a row count and one value array per column.
+func renderUsageColumnar(rows []core.UsageRow) map[string]any {
+	schema := make([]map[string]string, len(exportColumns))
+	columns := make(map[string][]any, len(exportColumns))
+	for i, c := range exportColumns {
+		schema[i] = map[string]string{"name": c.name, "type": c.typ}
+		columns[c.name] = make([]any, 0, len(rows))
+	}
+	for _, r := range rows {
+		for i, v := range exportValues(r) {
+			name := exportColumns[i].name
+			columns[name] = append(columns[name], v)
+		}
+	}
+	return map[string]any{
+		"schema":    schema,
+		"row_count": len(rows),
+		"columns":   columns,
+	}
+}
+
+func roundedChargeback(lines []core.ChargebackLine) []core.ChargebackLine {
+	for i := range lines {
+		lines[i].Cost = currencyTotals(lines[i].Cost).rounded()
+	}
+	return lines
+}
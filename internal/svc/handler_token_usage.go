@@ -9,6 +9,7 @@ import (
 	goharnesssession "github.com/DotNetAge/goharness/session"
 	"github.com/DotNetAge/mindx/internal/core"
 	"github.com/DotNetAge/mindx/pkg/rpc"
+	mindxses "github.com/DotNetAge/mindx/pkg/session"
 )
 
 func (d *Daemon) handleTokenUsageOverview(_ context.Context, params json.RawMessage) (any, error) {
@@ -68,13 +69,13 @@ func (d *Daemon) handleTokenUsageTotal(_ context.Context, _ json.RawMessage) (an
 	}
 
 	// Query all records (no time/session filter)
-	records, err := store.Query(context.Background(), goharnesssession.TokenUsageFilter{})
+	records, err := chargedUsage(store, goharnesssession.TokenUsageFilter{})
 	if err != nil {
 		return nil, fmt.Errorf("query all token usage: %w", err)
 	}
 
 	totalTokens := 0
-	totalCost := 0.0
+	byCurrency := currencyTotals{}
 	convSet := make(map[string]struct{})
 
 	for _, r := range records {
@@ -85,17 +86,17 @@ func (d *Daemon) handleTokenUsageTotal(_ context.Context, _ json.RawMessage) (an
 			key := r.SessionID + ":" + r.ConversationID
 			convSet[key] = struct{}{}
 		}
-		mc, hasMC := d.app.Costs().Get(r.ModelName)
-		if hasMC {
-			totalCost += calculateRecordCost(mc, r)
+		if cost, currency, ok := d.recordCost(r); ok {
+			byCurrency.add(currency, cost)
 		}
 	}
 
-	return map[string]any{
+	out := map[string]any{
 		"total_tokens":        totalTokens,
-		"total_cost":          roundCost(totalCost),
 		"total_conversations": len(convSet),
-	}, nil
+	}
+	byCurrency.put(out, "total_cost")
+	return out, nil
 }
 
 func (d *Daemon) handleTokenUsageSession(_ context.Context, params json.RawMessage) (any, error) {
@@ -124,21 +125,19 @@ func (d *Daemon) handleTokenUsageSession(_ context.Context, params json.RawMessa
 	}
 
 	totalTokens := 0
-	totalCost := 0.0
+	byCurrency := currencyTotals{}
 	for _, r := range records {
 		// Use chargeable tokens (prompt + completion - cached) to match the
 		// billing口径 used by monthly stats and ContextUsage.TotalActualTokens.
 		totalTokens += chargeableTokens(r)
-		mc, hasMC := d.app.Costs().Get(r.ModelName)
-		if hasMC {
-			totalCost += calculateRecordCost(mc, r)
+		if cost, currency, ok := d.recordCost(r); ok {
+			byCurrency.add(currency, cost)
 		}
 	}
 
-	return map[string]any{
-		"tokens_used": totalTokens,
-		"cost":        roundCost(totalCost),
-	}, nil
+	out := map[string]any{"tokens_used": totalTokens}
+	byCurrency.put(out, "cost")
+	return out, nil
 }
 
 func (d *Daemon) handleTokenUsageSessionDetail(_ context.Context, params json.RawMessage) (any, error) {
@@ -165,11 +164,7 @@ func (d *Daemon) handleTokenUsageSessionDetail(_ context.Context, params json.Ra
 
 	details := make([]any, 0, len(records))
 	for _, r := range records {
-		mc, hasMC := d.app.Costs().Get(r.ModelName)
-		cost := 0.0
-		if hasMC {
-			cost = calculateRecordCost(mc, r)
-		}
+		cost, currency, _ := d.recordCost(r)
 		details = append(details, map[string]any{
 			"timestamp":     r.Timestamp,
 			"input_tokens":  r.PromptTokens,
@@ -178,6 +173,7 @@ func (d *Daemon) handleTokenUsageSessionDetail(_ context.Context, params json.Ra
 			// total_tokens 与会话/月度汇总保持一致：计费口径 prompt + completion - cached
 			"total_tokens":  chargeableTokens(r),
 			"cost":          roundCost(cost),
+			"currency":      currency,
 			"model_name":    r.ModelName,
 			"provider_name": r.ProviderName,
 		})
@@ -222,18 +218,16 @@ func (d *Daemon) handleTokenUsageByModel(_ context.Context, params json.RawMessa
 		Until:     until,
 	}
 
-	records, err := store.Query(context.Background(), filter)
+	records, err := chargedUsage(store, filter)
 	if err != nil {
 		return nil, fmt.Errorf("query token usage: %w", err)
 	}
 
-	modelCost, hasCost := d.app.Costs().Get(p.Model)
-
 	totalTokens := 0
 	totalInput := 0
 	totalOutput := 0
 	totalCached := 0
-	totalCost := 0.0
+	byCurrency := currencyTotals{}
 	requestCount := len(records)
 
 	for _, r := range records {
@@ -243,8 +237,8 @@ func (d *Daemon) handleTokenUsageByModel(_ context.Context, params json.RawMessa
 		totalInput += r.PromptTokens
 		totalOutput += r.CompletionTokens
 		totalCached += r.CachedTokens
-		if hasCost {
-			totalCost += calculateRecordCost(modelCost, r)
+		if cost, currency, ok := d.recordCost(r); ok {
+			byCurrency.add(currency, cost)
 		}
 	}
 
@@ -253,16 +247,17 @@ func (d *Daemon) handleTokenUsageByModel(_ context.Context, params json.RawMessa
 		avgPerRequest = totalTokens / requestCount
 	}
 
-	return []any{map[string]any{
+	out := map[string]any{
 		"model":                  p.Model,
 		"provider":               resolveProvider(records),
 		"total_tokens":           totalTokens,
 		"input_tokens":           totalInput,
 		"output_tokens":          totalOutput,
-		"total_cost":             roundCost(totalCost),
 		"request_count":          requestCount,
 		"avg_tokens_per_request": avgPerRequest,
-	}}, nil
+	}
+	byCurrency.put(out, "total_cost")
+	return []any{out}, nil
 }
 
 func (d *Daemon) buildMonthlyStats(year, month int) (map[string]any, error) {
@@ -284,7 +279,7 @@ func (d *Daemon) buildMonthlyStats(year, month int) (map[string]any, error) {
 		Until: until,
 	}
 
-	records, err := store.Query(context.Background(), filter)
+	records, err := chargedUsage(store, filter)
 	d.logger.Debug("query result",
 		"record_count", len(records),
 		"query_err", err,
@@ -298,13 +293,13 @@ func (d *Daemon) buildMonthlyStats(year, month int) (map[string]any, error) {
 
 	dailyMap := make(map[string]*dayAgg)
 	modelMap := make(map[string]*modelAgg)
-	totalCost := 0.0
+	byCurrency := currencyTotals{}
 
 	for _, r := range records {
 		dateKey := r.Timestamp.Format("2006-01-02")
 		dayData := dailyMap[dateKey]
 		if dayData == nil {
-			dayData = &dayAgg{}
+			dayData = &dayAgg{cost: currencyTotals{}}
 			dailyMap[dateKey] = dayData
 		}
 		dayData.inputTokens += r.PromptTokens
@@ -317,8 +312,9 @@ func (d *Daemon) buildMonthlyStats(year, month int) (map[string]any, error) {
 		mData := modelMap[mKey]
 		if mData == nil {
 			mData = &modelAgg{
-				model:    r.ModelName,
-				provider: r.ProviderName,
+				model:     r.ModelName,
+				provider:  r.ProviderName,
+				totalCost: currencyTotals{},
 			}
 			modelMap[mKey] = mData
 		}
@@ -327,12 +323,10 @@ func (d *Daemon) buildMonthlyStats(year, month int) (map[string]any, error) {
 		mData.cachedTokens += r.CachedTokens
 		mData.requestCount++
 
-		mc, hasMC := d.app.Costs().Get(r.ModelName)
-		if hasMC {
-			cost := calculateRecordCost(mc, r)
-			dayData.cost += cost
-			mData.totalCost += cost
-			totalCost += cost
+		if cost, currency, ok := d.recordCost(r); ok {
+			dayData.cost.add(currency, cost)
+			mData.totalCost.add(currency, cost)
+			byCurrency.add(currency, cost)
 		}
 	}
 
@@ -351,16 +345,17 @@ func (d *Daemon) buildMonthlyStats(year, month int) (map[string]any, error) {
 		if dayTotal < 0 {
 			dayTotal = 0
 		}
-		dailyUsage = append(dailyUsage, map[string]any{
+		day := map[string]any{
 			"date":          dateStr,
 			"input_tokens":  da.inputTokens,
 			"output_tokens": da.outputTokens,
 			"cached_tokens": da.cachedTokens,
 			"total_tokens":  dayTotal,
-			"cost":          roundCost(da.cost),
 			"request_count": da.requestCount,
 			"model":         da.model,
-		})
+		}
+		da.cost.put(day, "cost")
+		dailyUsage = append(dailyUsage, day)
 	}
 
 	modelBreakdown := make([]any, 0, len(modelMap))
@@ -373,28 +368,30 @@ func (d *Daemon) buildMonthlyStats(year, month int) (map[string]any, error) {
 		if ma.requestCount > 0 {
 			avgReq = modelTotal / ma.requestCount
 		}
-		modelBreakdown = append(modelBreakdown, map[string]any{
+		breakdown := map[string]any{
 			"model":                  ma.model,
 			"provider":               ma.provider,
 			"total_tokens":           modelTotal,
 			"input_tokens":           ma.inputTokens,
 			"output_tokens":          ma.outputTokens,
 			"cached_tokens":          ma.cachedTokens,
-			"total_cost":             roundCost(ma.totalCost),
 			"request_count":          ma.requestCount,
 			"avg_tokens_per_request": avgReq,
-		})
+		}
+		ma.totalCost.put(breakdown, "total_cost")
+		modelBreakdown = append(modelBreakdown, breakdown)
 	}
 
-	return map[string]any{
+	out := map[string]any{
 		"year":            year,
 		"month":           month,
-		"total_cost":      roundCost(totalCost),
 		"total_tokens":    totalTokens,
 		"total_requests":  len(records),
 		"daily_usage":     dailyUsage,
 		"model_breakdown": modelBreakdown,
-	}, nil
+	}
+	byCurrency.put(out, "total_cost")
+	return out, nil
 }
 
 func (d *Daemon) listAvailableModels() []string {
@@ -412,7 +409,7 @@ type dayAgg struct {
 	outputTokens int
 	cachedTokens int
 	requestCount int
-	cost         float64
+	cost         currencyTotals
 	model        string
 }
 
@@ -423,19 +420,79 @@ type modelAgg struct {
 	outputTokens int
 	cachedTokens int
 	requestCount int
-	totalCost    float64
+	totalCost    currencyTotals
 }
 
 func emptyMonthlyResult(year, month int) map[string]any {
 	return map[string]any{
-		"year":            year,
-		"month":           month,
-		"total_cost":      0.0,
-		"total_tokens":    0,
-		"total_requests":  0,
-		"daily_usage":     []any{},
-		"model_breakdown": []any{},
+		"year":             year,
+		"month":            month,
+		"total_cost":       0.0,
+		"cost_by_currency": map[string]float64{},
+		"total_tokens":     0,
+		"total_requests":   0,
+		"daily_usage":      []any{},
+		"model_breakdown":  []any{},
+	}
+}
+
+// chargedUsage returns the usage records matching filter without the
+// usage that came with imported sessions: it was spent, and is reported,
+// where the session was exported. Per-session reports keep it.
+func chargedUsage(store *mindxses.FileTokenUsageStore, filter goharnesssession.TokenUsageFilter) ([]goharnesssession.TokenUsageRecord, error) {
+	records, err := store.QueryWithSource(context.Background(), filter)
+	if err != nil {
+		return nil, err
+	}
+	charged := make([]goharnesssession.TokenUsageRecord, 0, len(records))
+	for _, r := range records {
+		if r.Source != mindxses.UsageSourceImport {
+			charged = append(charged, r.TokenUsageRecord)
+		}
+	}
+	return charged, nil
+}
+
+// recordCost prices a usage record at the rate in force at its timestamp
+// for its model and provider, so later price changes do not rewrite past
+// reports. ok is false when the model has no price.
+func (d *Daemon) recordCost(r goharnesssession.TokenUsageRecord) (cost float64, currency string, ok bool) {
+	mc, ok := d.app.Costs().PriceAt(r.ModelName, r.ProviderName, r.Timestamp)
+	if !ok {
+		return 0, "", false
+	}
+	return calculateRecordCost(mc, r), mc.CurrencyCode(), true
+}
+
+// currencyTotals sums costs per currency. Costs in different currencies
+// cannot be added, so a report carries them per currency.
+type currencyTotals map[string]float64
+
+func (c currencyTotals) add(currency string, cost float64) {
+	c[currency] += cost
+}
+
+// put sets cost_by_currency in out and, when every cost is in one
+// currency, key to their total and currency to its code. A mix of
+// currencies has no single total: key and currency are left out.
+func (c currencyTotals) put(out map[string]any, key string) {
+	out["cost_by_currency"] = c.rounded()
+	if len(c) > 1 {
+		return
+	}
+	out[key] = 0.0
+	for currency, cost := range c {
+		out[key] = roundCost(cost)
+		out["currency"] = currency
+	}
+}
+
+func (c currencyTotals) rounded() map[string]float64 {
+	out := make(map[string]float64, len(c))
+	for k, v := range c {
+		out[k] = roundCost(v)
 	}
+	return out
 }
 
 func calculateRecordCost(mc core.ModelCost, r goharnesssession.TokenUsageRecord) float64 {
@@ -15,6 +15,7 @@ import (
 	"github.com/DotNetAge/mindx/internal/core"
 	"github.com/DotNetAge/mindx/pkg/indexing"
 	"github.com/DotNetAge/mindx/pkg/logging"
+	"github.com/DotNetAge/mindx/pkg/memory"
 )
 
 // newKBStack creates the full knowledge-base stack: GraphIndexer and RegionIndexer.
@@ -64,10 +65,24 @@ func newKBStack(
 		return nil, nil, fmt.Errorf("KB vector directory creation failed: %w", mkErr)
 	}
 
+	// Each embedder has its own store file; after a switch the new one
+	// starts empty until `mindx memory reembed` re-indexes the projects.
+	kbVecFile, prevStamp, stampErr := memory.ResolveVectorStore(kbVecDir, "kb", app.EmbedderID(), emb.Dim(), false)
+	if stampErr != nil {
+		return nil, nil, fmt.Errorf("KB embedder stamp unreadable: %w", stampErr)
+	}
+	if prevStamp != nil {
+		logger.Warn("KB vectors were built with another embedder, run 'mindx memory reembed' to rebuild them",
+			"previous", prevStamp.File,
+			"current", kbVecFile,
+		)
+	}
+	kbVecPath := filepath.Join(kbVecDir, kbVecFile)
+
 	kbVS, vsErr := govector.NewStore(
 		govector.WithCollection("kb_sem"),
 		govector.WithDimension(emb.Dim()),
-		govector.WithDBPath(filepath.Join(kbVecDir, "kb.db")),
+		govector.WithDBPath(kbVecPath),
 		govector.WithHNSW(true),
 	)
 	if vsErr != nil {
@@ -90,7 +105,7 @@ func newKBStack(
 	)
 	logger.Info("GraphIndexer initialized for knowledge base",
 		"vector_dim", emb.Dim(),
-		"vec_db", filepath.Join(kbVecDir, "kb.db"),
+		"vec_db", kbVecPath,
 	)
 
 	// ── 4. Create RegionIndexer ────────────────────────────────────
@@ -0,0 +1,162 @@
+package svc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	goharnessconfig "github.com/DotNetAge/goharness/config"
+	goharnessmemory "github.com/DotNetAge/goharness/memory"
+	goharnesssession "github.com/DotNetAge/goharness/session"
+	"github.com/DotNetAge/mindx/internal/core"
+	"github.com/DotNetAge/mindx/internal/i18n"
+	"github.com/DotNetAge/mindx/pkg/memory"
+	mindxses "github.com/DotNetAge/mindx/pkg/session"
+	"github.com/google/uuid"
+)
+
+// memoryConsolidationLoop consolidates the shared memory shortly after
+// start and then once per policy interval. Like the session retention
+// loop it re-reads the policy on every run.
+func (d *Daemon) memoryConsolidationLoop(ctx context.Context) {
+	timer := time.NewTimer(5 * time.Minute)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		policy := d.app.MemoryConsolidation()
+		if policy.Enabled && d.sharedMemory != nil {
+			d.runMemoryConsolidation(ctx, policy)
+		}
+		timer.Reset(policy.Interval())
+	}
+}
+
+func (d *Daemon) runMemoryConsolidation(ctx context.Context, policy core.MemoryConsolidation) {
+	report, err := d.consolidateMemory(ctx, policy, false)
+	if err != nil {
+		d.logger.Warn("memory consolidation failed", "error", err)
+		if report == nil {
+			return
+		}
+	}
+	if len(report.Clusters) > 0 || len(report.Pruned) > 0 {
+		merged := 0
+		for _, c := range report.Clusters {
+			merged += len(c.MergedFrom)
+		}
+		d.logger.Info("memory consolidation run complete",
+			"scanned", report.Scanned,
+			"clusters", len(report.Clusters),
+			"merged", merged,
+			"pruned", len(report.Pruned),
+		)
+	}
+}
+
+// consolidateMemory runs one consolidation of the shared memory under
+// policy, merging clusters through the default model.
+func (d *Daemon) consolidateMemory(ctx context.Context, policy core.MemoryConsolidation, dryRun bool) (*memory.ConsolidateReport, error) {
+	mem := d.sharedMemory
+	if mem == nil {
+		return nil, fmt.Errorf("memory service not available (embedder not configured)")
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	opts := policy.Options()
+	opts.DryRun = dryRun
+	if !dryRun {
+		modelCfg := d.app.ResolveDefaultModel()
+		if modelCfg == nil {
+			return nil, fmt.Errorf("no default model configured")
+		}
+		opts.Summarize = d.memorySummarizer(modelCfg)
+	}
+	return mem.Consolidate(ctx, opts)
+}
+
+// memorySummarizer merges a cluster of memories with one call to the
+// model, recording its token usage. Each call is checked against the
+// budgets first, as the memory agent on the model: a blocking budget
+// stops the consolidation and a downgrade switches the model.
+func (d *Daemon) memorySummarizer(modelCfg *goharnessconfig.ModelConfig) memory.Summarizer {
+	return func(ctx context.Context, cluster []goharnessmemory.MemoryChunk) (string, string, error) {
+		var b strings.Builder
+		for i, c := range cluster {
+			fmt.Fprintf(&b, "%d. %s\n", i+1, c.Summary)
+			if c.Content != "" && c.Content != c.Summary {
+				b.WriteString(c.Content)
+				b.WriteString("\n")
+			}
+			b.WriteString("\n")
+		}
+
+		modelCfg := modelCfg
+		subject := core.BudgetSubject{Agent: "memory", Model: modelCfg.Name, Provider: modelCfg.Provider}
+		decision := d.checkBudget(ctx, subject, "", "memory")
+		switch {
+		case decision.Blocked():
+			return "", "", fmt.Errorf(i18n.T("svc.event.budget.blocked"), budgetNames(decision))
+		case decision.Action == core.BudgetDowngrade:
+			if downgraded := d.app.ResolveModel(decision.Model); downgraded != nil {
+				modelCfg = downgraded
+			} else {
+				d.logger.Warn("budget downgrade failed, keeping current model", "agent", subject.Agent, "model", decision.Model)
+			}
+		}
+
+		caller := core.NewCaller(modelCfg, core.PROMPT_MEMORY_CONSOLIDATE)
+		result, err := caller.CallContext(ctx, b.String())
+		if err != nil {
+			return "", "", err
+		}
+
+		if result.Tokens.TotalTokens > 0 {
+			cachedTokens := 0
+			if result.Tokens.PromptTokensDetails != nil {
+				cachedTokens = result.Tokens.PromptTokensDetails.CachedTokens
+			}
+			reasoningTokens := 0
+			if result.Tokens.CompletionTokensDetails != nil {
+				reasoningTokens = result.Tokens.CompletionTokensDetails.ReasoningTokens
+			}
+			record := goharnesssession.TokenUsageRecord{
+				ID:               uuid.New().String(),
+				ModelName:        modelCfg.Name,
+				ProviderName:     modelCfg.Provider,
+				AgentName:        "memory",
+				PromptTokens:     result.Tokens.PromptTokens,
+				CompletionTokens: result.Tokens.CompletionTokens,
+				CachedTokens:     cachedTokens,
+				ReasoningTokens:  reasoningTokens,
+				TotalTokens:      result.Tokens.TotalTokens,
+				Timestamp:        time.Now(),
+			}
+			if err := d.app.TokenUsageStore().AppendWithSource(context.Background(), record, string(mindxses.UsageSourceMemory)); err != nil {
+				d.logger.Warn("failed to record token usage for memory consolidation", "error", err)
+			}
+		}
+
+		summary, content := splitMergedMemory(result.Result)
+		if summary == "" {
+			return "", "", fmt.Errorf("model returned an empty memory")
+		}
+		return summary, content, nil
+	}
+}
+
+// splitMergedMemory splits the model's answer to PROMPT_MEMORY_CONSOLIDATE
+// into the title on its first line and the body after it.
+func splitMergedMemory(text string) (summary, content string) {
+	text = strings.TrimSpace(text)
+	summary, content, _ = strings.Cut(text, "\n")
+	summary = strings.TrimSpace(strings.TrimLeft(summary, "# "))
+	content = strings.TrimSpace(content)
+	return summary, content
+}
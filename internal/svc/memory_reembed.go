@@ -0,0 +1,247 @@
+package svc
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	goharnesssession "github.com/DotNetAge/goharness/session"
+	"github.com/DotNetAge/mindx/pkg/indexing"
+	"github.com/DotNetAge/mindx/pkg/memory"
+	"github.com/DotNetAge/mindx/pkg/rpc"
+)
+
+// reembedJob is the state of the running or last re-embedding migration.
+type reembedJob struct {
+	mu     sync.Mutex
+	status rpc.MemoryReembedStatus
+	// indexers are the project indexers requeued for the knowledge base.
+	indexers []*indexing.Indexer
+}
+
+// startReembed starts re-embedding the shared memory and the knowledge
+// base with the configured embedder. The memory is migrated in a
+// background goroutine; the knowledge base is re-indexed by requeueing
+// every indexed file of every known project, which the project indexers'
+// workers then process.
+func (d *Daemon) startReembed(ctx context.Context) (rpc.MemoryReembedStatus, error) {
+	if d.sharedMemory == nil && d.graphIndexer == nil {
+		return rpc.MemoryReembedStatus{}, fmt.Errorf("memory and knowledge base not available (embedder not configured)")
+	}
+
+	d.reembed.mu.Lock()
+	if d.reembed.status.Running {
+		d.reembed.mu.Unlock()
+		return d.reembedStatus(ctx), fmt.Errorf("a re-embedding is already running")
+	}
+	d.reembed.status = rpc.MemoryReembedStatus{Running: true, StartedAt: time.Now().UnixMilli()}
+	d.reembed.indexers = nil
+	d.reembed.mu.Unlock()
+
+	queued, indexers := d.reembedKB(ctx)
+	d.reembed.mu.Lock()
+	d.reembed.status.KBQueued = queued
+	d.reembed.indexers = indexers
+	d.reembed.mu.Unlock()
+
+	go d.runReembed(context.WithoutCancel(ctx))
+	return d.reembedStatus(ctx), nil
+}
+
+// reembedPollInterval is how often runReembed checks whether the
+// knowledge-base re-index has drained.
+const reembedPollInterval = time.Second
+
+// runReembed migrates the shared memory, waits for the knowledge base to
+// be re-indexed, and records the outcome.
+func (d *Daemon) runReembed(ctx context.Context) {
+	defer func() {
+		if r := recover(); r != nil {
+			d.logger.Error("memory reembed: goroutine panic", fmt.Errorf("%v", r))
+			d.finishReembed(0, fmt.Errorf("panic: %v", r))
+		}
+	}()
+
+	var stored int
+	var err error
+	if mem := d.sharedMemory; mem != nil {
+		lastBroadcast := time.Time{}
+		stored, err = mem.Reembed(ctx, func(done, total int) {
+			d.reembed.mu.Lock()
+			d.reembed.status.MemoryDone = done
+			d.reembed.status.MemoryTotal = total
+			d.reembed.mu.Unlock()
+			if done == total || time.Since(lastBroadcast) >= time.Second {
+				lastBroadcast = time.Now()
+				d.broadcastReembed(ctx)
+			}
+		})
+	}
+	if kbErr := d.stampKB(ctx); err == nil {
+		err = kbErr
+	}
+	d.finishReembed(stored, err)
+}
+
+func (d *Daemon) finishReembed(stored int, err error) {
+	d.reembed.mu.Lock()
+	d.reembed.status.Running = false
+	d.reembed.status.MemoryStored = stored
+	d.reembed.status.FinishedAt = time.Now().UnixMilli()
+	if err != nil {
+		d.reembed.status.Error = err.Error()
+	}
+	st := d.reembed.status
+	d.reembed.mu.Unlock()
+
+	if err != nil {
+		d.logger.Warn("memory reembed failed", "error", err, "stored", stored)
+	} else {
+		d.logger.Info("memory reembed complete",
+			"memories", st.MemoryTotal,
+			"stored", stored,
+			"kb_queued", st.KBQueued,
+		)
+	}
+	d.broadcastReembed(context.Background())
+}
+
+// reembedKB requeues every indexed file of the known projects so the
+// knowledge base is rebuilt with the current embedder. Returns the number
+// of entries queued and the indexers they were queued on.
+func (d *Daemon) reembedKB(ctx context.Context) (int, []*indexing.Indexer) {
+	if d.graphIndexer == nil || d.app.Embedder() == nil {
+		return 0, nil
+	}
+
+	var queued int
+	var indexers []*indexing.Indexer
+	for _, projectDir := range d.kbProjects(ctx) {
+		pi, err := d.getIndexer(projectDir)
+		if err != nil {
+			d.logger.Warn("memory reembed: skip project", "project_dir", projectDir, "error", err)
+			continue
+		}
+		if n := pi.Reindex(ctx); n > 0 {
+			queued += n
+			indexers = append(indexers, pi)
+		}
+	}
+	d.logger.Info("memory reembed: knowledge base queued for re-indexing", "entries", queued, "projects", len(indexers))
+	return queued, indexers
+}
+
+// stampKB waits for the indexers requeued by reembedKB to drain, then
+// makes the current embedder's KB store the active one. Stamping earlier
+// would mark the store complete while it still lacks the files waiting
+// in the queues; an indexer stopping before its queue drains leaves the
+// stamp as it was.
+func (d *Daemon) stampKB(ctx context.Context) error {
+	emb := d.app.Embedder()
+	if d.graphIndexer == nil || emb == nil {
+		return nil
+	}
+	d.reembed.mu.Lock()
+	indexers := d.reembed.indexers
+	d.reembed.mu.Unlock()
+
+	ticker := time.NewTicker(reembedPollInterval)
+	defer ticker.Stop()
+	for {
+		remaining := 0
+		for _, pi := range indexers {
+			s := pi.Status(ctx)
+			if !s.Running && (s.Enqueued > 0 || s.Processing != "") {
+				return fmt.Errorf("knowledge base re-index of %s stopped with %d files left", s.ProjectDir, s.Enqueued)
+			}
+			remaining += s.Enqueued
+			if s.Processing != "" {
+				remaining++
+			}
+		}
+		if remaining == 0 {
+			break
+		}
+		d.broadcastReembed(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	kbVecDir := filepath.Join(d.dataDir, "kb-vectors")
+	file, _, err := memory.ResolveVectorStore(kbVecDir, "kb", d.app.EmbedderID(), emb.Dim(), true)
+	if err == nil {
+		err = memory.WriteEmbedderStamp(kbVecDir, memory.EmbedderStamp{ID: d.app.EmbedderID(), Dim: emb.Dim(), File: file})
+	}
+	if err != nil {
+		return fmt.Errorf("update KB embedder stamp: %w", err)
+	}
+	d.logger.Info("memory reembed: knowledge base re-indexed", "projects", len(indexers))
+	return nil
+}
+
+// kbProjects returns the project directories with a knowledge-base
+// manifest: those with a loaded indexer and those of sessions that were
+// indexed before.
+func (d *Daemon) kbProjects(ctx context.Context) []string {
+	seen := map[string]bool{}
+	var dirs []string
+
+	d.indexersMu.RLock()
+	for projectDir := range d.indexers {
+		seen[projectDir] = true
+		dirs = append(dirs, projectDir)
+	}
+	d.indexersMu.RUnlock()
+
+	if d.app.SessDB() == nil {
+		return dirs
+	}
+	sessions, err := goharnesssession.ListSessions(ctx, d.app.SessDB())
+	if err != nil {
+		return dirs
+	}
+	baseDir := filepath.Dir(d.dataDir)
+	for _, s := range sessions {
+		if s.ProjectDir == "" || seen[s.ProjectDir] {
+			continue
+		}
+		seen[s.ProjectDir] = true
+		if indexing.ManifestExists(s.ProjectDir, baseDir) {
+			dirs = append(dirs, s.ProjectDir)
+		}
+	}
+	return dirs
+}
+
+// reembedStatus returns the migration status, with the knowledge-base
+// entries still waiting counted now.
+func (d *Daemon) reembedStatus(ctx context.Context) rpc.MemoryReembedStatus {
+	d.reembed.mu.Lock()
+	st := d.reembed.status
+	indexers := d.reembed.indexers
+	d.reembed.mu.Unlock()
+
+	for _, pi := range indexers {
+		s := pi.Status(ctx)
+		st.KBRemaining += s.Enqueued
+		if s.Processing != "" {
+			st.KBRemaining++
+		}
+	}
+	if d.sharedMemory != nil {
+		st.Pending = d.sharedMemory.NeedsReembed()
+	}
+	return st
+}
+
+func (d *Daemon) broadcastReembed(ctx context.Context) {
+	if d.gw == nil {
+		return
+	}
+	d.gw.BroadcastNotification("memory_reembed", d.reembedStatus(ctx))
+}
@@ -0,0 +1,94 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	goharnessmemory "github.com/DotNetAge/goharness/memory"
+	"github.com/DotNetAge/mindx/pkg/scheduler"
+	"go.etcd.io/bbolt"
+)
+
+// kvSinkMaxItems bounds the list a KV sink appends to; the oldest
+// deliveries are dropped first.
+const kvSinkMaxItems = 100
+
+// registerScheduleSinks installs the delivery sinks that depend on daemon
+// stores. File and webhook sinks are built into the scheduler.
+func (d *Daemon) registerScheduleSinks() {
+	d.scheduler.RegisterSink(scheduler.SinkMemory, d.deliverScheduleToMemory)
+	d.scheduler.RegisterSink(scheduler.SinkKV, d.deliverScheduleToKV)
+}
+
+// deliverScheduleToMemory stores a run's output as a long-term memory chunk.
+func (d *Daemon) deliverScheduleToMemory(ctx context.Context, sink scheduler.Sink, run scheduler.Delivery) error {
+	mem := d.sharedMemory
+	if mem == nil {
+		return fmt.Errorf("memory service not available (embedder not configured)")
+	}
+	if run.Output == "" {
+		return fmt.Errorf("run produced no output")
+	}
+
+	tags := append([]string{"scheduled", "schedule:" + run.EntryID}, sink.Tags...)
+	_, err := mem.Store(ctx, goharnessmemory.MemoryChunk{
+		Summary:    fmt.Sprintf("Scheduled task %s (@%s) %s", run.EntryID, run.Agent, run.EndedAt.Format("2006-01-02 15:04")),
+		Content:    run.Output,
+		AgentName:  run.Agent,
+		SessionID:  run.SessionID,
+		ProjectDir: run.ProjectDir,
+		Tags:       tags,
+		Timestamp:  run.EndedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("memory store failed: %w", err)
+	}
+	return nil
+}
+
+// deliverScheduleToKV appends a run to the JSON list stored under the
+// sink's key, keeping the newest kvSinkMaxItems entries.
+func (d *Daemon) deliverScheduleToKV(_ context.Context, sink scheduler.Sink, run scheduler.Delivery) error {
+	db := d.kvStore
+	if db == nil {
+		return fmt.Errorf("kvstore not initialized")
+	}
+	key := run.Expand(sink.Key)
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(kvStoreBucket))
+		if err != nil {
+			return err
+		}
+
+		var items []any
+		if v := b.Get([]byte(key)); v != nil {
+			if existing, ok := decodeKVItem(key, v).Value.([]any); ok {
+				items = existing
+			}
+		}
+		items = append(items, map[string]any{
+			"run_id":   run.RunID,
+			"entry_id": run.EntryID,
+			"agent":    run.Agent,
+			"status":   run.Status,
+			"output":   run.Output,
+			"at":       run.EndedAt.Format(time.RFC3339),
+		})
+		if len(items) > kvSinkMaxItems {
+			items = items[len(items)-kvSinkMaxItems:]
+		}
+
+		data, err := json.Marshal(kvItem{Key: key, Value: items, CreatedAt: time.Now().Unix()})
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), data)
+	})
+	if err != nil {
+		return fmt.Errorf("kvstore append failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package svc
+
+import (
+	"context"
+	"time"
+
+	"github.com/DotNetAge/mindx/internal/core"
+)
+
+// sessionRetentionLoop applies the configured session retention policy
+// shortly after start and then once per policy interval. The policy is
+// re-read on every run, so edits to mindx.json apply without a restart of
+// the loop.
+func (d *Daemon) sessionRetentionLoop(ctx context.Context) {
+	timer := time.NewTimer(time.Minute)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		policy := d.app.SessionRetention()
+		if policy.Enabled() && d.app.SessDB() != nil {
+			d.runSessionRetention(ctx, policy)
+		}
+		timer.Reset(policy.Interval())
+	}
+}
+
+// runSessionRetention collects sessions under policy, skipping sessions
+// with a request in flight.
+func (d *Daemon) runSessionRetention(ctx context.Context, policy core.SessionRetention) {
+	janitor := d.sessionJanitor()
+	report, err := janitor.Run(ctx, policy, false)
+	if err != nil {
+		d.logger.Warn("session retention failed", "error", err)
+		return
+	}
+	failed := 0
+	for _, s := range report.Sessions {
+		if s.Error != "" {
+			failed++
+			d.logger.Warn("session retention: session not collected",
+				"session_id", s.SessionID,
+				"agent", s.AgentName,
+				"error", s.Error,
+			)
+		}
+	}
+	if len(report.Sessions) > 0 {
+		d.logger.Info("session retention run complete",
+			"action", report.Action,
+			"collected", len(report.Sessions)-failed,
+			"failed", failed,
+			"reclaimed_bytes", report.ReclaimedBytes,
+			"archived_bytes", report.ArchivedBytes,
+		)
+	}
+}
+
+// sessionJanitor returns the app's janitor, aware of the daemon's active
+// sessions.
+func (d *Daemon) sessionJanitor() *core.SessionJanitor {
+	janitor := d.app.SessionJanitor()
+	janitor.Active = func(sessionID string) bool {
+		_, ok := d.activeSessions.Load(sessionID)
+		return ok
+	}
+	return janitor
+}
@@ -0,0 +1,65 @@
+package svc
+
+import (
+	"sync"
+
+	goharnesssession "github.com/DotNetAge/goharness/session"
+)
+
+// applyWindowEstimate overwrites window_tokens and usage_ratio in a
+// context usage payload with an estimate of the session's active window
+// made by the tokenizer of its agent's model, which is far closer to the
+// provider's count than the built-in bytes/4 estimate for CJK text and
+// code. maxWindow is the payload's max_window_size.
+func (d *Daemon) applyWindowEstimate(data map[string]any, sess *goharnesssession.Session, maxWindow float64) {
+	reg := d.app.Tokenizers()
+	if reg == nil || sess == nil {
+		return
+	}
+	est := reg.For(d.app.AgentModel(sess.AgentName()))
+	tokens := 0
+	for _, m := range sess.Current() {
+		tokens += est.Estimate(m.Content)
+	}
+	data["window_tokens"] = tokens
+	if maxWindow > 0 {
+		data["usage_ratio"] = float64(tokens) / maxWindow
+	}
+}
+
+// tokenCalibrationGuard returns a TokenUsageRecorded handler that feeds
+// the tokenizer calibration. Between two consecutive LLM calls of a
+// request the prompt grows by the messages appended in between, so the
+// growth in reported prompt tokens against the uncalibrated estimate of
+// those messages is a sample free of the fixed system prompt and tool
+// schema overhead. Records of other agents (sub-agents) are ignored.
+// next, when non-nil, is called first.
+func (d *Daemon) tokenCalibrationGuard(sess *goharnesssession.Session, next func(goharnesssession.TokenUsageRecord)) func(goharnesssession.TokenUsageRecord) {
+	reg := d.app.Tokenizers()
+	if reg == nil || !reg.Calibrating() {
+		return next
+	}
+	var mu sync.Mutex
+	prevLen, prevPrompt, prevModel := -1, 0, ""
+	return func(record goharnesssession.TokenUsageRecord) {
+		if next != nil {
+			next(record)
+		}
+		if record.AgentName != "" && record.AgentName != sess.AgentName() {
+			return
+		}
+		msgs := sess.All()
+
+		mu.Lock()
+		defer mu.Unlock()
+		if prevLen >= 0 && record.ModelName == prevModel && len(msgs) > prevLen && record.PromptTokens > prevPrompt {
+			est := reg.Base(record.ModelName)
+			estimated := 0
+			for _, m := range msgs[prevLen:] {
+				estimated += est.Estimate(m.Content)
+			}
+			reg.Observe(record.ModelName, estimated, record.PromptTokens-prevPrompt)
+		}
+		prevLen, prevPrompt, prevModel = len(msgs), record.PromptTokens, record.ModelName
+	}
+}
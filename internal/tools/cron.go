@@ -2,6 +2,8 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -29,9 +31,27 @@ func (t *Cron) Info() *tools.ToolInfo {
 
 操作：
 - **list**：列出所有定时任务及其状态和下次运行信息。
-- **create**：创建新的定时任务。需要 agent、content（要发送的提示词）和 cron_expr（6 字段 cron 表达式，支持秒级，如 "0 0 9 * * *" 表示每天 9 点）。
+- **create**：创建新的定时任务。需要 agent、content（要发送的提示词）以及触发方式。
 - **update**：按 id 更新现有定时任务。只更新提供的字段。
-- **delete**：按 id 删除定时任务。`,
+- **delete**：按 id 删除定时任务。
+
+触发方式（trigger）：
+- **cron**（默认）：按 cron_expr 周期运行（6 字段，支持秒级，如 "0 0 9 * * *" 表示每天 9 点）。日字段支持 "L"（每月最后一天）和 "LW"（每月最后一个工作日），如 "0 0 9 LW * *"。
+- **at**：在 run_at 指定的时间运行一次（如 "2026-11-01 09:00"）。
+- **every**：按固定间隔运行（如 every="90m"），从 start_at 开始计时，省略时从创建时刻开始。
+
+可选：timezone（IANA 时区，如 "Asia/Shanghai"）、start_at / end_at（生效时间窗口）、max_runs（最多运行次数）。触发次数用尽的任务会自动停用。
+
+执行策略（可选）：timeout（单次执行超时，默认 5m）、max_retries（失败后重试次数）、retry_backoff（首次重试前的等待时间，默认 30s，之后每次翻倍）、overlap（上一次运行尚未结束时的处理方式："skip" 跳过（默认）、"queue" 排队等待、"allow" 并行运行）、catch_up（守护进程停机期间错过的运行在启动后如何补跑："none" 不补跑（默认）、"once" 补跑一次、"all" 逐次补跑，最多 catch_up_max 次，默认 10）。
+
+任务链（可选）：on_success / on_failure 指定本次运行成功或失败后交给另一个代理继续执行的步骤，形如 {"agent": "writer", "content": "根据以下调研写一篇文章：{{output}}"}，步骤内可继续嵌套 on_success / on_failure。content 中可使用 {{output}}（上一步的最终回答）、{{error}}、{{status}}、{{agent}}、{{session_id}}。整条链记录为一次运行。
+
+输出投递（可选）：sinks 为投递目标数组，每次运行结束后把最终输出送达，每个目标的投递结果单独记录在运行历史中：
+- {"type": "file", "path": "~/reports/{{date}}.md", "append": true}：写入（或追加到）文件
+- {"type": "webhook", "url": "https://...", "headers": {...}}：以 JSON POST 到 URL
+- {"type": "memory", "tags": ["日报"]}：存入长期记忆
+- {"type": "kv", "key": "reports"}：追加到 KV 存储中的列表
+每个目标可设 "when"："success"（默认）、"failure" 或 "always"。path 和 key 支持 {{date}}、{{entry_id}}、{{run_id}}。`,
 		IsReadOnly: false,
 		Parameters: []tools.Parameter{
 			{
@@ -59,10 +79,109 @@ func (t *Cron) Info() *tools.ToolInfo {
 				Description: "任务运行时发送给代理的提示词内容。create 必需。",
 				Required:    false,
 			},
+			{
+				Name:        "trigger",
+				Type:        "string",
+				Description: "触发方式：\"cron\"（默认）、\"at\"（一次性）或 \"every\"（固定间隔）。",
+				Required:    false,
+				Enum:        []any{"cron", "at", "every"},
+			},
 			{
 				Name:        "cron_expr",
 				Type:        "string",
-				Description: "6 字段 cron 表达式，支持秒级（如 \"0 0 9 * * *\" 表示每天 9 点，\"0 */30 * * * *\" 表示每 30 分钟）。create 必需。",
+				Description: "6 字段 cron 表达式，支持秒级（如 \"0 0 9 * * *\" 表示每天 9 点，\"0 */30 * * * *\" 表示每 30 分钟，\"0 0 9 LW * *\" 表示每月最后一个工作日 9 点）。trigger 为 cron 时必需。",
+				Required:    false,
+			},
+			{
+				Name:        "run_at",
+				Type:        "string",
+				Description: "一次性运行时间（如 \"2026-11-01 09:00\" 或 RFC 3339）。trigger 为 at 时必需。",
+				Required:    false,
+			},
+			{
+				Name:        "every",
+				Type:        "string",
+				Description: "运行间隔（如 \"90m\"、\"2h\"、\"24h\"）。trigger 为 every 时必需。",
+				Required:    false,
+			},
+			{
+				Name:        "timezone",
+				Type:        "string",
+				Description: "IANA 时区（如 \"Asia/Shanghai\"）。省略时使用守护进程本地时区。",
+				Required:    false,
+			},
+			{
+				Name:        "start_at",
+				Type:        "string",
+				Description: "生效开始时间，此前不会运行。",
+				Required:    false,
+			},
+			{
+				Name:        "end_at",
+				Type:        "string",
+				Description: "生效结束时间，此后不再运行，任务自动停用。",
+				Required:    false,
+			},
+			{
+				Name:        "max_runs",
+				Type:        "integer",
+				Description: "最多运行次数，达到后任务自动停用。0 表示不限。",
+				Required:    false,
+			},
+			{
+				Name:        "timeout",
+				Type:        "string",
+				Description: "单次执行超时（如 \"10m\"）。默认 5m。",
+				Required:    false,
+			},
+			{
+				Name:        "max_retries",
+				Type:        "integer",
+				Description: "执行失败后的重试次数。默认 0（不重试）。",
+				Required:    false,
+			},
+			{
+				Name:        "retry_backoff",
+				Type:        "string",
+				Description: "首次重试前的等待时间（如 \"30s\"），之后每次重试翻倍。默认 30s。",
+				Required:    false,
+			},
+			{
+				Name:        "overlap",
+				Type:        "string",
+				Description: "上一次运行尚未结束时的处理方式：\"skip\"（默认，跳过本次）、\"queue\"（等待上一次结束后运行）或 \"allow\"（并行运行）。",
+				Required:    false,
+				Enum:        []any{"skip", "queue", "allow"},
+			},
+			{
+				Name:        "catch_up",
+				Type:        "string",
+				Description: "守护进程停机期间错过的运行如何补跑：\"none\"（默认，不补跑）、\"once\"（补跑一次）或 \"all\"（逐次补跑）。",
+				Required:    false,
+				Enum:        []any{"none", "once", "all"},
+			},
+			{
+				Name:        "catch_up_max",
+				Type:        "integer",
+				Description: "catch_up 为 all 时最多补跑的次数（保留最近的几次）。默认 10。",
+				Required:    false,
+			},
+			{
+				Name:        "on_success",
+				Type:        "object",
+				Description: "成功后执行的后续步骤：{\"agent\", \"content\", 可选 \"session_id\"、\"project_dir\"、\"on_success\"、\"on_failure\"}。传 null 可移除。",
+				Required:    false,
+			},
+			{
+				Name:        "on_failure",
+				Type:        "object",
+				Description: "失败后执行的后续步骤，格式同 on_success。传 null 可移除。",
+				Required:    false,
+			},
+			{
+				Name:        "sinks",
+				Type:        "array",
+				Description: "输出投递目标数组，元素形如 {\"type\": \"file\"|\"webhook\"|\"memory\"|\"kv\", ...}。update 时整体替换，传空数组可清除。",
 				Required:    false,
 			},
 			{
@@ -132,11 +251,6 @@ func (t *Cron) createEntry(ctx context.Context, params map[string]any) (any, err
 	if err != nil {
 		return nil, fmt.Errorf("Cron：create 需要 content：%w", err)
 	}
-	cronExpr, err := tools.ValidateRequiredString(params, "cron_expr")
-	if err != nil {
-		return nil, fmt.Errorf("Cron：create 需要 cron_expr：%w", err)
-	}
-
 	idRaw, _ := getParam(params, "id")
 	id, _ := idRaw.(string)
 	if id == "" {
@@ -159,14 +273,19 @@ func (t *Cron) createEntry(ctx context.Context, params map[string]any) (any, err
 		ID:         id,
 		Agent:      agent,
 		Content:    content,
-		CronExpr:   cronExpr,
 		Enabled:    enabled,
 		SessionID:  sessionID,
 		ProjectDir: projectDir,
 		CreatedAt:  time.Now(),
 	}
+	if err := applyTriggerParams(entry, params); err != nil {
+		return nil, err
+	}
 
 	if err := t.store.Save(ctx, entry); err != nil {
+		if errors.Is(err, scheduler.ErrNotApplied) {
+			return nil, fmt.Errorf("Cron：任务 %q 已保存但未能生效：%w", id, err)
+		}
 		return nil, fmt.Errorf("Cron：保存任务失败：%w", err)
 	}
 
@@ -198,11 +317,6 @@ func (t *Cron) updateEntry(ctx context.Context, params map[string]any) (any, err
 			existing.Content = s
 		}
 	}
-	if v, ok := getParam(params, "cron_expr"); ok {
-		if s, ok := v.(string); ok && s != "" {
-			existing.CronExpr = s
-		}
-	}
 	if v, ok := getParam(params, "enabled"); ok {
 		if s, ok := v.(bool); ok {
 			existing.Enabled = s
@@ -218,8 +332,15 @@ func (t *Cron) updateEntry(ctx context.Context, params map[string]any) (any, err
 			existing.ProjectDir = s
 		}
 	}
+	if err := applyTriggerParams(existing, params); err != nil {
+		return nil, err
+	}
+	existing.RetiredAt = time.Time{}
 
 	if err := t.store.Save(ctx, existing); err != nil {
+		if errors.Is(err, scheduler.ErrNotApplied) {
+			return nil, fmt.Errorf("Cron：任务 %q 已更新但未能生效：%w", id, err)
+		}
 		return nil, fmt.Errorf("Cron：更新任务失败：%w", err)
 	}
 
@@ -230,6 +351,153 @@ func (t *Cron) updateEntry(ctx context.Context, params map[string]any) (any, err
 	}, nil
 }
 
+// applyTriggerParams copies the trigger and execution policy params onto
+// entry and validates the result. Absent params leave the entry's fields unchanged.
+func applyTriggerParams(entry *scheduler.ScheduleEntry, params map[string]any) error {
+	str := func(key string) (string, bool) {
+		v, ok := getParam(params, key)
+		if !ok {
+			return "", false
+		}
+		s, ok := v.(string)
+		return s, ok
+	}
+
+	if s, ok := str("trigger"); ok && s != "" {
+		entry.Trigger = scheduler.TriggerType(s)
+	}
+	if s, ok := str("cron_expr"); ok && s != "" {
+		entry.CronExpr = s
+	}
+	if s, ok := str("every"); ok && s != "" {
+		entry.Every = s
+	}
+	if s, ok := str("timezone"); ok {
+		entry.Timezone = s
+	}
+	for key, dst := range map[string]*time.Time{"run_at": &entry.RunAt, "start_at": &entry.StartAt, "end_at": &entry.EndAt} {
+		s, ok := str(key)
+		if !ok {
+			continue
+		}
+		if s == "" {
+			*dst = time.Time{}
+			continue
+		}
+		ts, err := scheduler.ParseTime(s, entry.Timezone)
+		if err != nil {
+			return fmt.Errorf("Cron：%s 无效：%w", key, err)
+		}
+		*dst = ts
+	}
+	for key, dst := range map[string]*int{"max_runs": &entry.MaxRuns, "max_retries": &entry.MaxRetries, "catch_up_max": &entry.CatchUpMax} {
+		v, ok := getParam(params, key)
+		if !ok {
+			continue
+		}
+		switch n := v.(type) {
+		case float64:
+			*dst = int(n)
+		case int:
+			*dst = n
+		}
+	}
+	if s, ok := str("timeout"); ok {
+		entry.Timeout = s
+	}
+	if s, ok := str("retry_backoff"); ok {
+		entry.RetryBackoff = s
+	}
+	if s, ok := str("overlap"); ok {
+		entry.Overlap = scheduler.OverlapPolicy(s)
+	}
+	if s, ok := str("catch_up"); ok {
+		entry.CatchUp = scheduler.CatchUpPolicy(s)
+	}
+	for key, dst := range map[string]**scheduler.ChainStep{"on_success": &entry.OnSuccess, "on_failure": &entry.OnFailure} {
+		v, ok := getParam(params, key)
+		if !ok {
+			continue
+		}
+		step, err := parseChainStep(v)
+		if err != nil {
+			return fmt.Errorf("Cron：%s 无效：%w", key, err)
+		}
+		*dst = step
+	}
+	if v, ok := getParam(params, "sinks"); ok {
+		sinks, err := parseSinks(v)
+		if err != nil {
+			return fmt.Errorf("Cron：sinks 无效：%w", err)
+		}
+		entry.Sinks = sinks
+	}
+
+	// Infer the trigger from the fields given when it was not set explicitly.
+	if entry.Trigger == "" {
+		switch {
+		case entry.CronExpr != "":
+			entry.Trigger = scheduler.TriggerCron
+		case !entry.RunAt.IsZero():
+			entry.Trigger = scheduler.TriggerAt
+		case entry.Every != "":
+			entry.Trigger = scheduler.TriggerEvery
+		}
+	}
+
+	if err := entry.Validate(); err != nil {
+		return fmt.Errorf("Cron：任务配置无效：%w", err)
+	}
+	return nil
+}
+
+// parseChainStep converts a follow-up step param (an object, its JSON
+// encoding, or null to remove the step) into a ChainStep.
+func parseChainStep(v any) (*scheduler.ChainStep, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, ok := v.(string)
+	if !ok {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		data = string(b)
+	}
+	if data == "" || data == "null" {
+		return nil, nil
+	}
+	var step scheduler.ChainStep
+	if err := json.Unmarshal([]byte(data), &step); err != nil {
+		return nil, err
+	}
+	return &step, nil
+}
+
+// parseSinks converts the sinks param (an array or its JSON encoding).
+func parseSinks(v any) ([]scheduler.Sink, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, ok := v.(string)
+	if !ok {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		data = string(b)
+	}
+	if data == "" || data == "null" {
+		return nil, nil
+	}
+	var sinks []scheduler.Sink
+	if err := json.Unmarshal([]byte(data), &sinks); err != nil {
+		return nil, err
+	}
+	return sinks, nil
+}
+
 func (t *Cron) deleteEntry(ctx context.Context, params map[string]any) (any, error) {
 	id, err := tools.ValidateRequiredString(params, "id")
 	if err != nil {
@@ -4,7 +4,6 @@ import (
 	"context"
 	"crypto/sha256"
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
 
@@ -12,6 +11,7 @@ import (
 	"github.com/DotNetAge/gorag/v2/core"
 	goragindexer "github.com/DotNetAge/gorag/v2/indexer"
 	"github.com/DotNetAge/gorag/v2/query"
+	"github.com/DotNetAge/mindx/pkg/execctx"
 )
 
 // FindRelation traverses entity relationships in the knowledge graph — find
@@ -126,13 +126,11 @@ func (t *FindRelation) Execute(ctx context.Context, params map[string]any) (any,
 		gq.SetEdgeTypes(edgeTypes)
 	}
 
-	// Apply projectDir filter — default to current working directory
+	// Apply projectDir filter — default to the execution's working directory
 	projectDirRaw, _ := getParam(params, "projectDir")
 	projectDir, _ := projectDirRaw.(string)
 	if projectDir == "" {
-		if cwd, err := os.Getwd(); err == nil {
-			projectDir = cwd
-		}
+		projectDir = execctx.WorkDir(ctx)
 	}
 	if projectDir != "" {
 		regionID := fmt.Sprintf("%x", sha256.Sum256([]byte(filepath.Clean(projectDir))))
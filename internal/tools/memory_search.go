@@ -0,0 +1,167 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	goharnessmemory "github.com/DotNetAge/goharness/memory"
+	"github.com/DotNetAge/goharness/tools"
+	"github.com/DotNetAge/mindx/pkg/execctx"
+	"github.com/DotNetAge/mindx/pkg/memory"
+)
+
+// MemorySearch recalls long-term memories within the scopes its agent may
+// read. The search runs with a memory.Access for the agent and the
+// execution's project and session in its context, so the memory leaves
+// out what the agent may not see; the marketing agent does not recall
+// the backend engineer's memories.
+//
+// A runtime and its tools are shared by every session of the agent, so
+// the project and session come from the execution context (see
+// pkg/execctx), which every ask sets, rather than from the tool.
+type MemorySearch struct {
+	mem       goharnessmemory.Memory
+	agentName string
+	policy    memory.ScopePolicy
+}
+
+// NewMemorySearch creates a MemorySearch tool for agentName over mem,
+// limited to the scopes policy lets it read.
+func NewMemorySearch(mem goharnessmemory.Memory, agentName string, policy memory.ScopePolicy) tools.FuncTool {
+	return &MemorySearch{mem: mem, agentName: agentName, policy: policy}
+}
+
+func (t *MemorySearch) Info() *tools.ToolInfo {
+	scopes := make([]any, len(t.policy.Read))
+	names := make([]string, len(t.policy.Read))
+	for i, s := range t.policy.Read {
+		scopes[i] = string(s)
+		names[i] = string(s)
+	}
+	return &tools.ToolInfo{
+		Name:        "MemorySearch",
+		Description: "检索长期记忆 — 回忆过往会话摘要、决定与结论。",
+		Prompt: `按含义检索长期记忆，回忆过往会话中的摘要、决定和结论。
+
+记忆按范围（scope）隔离：global（所有智能体共享）、project（同一项目内的智能体共享）、agent（仅自己）、session（仅当前会话）。
+你可以读取的范围：` + strings.Join(names, "、") + `。其他智能体的私有记忆不会出现在结果中。`,
+		IsReadOnly: true,
+		Parameters: []tools.Parameter{
+			{
+				Name:        "query",
+				Type:        "string",
+				Description: "自然语言查询 — 要回忆的主题、问题或关键词。",
+				Required:    true,
+			},
+			{
+				Name:        "limit",
+				Type:        "integer",
+				Description: "最大结果数（1-20，默认：5）。",
+				Required:    false,
+				Default:     float64(5),
+			},
+			{
+				Name:        "scope",
+				Type:        "string",
+				Description: "只检索该范围的记忆。省略则检索所有可读范围。",
+				Required:    false,
+				Enum:        scopes,
+			},
+		},
+	}
+}
+
+func (t *MemorySearch) Execute(ctx context.Context, params map[string]any) (any, error) {
+	if t.mem == nil {
+		return nil, fmt.Errorf("MemorySearch：长期记忆未初始化")
+	}
+
+	queryStr, err := tools.ValidateRequiredString(params, "query")
+	if err != nil {
+		return nil, err
+	}
+
+	limit := 5
+	if raw, ok := getParam(params, "limit"); ok {
+		if v, ok := tools.ToFloat64(raw); ok && v > 0 {
+			limit = int(v)
+			if limit > 20 {
+				limit = 20
+			}
+		}
+	}
+
+	access := t.access(ctx)
+	if raw, ok := getParam(params, "scope"); ok {
+		if s, _ := raw.(string); s != "" {
+			scope, err := memory.ParseScope(s)
+			if err != nil {
+				return nil, err
+			}
+			if !access.CanRead(scope) {
+				return nil, fmt.Errorf("无权读取 %s 范围的记忆", scope)
+			}
+			if scope == memory.ScopeProject && access.ProjectDir == "" {
+				return nil, fmt.Errorf("MemorySearch：当前执行没有项目目录，无法检索 project 范围的记忆")
+			}
+			if scope == memory.ScopeSession && access.SessionID == "" {
+				return nil, fmt.Errorf("MemorySearch：当前执行没有会话，无法检索 session 范围的记忆")
+			}
+			access.Policy.Read = []memory.Scope{scope}
+		}
+	}
+
+	chunks, err := t.mem.Retrieve(memory.WithAccess(ctx, access), queryStr, goharnessmemory.WithMemoryLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("MemorySearch：%w", err)
+	}
+	if len(chunks) == 0 {
+		return "没有找到相关记忆。", nil
+	}
+	return formatMemoryResults(chunks), nil
+}
+
+// access returns the memory access of the agent in the execution of ctx.
+func (t *MemorySearch) access(ctx context.Context) memory.Access {
+	return memory.Access{
+		AgentName:  t.agentName,
+		ProjectDir: execctx.ProjectDir(ctx),
+		SessionID:  execctx.SessionID(ctx),
+		Policy:     memory.ScopePolicy{Read: t.policy.Read},
+	}
+}
+
+// ── MemorySearch output formatting ────────────────────────────────────────────────
+
+func formatMemoryResults(chunks []goharnessmemory.MemoryChunk) string {
+	var sb strings.Builder
+	sb.WriteString("## 记忆检索结果\n\n")
+	for _, c := range chunks {
+		sb.WriteString("[")
+		sb.WriteString(c.Summary)
+		sb.WriteString("]")
+		if !c.Timestamp.IsZero() {
+			sb.WriteString("[TIME:")
+			sb.WriteString(c.Timestamp.Format("2006-01-02 15:04"))
+			sb.WriteString("]")
+		}
+		if c.AgentName != "" {
+			sb.WriteString("[AGENT:")
+			sb.WriteString(c.AgentName)
+			sb.WriteString("]")
+		}
+		if len(c.Tags) > 0 {
+			sb.WriteString("[TAGS:")
+			sb.WriteString(strings.Join(c.Tags, ", "))
+			sb.WriteString("]")
+		}
+		sb.WriteString("\n")
+		if c.Content != "" && c.Content != c.Summary {
+			sb.WriteString(c.Content)
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n---\n\n")
+	}
+	return sb.String()
+}
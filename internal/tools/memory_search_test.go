@@ -0,0 +1,32 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DotNetAge/mindx/pkg/execctx"
+	"github.com/DotNetAge/mindx/pkg/memory"
+)
+
+func TestMemorySearchAccess(t *testing.T) {
+	ms := &MemorySearch{agentName: "coder", policy: memory.DefaultScopePolicy}
+
+	ctx := execctx.WithSessionID(execctx.WithProjectDir(context.Background(), "/work/api"), "s1")
+	a := ms.access(ctx)
+	if a.AgentName != "coder" || a.ProjectDir != "/work/api" || a.SessionID != "s1" {
+		t.Errorf("access = %+v, want the execution's project and session", a)
+	}
+	if len(a.Policy.Write) != 0 {
+		t.Errorf("search access may write %v", a.Policy.Write)
+	}
+
+	// Another session of the same runtime gets its own access.
+	other := ms.access(execctx.WithSessionID(execctx.WithProjectDir(context.Background(), "/work/web"), "s2"))
+	if other.ProjectDir != "/work/web" || other.SessionID != "s2" {
+		t.Errorf("access = %+v, want /work/web and s2", other)
+	}
+
+	if _, err := ms.Execute(context.Background(), map[string]any{"query": "deploy"}); err == nil {
+		t.Error("a search without memory should fail")
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"crypto/sha256"
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
 
@@ -12,6 +11,7 @@ import (
 	"github.com/DotNetAge/gorag/v2/core"
 	goragindexer "github.com/DotNetAge/gorag/v2/indexer"
 	"github.com/DotNetAge/gorag/v2/query"
+	"github.com/DotNetAge/mindx/pkg/execctx"
 )
 
 // QuickSearch performs semantic search over the local knowledge base.
@@ -93,13 +93,11 @@ func (t *QuickSearch) Execute(ctx context.Context, params map[string]any) (any,
 		}
 	}
 
-	// Apply projectDir filter — default to current working directory
+	// Apply projectDir filter — default to the execution's working directory
 	projectDirRaw, _ := getParam(params, "projectDir")
 	projectDir, _ := projectDirRaw.(string)
 	if projectDir == "" {
-		if cwd, err := os.Getwd(); err == nil {
-			projectDir = cwd
-		}
+		projectDir = execctx.WorkDir(ctx)
 	}
 
 	// Pre-check + 预计算 regionID 供 per-token 使用
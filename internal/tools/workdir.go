@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"context"
+	"runtime"
+	"strings"
+
+	"github.com/DotNetAge/goharness/tools"
+	"github.com/DotNetAge/mindx/pkg/execctx"
+)
+
+// WorkDirTools lists the built-in file and shell tools that are wrapped by
+// WithWorkDir so they honor the project directory carried in the context.
+var WorkDirTools = []string{"Read", "Write", "Edit", "MultiEdit", "Glob", "Grep", "LS", "Bash", "RunScript"}
+
+// pathParams are the parameter names treated as file system paths.
+var pathParams = []string{"file_path", "path", "dir", "directory", "cwd"}
+
+// dirParams maps the tools that search or run in a directory to the
+// parameter naming it. When the call leaves it out the tool would fall
+// back to the process working directory, so the project dir is filled in.
+var dirParams = map[string]string{
+	"Glob":      "path",
+	"Grep":      "path",
+	"LS":        "path",
+	"Bash":      "cwd",
+	"RunScript": "cwd",
+}
+
+// workDirTool decorates a FuncTool so that relative paths resolve against
+// execctx.ProjectDir(ctx), directory searches default to it and shell
+// commands run in it (or in the cwd they name) on every OS, with
+// MINDX_PROJECT_DIR / MINDX_SESSION_ID exported. Without a project dir in
+// the context the call passes through unchanged.
+type workDirTool struct {
+	inner tools.FuncTool
+}
+
+var _ tools.FuncTool = (*workDirTool)(nil)
+
+// WithWorkDir wraps a tool so it works in the execution's project directory
+// instead of the process working directory.
+func WithWorkDir(inner tools.FuncTool) tools.FuncTool {
+	return &workDirTool{inner: inner}
+}
+
+// Info returns the wrapped tool's metadata unchanged.
+func (t *workDirTool) Info() *tools.ToolInfo {
+	return t.inner.Info()
+}
+
+// Execute rewrites path and command parameters for the context's project
+// directory, then delegates to the wrapped tool.
+func (t *workDirTool) Execute(ctx context.Context, params map[string]any) (any, error) {
+	dir := execctx.ProjectDir(ctx)
+	if dir == "" {
+		return t.inner.Execute(ctx, params)
+	}
+
+	rewritten := make(map[string]any, len(params))
+	for k, v := range params {
+		rewritten[k] = v
+	}
+	for _, key := range pathParams {
+		if p, ok := rewritten[key].(string); ok {
+			rewritten[key] = execctx.ResolvePath(ctx, p)
+		}
+	}
+	cwd := dir
+	if key, ok := dirParams[t.inner.Info().Name]; ok {
+		if p, _ := rewritten[key].(string); p == "" {
+			rewritten[key] = dir
+		} else {
+			cwd = p
+		}
+	}
+	if cmd, ok := rewritten["command"].(string); ok && cmd != "" {
+		rewritten["command"] = shellPrelude(ctx, runtime.GOOS, cwd) + cmd
+	}
+	return t.inner.Execute(ctx, rewritten)
+}
+
+// shellPrelude returns a shell prefix that enters cwd and exports the
+// execution's environment variables: POSIX sh syntax, or cmd.exe syntax on
+// Windows.
+func shellPrelude(ctx context.Context, goos, cwd string) string {
+	dir, id := execctx.ProjectDir(ctx), execctx.SessionID(ctx)
+	var sb strings.Builder
+	if goos == "windows" {
+		sb.WriteString(`cd /d "` + cwd + `" && `)
+		sb.WriteString(`set "` + execctx.EnvProjectDir + "=" + dir + `" && `)
+		if id != "" {
+			sb.WriteString(`set "` + execctx.EnvSessionID + "=" + id + `" && `)
+		}
+		return sb.String()
+	}
+	sb.WriteString("cd " + shellQuote(cwd) + " && ")
+	sb.WriteString("export " + execctx.EnvProjectDir + "=" + shellQuote(dir))
+	if id != "" {
+		sb.WriteString(" " + execctx.EnvSessionID + "=" + shellQuote(id))
+	}
+	sb.WriteString(" && ")
+	return sb.String()
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
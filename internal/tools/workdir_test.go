@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/DotNetAge/goharness/tools"
+	"github.com/DotNetAge/mindx/pkg/execctx"
+)
+
+func TestShellPrelude(t *testing.T) {
+	ctx := execctx.WithSessionID(execctx.WithProjectDir(context.Background(), "/work/it's"), "s1")
+	if got, want := shellPrelude(ctx, "linux", "/work/it's"),
+		`cd '/work/it'\''s' && export MINDX_PROJECT_DIR='/work/it'\''s' MINDX_SESSION_ID='s1' && `; got != want {
+		t.Errorf("posix prelude = %q, want %q", got, want)
+	}
+
+	ctx = execctx.WithSessionID(execctx.WithProjectDir(context.Background(), `C:\work\api`), "s1")
+	if got, want := shellPrelude(ctx, "windows", `C:\work\api`),
+		`cd /d "C:\work\api" && set "MINDX_PROJECT_DIR=C:\work\api" && set "MINDX_SESSION_ID=s1" && `; got != want {
+		t.Errorf("windows prelude = %q, want %q", got, want)
+	}
+}
+
+// recordTool records the parameters of its last call.
+type recordTool struct {
+	name   string
+	params map[string]any
+}
+
+func (t *recordTool) Info() *tools.ToolInfo { return &tools.ToolInfo{Name: t.name} }
+
+func (t *recordTool) Execute(_ context.Context, params map[string]any) (any, error) {
+	t.params = params
+	return nil, nil
+}
+
+func TestWorkDirToolCwd(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("checks the POSIX prelude")
+	}
+	ctx := execctx.WithProjectDir(context.Background(), "/work/api")
+	inner := &recordTool{name: "Bash"}
+	tool := WithWorkDir(inner)
+
+	if _, err := tool.Execute(ctx, map[string]any{"command": "make", "cwd": "cmd/server"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := inner.params["cwd"]; got != "/work/api/cmd/server" {
+		t.Errorf("cwd = %v, want /work/api/cmd/server", got)
+	}
+	cmd, _ := inner.params["command"].(string)
+	if !strings.HasPrefix(cmd, "cd '/work/api/cmd/server' && ") || !strings.Contains(cmd, "MINDX_PROJECT_DIR='/work/api'") {
+		t.Errorf("command = %q, want it to enter the explicit cwd", cmd)
+	}
+
+	if _, err := tool.Execute(ctx, map[string]any{"command": "make"}); err != nil {
+		t.Fatal(err)
+	}
+	if cmd, _ := inner.params["command"].(string); !strings.HasPrefix(cmd, "cd '/work/api' && ") {
+		t.Errorf("command = %q, want it to enter the project dir", cmd)
+	}
+}
@@ -0,0 +1,74 @@
+// Package execctx carries per-execution settings — the project directory
+// and session ID — through a context.Context. Tools read them from the
+// context instead of the process-wide working directory and environment,
+// so executions in different projects can run concurrently.
+package execctx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type ctxKey int
+
+const (
+	projectDirKey ctxKey = iota
+	sessionIDKey
+)
+
+// Environment variables exported to child processes started by tools.
+const (
+	EnvProjectDir = "MINDX_PROJECT_DIR"
+	EnvSessionID  = "MINDX_SESSION_ID"
+)
+
+// WithProjectDir returns a context whose executions work in dir.
+func WithProjectDir(ctx context.Context, dir string) context.Context {
+	if dir == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, projectDirKey, filepath.Clean(dir))
+}
+
+// ProjectDir returns the project directory carried by ctx, or "".
+func ProjectDir(ctx context.Context) string {
+	dir, _ := ctx.Value(projectDirKey).(string)
+	return dir
+}
+
+// WithSessionID returns a context tagged with the executing session.
+func WithSessionID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, sessionIDKey, id)
+}
+
+// SessionID returns the session ID carried by ctx, or "".
+func SessionID(ctx context.Context) string {
+	id, _ := ctx.Value(sessionIDKey).(string)
+	return id
+}
+
+// WorkDir returns the directory an execution should work in: the project
+// directory from ctx, falling back to the process working directory.
+func WorkDir(ctx context.Context) string {
+	if dir := ProjectDir(ctx); dir != "" {
+		return dir
+	}
+	cwd, _ := os.Getwd()
+	return cwd
+}
+
+// ResolvePath makes a relative path absolute against the project directory
+// in ctx. Absolute paths, "~" paths and paths without a project directory
+// in ctx are returned unchanged.
+func ResolvePath(ctx context.Context, path string) string {
+	dir := ProjectDir(ctx)
+	if dir == "" || path == "" || filepath.IsAbs(path) || strings.HasPrefix(path, "~") {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
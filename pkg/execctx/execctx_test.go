@@ -0,0 +1,27 @@
+package execctx
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolvePath(t *testing.T) {
+	dir := filepath.Join(string(filepath.Separator), "work", "proj")
+	ctx := WithSessionID(WithProjectDir(context.Background(), dir), "sess-1")
+
+	if got, want := ResolvePath(ctx, "src/main.go"), filepath.Join(dir, "src", "main.go"); got != want {
+		t.Errorf("ResolvePath(relative) = %q, want %q", got, want)
+	}
+	abs := filepath.Join(string(filepath.Separator), "etc", "hosts")
+	if got := ResolvePath(ctx, abs); got != abs {
+		t.Errorf("ResolvePath(absolute) = %q, want unchanged", got)
+	}
+	if got := ResolvePath(context.Background(), "a.txt"); got != "a.txt" {
+		t.Errorf("ResolvePath without project dir = %q, want unchanged", got)
+	}
+
+	if WorkDir(ctx) != dir || SessionID(ctx) != "sess-1" {
+		t.Errorf("WorkDir = %q, SessionID = %q", WorkDir(ctx), SessionID(ctx))
+	}
+}
@@ -312,6 +312,29 @@ func (ix *Indexer) Enqueue(ctx context.Context, files ...string) int {
 	return len(moved)
 }
 
+// Reindex moves every indexed or failed file and directory back to
+// Enqueued and wakes the worker, so the whole project is indexed again
+// with the current embedder. Returns the number of entries requeued.
+func (ix *Indexer) Reindex(ctx context.Context) int {
+	if ix.manifest == nil {
+		return 0
+	}
+	moved, err := ix.manifest.requeueDone()
+	if err != nil && ix.logger != nil {
+		ix.logger.Error("indexer: reindex failed", fmt.Errorf("%w", err))
+	}
+	if len(moved) > 0 {
+		if ix.callbacks.OnFilesEnqueued != nil {
+			ix.callbacks.OnFilesEnqueued(ctx, moved)
+		}
+		select {
+		case ix.notify <- struct{}{}:
+		default:
+		}
+	}
+	return len(moved)
+}
+
 // upsertParentDirs ensures all parent directories of a file path have directory entries.
 // If an existing dir entry is in Indexed state, it is reset to Pending to trigger re-summarization.
 func (ix *Indexer) upsertParentDirs(ctx context.Context, absPath string) {
@@ -27,8 +27,7 @@ const (
 // openManifest opens (or creates) a boltDB manifest store for the given project directory.
 // The DB file is stored at ~/.mindx/projects/<sha256(projectDir)>/manifest.db
 func openManifest(projectDir string, baseDir string) (*manifestStore, error) {
-	hash := sha256.Sum256([]byte(projectDir))
-	dir := filepath.Join(baseDir, "projects", fmt.Sprintf("%x", hash))
+	dir := manifestDir(projectDir, baseDir)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("create manifest dir: %w", err)
 	}
@@ -250,6 +249,49 @@ func (ms *manifestStore) moveToEnqueuedByPaths(paths []string) ([]string, error)
 	return moved, err
 }
 
+// requeueDone moves every Indexed or Failed entry back to Enqueued so the
+// worker indexes it again. Returns the paths that were moved.
+func (ms *manifestStore) requeueDone() ([]string, error) {
+	var moved []string
+	err := ms.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(filesBucket))
+		return b.ForEach(func(k, v []byte) error {
+			meta := &FileMeta{}
+			if err := json.Unmarshal(v, meta); err != nil {
+				return nil
+			}
+			if meta.State != FileIndexed && meta.State != FileFailed {
+				return nil
+			}
+			meta.State = FileEnqueued
+			meta.Error = ""
+			data, err := json.Marshal(meta)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(k, data); err != nil {
+				return err
+			}
+			moved = append(moved, meta.Path)
+			return nil
+		})
+	})
+	return moved, err
+}
+
+// manifestDir returns the directory holding the manifest of projectDir.
+func manifestDir(projectDir, baseDir string) string {
+	hash := sha256.Sum256([]byte(projectDir))
+	return filepath.Join(baseDir, "projects", fmt.Sprintf("%x", hash))
+}
+
+// ManifestExists reports whether projectDir has an index manifest under
+// baseDir, i.e. whether it was ever indexed.
+func ManifestExists(projectDir, baseDir string) bool {
+	_, err := os.Stat(filepath.Join(manifestDir(projectDir, baseDir), "manifest.db"))
+	return err == nil
+}
+
 // meta helpers for project-level metadata
 func (ms *manifestStore) getMeta(key string) (string, error) {
 	var val string
@@ -0,0 +1,187 @@
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DotNetAge/mindx/pkg/storage"
+)
+
+// Reasons a chunk is archived.
+const (
+	// ArchiveReasonMerged marks a chunk consolidation merged into another.
+	ArchiveReasonMerged = "merged"
+	// ArchiveReasonDecayed marks a chunk pruned once its decayed score
+	// fell below the prune threshold.
+	ArchiveReasonDecayed = "decayed"
+)
+
+// ArchivedChunk is a memory removed from the vector store by
+// consolidation, kept in the archive so it can be restored.
+type ArchivedChunk struct {
+	ID         string    `json:"id"`
+	AgentName  string    `json:"agent_name,omitempty"`
+	SessionID  string    `json:"session_id,omitempty"`
+	ProjectDir string    `json:"project_dir,omitempty"`
+	Scope      Scope     `json:"scope,omitempty"`
+	Summary    string    `json:"summary"`
+	Content    string    `json:"content,omitempty"`
+	Tags       []string  `json:"tags,omitempty"`
+	Timestamp  time.Time `json:"timestamp,omitempty"`
+	Importance float64   `json:"importance"`
+	MergedFrom []string  `json:"merged_from,omitempty"`
+	Reason     string    `json:"reason"`
+	// MergedInto is the ID of the consolidated chunk, for merged chunks.
+	MergedInto string    `json:"merged_into,omitempty"`
+	ArchivedAt time.Time `json:"archived_at"`
+}
+
+// archive is the append-only store of archived chunks: one JSONL file per
+// month under dir, each line sealed when storage encryption is on.
+type archive struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func (a *archive) path(t time.Time) string {
+	return filepath.Join(a.dir, t.Format("2006-01")+".jsonl")
+}
+
+// append writes chunks to the archive file of the month they were
+// archived in.
+func (a *archive) append(chunks []ArchivedChunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := os.MkdirAll(a.dir, 0755); err != nil {
+		return fmt.Errorf("memory: 创建归档目录失败: %w", err)
+	}
+	byFile := map[string][]byte{}
+	for _, c := range chunks {
+		line, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		if line, err = storage.SealLine(line); err != nil {
+			return fmt.Errorf("memory: 加密归档失败: %w", err)
+		}
+		p := a.path(c.ArchivedAt)
+		byFile[p] = append(append(byFile[p], line...), '\n')
+	}
+	for p, data := range byFile {
+		f, err := os.OpenFile(p, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("memory: 写入归档失败: %w", err)
+		}
+		_, err = f.Write(data)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("memory: 写入归档失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// files returns the archive files, oldest month first.
+func (a *archive) files() ([]string, error) {
+	entries, err := os.ReadDir(a.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".jsonl") {
+			files = append(files, filepath.Join(a.dir, e.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// list returns archived chunks, most recently archived first, up to limit;
+// a limit of 0 or less returns them all. When id is set only the entries
+// of that chunk are returned.
+func (a *archive) list(id string, limit int) ([]ArchivedChunk, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	files, err := a.files()
+	if err != nil {
+		return nil, fmt.Errorf("memory: 读取归档失败: %w", err)
+	}
+	var out []ArchivedChunk
+	for _, p := range files {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("memory: 读取归档失败: %w", err)
+		}
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for sc.Scan() {
+			line := bytes.TrimSpace(sc.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			line, err := storage.OpenLine(line)
+			if err != nil {
+				return nil, fmt.Errorf("memory: %s: %w", p, err)
+			}
+			var c ArchivedChunk
+			if err := json.Unmarshal(line, &c); err != nil {
+				continue
+			}
+			if id == "" || c.ID == id {
+				out = append(out, c)
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return nil, fmt.Errorf("memory: 读取归档失败: %w", err)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].ArchivedAt.After(out[j].ArchivedAt)
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// reseal rewrites the archive files not in the current storage form and
+// reports how many were rewritten.
+func (a *archive) reseal() (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	files, err := a.files()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, p := range files {
+		changed, err := storage.ResealLines(p)
+		if err != nil {
+			return n, err
+		}
+		if changed {
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestArchiveAppendList(t *testing.T) {
+	a := &archive{dir: filepath.Join(t.TempDir(), "archive")}
+
+	if got, err := a.list("", 0); err != nil || len(got) != 0 {
+		t.Fatalf("list of a missing archive = %v, %v", got, err)
+	}
+
+	march := time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC)
+	april := time.Date(2026, 4, 2, 8, 0, 0, 0, time.UTC)
+	err := a.append([]ArchivedChunk{
+		{ID: "a", Summary: "first", Reason: ArchiveReasonMerged, MergedInto: "m", ArchivedAt: march},
+		{ID: "b", Summary: "second", Reason: ArchiveReasonDecayed, Importance: 0.2, ArchivedAt: april},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.append([]ArchivedChunk{{ID: "a", Summary: "again", Reason: ArchiveReasonDecayed, ArchivedAt: april.Add(time.Hour)}}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(a.dir, "*.jsonl"))
+	if len(files) != 2 {
+		t.Errorf("archive files = %v, want one per month", files)
+	}
+
+	all, err := a.list("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 || all[0].Summary != "again" || all[2].ID != "a" || all[2].MergedInto != "m" {
+		t.Errorf("list = %+v", all)
+	}
+	if got, _ := a.list("a", 1); len(got) != 1 || got[0].Summary != "again" {
+		t.Errorf("latest entry of a = %+v", got)
+	}
+	if got, _ := a.list("b", 0); len(got) != 1 || got[0].Importance != 0.2 {
+		t.Errorf("entries of b = %+v", got)
+	}
+
+	info, err := os.Stat(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("archive file mode = %v, want 0600", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,394 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/DotNetAge/goharness/memory"
+)
+
+// ConsolidatedTag tags the chunks consolidation merges.
+const ConsolidatedTag = "consolidated"
+
+// Summarizer merges a cluster of similar memories into one, returning the
+// merged summary and content.
+type Summarizer func(ctx context.Context, cluster []memory.MemoryChunk) (summary, content string, err error)
+
+// ConsolidateOptions configures a consolidation run.
+type ConsolidateOptions struct {
+	// Similarity is the least search score at which two memories of one
+	// agent and project cluster. Defaults to 0.9.
+	Similarity float64
+	// MaxCluster caps how many memories merge into one. Defaults to 8.
+	MaxCluster int
+	// PruneBelow archives memories whose decayed score has fallen below
+	// it. Zero prunes nothing.
+	PruneBelow float64
+	// Summarize merges a cluster. Required unless DryRun is set.
+	Summarize Summarizer
+	// DryRun reports what a run would merge and prune without changing
+	// anything.
+	DryRun bool
+}
+
+// ConsolidatedCluster is a set of memories merged into one.
+type ConsolidatedCluster struct {
+	// ID is the merged chunk; empty on a dry run.
+	ID         string   `json:"id,omitempty"`
+	AgentName  string   `json:"agent_name,omitempty"`
+	ProjectDir string   `json:"project_dir,omitempty"`
+	Summary    string   `json:"summary,omitempty"`
+	MergedFrom []string `json:"merged_from"`
+}
+
+// ConsolidateReport is the outcome of a consolidation run.
+type ConsolidateReport struct {
+	Scanned  int                   `json:"scanned"`
+	Clusters []ConsolidatedCluster `json:"clusters,omitempty"`
+	Pruned   []string              `json:"pruned,omitempty"`
+	DryRun   bool                  `json:"dry_run,omitempty"`
+}
+
+// consolidationEntry is a stored chunk as consolidation sees it.
+type consolidationEntry struct {
+	chunk      memory.MemoryChunk
+	scope      Scope
+	importance float64
+	mergedFrom []string
+}
+
+// Consolidate merges clusters of near-duplicate memories and prunes
+// decayed ones. Memories of one agent and project whose search score
+// against each other reaches opts.Similarity are merged through
+// opts.Summarize into a chunk recording their IDs under merged_from; the
+// merged chunk is as important as the most important of them, plus a
+// little for each repeat. Merged and pruned chunks move to the archive,
+// from which Restore brings them back.
+func (m *RAGMemory) Consolidate(ctx context.Context, opts ConsolidateOptions) (*ConsolidateReport, error) {
+	if m.semantic == nil || m.embedder == nil {
+		return nil, fmt.Errorf("memory: 语义索引器未初始化")
+	}
+	if m.archive == nil {
+		return nil, fmt.Errorf("memory: 未配置归档目录，无法整理记忆")
+	}
+	if !opts.DryRun && opts.Summarize == nil {
+		return nil, fmt.Errorf("memory: 整理记忆需要 Summarize")
+	}
+	if opts.Similarity <= 0 {
+		opts.Similarity = 0.9
+	}
+	if opts.MaxCluster < 2 {
+		opts.MaxCluster = 8
+	}
+	if !m.consolidateMu.TryLock() {
+		return nil, fmt.Errorf("memory: 记忆整理已在进行中")
+	}
+	defer m.consolidateMu.Unlock()
+
+	entries, err := m.consolidationEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report := &ConsolidateReport{Scanned: len(entries), DryRun: opts.DryRun}
+
+	// 最新的记忆作为聚类种子，合并后的记忆保留最新的时间戳。
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].chunk.Timestamp.After(entries[j].chunk.Timestamp)
+	})
+	byID := make(map[string]*consolidationEntry, len(entries))
+	for i := range entries {
+		byID[entries[i].chunk.ID] = &entries[i]
+	}
+
+	used := map[string]bool{}
+	now := time.Now()
+	for i := range entries {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		seed := &entries[i]
+		if used[seed.chunk.ID] {
+			continue
+		}
+		cluster, err := m.neighbours(ctx, seed, byID, used, opts)
+		if err != nil {
+			return report, err
+		}
+		if len(cluster) < 2 {
+			continue
+		}
+		ids := make([]string, len(cluster))
+		for j, e := range cluster {
+			ids[j] = e.chunk.ID
+			used[e.chunk.ID] = true
+		}
+		if opts.DryRun {
+			report.Clusters = append(report.Clusters, ConsolidatedCluster{
+				AgentName:  seed.chunk.AgentName,
+				ProjectDir: seed.chunk.ProjectDir,
+				Summary:    seed.chunk.Summary,
+				MergedFrom: ids,
+			})
+			continue
+		}
+		merged, err := m.mergeCluster(ctx, cluster, opts.Summarize, now)
+		if err != nil {
+			return report, err
+		}
+		used[merged.ID] = true
+		report.Clusters = append(report.Clusters, *merged)
+	}
+
+	if opts.PruneBelow > 0 && m.decay.HalfLife > 0 {
+		var pruned []*consolidationEntry
+		for i := range entries {
+			e := &entries[i]
+			if used[e.chunk.ID] {
+				continue
+			}
+			if m.decay.Score(e.importance, e.chunk.Timestamp, now) < opts.PruneBelow {
+				pruned = append(pruned, e)
+				report.Pruned = append(report.Pruned, e.chunk.ID)
+			}
+		}
+		if !opts.DryRun && len(pruned) > 0 {
+			archived := make([]ArchivedChunk, len(pruned))
+			for i, e := range pruned {
+				archived[i] = e.archived(ArchiveReasonDecayed, "", now)
+			}
+			if err := m.archive.append(archived); err != nil {
+				return report, err
+			}
+			for _, e := range pruned {
+				if err := m.Delete(ctx, e.chunk.ID); err != nil {
+					return report, err
+				}
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// consolidationEntries lists every stored chunk.
+func (m *RAGMemory) consolidationEntries(ctx context.Context) ([]consolidationEntry, error) {
+	var entries []consolidationEntry
+	const pageSize = 200
+	for offset := 0; ; offset += pageSize {
+		hits, err := m.semantic.List(ctx, offset, pageSize)
+		if err != nil {
+			return nil, fmt.Errorf("memory: 列出记忆失败: %w", err)
+		}
+		for _, hit := range hits {
+			chunk := hitToChunk(hit)
+			if chunk == nil {
+				continue
+			}
+			r := hitToRecord(hit)
+			entries = append(entries, consolidationEntry{
+				chunk:      *chunk,
+				scope:      r.scope(),
+				importance: importanceOf(hit.Metadata),
+				mergedFrom: metaStrings(hit.Metadata[metaMergedFrom]),
+			})
+		}
+		if len(hits) < pageSize {
+			break
+		}
+	}
+	return entries, nil
+}
+
+// neighbours returns seed and the unused chunks of its agent, project and
+// scope most similar to it, at least opts.Similarity, up to
+// opts.MaxCluster in all. Session-scoped chunks cluster only within their
+// session.
+func (m *RAGMemory) neighbours(ctx context.Context, seed *consolidationEntry, byID map[string]*consolidationEntry, used map[string]bool, opts ConsolidateOptions) ([]*consolidationEntry, error) {
+	cfg := memory.DefaultRetrieveConfig()
+	cfg.AgentName = seed.chunk.AgentName
+	cfg.ProjectDir = seed.chunk.ProjectDir
+	text := seed.chunk.Summary
+	if seed.chunk.Content != "" && seed.chunk.Content != seed.chunk.Summary {
+		text = seed.chunk.Summary + "\n" + seed.chunk.Content
+	}
+	q := m.buildQueryWithFilter(text, cfg)
+	if q == nil {
+		return nil, nil
+	}
+	hits, err := m.semantic.Search(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("memory: 检索失败: %w", err)
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+
+	cluster := []*consolidationEntry{seed}
+	for _, hit := range hits {
+		if len(cluster) >= opts.MaxCluster {
+			break
+		}
+		if float64(hit.Score) < opts.Similarity {
+			break
+		}
+		e, ok := byID[hit.ID]
+		if !ok || used[hit.ID] || hit.ID == seed.chunk.ID {
+			continue
+		}
+		// 过滤条件为空时不会限定范围，这里再按 agent / project / scope 精确比对。
+		if e.chunk.AgentName != seed.chunk.AgentName || e.chunk.ProjectDir != seed.chunk.ProjectDir {
+			continue
+		}
+		if e.scope != seed.scope || seed.scope == ScopeSession && e.chunk.SessionID != seed.chunk.SessionID {
+			continue
+		}
+		cluster = append(cluster, e)
+	}
+	return cluster, nil
+}
+
+// mergeCluster archives the members of cluster, stores their merged
+// chunk, then removes them, so a failure part way loses nothing.
+func (m *RAGMemory) mergeCluster(ctx context.Context, cluster []*consolidationEntry, summarize Summarizer, now time.Time) (*ConsolidatedCluster, error) {
+	chunks := make([]memory.MemoryChunk, len(cluster))
+	for i, e := range cluster {
+		chunks[i] = e.chunk
+	}
+	summary, content, err := summarize(ctx, chunks)
+	if err != nil {
+		return nil, fmt.Errorf("memory: 合并记忆失败: %w", err)
+	}
+	if content == "" {
+		content = summary
+	}
+
+	first := cluster[0].chunk
+	merged := memory.MemoryChunk{
+		ID:         contentHash(content),
+		AgentName:  first.AgentName,
+		SessionID:  first.SessionID,
+		ProjectDir: first.ProjectDir,
+		Summary:    summary,
+		Content:    content,
+		Timestamp:  first.Timestamp,
+	}
+	importance := 0.0
+	ids := make([]string, len(cluster))
+	seenTag := map[string]bool{}
+	for i, e := range cluster {
+		ids[i] = e.chunk.ID
+		importance = math.Max(importance, e.importance)
+		if e.chunk.SessionID != merged.SessionID {
+			merged.SessionID = ""
+		}
+		if e.chunk.Timestamp.After(merged.Timestamp) {
+			merged.Timestamp = e.chunk.Timestamp
+		}
+		for _, tag := range e.chunk.Tags {
+			if !seenTag[tag] {
+				seenTag[tag] = true
+				merged.Tags = append(merged.Tags, tag)
+			}
+		}
+	}
+	if !seenTag[ConsolidatedTag] {
+		merged.Tags = append(merged.Tags, ConsolidatedTag)
+	}
+	importance = math.Min(1, importance+0.1*float64(len(cluster)-1))
+
+	archived := make([]ArchivedChunk, len(cluster))
+	for i, e := range cluster {
+		archived[i] = e.archived(ArchiveReasonMerged, merged.ID, now)
+	}
+	if err := m.archive.append(archived); err != nil {
+		return nil, err
+	}
+	extra := map[string]any{metaScope: string(cluster[0].scope), metaImportance: importance, metaMergedFrom: ids}
+	if err := m.storeMemoryChunk(ctx, merged, extra); err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if id == merged.ID {
+			continue
+		}
+		if err := m.Delete(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	return &ConsolidatedCluster{
+		ID:         merged.ID,
+		AgentName:  merged.AgentName,
+		ProjectDir: merged.ProjectDir,
+		Summary:    merged.Summary,
+		MergedFrom: ids,
+	}, nil
+}
+
+func (e *consolidationEntry) archived(reason, mergedInto string, now time.Time) ArchivedChunk {
+	return ArchivedChunk{
+		ID:         e.chunk.ID,
+		AgentName:  e.chunk.AgentName,
+		SessionID:  e.chunk.SessionID,
+		ProjectDir: e.chunk.ProjectDir,
+		Scope:      e.scope,
+		Summary:    e.chunk.Summary,
+		Content:    e.chunk.Content,
+		Tags:       e.chunk.Tags,
+		Timestamp:  e.chunk.Timestamp,
+		Importance: e.importance,
+		MergedFrom: e.mergedFrom,
+		Reason:     reason,
+		MergedInto: mergedInto,
+		ArchivedAt: now,
+	}
+}
+
+// Archived returns archived memories, most recently archived first, up to
+// limit; a limit of 0 or less returns them all.
+func (m *RAGMemory) Archived(limit int) ([]ArchivedChunk, error) {
+	if m.archive == nil {
+		return nil, nil
+	}
+	return m.archive.list("", limit)
+}
+
+// Restore stores the most recently archived copy of the memory id again,
+// with its importance and provenance, and returns it. The restored memory
+// is stamped with the current time, so decay does not prune it again on
+// the next run. The archive entry is kept; the memory it was merged into
+// is not removed.
+func (m *RAGMemory) Restore(ctx context.Context, id string) (*ArchivedChunk, error) {
+	if m.archive == nil {
+		return nil, memory.ErrMemoryNotFound
+	}
+	found, err := m.archive.list(id, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, memory.ErrMemoryNotFound
+	}
+	a := found[0]
+	chunk := memory.MemoryChunk{
+		ID:         a.ID,
+		AgentName:  a.AgentName,
+		SessionID:  a.SessionID,
+		ProjectDir: a.ProjectDir,
+		Summary:    a.Summary,
+		Content:    a.Content,
+		Tags:       a.Tags,
+		Timestamp:  time.Now(),
+	}
+	extra := map[string]any{metaImportance: a.Importance}
+	if a.Scope != "" {
+		extra[metaScope] = string(a.Scope)
+	}
+	if len(a.MergedFrom) > 0 {
+		extra[metaMergedFrom] = a.MergedFrom
+	}
+	if err := m.storeMemoryChunk(ctx, chunk, extra); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Metadata keys a memory chunk carries besides its filter fields.
+const (
+	// metaImportance is the chunk's importance in [0, 1].
+	metaImportance = "importance"
+	// metaMergedFrom lists the IDs of the chunks a consolidated chunk was
+	// merged from.
+	metaMergedFrom = "merged_from"
+)
+
+// DefaultImportance is the importance of a chunk stored without one.
+const DefaultImportance = 0.5
+
+// Decay weighs how recent and how important a memory is against how well
+// it matches a query. A memory's decayed score is its importance halved
+// once per HalfLife of age; Retrieve ranks hits by
+//
+//	(1-Weight)*relevance + Weight*decayed score
+//
+// where relevance, in [0, 1], is the similarity of a vector search or the
+// normalized score of a lexical or hybrid one. A zero HalfLife or Weight
+// leaves the ranking to relevance alone.
+type Decay struct {
+	HalfLife time.Duration
+	Weight   float64
+}
+
+// DefaultDecay is the decay RAGMemory ranks with unless configured
+// otherwise.
+var DefaultDecay = Decay{HalfLife: 30 * 24 * time.Hour, Weight: 0.2}
+
+// Enabled reports whether d affects ranking.
+func (d Decay) Enabled() bool {
+	return d.HalfLife > 0 && d.Weight > 0
+}
+
+// Score returns the decayed score at now of a memory of the given
+// importance stored at ts. A memory without a timestamp does not decay.
+func (d Decay) Score(importance float64, ts, now time.Time) float64 {
+	if d.HalfLife <= 0 || ts.IsZero() {
+		return importance
+	}
+	age := now.Sub(ts)
+	if age <= 0 {
+		return importance
+	}
+	return importance * math.Exp2(-float64(age)/float64(d.HalfLife))
+}
+
+// rank returns the ranking score of a hit with the given relevance.
+func (d Decay) rank(relevance, importance float64, ts, now time.Time) float64 {
+	if !d.Enabled() {
+		return relevance
+	}
+	w := math.Min(d.Weight, 1)
+	return (1-w)*relevance + w*d.Score(importance, ts, now)
+}
+
+// rankedChunk is a retrieved chunk with the inputs of its ranking.
+type rankedChunk struct {
+	chunk      int // index into the retrieved chunks
+	relevance  float64
+	importance float64
+	ts         time.Time
+}
+
+// sortByDecay orders ranked, best first, by d at now. Ties keep their
+// relevance order.
+func (d Decay) sortByDecay(ranked []rankedChunk, now time.Time) {
+	if !d.Enabled() {
+		return
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		return d.rank(a.relevance, a.importance, a.ts, now) > d.rank(b.relevance, b.importance, b.ts, now)
+	})
+}
+
+// metaFloat reads a numeric metadata value, which is float64 after a JSON
+// round trip through the vector store.
+func metaFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// importanceOf returns the importance recorded in md, or
+// DefaultImportance.
+func importanceOf(md map[string]any) float64 {
+	if v, ok := metaFloat(md[metaImportance]); ok {
+		return math.Max(0, math.Min(v, 1))
+	}
+	return DefaultImportance
+}
+
+// metaStrings reads a string list from metadata.
+func metaStrings(v any) []string {
+	switch l := v.(type) {
+	case []string:
+		return l
+	case []any:
+		var out []string
+		for _, e := range l {
+			if s, ok := e.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// chunkExtras returns the scope and consolidation metadata of md that a
+// re-store must carry over.
+func chunkExtras(md map[string]any) map[string]any {
+	extra := map[string]any{}
+	if s, ok := md[metaScope].(string); ok && s != "" {
+		extra[metaScope] = s
+	}
+	if v, ok := metaFloat(md[metaImportance]); ok {
+		extra[metaImportance] = v
+	}
+	if from := metaStrings(md[metaMergedFrom]); len(from) > 0 {
+		extra[metaMergedFrom] = from
+	}
+	return extra
+}
@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDecayScore(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	d := Decay{HalfLife: 10 * 24 * time.Hour, Weight: 0.5}
+
+	tests := []struct {
+		importance float64
+		ts         time.Time
+		want       float64
+	}{
+		{0.8, now, 0.8},
+		{0.8, now.Add(-10 * 24 * time.Hour), 0.4},
+		{0.8, now.Add(-20 * 24 * time.Hour), 0.2},
+		{0.8, time.Time{}, 0.8},
+		{0.8, now.Add(time.Hour), 0.8},
+	}
+	for _, tt := range tests {
+		if got := d.Score(tt.importance, tt.ts, now); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Score(%v, %v) = %v, want %v", tt.importance, tt.ts, got, tt.want)
+		}
+	}
+	if got := (Decay{}).Score(0.3, now.AddDate(-1, 0, 0), now); got != 0.3 {
+		t.Errorf("Score without half-life = %v, want 0.3", got)
+	}
+}
+
+func TestDecaySortByDecay(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	old := now.AddDate(0, -6, 0)
+	ranked := []rankedChunk{
+		{chunk: 0, relevance: 0.82, importance: DefaultImportance, ts: old},
+		{chunk: 1, relevance: 0.80, importance: DefaultImportance, ts: now},
+		{chunk: 2, relevance: 0.50, importance: 1, ts: now},
+	}
+	order := func() []int {
+		out := make([]int, len(ranked))
+		for i, r := range ranked {
+			out[i] = r.chunk
+		}
+		return out
+	}
+
+	// Without decay the relevance order stands.
+	Decay{}.sortByDecay(ranked, now)
+	if got := order(); got[0] != 0 || got[1] != 1 || got[2] != 2 {
+		t.Fatalf("order without decay = %v", got)
+	}
+
+	// A fresh memory outranks a slightly closer stale one.
+	DefaultDecay.sortByDecay(ranked, now)
+	if got := order(); got[0] != 1 || got[1] != 0 || got[2] != 2 {
+		t.Errorf("order with decay = %v, want [1 0 2]", got)
+	}
+}
+
+func TestChunkExtras(t *testing.T) {
+	md := map[string]any{
+		"agent_name":   "coder",
+		metaImportance: 0.7,
+		metaMergedFrom: []any{"a", "b"},
+	}
+	extra := chunkExtras(md)
+	if extra[metaImportance] != 0.7 || len(extra) != 2 {
+		t.Errorf("extras = %v", extra)
+	}
+	if from, _ := extra[metaMergedFrom].([]string); len(from) != 2 || from[1] != "b" {
+		t.Errorf("merged_from = %v", extra[metaMergedFrom])
+	}
+	if got := importanceOf(map[string]any{}); got != DefaultImportance {
+		t.Errorf("default importance = %v", got)
+	}
+	if got := importanceOf(map[string]any{metaImportance: 3.0}); got != 1 {
+		t.Errorf("importance is not clamped: %v", got)
+	}
+}
@@ -0,0 +1,152 @@
+package memory
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	goragcore "github.com/DotNetAge/gorag/v2/core"
+	"github.com/DotNetAge/gorag/v2/embedder"
+)
+
+// Embedder providers.
+const (
+	// EmbedderONNX runs a local ONNX model of a registered family. It is
+	// the default.
+	EmbedderONNX = "onnx"
+	// EmbedderOpenAI calls an OpenAI-compatible /embeddings endpoint.
+	EmbedderOpenAI = "openai"
+	// EmbedderHash is the deterministic hashing embedder, for tests.
+	EmbedderHash = "hash"
+)
+
+// DefaultONNXFamily is the ONNX model family used when none is set.
+const DefaultONNXFamily = "chinese-clip"
+
+// EmbedderConfig selects and configures the embedder behind memory and the
+// knowledge base.
+type EmbedderConfig struct {
+	// Provider is EmbedderONNX (the default), EmbedderOpenAI or
+	// EmbedderHash.
+	Provider string
+	// Family is the ONNX model family; defaults to DefaultONNXFamily.
+	Family string
+	// ModelPath is the ONNX model file.
+	ModelPath string
+	// BaseURL is the endpoint root the OpenAI provider posts
+	// /embeddings to, e.g. https://api.openai.com/v1.
+	BaseURL string
+	// Model is the model name sent to the endpoint.
+	Model string
+	// APIKey is sent as a bearer token when set.
+	APIKey string
+	// Dim is the vector dimension. The hash provider defaults to
+	// DefaultHashDim; the OpenAI provider probes the endpoint when it is
+	// zero. ONNX models have a fixed dimension and ignore it.
+	Dim int
+	// Timeout bounds each endpoint request; defaults to 60s.
+	Timeout time.Duration
+}
+
+func (c EmbedderConfig) provider() string {
+	if c.Provider == "" {
+		return EmbedderONNX
+	}
+	return c.Provider
+}
+
+func (c EmbedderConfig) family() string {
+	if c.Family == "" {
+		return DefaultONNXFamily
+	}
+	return c.Family
+}
+
+// ID identifies the vectors the configured embedder produces: two configs
+// with the same ID embed text alike. Stores keep vectors of one embedder
+// only, so a change of ID calls for a re-embedding (see Reembed).
+func (c EmbedderConfig) ID() string {
+	var key string
+	switch p := c.provider(); p {
+	case EmbedderONNX:
+		key = p + "|" + c.family() + "|" + filepath.Base(c.ModelPath)
+	case EmbedderOpenAI:
+		key = fmt.Sprintf("%s|%s|%s|%d", p, strings.TrimRight(c.BaseURL, "/"), c.Model, c.Dim)
+	default:
+		key = fmt.Sprintf("%s|%d", p, c.Dim)
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:6])
+}
+
+// ONNXFactory creates an embedder from an ONNX model file.
+type ONNXFactory func(modelPath string) (goragcore.Embedder, error)
+
+var (
+	onnxMu       sync.RWMutex
+	onnxFamilies = map[string]ONNXFactory{
+		DefaultONNXFamily: func(modelPath string) (goragcore.Embedder, error) {
+			return embedder.NewChineseClipEmbedder(embedder.WithModelFile(modelPath))
+		},
+	}
+)
+
+// RegisterONNXFamily makes an ONNX model family available to the ONNX
+// provider under name, replacing any earlier registration.
+func RegisterONNXFamily(name string, factory ONNXFactory) {
+	onnxMu.Lock()
+	defer onnxMu.Unlock()
+	onnxFamilies[name] = factory
+}
+
+// ONNXFamilies returns the registered ONNX model families, sorted.
+func ONNXFamilies() []string {
+	onnxMu.RLock()
+	defer onnxMu.RUnlock()
+	names := make([]string, 0, len(onnxFamilies))
+	for name := range onnxFamilies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewEmbedder creates the embedder cfg selects.
+func NewEmbedder(cfg EmbedderConfig) (goragcore.Embedder, error) {
+	switch cfg.provider() {
+	case EmbedderONNX:
+		if cfg.ModelPath == "" {
+			return nil, fmt.Errorf("memory: 未配置 embedder 模型文件")
+		}
+		onnxMu.RLock()
+		factory, ok := onnxFamilies[cfg.family()]
+		onnxMu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("memory: 未知的 ONNX 模型系列 %q（可选 %s）", cfg.family(), strings.Join(ONNXFamilies(), "、"))
+		}
+		emb, err := factory(cfg.ModelPath)
+		if err != nil {
+			return nil, fmt.Errorf("memory: 创建 embedder 失败: %w", err)
+		}
+		return emb, nil
+	case EmbedderOpenAI:
+		return NewHTTPEmbedder(cfg)
+	case EmbedderHash:
+		return NewHashEmbedder(cfg.Dim), nil
+	}
+	return nil, fmt.Errorf("memory: 未知的 embedder 类型 %q（可选 onnx、openai、hash）", cfg.Provider)
+}
+
+// NewEmbedderFromConfig creates the default ONNX embedder from a model
+// file; an empty path yields no embedder.
+func NewEmbedderFromConfig(modelPath string) (goragcore.Embedder, error) {
+	if modelPath == "" {
+		return nil, nil
+	}
+	return NewEmbedder(EmbedderConfig{ModelPath: modelPath})
+}
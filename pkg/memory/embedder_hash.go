@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+
+	goragcore "github.com/DotNetAge/gorag/v2/core"
+)
+
+// DefaultHashDim is the dimension of a HashEmbedder created without one.
+const DefaultHashDim = 256
+
+var _ goragcore.Embedder = (*HashEmbedder)(nil)
+
+// HashEmbedder embeds text by feature hashing: each lexical term (see
+// lexicalTerms) adds ±1 to the dimension its hash picks, and the vector is
+// then normalized to unit length. It needs no model, gives the same vector
+// for the same text on every machine, and ranks texts sharing words as
+// similar, which makes it a fixture for tests rather than a semantic
+// embedder.
+type HashEmbedder struct {
+	dim int
+}
+
+// NewHashEmbedder returns a HashEmbedder of dimension dim, or of
+// DefaultHashDim when dim is not positive.
+func NewHashEmbedder(dim int) *HashEmbedder {
+	if dim <= 0 {
+		dim = DefaultHashDim
+	}
+	return &HashEmbedder{dim: dim}
+}
+
+// Dim returns the vector dimension.
+func (e *HashEmbedder) Dim() int {
+	return e.dim
+}
+
+// Embed returns the vector of each text.
+func (e *HashEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		out[i] = e.embed(text)
+	}
+	return out, nil
+}
+
+func (e *HashEmbedder) embed(text string) []float32 {
+	v := make([]float32, e.dim)
+	for _, t := range lexicalTerms(text) {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(t))
+		sum := h.Sum64()
+		if sum>>63 == 1 {
+			v[sum%uint64(e.dim)]--
+		} else {
+			v[sum%uint64(e.dim)]++
+		}
+	}
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	if norm == 0 {
+		return v
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range v {
+		v[i] *= scale
+	}
+	return v
+}
@@ -0,0 +1,151 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	goragcore "github.com/DotNetAge/gorag/v2/core"
+)
+
+// httpEmbedBatch caps the inputs of one /embeddings request.
+const httpEmbedBatch = 64
+
+var _ goragcore.Embedder = (*HTTPEmbedder)(nil)
+
+// HTTPEmbedder embeds text through an OpenAI-compatible /embeddings
+// endpoint, as served by OpenAI, Ollama, vLLM, LM Studio and most hosted
+// embedding APIs.
+type HTTPEmbedder struct {
+	url    string
+	model  string
+	apiKey string
+	dim    int
+	client *http.Client
+}
+
+type embeddingsRequest struct {
+	Model string   `json:"model,omitempty"`
+	Input []string `json:"input"`
+}
+
+type embeddingsResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// NewHTTPEmbedder creates an HTTPEmbedder for cfg.BaseURL. Without a
+// configured dimension it embeds one probe text to learn it.
+func NewHTTPEmbedder(cfg EmbedderConfig) (*HTTPEmbedder, error) {
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("memory: embedder 未配置 base_url")
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+	e := &HTTPEmbedder{
+		url:    strings.TrimRight(cfg.BaseURL, "/") + "/embeddings",
+		model:  cfg.Model,
+		apiKey: cfg.APIKey,
+		dim:    cfg.Dim,
+		client: &http.Client{Timeout: timeout},
+	}
+	if e.dim <= 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		vecs, err := e.post(ctx, []string{"dimension probe"})
+		if err != nil {
+			return nil, fmt.Errorf("memory: 探测向量维度失败: %w", err)
+		}
+		e.dim = len(vecs[0])
+	}
+	return e, nil
+}
+
+// Dim returns the vector dimension.
+func (e *HTTPEmbedder) Dim() int {
+	return e.dim
+}
+
+// Embed returns the vector of each text, in batches of httpEmbedBatch.
+func (e *HTTPEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += httpEmbedBatch {
+		end := min(start+httpEmbedBatch, len(texts))
+		vecs, err := e.post(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range vecs {
+			if len(v) != e.dim {
+				return nil, fmt.Errorf("memory: embedder 返回 %d 维向量，应为 %d 维", len(v), e.dim)
+			}
+		}
+		out = append(out, vecs...)
+	}
+	return out, nil
+}
+
+// post sends one /embeddings request and returns the vectors in input
+// order.
+func (e *HTTPEmbedder) post(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(embeddingsRequest{Model: e.model, Input: texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("memory: 请求 embedder 失败: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return nil, fmt.Errorf("memory: 读取 embedder 响应失败: %w", err)
+	}
+
+	var parsed embeddingsResponse
+	jsonErr := json.Unmarshal(data, &parsed)
+	if resp.StatusCode != http.StatusOK {
+		msg := strings.TrimSpace(string(data))
+		if jsonErr == nil && parsed.Error != nil && parsed.Error.Message != "" {
+			msg = parsed.Error.Message
+		}
+		if len(msg) > 200 {
+			msg = msg[:200]
+		}
+		return nil, fmt.Errorf("memory: embedder 返回 %s: %s", resp.Status, msg)
+	}
+	if jsonErr != nil {
+		return nil, fmt.Errorf("memory: 解析 embedder 响应失败: %w", jsonErr)
+	}
+	if len(parsed.Data) != len(texts) {
+		return nil, fmt.Errorf("memory: embedder 返回 %d 个向量，应为 %d 个", len(parsed.Data), len(texts))
+	}
+	vecs := make([][]float32, len(texts))
+	for _, d := range parsed.Data {
+		if d.Index < 0 || d.Index >= len(texts) || vecs[d.Index] != nil {
+			return nil, fmt.Errorf("memory: embedder 返回的向量序号 %d 无效", d.Index)
+		}
+		vecs[d.Index] = d.Embedding
+	}
+	return vecs, nil
+}
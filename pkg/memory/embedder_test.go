@@ -0,0 +1,244 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	goragcore "github.com/DotNetAge/gorag/v2/core"
+	"github.com/DotNetAge/mindx/pkg/storage"
+)
+
+func TestHashEmbedder(t *testing.T) {
+	e := NewHashEmbedder(0)
+	if e.Dim() != DefaultHashDim {
+		t.Fatalf("Dim = %d, want %d", e.Dim(), DefaultHashDim)
+	}
+	vecs, err := e.Embed(context.Background(), []string{
+		"fix PROJ-1234 in parseConfig",
+		"fix PROJ-1234 in parseConfig",
+		"缓存失效导致登录失败",
+		"",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range vecs[:3] {
+		if len(v) != DefaultHashDim {
+			t.Fatalf("vector %d has %d dimensions", i, len(v))
+		}
+		if n := norm(v); math.Abs(n-1) > 1e-5 {
+			t.Errorf("vector %d norm = %v, want 1", i, n)
+		}
+	}
+	if dot(vecs[0], vecs[1]) < 0.9999 {
+		t.Error("same text embeds differently")
+	}
+	if dot(vecs[0], vecs[2]) > 0.5 {
+		t.Errorf("unrelated texts too similar: %v", dot(vecs[0], vecs[2]))
+	}
+	if norm(vecs[3]) != 0 {
+		t.Error("empty text should embed to the zero vector")
+	}
+}
+
+func TestHTTPEmbedder(t *testing.T) {
+	var requests []embeddingsRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"message":"bad key"}}`))
+			return
+		}
+		var req embeddingsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requests = append(requests, req)
+		type item struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		}
+		var resp struct {
+			Data []item `json:"data"`
+		}
+		// Answer out of order; the embedder must sort by index.
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			resp.Data = append(resp.Data, item{Index: i, Embedding: []float32{float32(len(req.Input[i])), 1, 0}})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	e, err := NewHTTPEmbedder(EmbedderConfig{BaseURL: srv.URL + "/v1/", Model: "text-embed", APIKey: "sk-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Dim() != 3 {
+		t.Fatalf("probed Dim = %d, want 3", e.Dim())
+	}
+
+	texts := make([]string, httpEmbedBatch+2)
+	for i := range texts {
+		texts[i] = strings.Repeat("x", i)
+	}
+	requests = nil
+	vecs, err := e.Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 || len(requests[0].Input) != httpEmbedBatch || requests[0].Model != "text-embed" {
+		t.Fatalf("requests = %d, first has %d inputs", len(requests), len(requests[0].Input))
+	}
+	for i, v := range vecs {
+		if int(v[0]) != i {
+			t.Fatalf("vector %d belongs to text %d", i, int(v[0]))
+		}
+	}
+
+	_, err = NewHTTPEmbedder(EmbedderConfig{BaseURL: srv.URL + "/v1", Dim: 3})
+	if err != nil {
+		t.Fatalf("configured Dim should skip the probe: %v", err)
+	}
+	bad, _ := NewHTTPEmbedder(EmbedderConfig{BaseURL: srv.URL + "/v1", Dim: 3})
+	if _, err := bad.Embed(context.Background(), []string{"a"}); err == nil || !strings.Contains(err.Error(), "bad key") {
+		t.Errorf("Embed without key: err = %v, want the endpoint's message", err)
+	}
+	wrongDim, _ := NewHTTPEmbedder(EmbedderConfig{BaseURL: srv.URL + "/v1", APIKey: "sk-test", Dim: 8})
+	if _, err := wrongDim.Embed(context.Background(), []string{"a"}); err == nil {
+		t.Error("Embed should reject vectors of the wrong dimension")
+	}
+}
+
+func TestNewEmbedder(t *testing.T) {
+	emb, err := NewEmbedder(EmbedderConfig{Provider: EmbedderHash, Dim: 32})
+	if err != nil || emb.Dim() != 32 {
+		t.Fatalf("hash embedder: %v, %v", emb, err)
+	}
+	for _, cfg := range []EmbedderConfig{
+		{Provider: "word2vec"},
+		{Provider: EmbedderONNX},
+		{Provider: EmbedderONNX, Family: "unknown", ModelPath: "/m.onnx"},
+		{Provider: EmbedderOpenAI},
+	} {
+		if _, err := NewEmbedder(cfg); err == nil {
+			t.Errorf("NewEmbedder(%+v) should fail", cfg)
+		}
+	}
+	if emb, err := NewEmbedderFromConfig(""); emb != nil || err != nil {
+		t.Errorf("NewEmbedderFromConfig(\"\") = %v, %v", emb, err)
+	}
+}
+
+func TestEmbedderConfigID(t *testing.T) {
+	a := EmbedderConfig{ModelPath: "/ws/data/models/model_q4.onnx"}
+	same := EmbedderConfig{Provider: EmbedderONNX, Family: DefaultONNXFamily, ModelPath: "/other/model_q4.onnx"}
+	if a.ID() != same.ID() {
+		t.Error("ID should not depend on the model's directory or on explicit defaults")
+	}
+	for _, other := range []EmbedderConfig{
+		{ModelPath: "/ws/data/models/model_fp16.onnx"},
+		{Provider: EmbedderHash},
+		{Provider: EmbedderHash, Dim: 64},
+		{Provider: EmbedderOpenAI, BaseURL: "http://localhost:11434/v1", Model: "bge-m3"},
+	} {
+		if other.ID() == a.ID() {
+			t.Errorf("%+v shares the ID of %+v", other, a)
+		}
+	}
+}
+
+func TestResolveVectorStore(t *testing.T) {
+	dir := t.TempDir()
+
+	// Read-only opens of an unstamped directory leave it unstamped.
+	file, prev, err := ResolveVectorStore(dir, "shared", "aaa", 512, true)
+	if err != nil || file != "shared.db" || prev != nil {
+		t.Fatalf("read-only = %q, %v, %v", file, prev, err)
+	}
+	if s, _ := ReadEmbedderStamp(dir); s != nil {
+		t.Fatal("read-only resolve wrote a stamp")
+	}
+
+	// The first writable open adopts the legacy store.
+	file, prev, err = ResolveVectorStore(dir, "shared", "aaa", 512, false)
+	if err != nil || file != "shared.db" || prev != nil {
+		t.Fatalf("first = %q, %v, %v", file, prev, err)
+	}
+	file, prev, _ = ResolveVectorStore(dir, "shared", "aaa", 512, false)
+	if file != "shared.db" || prev != nil {
+		t.Fatalf("same embedder = %q, %v", file, prev)
+	}
+
+	// Another embedder gets a store of its own until the stamp moves.
+	file, prev, _ = ResolveVectorStore(dir, "shared", "bbb", 256, false)
+	if file != "shared-bbb.db" || prev == nil || prev.File != "shared.db" || prev.Dim != 512 {
+		t.Fatalf("new embedder = %q, %+v", file, prev)
+	}
+	if err := WriteEmbedderStamp(dir, EmbedderStamp{ID: "bbb", Dim: 256, File: file}); err != nil {
+		t.Fatal(err)
+	}
+	file, prev, _ = ResolveVectorStore(dir, "shared", "bbb", 256, false)
+	if file != "shared-bbb.db" || prev != nil {
+		t.Fatalf("after migration = %q, %v", file, prev)
+	}
+
+	if file, _, _ := ResolveVectorStore(dir, "shared", "", 256, false); file != "shared.db" {
+		t.Errorf("empty id = %q, want shared.db", file)
+	}
+}
+
+func dot(a, b []float32) float64 {
+	var s float64
+	for i := range a {
+		s += float64(a[i]) * float64(b[i])
+	}
+	return s
+}
+
+func norm(v []float32) float64 {
+	return math.Sqrt(dot(v, v))
+}
+
+func TestOpeningEmbedder(t *testing.T) {
+	key, err := storage.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring, err := storage.NewKeyring("k1", map[string][]byte{"k1": key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage.Use(ring, true)
+	t.Cleanup(func() { storage.Use(nil, false) })
+
+	const text = "部署窗口定在周五晚上"
+	sealed, err := storage.SealText(text)
+	if err != nil || sealed == text {
+		t.Fatalf("SealText = %q, %v", sealed, err)
+	}
+
+	inner := NewHashEmbedder(0)
+	got, err := openingEmbedder{inner}.Embed(context.Background(), []string{sealed, text})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := inner.Embed(context.Background(), []string{text})
+	if dot(got[0], want[0]) < 0.9999 || dot(got[1], want[0]) < 0.9999 {
+		t.Error("sealed text not embedded as its plaintext")
+	}
+
+	hit, err := OpenHit(goragcore.Hit{ID: "m1", Content: sealed})
+	if err != nil || hit.Content != text {
+		t.Errorf("OpenHit content = %q, %v", hit.Content, err)
+	}
+}
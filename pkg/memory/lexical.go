@@ -0,0 +1,308 @@
+package memory
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/DotNetAge/mindx/pkg/storage"
+)
+
+// BM25 parameters.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// lexicalIndex is an in-memory BM25 index over the summary and content of
+// every chunk. It complements semantic search for exact identifiers such
+// as ticket numbers, function names and error codes, which embeddings
+// tend to blur. It is rebuilt from the metadata index (or the vector
+// store) on first use and kept in step by RAGMemory's writes.
+type lexicalIndex struct {
+	mu       sync.RWMutex
+	docs     map[string]*lexicalDoc
+	postings map[string]map[string]int // term → chunk ID → term frequency
+	totalLen int
+	// vectors is the vector store's chunk count the index is in step
+	// with, or -1 before the first build.
+	vectors int
+}
+
+type lexicalDoc struct {
+	record metaRecord
+	terms  map[string]int
+	length int
+}
+
+// lexicalHit is a chunk matching a lexical query.
+type lexicalHit struct {
+	record metaRecord
+	score  float64
+}
+
+func newLexicalIndex() *lexicalIndex {
+	return &lexicalIndex{
+		docs:     map[string]*lexicalDoc{},
+		postings: map[string]map[string]int{},
+		vectors:  -1,
+	}
+}
+
+// inStep reports whether the index matches a vector store of n chunks.
+func (x *lexicalIndex) inStep(n int) bool {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.vectors == n
+}
+
+// reset replaces the index with records, in step with a vector store of
+// vectors chunks. Records whose sealed text cannot be opened are left out.
+func (x *lexicalIndex) reset(records []metaRecord, vectors int) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.docs = make(map[string]*lexicalDoc, len(records))
+	x.postings = map[string]map[string]int{}
+	x.totalLen = 0
+	for _, r := range records {
+		summary, err := storage.OpenText(r.Summary)
+		if err != nil {
+			continue
+		}
+		content, err := storage.OpenText(r.Content)
+		if err != nil {
+			continue
+		}
+		x.addLocked(r, summary, content)
+	}
+	x.vectors = vectors
+}
+
+// put indexes a chunk stored with the given plaintext summary and content,
+// replacing any earlier version of it.
+func (x *lexicalIndex) put(r metaRecord, summary, content string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.vectors < 0 {
+		// Not built yet; the first lookup builds it from the store.
+		return
+	}
+	if !x.removeLocked(r.ID) {
+		x.vectors++
+	}
+	x.addLocked(r, summary, content)
+}
+
+// remove drops a chunk from the index.
+func (x *lexicalIndex) remove(id string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.vectors < 0 {
+		return
+	}
+	if x.removeLocked(id) {
+		x.vectors--
+	}
+}
+
+func (x *lexicalIndex) addLocked(r metaRecord, summary, content string) {
+	text := summary
+	if content != "" && content != summary {
+		text = summary + "\n" + content
+	}
+	terms := map[string]int{}
+	length := 0
+	for _, t := range lexicalTerms(text) {
+		terms[t]++
+		length++
+	}
+	for _, tag := range r.Tags {
+		for _, t := range lexicalTerms(tag) {
+			terms[t]++
+			length++
+		}
+	}
+	x.docs[r.ID] = &lexicalDoc{record: r, terms: terms, length: length}
+	x.totalLen += length
+	for t, tf := range terms {
+		p := x.postings[t]
+		if p == nil {
+			p = map[string]int{}
+			x.postings[t] = p
+		}
+		p[r.ID] = tf
+	}
+}
+
+func (x *lexicalIndex) removeLocked(id string) bool {
+	doc, ok := x.docs[id]
+	if !ok {
+		return false
+	}
+	for t := range doc.terms {
+		if p := x.postings[t]; p != nil {
+			delete(p, id)
+			if len(p) == 0 {
+				delete(x.postings, t)
+			}
+		}
+	}
+	x.totalLen -= doc.length
+	delete(x.docs, id)
+	return true
+}
+
+// search returns up to limit chunks matching f ranked by BM25 against
+// query, best first.
+func (x *lexicalIndex) search(query string, f MemoryFilter, limit int) []lexicalHit {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	n := len(x.docs)
+	if n == 0 {
+		return nil
+	}
+	avgLen := float64(x.totalLen) / float64(n)
+	if avgLen == 0 {
+		avgLen = 1
+	}
+
+	scores := map[string]float64{}
+	seen := map[string]bool{}
+	for _, t := range lexicalTerms(query) {
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		p := x.postings[t]
+		if len(p) == 0 {
+			continue
+		}
+		df := float64(len(p))
+		idf := math.Log(1 + (float64(n)-df+0.5)/(df+0.5))
+		for id, tf := range p {
+			doc := x.docs[id]
+			if !f.matches(&doc.record) {
+				continue
+			}
+			ftf := float64(tf)
+			norm := ftf + bm25K1*(1-bm25B+bm25B*float64(doc.length)/avgLen)
+			scores[id] += idf * ftf * (bm25K1 + 1) / norm
+		}
+	}
+
+	hits := make([]lexicalHit, 0, len(scores))
+	for id, s := range scores {
+		hits = append(hits, lexicalHit{record: x.docs[id].record, score: s})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return hits[i].record.Timestamp > hits[j].record.Timestamp
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// lexicalTerms splits text into lower-cased index terms. Runs of letters,
+// digits and underscores form words; a word may span the joiners - . / :
+// and #, so "PROJ-1234", "v1.2.3" and "pkg/memory" stay whole, and such
+// compound words, snake_case and camelCase identifiers also yield their
+// parts. Han, kana and Hangul text, which has no spaces, yields every
+// character and every pair of adjacent characters.
+func lexicalTerms(text string) []string {
+	var terms []string
+	var word []rune
+	var prevCJK rune
+
+	flush := func() {
+		for len(word) > 0 && isLexicalJoiner(word[len(word)-1]) {
+			word = word[:len(word)-1]
+		}
+		if len(word) > 0 {
+			terms = appendWordTerms(terms, string(word))
+		}
+		word = word[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flush()
+			terms = append(terms, string(r))
+			if prevCJK != 0 {
+				terms = append(terms, string([]rune{prevCJK, r}))
+			}
+			prevCJK = r
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			word = append(word, r)
+		case isLexicalJoiner(r) && len(word) > 0:
+			word = append(word, r)
+		default:
+			flush()
+		}
+		prevCJK = 0
+	}
+	flush()
+	return terms
+}
+
+func isLexicalJoiner(r rune) bool {
+	switch r {
+	case '-', '.', '/', ':', '#':
+		return true
+	}
+	return false
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r)
+}
+
+// appendWordTerms appends the terms of one word: the word itself and,
+// for compound words and identifiers, its parts.
+func appendWordTerms(terms []string, word string) []string {
+	whole := strings.ToLower(word)
+	terms = append(terms, whole)
+
+	var parts []string
+	for _, p := range strings.FieldsFunc(word, func(r rune) bool {
+		return r == '_' || isLexicalJoiner(r)
+	}) {
+		parts = append(parts, splitCamel(p)...)
+	}
+	if len(parts) > 1 {
+		for _, p := range parts {
+			terms = append(terms, strings.ToLower(p))
+		}
+	}
+	return terms
+}
+
+// splitCamel splits a camelCase or PascalCase word at each lower-to-upper
+// change and at the last capital of an acronym, so "parseHTTPConfig"
+// yields parse, HTTP and Config.
+func splitCamel(word string) []string {
+	runes := []rune(word)
+	var parts []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+		boundary := unicode.IsLower(prev) && unicode.IsUpper(cur) ||
+			unicode.IsUpper(prev) && unicode.IsUpper(cur) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if boundary {
+			parts = append(parts, string(runes[start:i]))
+			start = i
+		}
+	}
+	return append(parts, string(runes[start:]))
+}
@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLexicalTerms(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Fix PROJ-1234 today.", "[fix proj-1234 proj 1234 today]"},
+		{"call parseHTTPConfig()", "[call parsehttpconfig parse http config]"},
+		{"ERR_CONN_RESET at pkg/memory", "[err_conn_reset err conn reset at pkg/memory pkg memory]"},
+		{"v1.2.3", "[v1.2.3 v1 2 3]"},
+		{"记忆整理", "[记 忆 记忆 整 忆整 理 整理]"},
+		{"修复E1001错误", "[修 复 修复 e1001 错 误 错误]"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(lexicalTerms(tt.text)); got != tt.want {
+			t.Errorf("lexicalTerms(%q) = %s, want %s", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestLexicalIndexSearch(t *testing.T) {
+	x := newLexicalIndex()
+	x.reset([]metaRecord{
+		{ID: "a", AgentName: "coder", Summary: "Deploy notes", Content: "The staging deploy failed with E4021 twice.", Timestamp: 1},
+		{ID: "b", AgentName: "coder", Summary: "Deploy notes", Content: "Deploys go out on Fridays.", Timestamp: 2},
+		{ID: "c", AgentName: "writer", Summary: "Style", Content: "E4021 is also the chapter code.", Timestamp: 3},
+	}, 3)
+
+	ids := func(hits []lexicalHit) []string {
+		out := make([]string, len(hits))
+		for i, h := range hits {
+			out[i] = h.record.ID
+		}
+		return out
+	}
+
+	if got := fmt.Sprint(ids(x.search("e4021", MemoryFilter{}, 0))); got != "[a c]" && got != "[c a]" {
+		t.Errorf("search(e4021) = %s", got)
+	}
+	if got := fmt.Sprint(ids(x.search("E4021", MemoryFilter{AgentName: "coder"}, 0))); got != "[a]" {
+		t.Errorf("search(E4021, coder) = %s, want [a]", got)
+	}
+	// The rare term outweighs the common one.
+	if got := ids(x.search("deploy E4021", MemoryFilter{}, 0)); len(got) != 3 || got[0] != "a" {
+		t.Errorf("search(deploy E4021) = %v, want a first", got)
+	}
+	if got := x.search("nothing here", MemoryFilter{}, 0); len(got) != 0 {
+		t.Errorf("search(nothing here) = %v", ids(got))
+	}
+
+	x.put(metaRecord{ID: "a", AgentName: "coder", Summary: "Deploy notes"}, "Deploy notes", "Fixed now.")
+	if got := x.search("e4021", MemoryFilter{AgentName: "coder"}, 0); len(got) != 0 {
+		t.Errorf("replaced text still matches: %v", ids(got))
+	}
+	x.remove("c")
+	if got := x.search("e4021", MemoryFilter{}, 0); len(got) != 0 {
+		t.Errorf("removed chunk still matches: %v", ids(got))
+	}
+	if !x.inStep(2) {
+		t.Errorf("vectors = %d, want 2", x.vectors)
+	}
+}
+
+func TestLexicalIndexUnbuilt(t *testing.T) {
+	x := newLexicalIndex()
+	x.put(metaRecord{ID: "a"}, "hello", "")
+	if x.inStep(0) || x.inStep(1) || len(x.docs) != 0 {
+		t.Error("writes before the first build should be left to the build")
+	}
+}
@@ -9,26 +9,51 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/DotNetAge/goharness/memory"
 	goragcore "github.com/DotNetAge/gorag/v2/core"
-	"github.com/DotNetAge/gorag/v2/embedder"
-	goragindexer "github.com/DotNetAge/gorag/v2/indexer"
 	"github.com/DotNetAge/gorag/v2/logging"
 	querypkg "github.com/DotNetAge/gorag/v2/query"
-	"github.com/DotNetAge/gorag/v2/store/vector/govector"
+	"github.com/DotNetAge/mindx/pkg/storage"
 )
 
 var _ memory.Memory = (*RAGMemory)(nil)
 
 // RAGMemory implements memory.Memory using SemanticIndexer for unified memory storage.
 // All agents' memories are stored in the same vector store, differentiated by metadata
-// fields (agent_name, session_id) for filter-based retrieval.
+// fields (agent_name, session_id) for filter-based retrieval. Each memory has a Scope;
+// a context carrying an Access limits reads and writes to the scopes it allows.
 type RAGMemory struct {
 	semantic goragcore.Indexer // SemanticIndexer（统一记忆存储）
 	embedder goragcore.Embedder
 	logger   logging.Logger
+
+	// index 是元数据索引（按 agent / project / session / 时间定位），为 nil 时
+	// 回退到全量扫描。写入持有 indexMu 读锁，重建索引持有写锁。
+	index   *metaIndex
+	indexMu sync.RWMutex
+
+	// lexical 是与语义索引并列的 BM25 词法索引（内存中），mode 为
+	// Retrieve 的默认检索模式。
+	lexical *lexicalIndex
+	mode    RetrieveMode
+
+	// decay 决定 Retrieve 排序中重要度与新近度所占的权重。
+	decay Decay
+	// archive 保存整理（consolidation）移出向量存储的记忆，为 nil 时不能整理。
+	archive       *archive
+	consolidateMu sync.Mutex
+
+	// vectorDir / vectorFile 是当前 embedder 的向量存储位置；pending 为更换
+	// embedder 前的存储，等待 Reembed 迁移。
+	vectorDir  string
+	vectorFile string
+	embedderID string
+	pending    atomic.Pointer[EmbedderStamp]
+	reembedMu  sync.Mutex
 }
 
 type RAGMemoryOption func(*RAGMemory)
@@ -42,29 +67,31 @@ type MemoryConfig struct {
 
 	Embedder goragcore.Embedder
 
+	// EmbedderID is the EmbedderConfig.ID of Embedder. Each embedder keeps
+	// its vectors in a store of its own (see ResolveVectorStore).
+	EmbedderID string
+
 	ReadOnly bool
+
+	// Decay overrides DefaultDecay for Retrieve ranking.
+	Decay *Decay
+
+	// QueryMode is the default retrieve mode; empty is RetrieveHybrid.
+	QueryMode RetrieveMode
 }
 
 func (c MemoryConfig) dataDir() string {
 	return filepath.Join(c.MemoryDir, "shared")
 }
 
-func NewEmbedderFromConfig(modelPath string) (goragcore.Embedder, error) {
-	if modelPath == "" {
-		return nil, nil
-	}
-	emb, err := embedder.NewChineseClipEmbedder(embedder.WithModelFile(modelPath))
-	if err != nil {
-		return nil, fmt.Errorf("memory: 创建 embedder 失败: %w", err)
-	}
-	return emb, nil
-}
-
 // NewRAGMemory 创建一个 RAGMemory 实例。
 // semanticIdx 始终非空；graphIdx 可为 nil（仅使用语义检索）。
 func NewRAGMemory(semanticIdx goragcore.Indexer, opts ...RAGMemoryOption) *RAGMemory {
 	m := &RAGMemory{
 		semantic: semanticIdx,
+		decay:    DefaultDecay,
+		lexical:  newLexicalIndex(),
+		mode:     RetrieveHybrid,
 	}
 	for _, opt := range opts {
 		opt(m)
@@ -98,24 +125,48 @@ func NewRAGMemoryFromConfig(cfg MemoryConfig) (*RAGMemory, error) {
 	if mkErr := os.MkdirAll(semVecDir, 0755); mkErr != nil {
 		return nil, fmt.Errorf("memory: 创建语义向量目录 %s 失败: %w", semVecDir, mkErr)
 	}
-	semVS, err := govector.NewStore(
-		govector.WithCollection("shared_sem"),
-		govector.WithDimension(cfg.Embedder.Dim()),
-		govector.WithDBPath(filepath.Join(semVecDir, "shared.db")),
-		govector.WithHNSW(true),
-		govector.WithReadOnly(cfg.ReadOnly),
-	)
+	vecFile, prev, err := ResolveVectorStore(semVecDir, "shared", cfg.EmbedderID, cfg.Embedder.Dim(), cfg.ReadOnly)
 	if err != nil {
-		return nil, fmt.Errorf("memory: 创建语义向量存储 %s 失败: %w", semVecDir, err)
+		return nil, fmt.Errorf("memory: 读取 embedder 标记失败: %w", err)
+	}
+	semIdx, err := openSemanticIndexer(filepath.Join(semVecDir, vecFile), cfg.Embedder, cfg.Embedder.Dim(), cfg.ReadOnly, logger)
+	if err != nil {
+		return nil, err
+	}
+	if prev != nil {
+		logger.Warn("memory: embedder 已更换，已有记忆需运行 mindx memory reembed 迁移",
+			"previous", prev.File,
+			"current", vecFile,
+		)
+	}
+
+	// ── 元数据索引（与向量存储并列）──────────────────────────
+	index, err := openMetaIndex(filepath.Join(dataDir, "meta.db"), cfg.ReadOnly)
+	if err != nil {
+		logger.Warn("memory: 元数据索引不可用，回退到全量扫描", "error", err)
+		index = nil
 	}
-	semIdx := goragindexer.NewSemanticIndexer(semVS, cfg.Embedder,
-		goragindexer.WithSemanticLogger(logger),
-	)
 
 	m := &RAGMemory{
 		semantic: semIdx,
 		embedder: cfg.Embedder,
 		logger:   logger,
+		index:    index,
+		decay:    DefaultDecay,
+		archive:  &archive{dir: filepath.Join(dataDir, "archive")},
+		lexical:  newLexicalIndex(),
+		mode:     RetrieveHybrid,
+
+		vectorDir:  semVecDir,
+		vectorFile: vecFile,
+		embedderID: cfg.EmbedderID,
+	}
+	m.pending.Store(prev)
+	if cfg.Decay != nil {
+		m.decay = *cfg.Decay
+	}
+	if cfg.QueryMode != "" {
+		m.mode = cfg.QueryMode
 	}
 
 	logger.Info("memory: 初始化完成",
@@ -132,6 +183,27 @@ func WithEmbedder(embedder goragcore.Embedder) RAGMemoryOption {
 	}
 }
 
+// WithDecay sets the decay Retrieve ranks with.
+func WithDecay(d Decay) RAGMemoryOption {
+	return func(m *RAGMemory) {
+		m.decay = d
+	}
+}
+
+// WithRetrieveMode sets the default retrieve mode.
+func WithRetrieveMode(mode RetrieveMode) RAGMemoryOption {
+	return func(m *RAGMemory) {
+		m.mode = mode
+	}
+}
+
+// WithArchiveDir sets the directory consolidation archives chunks under.
+func WithArchiveDir(dir string) RAGMemoryOption {
+	return func(m *RAGMemory) {
+		m.archive = &archive{dir: dir}
+	}
+}
+
 // Semantic 返回 SemanticIndexer，用于统一记忆存储。
 func (m *RAGMemory) Semantic() goragcore.Indexer {
 	return m.semantic
@@ -143,245 +215,387 @@ func (m *RAGMemory) StoreMemoryChunks(ctx context.Context, chunks []memory.Memor
 		return nil
 	}
 	for _, chunk := range chunks {
-		if chunk.ID == "" && chunk.Content != "" {
-			chunk.ID = contentHash(chunk.Content)
-		}
-		if err := m.storeMemoryChunk(ctx, chunk); err != nil {
+		if _, err := m.StoreScoped(ctx, chunk, ""); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-// storeMemoryChunk stores a single MemoryChunk with full Vector metadata.
-func (m *RAGMemory) storeMemoryChunk(ctx context.Context, chunk memory.MemoryChunk) error {
-	content := chunk.Summary
+// chunkText is the text of chunk that is embedded: its summary and
+// content.
+func chunkText(chunk memory.MemoryChunk) string {
 	if chunk.Content != "" {
-		content = chunk.Summary + "\n" + chunk.Content
+		return chunk.Summary + "\n" + chunk.Content
 	}
+	return chunk.Summary
+}
+
+// storeMemoryChunk stores a single MemoryChunk with full Vector metadata.
+// With storage encryption on, the summary and content kept in the metadata
+// are sealed, and so is the chunk text handed to the indexer, which the
+// vector store keeps as is; openingEmbedder embeds its plaintext. Only the
+// filter fields stay plaintext.
+// extra adds the scope and consolidation metadata (see chunkExtras); the
+// scope is inferred from the chunk when extra has none.
+func (m *RAGMemory) storeMemoryChunk(ctx context.Context, chunk memory.MemoryChunk, extra map[string]any) error {
+	content := chunkText(chunk)
 
 	tagStrs := make([]string, len(chunk.Tags))
 	copy(tagStrs, chunk.Tags)
 
+	summary, err := storage.SealText(chunk.Summary)
+	if err != nil {
+		return fmt.Errorf("memory: 加密 chunk 失败: %w", err)
+	}
+	sealedContent, err := storage.SealText(chunk.Content)
+	if err != nil {
+		return fmt.Errorf("memory: 加密 chunk 失败: %w", err)
+	}
+	sealedText, err := storage.SealText(content)
+	if err != nil {
+		return fmt.Errorf("memory: 加密 chunk 失败: %w", err)
+	}
+
+	scope, _ := extra[metaScope].(string)
+	if scope == "" {
+		scope = string(inferScope(chunk.AgentName, chunk.ProjectDir))
+	}
+
 	metadata := map[string]any{
+		metaScope:     scope,
 		"agent_name":  chunk.AgentName,
 		"session_id":  chunk.SessionID,
 		"project_dir": chunk.ProjectDir,
-		"summary":     chunk.Summary,
+		"summary":     summary,
 		"tags":        tagStrs,
-		"content":     chunk.Content,
-		"title":       chunk.Summary,
+		"content":     sealedContent,
+		"title":       summary,
 	}
 	if !chunk.Timestamp.IsZero() {
 		metadata["timestamp"] = chunk.Timestamp.UnixMilli()
 	}
+	for k, v := range extra {
+		metadata[k] = v
+	}
 
 	coreChunk := &goragcore.Chunk{
 		ID:       chunk.ID,
-		Content:  content,
-		Title:    chunk.Summary,
+		Content:  sealedText,
+		Title:    summary,
 		DocID:    chunk.AgentName,
 		Metadata: metadata,
 	}
 
+	record := metaRecord{
+		ID:         chunk.ID,
+		AgentName:  chunk.AgentName,
+		SessionID:  chunk.SessionID,
+		ProjectDir: chunk.ProjectDir,
+		Scope:      Scope(scope),
+		Summary:    summary,
+		Content:    sealedContent,
+		Tags:       tagStrs,
+		Importance: importanceOf(metadata),
+	}
+	if !chunk.Timestamp.IsZero() {
+		record.Timestamp = chunk.Timestamp.UnixMilli()
+	}
+
+	m.indexMu.RLock()
+	defer m.indexMu.RUnlock()
 	if err := m.semantic.StoreChunk(ctx, coreChunk); err != nil {
 		return fmt.Errorf("memory: 存储 chunk 失败: %w", err)
 	}
+	if m.lexical != nil {
+		m.lexical.put(record, chunk.Summary, chunk.Content)
+	}
+	if m.index != nil && !m.index.readOnly {
+		// A missed update leaves the index a chunk behind the vector store,
+		// which makes the next lookup rebuild it.
+		if err := m.index.put(record); err != nil {
+			m.logger.Warn("memory: 更新元数据索引失败", "id", chunk.ID, "error", err)
+		}
+	}
 	return nil
 }
 
-// Retrieve implements memory.Memory.
-func (m *RAGMemory) Retrieve(ctx context.Context, query string, opts ...memory.RetrieveOption) ([]memory.MemoryChunk, error) {
-	cfg := memory.DefaultRetrieveConfig()
-	for _, opt := range opts {
-		opt(&cfg)
-	}
-
+// Reseal re-stores every memory whose sealed text or metadata is not in the
+// current storage form (see storage.Current) and reports how many were
+// re-stored. Re-storing re-embeds the memory. Archive files not in the
+// current form are rewritten too, and count one each.
+func (m *RAGMemory) Reseal(ctx context.Context) (int, error) {
 	idx := m.semantic
 	if idx == nil {
-		return nil, fmt.Errorf("memory: 语义索引器未初始化")
-	}
-
-	q := m.buildQueryWithFilter(query, cfg)
-	if q == nil {
-		return nil, nil
-	}
-
-	hits, err := idx.Search(ctx, q)
-	if err != nil {
-		return nil, fmt.Errorf("memory: 检索失败: %w", err)
+		return 0, fmt.Errorf("memory: 语义索引器未初始化")
 	}
 
-	if len(hits) == 0 {
-		return nil, nil
+	var stale []goragcore.Hit
+	const pageSize = 200
+	for offset := 0; ; offset += pageSize {
+		hits, err := idx.List(ctx, offset, pageSize)
+		if err != nil {
+			return 0, fmt.Errorf("memory: 列出记忆失败: %w", err)
+		}
+		for _, hit := range hits {
+			if !sealedCurrent(hit) {
+				stale = append(stale, hit)
+			}
+		}
+		if len(hits) < pageSize {
+			break
+		}
 	}
 
-	var chunks []memory.MemoryChunk
-	for _, hit := range hits {
-		chunk := hitToChunk(hit)
-		if chunk == nil {
-			continue
+	n := 0
+	for _, hit := range stale {
+		opened, err := OpenHit(hit)
+		if err != nil {
+			return n, fmt.Errorf("memory: 解密记忆 %s 失败: %w", hit.ID, err)
 		}
-
-		if cfg.MinScore > 0 && float64(hit.Score) < cfg.MinScore {
-			continue
+		// Stored again under its own ID, the memory replaces its entry.
+		chunk := hitToChunk(opened)
+		if err := m.storeMemoryChunk(ctx, *chunk, chunkExtras(hit.Metadata)); err != nil {
+			return n, err
 		}
+		n++
+	}
 
-		chunks = append(chunks, *chunk)
+	if m.archive != nil {
+		files, err := m.archive.reseal()
+		n += files
+		if err != nil {
+			return n, fmt.Errorf("memory: 重新加密归档失败: %w", err)
+		}
 	}
+	return n, nil
+}
 
-	if cfg.Limit > 0 && len(chunks) > cfg.Limit {
-		chunks = chunks[:cfg.Limit]
+// sealedCurrent reports whether the sealed text and metadata of hit are
+// in the current storage form.
+func sealedCurrent(hit goragcore.Hit) bool {
+	if hit.Content != "" && !storage.Current([]byte(hit.Content)) {
+		return false
+	}
+	for _, key := range []string{"summary", "content"} {
+		if v, ok := hit.Metadata[key].(string); ok && v != "" && !storage.Current([]byte(v)) {
+			return false
+		}
 	}
+	return true
+}
 
-	return chunks, nil
+// Retrieve implements memory.Memory. It matches query in the default
+// retrieve mode, hybrid unless configured otherwise; see RetrieveWithMode.
+// With an Access in ctx only the memories it may read are returned.
+func (m *RAGMemory) Retrieve(ctx context.Context, query string, opts ...memory.RetrieveOption) ([]memory.MemoryChunk, error) {
+	return m.RetrieveWithMode(ctx, query, "", opts...)
 }
 
 // RetrieveLatest 按时间倒序取出当前 AgentName+ProjectDir 范围内最新的 N 条记忆。
-// 不依赖向量检索，通过 Indexer.List 分页拉取所有 chunk，按 metadata 过滤后
-// 按 timestamp 倒序取前 limit 条。
+// 不依赖向量检索，由元数据索引按 (agent, project, timestamp) 直接定位。
 //
 // 用于 memmache.md 中"记忆缓冲区固定取最新10条"的需求：每次 LLM 调用前
 // 取最新记忆拼到系统指令区末尾。
 //
 // 实现 memory.LatestRetriever 可选接口。
 func (m *RAGMemory) RetrieveLatest(ctx context.Context, agentName, projectDir string, limit int) ([]memory.MemoryChunk, error) {
-	idx := m.semantic
-	if idx == nil {
-		return nil, fmt.Errorf("memory: 语义索引器未初始化")
+	if limit <= 0 {
+		limit = 10
 	}
+	return m.List(ctx, MemoryFilter{AgentName: agentName, ProjectDir: projectDir, Access: accessFilter(ctx)}, limit)
+}
+
+// RetrieveBySession 实现 memory.SessionRetriever 可选接口：按 sessionID 取最新记忆。
+// 无视 agentName / projectDir 过滤，作为 RetrieveLatest 的兜底。
+func (m *RAGMemory) RetrieveBySession(ctx context.Context, sessionID string, limit int) ([]memory.MemoryChunk, error) {
 	if limit <= 0 {
 		limit = 10
 	}
+	return m.List(ctx, MemoryFilter{SessionID: sessionID, Access: accessFilter(ctx)}, limit)
+}
+
+// List returns up to limit memories matching f, newest first; a limit of
+// 0 or less returns them all. It is answered by the metadata index, and
+// falls back to listing every chunk when the index is unavailable.
+func (m *RAGMemory) List(ctx context.Context, f MemoryFilter, limit int) ([]memory.MemoryChunk, error) {
+	if m.semantic == nil {
+		return nil, fmt.Errorf("memory: 语义索引器未初始化")
+	}
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	// 分页拉取所有 hits，按 metadata 过滤
-	var matched []memory.MemoryChunk
-	const pageSize = 200
-	offset := 0
-	totalHits := 0
-	for {
-		hits, err := idx.List(ctx, offset, pageSize)
-		if err != nil {
-			return nil, fmt.Errorf("memory: 检索最新记忆失败: %w", err)
-		}
-		if len(hits) == 0 {
-			break
-		}
-		totalHits += len(hits)
-		for _, hit := range hits {
-			chunk := hitToChunk(hit)
-			if chunk == nil {
-				m.logger.Debug("RetrieveLatest: hitToChunk returned nil", "hit_id", hit.ID)
-				continue
-			}
-			m.logger.Debug("RetrieveLatest: chunk metadata",
-				"hit_id", hit.ID, "agent_name", chunk.AgentName,
-				"project_dir", chunk.ProjectDir, "summary", chunk.Summary)
-			// 过滤 agent_name
-			if agentName != "" && chunk.AgentName != agentName {
-				m.logger.Debug("RetrieveLatest: filtered by agent_name",
-					"want", agentName, "got", chunk.AgentName, "hit_id", hit.ID)
-				continue
-			}
-			// 过滤 project_dir
-			if projectDir != "" && chunk.ProjectDir != projectDir {
-				m.logger.Debug("RetrieveLatest: filtered by project_dir",
-					"want", projectDir, "got", chunk.ProjectDir, "hit_id", hit.ID)
-				continue
+	if m.indexReady(ctx) {
+		records, err := m.index.latest(f, limit)
+		if err == nil {
+			chunks := make([]memory.MemoryChunk, 0, len(records))
+			for i := range records {
+				if chunk := recordToChunk(&records[i]); chunk != nil {
+					chunks = append(chunks, *chunk)
+				}
 			}
-			matched = append(matched, *chunk)
-		}
-		if len(hits) < pageSize {
-			break
+			return chunks, nil
 		}
-		offset += pageSize
+		m.logger.Warn("memory: 元数据索引查询失败，回退到全量扫描", "error", err)
 	}
-	m.logger.Debug("RetrieveLatest: summary",
-		"total_hits", totalHits, "matched", len(matched),
-		"agent_name", agentName, "project_dir", projectDir)
 
+	matched, err := m.scan(ctx, f)
+	if err != nil {
+		return nil, err
+	}
 	// 按 timestamp 倒序排序（最新在前）
 	sort.Slice(matched, func(i, j int) bool {
 		return matched[i].Timestamp.After(matched[j].Timestamp)
 	})
-
-	// 取前 limit 条
-	if len(matched) > limit {
+	if limit > 0 && len(matched) > limit {
 		matched = matched[:limit]
 	}
-
 	return matched, nil
 }
 
-// RetrieveBySession 实现 memory.SessionRetriever 可选接口：按 sessionID 取最新记忆。
-// 无视 agentName / projectDir 过滤，作为 RetrieveLatest 的兜底。
-func (m *RAGMemory) RetrieveBySession(ctx context.Context, sessionID string, limit int) ([]memory.MemoryChunk, error) {
-	idx := m.semantic
-	if idx == nil {
-		return nil, fmt.Errorf("memory: 语义索引器未初始化")
+// Count returns the number of memories matching f. An empty filter counts
+// the whole vector store.
+func (m *RAGMemory) Count(ctx context.Context, f MemoryFilter) (int, error) {
+	if m.semantic == nil {
+		return 0, fmt.Errorf("memory: 语义索引器未初始化")
 	}
-	if limit <= 0 {
-		limit = 10
+	if f == (MemoryFilter{}) {
+		return m.semantic.Count(ctx)
 	}
-	if ctx == nil {
-		ctx = context.Background()
+	if m.indexReady(ctx) {
+		n, err := m.index.count(f)
+		if err == nil {
+			return n, nil
+		}
+		m.logger.Warn("memory: 元数据索引计数失败，回退到全量扫描", "error", err)
 	}
+	matched, err := m.scan(ctx, f)
+	return len(matched), err
+}
 
+// scan pages through every chunk via Indexer.List and keeps those
+// matching f. It is the fallback when the metadata index is unavailable.
+func (m *RAGMemory) scan(ctx context.Context, f MemoryFilter) ([]memory.MemoryChunk, error) {
 	var matched []memory.MemoryChunk
 	const pageSize = 200
-	offset := 0
-	totalHits := 0
-	for {
-		hits, err := idx.List(ctx, offset, pageSize)
+	for offset := 0; ; offset += pageSize {
+		hits, err := m.semantic.List(ctx, offset, pageSize)
 		if err != nil {
-			return nil, fmt.Errorf("memory: 检索会话记忆失败: %w", err)
-		}
-		if len(hits) == 0 {
-			break
+			return nil, fmt.Errorf("memory: 列出记忆失败: %w", err)
 		}
-		totalHits += len(hits)
 		for _, hit := range hits {
 			chunk := hitToChunk(hit)
 			if chunk == nil {
 				continue
 			}
-			// 仅按 session_id 过滤
-			if sessionID != "" && chunk.SessionID != sessionID {
+			if f.AgentName != "" && chunk.AgentName != f.AgentName ||
+				f.ProjectDir != "" && chunk.ProjectDir != f.ProjectDir ||
+				f.SessionID != "" && chunk.SessionID != f.SessionID {
 				continue
 			}
+			if f.Access != nil {
+				if r := hitToRecord(hit); !f.Access.readable(&r) {
+					continue
+				}
+			}
 			matched = append(matched, *chunk)
 		}
 		if len(hits) < pageSize {
 			break
 		}
-		offset += pageSize
 	}
-	m.logger.Debug("RetrieveBySession: summary",
-		"total_hits", totalHits, "matched", len(matched),
-		"session_id", sessionID)
+	return matched, nil
+}
 
-	// 按 timestamp 倒序排序（最新在前）
-	sort.Slice(matched, func(i, j int) bool {
-		return matched[i].Timestamp.After(matched[j].Timestamp)
-	})
+// indexReady reports whether the metadata index can answer a lookup. The
+// index records the vector store's chunk count it is in step with; when
+// the counts differ, as on first use or after writes by another process,
+// it is rebuilt from the vector store first.
+func (m *RAGMemory) indexReady(ctx context.Context) bool {
+	if m.index == nil {
+		return false
+	}
+	n, err := m.semantic.Count(ctx)
+	if err != nil {
+		return false
+	}
+	if n == m.index.vectors() {
+		return true
+	}
+	if m.index.readOnly {
+		return false
+	}
 
-	// 取前 limit 条
-	if len(matched) > limit {
-		matched = matched[:limit]
+	m.indexMu.Lock()
+	defer m.indexMu.Unlock()
+	if n, err = m.semantic.Count(ctx); err != nil {
+		return false
+	}
+	if n == m.index.vectors() {
+		return true
 	}
+	if err := m.rebuildIndex(ctx, n); err != nil {
+		m.logger.Warn("memory: 重建元数据索引失败", "error", err)
+		return false
+	}
+	return true
+}
 
-	return matched, nil
+// rebuildIndex replaces the metadata index with the metadata of every
+// chunk in the vector store, which holds vectors chunks.
+func (m *RAGMemory) rebuildIndex(ctx context.Context, vectors int) error {
+	records, err := m.listRecords(ctx)
+	if err != nil {
+		return err
+	}
+	if err := m.index.reset(records, vectors); err != nil {
+		return err
+	}
+	m.logger.Info("memory: 元数据索引已重建", "chunks", len(records))
+	return nil
+}
+
+// listRecords returns the metadata record of every chunk in the vector
+// store.
+func (m *RAGMemory) listRecords(ctx context.Context) ([]metaRecord, error) {
+	var records []metaRecord
+	const pageSize = 200
+	for offset := 0; ; offset += pageSize {
+		hits, err := m.semantic.List(ctx, offset, pageSize)
+		if err != nil {
+			return nil, fmt.Errorf("memory: 列出记忆失败: %w", err)
+		}
+		for _, hit := range hits {
+			records = append(records, hitToRecord(hit))
+		}
+		if len(hits) < pageSize {
+			break
+		}
+	}
+	return records, nil
 }
 
-// Store implements memory.Memory.
+// Store implements memory.Memory. The chunk is stored in the first scope
+// the Access in ctx may write or, without one, in the scope its fields
+// imply; see StoreScoped.
 func (m *RAGMemory) Store(ctx context.Context, chunk memory.MemoryChunk) (string, error) {
+	return m.StoreScoped(ctx, chunk, "")
+}
+
+// StoreScoped stores chunk in scope; an empty scope picks one as Store
+// does. With an Access in ctx the scope must be writable by it and the
+// chunk is filed under the access's own agent, project or session; a
+// denied write fails with ErrScopeDenied.
+func (m *RAGMemory) StoreScoped(ctx context.Context, chunk memory.MemoryChunk, scope Scope) (string, error) {
+	scope, err := resolveScope(ctx, &chunk, scope)
+	if err != nil {
+		return "", err
+	}
 	if chunk.ID == "" && chunk.Content != "" {
 		chunk.ID = contentHash(chunk.Content)
 	}
-	if err := m.storeMemoryChunk(ctx, chunk); err != nil {
+	if err := m.storeMemoryChunk(ctx, chunk, map[string]any{metaScope: string(scope)}); err != nil {
 		return "", err
 	}
 	return chunk.ID, nil
@@ -401,7 +615,7 @@ func (m *RAGMemory) Update(ctx context.Context, id string, chunk memory.MemoryCh
 		return fmt.Errorf("memory update failed to remove old record %s: %w", id, err)
 	}
 	chunk.ID = id
-	return m.storeMemoryChunk(ctx, chunk)
+	return m.storeMemoryChunk(ctx, chunk, nil)
 }
 
 func (m *RAGMemory) Delete(ctx context.Context, id string) error {
@@ -414,10 +628,20 @@ func (m *RAGMemory) Delete(ctx context.Context, id string) error {
 		return memory.ErrMemoryNotFound
 	}
 
+	m.indexMu.RLock()
+	defer m.indexMu.RUnlock()
 	err := idx.Remove(ctx, id)
 	if err != nil {
 		return fmt.Errorf("memory: 删除记忆失败 %s: %w", id, err)
 	}
+	if m.lexical != nil {
+		m.lexical.remove(id)
+	}
+	if m.index != nil && !m.index.readOnly {
+		if err := m.index.remove(id); err != nil {
+			m.logger.Warn("memory: 更新元数据索引失败", "id", id, "error", err)
+		}
+	}
 
 	return nil
 }
@@ -433,6 +657,13 @@ func (m *RAGMemory) Close(ctx context.Context) error {
 		}
 	}
 
+	if m.index != nil {
+		if err := m.index.close(); err != nil {
+			errs = append(errs, err)
+		}
+		m.index = nil
+	}
+
 	if closer, ok := m.logger.(io.Closer); ok {
 		if err := closer.Close(); err != nil {
 			errs = append(errs, err)
@@ -468,7 +699,43 @@ func (m *RAGMemory) buildQueryWithFilter(query string, cfg memory.RetrieveConfig
 	return q
 }
 
+// OpenHit returns hit with the text and metadata sealed by
+// storeMemoryChunk opened. The hit's metadata map is copied, not modified.
+func OpenHit(hit goragcore.Hit) (goragcore.Hit, error) {
+	title, err := storage.OpenText(hit.Title)
+	if err != nil {
+		return hit, err
+	}
+	content, err := storage.OpenText(hit.Content)
+	if err != nil {
+		return hit, err
+	}
+	hit.Title, hit.Content = title, content
+	if hit.Metadata == nil {
+		return hit, nil
+	}
+	meta := make(map[string]any, len(hit.Metadata))
+	for k, v := range hit.Metadata {
+		meta[k] = v
+	}
+	for _, key := range []string{"summary", "content", "title"} {
+		if v, ok := meta[key].(string); ok && v != "" {
+			if meta[key], err = storage.OpenText(v); err != nil {
+				return hit, err
+			}
+		}
+	}
+	hit.Metadata = meta
+	return hit, nil
+}
+
+// hitToChunk converts a search hit back into a MemoryChunk. It returns nil
+// when the hit's sealed metadata cannot be opened.
 func hitToChunk(hit goragcore.Hit) *memory.MemoryChunk {
+	hit, err := OpenHit(hit)
+	if err != nil {
+		return nil
+	}
 	chunk := &memory.MemoryChunk{
 		ID:      hit.ID,
 		Content: hit.Content,
@@ -524,6 +791,76 @@ func hitToChunk(hit goragcore.Hit) *memory.MemoryChunk {
 	return chunk
 }
 
+// hitToRecord extracts the metadata index record of a hit, leaving the
+// sealed fields as stored.
+func hitToRecord(hit goragcore.Hit) metaRecord {
+	r := metaRecord{ID: hit.ID, Summary: hit.Title}
+	md := hit.Metadata
+	r.AgentName, _ = md["agent_name"].(string)
+	r.SessionID, _ = md["session_id"].(string)
+	r.ProjectDir, _ = md["project_dir"].(string)
+	if s, ok := md[metaScope].(string); ok {
+		r.Scope = Scope(s)
+	}
+	if s, ok := md["summary"].(string); ok && s != "" {
+		r.Summary = s
+	}
+	r.Content, _ = md["content"].(string)
+	switch v := md["tags"].(type) {
+	case []string:
+		r.Tags = v
+	case []any:
+		for _, tag := range v {
+			if s, ok := tag.(string); ok {
+				r.Tags = append(r.Tags, s)
+			}
+		}
+	}
+	r.Timestamp = recordTimestamp(md["timestamp"])
+	r.Importance = importanceOf(md)
+	return r
+}
+
+// recordImportance returns the importance of r. Records indexed before
+// importance was recorded have none and count as DefaultImportance.
+func recordImportance(r *metaRecord) float64 {
+	if r.Importance == 0 {
+		return DefaultImportance
+	}
+	return r.Importance
+}
+
+// recordToChunk converts a metadata index record into a MemoryChunk, like
+// hitToChunk. It returns nil when the sealed fields cannot be opened.
+func recordToChunk(r *metaRecord) *memory.MemoryChunk {
+	summary, err := storage.OpenText(r.Summary)
+	if err != nil {
+		return nil
+	}
+	content, err := storage.OpenText(r.Content)
+	if err != nil {
+		return nil
+	}
+	if content == "" {
+		// Without content the embedded text, and so the hit's content, is
+		// the summary alone.
+		content = summary
+	}
+	chunk := &memory.MemoryChunk{
+		ID:         r.ID,
+		AgentName:  r.AgentName,
+		SessionID:  r.SessionID,
+		ProjectDir: r.ProjectDir,
+		Summary:    summary,
+		Content:    content,
+		Tags:       r.Tags,
+	}
+	if r.Timestamp > 0 {
+		chunk.Timestamp = time.UnixMilli(r.Timestamp)
+	}
+	return chunk
+}
+
 func contentHash(content string) string {
 	h := sha256.Sum256([]byte(content))
 	return hex.EncodeToString(h[:])
@@ -0,0 +1,414 @@
+package memory
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// metaIndex is a secondary index of memory metadata kept in bbolt beside
+// the vector store (memory/shared/meta.db). It holds one record per chunk
+// plus ordered keys per agent, project, agent+project, session and time,
+// so latest-N and per-session lookups and scoped counts seek instead of
+// listing every chunk of the vector store.
+//
+// Summary and content are stored exactly as in the vector store's
+// metadata, so they are sealed when storage encryption is on.
+//
+// A bbolt file can be open only once, so RAGMemory instances over the same
+// directory in one process share the index through openMetaIndex.
+type metaIndex struct {
+	db       *bbolt.DB
+	path     string
+	readOnly bool
+	refs     int
+}
+
+const (
+	metaRecordsBucket = "records"
+	metaStateBucket   = "state"
+	// metaVectorsKey holds the vector store's chunk count the index was
+	// last in step with; a different count means the index is stale.
+	metaVectorsKey = "vectors"
+)
+
+// metaKeyIndexes are the ordered key buckets. Keys are the index's field
+// values, each followed by a NUL, then the timestamp (8 bytes big-endian
+// Unix ms) and the chunk ID; the value is the chunk ID.
+var metaKeyIndexes = []struct {
+	bucket string
+	fields func(r *metaRecord) []string
+}{
+	{"by_time", func(r *metaRecord) []string { return nil }},
+	{"by_agent", func(r *metaRecord) []string { return []string{r.AgentName} }},
+	{"by_project", func(r *metaRecord) []string { return []string{r.ProjectDir} }},
+	{"by_scope", func(r *metaRecord) []string { return []string{r.AgentName, r.ProjectDir} }},
+	{"by_session", func(r *metaRecord) []string { return []string{r.SessionID} }},
+}
+
+// metaRecord is the indexed metadata of one chunk.
+type metaRecord struct {
+	ID         string   `json:"id"`
+	AgentName  string   `json:"agent_name,omitempty"`
+	SessionID  string   `json:"session_id,omitempty"`
+	ProjectDir string   `json:"project_dir,omitempty"`
+	Scope      Scope    `json:"scope,omitempty"`
+	Summary    string   `json:"summary,omitempty"`
+	Content    string   `json:"content,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Timestamp  int64    `json:"timestamp,omitempty"` // Unix ms
+	Importance float64  `json:"importance,omitempty"`
+}
+
+// MemoryFilter selects memories by metadata. Empty fields match any value.
+type MemoryFilter struct {
+	AgentName  string
+	ProjectDir string
+	SessionID  string
+	// Access, when set, keeps only the memories it may read.
+	Access *Access
+}
+
+func (f MemoryFilter) matches(r *metaRecord) bool {
+	return (f.AgentName == "" || r.AgentName == f.AgentName) &&
+		(f.ProjectDir == "" || r.ProjectDir == f.ProjectDir) &&
+		(f.SessionID == "" || r.SessionID == f.SessionID) &&
+		(f.Access == nil || f.Access.readable(r))
+}
+
+// keyIndex picks the key bucket serving f and the key prefix within it.
+// exact reports whether every key under the prefix matches f, so the
+// records need not be read to check.
+func (f MemoryFilter) keyIndex() (bucket string, prefix []byte, exact bool) {
+	if f.Access != nil {
+		bucket, prefix, _ = MemoryFilter{AgentName: f.AgentName, ProjectDir: f.ProjectDir, SessionID: f.SessionID}.keyIndex()
+		return bucket, prefix, false
+	}
+	switch {
+	case f.SessionID != "":
+		return "by_session", metaKeyPrefix(f.SessionID), f.AgentName == "" && f.ProjectDir == ""
+	case f.AgentName != "" && f.ProjectDir != "":
+		return "by_scope", metaKeyPrefix(f.AgentName, f.ProjectDir), true
+	case f.AgentName != "":
+		return "by_agent", metaKeyPrefix(f.AgentName), true
+	case f.ProjectDir != "":
+		return "by_project", metaKeyPrefix(f.ProjectDir), true
+	default:
+		return "by_time", nil, true
+	}
+}
+
+func metaKeyPrefix(fields ...string) []byte {
+	var b bytes.Buffer
+	for _, f := range fields {
+		b.WriteString(f)
+		b.WriteByte(0)
+	}
+	return b.Bytes()
+}
+
+func metaKey(fields []string, r *metaRecord) []byte {
+	key := metaKeyPrefix(fields...)
+	var ts [8]byte
+	if r.Timestamp > 0 {
+		binary.BigEndian.PutUint64(ts[:], uint64(r.Timestamp))
+	}
+	key = append(key, ts[:]...)
+	return append(key, r.ID...)
+}
+
+var (
+	metaIndexesMu sync.Mutex
+	openIndexes   = map[string]*metaIndex{}
+)
+
+// openMetaIndex opens the index at path, or returns the one already open
+// in this process. A read-only index opened first is reused read-only.
+func openMetaIndex(path string, readOnly bool) (*metaIndex, error) {
+	metaIndexesMu.Lock()
+	defer metaIndexesMu.Unlock()
+	if x := openIndexes[path]; x != nil {
+		x.refs++
+		return x, nil
+	}
+
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second, ReadOnly: readOnly})
+	if err != nil {
+		return nil, fmt.Errorf("memory: 打开元数据索引 %s 失败: %w", path, err)
+	}
+	if !readOnly {
+		err = db.Update(func(tx *bbolt.Tx) error {
+			return createMetaBuckets(tx)
+		})
+		if err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("memory: 初始化元数据索引失败: %w", err)
+		}
+	}
+	x := &metaIndex{db: db, path: path, readOnly: readOnly, refs: 1}
+	openIndexes[path] = x
+	return x, nil
+}
+
+func createMetaBuckets(tx *bbolt.Tx) error {
+	names := []string{metaRecordsBucket, metaStateBucket}
+	for _, ix := range metaKeyIndexes {
+		names = append(names, ix.bucket)
+	}
+	for _, name := range names {
+		if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close releases one reference and closes the database with the last.
+func (x *metaIndex) close() error {
+	metaIndexesMu.Lock()
+	defer metaIndexesMu.Unlock()
+	if x.refs--; x.refs > 0 {
+		return nil
+	}
+	delete(openIndexes, x.path)
+	return x.db.Close()
+}
+
+// vectors returns the vector store count the index is in step with, or
+// -1 when it was never built.
+func (x *metaIndex) vectors() int {
+	n := -1
+	_ = x.db.View(func(tx *bbolt.Tx) error {
+		n = vectorsIn(tx)
+		return nil
+	})
+	return n
+}
+
+func vectorsIn(tx *bbolt.Tx) int {
+	b := tx.Bucket([]byte(metaStateBucket))
+	if b == nil {
+		return -1
+	}
+	v := b.Get([]byte(metaVectorsKey))
+	if v == nil {
+		return -1
+	}
+	n, err := strconv.Atoi(string(v))
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func setVectors(tx *bbolt.Tx, n int) error {
+	return tx.Bucket([]byte(metaStateBucket)).Put([]byte(metaVectorsKey), []byte(strconv.Itoa(n)))
+}
+
+// put adds or replaces the record of r.ID. A new ID also counts one more
+// chunk in the vector store.
+func (x *metaIndex) put(r metaRecord) error {
+	return x.db.Update(func(tx *bbolt.Tx) error {
+		existed, err := deleteMetaRecord(tx, r.ID)
+		if err != nil {
+			return err
+		}
+		if err := putMetaRecord(tx, &r); err != nil {
+			return err
+		}
+		if n := vectorsIn(tx); !existed && n >= 0 {
+			return setVectors(tx, n+1)
+		}
+		return nil
+	})
+}
+
+// remove deletes the record of id, if any, and counts one chunk less.
+func (x *metaIndex) remove(id string) error {
+	return x.db.Update(func(tx *bbolt.Tx) error {
+		existed, err := deleteMetaRecord(tx, id)
+		if err != nil {
+			return err
+		}
+		if n := vectorsIn(tx); existed && n > 0 {
+			return setVectors(tx, n-1)
+		}
+		return nil
+	})
+}
+
+// reset replaces the whole index with records, in step with a vector
+// store holding vectors chunks.
+func (x *metaIndex) reset(records []metaRecord, vectors int) error {
+	return x.db.Update(func(tx *bbolt.Tx) error {
+		names := []string{metaRecordsBucket, metaStateBucket}
+		for _, ix := range metaKeyIndexes {
+			names = append(names, ix.bucket)
+		}
+		for _, name := range names {
+			if err := tx.DeleteBucket([]byte(name)); err != nil && !errors.Is(err, bbolt.ErrBucketNotFound) {
+				return err
+			}
+		}
+		if err := createMetaBuckets(tx); err != nil {
+			return err
+		}
+		for i := range records {
+			if err := putMetaRecord(tx, &records[i]); err != nil {
+				return err
+			}
+		}
+		return setVectors(tx, vectors)
+	})
+}
+
+func putMetaRecord(tx *bbolt.Tx, r *metaRecord) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket([]byte(metaRecordsBucket)).Put([]byte(r.ID), data); err != nil {
+		return err
+	}
+	for _, ix := range metaKeyIndexes {
+		if err := tx.Bucket([]byte(ix.bucket)).Put(metaKey(ix.fields(r), r), []byte(r.ID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deleteMetaRecord(tx *bbolt.Tx, id string) (bool, error) {
+	records := tx.Bucket([]byte(metaRecordsBucket))
+	data := records.Get([]byte(id))
+	if data == nil {
+		return false, nil
+	}
+	var old metaRecord
+	if err := json.Unmarshal(data, &old); err != nil {
+		return false, fmt.Errorf("memory: 元数据索引记录 %s 损坏: %w", id, err)
+	}
+	for _, ix := range metaKeyIndexes {
+		if err := tx.Bucket([]byte(ix.bucket)).Delete(metaKey(ix.fields(&old), &old)); err != nil {
+			return false, err
+		}
+	}
+	return true, records.Delete([]byte(id))
+}
+
+// get returns the record of id, or nil.
+func (x *metaIndex) get(id string) (*metaRecord, error) {
+	var r *metaRecord
+	err := x.db.View(func(tx *bbolt.Tx) error {
+		data := tx.Bucket([]byte(metaRecordsBucket)).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		r = &metaRecord{}
+		return json.Unmarshal(data, r)
+	})
+	return r, err
+}
+
+// latest returns up to limit records matching f, newest first. A limit
+// of 0 or less returns them all.
+func (x *metaIndex) latest(f MemoryFilter, limit int) ([]metaRecord, error) {
+	var out []metaRecord
+	err := x.scan(f, func(records *bbolt.Bucket, id []byte) (bool, error) {
+		var r metaRecord
+		data := records.Get(id)
+		if data == nil {
+			return true, nil
+		}
+		if err := json.Unmarshal(data, &r); err != nil {
+			return false, err
+		}
+		if f.matches(&r) {
+			out = append(out, r)
+		}
+		return limit <= 0 || len(out) < limit, nil
+	}, false)
+	return out, err
+}
+
+// count returns the number of records matching f.
+func (x *metaIndex) count(f MemoryFilter) (int, error) {
+	_, _, exact := f.keyIndex()
+	n := 0
+	err := x.scan(f, func(records *bbolt.Bucket, id []byte) (bool, error) {
+		if exact {
+			n++
+			return true, nil
+		}
+		var r metaRecord
+		if data := records.Get(id); data != nil && json.Unmarshal(data, &r) == nil && f.matches(&r) {
+			n++
+		}
+		return true, nil
+	}, true)
+	return n, err
+}
+
+// scan calls fn with the ID of every key under f's prefix, newest first
+// unless any order will do, until fn returns false.
+func (x *metaIndex) scan(f MemoryFilter, fn func(records *bbolt.Bucket, id []byte) (bool, error), anyOrder bool) error {
+	bucket, prefix, _ := f.keyIndex()
+	return x.db.View(func(tx *bbolt.Tx) error {
+		records := tx.Bucket([]byte(metaRecordsBucket))
+		b := tx.Bucket([]byte(bucket))
+		if records == nil || b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		var k, v []byte
+		next := c.Next
+		if anyOrder {
+			k, v = c.Seek(prefix)
+		} else {
+			k, v = seekLast(c, prefix)
+			next = c.Prev
+		}
+		for ; k != nil && bytes.HasPrefix(k, prefix); k, v = next() {
+			more, err := fn(records, v)
+			if err != nil || !more {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// seekLast positions c on the last key with prefix, or past it when there
+// is none.
+func seekLast(c *bbolt.Cursor, prefix []byte) ([]byte, []byte) {
+	if len(prefix) == 0 {
+		return c.Last()
+	}
+	// Prefixes end in NUL, so the first key after them all starts with the
+	// prefix's last byte raised to 1.
+	end := append(append([]byte(nil), prefix[:len(prefix)-1]...), 1)
+	if k, _ := c.Seek(end); k == nil {
+		return c.Last()
+	}
+	return c.Prev()
+}
+
+// recordTimestamp converts a metadata timestamp value to Unix ms.
+func recordTimestamp(v any) int64 {
+	switch ts := v.(type) {
+	case float64:
+		return int64(ts)
+	case int64:
+		return ts
+	case int:
+		return int64(ts)
+	}
+	return 0
+}
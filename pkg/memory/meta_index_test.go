@@ -0,0 +1,135 @@
+package memory
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func testMetaIndex(t *testing.T) *metaIndex {
+	t.Helper()
+	x, err := openMetaIndex(filepath.Join(t.TempDir(), "meta.db"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = x.close() })
+	return x
+}
+
+func recordIDs(records []metaRecord) []string {
+	ids := make([]string, len(records))
+	for i, r := range records {
+		ids[i] = r.ID
+	}
+	return ids
+}
+
+func TestMetaIndexLatest(t *testing.T) {
+	x := testMetaIndex(t)
+	if err := x.reset(nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	put := func(id, agent, project, session string, ts int64) {
+		t.Helper()
+		if err := x.put(metaRecord{ID: id, AgentName: agent, ProjectDir: project, SessionID: session, Timestamp: ts}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put("a1", "coder", "/work/api", "s1", 100)
+	put("a2", "coder", "/work/api", "s1", 300)
+	put("a3", "coder", "/work/web", "s2", 200)
+	put("b1", "writer", "/work/api", "s3", 400)
+	put("b2", "writer", "/work/apix", "s3", 500)
+	put("old", "coder", "/work/api", "s1", 0)
+
+	tests := []struct {
+		filter MemoryFilter
+		limit  int
+		want   string
+	}{
+		{MemoryFilter{}, 0, "[b2 b1 a2 a3 a1 old]"},
+		{MemoryFilter{}, 2, "[b2 b1]"},
+		{MemoryFilter{AgentName: "coder"}, 0, "[a2 a3 a1 old]"},
+		{MemoryFilter{AgentName: "coder", ProjectDir: "/work/api"}, 2, "[a2 a1]"},
+		{MemoryFilter{ProjectDir: "/work/api"}, 0, "[b1 a2 a1 old]"},
+		{MemoryFilter{SessionID: "s3"}, 0, "[b2 b1]"},
+		{MemoryFilter{SessionID: "s1", ProjectDir: "/work/web"}, 0, "[]"},
+		{MemoryFilter{AgentName: "nobody"}, 0, "[]"},
+	}
+	for _, tt := range tests {
+		records, err := x.latest(tt.filter, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(recordIDs(records)); got != tt.want {
+			t.Errorf("latest(%+v, %d) = %s, want %s", tt.filter, tt.limit, got, tt.want)
+		}
+	}
+
+	if n, _ := x.count(MemoryFilter{AgentName: "coder"}); n != 4 {
+		t.Errorf("count(coder) = %d, want 4", n)
+	}
+	if n, _ := x.count(MemoryFilter{SessionID: "s1", AgentName: "coder"}); n != 3 {
+		t.Errorf("count(s1, coder) = %d, want 3", n)
+	}
+	if n := x.vectors(); n != 6 {
+		t.Errorf("vectors = %d, want 6", n)
+	}
+}
+
+func TestMetaIndexReplaceAndRemove(t *testing.T) {
+	x := testMetaIndex(t)
+	if n := x.vectors(); n != -1 {
+		t.Errorf("vectors of a new index = %d, want -1", n)
+	}
+	if err := x.reset([]metaRecord{{ID: "a", AgentName: "coder", Timestamp: 1}}, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// Re-storing an ID moves it rather than adding a second entry.
+	if err := x.put(metaRecord{ID: "a", AgentName: "writer", Summary: "s", Timestamp: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := x.count(MemoryFilter{AgentName: "coder"}); n != 0 {
+		t.Errorf("stale key left under the old agent: %d", n)
+	}
+	if r, _ := x.get("a"); r == nil || r.AgentName != "writer" || r.Summary != "s" {
+		t.Errorf("get = %+v", r)
+	}
+	if n := x.vectors(); n != 1 {
+		t.Errorf("vectors after replace = %d, want 1", n)
+	}
+
+	if err := x.remove("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := x.remove("missing"); err != nil {
+		t.Fatal(err)
+	}
+	if records, _ := x.latest(MemoryFilter{}, 0); len(records) != 0 {
+		t.Errorf("records after remove = %v", recordIDs(records))
+	}
+	if n := x.vectors(); n != 0 {
+		t.Errorf("vectors after remove = %d, want 0", n)
+	}
+}
+
+func TestOpenMetaIndexShared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "meta.db")
+	a, err := openMetaIndex(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := openMetaIndex(path, false)
+	if err != nil {
+		t.Fatalf("second open in one process: %v", err)
+	}
+	if a != b {
+		t.Error("opens of one path should share the index")
+	}
+	_ = a.close()
+	if err := b.reset(nil, 0); err != nil {
+		t.Errorf("index closed while still referenced: %v", err)
+	}
+	_ = b.close()
+}
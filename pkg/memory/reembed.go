@@ -0,0 +1,273 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/DotNetAge/goharness/memory"
+	goragcore "github.com/DotNetAge/gorag/v2/core"
+	goragindexer "github.com/DotNetAge/gorag/v2/indexer"
+	"github.com/DotNetAge/gorag/v2/logging"
+	"github.com/DotNetAge/gorag/v2/store/vector/govector"
+	"github.com/DotNetAge/mindx/pkg/storage"
+)
+
+// embedderStampFile is the file in a vector directory recording which
+// embedder its active store was built with.
+const embedderStampFile = "embedder.json"
+
+// EmbedderStamp records the embedder the vectors of a store were made
+// with.
+type EmbedderStamp struct {
+	// ID is the EmbedderConfig.ID of the embedder.
+	ID string `json:"id"`
+	// Dim is the vector dimension.
+	Dim int `json:"dim"`
+	// File is the store's file name within the vector directory.
+	File string `json:"file"`
+}
+
+// ReadEmbedderStamp reads the stamp of the vector directory dir; a
+// directory without one yields nil.
+func ReadEmbedderStamp(dir string) (*EmbedderStamp, error) {
+	data, err := os.ReadFile(filepath.Join(dir, embedderStampFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var s EmbedderStamp
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("memory: 解析 %s 失败: %w", embedderStampFile, err)
+	}
+	return &s, nil
+}
+
+// WriteEmbedderStamp makes s the stamp of the vector directory dir.
+func WriteEmbedderStamp(dir string, s EmbedderStamp) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, embedderStampFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, embedderStampFile))
+}
+
+// ResolveVectorStore returns the file, within the vector directory dir,
+// of the store named name holding vectors of embedder id.
+//
+// Vectors of different embedders differ in meaning and often in
+// dimension, so each embedder gets its own store file and the stamp
+// records which one is active. A directory without a stamp predates
+// pluggable embedders: its name.db store is taken to be id's and, unless
+// readOnly, stamped as such. When the stamp names another embedder the
+// store of id is name-<id>.db and the stamp is returned as prev: the data
+// in prev's store still has to be re-embedded into it. An empty id
+// resolves to name.db without consulting the stamp.
+func ResolveVectorStore(dir, name, id string, dim int, readOnly bool) (file string, prev *EmbedderStamp, err error) {
+	if id == "" {
+		return name + ".db", nil, nil
+	}
+	stamp, err := ReadEmbedderStamp(dir)
+	if err != nil {
+		return "", nil, err
+	}
+	switch {
+	case stamp == nil:
+		file = name + ".db"
+		if !readOnly {
+			err = WriteEmbedderStamp(dir, EmbedderStamp{ID: id, Dim: dim, File: file})
+		}
+		return file, nil, err
+	case stamp.ID == id:
+		return stamp.File, nil, nil
+	}
+	return name + "-" + id + ".db", stamp, nil
+}
+
+// openSemanticIndexer opens the memory vector store at path and the
+// semantic indexer over it.
+func openSemanticIndexer(path string, emb goragcore.Embedder, dim int, readOnly bool, logger logging.Logger) (goragcore.Indexer, error) {
+	vs, err := govector.NewStore(
+		govector.WithCollection("shared_sem"),
+		govector.WithDimension(dim),
+		govector.WithDBPath(path),
+		govector.WithHNSW(true),
+		govector.WithReadOnly(readOnly),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("memory: 创建语义向量存储 %s 失败: %w", path, err)
+	}
+	return goragindexer.NewSemanticIndexer(vs, openingEmbedder{emb},
+		goragindexer.WithSemanticLogger(logger),
+	), nil
+}
+
+// openingEmbedder opens the texts it is given before embedding them.
+// storeMemoryChunk hands the indexer sealed chunk text, which the vector
+// store keeps as is; the vectors must still be made from the plaintext.
+type openingEmbedder struct {
+	goragcore.Embedder
+}
+
+func (e openingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	opened := make([]string, len(texts))
+	for i, t := range texts {
+		text, err := storage.OpenText(t)
+		if err != nil {
+			return nil, fmt.Errorf("memory: 解密待嵌入文本失败: %w", err)
+		}
+		opened[i] = text
+	}
+	return e.Embedder.Embed(ctx, opened)
+}
+
+// NeedsReembed reports whether memories stored with an earlier embedder
+// are waiting to be re-embedded into the current store.
+func (m *RAGMemory) NeedsReembed() bool {
+	return m.pending.Load() != nil
+}
+
+// Reembed re-embeds every memory with the current embedder and reports
+// how many it stored. After an embedder change it copies the memories of
+// the previous embedder's store into the current one, skipping those
+// already there, and then makes the current store the active one; the
+// previous store file is left in place. Otherwise it re-stores the
+// memories of the current store in place. progress, when set, is called
+// after each memory with the counts done and in total.
+func (m *RAGMemory) Reembed(ctx context.Context, progress func(done, total int)) (int, error) {
+	if m.semantic == nil {
+		return 0, fmt.Errorf("memory: 语义索引器未初始化")
+	}
+	if !m.reembedMu.TryLock() {
+		return 0, fmt.Errorf("memory: 重新嵌入正在进行")
+	}
+	defer m.reembedMu.Unlock()
+
+	prev := m.pending.Load()
+	if prev == nil {
+		return m.reembedInPlace(ctx, progress)
+	}
+
+	src, err := openSemanticIndexer(filepath.Join(m.vectorDir, prev.File), m.embedder, prev.Dim, true, m.logger)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if closer, ok := src.(io.Closer); ok {
+			_ = closer.Close()
+		}
+	}()
+	hits, err := listHits(ctx, src)
+	if err != nil {
+		return 0, err
+	}
+	existing, err := listHits(ctx, m.semantic)
+	if err != nil {
+		return 0, err
+	}
+	have := make(map[string]bool, len(existing))
+	for _, hit := range existing {
+		have[hit.ID] = true
+	}
+
+	n := 0
+	for i, hit := range hits {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		if !have[hit.ID] {
+			if err := m.restoreHit(ctx, hit); err != nil {
+				return n, err
+			}
+			n++
+		}
+		if progress != nil {
+			progress(i+1, len(hits))
+		}
+	}
+
+	stamp := EmbedderStamp{ID: m.embedderID, Dim: m.embedder.Dim(), File: m.vectorFile}
+	if err := WriteEmbedderStamp(m.vectorDir, stamp); err != nil {
+		return n, fmt.Errorf("memory: 写入 embedder 标记失败: %w", err)
+	}
+	m.pending.Store(nil)
+	m.logger.Info("memory: 重新嵌入完成",
+		"chunks", n,
+		"from", prev.File,
+		"to", m.vectorFile,
+	)
+	return n, nil
+}
+
+// reembedInPlace re-stores every memory of the current store through the
+// current embedder. A memory is stored again under its own ID, which
+// replaces its entry only once the new vector is made, so a failed
+// embedding or a crash part way leaves every memory in the store.
+func (m *RAGMemory) reembedInPlace(ctx context.Context, progress func(done, total int)) (int, error) {
+	hits, err := listHits(ctx, m.semantic)
+	if err != nil {
+		return 0, err
+	}
+	for i, hit := range hits {
+		if err := ctx.Err(); err != nil {
+			return i, err
+		}
+		if err := m.restoreHit(ctx, hit); err != nil {
+			return i, fmt.Errorf("memory: 重新嵌入记忆 %s 失败: %w", hit.ID, err)
+		}
+		if progress != nil {
+			progress(i+1, len(hits))
+		}
+	}
+	return len(hits), nil
+}
+
+// restoreHit stores the memory of a listed hit, with its consolidation
+// metadata, through the current embedder.
+func (m *RAGMemory) restoreHit(ctx context.Context, hit goragcore.Hit) error {
+	chunk, extra, err := openHitChunk(hit)
+	if err != nil {
+		return err
+	}
+	return m.storeMemoryChunk(ctx, *chunk, extra)
+}
+
+// openHitChunk returns the memory of a listed hit and its consolidation
+// metadata.
+func openHitChunk(hit goragcore.Hit) (*memory.MemoryChunk, map[string]any, error) {
+	opened, err := OpenHit(hit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("memory: 解密记忆 %s 失败: %w", hit.ID, err)
+	}
+	chunk := hitToChunk(opened)
+	if chunk == nil {
+		return nil, nil, fmt.Errorf("memory: 读取记忆 %s 失败", hit.ID)
+	}
+	return chunk, chunkExtras(hit.Metadata), nil
+}
+
+// listHits pages through every chunk of idx.
+func listHits(ctx context.Context, idx goragcore.Indexer) ([]goragcore.Hit, error) {
+	var all []goragcore.Hit
+	const pageSize = 200
+	for offset := 0; ; offset += pageSize {
+		hits, err := idx.List(ctx, offset, pageSize)
+		if err != nil {
+			return nil, fmt.Errorf("memory: 列出记忆失败: %w", err)
+		}
+		all = append(all, hits...)
+		if len(hits) < pageSize {
+			break
+		}
+	}
+	return all, nil
+}
@@ -0,0 +1,248 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/DotNetAge/goharness/memory"
+)
+
+// RetrieveMode selects how Retrieve matches a query.
+type RetrieveMode string
+
+const (
+	// RetrieveVector ranks by embedding similarity alone.
+	RetrieveVector RetrieveMode = "vector"
+	// RetrieveLexical ranks by BM25 over the memories' words alone.
+	RetrieveLexical RetrieveMode = "lexical"
+	// RetrieveHybrid fuses the vector and lexical rankings with
+	// reciprocal-rank fusion. It is the default.
+	RetrieveHybrid RetrieveMode = "hybrid"
+)
+
+// rrfK is the rank constant of reciprocal-rank fusion.
+const rrfK = 60
+
+// ParseRetrieveMode parses a retrieve mode; an empty string is
+// RetrieveHybrid.
+func ParseRetrieveMode(s string) (RetrieveMode, error) {
+	switch RetrieveMode(s) {
+	case "":
+		return RetrieveHybrid, nil
+	case RetrieveVector, RetrieveLexical, RetrieveHybrid:
+		return RetrieveMode(s), nil
+	}
+	return "", fmt.Errorf("memory: 未知的检索模式 %q（可选 vector、lexical、hybrid）", s)
+}
+
+// retrieved collects the chunks of one Retrieve call, each once.
+type retrieved struct {
+	chunks []memory.MemoryChunk
+	byID   map[string]int
+}
+
+func (r *retrieved) add(chunk memory.MemoryChunk) int {
+	if i, ok := r.byID[chunk.ID]; ok {
+		return i
+	}
+	if r.byID == nil {
+		r.byID = map[string]int{}
+	}
+	r.byID[chunk.ID] = len(r.chunks)
+	r.chunks = append(r.chunks, chunk)
+	return len(r.chunks) - 1
+}
+
+// RetrieveWithMode is Retrieve in the given mode; an empty mode is the
+// RAGMemory's default. MinScore filters vector hits by similarity and
+// does not apply to lexical ones. When the lexical index is unavailable a
+// hybrid retrieve falls back to vector search. With an Access in ctx,
+// memories it may not read are left out of both rankings.
+func (m *RAGMemory) RetrieveWithMode(ctx context.Context, query string, mode RetrieveMode, opts ...memory.RetrieveOption) ([]memory.MemoryChunk, error) {
+	cfg := memory.DefaultRetrieveConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if m.semantic == nil {
+		return nil, fmt.Errorf("memory: 语义索引器未初始化")
+	}
+	if mode == "" {
+		mode = m.mode
+	}
+	if mode == "" {
+		mode = RetrieveHybrid
+	}
+	if mode != RetrieveVector && !m.lexicalReady(ctx) {
+		if mode == RetrieveLexical {
+			return nil, fmt.Errorf("memory: 词法索引不可用")
+		}
+		mode = RetrieveVector
+	}
+
+	var found retrieved
+	var vector, lexical []rankedChunk
+	if mode != RetrieveLexical {
+		var err error
+		if vector, err = m.vectorRanking(ctx, query, cfg, &found); err != nil {
+			return nil, err
+		}
+	}
+	if mode != RetrieveVector {
+		lexical = m.lexicalRanking(query, cfg, accessFilter(ctx), &found)
+	}
+
+	var ranked []rankedChunk
+	switch mode {
+	case RetrieveVector:
+		ranked = vector
+	case RetrieveLexical:
+		ranked = lexical
+	default:
+		ranked = fuseRankings(vector, lexical)
+	}
+	if len(ranked) == 0 {
+		return nil, nil
+	}
+
+	// 按相关度、重要度与新近度综合排序。
+	m.decay.sortByDecay(ranked, time.Now())
+	if cfg.Limit > 0 && len(ranked) > cfg.Limit {
+		ranked = ranked[:cfg.Limit]
+	}
+	out := make([]memory.MemoryChunk, len(ranked))
+	for i, r := range ranked {
+		out[i] = found.chunks[r.chunk]
+	}
+	return out, nil
+}
+
+// vectorRanking runs the semantic search, best first, with similarity as
+// relevance.
+func (m *RAGMemory) vectorRanking(ctx context.Context, query string, cfg memory.RetrieveConfig, found *retrieved) ([]rankedChunk, error) {
+	q := m.buildQueryWithFilter(query, cfg)
+	if q == nil {
+		return nil, nil
+	}
+	hits, err := m.semantic.Search(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("memory: 检索失败: %w", err)
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+
+	access := accessFilter(ctx)
+	var ranked []rankedChunk
+	for _, hit := range hits {
+		if cfg.MinScore > 0 && float64(hit.Score) < cfg.MinScore {
+			continue
+		}
+		if access != nil {
+			if r := hitToRecord(hit); !access.readable(&r) {
+				continue
+			}
+		}
+		chunk := hitToChunk(hit)
+		if chunk == nil {
+			continue
+		}
+		ranked = append(ranked, rankedChunk{
+			chunk:      found.add(*chunk),
+			relevance:  float64(hit.Score),
+			importance: importanceOf(hit.Metadata),
+			ts:         chunk.Timestamp,
+		})
+	}
+	return ranked, nil
+}
+
+// lexicalRanking runs the BM25 search, best first, with the score
+// relative to the best hit as relevance.
+func (m *RAGMemory) lexicalRanking(query string, cfg memory.RetrieveConfig, access *Access, found *retrieved) []rankedChunk {
+	limit := 2 * cfg.Limit
+	if limit < 20 {
+		limit = 20
+	}
+	f := MemoryFilter{AgentName: cfg.AgentName, ProjectDir: cfg.ProjectDir, SessionID: cfg.SessionID, Access: access}
+	hits := m.lexical.search(query, f, limit)
+
+	var ranked []rankedChunk
+	for i := range hits {
+		chunk := recordToChunk(&hits[i].record)
+		if chunk == nil {
+			continue
+		}
+		ranked = append(ranked, rankedChunk{
+			chunk:      found.add(*chunk),
+			relevance:  hits[i].score / hits[0].score,
+			importance: recordImportance(&hits[i].record),
+			ts:         chunk.Timestamp,
+		})
+	}
+	return ranked
+}
+
+// fuseRankings merges rankings by reciprocal-rank fusion: a chunk scores
+// the sum of 1/(rrfK+rank) over the rankings it appears in. Relevance is
+// that sum relative to the score of a chunk ranked first in all of them.
+func fuseRankings(rankings ...[]rankedChunk) []rankedChunk {
+	var fused []rankedChunk
+	pos := map[int]int{} // chunk → index in fused
+	for _, ranking := range rankings {
+		for rank, r := range ranking {
+			i, ok := pos[r.chunk]
+			if !ok {
+				i = len(fused)
+				pos[r.chunk] = i
+				r.relevance = 0
+				fused = append(fused, r)
+			}
+			fused[i].relevance += 1 / float64(rrfK+rank+1)
+		}
+	}
+	best := float64(len(rankings)) / float64(rrfK+1)
+	for i := range fused {
+		fused[i].relevance /= best
+	}
+	sort.SliceStable(fused, func(i, j int) bool { return fused[i].relevance > fused[j].relevance })
+	return fused
+}
+
+// lexicalReady reports whether the lexical index can answer a query,
+// building it first when it is not in step with the vector store.
+func (m *RAGMemory) lexicalReady(ctx context.Context) bool {
+	if m.lexical == nil {
+		return false
+	}
+	n, err := m.semantic.Count(ctx)
+	if err != nil {
+		return false
+	}
+	if m.lexical.inStep(n) {
+		return true
+	}
+	useIndex := m.indexReady(ctx)
+
+	m.indexMu.Lock()
+	defer m.indexMu.Unlock()
+	if n, err = m.semantic.Count(ctx); err != nil {
+		return false
+	}
+	if m.lexical.inStep(n) {
+		return true
+	}
+	var records []metaRecord
+	if useIndex && m.index.vectors() == n {
+		records, err = m.index.latest(MemoryFilter{}, 0)
+	} else {
+		records, err = m.listRecords(ctx)
+	}
+	if err != nil {
+		m.logger.Warn("memory: 构建词法索引失败", "error", err)
+		return false
+	}
+	m.lexical.reset(records, n)
+	m.logger.Info("memory: 词法索引已构建", "chunks", len(records))
+	return true
+}
@@ -0,0 +1,46 @@
+package memory
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseRetrieveMode(t *testing.T) {
+	for in, want := range map[string]RetrieveMode{
+		"":        RetrieveHybrid,
+		"vector":  RetrieveVector,
+		"lexical": RetrieveLexical,
+		"hybrid":  RetrieveHybrid,
+	} {
+		if got, err := ParseRetrieveMode(in); err != nil || got != want {
+			t.Errorf("ParseRetrieveMode(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseRetrieveMode("bm25"); err == nil {
+		t.Error("ParseRetrieveMode(bm25) should fail")
+	}
+}
+
+func TestFuseRankings(t *testing.T) {
+	vector := []rankedChunk{{chunk: 0, relevance: 0.9}, {chunk: 1, relevance: 0.8}, {chunk: 2, relevance: 0.7}}
+	lexical := []rankedChunk{{chunk: 2, relevance: 1}, {chunk: 3, relevance: 0.5}}
+
+	fused := fuseRankings(vector, lexical)
+	order := make([]int, len(fused))
+	for i, r := range fused {
+		order[i] = r.chunk
+	}
+	// 2 is found by both searches and wins; 0 ranks first in one; 1 and 3
+	// both rank second in one and keep the order they were found in.
+	if len(order) != 4 || order[0] != 2 || order[1] != 0 || order[2] != 1 || order[3] != 3 {
+		t.Fatalf("fused order = %v, want [2 0 1 3]", order)
+	}
+	want := (1.0/63 + 1.0/61) / (2.0 / 61)
+	if math.Abs(fused[0].relevance-want) > 1e-12 {
+		t.Errorf("relevance = %v, want %v", fused[0].relevance, want)
+	}
+
+	if got := fuseRankings(vector, nil); got[0].chunk != 0 || got[0].relevance >= 1 {
+		t.Errorf("single ranking fused = %+v", got[0])
+	}
+}
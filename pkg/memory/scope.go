@@ -0,0 +1,210 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/DotNetAge/goharness/memory"
+)
+
+// Scope is the visibility of a memory.
+type Scope string
+
+const (
+	// ScopeGlobal memories are visible to every agent in every project.
+	ScopeGlobal Scope = "global"
+	// ScopeProject memories are shared by the agents working in one
+	// project directory.
+	ScopeProject Scope = "project"
+	// ScopeAgent memories belong to one agent, across projects.
+	ScopeAgent Scope = "agent"
+	// ScopeSession memories belong to one session.
+	ScopeSession Scope = "session"
+)
+
+// metaScope is the chunk metadata key holding its Scope.
+const metaScope = "scope"
+
+// AllScopes lists the scopes from the widest to the narrowest.
+var AllScopes = []Scope{ScopeGlobal, ScopeProject, ScopeAgent, ScopeSession}
+
+// ErrScopeDenied is returned for a write to a scope the writer may not
+// write.
+var ErrScopeDenied = errors.New("memory: 无权写入该记忆范围")
+
+// ParseScope parses a scope name.
+func ParseScope(s string) (Scope, error) {
+	if slices.Contains(AllScopes, Scope(s)) {
+		return Scope(s), nil
+	}
+	return "", fmt.Errorf("memory: 未知的记忆范围 %q（可选 global、project、agent、session）", s)
+}
+
+// inferScope returns the scope of a memory stored without one, as were
+// all memories before scopes: the agent's own when it names an agent,
+// else its project's, else global.
+func inferScope(agentName, projectDir string) Scope {
+	switch {
+	case agentName != "":
+		return ScopeAgent
+	case projectDir != "":
+		return ScopeProject
+	default:
+		return ScopeGlobal
+	}
+}
+
+// scope returns the scope of r.
+func (r *metaRecord) scope() Scope {
+	if r.Scope != "" {
+		return r.Scope
+	}
+	return inferScope(r.AgentName, r.ProjectDir)
+}
+
+// ScopePolicy is the scopes an agent may read and write, declared under
+// "memory" in the agent's front-matter.
+type ScopePolicy struct {
+	Read []Scope `json:"read,omitempty" yaml:"read"`
+	// Write lists the writable scopes; the agent's memories are stored in
+	// the first.
+	Write []Scope `json:"write,omitempty" yaml:"write"`
+}
+
+// DefaultScopePolicy applies to agents that declare none: they read every
+// scope and keep what they write to themselves.
+var DefaultScopePolicy = ScopePolicy{
+	Read:  AllScopes,
+	Write: []Scope{ScopeAgent},
+}
+
+// Validate reports the first unknown scope in p.
+func (p ScopePolicy) Validate() error {
+	for _, s := range append(slices.Clone(p.Read), p.Write...) {
+		if _, err := ParseScope(string(s)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Access is who reads or writes memory, and what they may reach. A
+// context carrying one (see WithAccess) limits RAGMemory to it; without
+// one, as for the CLI, every memory is reachable.
+type Access struct {
+	AgentName  string
+	ProjectDir string
+	SessionID  string
+	Policy     ScopePolicy
+}
+
+// CanRead reports whether a may read memories of scope s.
+func (a Access) CanRead(s Scope) bool {
+	return slices.Contains(a.Policy.Read, s)
+}
+
+// CanWrite reports whether a may write memories of scope s.
+func (a Access) CanWrite(s Scope) bool {
+	return slices.Contains(a.Policy.Write, s)
+}
+
+// readable reports whether a may read r: its scope must be readable and,
+// below global, r must belong to a's project, agent or session.
+func (a Access) readable(r *metaRecord) bool {
+	s := r.scope()
+	if !a.CanRead(s) {
+		return false
+	}
+	switch s {
+	case ScopeGlobal:
+		return true
+	case ScopeProject:
+		return a.ProjectDir != "" && r.ProjectDir == a.ProjectDir
+	case ScopeAgent:
+		return a.AgentName != "" && r.AgentName == a.AgentName
+	case ScopeSession:
+		return a.SessionID != "" && r.SessionID == a.SessionID
+	}
+	return false
+}
+
+type accessKey struct{}
+
+// WithAccess returns a context limiting the memory it is passed to to a.
+func WithAccess(ctx context.Context, a Access) context.Context {
+	return context.WithValue(ctx, accessKey{}, a)
+}
+
+// AccessFrom returns the access carried by ctx.
+func AccessFrom(ctx context.Context) (Access, bool) {
+	if ctx == nil {
+		return Access{}, false
+	}
+	a, ok := ctx.Value(accessKey{}).(Access)
+	return a, ok
+}
+
+// accessFilter returns the access carried by ctx for a MemoryFilter, or
+// nil.
+func accessFilter(ctx context.Context) *Access {
+	if a, ok := AccessFrom(ctx); ok {
+		return &a
+	}
+	return nil
+}
+
+// resolveScope returns the scope chunk is stored in. An empty scope is
+// the first scope the access in ctx may write or, without an access,
+// inferred from the chunk. The chunk must name the project, agent or
+// session of its scope; the access fills those left empty and may only
+// write its own.
+func resolveScope(ctx context.Context, chunk *memory.MemoryChunk, scope Scope) (Scope, error) {
+	a, limited := AccessFrom(ctx)
+	if scope == "" {
+		if !limited {
+			return inferScope(chunk.AgentName, chunk.ProjectDir), nil
+		}
+		if len(a.Policy.Write) == 0 {
+			return "", fmt.Errorf("%w: agent %s 不能写入记忆", ErrScopeDenied, a.AgentName)
+		}
+		scope = a.Policy.Write[0]
+	}
+	if _, err := ParseScope(string(scope)); err != nil {
+		return "", err
+	}
+	if limited {
+		if !a.CanWrite(scope) {
+			return "", fmt.Errorf("%w: agent %s 不能写入 %s 范围", ErrScopeDenied, a.AgentName, scope)
+		}
+		if chunk.AgentName == "" {
+			chunk.AgentName = a.AgentName
+		}
+		if chunk.ProjectDir == "" {
+			chunk.ProjectDir = a.ProjectDir
+		}
+		if chunk.SessionID == "" {
+			chunk.SessionID = a.SessionID
+		}
+	}
+
+	var owner, own, field string
+	switch scope {
+	case ScopeProject:
+		owner, own, field = chunk.ProjectDir, a.ProjectDir, "project_dir"
+	case ScopeAgent:
+		owner, own, field = chunk.AgentName, a.AgentName, "agent_name"
+	case ScopeSession:
+		owner, own, field = chunk.SessionID, a.SessionID, "session_id"
+	default:
+		return scope, nil
+	}
+	if owner == "" {
+		return "", fmt.Errorf("memory: %s 范围的记忆缺少 %s", scope, field)
+	}
+	if limited && owner != own {
+		return "", fmt.Errorf("%w: agent %s 不能写入其他 %s 的记忆", ErrScopeDenied, a.AgentName, field)
+	}
+	return scope, nil
+}
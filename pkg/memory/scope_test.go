@@ -0,0 +1,151 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/DotNetAge/goharness/memory"
+)
+
+func TestParseScope(t *testing.T) {
+	for _, s := range AllScopes {
+		if got, err := ParseScope(string(s)); err != nil || got != s {
+			t.Errorf("ParseScope(%q) = %q, %v", s, got, err)
+		}
+	}
+	if _, err := ParseScope("team"); err == nil {
+		t.Error("ParseScope(team) should fail")
+	}
+	if err := (ScopePolicy{Read: []Scope{ScopeGlobal}, Write: []Scope{"team"}}).Validate(); err == nil {
+		t.Error("Validate should reject an unknown write scope")
+	}
+	if err := DefaultScopePolicy.Validate(); err != nil {
+		t.Errorf("DefaultScopePolicy: %v", err)
+	}
+}
+
+func TestRecordScope(t *testing.T) {
+	tests := []struct {
+		r    metaRecord
+		want Scope
+	}{
+		{metaRecord{Scope: ScopeSession, AgentName: "coder"}, ScopeSession},
+		// Memories stored before scopes stay with their agent.
+		{metaRecord{AgentName: "coder", ProjectDir: "/work/api"}, ScopeAgent},
+		{metaRecord{ProjectDir: "/work/api"}, ScopeProject},
+		{metaRecord{}, ScopeGlobal},
+	}
+	for _, tt := range tests {
+		if got := tt.r.scope(); got != tt.want {
+			t.Errorf("scope of %+v = %q, want %q", tt.r, got, tt.want)
+		}
+	}
+}
+
+func TestAccessReadable(t *testing.T) {
+	coder := Access{AgentName: "coder", ProjectDir: "/work/api", SessionID: "s1", Policy: DefaultScopePolicy}
+	tests := []struct {
+		r    metaRecord
+		want bool
+	}{
+		{metaRecord{Scope: ScopeGlobal, AgentName: "writer"}, true},
+		{metaRecord{Scope: ScopeProject, AgentName: "writer", ProjectDir: "/work/api"}, true},
+		{metaRecord{Scope: ScopeProject, AgentName: "coder", ProjectDir: "/work/web"}, false},
+		{metaRecord{Scope: ScopeAgent, AgentName: "coder", ProjectDir: "/work/web"}, true},
+		{metaRecord{Scope: ScopeAgent, AgentName: "writer", ProjectDir: "/work/api"}, false},
+		{metaRecord{AgentName: "writer", ProjectDir: "/work/api"}, false},
+		{metaRecord{Scope: ScopeSession, AgentName: "writer", SessionID: "s1"}, true},
+		{metaRecord{Scope: ScopeSession, AgentName: "coder", SessionID: "s2"}, false},
+		{metaRecord{Scope: "team", AgentName: "coder"}, false},
+	}
+	for _, tt := range tests {
+		if got := coder.readable(&tt.r); got != tt.want {
+			t.Errorf("readable(%+v) = %v, want %v", tt.r, got, tt.want)
+		}
+	}
+
+	private := coder
+	private.Policy = ScopePolicy{Read: []Scope{ScopeAgent}}
+	if private.readable(&metaRecord{Scope: ScopeGlobal}) {
+		t.Error("global memories readable without the global scope")
+	}
+	if !private.readable(&metaRecord{Scope: ScopeAgent, AgentName: "coder"}) {
+		t.Error("own memories unreadable with the agent scope")
+	}
+}
+
+func TestResolveScope(t *testing.T) {
+	// Without an access the scope follows the chunk's fields.
+	chunk := memory.MemoryChunk{ProjectDir: "/work/api"}
+	if s, err := resolveScope(context.Background(), &chunk, ""); err != nil || s != ScopeProject {
+		t.Errorf("unlimited = %q, %v", s, err)
+	}
+	if _, err := resolveScope(context.Background(), &memory.MemoryChunk{}, ScopeSession); err == nil {
+		t.Error("a session memory without a session should fail")
+	}
+
+	ctx := WithAccess(context.Background(), Access{
+		AgentName:  "coder",
+		ProjectDir: "/work/api",
+		SessionID:  "s1",
+		Policy:     ScopePolicy{Read: AllScopes, Write: []Scope{ScopeSession, ScopeProject}},
+	})
+	chunk = memory.MemoryChunk{Content: "deploy on Fridays"}
+	s, err := resolveScope(ctx, &chunk, "")
+	if err != nil || s != ScopeSession {
+		t.Fatalf("default write scope = %q, %v", s, err)
+	}
+	if chunk.AgentName != "coder" || chunk.ProjectDir != "/work/api" || chunk.SessionID != "s1" {
+		t.Errorf("chunk not filed under the access: %+v", chunk)
+	}
+	if _, err := resolveScope(ctx, &memory.MemoryChunk{}, ScopeGlobal); !errors.Is(err, ErrScopeDenied) {
+		t.Errorf("global write: err = %v, want ErrScopeDenied", err)
+	}
+	if _, err := resolveScope(ctx, &memory.MemoryChunk{ProjectDir: "/work/web"}, ScopeProject); !errors.Is(err, ErrScopeDenied) {
+		t.Errorf("write to another project: err = %v, want ErrScopeDenied", err)
+	}
+
+	readOnly := WithAccess(context.Background(), Access{AgentName: "coder", Policy: ScopePolicy{Read: AllScopes}})
+	if _, err := resolveScope(readOnly, &memory.MemoryChunk{}, ""); !errors.Is(err, ErrScopeDenied) {
+		t.Errorf("read-only agent: err = %v, want ErrScopeDenied", err)
+	}
+}
+
+func TestAccessFilter(t *testing.T) {
+	records := []metaRecord{
+		{ID: "g", Scope: ScopeGlobal, Summary: "deploy checklist", Timestamp: 1},
+		{ID: "mine", AgentName: "coder", ProjectDir: "/work/api", Summary: "deploy token rotation", Timestamp: 2},
+		{ID: "theirs", AgentName: "backend", ProjectDir: "/work/api", Summary: "deploy secret key", Timestamp: 3},
+		{ID: "proj", Scope: ScopeProject, AgentName: "backend", ProjectDir: "/work/api", Summary: "deploy window", Timestamp: 4},
+	}
+	access := &Access{AgentName: "coder", ProjectDir: "/work/api", Policy: DefaultScopePolicy}
+
+	x := testMetaIndex(t)
+	if err := x.reset(records, len(records)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := x.latest(MemoryFilter{ProjectDir: "/work/api", Access: access}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := fmt.Sprint(recordIDs(got)); ids != "[proj mine]" {
+		t.Errorf("latest = %s, want [proj mine]", ids)
+	}
+	if n, _ := x.count(MemoryFilter{Access: access}); n != 3 {
+		t.Errorf("count = %d, want 3", n)
+	}
+
+	lex := newLexicalIndex()
+	lex.reset(records, len(records))
+	hits := lex.search("deploy", MemoryFilter{Access: access}, 10)
+	for _, h := range hits {
+		if h.record.ID == "theirs" {
+			t.Error("lexical search returned another agent's memory")
+		}
+	}
+	if len(hits) != 3 {
+		t.Errorf("lexical hits = %d, want 3", len(hits))
+	}
+}
@@ -196,6 +196,12 @@ func TestSessionMethods(t *testing.T) {
 		})
 	})
 
+	t.Run("ListLineage", func(t *testing.T) {
+		testRPC(t, c, m, "session.list", SessionListParams{Agent: "agent-x", Lineage: true}, func() (json.RawMessage, error) {
+			return c.SessionListLineage("agent-x")
+		})
+	})
+
 	t.Run("Get", func(t *testing.T) {
 		testRPC(t, c, m, "session.get", SessionGetParams{SessionID: "sess_123"}, func() (json.RawMessage, error) {
 			return c.SessionGet("sess_123")
@@ -214,6 +220,62 @@ func TestSessionMethods(t *testing.T) {
 		})
 	})
 
+	t.Run("Fork", func(t *testing.T) {
+		testRPC(t, c, m, "session.fork", SessionForkParams{SessionID: "sess_123", AtMessageIndex: 4}, func() (json.RawMessage, error) {
+			return c.SessionFork("sess_123", 4)
+		})
+	})
+
+	t.Run("Export", func(t *testing.T) {
+		testRPC(t, c, m, "session.export", SessionExportParams{SessionID: "sess_123", Format: "markdown"}, func() (json.RawMessage, error) {
+			return c.SessionExport("sess_123", "markdown")
+		})
+	})
+
+	t.Run("Import", func(t *testing.T) {
+		params := SessionImportParams{Data: "H4sI", Agent: "coder", ProjectDir: "/work/api"}
+		testRPC(t, c, m, "session.import", params, func() (json.RawMessage, error) {
+			return c.SessionImport(params)
+		})
+	})
+
+	t.Run("Star", func(t *testing.T) {
+		testRPC(t, c, m, "session.star", SessionStarParams{SessionID: "sess_123", Starred: true}, func() (json.RawMessage, error) {
+			return c.SessionStar("sess_123", true)
+		})
+	})
+
+	t.Run("GC", func(t *testing.T) {
+		params := SessionGCParams{DryRun: true, MaxAgeDays: 90}
+		testRPC(t, c, m, "session.gc", params, func() (json.RawMessage, error) {
+			return c.SessionGC(params)
+		})
+	})
+
+	t.Run("Share", func(t *testing.T) {
+		params := SessionShareParams{SessionID: "sess_123", TTL: 3600, BaseURL: "http://devbox:1313"}
+		testRPC(t, c, m, "session.share", params, func() (json.RawMessage, error) {
+			return c.SessionShare(params)
+		})
+	})
+
+	t.Run("Unshare", func(t *testing.T) {
+		params := SessionUnshareParams{Token: "tok_abc"}
+		testRPC(t, c, m, "session.unshare", params, func() (json.RawMessage, error) {
+			return c.SessionUnshare(params)
+		})
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		params := SessionSearchParams{
+			Query: "decided sqlite", Agent: "coder", ProjectDir: "/work",
+			Since: "2026-09-01", Roles: []string{"assistant"}, Limit: 10,
+		}
+		testRPC(t, c, m, "session.search", params, func() (json.RawMessage, error) {
+			return c.SessionSearch(params)
+		})
+	})
+
 	t.Run("ConfirmFiles", func(t *testing.T) {
 		testRPC(t, c, m, "session.confirm_files", SessionFileActionParams{
 			SessionID: "sess_123", Files: []string{"a.go", "b.go"},
@@ -384,6 +446,13 @@ func TestMemoryMethods(t *testing.T) {
 		})
 	})
 
+	t.Run("QueryBy", func(t *testing.T) {
+		params := MemoryQueryParams{Query: "PROJ-1234", Limit: 5, Mode: "lexical"}
+		testRPC(t, c, m, "memory.query", params, func() (json.RawMessage, error) {
+			return c.MemoryQueryBy(params)
+		})
+	})
+
 	t.Run("Store", func(t *testing.T) {
 		testRPC(t, c, m, "memory.store", MemoryStoreParams{
 			Content: "hello", Title: "t", Description: "d", Source: "s",
@@ -392,6 +461,13 @@ func TestMemoryMethods(t *testing.T) {
 		})
 	})
 
+	t.Run("StoreBy", func(t *testing.T) {
+		params := MemoryStoreParams{Content: "hello", Scope: "project", ProjectDir: "/work/api"}
+		testRPC(t, c, m, "memory.store", params, func() (json.RawMessage, error) {
+			return c.MemoryStoreBy(params)
+		})
+	})
+
 	t.Run("Delete", func(t *testing.T) {
 		testRPC(t, c, m, "memory.delete", MemoryDeleteParams{ID: "mem_1"}, func() (json.RawMessage, error) {
 			return c.MemoryDelete("mem_1")
@@ -415,6 +491,43 @@ func TestMemoryMethods(t *testing.T) {
 			return c.MemoryCount()
 		})
 	})
+
+	t.Run("CountBy", func(t *testing.T) {
+		params := MemoryCountParams{AgentName: "coder", ProjectDir: "/work/api"}
+		testRPC(t, c, m, "memory.count", params, func() (json.RawMessage, error) {
+			return c.MemoryCountBy(params)
+		})
+	})
+
+	t.Run("Consolidate", func(t *testing.T) {
+		testRPC(t, c, m, "memory.consolidate", MemoryConsolidateParams{DryRun: true}, func() (json.RawMessage, error) {
+			return c.MemoryConsolidate(context.Background(), true)
+		})
+	})
+
+	t.Run("Archived", func(t *testing.T) {
+		testRPC(t, c, m, "memory.archived", MemoryArchivedParams{Limit: 20}, func() (json.RawMessage, error) {
+			return c.MemoryArchived(20)
+		})
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		testRPC(t, c, m, "memory.restore", MemoryRestoreParams{ID: "mem_1"}, func() (json.RawMessage, error) {
+			return c.MemoryRestore("mem_1")
+		})
+	})
+
+	t.Run("Reembed", func(t *testing.T) {
+		testRPCNoParams(t, c, m, "memory.reembed", func() (json.RawMessage, error) {
+			return c.MemoryReembed()
+		})
+	})
+
+	t.Run("ReembedStatus", func(t *testing.T) {
+		testRPCNoParams(t, c, m, "memory.reembed_status", func() (json.RawMessage, error) {
+			return c.MemoryReembedStatus()
+		})
+	})
 }
 
 // ============================================================================
@@ -672,6 +785,16 @@ func TestTokenUsageMethods(t *testing.T) {
 			return c.TokenUsageSession("sess_1")
 		})
 	})
+
+	t.Run("Export", func(t *testing.T) {
+		params := TokenUsageExportParams{
+			Since: "2026-09-01", Until: "2026-10-01", Format: "csv",
+			GroupBy: []string{"project_dir", "agent"}, Offset: 100, Limit: 50, IncludeImported: true, Cursor: "c1",
+		}
+		testRPC(t, c, m, "token.usage.export", params, func() (json.RawMessage, error) {
+			return c.TokenUsageExport(params)
+		})
+	})
 }
 
 // ============================================================================
@@ -924,3 +1047,18 @@ func TestUserMethods(t *testing.T) {
 		})
 	})
 }
+
+// ============================================================================
+// Storage domain
+// ============================================================================
+
+func TestStorageMethods(t *testing.T) {
+	m := newMockGateway()
+	c := &Client{gw: m}
+
+	t.Run("Rekey", func(t *testing.T) {
+		testRPCNoParams(t, c, m, "storage.rekey", func() (json.RawMessage, error) {
+			return c.StorageRekey(context.Background())
+		})
+	})
+}
@@ -1,20 +1,32 @@
 package rpc
 
-import "encoding/json"
+import (
+	"context"
+	"encoding/json"
+)
 
-// MemoryQueryParams are the params for memory.query.
+// MemoryQueryParams are the params for memory.query. Mode is "vector",
+// "lexical" or "hybrid" (the default, fusing both rankings).
 type MemoryQueryParams struct {
 	Query    string  `json:"query"`
 	Limit    int     `json:"limit,omitempty"`
 	MinScore float64 `json:"min_score,omitempty"`
+	Mode     string  `json:"mode,omitempty"`
 }
 
-// MemoryStoreParams are the params for memory.store.
+// MemoryStoreParams are the params for memory.store. Scope is "global",
+// "project", "agent" or "session", and the field of the same name says
+// whose; an empty Scope infers it from AgentName and ProjectDir, global
+// when both are empty.
 type MemoryStoreParams struct {
 	Content     string `json:"content"`
 	Title       string `json:"title,omitempty"`
 	Description string `json:"description,omitempty"`
 	Source      string `json:"source,omitempty"`
+	Scope       string `json:"scope,omitempty"`
+	AgentName   string `json:"agent_name,omitempty"`
+	ProjectDir  string `json:"project_dir,omitempty"`
+	SessionID   string `json:"session_id,omitempty"`
 }
 
 // MemoryDeleteParams are the params for memory.delete.
@@ -40,12 +52,20 @@ func (c *Client) MemoryQuery(query string, limit int, minScore float64) (json.Ra
 	})
 }
 
+func (c *Client) MemoryQueryBy(p MemoryQueryParams) (json.RawMessage, error) {
+	return c.CallWithTimeout("memory.query", p)
+}
+
 func (c *Client) MemoryStore(content, title, description, source string) (json.RawMessage, error) {
 	return c.CallWithTimeout("memory.store", MemoryStoreParams{
 		Content: content, Title: title, Description: description, Source: source,
 	})
 }
 
+func (c *Client) MemoryStoreBy(p MemoryStoreParams) (json.RawMessage, error) {
+	return c.CallWithTimeout("memory.store", p)
+}
+
 func (c *Client) MemoryDelete(id string) (json.RawMessage, error) {
 	return c.CallWithTimeout("memory.delete", MemoryDeleteParams{ID: id})
 }
@@ -89,6 +109,15 @@ type ChunkMetaItem struct {
 	HeadingPath  []string `json:"heading_path,omitempty"`
 }
 
+// MemoryCountParams are the optional params for memory.count. Set fields
+// narrow the count to memories of that agent, project or session; with
+// none set the whole index is counted.
+type MemoryCountParams struct {
+	AgentName  string `json:"agent_name,omitempty"`
+	ProjectDir string `json:"project_dir,omitempty"`
+	SessionID  string `json:"session_id,omitempty"`
+}
+
 // MemoryCountResult is the result for memory.count.
 type MemoryCountResult struct {
 	Count int `json:"count"`
@@ -98,6 +127,10 @@ func (c *Client) MemoryCount() (json.RawMessage, error) {
 	return c.CallWithTimeout("memory.count", nil)
 }
 
+func (c *Client) MemoryCountBy(p MemoryCountParams) (json.RawMessage, error) {
+	return c.CallWithTimeout("memory.count", p)
+}
+
 // ── memory.list_by_session ─────────────────────────────────────
 
 // MemoryListBySessionParams are the params for memory.list_by_session.
@@ -143,3 +176,109 @@ func (c *Client) MemoryUpdate(id, summary, content string, tags []string) (json.
 		ID: id, Summary: summary, Content: content, Tags: tags,
 	})
 }
+
+// ── memory.consolidate / memory.archived / memory.restore ──────
+
+// MemoryConsolidateParams are the params for memory.consolidate.
+type MemoryConsolidateParams struct {
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// MemoryClusterItem is a set of memories consolidation merged, or would
+// merge on a dry run.
+type MemoryClusterItem struct {
+	ID         string   `json:"id,omitempty"`
+	AgentName  string   `json:"agent_name,omitempty"`
+	ProjectDir string   `json:"project_dir,omitempty"`
+	Summary    string   `json:"summary,omitempty"`
+	MergedFrom []string `json:"merged_from"`
+}
+
+// MemoryConsolidateResult is the result for memory.consolidate.
+type MemoryConsolidateResult struct {
+	Scanned  int                 `json:"scanned"`
+	Clusters []MemoryClusterItem `json:"clusters"`
+	Pruned   []string            `json:"pruned"`
+	DryRun   bool                `json:"dry_run,omitempty"`
+}
+
+// MemoryArchivedParams are the params for memory.archived.
+type MemoryArchivedParams struct {
+	Limit int `json:"limit,omitempty"`
+}
+
+// MemoryArchivedItem is a memory consolidation moved to the archive.
+type MemoryArchivedItem struct {
+	ID         string   `json:"id"`
+	AgentName  string   `json:"agent_name,omitempty"`
+	SessionID  string   `json:"session_id,omitempty"`
+	ProjectDir string   `json:"project_dir,omitempty"`
+	Summary    string   `json:"summary"`
+	Content    string   `json:"content,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Importance float64  `json:"importance"`
+	MergedFrom []string `json:"merged_from,omitempty"`
+	Reason     string   `json:"reason"`
+	MergedInto string   `json:"merged_into,omitempty"`
+	Timestamp  int64    `json:"timestamp"`
+	ArchivedAt int64    `json:"archived_at"`
+}
+
+// MemoryArchivedResult is the result for memory.archived.
+type MemoryArchivedResult struct {
+	Chunks []MemoryArchivedItem `json:"chunks"`
+	Count  int                  `json:"count"`
+}
+
+// MemoryRestoreParams are the params for memory.restore.
+type MemoryRestoreParams struct {
+	ID string `json:"id"`
+}
+
+// MemoryConsolidate runs a memory consolidation now. Merging calls the
+// model once per cluster, so it runs under ctx instead of the default
+// timeout.
+func (c *Client) MemoryConsolidate(ctx context.Context, dryRun bool) (json.RawMessage, error) {
+	return c.Call(ctx, "memory.consolidate", MemoryConsolidateParams{DryRun: dryRun})
+}
+
+func (c *Client) MemoryArchived(limit int) (json.RawMessage, error) {
+	return c.CallWithTimeout("memory.archived", MemoryArchivedParams{Limit: limit})
+}
+
+func (c *Client) MemoryRestore(id string) (json.RawMessage, error) {
+	return c.CallWithTimeout("memory.restore", MemoryRestoreParams{ID: id})
+}
+
+// ── memory.reembed / memory.reembed_status ─────────────────────
+
+// MemoryReembedStatus is the progress of a re-embedding migration, the
+// result of both memory.reembed and memory.reembed_status.
+type MemoryReembedStatus struct {
+	Running bool `json:"running"`
+	// Pending reports that memories stored with an earlier embedder have
+	// not been migrated yet.
+	Pending bool `json:"pending"`
+	// MemoryDone and MemoryTotal count the memories processed so far and
+	// in all; MemoryStored counts those re-embedded.
+	MemoryDone   int `json:"memory_done"`
+	MemoryTotal  int `json:"memory_total"`
+	MemoryStored int `json:"memory_stored"`
+	// KBQueued is the number of knowledge-base files and directories
+	// queued for re-indexing; KBRemaining of them still wait.
+	KBQueued    int    `json:"kb_queued"`
+	KBRemaining int    `json:"kb_remaining"`
+	StartedAt   int64  `json:"started_at,omitempty"`
+	FinishedAt  int64  `json:"finished_at,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// MemoryReembed starts re-embedding the memory and knowledge-base vectors
+// with the configured embedder in the background.
+func (c *Client) MemoryReembed() (json.RawMessage, error) {
+	return c.CallWithTimeout("memory.reembed", nil)
+}
+
+func (c *Client) MemoryReembedStatus() (json.RawMessage, error) {
+	return c.CallWithTimeout("memory.reembed_status", nil)
+}
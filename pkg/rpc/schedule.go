@@ -8,8 +8,40 @@ type ScheduleAddParams struct {
 	SessionID  string `json:"session_id,omitempty"`
 	ProjectDir string `json:"project_dir,omitempty"`
 	Content    string `json:"content"`
-	CronExpr   string `json:"cron_expr"`
+	CronExpr   string `json:"cron_expr,omitempty"`
 	Enabled    bool   `json:"enabled,omitempty"`
+
+	// Trigger is "cron" (default), "at" or "every". Times accept RFC 3339
+	// or "2006-01-02 15:04" in Timezone.
+	Trigger  string `json:"trigger,omitempty"`
+	RunAt    string `json:"run_at,omitempty"`
+	Every    string `json:"every,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+	StartAt  string `json:"start_at,omitempty"`
+	EndAt    string `json:"end_at,omitempty"`
+	MaxRuns  int    `json:"max_runs,omitempty"`
+
+	// Execution policy. Durations use Go syntax ("10m"); Overlap is
+	// "skip" (default), "queue" or "allow"; CatchUp is "none" (default),
+	// "once" or "all".
+	Timeout      string `json:"timeout,omitempty"`
+	MaxRetries   int    `json:"max_retries,omitempty"`
+	RetryBackoff string `json:"retry_backoff,omitempty"`
+	Overlap      string `json:"overlap,omitempty"`
+	CatchUp      string `json:"catch_up,omitempty"`
+	CatchUpMax   int    `json:"catch_up_max,omitempty"`
+
+	// Follow-up steps, each a JSON object {"agent", "content", "session_id",
+	// "project_dir", "on_success", "on_failure"}. Content may use
+	// {{output}} to inject the previous step's answer.
+	OnSuccess json.RawMessage `json:"on_success,omitempty"`
+	OnFailure json.RawMessage `json:"on_failure,omitempty"`
+
+	// Sinks is a JSON array of delivery targets, e.g.
+	// [{"type": "file", "path": "~/reports/{{date}}.md"},
+	//  {"type": "webhook", "url": "https://..."}, {"type": "memory"},
+	//  {"type": "kv", "key": "reports"}].
+	Sinks json.RawMessage `json:"sinks,omitempty"`
 }
 
 // ScheduleDeleteParams are the params for schedule.del.
@@ -17,6 +49,17 @@ type ScheduleDeleteParams struct {
 	ID string `json:"id"`
 }
 
+// ScheduleRunsParams are the params for schedule.runs.
+type ScheduleRunsParams struct {
+	ID    string `json:"id"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+// ScheduleRunGetParams are the params for schedule.run.get.
+type ScheduleRunGetParams struct {
+	RunID string `json:"run_id"`
+}
+
 func (c *Client) ScheduleList() (json.RawMessage, error) {
 	return c.CallWithTimeout("schedule.list", nil)
 }
@@ -28,3 +71,11 @@ func (c *Client) ScheduleAdd(params ScheduleAddParams) (json.RawMessage, error)
 func (c *Client) ScheduleDelete(id string) (json.RawMessage, error) {
 	return c.CallWithTimeout("schedule.del", ScheduleDeleteParams{ID: id})
 }
+
+func (c *Client) ScheduleRuns(id string, limit int) (json.RawMessage, error) {
+	return c.CallWithTimeout("schedule.runs", ScheduleRunsParams{ID: id, Limit: limit})
+}
+
+func (c *Client) ScheduleRunGet(runID string) (json.RawMessage, error) {
+	return c.CallWithTimeout("schedule.run.get", ScheduleRunGetParams{RunID: runID})
+}
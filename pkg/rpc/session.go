@@ -16,7 +16,8 @@ type SessionGetParams struct {
 
 // SessionListParams are the params for session.list.
 type SessionListParams struct {
-	Agent string `json:"agent,omitempty"`
+	Agent   string `json:"agent,omitempty"`
+	Lineage bool   `json:"lineage,omitempty"` // 为 true 时附带 fork 关系（parent_session_id 等）
 }
 
 // SessionDeleteParams are the params for session.delete.
@@ -61,6 +62,95 @@ type SessionCompactParams struct {
 	Mode      string `json:"mode,omitempty"` // "full" (default) or "micro"
 }
 
+// SessionForkParams are the params for session.fork.
+// AtMessageIndex is the 0-based index, in the session's full history, of
+// the last message the fork keeps.
+type SessionForkParams struct {
+	SessionID      string `json:"session_id"`
+	AtMessageIndex int    `json:"at_message_index"`
+}
+
+// SessionSearchParams are the params for session.search.
+//
+// Every term of Query must occur in a message. Since and Until take
+// YYYY-MM-DD or RFC 3339; Until is exclusive.
+type SessionSearchParams struct {
+	Query      string   `json:"query"`
+	Agent      string   `json:"agent,omitempty"`
+	ProjectDir string   `json:"project_dir,omitempty"`
+	Since      string   `json:"since,omitempty"`
+	Until      string   `json:"until,omitempty"`
+	Roles      []string `json:"roles,omitempty"`
+	Limit      int      `json:"limit,omitempty"`
+}
+
+// SessionExportParams are the params for session.export. Format is
+// "bundle" (default), "markdown" or "openai".
+type SessionExportParams struct {
+	SessionID string `json:"session_id"`
+	Format    string `json:"format,omitempty"`
+}
+
+// SessionExportResult is the result of session.export. Data is base64 for
+// the binary bundle format and plain text otherwise, as Encoding says.
+type SessionExportResult struct {
+	Format   string `json:"format"`
+	Filename string `json:"filename"`
+	Encoding string `json:"encoding"`
+	Data     string `json:"data"`
+}
+
+// SessionImportParams are the params for session.import. Data is the
+// base64-encoded bundle. Agent and ProjectDir, when set, override the
+// bundle's own; tracked file paths under the old project directory are
+// moved to the new one.
+type SessionImportParams struct {
+	Data       string `json:"data"`
+	Agent      string `json:"agent,omitempty"`
+	ProjectDir string `json:"project_dir,omitempty"`
+}
+
+// SessionStarParams are the params for session.star. Starred sessions
+// are exempt from retention unless the policy sets keep_starred to false.
+type SessionStarParams struct {
+	SessionID string `json:"session_id"`
+	Starred   bool   `json:"starred"`
+}
+
+// SessionGCParams are the params for session.gc. The policy is
+// session_retention from mindx.json; non-zero fields here override it.
+type SessionGCParams struct {
+	DryRun      bool   `json:"dry_run,omitempty"`
+	MaxAgeDays  int    `json:"max_age_days,omitempty"`
+	MaxPerAgent int    `json:"max_per_agent,omitempty"`
+	Action      string `json:"action,omitempty"` // "archive" | "delete"
+}
+
+// SessionShareParams are the params for session.share. TTL is the link
+// lifetime in seconds (default one day, at most 30 days). BaseURL replaces
+// the daemon's own http://localhost:1313 origin in the returned link, for
+// teammates who reach the WebUI server under another host name.
+type SessionShareParams struct {
+	SessionID string `json:"session_id"`
+	TTL       int    `json:"ttl,omitempty"`
+	BaseURL   string `json:"base_url,omitempty"`
+}
+
+// SessionShareResult is the result of session.share.
+type SessionShareResult struct {
+	SessionID string `json:"session_id"`
+	Token     string `json:"token"`
+	URL       string `json:"url"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// SessionUnshareParams are the params for session.unshare. Token revokes
+// one link; SessionID alone revokes every link of the session.
+type SessionUnshareParams struct {
+	Token     string `json:"token,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+}
+
 // ContextWindowUsage is the result of session.context.
 // It mirrors goharness/session.ContextWindowUsage.
 type ContextWindowUsage struct {
@@ -84,6 +174,10 @@ func (c *Client) SessionList(agent string) (json.RawMessage, error) {
 	return c.CallWithTimeout("session.list", SessionListParams{Agent: agent})
 }
 
+func (c *Client) SessionListLineage(agent string) (json.RawMessage, error) {
+	return c.CallWithTimeout("session.list", SessionListParams{Agent: agent, Lineage: true})
+}
+
 func (c *Client) SessionGet(sessionID string) (json.RawMessage, error) {
 	return c.CallWithTimeout("session.get", SessionGetParams{SessionID: sessionID})
 }
@@ -112,6 +206,40 @@ func (c *Client) SessionTruncate(sessionID string) (json.RawMessage, error) {
 	return c.CallWithTimeout("session.truncate", SessionTruncateParams{SessionID: sessionID})
 }
 
+func (c *Client) SessionFork(sessionID string, atMessageIndex int) (json.RawMessage, error) {
+	return c.CallWithTimeout("session.fork", SessionForkParams{
+		SessionID: sessionID, AtMessageIndex: atMessageIndex,
+	})
+}
+
+func (c *Client) SessionSearch(p SessionSearchParams) (json.RawMessage, error) {
+	return c.CallWithTimeout("session.search", p)
+}
+
+func (c *Client) SessionExport(sessionID, format string) (json.RawMessage, error) {
+	return c.CallWithTimeout("session.export", SessionExportParams{SessionID: sessionID, Format: format})
+}
+
+func (c *Client) SessionImport(p SessionImportParams) (json.RawMessage, error) {
+	return c.CallWithTimeout("session.import", p)
+}
+
+func (c *Client) SessionStar(sessionID string, starred bool) (json.RawMessage, error) {
+	return c.CallWithTimeout("session.star", SessionStarParams{SessionID: sessionID, Starred: starred})
+}
+
+func (c *Client) SessionGC(p SessionGCParams) (json.RawMessage, error) {
+	return c.CallWithTimeout("session.gc", p)
+}
+
 func (c *Client) SessionContext(sessionID string) (json.RawMessage, error) {
 	return c.CallWithTimeout("session.context", SessionContextParams{SessionID: sessionID})
 }
+
+func (c *Client) SessionShare(p SessionShareParams) (json.RawMessage, error) {
+	return c.CallWithTimeout("session.share", p)
+}
+
+func (c *Client) SessionUnshare(p SessionUnshareParams) (json.RawMessage, error) {
+	return c.CallWithTimeout("session.unshare", p)
+}
@@ -0,0 +1,13 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// StorageRekey rotates the storage data key and rewrites all stored data
+// with it. It can take a while on a large data directory, so it runs
+// under ctx instead of the default timeout.
+func (c *Client) StorageRekey(ctx context.Context) (json.RawMessage, error) {
+	return c.Call(ctx, "storage.rekey", nil)
+}
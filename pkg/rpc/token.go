@@ -25,6 +25,24 @@ type TokenUsageSessionDetailParams struct {
 	SessionID string `json:"session_id"`
 }
 
+// TokenUsageExportParams are the params for token.usage.export. Since and
+// Until are local dates (YYYY-MM-DD); Until is exclusive. Records are
+// returned a page at a time from Offset; Limit 0 uses the server default.
+// The first page of a multi-page export returns a cursor; later pages pass
+// it back, and are served from the records the first page queried.
+// Usage that came with imported sessions is left out unless
+// IncludeImported is set.
+type TokenUsageExportParams struct {
+	Since           string   `json:"since,omitempty"`
+	Until           string   `json:"until,omitempty"`
+	Format          string   `json:"format,omitempty"`
+	GroupBy         []string `json:"group_by,omitempty"`
+	Offset          int      `json:"offset,omitempty"`
+	Limit           int      `json:"limit,omitempty"`
+	IncludeImported bool     `json:"include_imported,omitempty"`
+	Cursor          string   `json:"cursor,omitempty"`
+}
+
 func (c *Client) TokenUsageOverview() (json.RawMessage, error) {
 	return c.CallWithTimeout("token.usage.overview", nil)
 }
@@ -50,3 +68,7 @@ func (c *Client) TokenUsageSession(sessionID string) (json.RawMessage, error) {
 func (c *Client) TokenUsageSessionDetail(sessionID string) (json.RawMessage, error) {
 	return c.CallWithTimeout("token.usage.session.detail", TokenUsageSessionDetailParams{SessionID: sessionID})
 }
+
+func (c *Client) TokenUsageExport(p TokenUsageExportParams) (json.RawMessage, error) {
+	return c.CallWithTimeout("token.usage.export", p)
+}
@@ -0,0 +1,128 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// maxCatchUpScan bounds how many fire times one walk of missedRuns steps
+// through, so a seconds-level schedule after a long downtime cannot stall
+// Start.
+const maxCatchUpScan = 10000
+
+// missedRuns returns the fire times of entry that fell in (since, now],
+// oldest first, keeping only the most recent limit of them. When the walk
+// from since gives up before reaching now, it walks again from a window
+// before now sized for limit fire times at the pace seen so far,
+// widening the window until it holds limit of them. At most half of
+// maxCatchUpScan runs are returned.
+func (e *ScheduleEntry) missedRuns(since, now time.Time, limit int) []time.Time {
+	if limit <= 0 || !since.Before(now) {
+		return nil
+	}
+	limit = min(limit, maxCatchUpScan/2)
+	if e.MaxRuns > 0 {
+		limit = min(limit, e.MaxRuns-e.Runs())
+		if limit <= 0 {
+			return nil
+		}
+	}
+	sched, err := e.Schedule()
+	if err != nil {
+		return nil
+	}
+
+	missed, reached, capped := walkRuns(sched, since, now, limit)
+	if !capped {
+		return missed
+	}
+	step := max(reached.Sub(since)/maxCatchUpScan, time.Second)
+	for window := step * time.Duration(limit+1); ; window *= 2 {
+		start := now.Add(-window)
+		if !start.After(since) {
+			// Unreachable in practice: the first walk covered more.
+			return missed
+		}
+		recent, _, capped := walkRuns(sched, start, now, limit)
+		if len(recent) >= limit || capped {
+			return recent
+		}
+	}
+}
+
+// walkRuns walks the fire times of sched in (since, now], keeping the
+// most recent limit of them. It reports the last fire time it reached and
+// whether it stopped at maxCatchUpScan before passing now.
+func walkRuns(sched cron.Schedule, since, now time.Time, limit int) (missed []time.Time, reached time.Time, capped bool) {
+	t := since
+	for range maxCatchUpScan {
+		t = sched.Next(t)
+		if t.IsZero() || t.After(now) {
+			return missed, reached, false
+		}
+		reached = t
+		missed = append(missed, t)
+		if len(missed) > limit {
+			missed = missed[1:]
+		}
+	}
+	return missed, reached, true
+}
+
+// catchUpRun is the missed fire times of one entry, found at Start.
+type catchUpRun struct {
+	entry  *ScheduleEntry
+	missed []time.Time
+}
+
+// planCatchUp finds the runs each enabled entry missed while the daemon
+// was not running, according to its CatchUp policy. A missed run is a fire
+// time after the entry's last run (or its creation) and before Start.
+// Start calls it before reloadAll, which retires entries with no fire
+// times left, such as a past one-shot, so that their last missed run is
+// still replayed.
+func (s *Scheduler) planCatchUp() []catchUpRun {
+	entries, err := s.store.List(context.Background())
+	if err != nil {
+		s.logger.Warn("scheduler: catch-up skipped, cannot list schedules", "error", err)
+		return nil
+	}
+
+	now := time.Now()
+	var plan []catchUpRun
+	for i := range entries {
+		entry := &entries[i]
+		if !entry.Enabled || entry.CatchUpKind() == CatchUpNone {
+			continue
+		}
+		since := entry.LastRunAt
+		if since.IsZero() || since.Before(entry.CreatedAt) {
+			since = entry.CreatedAt
+		}
+		missed := entry.missedRuns(since, now, entry.catchUpLimit())
+		if len(missed) == 0 {
+			continue
+		}
+
+		s.logger.Info("scheduler: catching up missed runs", "id", entry.ID,
+			"policy", entry.CatchUpKind(), "runs", len(missed), "since", since)
+		plan = append(plan, catchUpRun{entry: entry, missed: missed})
+	}
+	return plan
+}
+
+// catchUp replays the runs planCatchUp found, one goroutine per entry.
+func (s *Scheduler) catchUp(plan []catchUpRun) {
+	for _, run := range plan {
+		go func() {
+			for _, at := range run.missed {
+				if s.stopped() {
+					return
+				}
+				s.runJob(run.entry, at)
+			}
+		}()
+	}
+}
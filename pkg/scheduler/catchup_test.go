@@ -0,0 +1,139 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestScheduleEntry_MissedRuns(t *testing.T) {
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	now := since.Add(5*time.Hour + 30*time.Minute)
+	entry := &ScheduleEntry{ID: "hourly", CronExpr: "0 0 * * * *", Timezone: "UTC"}
+
+	all := entry.missedRuns(since, now, 10)
+	if len(all) != 5 {
+		t.Fatalf("missedRuns = %v, want 5 hourly fire times", all)
+	}
+
+	latest := entry.missedRuns(since, now, 2)
+	want := []time.Time{since.Add(4 * time.Hour), since.Add(5 * time.Hour)}
+	if len(latest) != 2 || !latest[0].Equal(want[0]) || !latest[1].Equal(want[1]) {
+		t.Errorf("missedRuns(limit 2) = %v, want %v", latest, want)
+	}
+
+	entry.MaxRuns, entry.SuccessCnt = 3, 2
+	if got := entry.missedRuns(since, now, 10); len(got) != 1 {
+		t.Errorf("missedRuns with one run left = %v, want 1", got)
+	}
+}
+
+func TestScheduleEntry_MissedRunsPastScanCap(t *testing.T) {
+	// A run every second over a day is more fire times than one walk
+	// steps through; the most recent ones are still returned.
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	now := since.Add(24*time.Hour + 500*time.Millisecond)
+	entry := &ScheduleEntry{ID: "secondly", CronExpr: "* * * * * *", Timezone: "UTC"}
+
+	got := entry.missedRuns(since, now, 3)
+	last := since.Add(24 * time.Hour)
+	want := []time.Time{last.Add(-2 * time.Second), last.Add(-time.Second), last}
+	if len(got) != 3 || !got[0].Equal(want[0]) || !got[1].Equal(want[1]) || !got[2].Equal(want[2]) {
+		t.Errorf("missedRuns = %v, want %v", got, want)
+	}
+}
+
+func TestScheduler_CatchUpOnStart(t *testing.T) {
+	var mu sync.Mutex
+	done := make(chan struct{}, 10)
+	var catchUps []JobLifecycleInfo
+
+	s, store := newTestScheduler(t, func(ctx context.Context, req RunRequest) (*RunResult, error) {
+		return &RunResult{}, nil
+	})
+	s.OnLifecycle(func(info JobLifecycleInfo) {
+		if info.Status != "completed" {
+			return
+		}
+		mu.Lock()
+		catchUps = append(catchUps, info)
+		mu.Unlock()
+		done <- struct{}{}
+	})
+
+	entry := &ScheduleEntry{ID: "daily", Agent: "a", Content: "c", CronExpr: "0 0 9 * * *",
+		Enabled: true, CatchUp: CatchUpOnce, CreatedAt: time.Now().Add(-72 * time.Hour)}
+	if err := store.Save(context.Background(), entry); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := s.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer s.Stop()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no catch-up run after Start")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(catchUps) != 1 || !catchUps[0].CatchUp || catchUps[0].ScheduledAt.IsZero() {
+		t.Errorf("lifecycle = %+v, want one catch-up completion", catchUps)
+	}
+}
+
+func TestScheduler_CatchUpPastOneShot(t *testing.T) {
+	store, err := NewFileSchedulerStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileSchedulerStore failed: %v", err)
+	}
+	// Saved while the daemon was down: the one-shot's time passed before
+	// the scheduler came up.
+	entry := &ScheduleEntry{ID: "once", Agent: "a", Content: "c", Trigger: TriggerAt,
+		RunAt: time.Now().Add(-2 * time.Hour), Enabled: true, CatchUp: CatchUpOnce,
+		CreatedAt: time.Now().Add(-3 * time.Hour)}
+	if err := store.Save(context.Background(), entry); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	done := make(chan JobLifecycleInfo, 10)
+	s := NewScheduler(store, func(ctx context.Context, req RunRequest) (*RunResult, error) {
+		return &RunResult{}, nil
+	}, nil)
+	s.OnLifecycle(func(info JobLifecycleInfo) {
+		if info.Status == "completed" {
+			done <- info
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := s.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer s.Stop()
+
+	select {
+	case info := <-done:
+		if !info.CatchUp {
+			t.Errorf("lifecycle = %+v, want a catch-up completion", info)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("past one-shot was retired without its catch-up run")
+	}
+
+	got, err := store.Load(context.Background(), "once")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Enabled || got.RetiredAt.IsZero() || got.SuccessCnt != 1 {
+		t.Errorf("entry = enabled %v retired %v runs %d, want retired after one run",
+			got.Enabled, got.RetiredAt, got.SuccessCnt)
+	}
+}
@@ -0,0 +1,204 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxChainDepth bounds how many follow-ups a single run may chain.
+const maxChainDepth = 10
+
+// ChainStep is a follow-up run started when the previous step succeeds
+// (OnSuccess) or fails (OnFailure). Content may reference the previous
+// step through these placeholders:
+//
+//	{{output}}      final answer of the previous step
+//	{{error}}       its error message, empty on success
+//	{{status}}      "success" or "failed"
+//	{{agent}}       the agent that ran it
+//	{{session_id}}  the session it ran in
+type ChainStep struct {
+	Agent      string     `json:"agent"`
+	Content    string     `json:"content"`
+	SessionID  string     `json:"session_id,omitempty"`  // empty or "new": a fresh session per run
+	ProjectDir string     `json:"project_dir,omitempty"` // empty: inherit from the previous step
+	OnSuccess  *ChainStep `json:"on_success,omitempty"`
+	OnFailure  *ChainStep `json:"on_failure,omitempty"`
+}
+
+// StepRecord is the outcome of one step of a chained run. Branch is empty
+// for the entry's own step and "on_success" / "on_failure" for follow-ups.
+type StepRecord struct {
+	Agent        string    `json:"agent"`
+	Branch       string    `json:"branch,omitempty"`
+	SessionID    string    `json:"session_id,omitempty"`
+	Status       string    `json:"status"` // "success", "failed"
+	Error        string    `json:"error,omitempty"`
+	StartedAt    time.Time `json:"started_at"`
+	DurationMs   int64     `json:"duration_ms"`
+	Usage        RunUsage  `json:"usage"`
+	Output       string    `json:"output,omitempty"`
+	MessageStart int       `json:"message_start"`
+	MessageEnd   int       `json:"message_end"`
+}
+
+// HasChain reports whether the entry declares any follow-up steps.
+func (e *ScheduleEntry) HasChain() bool {
+	return e.OnSuccess != nil || e.OnFailure != nil
+}
+
+// validateChain checks every follow-up step of the entry.
+func (e *ScheduleEntry) validateChain() error {
+	for branch, step := range map[string]*ChainStep{"on_success": e.OnSuccess, "on_failure": e.OnFailure} {
+		if err := step.validate(branch, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ChainStep) validate(path string, depth int) error {
+	if s == nil {
+		return nil
+	}
+	if depth > maxChainDepth {
+		return fmt.Errorf("%s: chain is deeper than %d steps", path, maxChainDepth)
+	}
+	if strings.TrimPrefix(s.Agent, "@") == "" {
+		return fmt.Errorf("%s: agent is required", path)
+	}
+	if s.Content == "" {
+		return fmt.Errorf("%s: content is required", path)
+	}
+	if err := s.OnSuccess.validate(path+".on_success", depth+1); err != nil {
+		return err
+	}
+	return s.OnFailure.validate(path+".on_failure", depth+1)
+}
+
+// render substitutes the previous step's results into content.
+func render(content string, prev StepRecord) string {
+	return strings.NewReplacer(
+		"{{output}}", prev.Output,
+		"{{error}}", prev.Error,
+		"{{status}}", prev.Status,
+		"{{agent}}", prev.Agent,
+		"{{session_id}}", prev.SessionID,
+	).Replace(content)
+}
+
+// followUpError is the failure of a follow-up step. The entry's own step
+// already ran, so retrying the run would repeat it and its side effects;
+// the scheduler does not retry on a followUpError.
+type followUpError struct {
+	err error
+}
+
+func (e *followUpError) Error() string { return e.err.Error() }
+
+func (e *followUpError) Unwrap() error { return e.err }
+
+// retryable reports whether a failed run may be retried: every failure
+// but that of a follow-up step.
+func retryable(err error) bool {
+	var f *followUpError
+	return !errors.As(err, &f)
+}
+
+// RunChain executes req as the first step and then follows its OnSuccess /
+// OnFailure steps, calling run for each one. The whole chain is reported
+// as a single RunResult: session and message range of the first step, the
+// output of the last step, the summed usage and every step in Steps.
+//
+// The returned error is that of the first failed step. When the first step
+// fails and req.LastAttempt is false, the chain stops there so that the
+// retry re-runs it, and failure branches only fire on the final attempt.
+// A follow-up failure after a successful first step is not retried: the
+// run fails without running the first step again. With req.StepTimeout
+// set every step gets that long of its own, so a slow step does not cut
+// short the ones after it, and a step that times out takes its failure
+// branch.
+func RunChain(ctx context.Context, req RunRequest, run CommandExecutor) (*RunResult, error) {
+	result := &RunResult{}
+	var firstErr error
+
+	step := &ChainStep{
+		Agent: req.Agent, Content: req.Content, SessionID: req.SessionID, ProjectDir: req.ProjectDir,
+		OnSuccess: req.OnSuccess, OnFailure: req.OnFailure,
+	}
+	branch := ""
+	projectDir := req.ProjectDir
+	for depth := 0; step != nil && depth <= maxChainDepth; depth++ {
+		if step.ProjectDir != "" {
+			projectDir = step.ProjectDir
+		}
+		content := step.Content
+		if depth > 0 {
+			content = render(content, result.Steps[len(result.Steps)-1])
+		}
+
+		started := time.Now()
+		stepCtx, cancel := ctx, context.CancelFunc(func() {})
+		if req.StepTimeout > 0 {
+			stepCtx, cancel = context.WithTimeout(ctx, req.StepTimeout)
+		}
+		res, err := run(stepCtx, RunRequest{
+			EntryID:     req.EntryID,
+			RunID:       req.RunID,
+			Attempt:     req.Attempt,
+			LastAttempt: req.LastAttempt,
+			Agent:       strings.TrimPrefix(step.Agent, "@"),
+			SessionID:   step.SessionID,
+			Content:     content,
+			ProjectDir:  projectDir,
+		})
+		if err != nil && ctx.Err() == nil && errors.Is(stepCtx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s: %w", req.StepTimeout, err)
+		}
+		cancel()
+
+		rec := StepRecord{Agent: step.Agent, Branch: branch, StartedAt: started,
+			DurationMs: time.Since(started).Milliseconds(), Status: "success"}
+		if res != nil {
+			rec.SessionID = res.SessionID
+			rec.Output = res.Output
+			rec.Usage = res.Usage
+			rec.MessageStart = res.MessageStart
+			rec.MessageEnd = res.MessageEnd
+			result.Usage.add(res.Usage)
+		}
+		if err != nil {
+			rec.Status = "failed"
+			rec.Error = err.Error()
+		}
+		result.Steps = append(result.Steps, rec)
+		result.Output = rec.Output
+		if depth == 0 {
+			result.SessionID = rec.SessionID
+			result.MessageStart = rec.MessageStart
+			result.MessageEnd = rec.MessageEnd
+		}
+
+		if err == nil {
+			step, branch = step.OnSuccess, "on_success"
+			continue
+		}
+		if firstErr == nil {
+			firstErr = err
+			if depth > 0 {
+				firstErr = &followUpError{fmt.Errorf("step %d (@%s): %w", depth+1, rec.Agent, err)}
+			}
+		}
+		if depth == 0 && !req.LastAttempt {
+			break
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		step, branch = step.OnFailure, "on_failure"
+	}
+	return result, firstErr
+}
@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunChain_FollowsBranches(t *testing.T) {
+	var contents []string
+	run := func(ctx context.Context, req RunRequest) (*RunResult, error) {
+		contents = append(contents, req.Agent+": "+req.Content)
+		if req.Agent == "writer" {
+			return &RunResult{Output: "draft"}, errors.New("publish quota exceeded")
+		}
+		return &RunResult{Output: req.Agent + " done", Usage: RunUsage{TotalTokens: 10}}, nil
+	}
+
+	req := RunRequest{
+		Agent: "researcher", Content: "research", LastAttempt: true,
+		OnSuccess: &ChainStep{
+			Agent: "@writer", Content: "write about {{output}}",
+			OnSuccess: &ChainStep{Agent: "publisher", Content: "publish"},
+			OnFailure: &ChainStep{Agent: "notifier", Content: "{{agent}} failed: {{error}}"},
+		},
+	}
+	result, err := RunChain(context.Background(), req, run)
+	if err == nil {
+		t.Fatal("RunChain should report the failed step")
+	}
+
+	want := []string{
+		"researcher: research",
+		"writer: write about researcher done",
+		"notifier: @writer failed: publish quota exceeded",
+	}
+	if len(contents) != len(want) {
+		t.Fatalf("steps = %q, want %q", contents, want)
+	}
+	for i := range want {
+		if contents[i] != want[i] {
+			t.Errorf("step %d = %q, want %q", i, contents[i], want[i])
+		}
+	}
+	if len(result.Steps) != 3 || result.Steps[2].Branch != "on_failure" {
+		t.Errorf("Steps = %+v, want 3 with a final on_failure step", result.Steps)
+	}
+	if result.Usage.TotalTokens != 20 || result.Output != "notifier done" {
+		t.Errorf("result = %+v, want summed usage and last output", result)
+	}
+}
+
+func TestRunChain_FailureBranchWaitsForLastAttempt(t *testing.T) {
+	calls := 0
+	run := func(ctx context.Context, req RunRequest) (*RunResult, error) {
+		calls++
+		return nil, errors.New("boom")
+	}
+	req := RunRequest{Agent: "a", Content: "c", OnFailure: &ChainStep{Agent: "b", Content: "alert"}}
+
+	if _, err := RunChain(context.Background(), req, run); err == nil || calls != 1 {
+		t.Errorf("calls = %d, err = %v; want the chain to stop before on_failure", calls, err)
+	}
+}
+
+func TestRunChain_TimesEachStep(t *testing.T) {
+	var agents []string
+	run := func(ctx context.Context, req RunRequest) (*RunResult, error) {
+		agents = append(agents, req.Agent)
+		wait := 30 * time.Millisecond
+		if req.Agent == "stuck" {
+			wait = time.Hour
+		}
+		select {
+		case <-time.After(wait):
+			return &RunResult{Output: req.Agent}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// Two steps of 30ms each fit a 50ms step timeout, though not a 50ms
+	// budget for the whole chain.
+	req := RunRequest{Agent: "a", Content: "c", LastAttempt: true, StepTimeout: 50 * time.Millisecond,
+		OnSuccess: &ChainStep{Agent: "b", Content: "c",
+			OnSuccess: &ChainStep{Agent: "stuck", Content: "c",
+				OnFailure: &ChainStep{Agent: "notifier", Content: "{{error}}"}}}}
+	result, err := RunChain(context.Background(), req, run)
+	if err == nil || !strings.Contains(err.Error(), "timed out after 50ms") {
+		t.Errorf("err = %v, want the stuck step to time out", err)
+	}
+	if want := []string{"a", "b", "stuck", "notifier"}; !slices.Equal(agents, want) {
+		t.Errorf("steps = %v, want %v", agents, want)
+	}
+	if len(result.Steps) != 4 || result.Steps[1].Status != "success" || result.Steps[3].Status != "success" {
+		t.Errorf("Steps = %+v", result.Steps)
+	}
+}
+
+func TestScheduleEntry_ValidateChain(t *testing.T) {
+	entry := &ScheduleEntry{ID: "c", CronExpr: "0 0 9 * * *", OnSuccess: &ChainStep{Agent: "writer"}}
+	if err := entry.Validate(); err == nil {
+		t.Error("follow-up step without content should be rejected")
+	}
+}
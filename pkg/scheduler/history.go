@@ -0,0 +1,258 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxRunsPerEntry bounds the history kept for each schedule entry; the
+// oldest records are pruned when a new run is saved.
+const maxRunsPerEntry = 200
+
+// RunUsage is the token consumption of a single run, summed over its attempts.
+type RunUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	CachedTokens     int `json:"cached_tokens"`
+	ReasoningTokens  int `json:"reasoning_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+func (u *RunUsage) add(o RunUsage) {
+	u.PromptTokens += o.PromptTokens
+	u.CompletionTokens += o.CompletionTokens
+	u.CachedTokens += o.CachedTokens
+	u.ReasoningTokens += o.ReasoningTokens
+	u.TotalTokens += o.TotalTokens
+}
+
+// RunRecord is the persisted history of one execution of a schedule entry.
+// MessageStart/MessageEnd delimit the messages the run appended to its
+// session ([start, end) in Session.All() order), so the full transcript can
+// be recovered from the session store.
+type RunRecord struct {
+	RunID        string    `json:"run_id"`
+	EntryID      string    `json:"entry_id"`
+	Agent        string    `json:"agent"`
+	SessionID    string    `json:"session_id,omitempty"`
+	ProjectDir   string    `json:"project_dir,omitempty"`
+	Status       string    `json:"status"` // "running", "retrying", "success", "failed", "skipped"
+	Error        string    `json:"error,omitempty"`
+	Attempts     int       `json:"attempts,omitempty"`
+	CatchUp      bool      `json:"catch_up,omitempty"`
+	ScheduledAt  time.Time `json:"scheduled_at,omitzero"` // the missed fire time of a catch-up run
+	StartedAt    time.Time `json:"started_at"`
+	EndedAt      time.Time `json:"ended_at,omitzero"`
+	DurationMs   int64     `json:"duration_ms"`
+	Usage        RunUsage  `json:"usage"`
+	Output       string    `json:"output,omitempty"`
+	MessageStart int       `json:"message_start"`
+	MessageEnd   int       `json:"message_end"`
+
+	// Steps lists every step of a chained run, in execution order.
+	Steps []StepRecord `json:"steps,omitempty"`
+	// Deliveries holds the outcome of each sink the run was delivered to.
+	Deliveries []SinkResult `json:"deliveries,omitempty"`
+}
+
+// RunRequest describes one execution handed to a CommandExecutor. Retries
+// of a run share its RunID and carry an increasing Attempt (1-based);
+// LastAttempt is set when no retry will follow. OnSuccess and OnFailure
+// are the entry's follow-up steps, run by RunChain.
+type RunRequest struct {
+	EntryID     string
+	RunID       string
+	Attempt     int
+	LastAttempt bool
+	Agent       string
+	SessionID   string
+	Content     string
+	ProjectDir  string
+
+	OnSuccess *ChainStep
+	OnFailure *ChainStep
+	// StepTimeout, when set, limits each step of the run on its own; see
+	// RunChain.
+	StepTimeout time.Duration
+}
+
+// RunResult is what a CommandExecutor reports back about a finished run.
+// SessionID is the session actually used, which differs from the request
+// when the entry asks for a new session per run. Steps is filled in for
+// chained runs.
+type RunResult struct {
+	SessionID    string
+	Output       string
+	Usage        RunUsage
+	MessageStart int
+	MessageEnd   int
+	Steps        []StepRecord
+}
+
+// FileRunStore persists RunRecords as one JSON file per run under
+// <dataDir>/<entryID>/<runID>.json.
+type FileRunStore struct {
+	dataDir string
+	mu      sync.RWMutex
+}
+
+// NewFileRunStore creates a run history store rooted at dataDir.
+func NewFileRunStore(dataDir string) (*FileRunStore, error) {
+	if err := os.MkdirAll(dataDir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create run history dir: %w", err)
+	}
+	return &FileRunStore{dataDir: dataDir}, nil
+}
+
+func (s *FileRunStore) entryDir(entryID string) string {
+	return filepath.Join(s.dataDir, entryID)
+}
+
+// Save writes (or overwrites) a run record and prunes old runs of the same entry.
+func (s *FileRunStore) Save(rec *RunRecord) error {
+	if rec.EntryID == "" || rec.RunID == "" {
+		return fmt.Errorf("run record requires entry_id and run_id")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := s.entryDir(rec.EntryID)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return fmt.Errorf("failed to create run dir: %w", err)
+	}
+
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal run record: %w", err)
+	}
+
+	path := filepath.Join(dir, rec.RunID+".json")
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write run record: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to rename run record: %w", err)
+	}
+
+	s.prune(rec.EntryID)
+	return nil
+}
+
+// List returns the runs of an entry, newest first. limit <= 0 means all.
+func (s *FileRunStore) List(entryID string, limit int) ([]RunRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	runs, err := s.readAll(entryID)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
+
+// Get looks up a single run by ID across all entries.
+func (s *FileRunStore) Get(runID string) (*RunRecord, error) {
+	if !validRunID(runID) {
+		return nil, fmt.Errorf("invalid run ID %q", runID)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matches, err := filepath.Glob(filepath.Join(s.dataDir, "*", runID+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search run records: %w", err)
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("run %q not found", runID)
+	case 1:
+	default:
+		// Runs recorded before IDs were full UUIDs can share an ID.
+		return nil, fmt.Errorf("run ID %q matches %d runs; list the task's history to tell them apart", runID, len(matches))
+	}
+
+	data, err := os.ReadFile(matches[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run record: %w", err)
+	}
+	var rec RunRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal run record: %w", err)
+	}
+	return &rec, nil
+}
+
+// validRunID reports whether id can name a run record file: letters,
+// digits and dashes only, as in the UUIDs runs are given, so that it
+// cannot leave the history directory or act as a glob pattern.
+func validRunID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// DeleteEntry removes the whole history of an entry.
+func (s *FileRunStore) DeleteEntry(entryID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.RemoveAll(s.entryDir(entryID)); err != nil {
+		return fmt.Errorf("failed to delete run history: %w", err)
+	}
+	return nil
+}
+
+// readAll loads every run of an entry sorted newest first. Caller holds mu.
+func (s *FileRunStore) readAll(entryID string) ([]RunRecord, error) {
+	paths, err := filepath.Glob(filepath.Join(s.entryDir(entryID), "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list run records: %w", err)
+	}
+
+	runs := make([]RunRecord, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var rec RunRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			continue
+		}
+		runs = append(runs, rec)
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].StartedAt.After(runs[j].StartedAt)
+	})
+	return runs, nil
+}
+
+// prune drops the oldest runs beyond maxRunsPerEntry. Caller holds mu.
+func (s *FileRunStore) prune(entryID string) {
+	runs, err := s.readAll(entryID)
+	if err != nil || len(runs) <= maxRunsPerEntry {
+		return
+	}
+	for _, rec := range runs[maxRunsPerEntry:] {
+		_ = os.Remove(filepath.Join(s.entryDir(entryID), rec.RunID+".json"))
+	}
+}
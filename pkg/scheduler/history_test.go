@@ -0,0 +1,72 @@
+package scheduler
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestFileRunStore_SaveListGet(t *testing.T) {
+	store, err := NewFileRunStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileRunStore failed: %v", err)
+	}
+
+	base := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		rec := &RunRecord{
+			RunID:     fmt.Sprintf("run-%d", i),
+			EntryID:   "job",
+			Status:    "success",
+			StartedAt: base.Add(time.Duration(i) * time.Hour),
+		}
+		if err := store.Save(rec); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+
+	runs, err := store.List("job", 2)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(runs) != 2 || runs[0].RunID != "run-2" || runs[1].RunID != "run-1" {
+		t.Errorf("List = %+v, want run-2, run-1", runs)
+	}
+
+	rec, err := store.Get("run-0")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if rec.EntryID != "job" {
+		t.Errorf("EntryID = %q, want job", rec.EntryID)
+	}
+
+	if err := store.DeleteEntry("job"); err != nil {
+		t.Fatalf("DeleteEntry failed: %v", err)
+	}
+	if _, err := store.Get("run-0"); err == nil {
+		t.Error("Get should fail after DeleteEntry")
+	}
+}
+
+func TestFileRunStore_GetRejectsBadIDs(t *testing.T) {
+	store, err := NewFileRunStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileRunStore failed: %v", err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if err := store.Save(&RunRecord{RunID: "run-1", EntryID: id, StartedAt: time.Now()}); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+
+	for _, id := range []string{"", "*", "run-?", "../a/run-1", "run/1", "run-[1]"} {
+		if _, err := store.Get(id); err == nil {
+			t.Errorf("Get(%q) should fail", id)
+		}
+	}
+	// Two entries with the same run ID are ambiguous, not the first found.
+	if _, err := store.Get("run-1"); err == nil {
+		t.Error("Get of a shared run ID should fail")
+	}
+}
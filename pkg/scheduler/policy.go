@@ -0,0 +1,140 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+)
+
+// OverlapPolicy decides what happens when an entry fires while a previous
+// run of the same entry is still in progress.
+type OverlapPolicy string
+
+const (
+	// OverlapSkip drops the new run and records it as skipped. It is the
+	// default when Overlap is empty.
+	OverlapSkip OverlapPolicy = "skip"
+	// OverlapQueue holds the new run until the previous one finishes. At
+	// most one run per entry waits; further ticks are skipped.
+	OverlapQueue OverlapPolicy = "queue"
+	// OverlapAllow starts the new run alongside the previous one.
+	OverlapAllow OverlapPolicy = "allow"
+)
+
+// CatchUpPolicy decides what happens on Start to fire times missed while
+// the daemon was not running.
+type CatchUpPolicy string
+
+const (
+	// CatchUpNone drops missed runs. It is the default when CatchUp is empty.
+	CatchUpNone CatchUpPolicy = "none"
+	// CatchUpOnce runs a single catch-up for however many times were missed.
+	CatchUpOnce CatchUpPolicy = "once"
+	// CatchUpAll runs every missed time, oldest first, up to CatchUpMax.
+	CatchUpAll CatchUpPolicy = "all"
+)
+
+const (
+	// DefaultJobTimeout bounds a single attempt when the entry sets no Timeout.
+	DefaultJobTimeout = 5 * time.Minute
+	// DefaultRetryBackoff is the delay before the first retry when the entry
+	// sets no RetryBackoff. Each further retry doubles it.
+	DefaultRetryBackoff = 30 * time.Second
+	// maxRetryBackoff caps the exponential retry delay.
+	maxRetryBackoff = 30 * time.Minute
+	// DefaultMaxConcurrent is the global cap on jobs running at once.
+	DefaultMaxConcurrent = 4
+	// DefaultCatchUpMax caps CatchUpAll when the entry sets no CatchUpMax.
+	DefaultCatchUpMax = 10
+)
+
+// OverlapKind returns the effective overlap policy, defaulting to OverlapSkip.
+func (e *ScheduleEntry) OverlapKind() OverlapPolicy {
+	if e.Overlap == "" {
+		return OverlapSkip
+	}
+	return e.Overlap
+}
+
+// CatchUpKind returns the effective catch-up policy, defaulting to CatchUpNone.
+func (e *ScheduleEntry) CatchUpKind() CatchUpPolicy {
+	if e.CatchUp == "" {
+		return CatchUpNone
+	}
+	return e.CatchUp
+}
+
+// catchUpLimit returns how many missed runs the policy replays.
+func (e *ScheduleEntry) catchUpLimit() int {
+	switch e.CatchUpKind() {
+	case CatchUpOnce:
+		return 1
+	case CatchUpAll:
+		if e.CatchUpMax > 0 {
+			return e.CatchUpMax
+		}
+		return DefaultCatchUpMax
+	default:
+		return 0
+	}
+}
+
+// JobTimeout returns the per-attempt timeout, which a chained run applies
+// to each of its steps, defaulting to DefaultJobTimeout.
+func (e *ScheduleEntry) JobTimeout() time.Duration {
+	if d, err := time.ParseDuration(e.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return DefaultJobTimeout
+}
+
+// RetryDelay returns the wait before retry number attempt (1-based):
+// RetryBackoff doubled for each earlier retry, capped at maxRetryBackoff.
+func (e *ScheduleEntry) RetryDelay(attempt int) time.Duration {
+	delay := DefaultRetryBackoff
+	if d, err := time.ParseDuration(e.RetryBackoff); err == nil && d > 0 {
+		delay = d
+	}
+	for i := 1; i < attempt && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryBackoff)
+}
+
+// validatePolicy checks the timeout, retry, overlap and catch-up settings.
+func (e *ScheduleEntry) validatePolicy() error {
+	if e.Timeout != "" {
+		d, err := time.ParseDuration(e.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout %q: %w", e.Timeout, err)
+		}
+		if d < time.Second {
+			return fmt.Errorf("timeout must be at least 1s")
+		}
+	}
+	if e.MaxRetries < 0 {
+		return fmt.Errorf("max_retries must not be negative")
+	}
+	if e.RetryBackoff != "" {
+		d, err := time.ParseDuration(e.RetryBackoff)
+		if err != nil {
+			return fmt.Errorf("invalid retry_backoff %q: %w", e.RetryBackoff, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("retry_backoff must be positive")
+		}
+	}
+	switch e.OverlapKind() {
+	case OverlapSkip, OverlapQueue, OverlapAllow:
+	default:
+		return fmt.Errorf("unknown overlap policy %q (want skip, queue or allow)", e.Overlap)
+	}
+	switch e.CatchUpKind() {
+	case CatchUpNone, CatchUpOnce, CatchUpAll:
+	default:
+		return fmt.Errorf("unknown catch_up policy %q (want none, once or all)", e.CatchUp)
+	}
+	if e.CatchUpMax < 0 {
+		return fmt.Errorf("catch_up_max must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestScheduleEntry_RetryDelay(t *testing.T) {
+	entry := &ScheduleEntry{RetryBackoff: "10s"}
+	want := []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second}
+	for i, w := range want {
+		if got := entry.RetryDelay(i + 1); got != w {
+			t.Errorf("RetryDelay(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+	if got := entry.RetryDelay(30); got != maxRetryBackoff {
+		t.Errorf("RetryDelay(30) = %v, want cap %v", got, maxRetryBackoff)
+	}
+}
+
+func TestScheduleEntry_ValidatePolicy(t *testing.T) {
+	entry := &ScheduleEntry{ID: "p", CronExpr: "0 0 9 * * *", Overlap: "sometimes"}
+	if err := entry.Validate(); err == nil {
+		t.Error("unknown overlap policy should be rejected")
+	}
+	entry.Overlap = OverlapQueue
+	entry.Timeout = "10ms"
+	if err := entry.Validate(); err == nil {
+		t.Error("sub-second timeout should be rejected")
+	}
+	entry.Timeout = "2m"
+	if err := entry.Validate(); err != nil {
+		t.Errorf("Validate failed: %v", err)
+	}
+}
+
+func newTestScheduler(t *testing.T, exec CommandExecutor) (*Scheduler, *FileSchedulerStore) {
+	t.Helper()
+	store, err := NewFileSchedulerStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileSchedulerStore failed: %v", err)
+	}
+	return NewScheduler(store, exec, nil), store
+}
+
+func TestScheduler_RetriesUntilSuccess(t *testing.T) {
+	calls := 0
+	s, store := newTestScheduler(t, func(ctx context.Context, req RunRequest) (*RunResult, error) {
+		calls++
+		if req.Attempt < 3 {
+			return nil, errors.New("flaky")
+		}
+		return &RunResult{Output: "ok"}, nil
+	})
+
+	var statuses []string
+	s.OnLifecycle(func(info JobLifecycleInfo) { statuses = append(statuses, info.Status) })
+
+	entry := &ScheduleEntry{ID: "retry", Agent: "a", CronExpr: "0 0 9 * * *", Enabled: true,
+		MaxRetries: 2, RetryBackoff: "1ms"}
+	if err := store.Save(context.Background(), entry); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	s.executeJob(entry)
+
+	if calls != 3 {
+		t.Errorf("executor called %d times, want 3", calls)
+	}
+	want := []string{"started", "retrying", "retrying", "completed"}
+	if len(statuses) != len(want) {
+		t.Fatalf("statuses = %v, want %v", statuses, want)
+	}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Errorf("statuses = %v, want %v", statuses, want)
+			break
+		}
+	}
+
+	runs, _ := store.Runs().List("retry", 0)
+	if len(runs) != 1 || runs[0].Status != "success" || runs[0].Attempts != 3 {
+		t.Errorf("runs = %+v, want one success after 3 attempts", runs)
+	}
+}
+
+func TestScheduler_DoesNotRetryFollowUpFailure(t *testing.T) {
+	var agents []string
+	s, store := newTestScheduler(t, func(ctx context.Context, req RunRequest) (*RunResult, error) {
+		return RunChain(ctx, req, func(ctx context.Context, step RunRequest) (*RunResult, error) {
+			agents = append(agents, step.Agent)
+			if step.Agent == "notifier" {
+				return nil, errors.New("webhook down")
+			}
+			return &RunResult{Output: "report"}, nil
+		})
+	})
+
+	entry := &ScheduleEntry{ID: "followup", Agent: "reporter", Content: "report", CronExpr: "0 0 9 * * *",
+		Enabled: true, MaxRetries: 2, RetryBackoff: "1ms",
+		OnSuccess: &ChainStep{Agent: "notifier", Content: "send {{output}}"}}
+	if err := store.Save(context.Background(), entry); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	s.executeJob(entry)
+
+	if len(agents) != 2 {
+		t.Errorf("steps run = %v, want reporter and notifier once", agents)
+	}
+	runs, _ := store.Runs().List("followup", 0)
+	if len(runs) != 1 || runs[0].Status != "failed" || runs[0].Attempts != 1 {
+		t.Errorf("runs = %+v, want one failed run after 1 attempt", runs)
+	}
+}
+
+func TestScheduler_OverlapSkip(t *testing.T) {
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	s, store := newTestScheduler(t, func(ctx context.Context, req RunRequest) (*RunResult, error) {
+		close(started)
+		<-unblock
+		return &RunResult{}, nil
+	})
+
+	var mu sync.Mutex
+	var statuses []string
+	s.OnLifecycle(func(info JobLifecycleInfo) {
+		mu.Lock()
+		statuses = append(statuses, info.Status)
+		mu.Unlock()
+	})
+
+	entry := &ScheduleEntry{ID: "slow", Agent: "a", CronExpr: "0 0 9 * * *", Enabled: true}
+	if err := store.Save(context.Background(), entry); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.executeJob(entry)
+		close(done)
+	}()
+	<-started
+	s.executeJob(entry) // overlaps the first run and must be skipped
+	close(unblock)
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	skipped := 0
+	for _, st := range statuses {
+		if st == "skipped" {
+			skipped++
+		}
+	}
+	if skipped != 1 {
+		t.Errorf("statuses = %v, want exactly one skipped", statuses)
+	}
+}
@@ -2,17 +2,32 @@ package scheduler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/DotNetAge/mindx/pkg/logging"
+	"github.com/fsnotify/fsnotify"
 	"github.com/google/uuid"
 	"github.com/robfig/cron/v3"
 )
 
-type CommandExecutor func(ctx context.Context, agent string, sessionID string, content string, projectDir string) error
+const (
+	// reloadDebounce coalesces bursts of file events (an atomic write is a
+	// create plus a rename) into one reload.
+	reloadDebounce = 500 * time.Millisecond
+	// pollInterval is used only when the schedules dir cannot be watched.
+	pollInterval = 5 * time.Second
+)
+
+// CommandExecutor runs one scheduled execution and reports what it produced.
+// The result may be non-nil even when an error is returned, so partial
+// output and usage of a failed run are still recorded.
+type CommandExecutor func(ctx context.Context, req RunRequest) (*RunResult, error)
 
 // JobLifecycleInfo describes a scheduled job lifecycle event broadcast to clients.
 type JobLifecycleInfo struct {
@@ -20,11 +35,18 @@ type JobLifecycleInfo struct {
 	RunID     string `json:"run_id"`
 	Agent     string `json:"agent"`
 	SessionID string `json:"session_id"`
-	Status    string `json:"status"` // "started", "completed", "failed"
-	Error     string `json:"error,omitempty"`
+	// Status is one of "started", "retrying", "completed", "failed",
+	// "skipped" or "retired".
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Attempt int    `json:"attempt,omitempty"`
+	// CatchUp marks a run replaying a fire time missed while the daemon
+	// was down; ScheduledAt is that missed time.
+	CatchUp     bool      `json:"catch_up,omitempty"`
+	ScheduledAt time.Time `json:"scheduled_at,omitzero"`
 }
 
-// LifecycleCallback is called when a scheduled job starts, completes, or fails.
+// LifecycleCallback is called when a scheduled job changes state.
 type LifecycleCallback func(info JobLifecycleInfo)
 
 type Scheduler struct {
@@ -32,9 +54,24 @@ type Scheduler struct {
 	store       *FileSchedulerStore
 	executor    CommandExecutor
 	entries     map[string]cron.EntryID
+	hashes      map[string]string // ConfigHash of each registered entry
 	mu          sync.RWMutex
+	syncMu      sync.Mutex // serializes reloads from the watcher and the store hook
 	logger      logging.Logger
 	lifecycleCb LifecycleCallback
+
+	// slots caps the number of attempts running at once across all entries.
+	slots chan struct{}
+	// running holds a one-slot lock per entry for the skip and queue
+	// overlap policies; queued marks entries with a run waiting on it.
+	running  map[string]chan struct{}
+	queued   map[string]bool
+	runMu    sync.Mutex
+	done     chan struct{}
+	stopOnce sync.Once
+
+	sinks  map[SinkType]SinkFunc
+	sinkMu sync.RWMutex
 }
 
 func NewScheduler(store *FileSchedulerStore, executor CommandExecutor, logger logging.Logger) *Scheduler {
@@ -45,82 +82,232 @@ func NewScheduler(store *FileSchedulerStore, executor CommandExecutor, logger lo
 		cron.WithSeconds(),
 		cron.WithLogger(cron.VerbosePrintfLogger(log.New(log.Writer(), "[scheduler] ", log.LstdFlags))),
 	)
-	return &Scheduler{
+	s := &Scheduler{
 		cron:     c,
 		store:    store,
 		executor: executor,
 		entries:  make(map[string]cron.EntryID),
+		hashes:   make(map[string]string),
 		logger:   logger,
+		slots:    make(chan struct{}, DefaultMaxConcurrent),
+		running:  make(map[string]chan struct{}),
+		queued:   make(map[string]bool),
+		done:     make(chan struct{}),
+		sinks: map[SinkType]SinkFunc{
+			SinkFile:    deliverFile,
+			SinkWebhook: deliverWebhook,
+		},
+	}
+	store.OnChange(s.applyChange)
+	return s
+}
+
+// RegisterSink installs the delivery function for a sink type, replacing
+// any previous one. File and webhook sinks are built in; the daemon
+// registers the memory and KV sinks, which need its stores.
+func (s *Scheduler) RegisterSink(t SinkType, fn SinkFunc) {
+	s.sinkMu.Lock()
+	defer s.sinkMu.Unlock()
+	s.sinks[t] = fn
+}
+
+// SetMaxConcurrent sets the global cap on jobs running at once. It must be
+// called before Start; n <= 0 restores DefaultMaxConcurrent.
+func (s *Scheduler) SetMaxConcurrent(n int) {
+	if n <= 0 {
+		n = DefaultMaxConcurrent
 	}
+	s.slots = make(chan struct{}, n)
 }
 
-// OnLifecycle sets a callback that fires when a scheduled job starts, completes, or fails.
+// OnLifecycle sets a callback that fires when a scheduled job changes state.
 func (s *Scheduler) OnLifecycle(cb LifecycleCallback) {
 	s.lifecycleCb = cb
 }
 
 func (s *Scheduler) Start(ctx context.Context) error {
+	missed := s.planCatchUp()
 	if err := s.reloadAll(); err != nil {
 		return err
 	}
 
 	s.cron.Start()
 	go s.watchLoop(ctx)
-	s.logger.Info("scheduler started", "jobs", len(s.entries))
+	s.catchUp(missed)
+	s.mu.RLock()
+	jobs := len(s.entries)
+	s.mu.RUnlock()
+	s.logger.Info("scheduler started", "jobs", jobs)
 	return nil
 }
 
 func (s *Scheduler) Stop() {
+	s.stopOnce.Do(func() { close(s.done) })
 	s.cron.Stop()
 	s.logger.Info("scheduler stopped")
 }
 
+// reloadAll syncs every stored entry with the registered cron jobs and
+// drops jobs whose entry file is gone. Per-entry failures are logged; only
+// a failure to read the store is returned.
 func (s *Scheduler) reloadAll() error {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
 	entries, err := s.store.List(context.Background())
 	if err != nil {
 		return fmt.Errorf("failed to load schedules from store: %w", err)
 	}
 
 	fileIDs := make(map[string]bool)
-	for _, entry := range entries {
+	for i := range entries {
+		entry := &entries[i]
 		fileIDs[entry.ID] = true
-		if !entry.Enabled {
-			s.removeJob(entry.ID)
-			continue
-		}
-		if err := s.addJob(&entry); err != nil {
-			s.logger.Warn("failed to add schedule job", "id", entry.ID, "error", err)
+		if err := s.syncEntry(entry); err != nil {
+			s.logger.Warn("failed to apply schedule job", "id", entry.ID, "error", err)
 		}
 	}
 
-	s.mu.Lock()
+	var stale []string
+	s.mu.RLock()
 	for id := range s.entries {
 		if !fileIDs[id] {
-			s.removeJob(id)
+			stale = append(stale, id)
 		}
 	}
-	s.mu.Unlock()
+	s.mu.RUnlock()
+	for _, id := range stale {
+		s.removeJob(id)
+	}
 	return nil
 }
 
+// applyChange syncs a single entry after it was saved or deleted through
+// the store. It is installed as the store's change hook, so its error
+// reaches whoever made the edit.
+func (s *Scheduler) applyChange(id string) error {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	entry, err := s.store.Load(context.Background(), id)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			s.removeJob(id)
+			return nil
+		}
+		return err
+	}
+	return s.syncEntry(entry)
+}
+
+// syncEntry brings the cron registration of one entry in line with its
+// stored definition: new entries are added, entries whose ConfigHash
+// changed are re-registered, and disabled, invalid or exhausted ones are
+// removed.
+// Caller holds syncMu.
+func (s *Scheduler) syncEntry(entry *ScheduleEntry) error {
+	if !entry.Enabled {
+		s.removeJob(entry.ID)
+		return nil
+	}
+	// An invalid definition would otherwise look exhausted and be retired.
+	if err := entry.Validate(); err != nil {
+		s.removeJob(entry.ID)
+		return err
+	}
+	if entry.Exhausted(time.Now()) {
+		s.retireJob(entry)
+		return nil
+	}
+
+	hash := entry.ConfigHash()
+	s.mu.RLock()
+	_, exists := s.entries[entry.ID]
+	unchanged := exists && s.hashes[entry.ID] == hash
+	s.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+	if exists {
+		s.logger.Info("schedule job changed, re-registering", "id", entry.ID)
+		s.removeJob(entry.ID)
+	}
+	return s.addJob(entry, hash)
+}
+
+// watchLoop reloads the schedules when their directory changes, coalescing
+// bursts of events within reloadDebounce. If the directory cannot be
+// watched it falls back to polling.
 func (s *Scheduler) watchLoop(ctx context.Context) {
-	ticker := time.NewTicker(5 * time.Second)
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		err = watcher.Add(s.store.Dir())
+	}
+	if err != nil {
+		s.logger.Warn("scheduler: cannot watch schedules dir, polling instead", "dir", s.store.Dir(), "error", err)
+		if watcher != nil {
+			_ = watcher.Close()
+		}
+		s.pollLoop(ctx)
+		return
+	}
+	defer func() { _ = watcher.Close() }()
+
+	ticker := time.NewTicker(reloadDebounce)
 	defer ticker.Stop()
+	pending := false
 
 	for {
 		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Ext(event.Name) == ".json" && event.Op&fsnotify.Chmod != event.Op {
+				pending = true
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			s.logger.Warn("scheduler: watch error", "error", err)
 		case <-ticker.C:
+			if !pending {
+				continue
+			}
+			pending = false
 			if err := s.reloadAll(); err != nil {
 				s.logger.Warn("scheduler reload failed", "error", err)
 			}
 		case <-ctx.Done():
 			s.logger.Info("scheduler context cancelled, stopping watch loop")
 			return
+		case <-s.done:
+			return
 		}
 	}
 }
 
-func (s *Scheduler) addJob(entry *ScheduleEntry) error {
+func (s *Scheduler) pollLoop(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.reloadAll(); err != nil {
+				s.logger.Warn("scheduler reload failed", "error", err)
+			}
+		case <-ctx.Done():
+			s.logger.Info("scheduler context cancelled, stopping watch loop")
+			return
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *Scheduler) addJob(entry *ScheduleEntry, hash string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -128,19 +315,40 @@ func (s *Scheduler) addJob(entry *ScheduleEntry) error {
 		return nil
 	}
 
-	e := *entry
-	id, err := s.cron.AddFunc(e.CronExpr, func() {
-		s.executeJob(&e)
-	})
+	sched, err := entry.Schedule()
 	if err != nil {
-		return fmt.Errorf("failed to add cron job: %w", err)
+		return fmt.Errorf("failed to build schedule: %w", err)
 	}
 
+	e := *entry
+	id := s.cron.Schedule(sched, cron.FuncJob(func() {
+		s.executeJob(&e)
+	}))
+
 	s.entries[entry.ID] = id
-	s.logger.Info("added schedule job", "id", entry.ID, "agent", entry.Agent, "cron", entry.CronExpr)
+	s.hashes[entry.ID] = hash
+	s.logger.Info("added schedule job", "id", entry.ID, "agent", entry.Agent,
+		"trigger", entry.TriggerKind(), "cron", entry.CronExpr, "next_run", s.cron.Entry(id).Next)
 	return nil
 }
 
+// retireJob disables an entry whose trigger has no fire times left and
+// unregisters it from cron.
+func (s *Scheduler) retireJob(entry *ScheduleEntry) {
+	if err := s.store.Retire(entry.ID); err != nil {
+		s.logger.Warn("failed to retire schedule job", "id", entry.ID, "error", err)
+		return
+	}
+	s.removeJob(entry.ID)
+	s.logger.Info("retired exhausted schedule job", "id", entry.ID, "runs", entry.Runs())
+	if s.lifecycleCb != nil {
+		s.lifecycleCb(JobLifecycleInfo{
+			EntryID: entry.ID, Agent: entry.Agent,
+			SessionID: entry.SessionID, Status: "retired",
+		})
+	}
+}
+
 func (s *Scheduler) removeJob(id string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -152,44 +360,277 @@ func (s *Scheduler) removeJob(id string) {
 
 	s.cron.Remove(entryID)
 	delete(s.entries, id)
+	delete(s.hashes, id)
 	s.logger.Info("removed schedule job", "id", id)
 }
 
 func (s *Scheduler) executeJob(entry *ScheduleEntry) {
-	runID := uuid.New().String()[:8]
-	s.logger.Info("executing schedule job", "id", entry.ID, "agent", entry.Agent, "run_id", runID)
+	s.runJob(entry, time.Time{})
+}
 
-	if s.lifecycleCb != nil {
-		s.lifecycleCb(JobLifecycleInfo{
-			EntryID: entry.ID, RunID: runID, Agent: entry.Agent,
-			SessionID: entry.SessionID, Status: "started",
-		})
+// runJob performs one run of entry. A non-zero missedAt marks it as a
+// catch-up for that fire time.
+func (s *Scheduler) runJob(entry *ScheduleEntry, missedAt time.Time) {
+	runID := uuid.New().String()
+	rec := &RunRecord{
+		RunID:       runID,
+		EntryID:     entry.ID,
+		Agent:       entry.Agent,
+		SessionID:   entry.SessionID,
+		ProjectDir:  entry.ProjectDir,
+		CatchUp:     !missedAt.IsZero(),
+		ScheduledAt: missedAt,
+		StartedAt:   time.Now(),
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
+	release, ok := s.admit(entry)
+	if !ok {
+		s.skipJob(entry, rec)
+		return
+	}
+	defer release()
+
+	s.logger.Info("executing schedule job", "id", entry.ID, "agent", entry.Agent, "run_id", runID,
+		"catch_up", rec.CatchUp)
+	s.notify(entry, rec, "started", "")
+
+	rec.Status = "running"
+	s.saveRun(rec)
+
+	var execErr error
+	for attempt := 1; ; attempt++ {
+		rec.Attempts = attempt
+		execErr = s.runAttempt(entry, rec, attempt)
+		if execErr == nil || attempt > entry.MaxRetries || !retryable(execErr) {
+			break
+		}
+
+		delay := entry.RetryDelay(attempt)
+		s.logger.Warn("schedule job attempt failed, retrying", "id", entry.ID, "run_id", runID,
+			"attempt", attempt, "delay", delay, "error", execErr)
+		rec.Status = "retrying"
+		rec.Error = execErr.Error()
+		s.saveRun(rec)
+		s.notify(entry, rec, "retrying", execErr.Error())
+
+		select {
+		case <-time.After(delay):
+		case <-s.done:
+			execErr = fmt.Errorf("scheduler stopped before retry: %w", execErr)
+		}
+		if s.stopped() {
+			break
+		}
+	}
+
+	rec.EndedAt = time.Now()
+	rec.DurationMs = rec.EndedAt.Sub(rec.StartedAt).Milliseconds()
+	if execErr != nil {
+		rec.Status = "failed"
+		rec.Error = execErr.Error()
+	} else {
+		rec.Status = "success"
+		rec.Error = ""
+	}
+	rec.Deliveries = s.deliver(entry, rec)
+	s.saveRun(rec)
 
-	execErr := s.executor(ctx, entry.Agent, entry.SessionID, entry.Content, entry.ProjectDir)
 	if storeErr := s.store.UpdateLastRun(entry.ID, runID, execErr); storeErr != nil {
 		s.logger.Warn("failed to update last run", "id", entry.ID, "error", storeErr)
 	}
 
 	if execErr != nil {
-		s.logger.Error("schedule job failed", execErr, "id", entry.ID, "run_id", runID)
-		if s.lifecycleCb != nil {
-			s.lifecycleCb(JobLifecycleInfo{
-				EntryID: entry.ID, RunID: runID, Agent: entry.Agent,
-				Status: "failed", Error: execErr.Error(),
-			})
-		}
+		s.logger.Error("schedule job failed", execErr, "id", entry.ID, "run_id", runID, "attempts", rec.Attempts)
+		s.notify(entry, rec, "failed", execErr.Error())
 	} else {
-		s.logger.Info("schedule job completed", "id", entry.ID, "run_id", runID)
-		if s.lifecycleCb != nil {
-			s.lifecycleCb(JobLifecycleInfo{
-				EntryID: entry.ID, RunID: runID, Agent: entry.Agent,
-				Status: "completed",
-			})
+		s.logger.Info("schedule job completed", "id", entry.ID, "run_id", runID, "attempts", rec.Attempts)
+		s.notify(entry, rec, "completed", "")
+	}
+
+	// Reload to pick up the run counter just written by UpdateLastRun.
+	if updated, err := s.store.Load(context.Background(), entry.ID); err == nil && updated.Exhausted(time.Now()) {
+		s.retireJob(updated)
+	}
+}
+
+// deliver hands a finished run to each of the entry's sinks whose When
+// matches the run's status, and reports every sink's outcome.
+func (s *Scheduler) deliver(entry *ScheduleEntry, rec *RunRecord) []SinkResult {
+	if len(entry.Sinks) == 0 {
+		return nil
+	}
+	d := Delivery{
+		EntryID: entry.ID, RunID: rec.RunID, Agent: entry.Agent,
+		SessionID: rec.SessionID, ProjectDir: rec.ProjectDir,
+		Status: rec.Status, Error: rec.Error, Output: rec.Output,
+		StartedAt: rec.StartedAt, EndedAt: rec.EndedAt,
+	}
+
+	var results []SinkResult
+	for _, sink := range entry.Sinks {
+		if !sink.matches(rec.Status) {
+			continue
+		}
+		res := SinkResult{Type: sink.Type, Target: d.Expand(sink.target()), Status: "delivered"}
+
+		s.sinkMu.RLock()
+		fn := s.sinks[sink.Type]
+		s.sinkMu.RUnlock()
+
+		var err error
+		if fn == nil {
+			err = fmt.Errorf("sink %q is not available", sink.Type)
+		} else {
+			ctx, cancel := context.WithTimeout(context.Background(), sinkTimeout)
+			err = fn(ctx, sink, d)
+			cancel()
+		}
+		if err != nil {
+			res.Status = "failed"
+			res.Error = err.Error()
+			s.logger.Warn("schedule output delivery failed", "id", entry.ID, "run_id", rec.RunID,
+				"sink", sink.Type, "target", res.Target, "error", err)
 		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// runAttempt performs one attempt of a run under the entry's timeout,
+// holding a global concurrency slot for its duration, and merges what the
+// executor reported into rec.
+func (s *Scheduler) runAttempt(entry *ScheduleEntry, rec *RunRecord, attempt int) error {
+	select {
+	case s.slots <- struct{}{}:
+	case <-s.done:
+		return fmt.Errorf("scheduler stopped")
+	}
+	defer func() { <-s.slots }()
+
+	// The timeout applies to each step of a chain (see RunChain); the
+	// attempt as a whole is only bounded by the longest chain allowed.
+	timeout := entry.JobTimeout()
+	if entry.HasChain() {
+		timeout *= maxChainDepth + 1
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	result, err := s.executor(ctx, RunRequest{
+		EntryID:     entry.ID,
+		RunID:       rec.RunID,
+		Attempt:     attempt,
+		LastAttempt: attempt > entry.MaxRetries,
+		Agent:       entry.Agent,
+		SessionID:   rec.SessionID,
+		Content:     entry.Content,
+		ProjectDir:  entry.ProjectDir,
+		OnSuccess:   entry.OnSuccess,
+		OnFailure:   entry.OnFailure,
+		StepTimeout: entry.JobTimeout(),
+	})
+	if result != nil {
+		if result.SessionID != "" {
+			rec.SessionID = result.SessionID
+		}
+		if attempt == 1 {
+			rec.MessageStart = result.MessageStart
+		}
+		rec.MessageEnd = result.MessageEnd
+		rec.Output = result.Output
+		rec.Usage.add(result.Usage)
+		rec.Steps = result.Steps
+	}
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s: %w", timeout, err)
+	}
+	return err
+}
+
+// admit applies the entry's overlap policy. It returns a release func and
+// true when the run may proceed, or false when it must be skipped. For
+// OverlapQueue it blocks until the previous run finishes.
+func (s *Scheduler) admit(entry *ScheduleEntry) (func(), bool) {
+	policy := entry.OverlapKind()
+	if policy == OverlapAllow {
+		return func() {}, true
+	}
+
+	s.runMu.Lock()
+	lock, ok := s.running[entry.ID]
+	if !ok {
+		lock = make(chan struct{}, 1)
+		s.running[entry.ID] = lock
+	}
+	s.runMu.Unlock()
+	release := func() { <-lock }
+
+	select {
+	case lock <- struct{}{}:
+		return release, true
+	default:
+	}
+	if policy == OverlapSkip {
+		return nil, false
+	}
+
+	s.runMu.Lock()
+	if s.queued[entry.ID] {
+		s.runMu.Unlock()
+		return nil, false
+	}
+	s.queued[entry.ID] = true
+	s.runMu.Unlock()
+	defer func() {
+		s.runMu.Lock()
+		delete(s.queued, entry.ID)
+		s.runMu.Unlock()
+	}()
+
+	select {
+	case lock <- struct{}{}:
+		return release, true
+	case <-s.done:
+		return nil, false
+	}
+}
+
+// skipJob records a run dropped by the overlap policy.
+func (s *Scheduler) skipJob(entry *ScheduleEntry, rec *RunRecord) {
+	reason := "previous run still in progress"
+	s.logger.Info("skipping schedule job", "id", entry.ID, "run_id", rec.RunID,
+		"overlap", entry.OverlapKind(), "reason", reason)
+	rec.Status = "skipped"
+	rec.Error = reason
+	rec.EndedAt = rec.StartedAt
+	s.saveRun(rec)
+	s.notify(entry, rec, "skipped", reason)
+}
+
+// notify fires the lifecycle callback, if any, for a run of entry.
+func (s *Scheduler) notify(entry *ScheduleEntry, rec *RunRecord, status, errMsg string) {
+	if s.lifecycleCb == nil {
+		return
+	}
+	s.lifecycleCb(JobLifecycleInfo{
+		EntryID: entry.ID, RunID: rec.RunID, Agent: entry.Agent,
+		SessionID: rec.SessionID, Status: status, Error: errMsg,
+		Attempt: rec.Attempts, CatchUp: rec.CatchUp, ScheduledAt: rec.ScheduledAt,
+	})
+}
+
+func (s *Scheduler) stopped() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Scheduler) saveRun(rec *RunRecord) {
+	if err := s.store.Runs().Save(rec); err != nil {
+		s.logger.Warn("failed to save run record", "id", rec.EntryID, "run_id", rec.RunID, "error", err)
 	}
 }
 
@@ -0,0 +1,51 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestScheduler_AppliesEdits(t *testing.T) {
+	ctx := context.Background()
+	s, store := newTestScheduler(t, func(ctx context.Context, req RunRequest) (*RunResult, error) {
+		return &RunResult{}, nil
+	})
+
+	entry := &ScheduleEntry{ID: "edit", Agent: "a", Content: "c", CronExpr: "0 0 9 * * *", Enabled: true}
+	if err := store.Save(ctx, entry); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	first := s.entries["edit"]
+
+	// Recording a run must not re-register the job.
+	if err := store.UpdateLastRun("edit", "r1", nil); err != nil {
+		t.Fatalf("UpdateLastRun failed: %v", err)
+	}
+	if err := s.reloadAll(); err != nil {
+		t.Fatalf("reloadAll failed: %v", err)
+	}
+	if s.entries["edit"] != first {
+		t.Error("job re-registered after a run was recorded")
+	}
+
+	entry.CronExpr = "0 30 18 * * *"
+	if err := store.Save(ctx, entry); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if s.entries["edit"] == first {
+		t.Error("job not re-registered after its cron expression changed")
+	}
+
+	entry.CronExpr = "not a cron"
+	if err := store.Save(ctx, entry); !errors.Is(err, ErrNotApplied) {
+		t.Errorf("Save(invalid) error = %v, want ErrNotApplied", err)
+	}
+
+	if err := store.Delete(ctx, "edit"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, ok := s.entries["edit"]; ok {
+		t.Error("job still registered after delete")
+	}
+}
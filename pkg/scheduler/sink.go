@@ -0,0 +1,200 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SinkType names a delivery target for the output of a run.
+type SinkType string
+
+const (
+	// SinkFile writes the output to Path, or appends to it when Append is set.
+	SinkFile SinkType = "file"
+	// SinkWebhook POSTs the Delivery as JSON to URL.
+	SinkWebhook SinkType = "webhook"
+	// SinkMemory stores the output as a long-term memory chunk.
+	SinkMemory SinkType = "memory"
+	// SinkKV appends the output to the list stored under Key in the KV store.
+	SinkKV SinkType = "kv"
+)
+
+// sinkTimeout bounds a single delivery.
+const sinkTimeout = 30 * time.Second
+
+// Sink is one delivery target of a schedule entry. Path and Key accept the
+// placeholders {{date}}, {{entry_id}} and {{run_id}}.
+type Sink struct {
+	Type SinkType `json:"type"`
+	// When selects which runs are delivered: "success" (default),
+	// "failure" or "always".
+	When string `json:"when,omitempty"`
+
+	Path    string            `json:"path,omitempty"`    // file
+	Append  bool              `json:"append,omitempty"`  // file
+	URL     string            `json:"url,omitempty"`     // webhook
+	Headers map[string]string `json:"headers,omitempty"` // webhook
+	Key     string            `json:"key,omitempty"`     // kv
+	Tags    []string          `json:"tags,omitempty"`    // memory
+}
+
+// Delivery is the payload handed to a sink: a finished run and its output.
+type Delivery struct {
+	EntryID    string    `json:"entry_id"`
+	RunID      string    `json:"run_id"`
+	Agent      string    `json:"agent"`
+	SessionID  string    `json:"session_id,omitempty"`
+	ProjectDir string    `json:"project_dir,omitempty"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	Output     string    `json:"output"`
+	StartedAt  time.Time `json:"started_at"`
+	EndedAt    time.Time `json:"ended_at"`
+}
+
+// SinkResult records how one sink handled a run.
+type SinkResult struct {
+	Type   SinkType `json:"type"`
+	Target string   `json:"target,omitempty"`
+	Status string   `json:"status"` // "delivered", "failed"
+	Error  string   `json:"error,omitempty"`
+}
+
+// SinkFunc delivers a run to one sink.
+type SinkFunc func(ctx context.Context, sink Sink, d Delivery) error
+
+// target describes where the sink delivers, for run records and logs.
+func (k Sink) target() string {
+	switch k.Type {
+	case SinkFile:
+		return k.Path
+	case SinkWebhook:
+		return k.URL
+	case SinkKV:
+		return k.Key
+	default:
+		return ""
+	}
+}
+
+// matches reports whether a run with the given status should be delivered.
+func (k Sink) matches(status string) bool {
+	switch k.When {
+	case "always":
+		return true
+	case "failure":
+		return status == "failed"
+	default:
+		return status == "success"
+	}
+}
+
+func (k Sink) validate() error {
+	switch k.When {
+	case "", "success", "failure", "always":
+	default:
+		return fmt.Errorf("sink %s: unknown when %q (want success, failure or always)", k.Type, k.When)
+	}
+	switch k.Type {
+	case SinkFile:
+		if k.Path == "" {
+			return fmt.Errorf("file sink requires path")
+		}
+	case SinkWebhook:
+		u, err := url.Parse(k.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook sink requires an http(s) url")
+		}
+	case SinkKV:
+		if k.Key == "" {
+			return fmt.Errorf("kv sink requires key")
+		}
+	case SinkMemory:
+	default:
+		return fmt.Errorf("unknown sink type %q (want file, webhook, memory or kv)", k.Type)
+	}
+	return nil
+}
+
+// Expand substitutes the delivery placeholders in s.
+func (d Delivery) Expand(s string) string {
+	return strings.NewReplacer(
+		"{{date}}", d.StartedAt.Format("2006-01-02"),
+		"{{entry_id}}", d.EntryID,
+		"{{run_id}}", d.RunID,
+	).Replace(s)
+}
+
+// deliverFile writes or appends the output to the sink's path.
+func deliverFile(_ context.Context, sink Sink, d Delivery) error {
+	path := d.Expand(sink.Path)
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("failed to create sink dir: %w", err)
+	}
+
+	if !sink.Append {
+		tmpPath := path + ".tmp"
+		if err := os.WriteFile(tmpPath, []byte(d.Output), 0600); err != nil {
+			return fmt.Errorf("failed to write sink file: %w", err)
+		}
+		if err := os.Rename(tmpPath, path); err != nil {
+			_ = os.Remove(tmpPath)
+			return fmt.Errorf("failed to rename sink file: %w", err)
+		}
+		return nil
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open sink file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	header := fmt.Sprintf("## %s @%s (%s, run %s)\n\n", d.EndedAt.Format("2006-01-02 15:04:05"), d.Agent, d.Status, d.RunID)
+	if _, err := f.WriteString(header + d.Output + "\n\n"); err != nil {
+		return fmt.Errorf("failed to append to sink file: %w", err)
+	}
+	return nil
+}
+
+var webhookClient = &http.Client{Timeout: sinkTimeout}
+
+// deliverWebhook POSTs the delivery as JSON; any non-2xx response fails.
+func deliverWebhook(ctx context.Context, sink Sink, d Delivery) error {
+	body, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("failed to marshal delivery: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sink.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range sink.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScheduler_DeliversToSinks(t *testing.T) {
+	var got Delivery
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	outDir := t.TempDir()
+	s, store := newTestScheduler(t, func(ctx context.Context, req RunRequest) (*RunResult, error) {
+		return &RunResult{Output: "daily report"}, nil
+	})
+
+	entry := &ScheduleEntry{ID: "report", Agent: "a", Content: "c", CronExpr: "0 0 9 * * *", Enabled: true,
+		Sinks: []Sink{
+			{Type: SinkFile, Path: filepath.Join(outDir, "{{entry_id}}.md"), Append: true},
+			{Type: SinkWebhook, URL: srv.URL},
+			{Type: SinkKV, Key: "reports"}, // not registered in this test
+			{Type: SinkWebhook, URL: srv.URL, When: "failure"},
+		}}
+	if err := store.Save(context.Background(), entry); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	s.executeJob(entry)
+
+	runs, _ := store.Runs().List("report", 1)
+	if len(runs) != 1 {
+		t.Fatalf("expected one run, got %d", len(runs))
+	}
+	deliveries := runs[0].Deliveries
+	if len(deliveries) != 3 {
+		t.Fatalf("deliveries = %+v, want 3 (failure-only sink skipped)", deliveries)
+	}
+	for i, want := range []string{"delivered", "delivered", "failed"} {
+		if deliveries[i].Status != want {
+			t.Errorf("delivery %d (%s) status = %q, want %q", i, deliveries[i].Type, deliveries[i].Status, want)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(outDir, "report.md"))
+	if err != nil || !strings.Contains(string(data), "daily report") {
+		t.Errorf("file sink content = %q, err = %v", data, err)
+	}
+	if got.Output != "daily report" || got.Status != "success" {
+		t.Errorf("webhook payload = %+v", got)
+	}
+}
+
+func TestSink_Validate(t *testing.T) {
+	for _, sink := range []Sink{
+		{Type: SinkFile},
+		{Type: SinkWebhook, URL: "ftp://example.com"},
+		{Type: SinkKV},
+		{Type: "email"},
+		{Type: SinkMemory, When: "sometimes"},
+	} {
+		if err := sink.validate(); err == nil {
+			t.Errorf("validate(%+v) should fail", sink)
+		}
+	}
+}
@@ -2,7 +2,10 @@ package scheduler
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -12,13 +15,41 @@ import (
 )
 
 type ScheduleEntry struct {
-	ID         string    `json:"id"`
-	Agent      string    `json:"agent"`
-	SessionID  string    `json:"session_id,omitempty"`
-	ProjectDir string    `json:"project_dir,omitempty"`
-	Content    string    `json:"content"`
-	CronExpr   string    `json:"cron_expr"`
-	Enabled    bool      `json:"enabled"`
+	ID         string `json:"id"`
+	Agent      string `json:"agent"`
+	SessionID  string `json:"session_id,omitempty"`
+	ProjectDir string `json:"project_dir,omitempty"`
+	Content    string `json:"content"`
+	CronExpr   string `json:"cron_expr"`
+	Enabled    bool   `json:"enabled"`
+
+	// Trigger selects the schedule type; empty means TriggerCron.
+	Trigger  TriggerType `json:"trigger,omitempty"`
+	RunAt    time.Time   `json:"run_at,omitzero"`    // TriggerAt: the single fire time
+	Every    string      `json:"every,omitempty"`    // TriggerEvery: Go duration, e.g. "90m"
+	Timezone string      `json:"timezone,omitempty"` // IANA name; empty means daemon local time
+	StartAt  time.Time   `json:"start_at,omitzero"`  // no fire times before this instant
+	EndAt    time.Time   `json:"end_at,omitzero"`    // no fire times after this instant
+	MaxRuns  int         `json:"max_runs,omitempty"` // retire after this many runs; 0 = unlimited
+
+	// Execution policy; see policy.go for defaults.
+	Timeout      string        `json:"timeout,omitempty"`       // per-attempt limit, per step for a chain; Go duration; default 5m
+	MaxRetries   int           `json:"max_retries,omitempty"`   // extra attempts after a failure
+	RetryBackoff string        `json:"retry_backoff,omitempty"` // delay before the first retry, doubled each time; default 30s
+	Overlap      OverlapPolicy `json:"overlap,omitempty"`       // skip (default), queue or allow
+	CatchUp      CatchUpPolicy `json:"catch_up,omitempty"`      // none (default), once or all
+	CatchUpMax   int           `json:"catch_up_max,omitempty"`  // cap for catch_up=all; default 10
+
+	// Follow-up steps run after this entry's own step; see ChainStep.
+	OnSuccess *ChainStep `json:"on_success,omitempty"`
+	OnFailure *ChainStep `json:"on_failure,omitempty"`
+
+	// Sinks receive the output of every finished run; see Sink.
+	Sinks []Sink `json:"sinks,omitempty"`
+
+	// RetiredAt is set when the entry exhausted its trigger and was disabled.
+	RetiredAt time.Time `json:"retired_at,omitzero"`
+
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
 	LastRunAt  time.Time `json:"last_run_at,omitempty"`
@@ -29,16 +60,74 @@ type ScheduleEntry struct {
 	FailureCnt int       `json:"failure_count"`
 }
 
+// ConfigHash fingerprints the fields that define what an entry runs and
+// when. Run bookkeeping (counters, last-run fields, timestamps) is left
+// out, so recording a run does not look like an edit.
+func (e *ScheduleEntry) ConfigHash() string {
+	c := *e
+	c.CreatedAt, c.UpdatedAt, c.LastRunAt, c.RetiredAt = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+	c.LastRunID, c.LastStatus, c.LastError = "", "", ""
+	c.SuccessCnt, c.FailureCnt = 0, 0
+	data, _ := json.Marshal(&c)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ErrNotApplied is returned by Save and Delete when the change was written
+// but the change hook (the running scheduler) rejected it.
+var ErrNotApplied = errors.New("schedule saved but not applied")
+
+// ChangeHook is called after an entry is saved or deleted through the
+// store. Its error is reported to the caller that made the edit.
+type ChangeHook func(id string) error
+
 type FileSchedulerStore struct {
-	dataDir string
-	mu      sync.RWMutex
+	dataDir  string
+	runs     *FileRunStore
+	mu       sync.RWMutex
+	onChange ChangeHook
 }
 
 func NewFileSchedulerStore(dataDir string) (*FileSchedulerStore, error) {
 	if err := os.MkdirAll(dataDir, 0750); err != nil {
 		return nil, fmt.Errorf("failed to create scheduler data dir: %w", err)
 	}
-	return &FileSchedulerStore{dataDir: dataDir}, nil
+	runs, err := NewFileRunStore(filepath.Join(dataDir, "runs"))
+	if err != nil {
+		return nil, err
+	}
+	return &FileSchedulerStore{dataDir: dataDir, runs: runs}, nil
+}
+
+// Dir returns the directory holding the schedule entry files.
+func (s *FileSchedulerStore) Dir() string {
+	return s.dataDir
+}
+
+// OnChange sets the hook run after each successful Save or Delete.
+func (s *FileSchedulerStore) OnChange(hook ChangeHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onChange = hook
+}
+
+// changed runs the change hook for id, if one is set.
+func (s *FileSchedulerStore) changed(id string) error {
+	s.mu.RLock()
+	hook := s.onChange
+	s.mu.RUnlock()
+	if hook == nil {
+		return nil
+	}
+	if err := hook(id); err != nil {
+		return fmt.Errorf("%w: %w", ErrNotApplied, err)
+	}
+	return nil
+}
+
+// Runs returns the run history store kept alongside the schedule entries.
+func (s *FileSchedulerStore) Runs() *FileRunStore {
+	return s.runs
 }
 
 func (s *FileSchedulerStore) filePath(id string) string {
@@ -56,6 +145,14 @@ func (s *FileSchedulerStore) Save(ctx context.Context, entry *ScheduleEntry) err
 	}
 	entry.UpdatedAt = now
 
+	if err := s.write(entry); err != nil {
+		return err
+	}
+	return s.changed(entry.ID)
+}
+
+// write persists entry atomically under the write lock.
+func (s *FileSchedulerStore) write(entry *ScheduleEntry) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -96,6 +193,13 @@ func (s *FileSchedulerStore) Load(ctx context.Context, id string) (*ScheduleEntr
 }
 
 func (s *FileSchedulerStore) Delete(ctx context.Context, id string) error {
+	if err := s.remove(id); err != nil {
+		return err
+	}
+	return s.changed(id)
+}
+
+func (s *FileSchedulerStore) remove(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -106,7 +210,7 @@ func (s *FileSchedulerStore) Delete(ctx context.Context, id string) error {
 		}
 		return fmt.Errorf("failed to delete schedule entry: %w", err)
 	}
-	return nil
+	return s.runs.DeleteEntry(id)
 }
 
 func (s *FileSchedulerStore) List(ctx context.Context) ([]ScheduleEntry, error) {
@@ -138,13 +242,41 @@ func (s *FileSchedulerStore) List(ctx context.Context) ([]ScheduleEntry, error)
 }
 
 func (s *FileSchedulerStore) UpdateLastRun(id string, runID string, err error) error {
+	return s.update(id, func(entry *ScheduleEntry) {
+		entry.LastRunAt = time.Now()
+		entry.LastRunID = runID
+
+		if err != nil {
+			entry.LastStatus = "failed"
+			entry.LastError = err.Error()
+			entry.FailureCnt++
+		} else {
+			entry.LastStatus = "success"
+			entry.LastError = ""
+			entry.SuccessCnt++
+		}
+	})
+}
+
+// Retire disables an entry whose trigger has no fire times left and
+// records when that happened. The entry file is kept for inspection.
+func (s *FileSchedulerStore) Retire(id string) error {
+	return s.update(id, func(entry *ScheduleEntry) {
+		entry.Enabled = false
+		entry.RetiredAt = time.Now()
+	})
+}
+
+// update applies fn to the stored entry under the write lock and persists
+// the result atomically.
+func (s *FileSchedulerStore) update(id string, fn func(entry *ScheduleEntry)) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	path := s.filePath(id)
 	data, readErr := os.ReadFile(path)
 	if readErr != nil {
-		return fmt.Errorf("failed to read entry for last run update: %w", readErr)
+		return fmt.Errorf("failed to read entry for update: %w", readErr)
 	}
 
 	var entry ScheduleEntry
@@ -152,18 +284,7 @@ func (s *FileSchedulerStore) UpdateLastRun(id string, runID string, err error) e
 		return fmt.Errorf("failed to unmarshal entry: %w", err)
 	}
 
-	entry.LastRunAt = time.Now()
-	entry.LastRunID = runID
-
-	if err != nil {
-		entry.LastStatus = "failed"
-		entry.LastError = err.Error()
-		entry.FailureCnt++
-	} else {
-		entry.LastStatus = "success"
-		entry.LastError = ""
-		entry.SuccessCnt++
-	}
+	fn(&entry)
 	entry.UpdatedAt = time.Now()
 
 	updated, marshalErr := json.MarshalIndent(&entry, "", "  ")
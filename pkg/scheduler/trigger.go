@@ -0,0 +1,298 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// TriggerType selects how a ScheduleEntry computes its fire times.
+type TriggerType string
+
+const (
+	// TriggerCron fires on a 6-field cron expression (CronExpr). It is the
+	// default when Trigger is empty, so entries written before triggers
+	// existed keep working unchanged.
+	TriggerCron TriggerType = "cron"
+	// TriggerAt fires exactly once at RunAt.
+	TriggerAt TriggerType = "at"
+	// TriggerEvery fires at a fixed interval (Every) anchored at StartAt,
+	// or at CreatedAt when no start is given.
+	TriggerEvery TriggerType = "every"
+)
+
+// cronParser accepts the 6-field (seconds-first) syntax used by the
+// scheduler's cron instance, plus descriptors such as "@daily".
+var cronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// timeLayouts are the accepted input formats for ParseTime, tried in order.
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// TriggerKind returns the effective trigger type, defaulting to TriggerCron.
+func (e *ScheduleEntry) TriggerKind() TriggerType {
+	if e.Trigger == "" {
+		return TriggerCron
+	}
+	return e.Trigger
+}
+
+// Runs returns the total number of completed runs (successful or failed).
+func (e *ScheduleEntry) Runs() int {
+	return e.SuccessCnt + e.FailureCnt
+}
+
+// Validate checks that the entry's trigger, execution policy, follow-up
+// steps and sinks are complete and parseable.
+func (e *ScheduleEntry) Validate() error {
+	if _, err := e.Schedule(); err != nil {
+		return err
+	}
+	if err := e.validatePolicy(); err != nil {
+		return err
+	}
+	if err := e.validateChain(); err != nil {
+		return err
+	}
+	for _, sink := range e.Sinks {
+		if err := sink.validate(); err != nil {
+			return err
+		}
+	}
+	if e.MaxRuns < 0 {
+		return fmt.Errorf("max_runs must not be negative")
+	}
+	if !e.StartAt.IsZero() && !e.EndAt.IsZero() && !e.EndAt.After(e.StartAt) {
+		return fmt.Errorf("end_at must be after start_at")
+	}
+	return nil
+}
+
+// Schedule builds the cron.Schedule described by the entry's trigger,
+// time zone and start/end window. MaxRuns is not part of the schedule;
+// it is enforced by Exhausted after each run.
+func (e *ScheduleEntry) Schedule() (cron.Schedule, error) {
+	loc, err := loadLocation(e.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	var sched cron.Schedule
+	switch e.TriggerKind() {
+	case TriggerCron:
+		if e.CronExpr == "" {
+			return nil, fmt.Errorf("cron_expr is required for cron trigger")
+		}
+		sched, err = parseCronExpr(e.CronExpr, loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression: %w", err)
+		}
+	case TriggerAt:
+		if e.RunAt.IsZero() {
+			return nil, fmt.Errorf("run_at is required for at trigger")
+		}
+		sched = atSchedule{at: e.RunAt.In(loc)}
+	case TriggerEvery:
+		interval, err := time.ParseDuration(e.Every)
+		if err != nil {
+			return nil, fmt.Errorf("invalid every interval %q: %w", e.Every, err)
+		}
+		if interval < time.Second {
+			return nil, fmt.Errorf("every interval must be at least 1s")
+		}
+		anchor := e.StartAt
+		if anchor.IsZero() {
+			anchor = e.CreatedAt
+		}
+		if anchor.IsZero() {
+			anchor = time.Now()
+		}
+		sched = everySchedule{anchor: anchor.In(loc), interval: interval}
+	default:
+		return nil, fmt.Errorf("unknown trigger %q (want cron, at or every)", e.Trigger)
+	}
+
+	if !e.StartAt.IsZero() || !e.EndAt.IsZero() {
+		sched = windowSchedule{inner: sched, start: e.StartAt, end: e.EndAt}
+	}
+	return sched, nil
+}
+
+// NextRun returns the next fire time after t, or the zero time when the
+// entry will never fire again.
+func (e *ScheduleEntry) NextRun(t time.Time) time.Time {
+	if e.MaxRuns > 0 && e.Runs() >= e.MaxRuns {
+		return time.Time{}
+	}
+	sched, err := e.Schedule()
+	if err != nil {
+		return time.Time{}
+	}
+	return sched.Next(t)
+}
+
+// Exhausted reports whether the entry has no fire times left after t,
+// either because MaxRuns has been reached or because its trigger (a past
+// one-shot, an elapsed end window) can no longer fire.
+func (e *ScheduleEntry) Exhausted(t time.Time) bool {
+	return e.NextRun(t).IsZero()
+}
+
+// ParseTime parses a user-supplied timestamp in the given IANA time zone
+// (local time when tz is empty). RFC 3339 values carry their own offset
+// and ignore tz.
+func ParseTime(value, tz string) (time.Time, error) {
+	loc, err := loadLocation(tz)
+	if err != nil {
+		return time.Time{}, err
+	}
+	value = strings.TrimSpace(value)
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (want RFC 3339 or \"2006-01-02 15:04\")", value)
+}
+
+func loadLocation(tz string) (*time.Location, error) {
+	if tz == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", tz, err)
+	}
+	return loc, nil
+}
+
+// parseCronExpr parses a 6-field cron expression in loc. Besides the
+// standard syntax it accepts two calendar-aware day-of-month values:
+//
+//	L   last day of the month          "0 0 18 L * *"
+//	LW  last weekday (Mon–Fri)         "0 0 9 LW * *"
+//
+// Public holidays are not taken into account.
+func parseCronExpr(expr string, loc *time.Location) (cron.Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) > 0 && (strings.HasPrefix(fields[0], "TZ=") || strings.HasPrefix(fields[0], "CRON_TZ=")) {
+		l, err := loadLocation(fields[0][strings.Index(fields[0], "=")+1:])
+		if err != nil {
+			return nil, err
+		}
+		loc = l
+		fields = fields[1:]
+	}
+
+	var dom string
+	if len(fields) == 6 {
+		if v := strings.ToUpper(fields[3]); v == "L" || v == "LW" {
+			dom = v
+			fields[3] = "*"
+		}
+	}
+
+	sched, err := cronParser.Parse("CRON_TZ=" + loc.String() + " " + strings.Join(fields, " "))
+	if err != nil {
+		return nil, err
+	}
+	if dom != "" {
+		return calendarSchedule{base: sched, dom: dom, loc: loc}, nil
+	}
+	return sched, nil
+}
+
+// atSchedule fires once at a fixed instant.
+type atSchedule struct {
+	at time.Time
+}
+
+func (s atSchedule) Next(t time.Time) time.Time {
+	if t.Before(s.at) {
+		return s.at
+	}
+	return time.Time{}
+}
+
+// everySchedule fires at anchor + k*interval. Anchoring (rather than
+// cron.Every's "interval after registration") keeps fire times stable
+// across daemon restarts and reloads.
+type everySchedule struct {
+	anchor   time.Time
+	interval time.Duration
+}
+
+func (s everySchedule) Next(t time.Time) time.Time {
+	if t.Before(s.anchor) {
+		return s.anchor
+	}
+	k := t.Sub(s.anchor)/s.interval + 1
+	return s.anchor.Add(k * s.interval)
+}
+
+// windowSchedule restricts an inner schedule to [start, end].
+type windowSchedule struct {
+	inner      cron.Schedule
+	start, end time.Time
+}
+
+func (s windowSchedule) Next(t time.Time) time.Time {
+	if !s.start.IsZero() && t.Before(s.start) {
+		t = s.start.Add(-time.Second)
+	}
+	next := s.inner.Next(t)
+	if next.IsZero() || (!s.end.IsZero() && next.After(s.end)) {
+		return time.Time{}
+	}
+	return next
+}
+
+// maxCalendarSteps bounds the search in calendarSchedule.Next. Each step
+// skips at least one day, so this covers several years.
+const maxCalendarSteps = 2000
+
+// calendarSchedule filters a cron schedule down to the days matching a
+// calendar rule that robfig/cron cannot express natively.
+type calendarSchedule struct {
+	base cron.Schedule
+	dom  string
+	loc  *time.Location
+}
+
+func (s calendarSchedule) Next(t time.Time) time.Time {
+	next := t
+	for i := 0; i < maxCalendarSteps; i++ {
+		next = s.base.Next(next)
+		if next.IsZero() {
+			return next
+		}
+		next = next.In(s.loc)
+		if next.Day() == s.targetDay(next) {
+			return next
+		}
+		// Jump to the last second of the day so the next candidate lands
+		// on the following day.
+		y, m, d := next.Date()
+		next = time.Date(y, m, d, 23, 59, 59, 0, s.loc)
+	}
+	return time.Time{}
+}
+
+// targetDay returns the day of t's month selected by the rule.
+func (s calendarSchedule) targetDay(t time.Time) int {
+	last := time.Date(t.Year(), t.Month()+1, 0, 12, 0, 0, 0, s.loc)
+	if s.dom == "LW" {
+		for last.Weekday() == time.Saturday || last.Weekday() == time.Sunday {
+			last = last.AddDate(0, 0, -1)
+		}
+	}
+	return last.Day()
+}
@@ -0,0 +1,88 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleEntry_AtTrigger(t *testing.T) {
+	runAt := time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC)
+	entry := &ScheduleEntry{ID: "once", Trigger: TriggerAt, RunAt: runAt}
+
+	if err := entry.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if got := entry.NextRun(runAt.Add(-time.Hour)); !got.Equal(runAt) {
+		t.Errorf("NextRun before = %v, want %v", got, runAt)
+	}
+	if !entry.Exhausted(runAt) {
+		t.Error("at trigger should be exhausted once its time has passed")
+	}
+}
+
+func TestScheduleEntry_EveryTrigger(t *testing.T) {
+	start := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	entry := &ScheduleEntry{ID: "every", Trigger: TriggerEvery, Every: "90m", StartAt: start}
+
+	if got := entry.NextRun(start.Add(-time.Minute)); !got.Equal(start) {
+		t.Errorf("NextRun before start = %v, want %v", got, start)
+	}
+	want := start.Add(3 * time.Hour)
+	if got := entry.NextRun(start.Add(2 * time.Hour)); !got.Equal(want) {
+		t.Errorf("NextRun = %v, want %v", got, want)
+	}
+
+	entry.Every = "500ms"
+	if err := entry.Validate(); err == nil {
+		t.Error("sub-second interval should be rejected")
+	}
+}
+
+func TestScheduleEntry_LastWeekdayOfMonth(t *testing.T) {
+	// 2026-10-31 is a Saturday, so the last business day is Friday 10-30.
+	entry := &ScheduleEntry{ID: "lw", CronExpr: "0 0 9 LW * *", Timezone: "UTC"}
+	got := entry.NextRun(time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC))
+	want := time.Date(2026, 10, 30, 9, 0, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("NextRun(LW) = %v, want %v", got, want)
+	}
+
+	entry.CronExpr = "0 0 18 L * *"
+	got = entry.NextRun(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC))
+	want = time.Date(2026, 2, 28, 18, 0, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("NextRun(L) = %v, want %v", got, want)
+	}
+}
+
+func TestScheduleEntry_WindowAndMaxRuns(t *testing.T) {
+	end := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	entry := &ScheduleEntry{ID: "win", CronExpr: "0 0 * * * *", EndAt: end}
+
+	if entry.Exhausted(end.Add(-2 * time.Hour)) {
+		t.Error("entry should still fire before end_at")
+	}
+	if !entry.Exhausted(end) {
+		t.Error("entry should be exhausted after end_at")
+	}
+
+	entry.EndAt = time.Time{}
+	entry.MaxRuns = 2
+	entry.SuccessCnt, entry.FailureCnt = 1, 1
+	if !entry.Exhausted(end) {
+		t.Error("entry should be exhausted after max_runs")
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	got, err := ParseTime("2026-11-01 09:00", "Asia/Shanghai")
+	if err != nil {
+		t.Fatalf("ParseTime failed: %v", err)
+	}
+	if want := time.Date(2026, 11, 1, 1, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("ParseTime = %v, want %v", got.UTC(), want)
+	}
+	if _, err := ParseTime("tomorrow", ""); err == nil {
+		t.Error("ParseTime should reject free-form text")
+	}
+}
@@ -0,0 +1,204 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+// bpePreTokenizer splits text into the pieces BPE merges within. It is an
+// RE2-compatible form of the cl100k/o200k split pattern: contractions,
+// letter runs with one leading non-letter, digit groups of up to three,
+// punctuation runs and whitespace.
+var bpePreTokenizer = regexp.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`)
+
+// bpeCacheSize bounds the per-encoding cache of piece token counts.
+const bpeCacheSize = 1 << 14
+
+// BPEEncoding is a byte-level BPE tokenizer over a tiktoken-style
+// vocabulary. It implements TokenEstimator by counting the tokens the
+// vocabulary encodes text into.
+type BPEEncoding struct {
+	name  string
+	ranks map[string]int
+
+	mu    sync.Mutex
+	cache map[string]int
+}
+
+// NewBPEEncoding creates an encoding from token ranks; a lower rank merges
+// first.
+func NewBPEEncoding(name string, ranks map[string]int) *BPEEncoding {
+	return &BPEEncoding{name: name, ranks: ranks, cache: make(map[string]int)}
+}
+
+// LoadBPEFile loads a vocabulary in the tiktoken format: one token per
+// line, base64-encoded, followed by a space and its rank.
+func LoadBPEFile(name, path string) (*BPEEncoding, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open bpe vocabulary: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	return ReadBPE(name, f)
+}
+
+// ReadBPE reads a tiktoken-format vocabulary from r.
+func ReadBPE(name string, r io.Reader) (*BPEEncoding, error) {
+	ranks := make(map[string]int)
+	sc := bufio.NewScanner(r)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := bytes.TrimSpace(sc.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		sep := bytes.IndexByte(text, ' ')
+		if sep < 0 {
+			return nil, fmt.Errorf("bpe vocabulary %s line %d: want \"<base64 token> <rank>\"", name, line)
+		}
+		token, err := base64.StdEncoding.DecodeString(string(text[:sep]))
+		if err != nil {
+			return nil, fmt.Errorf("bpe vocabulary %s line %d: %w", name, line, err)
+		}
+		rank, err := strconv.Atoi(string(text[sep+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("bpe vocabulary %s line %d: %w", name, line, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read bpe vocabulary %s: %w", name, err)
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("bpe vocabulary %s is empty", name)
+	}
+	return NewBPEEncoding(name, ranks), nil
+}
+
+// Name returns the encoding name, e.g. "cl100k_base".
+func (e *BPEEncoding) Name() string {
+	return e.name
+}
+
+// Estimate returns the number of tokens text encodes into.
+func (e *BPEEncoding) Estimate(text string) int {
+	n := 0
+	for _, piece := range bpePreTokenizer.FindAllString(text, -1) {
+		n += e.countPiece(piece)
+	}
+	return n
+}
+
+func (e *BPEEncoding) countPiece(piece string) int {
+	if _, ok := e.ranks[piece]; ok {
+		return 1
+	}
+
+	e.mu.Lock()
+	n, ok := e.cache[piece]
+	e.mu.Unlock()
+	if ok {
+		return n
+	}
+
+	n = e.mergeCount(piece)
+
+	e.mu.Lock()
+	if len(e.cache) >= bpeCacheSize {
+		clear(e.cache)
+	}
+	e.cache[piece] = n
+	e.mu.Unlock()
+	return n
+}
+
+// mergeCount applies the vocabulary's merges to piece, lowest rank first
+// and leftmost among equal ranks, and returns the number of tokens left.
+// Bytes missing from the vocabulary stay single tokens. Candidate pairs
+// wait in a heap, so a long piece costs O(n log n) rather than a scan of
+// every pair per merge.
+func (e *BPEEncoding) mergeCount(piece string) int {
+	n := len(piece)
+	if n < 2 {
+		return n
+	}
+	// Part i starts at byte i; next and prev link the parts still alive.
+	next := make([]int, n)
+	prev := make([]int, n)
+	alive := make([]bool, n)
+	for i := range n {
+		next[i], prev[i], alive[i] = i+1, i-1, true
+	}
+	next[n-1] = -1
+	end := func(i int) int {
+		if next[i] < 0 {
+			return n
+		}
+		return next[i]
+	}
+
+	pairs := &bpePairs{}
+	push := func(i int) {
+		if i < 0 || next[i] < 0 {
+			return
+		}
+		j := next[i]
+		if rank, ok := e.ranks[piece[i:end(j)]]; ok {
+			heap.Push(pairs, bpePair{rank: rank, left: i, right: j, end: end(j)})
+		}
+	}
+	for i := 0; i+1 < n; i++ {
+		push(i)
+	}
+
+	count := n
+	for pairs.Len() > 0 {
+		p := heap.Pop(pairs).(bpePair)
+		if !alive[p.left] || !alive[p.right] || next[p.left] != p.right || end(p.right) != p.end {
+			continue // a merge since changed one of its parts
+		}
+		alive[p.right] = false
+		next[p.left] = next[p.right]
+		if next[p.left] >= 0 {
+			prev[next[p.left]] = p.left
+		}
+		count--
+		push(prev[p.left])
+		push(p.left)
+	}
+	return count
+}
+
+// bpePair is a merge candidate: the parts starting at left and right,
+// which together span piece[left:end].
+type bpePair struct {
+	rank, left, right, end int
+}
+
+// bpePairs is a min-heap of merge candidates by rank, then position.
+type bpePairs []bpePair
+
+func (h bpePairs) Len() int { return len(h) }
+func (h bpePairs) Less(i, j int) bool {
+	if h[i].rank != h[j].rank {
+		return h[i].rank < h[j].rank
+	}
+	return h[i].left < h[j].left
+}
+func (h bpePairs) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *bpePairs) Push(x any)   { *h = append(*h, x.(bpePair)) }
+func (h *bpePairs) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -13,21 +13,12 @@ import (
 	"time"
 
 	goharnesssession "github.com/DotNetAge/goharness/session"
+	"github.com/DotNetAge/mindx/pkg/storage"
 	"gopkg.in/yaml.v3"
 )
 
-// encodeMsg base64-encodes content fields for safe YAML storage.
-func encodeMsg(msg goharnesssession.Message) goharnesssession.Message {
-	encoded := msg
-	encoded.Content = base64.StdEncoding.EncodeToString([]byte(msg.Content))
-	encoded.ReasoningContent = base64.StdEncoding.EncodeToString([]byte(msg.ReasoningContent))
-	if msg.Compacted != "" {
-		encoded.Compacted = base64.StdEncoding.EncodeToString([]byte(msg.Compacted))
-	}
-	return encoded
-}
-
-// decodeMsg base64-decodes content fields, falling back to raw string on error.
+// decodeMsg base64-decodes the content fields of a message read from a
+// legacy session.yml, falling back to the raw string on error.
 func decodeMsg(msg goharnesssession.Message) goharnesssession.Message {
 	decoded := msg
 	if d, err := base64.StdEncoding.DecodeString(msg.Content); err == nil {
@@ -54,6 +45,8 @@ var _ goharnesssession.SessionStore = (*FileSessionStore)(nil)
 
 // GetCursor retrieves the compaction cursor position from the session's metadata.
 func (s *FileSessionStore) GetCursor(_ context.Context, sessionID string) (int, error) {
+	defer s.lockSession(sessionID)()
+
 	dirPath := s.findSessionDir(sessionID)
 	if dirPath == "" {
 		return 0, nil
@@ -67,6 +60,8 @@ func (s *FileSessionStore) GetCursor(_ context.Context, sessionID string) (int,
 
 // SetCursor persists the compaction cursor position to the session's metadata.
 func (s *FileSessionStore) SetCursor(_ context.Context, sessionID string, cursor int) error {
+	defer s.lockSession(sessionID)()
+
 	dirPath := s.findSessionDir(sessionID)
 	if dirPath == "" {
 		return fmt.Errorf("session %q not found", sessionID)
@@ -88,7 +83,15 @@ type FileSessionStore struct {
 	slideMu        sync.RWMutex
 	slideHandler   goharnesssession.SlideHandler
 	tokenEstimator TokenEstimator
-	ioMu           sync.Mutex // 保护所有文件 I/O 操作，防止并发读写导致数据损坏
+	estimatorFor   func(agentName string) TokenEstimator
+
+	// locks holds one *sync.Mutex per session ID, serialising file I/O on
+	// that session only, so sessions never contend with each other.
+	locks sync.Map
+	// dirs caches session ID -> session directory lookups.
+	dirs sync.Map
+	// search is the full-text index, nil until EnableSearch.
+	search *SearchIndex
 }
 
 func NewFileSessionStore(rootDir string) (*FileSessionStore, error) {
@@ -114,6 +117,29 @@ func (s *FileSessionStore) SetTokenEstimator(est TokenEstimator) {
 	}
 }
 
+// SetTokenEstimatorResolver makes CurrentContext estimate with the
+// estimator of the agent's model. A nil result falls back to the store's
+// default estimator.
+func (s *FileSessionStore) SetTokenEstimatorResolver(fn func(agentName string) TokenEstimator) {
+	s.estimatorFor = fn
+}
+
+func (s *FileSessionStore) estimator(agentName string) TokenEstimator {
+	if s.estimatorFor != nil {
+		if est := s.estimatorFor(agentName); est != nil {
+			return est
+		}
+	}
+	return s.tokenEstimator
+}
+
+// lockSession locks a session and returns the matching unlock.
+func (s *FileSessionStore) lockSession(sessionID string) func() {
+	mu, _ := s.locks.LoadOrStore(sessionID, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
 func (s *FileSessionStore) agentDir(agentName string) string {
 	return filepath.Join(s.rootDir, agentName)
 }
@@ -122,35 +148,38 @@ func (s *FileSessionStore) sessionDir(agentName, sessionID string) string {
 	return filepath.Join(s.agentDir(agentName), sessionID)
 }
 
-func (s *FileSessionStore) sessionFilePath(agentName, sessionID string) string {
-	return filepath.Join(s.sessionDir(agentName, sessionID), "session.yml")
-}
-
+// findSessionDir returns the directory of a session — one named after it
+// holding meta.json, a message log or a legacy session.yml — or "".
 func (s *FileSessionStore) findSessionDir(sessionID string) string {
+	if cached, ok := s.dirs.Load(sessionID); ok {
+		if _, err := os.Stat(cached.(string)); err == nil {
+			return cached.(string)
+		}
+		s.dirs.Delete(sessionID)
+	}
+
 	var result string
 	_ = filepath.Walk(s.rootDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil || !info.IsDir() || info.Name() != sessionID {
 			return nil
 		}
-		sessionFile := filepath.Join(path, "meta.json")
-		if info.IsDir() {
-			if _, statErr := os.Stat(sessionFile); statErr == nil {
-				result = path
-				return filepath.SkipAll
-			}
+		if _, statErr := os.Stat(filepath.Join(path, "meta.json")); statErr == nil || hasSessionData(path) {
+			result = path
+			return filepath.SkipAll
 		}
 		return nil
 	})
+	if result != "" {
+		s.dirs.Store(sessionID, result)
+	}
 	return result
 }
 
 func (s *FileSessionStore) Append(ctx context.Context, sessionID string, agentName string, sponsor string, msg goharnesssession.Message) error {
-	s.ioMu.Lock()
-	defer s.ioMu.Unlock()
-
 	if msg.Role == "system" {
 		return nil
 	}
+	defer s.lockSession(sessionID)()
 
 	timestamp := msg.Timestamp
 	if timestamp == 0 {
@@ -162,28 +191,16 @@ func (s *FileSessionStore) Append(ctx context.Context, sessionID string, agentNa
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("create session dir %s: %w", dir, err)
 	}
+	s.dirs.Store(sessionID, dir)
 
-	path := s.sessionFilePath(agentName, sessionID)
-
-	var msgs []goharnesssession.Message
-	if data, err := os.ReadFile(path); err == nil {
-		if err := yaml.Unmarshal(data, &msgs); err != nil {
-			return fmt.Errorf("parse existing session file %s: %w", path, err)
-		}
-	} else if !os.IsNotExist(err) {
-		return fmt.Errorf("read session file %s: %w", path, err)
-	}
-
-	msgs = append(msgs, encodeMsg(msg))
-
-	data, err := yaml.Marshal(msgs)
+	idx, err := openLogIndex(dir)
 	if err != nil {
-		return fmt.Errorf("marshal messages: %w", err)
+		return fmt.Errorf("open session log %s: %w", dir, err)
 	}
-
-	if err := os.WriteFile(path, data, 0600); err != nil {
-		return fmt.Errorf("write session file %s: %w", path, err)
+	if err := idx.append(dir, msg); err != nil {
+		return fmt.Errorf("append to session log %s: %w", dir, err)
 	}
+	s.indexAppend(dir, msg)
 
 	// 补录 title：首条 user 消息内容 → session 标题（仅首次，不覆盖）
 	if msg.Role == "user" && msg.Content != "" {
@@ -206,31 +223,56 @@ func (s *FileSessionStore) Append(ctx context.Context, sessionID string, agentNa
 }
 
 func (s *FileSessionStore) Get(ctx context.Context, sessionID string) ([]goharnesssession.Message, error) {
-	s.ioMu.Lock()
-	defer s.ioMu.Unlock()
+	defer s.lockSession(sessionID)()
 
-	var msgs []goharnesssession.Message
+	dir := s.findSessionDir(sessionID)
+	if dir == "" || !hasSessionData(dir) {
+		return nil, nil
+	}
+	msgs, err := s.readMessages(dir)
+	if err != nil {
+		log.Printf("[WARN] session: failed to read session log %s: %v", dir, err)
+		return nil, nil
+	}
 
-	err := filepath.Walk(s.rootDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() || info.Name() != "session.yml" {
-			return nil
-		}
-		parentDir := filepath.Base(filepath.Dir(path))
-		if parentDir != sessionID {
-			return nil
-		}
+	sort.SliceStable(msgs, func(i, j int) bool { return msgs[i].Timestamp < msgs[j].Timestamp })
+	return msgs, nil
+}
 
-		parsed, parseErr := parseMessagesFromFile(path)
-		if parseErr != nil {
-			log.Printf("[WARN] session: failed to parse session file %s: %v", path, parseErr)
-			return nil
-		}
-		msgs = parsed
-		return filepath.SkipAll
-	})
+// readMessages returns the messages of the session in dir in log order.
+// The caller holds the session lock.
+func (s *FileSessionStore) readMessages(dir string) ([]goharnesssession.Message, error) {
+	idx, err := openLogIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+	return idx.readLog(dir)
+}
 
-	sort.Slice(msgs, func(i, j int) bool { return msgs[i].Timestamp < msgs[j].Timestamp })
-	return msgs, err
+// rewriteMessages replaces the messages of the session in dir. The caller
+// holds the session lock.
+func (s *FileSessionStore) rewriteMessages(dir string, msgs []goharnesssession.Message) error {
+	idx, err := openLogIndex(dir)
+	if err != nil {
+		return err
+	}
+	return idx.rewrite(dir, msgs)
+}
+
+// Compact rewrites a session's message log as a single segment, dropping
+// segments left behind by rolls and any torn or corrupt lines.
+func (s *FileSessionStore) Compact(_ context.Context, sessionID string) error {
+	defer s.lockSession(sessionID)()
+
+	dir := s.findSessionDir(sessionID)
+	if dir == "" {
+		return goharnesssession.ErrSessionNotFound
+	}
+	idx, err := openLogIndex(dir)
+	if err != nil {
+		return err
+	}
+	return idx.compact(dir)
 }
 
 func (s *FileSessionStore) CurrentContext(ctx context.Context, agentName string, maxTokens int64) ([]goharnesssession.Message, error) {
@@ -244,11 +286,12 @@ func (s *FileSessionStore) CurrentContext(ctx context.Context, agentName string,
 		return nil, err
 	}
 
+	est := s.estimator(agentName)
 	var result []goharnesssession.Message
 	var totalTokens int64
 	for i := len(allMsgs) - 1; i >= 0; i-- {
 		msg := allMsgs[i]
-		tokens := int64(s.tokenEstimator.Estimate(msg.Content))
+		tokens := int64(est.Estimate(msg.Content))
 		if totalTokens+tokens > maxTokens && len(result) > 0 {
 			break
 		}
@@ -279,28 +322,14 @@ func (s *FileSessionStore) CurrentContext(ctx context.Context, agentName string,
 }
 
 func (s *FileSessionStore) Delete(ctx context.Context, timestamp int64, sessionID string) error {
-	s.ioMu.Lock()
-	defer s.ioMu.Unlock()
+	defer s.lockSession(sessionID)()
 
-	path := ""
-
-	_ = filepath.Walk(s.rootDir, func(p string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() || info.Name() != "session.yml" {
-			return nil
-		}
-		parentDir := filepath.Base(filepath.Dir(p))
-		if parentDir != sessionID {
-			return nil
-		}
-		path = p
-		return filepath.SkipAll
-	})
-
-	if path == "" {
+	dir := s.findSessionDir(sessionID)
+	if dir == "" || !hasSessionData(dir) {
 		return nil
 	}
 
-	msgs, err := parseMessagesFromFile(path)
+	msgs, err := s.readMessages(dir)
 	if err != nil {
 		return err
 	}
@@ -312,41 +341,37 @@ func (s *FileSessionStore) Delete(ctx context.Context, timestamp int64, sessionI
 		}
 	}
 
-	return writeMessagesToFile(path, filtered)
+	if err := s.rewriteMessages(dir, filtered); err != nil {
+		return err
+	}
+	s.indexReplace(dir, filtered)
+	return nil
 }
 
 func (s *FileSessionStore) Clear(ctx context.Context, sessionID string) error {
-	s.ioMu.Lock()
-	defer s.ioMu.Unlock()
+	defer s.lockSession(sessionID)()
 
-	var dirPath string
-	_ = filepath.Walk(s.rootDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() || info.Name() != "session.yml" {
-			return nil
-		}
-		parentDir := filepath.Base(filepath.Dir(path))
-		if parentDir == sessionID {
-			dirPath = filepath.Dir(path)
-			return filepath.SkipAll
-		}
-		return nil
-	})
-	if dirPath == "" {
+	dirPath := s.findSessionDir(sessionID)
+	if dirPath == "" || !hasSessionData(dirPath) {
 		return nil
 	}
+	s.dirs.Delete(sessionID)
+	s.indexReplace(dirPath, nil)
 	return os.RemoveAll(dirPath)
 }
 
 // DeleteSession removes the entire session directory and all its contents.
 // This permanently deletes the session and cannot be undone.
 func (s *FileSessionStore) DeleteSession(_ context.Context, sessionID string) error {
-	s.ioMu.Lock()
-	defer s.ioMu.Unlock()
+	defer s.lockSession(sessionID)()
 
 	dirPath := s.findSessionDir(sessionID)
 	if dirPath == "" {
 		return goharnesssession.ErrSessionNotFound
 	}
+	s.dirs.Delete(sessionID)
+	s.indexReplace(dirPath, nil)
+	s.unlinkChild(dirPath)
 
 	if rmErr := os.RemoveAll(dirPath); rmErr != nil {
 		log.Printf("[WARN] session: failed to remove session directory %s: %v", dirPath, rmErr)
@@ -366,9 +391,6 @@ func (s *FileSessionStore) Close() error {
 }
 
 func (s *FileSessionStore) ListSessions(ctx context.Context) ([]goharnesssession.SessionInfo, error) {
-	s.ioMu.Lock()
-	defer s.ioMu.Unlock()
-
 	var infos []goharnesssession.SessionInfo
 
 	_ = filepath.Walk(s.rootDir, func(path string, info os.FileInfo, err error) error {
@@ -428,8 +450,10 @@ func (s *FileSessionStore) findSessionByAgent(agentName string) (string, error)
 	return bestSession, nil
 }
 
+// parseMessagesFromFile reads a legacy session.yml, which held every message
+// with base64-encoded content fields.
 func parseMessagesFromFile(path string) ([]goharnesssession.Message, error) {
-	data, err := os.ReadFile(path)
+	data, err := storage.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
@@ -446,19 +470,6 @@ func parseMessagesFromFile(path string) ([]goharnesssession.Message, error) {
 	return msgs, nil
 }
 
-func writeMessagesToFile(path string, msgs []goharnesssession.Message) error {
-	encoded := make([]goharnesssession.Message, len(msgs))
-	for i, msg := range msgs {
-		encoded[i] = encodeMsg(msg)
-	}
-
-	data, err := yaml.Marshal(encoded)
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(path, data, 0600)
-}
-
 func statSessionInfo(agentName, sessionID, sessionDirPath string) (*goharnesssession.SessionInfo, error) {
 	info, err := os.Stat(sessionDirPath)
 	if err != nil {
@@ -502,7 +513,7 @@ func statSessionInfo(agentName, sessionID, sessionDirPath string) (*goharnessses
 	}
 
 	// 加载已追踪的修改文件列表
-	if mfData, mfErr := os.ReadFile(filepath.Join(sessionDirPath, "modify_files.yml")); mfErr == nil {
+	if mfData, mfErr := storage.ReadFile(filepath.Join(sessionDirPath, "modify_files.yml")); mfErr == nil {
 		var files []string
 		if yaml.Unmarshal(mfData, &files) == nil {
 			si.ModifyFiles = files
@@ -632,8 +643,7 @@ func (s *FileSessionStore) modifyFilesPath(dirPath string) string {
 // SaveModifyFiles persists the tracked modified file paths list to disk.
 // Files are stored as a YAML string array in the session directory.
 func (s *FileSessionStore) SaveModifyFiles(sessionID string, files []string) error {
-	s.ioMu.Lock()
-	defer s.ioMu.Unlock()
+	defer s.lockSession(sessionID)()
 
 	dirPath := s.findSessionDir(sessionID)
 	if dirPath == "" {
@@ -654,15 +664,17 @@ func (s *FileSessionStore) SaveModifyFiles(sessionID string, files []string) err
 	if err != nil {
 		return fmt.Errorf("marshal modify_files: %w", err)
 	}
+	if data, err = storage.Seal(data); err != nil {
+		return fmt.Errorf("seal modify_files: %w", err)
+	}
 
-	return os.WriteFile(path, data, 0644)
+	return writeFileAtomic(path, data, 0644)
 }
 
 // GetModifyFiles loads the tracked modified file paths list from disk.
 // Returns nil if no file exists (session has no tracked modifications).
 func (s *FileSessionStore) GetModifyFiles(sessionID string) ([]string, error) {
-	s.ioMu.Lock()
-	defer s.ioMu.Unlock()
+	defer s.lockSession(sessionID)()
 
 	dirPath := s.findSessionDir(sessionID)
 	if dirPath == "" {
@@ -670,7 +682,7 @@ func (s *FileSessionStore) GetModifyFiles(sessionID string) ([]string, error) {
 	}
 
 	path := s.modifyFilesPath(dirPath)
-	data, err := os.ReadFile(path)
+	data, err := storage.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, nil
@@ -686,20 +698,17 @@ func (s *FileSessionStore) GetModifyFiles(sessionID string) ([]string, error) {
 	return files, nil
 }
 
-// Truncate removes messages from the session file on disk, keeping only
-// the first keepCount messages. Remaining messages are written back to
-// the session.yml file in their original order.
+// Truncate removes messages from the session log, keeping only the first
+// keepCount messages in their original order.
 func (s *FileSessionStore) Truncate(_ context.Context, sessionID string, keepCount int) error {
-	s.ioMu.Lock()
-	defer s.ioMu.Unlock()
+	defer s.lockSession(sessionID)()
 
 	dirPath := s.findSessionDir(sessionID)
 	if dirPath == "" {
 		return nil
 	}
 
-	path := filepath.Join(dirPath, "session.yml")
-	msgs, err := parseMessagesFromFile(path)
+	msgs, err := s.readMessages(dirPath)
 	if err != nil {
 		return err
 	}
@@ -708,26 +717,27 @@ func (s *FileSessionStore) Truncate(_ context.Context, sessionID string, keepCou
 		return nil
 	}
 
-	msgs = msgs[:keepCount]
-	return writeMessagesToFile(path, msgs)
+	if err := s.rewriteMessages(dirPath, msgs[:keepCount]); err != nil {
+		return err
+	}
+	s.indexReplace(dirPath, msgs[:keepCount])
+	return nil
 }
 
-// UpdateMessages replaces all messages in the session file with the given messages.
-// This writes the complete message list to session.yml, overwriting any existing content.
-// Also persists the compaction cursor position.
+// UpdateMessages replaces all messages in the session log with the given
+// messages, and persists the compaction cursor position.
 func (s *FileSessionStore) UpdateMessages(_ context.Context, sessionID string, cursor int, msgs []goharnesssession.Message) error {
-	s.ioMu.Lock()
-	defer s.ioMu.Unlock()
+	defer s.lockSession(sessionID)()
 
 	dirPath := s.findSessionDir(sessionID)
 	if dirPath == "" {
 		return goharnesssession.ErrSessionNotFound
 	}
 
-	path := filepath.Join(dirPath, "session.yml")
-	if err := writeMessagesToFile(path, msgs); err != nil {
+	if err := s.rewriteMessages(dirPath, msgs); err != nil {
 		return err
 	}
+	s.indexReplace(dirPath, msgs)
 
 	// Persist cursor alongside messages
 	info, err := LoadSessionMeta(dirPath)
@@ -138,8 +138,8 @@ func TestFileStoreWithComplexContent(t *testing.T) {
 	})
 }
 
-func TestFileStoreYAMLFormat(t *testing.T) {
-	tmpDir, err := os.MkdirTemp("", "session-yaml-test")
+func TestFileStoreSegmentFormat(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "session-segment-test")
 	if err != nil {
 		t.Fatalf("create temp dir: %v", err)
 	}
@@ -152,7 +152,7 @@ func TestFileStoreYAMLFormat(t *testing.T) {
 	defer func() { _ = store.Close() }()
 
 	ctx := context.Background()
-	sessionID := "yaml-format-test"
+	sessionID := "segment-format-test"
 	agentName := "test-agent"
 
 	markdownContent := "# Complex Markdown\n\n## Features\n- **Bold** and *italic*\n- `code inline`\n- [links](http://example.com)\n\n```json\n{ \"key\": \"value\" }\n```\n\n> Blockquote with \"quotes\" and 'apostrophes'"
@@ -168,13 +168,13 @@ func TestFileStoreYAMLFormat(t *testing.T) {
 		t.Fatalf("Append failed: %v", err)
 	}
 
-	yamlPath := filepath.Join(tmpDir, agentName, sessionID, "session.yml")
-	data, err := os.ReadFile(yamlPath)
+	segPath := filepath.Join(tmpDir, agentName, sessionID, logDirName, "000001.jsonl")
+	data, err := os.ReadFile(segPath)
 	if err != nil {
-		t.Fatalf("read yaml file failed: %v", err)
+		t.Fatalf("read session segment failed: %v", err)
 	}
 
-	t.Logf("Generated YAML file content:\n%s", string(data))
+	t.Logf("Generated segment content:\n%s", string(data))
 
 	retrieved, err := store.Get(ctx, sessionID)
 	if err != nil {
@@ -191,7 +191,7 @@ func TestFileStoreYAMLFormat(t *testing.T) {
 	}
 }
 
-// TestFileStoreConcurrentAppend 验证并发 Append 不会导致数据损坏（ioMu 保护）。
+// TestFileStoreConcurrentAppend 验证并发 Append 不会导致数据损坏（会话级锁保护）。
 func TestFileStoreConcurrentAppend(t *testing.T) {
 	tmpDir := t.TempDir()
 	store, err := NewFileSessionStore(tmpDir)
@@ -310,3 +310,37 @@ func TestFileStoreDeleteSessionNotFound(t *testing.T) {
 		t.Errorf("expected ErrSessionNotFound, got %v", err)
 	}
 }
+
+// TestFileStoreSetStarred 验证星标写入 meta.json，且不会被后续的元数据更新覆盖。
+func TestFileStoreSetStarred(t *testing.T) {
+	store, err := NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("create store: %v", err)
+	}
+	ctx := context.Background()
+	info, err := store.Create(ctx, "test-agent")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	dir, _ := store.ResolveSessionDir(info.SessionID)
+
+	if err := store.SetStarred(info.SessionID, true); err != nil {
+		t.Fatalf("SetStarred failed: %v", err)
+	}
+	if err := store.Append(ctx, info.SessionID, "test-agent", "", goharnesssession.Message{Role: "user", Content: "hello"}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if !LoadSessionStarred(dir) {
+		t.Error("star lost after append")
+	}
+
+	if err := store.SetStarred(info.SessionID, false); err != nil {
+		t.Fatalf("SetStarred failed: %v", err)
+	}
+	if LoadSessionStarred(dir) {
+		t.Error("session still starred")
+	}
+	if err := store.SetStarred("nonexistent-session", true); err != goharnesssession.ErrSessionNotFound {
+		t.Errorf("expected ErrSessionNotFound, got %v", err)
+	}
+}
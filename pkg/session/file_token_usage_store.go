@@ -1,17 +1,34 @@
 package session
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	goharnesssession "github.com/DotNetAge/goharness/session"
+	"github.com/DotNetAge/mindx/pkg/storage"
 	"gopkg.in/yaml.v3"
 )
 
+const (
+	// usageDirName holds the monthly partitions, one JSONL file per month.
+	usageDirName = "token_usage"
+	// legacyUsageFile is the single YAML file used before partitioning. It
+	// is migrated into the partitions on first use and renamed afterwards.
+	legacyUsageFile = "token_usages.yml"
+	// partitionLayout names a partition file after its month.
+	partitionLayout = "2006-01"
+)
+
 // TokenUsageRecordWithSource extends goharness's TokenUsageRecord with a source field
 // that identifies where the token consumption originated (chat, indexing, translation, etc.).
 type TokenUsageRecordWithSource struct {
@@ -19,25 +36,26 @@ type TokenUsageRecordWithSource struct {
 	Source UsageSource `json:"source"`
 }
 
-// yamlTokenUsageRecord is the YAML-serializable form of TokenUsageRecord.
-type yamlTokenUsageRecord struct {
-	ID               string    `yaml:"id"`
-	SessionID        string    `yaml:"session_id"`
-	ConversationID   string    `yaml:"conversation_id"`
-	ModelName        string    `yaml:"model_name"`
-	ProviderName     string    `yaml:"provider_name"`
-	AgentName        string    `yaml:"agent_name"`
-	PromptTokens     int       `yaml:"prompt_tokens"`
-	CompletionTokens int       `yaml:"completion_tokens"`
-	CachedTokens     int       `yaml:"cached_tokens"`
-	ReasoningTokens  int       `yaml:"reasoning_tokens"`
-	TotalTokens      int       `yaml:"total_tokens"`
-	Timestamp        time.Time `yaml:"timestamp"`
-	Source           string    `yaml:"source"`
-}
-
-func toYamlRecord(r goharnesssession.TokenUsageRecord, source UsageSource) yamlTokenUsageRecord {
-	return yamlTokenUsageRecord{
+// storedUsageRecord is the on-disk form of a TokenUsageRecord: one JSON
+// line in a partition, or one YAML list item in the legacy file.
+type storedUsageRecord struct {
+	ID               string    `json:"id" yaml:"id"`
+	SessionID        string    `json:"session_id,omitempty" yaml:"session_id"`
+	ConversationID   string    `json:"conversation_id,omitempty" yaml:"conversation_id"`
+	ModelName        string    `json:"model_name,omitempty" yaml:"model_name"`
+	ProviderName     string    `json:"provider_name,omitempty" yaml:"provider_name"`
+	AgentName        string    `json:"agent_name,omitempty" yaml:"agent_name"`
+	PromptTokens     int       `json:"prompt_tokens" yaml:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens" yaml:"completion_tokens"`
+	CachedTokens     int       `json:"cached_tokens" yaml:"cached_tokens"`
+	ReasoningTokens  int       `json:"reasoning_tokens" yaml:"reasoning_tokens"`
+	TotalTokens      int       `json:"total_tokens" yaml:"total_tokens"`
+	Timestamp        time.Time `json:"timestamp" yaml:"timestamp"`
+	Source           string    `json:"source,omitempty" yaml:"source"`
+}
+
+func toStoredRecord(r goharnesssession.TokenUsageRecord, source UsageSource) storedUsageRecord {
+	return storedUsageRecord{
 		ID:               r.ID,
 		SessionID:        r.SessionID,
 		ConversationID:   r.ConversationID,
@@ -54,86 +72,131 @@ func toYamlRecord(r goharnesssession.TokenUsageRecord, source UsageSource) yamlT
 	}
 }
 
-func fromYamlRecord(yr yamlTokenUsageRecord) TokenUsageRecordWithSource {
-	source := UsageSource(yr.Source)
+func fromStoredRecord(sr storedUsageRecord) TokenUsageRecordWithSource {
+	source := UsageSource(sr.Source)
 	if source == "" {
 		source = UsageSourceChat
 	}
 	return TokenUsageRecordWithSource{
 		TokenUsageRecord: goharnesssession.TokenUsageRecord{
-			ID:               yr.ID,
-			SessionID:        yr.SessionID,
-			ConversationID:   yr.ConversationID,
-			ModelName:        yr.ModelName,
-			ProviderName:     yr.ProviderName,
-			AgentName:        yr.AgentName,
-			PromptTokens:     yr.PromptTokens,
-			CompletionTokens: yr.CompletionTokens,
-			CachedTokens:     yr.CachedTokens,
-			ReasoningTokens:  yr.ReasoningTokens,
-			TotalTokens:      yr.TotalTokens,
-			Timestamp:        yr.Timestamp,
+			ID:               sr.ID,
+			SessionID:        sr.SessionID,
+			ConversationID:   sr.ConversationID,
+			ModelName:        sr.ModelName,
+			ProviderName:     sr.ProviderName,
+			AgentName:        sr.AgentName,
+			PromptTokens:     sr.PromptTokens,
+			CompletionTokens: sr.CompletionTokens,
+			CachedTokens:     sr.CachedTokens,
+			ReasoningTokens:  sr.ReasoningTokens,
+			TotalTokens:      sr.TotalTokens,
+			Timestamp:        sr.Timestamp,
 		},
 		Source: source,
 	}
 }
 
-// FileTokenUsageStore implements goharness/session.TokenUsageStore with unified
-// file-backed persistence. ALL records from every agent, session, and model
-// are stored in a single YAML file, differentiated by the Provider / Model /
-// Agent / Session dimension fields on TokenUsageRecord.
+// usagePartition is the in-memory view of one monthly partition file:
+// the records read so far plus secondary indexes into them. offset is
+// the number of bytes consumed, so later appends (from this or another
+// process) are picked up by reading only the tail. file identifies the
+// file they were read from, so one written in its place is read anew.
+type usagePartition struct {
+	offset    int64
+	file      os.FileInfo
+	records   []TokenUsageRecordWithSource
+	bySession map[string][]int
+	byModel   map[string][]int
+	byAgent   map[string][]int
+}
+
+func newUsagePartition() *usagePartition {
+	return &usagePartition{
+		bySession: make(map[string][]int),
+		byModel:   make(map[string][]int),
+		byAgent:   make(map[string][]int),
+	}
+}
+
+func (p *usagePartition) add(r TokenUsageRecordWithSource) {
+	i := len(p.records)
+	p.records = append(p.records, r)
+	p.bySession[r.SessionID] = append(p.bySession[r.SessionID], i)
+	p.byModel[r.ModelName] = append(p.byModel[r.ModelName], i)
+	p.byAgent[r.AgentName] = append(p.byAgent[r.AgentName], i)
+}
+
+// candidates returns the positions of the records that can match filter,
+// using the most selective secondary index the filter allows.
+func (p *usagePartition) candidates(filter goharnesssession.TokenUsageFilter) []int {
+	switch {
+	case filter.SessionID != "":
+		return p.bySession[filter.SessionID]
+	case filter.AgentName != "":
+		return p.byAgent[filter.AgentName]
+	case filter.ModelName != "":
+		return p.byModel[filter.ModelName]
+	}
+	all := make([]int, len(p.records))
+	for i := range all {
+		all[i] = i
+	}
+	return all
+}
+
+// FileTokenUsageStore implements goharness/session.TokenUsageStore as an
+// append-only log partitioned by month. Each LLM call appends one JSON line
+// to the partition of its timestamp; nothing is ever rewritten.
 //
 // File layout:
 //
-//	<dataDir>/token_usages.yml
+//	<dataDir>/token_usage/2026-09.jsonl
+//	<dataDir>/token_usage/2026-10.jsonl
+//
+// Queries only open the partitions overlapping [Since, Until) and look up
+// candidates through per-partition indexes by session, model and agent.
+// Partitions are loaded lazily and kept in memory; appends made by other
+// processes (e.g. the TUI next to the daemon) are picked up on the next
+// query by reading the file tail.
+//
+// A legacy <dataDir>/token_usages.yml is migrated into the partitions on
+// first use and renamed to token_usages.yml.migrated.
 //
 // Thread-safe for concurrent read/write access.
 type FileTokenUsageStore struct {
 	dataDir string
-	mu      sync.RWMutex
+
+	mu    sync.Mutex // guards parts
+	parts map[string]*usagePartition
+
+	appendMu sync.Mutex // serializes appends within the process
+
+	migrateMu sync.Mutex
+	migrated  bool
 }
 
 // NewFileTokenUsageStore creates a FileTokenUsageStore rooted at the given
-// data directory. All token usage records are stored in a single file at
-// <dataDir>/token_usages.yml.
+// data directory. Partitions live under <dataDir>/token_usage.
 func NewFileTokenUsageStore(dataDir string) *FileTokenUsageStore {
-	return &FileTokenUsageStore{dataDir: dataDir}
+	return &FileTokenUsageStore{
+		dataDir: dataDir,
+		parts:   make(map[string]*usagePartition),
+	}
 }
 
-// filePath returns the single unified path for all token usage records.
-func (s *FileTokenUsageStore) filePath() string {
-	return filepath.Join(s.dataDir, "token_usages.yml")
+func (s *FileTokenUsageStore) partitionDir() string {
+	return filepath.Join(s.dataDir, usageDirName)
 }
 
-// loadAll reads all records from the single unified file.
-// Returns empty slice if file doesn't exist.
-func (s *FileTokenUsageStore) loadAll() ([]yamlTokenUsageRecord, error) {
-	path := s.filePath()
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil
-		}
-		return nil, err
-	}
-	var records []yamlTokenUsageRecord
-	if err := yaml.Unmarshal(data, &records); err != nil {
-		return nil, fmt.Errorf("parse %s: %w", path, err)
-	}
-	return records, nil
+func (s *FileTokenUsageStore) partitionPath(month string) string {
+	return filepath.Join(s.partitionDir(), month+".jsonl")
 }
 
-// saveAll writes all records to the single unified file atomically.
-func (s *FileTokenUsageStore) saveAll(records []yamlTokenUsageRecord) error {
-	path := s.filePath()
-	data, err := yaml.Marshal(records)
-	if err != nil {
-		return fmt.Errorf("marshal token_usages: %w", err)
-	}
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		return fmt.Errorf("create data directory: %w", err)
+func partitionMonth(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
 	}
-	return os.WriteFile(path, data, 0644)
+	return t.UTC().Format(partitionLayout)
 }
 
 // Append writes a single TokenUsageRecord with default source "chat".
@@ -148,22 +211,39 @@ func (s *FileTokenUsageStore) AppendWithSource(_ context.Context, record goharne
 	return s.appendWithSource(record, UsageSource(source))
 }
 
+// appendWithSource appends one JSON line to the record's partition. A
+// failed legacy migration does not block recording: it is retried by the
+// next query, and the migration de-duplicates by record ID.
 func (s *FileTokenUsageStore) appendWithSource(record goharnesssession.TokenUsageRecord, source UsageSource) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	_ = s.migrate()
 
-	records, err := s.loadAll()
+	line, err := marshalLine(toStoredRecord(record, source))
 	if err != nil {
-		return err
+		return fmt.Errorf("marshal token usage: %w", err)
 	}
-	records = append(records, toYamlRecord(record, source))
-	return s.saveAll(records)
+
+	s.appendMu.Lock()
+	defer s.appendMu.Unlock()
+
+	if err := os.MkdirAll(s.partitionDir(), 0755); err != nil {
+		return fmt.Errorf("create token usage directory: %w", err)
+	}
+	f, err := os.OpenFile(s.partitionPath(partitionMonth(record.Timestamp)), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("open token usage partition: %w", err)
+	}
+	// A single write of one line keeps O_APPEND records whole even when
+	// another process appends to the same partition.
+	if _, err := f.Write(line); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("append token usage: %w", err)
+	}
+	return f.Close()
 }
 
 // Query retrieves TokenUsageRecords matching the given filter from the unified store.
-// All filtering is done in-memory on the flat record collection.
-func (s *FileTokenUsageStore) Query(_ context.Context, filter goharnesssession.TokenUsageFilter) ([]goharnesssession.TokenUsageRecord, error) {
-	extRecords, err := s.QueryWithSource(context.Background(), filter)
+func (s *FileTokenUsageStore) Query(ctx context.Context, filter goharnesssession.TokenUsageFilter) ([]goharnesssession.TokenUsageRecord, error) {
+	extRecords, err := s.QueryWithSource(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -176,52 +256,303 @@ func (s *FileTokenUsageStore) Query(_ context.Context, filter goharnesssession.T
 
 // QueryWithSource retrieves TokenUsageRecordWithSource entries matching the given filter.
 // Unlike Query, this returns the source field so callers can distinguish indexing
-// consumption from chat consumption.
+// consumption from chat consumption. The time range is [Since, Until).
+// Records are returned in append order, oldest partition first.
 func (s *FileTokenUsageStore) QueryWithSource(_ context.Context, filter goharnesssession.TokenUsageFilter) ([]TokenUsageRecordWithSource, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	if err := s.migrate(); err != nil {
+		return nil, err
+	}
 
-	yamlRecs, err := s.loadAll()
+	months, err := s.months(filter.Since, filter.Until)
 	if err != nil {
 		return nil, err
 	}
-	if len(yamlRecs) == 0 {
-		return nil, nil
-	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
 	var result []TokenUsageRecordWithSource
-	for _, yr := range yamlRecs {
-		r := fromYamlRecord(yr)
-		if filter.SessionID != "" && r.SessionID != filter.SessionID {
-			continue
+	for _, month := range months {
+		p, err := s.refresh(month)
+		if err != nil {
+			return nil, err
 		}
-		if filter.ConversationID != "" && r.ConversationID != filter.ConversationID {
-			continue
+		for _, i := range p.candidates(filter) {
+			if r := p.records[i]; matchUsage(r, filter) {
+				result = append(result, r)
+			}
 		}
-		if filter.ModelName != "" && r.ModelName != filter.ModelName {
+	}
+	return result, nil
+}
+
+// Close is a no-op: partitions are opened per append.
+func (s *FileTokenUsageStore) Close() error {
+	return nil
+}
+
+func matchUsage(r TokenUsageRecordWithSource, filter goharnesssession.TokenUsageFilter) bool {
+	if filter.SessionID != "" && r.SessionID != filter.SessionID {
+		return false
+	}
+	if filter.ConversationID != "" && r.ConversationID != filter.ConversationID {
+		return false
+	}
+	if filter.ModelName != "" && r.ModelName != filter.ModelName {
+		return false
+	}
+	if filter.ProviderName != "" && r.ProviderName != filter.ProviderName {
+		return false
+	}
+	if filter.AgentName != "" && r.AgentName != filter.AgentName {
+		return false
+	}
+	if !filter.Since.IsZero() && r.Timestamp.Before(filter.Since) {
+		return false
+	}
+	if !filter.Until.IsZero() && !r.Timestamp.Before(filter.Until) {
+		return false
+	}
+	return true
+}
+
+// months lists the existing partitions overlapping [since, until) in
+// chronological order. Zero bounds are open.
+func (s *FileTokenUsageStore) months(since, until time.Time) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(s.partitionDir(), "*.jsonl"))
+	if err != nil {
+		return nil, fmt.Errorf("list token usage partitions: %w", err)
+	}
+
+	var out []string
+	for _, path := range paths {
+		month := strings.TrimSuffix(filepath.Base(path), ".jsonl")
+		start, err := time.Parse(partitionLayout, month)
+		if err != nil {
 			continue
 		}
-		if filter.ProviderName != "" && r.ProviderName != filter.ProviderName {
+		end := start.AddDate(0, 1, 0)
+		if !since.IsZero() && !end.After(since) {
 			continue
 		}
-		if filter.AgentName != "" && r.AgentName != filter.AgentName {
+		if !until.IsZero() && !until.After(start) {
 			continue
 		}
-		if !filter.Since.IsZero() && r.Timestamp.Before(filter.Since) {
+		out = append(out, month)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// refresh brings the cached partition up to date with its file, reading
+// only the bytes appended since the last call. A file replaced since,
+// as by a migration, a reseal or another process, is a different file
+// even at the same or a larger size, and is read from the start. Caller
+// holds mu.
+func (s *FileTokenUsageStore) refresh(month string) (*usagePartition, error) {
+	p := s.parts[month]
+	if p == nil {
+		p = newUsagePartition()
+		s.parts[month] = p
+	}
+
+	f, err := os.Open(s.partitionPath(month))
+	if err != nil {
+		if os.IsNotExist(err) {
+			if p.file != nil {
+				p = newUsagePartition()
+				s.parts[month] = p
+			}
+			return p, nil
+		}
+		return nil, fmt.Errorf("open token usage partition: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat token usage partition: %w", err)
+	}
+	if p.file != nil && (!os.SameFile(p.file, info) || info.Size() < p.offset) {
+		p = newUsagePartition()
+		s.parts[month] = p
+	}
+	p.file = info
+	if info.Size() == p.offset {
+		return p, nil
+	}
+
+	if _, err := f.Seek(p.offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("seek token usage partition: %w", err)
+	}
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			// A trailing line without newline is still being written;
+			// leave it for the next refresh.
+			break
+		}
+		plain, err := storage.OpenLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("read token usage partition: %w", err)
+		}
+		p.offset += int64(len(line))
+		plain = bytes.TrimSpace(plain)
+		if len(plain) == 0 {
 			continue
 		}
-		if !filter.Until.IsZero() && r.Timestamp.After(filter.Until) {
+		var sr storedUsageRecord
+		if err := json.Unmarshal(plain, &sr); err != nil {
 			continue
 		}
-		result = append(result, r)
+		p.add(fromStoredRecord(sr))
 	}
+	return p, nil
+}
 
-	out := make([]TokenUsageRecordWithSource, len(result))
-	copy(out, result)
-	return out, nil
+// migrate moves records from the legacy YAML file into monthly partitions
+// once. It is idempotent: records already present in a partition (by ID)
+// are not copied twice, so an interrupted migration can simply be re-run.
+func (s *FileTokenUsageStore) migrate() error {
+	s.migrateMu.Lock()
+	defer s.migrateMu.Unlock()
+	if s.migrated {
+		return nil
+	}
+
+	legacy := filepath.Join(s.dataDir, legacyUsageFile)
+	data, err := storage.ReadFile(legacy)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.migrated = true
+			return nil
+		}
+		return fmt.Errorf("read %s: %w", legacy, err)
+	}
+	var records []storedUsageRecord
+	if err := yaml.Unmarshal(data, &records); err != nil {
+		return fmt.Errorf("parse %s: %w", legacy, err)
+	}
+
+	byMonth := make(map[string][]storedUsageRecord)
+	for _, r := range records {
+		month := partitionMonth(r.Timestamp)
+		byMonth[month] = append(byMonth[month], r)
+	}
+
+	s.appendMu.Lock()
+	defer s.appendMu.Unlock()
+
+	if err := os.MkdirAll(s.partitionDir(), 0755); err != nil {
+		return fmt.Errorf("create token usage directory: %w", err)
+	}
+	for month, legacyRecs := range byMonth {
+		if err := s.mergePartition(month, legacyRecs); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(legacy, legacy+".migrated"); err != nil {
+		return fmt.Errorf("rename %s: %w", legacy, err)
+	}
+	s.migrated = true
+	return nil
 }
 
-// Close is a no-op for the file store.
-func (s *FileTokenUsageStore) Close() error {
+// mergePartition rewrites a partition as its current records plus the
+// given legacy ones, de-duplicated by ID and sorted by timestamp. Only
+// used by migrate; caller holds appendMu.
+func (s *FileTokenUsageStore) mergePartition(month string, legacy []storedUsageRecord) error {
+	path := s.partitionPath(month)
+
+	var merged []storedUsageRecord
+	seen := make(map[string]bool)
+	if data, err := os.ReadFile(path); err == nil {
+		for _, line := range bytes.Split(data, []byte("\n")) {
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			plain, err := storage.OpenLine(line)
+			if err != nil {
+				return fmt.Errorf("read token usage partition: %w", err)
+			}
+			var sr storedUsageRecord
+			if json.Unmarshal(plain, &sr) != nil {
+				continue
+			}
+			merged = append(merged, sr)
+			if sr.ID != "" {
+				seen[sr.ID] = true
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("read token usage partition: %w", err)
+	}
+	for _, r := range legacy {
+		if r.ID != "" && seen[r.ID] {
+			continue
+		}
+		merged = append(merged, r)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Timestamp.Before(merged[j].Timestamp)
+	})
+
+	var buf bytes.Buffer
+	for _, r := range merged {
+		line, err := marshalLine(r)
+		if err != nil {
+			return fmt.Errorf("marshal token usage: %w", err)
+		}
+		buf.Write(line)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("write token usage partition: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("rename token usage partition: %w", err)
+	}
 	return nil
 }
+
+// Reseal rewrites every partition, and the retired legacy YAML file, in
+// the current storage form and reports how many files were rewritten.
+func (s *FileTokenUsageStore) Reseal() (int, error) {
+	if err := s.migrate(); err != nil {
+		return 0, err
+	}
+	months, err := s.months(time.Time{}, time.Time{})
+	if err != nil {
+		return 0, err
+	}
+
+	s.appendMu.Lock()
+	defer s.appendMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	changed, err := storage.ResealFile(filepath.Join(s.dataDir, legacyUsageFile+".migrated"))
+	if err != nil {
+		return n, err
+	}
+	if changed {
+		n++
+	}
+	for _, month := range months {
+		changed, err := storage.ResealLines(s.partitionPath(month))
+		if err != nil {
+			return n, err
+		}
+		if changed {
+			// Offsets into the old file mean nothing in the new one.
+			delete(s.parts, month)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,195 @@
+package session
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	goharnesssession "github.com/DotNetAge/goharness/session"
+	"gopkg.in/yaml.v3"
+)
+
+func usageRecord(id, sessionID, model, agent string, ts time.Time, total int) goharnesssession.TokenUsageRecord {
+	return goharnesssession.TokenUsageRecord{
+		ID:          id,
+		SessionID:   sessionID,
+		ModelName:   model,
+		AgentName:   agent,
+		TotalTokens: total,
+		Timestamp:   ts,
+	}
+}
+
+func TestFileTokenUsageStorePartitionsAndQuery(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileTokenUsageStore(dir)
+	ctx := context.Background()
+
+	sep := time.Date(2026, 9, 15, 10, 0, 0, 0, time.UTC)
+	oct := time.Date(2026, 10, 2, 10, 0, 0, 0, time.UTC)
+	for _, r := range []goharnesssession.TokenUsageRecord{
+		usageRecord("a", "s1", "gpt", "coder", sep, 10),
+		usageRecord("b", "s2", "claude", "writer", sep.Add(time.Hour), 20),
+		usageRecord("c", "s1", "gpt", "coder", oct, 30),
+	} {
+		if err := store.Append(ctx, r); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	if err := store.AppendWithSource(ctx, usageRecord("d", "", "gpt", "", oct.Add(time.Hour), 40), "indexing"); err != nil {
+		t.Fatalf("AppendWithSource: %v", err)
+	}
+
+	for _, month := range []string{"2026-09", "2026-10"} {
+		if _, err := os.Stat(filepath.Join(dir, "token_usage", month+".jsonl")); err != nil {
+			t.Errorf("partition %s missing: %v", month, err)
+		}
+	}
+
+	cases := []struct {
+		name   string
+		filter goharnesssession.TokenUsageFilter
+		want   []string
+	}{
+		{"all", goharnesssession.TokenUsageFilter{}, []string{"a", "b", "c", "d"}},
+		{"session", goharnesssession.TokenUsageFilter{SessionID: "s1"}, []string{"a", "c"}},
+		{"model", goharnesssession.TokenUsageFilter{ModelName: "gpt"}, []string{"a", "c", "d"}},
+		{"agent", goharnesssession.TokenUsageFilter{AgentName: "writer"}, []string{"b"}},
+		{"month", goharnesssession.TokenUsageFilter{Since: oct.AddDate(0, 0, -1)}, []string{"c", "d"}},
+		{"session+range", goharnesssession.TokenUsageFilter{SessionID: "s1", Until: sep.AddDate(0, 0, 1)}, []string{"a"}},
+		{"until exclusive", goharnesssession.TokenUsageFilter{Since: sep, Until: oct}, []string{"a", "b"}},
+	}
+	for _, tc := range cases {
+		got, err := store.QueryWithSource(ctx, tc.filter)
+		if err != nil {
+			t.Fatalf("%s: QueryWithSource: %v", tc.name, err)
+		}
+		var ids []string
+		for _, r := range got {
+			ids = append(ids, r.ID)
+		}
+		if len(ids) != len(tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, ids, tc.want)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != tc.want[i] {
+				t.Errorf("%s: got %v, want %v", tc.name, ids, tc.want)
+				break
+			}
+		}
+	}
+
+	got, _ := store.QueryWithSource(ctx, goharnesssession.TokenUsageFilter{ModelName: "gpt", Since: oct})
+	if len(got) != 2 || got[0].Source != UsageSourceChat || got[1].Source != UsageSourceIndexing {
+		t.Errorf("sources = %+v, want chat then indexing", got)
+	}
+}
+
+func TestFileTokenUsageStorePicksUpExternalAppends(t *testing.T) {
+	dir := t.TempDir()
+	reader := NewFileTokenUsageStore(dir)
+	writer := NewFileTokenUsageStore(dir)
+	ctx := context.Background()
+	now := time.Now()
+
+	if err := writer.Append(ctx, usageRecord("1", "s", "m", "a", now, 1)); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if got, _ := reader.Query(ctx, goharnesssession.TokenUsageFilter{SessionID: "s"}); len(got) != 1 {
+		t.Fatalf("first query got %d records, want 1", len(got))
+	}
+	if err := writer.Append(ctx, usageRecord("2", "s", "m", "a", now, 2)); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if got, _ := reader.Query(ctx, goharnesssession.TokenUsageFilter{SessionID: "s"}); len(got) != 2 {
+		t.Errorf("second query got %d records, want 2", len(got))
+	}
+}
+
+func TestFileTokenUsageStoreRereadsReplacedPartition(t *testing.T) {
+	dir := t.TempDir()
+	reader := NewFileTokenUsageStore(dir)
+	ctx := context.Background()
+	now := time.Now()
+
+	if err := reader.Append(ctx, usageRecord("1", "s", "m", "a", now, 1)); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if got, _ := reader.Query(ctx, goharnesssession.TokenUsageFilter{}); len(got) != 1 {
+		t.Fatalf("first query got %d records, want 1", len(got))
+	}
+
+	// Another process writes a larger partition in its place.
+	other := NewFileTokenUsageStore(t.TempDir())
+	for _, id := range []string{"2", "3"} {
+		if err := other.Append(ctx, usageRecord(id, "s2", "m", "a", now, 2)); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	month := partitionMonth(now)
+	if err := os.Rename(other.partitionPath(month), reader.partitionPath(month)); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := reader.Query(ctx, goharnesssession.TokenUsageFilter{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(got) != 2 || got[0].SessionID != "s2" || got[1].SessionID != "s2" {
+		t.Errorf("after replacement got %+v, want the two records of s2", got)
+	}
+
+	if err := os.Remove(reader.partitionPath(month)); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := reader.Query(ctx, goharnesssession.TokenUsageFilter{}); len(got) != 0 {
+		t.Errorf("after removal got %d records, want 0", len(got))
+	}
+}
+
+func TestFileTokenUsageStoreMigratesLegacyYAML(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	ts := time.Date(2026, 8, 20, 9, 0, 0, 0, time.UTC)
+
+	legacy := []storedUsageRecord{
+		toStoredRecord(usageRecord("old-1", "s1", "gpt", "coder", ts, 100), UsageSourceChat),
+		toStoredRecord(usageRecord("old-2", "s1", "gpt", "coder", ts.AddDate(0, 1, 0), 200), UsageSourceTranslation),
+	}
+	data, err := yaml.Marshal(legacy)
+	if err != nil {
+		t.Fatalf("marshal legacy: %v", err)
+	}
+	legacyPath := filepath.Join(dir, "token_usages.yml")
+	if err := os.WriteFile(legacyPath, data, 0644); err != nil {
+		t.Fatalf("write legacy: %v", err)
+	}
+
+	store := NewFileTokenUsageStore(dir)
+	got, err := store.QueryWithSource(ctx, goharnesssession.TokenUsageFilter{SessionID: "s1"})
+	if err != nil {
+		t.Fatalf("QueryWithSource: %v", err)
+	}
+	if len(got) != 2 || got[0].TotalTokens != 100 || got[1].Source != UsageSourceTranslation {
+		t.Fatalf("migrated records = %+v", got)
+	}
+	if _, err := os.Stat(legacyPath); !os.IsNotExist(err) {
+		t.Error("legacy file should be renamed after migration")
+	}
+
+	// Re-running an interrupted migration must not duplicate records.
+	if err := os.Rename(legacyPath+".migrated", legacyPath); err != nil {
+		t.Fatalf("restore legacy: %v", err)
+	}
+	again := NewFileTokenUsageStore(dir)
+	all, err := again.Query(ctx, goharnesssession.TokenUsageFilter{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(all) != 2 {
+		t.Errorf("re-migration produced %d records, want 2", len(all))
+	}
+}
@@ -0,0 +1,288 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	goharnesssession "github.com/DotNetAge/goharness/session"
+	"github.com/DotNetAge/mindx/pkg/storage"
+	"gopkg.in/yaml.v3"
+)
+
+// Fork creates a new session of the same agent and project holding a copy
+// of the messages of sessionID up to and including the one at atIndex (an
+// index into the full message list, compacted messages included). The
+// tracked modified files, their backups and recorded versions from before
+// the next message are copied too, so the fork can confirm or roll back
+// the changes it inherited independently of its parent. Parent and fork
+// record each other in their lineage.
+func (s *FileSessionStore) Fork(_ context.Context, sessionID string, atIndex int) (*goharnesssession.SessionInfo, error) {
+	defer s.lockSession(sessionID)()
+
+	parentDir := s.findSessionDir(sessionID)
+	if parentDir == "" {
+		return nil, goharnesssession.ErrSessionNotFound
+	}
+	var msgs []goharnesssession.Message
+	if hasSessionData(parentDir) {
+		var err error
+		if msgs, err = s.readMessages(parentDir); err != nil {
+			return nil, fmt.Errorf("read session %q: %w", sessionID, err)
+		}
+	}
+	if atIndex < 0 || atIndex >= len(msgs) {
+		return nil, fmt.Errorf("message index %d out of range: session %q has %d messages", atIndex, sessionID, len(msgs))
+	}
+	kept := msgs[:atIndex+1]
+
+	parent, err := LoadSessionMeta(parentDir)
+	if err != nil {
+		parent = &goharnesssession.SessionInfo{SessionID: sessionID, AgentName: sessionAgent(parentDir)}
+	}
+	agentName := sessionAgent(parentDir)
+
+	childID := generateSessionID()
+	childDir := s.sessionDir(agentName, childID)
+	if err := os.MkdirAll(filepath.Join(childDir, "tmp"), 0755); err != nil {
+		return nil, fmt.Errorf("create session directory %s: %w", childDir, err)
+	}
+	// Remove the half-made fork on failure.
+	ok := false
+	defer func() {
+		if !ok {
+			_ = os.RemoveAll(childDir)
+		}
+	}()
+
+	idx := &logIndex{Version: logIndexVersion}
+	if err := idx.rewrite(childDir, kept); err != nil {
+		return nil, fmt.Errorf("write fork messages: %w", err)
+	}
+	// The fork keeps the file state from before the first message it
+	// leaves out.
+	var cutoff time.Time
+	if atIndex+1 < len(msgs) {
+		cutoff = time.UnixMilli(msgs[atIndex+1].Timestamp)
+	}
+	if err := copyTrackedFiles(parentDir, childDir, cutoff); err != nil {
+		return nil, err
+	}
+
+	// Forking before the compaction cursor would leave the fork's active
+	// window empty, so it starts uncompacted instead.
+	cursor := parent.Cursor
+	if cursor >= len(kept) {
+		cursor = 0
+	}
+	now := time.Now()
+	child := &goharnesssession.SessionInfo{
+		SessionID:      childID,
+		AgentName:      agentName,
+		Sponsor:        parent.Sponsor,
+		ProjectDir:     parent.ProjectDir,
+		Title:          parent.Title,
+		CreatedAt:      now,
+		LastActivityAt: now,
+		MessageCount:   len(kept),
+		Cursor:         cursor,
+	}
+	if err := SaveSessionMeta(childDir, child); err != nil {
+		return nil, fmt.Errorf("save session meta: %w", err)
+	}
+	if err := SaveSessionLineage(childDir, SessionLineage{ParentID: sessionID, ForkIndex: atIndex}); err != nil {
+		return nil, fmt.Errorf("save session lineage: %w", err)
+	}
+
+	lineage, _ := LoadSessionLineage(parentDir)
+	lineage.Children = append(lineage.Children, childID)
+	if _, err := os.Stat(filepath.Join(parentDir, "meta.json")); err != nil {
+		if err := SaveSessionMeta(parentDir, parent); err != nil {
+			return nil, fmt.Errorf("save session meta: %w", err)
+		}
+	}
+	if err := SaveSessionLineage(parentDir, lineage); err != nil {
+		return nil, fmt.Errorf("save session lineage: %w", err)
+	}
+	ok = true
+
+	s.dirs.Store(childID, childDir)
+	s.indexReplace(childDir, kept)
+
+	child.SessionDir = childDir
+	return child, nil
+}
+
+// unlinkChild removes a deleted session from its parent's lineage. The
+// caller holds the child's session lock.
+func (s *FileSessionStore) unlinkChild(childDir string) {
+	l, err := LoadSessionLineage(childDir)
+	if err != nil || l.ParentID == "" {
+		return
+	}
+	defer s.lockSession(l.ParentID)()
+	parentDir := s.findSessionDir(l.ParentID)
+	if parentDir == "" {
+		return
+	}
+	parent, err := LoadSessionLineage(parentDir)
+	if err != nil {
+		return
+	}
+	children := parent.Children[:0]
+	for _, id := range parent.Children {
+		if id != filepath.Base(childDir) {
+			children = append(children, id)
+		}
+	}
+	parent.Children = children
+	if err := SaveSessionLineage(parentDir, parent); err != nil {
+		log.Printf("[WARN] session: failed to unlink fork %s from %s: %v", filepath.Base(childDir), l.ParentID, err)
+	}
+}
+
+// Lineages returns the lineage of every session that has one, by session
+// ID.
+func (s *FileSessionStore) Lineages() map[string]SessionLineage {
+	out := make(map[string]SessionLineage)
+	_ = filepath.Walk(s.rootDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || info.Name() != "meta.json" {
+			return nil
+		}
+		dir := filepath.Dir(path)
+		if l, err := LoadSessionLineage(dir); err == nil && (l.ParentID != "" || len(l.Children) > 0) {
+			out[filepath.Base(dir)] = l
+		}
+		return nil
+	})
+	return out
+}
+
+// copyTrackedFiles copies the file state of a session as of cutoff from
+// one session directory to another: the backups and the recorded file
+// versions (files/<hash>/v<N>) written before cutoff, and the modified-file
+// list without the files first recorded after it. A zero cutoff copies
+// everything.
+func copyTrackedFiles(fromDir, toDir string, cutoff time.Time) error {
+	before := func(info os.FileInfo) bool {
+		return cutoff.IsZero() || info.ModTime().Before(cutoff)
+	}
+
+	backups, err := os.ReadDir(filepath.Join(fromDir, "backup"))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read backups: %w", err)
+	}
+	for _, e := range backups {
+		info, err := e.Info()
+		if err != nil || e.IsDir() || !before(info) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Join(toDir, "backup"), 0755); err != nil {
+			return fmt.Errorf("create backup dir: %w", err)
+		}
+		if err := copyFile(filepath.Join(fromDir, "backup", e.Name()), filepath.Join(toDir, "backup", e.Name())); err != nil {
+			return fmt.Errorf("copy backup %s: %w", e.Name(), err)
+		}
+	}
+
+	// later holds the tracked files whose first version postdates cutoff.
+	later := make(map[string]bool)
+	fileDirs, err := os.ReadDir(filepath.Join(fromDir, "files"))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read file versions: %w", err)
+	}
+	for _, d := range fileDirs {
+		if !d.IsDir() {
+			continue
+		}
+		src, dst := filepath.Join(fromDir, "files", d.Name()), filepath.Join(toDir, "files", d.Name())
+		versions, _ := filepath.Glob(filepath.Join(src, "v*"))
+		copied := 0
+		for _, v := range versions {
+			if info, err := os.Stat(v); err != nil || !before(info) {
+				continue
+			}
+			if err := os.MkdirAll(dst, 0755); err != nil {
+				return fmt.Errorf("create file version dir: %w", err)
+			}
+			if err := copyFile(v, filepath.Join(dst, filepath.Base(v))); err != nil {
+				return fmt.Errorf("copy file version %s: %w", v, err)
+			}
+			copied++
+		}
+		if copied > 0 {
+			if err := copyFile(filepath.Join(src, ".path"), filepath.Join(dst, ".path")); err != nil {
+				return fmt.Errorf("copy file version path: %w", err)
+			}
+		} else if name, err := storage.ReadFile(filepath.Join(src, ".path")); err == nil {
+			later[string(name)] = true
+		}
+	}
+
+	if len(later) == 0 {
+		if err := copyFile(filepath.Join(fromDir, "modify_files.yml"), filepath.Join(toDir, "modify_files.yml")); err != nil {
+			return fmt.Errorf("copy modify_files: %w", err)
+		}
+		return nil
+	}
+	data, err := storage.ReadFile(filepath.Join(fromDir, "modify_files.yml"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("read modify_files: %w", err)
+	}
+	var files, kept []string
+	if err := yaml.Unmarshal(data, &files); err != nil {
+		return fmt.Errorf("parse modify_files: %w", err)
+	}
+	for _, f := range files {
+		if !later[f] {
+			kept = append(kept, f)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	if data, err = yaml.Marshal(kept); err != nil {
+		return fmt.Errorf("marshal modify_files: %w", err)
+	}
+	if data, err = storage.Seal(data); err != nil {
+		return fmt.Errorf("seal modify_files: %w", err)
+	}
+	return writeFileAtomic(filepath.Join(toDir, "modify_files.yml"), data, 0644)
+}
+
+// copyFile copies src to dst with src's permissions and modification
+// time, which dates file versions for later forks. A missing src is not an
+// error.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
@@ -0,0 +1,170 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	goharnesssession "github.com/DotNetAge/goharness/session"
+)
+
+func TestFork(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewFileSessionStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	parentDir := filepath.Join(root, "agent", "parent")
+	if err := SaveSessionMeta(parentDir, &goharnesssession.SessionInfo{
+		SessionID: "parent", AgentName: "agent", ProjectDir: "/work/api", Title: "queue design", Cursor: 3,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	appendN(t, store, "parent", 0, 5)
+	if err := store.SaveModifyFiles("parent", []string{"/work/api/queue.go"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(parentDir, "backup"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(parentDir, "backup", "queue.go.bak"), []byte("package api\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Taken along with the first message.
+	if err := os.Chtimes(filepath.Join(parentDir, "backup", "queue.go.bak"), time.UnixMilli(1000), time.UnixMilli(1000)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Fork(ctx, "parent", 5); err == nil {
+		t.Error("Fork past the last message should fail")
+	}
+
+	child, err := store.Fork(ctx, "parent", 1)
+	if err != nil {
+		t.Fatalf("Fork: %v", err)
+	}
+	if child.AgentName != "agent" || child.ProjectDir != "/work/api" || child.Title != "queue design" {
+		t.Errorf("child = %+v", child)
+	}
+	if child.Cursor != 0 {
+		t.Errorf("cursor = %d, want 0 when forking before the cursor", child.Cursor)
+	}
+
+	msgs, _ := store.Get(ctx, child.SessionID)
+	if got := contents(msgs); got != "msg-0,msg-1" {
+		t.Errorf("fork messages = %q", got)
+	}
+	msgs, _ = store.Get(ctx, "parent")
+	if len(msgs) != 5 {
+		t.Errorf("parent has %d messages, want 5", len(msgs))
+	}
+
+	files, _ := store.GetModifyFiles(child.SessionID)
+	if len(files) != 1 || files[0] != "/work/api/queue.go" {
+		t.Errorf("fork modify files = %v", files)
+	}
+	if data, err := os.ReadFile(filepath.Join(child.SessionDir, "backup", "queue.go.bak")); err != nil || string(data) != "package api\n" {
+		t.Errorf("fork backup = %q, %v", data, err)
+	}
+
+	lineages := store.Lineages()
+	if l := lineages[child.SessionID]; l.ParentID != "parent" || l.ForkIndex != 1 {
+		t.Errorf("child lineage = %+v", l)
+	}
+	if l := lineages["parent"]; len(l.Children) != 1 || l.Children[0] != child.SessionID {
+		t.Errorf("parent lineage = %+v", l)
+	}
+
+	// Lineage survives ordinary metadata updates.
+	appendN(t, store, child.SessionID, 10, 11)
+	if l, _ := LoadSessionLineage(child.SessionDir); l.ParentID != "parent" {
+		t.Errorf("lineage lost after append: %+v", l)
+	}
+	if meta, _ := store.GetSessionMeta(child.SessionID); meta.MessageCount != 3 {
+		t.Errorf("fork message count = %d, want 3", meta.MessageCount)
+	}
+
+	if err := store.DeleteSession(ctx, child.SessionID); err != nil {
+		t.Fatal(err)
+	}
+	if l, _ := LoadSessionLineage(parentDir); len(l.Children) != 0 {
+		t.Errorf("parent children after deleting fork = %v", l.Children)
+	}
+}
+
+func TestForkBeforeLaterEdit(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewFileSessionStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parentDir := filepath.Join(root, "agent", "parent")
+	start := time.Now().Add(-time.Hour)
+	for i := range 4 {
+		msg := goharnesssession.Message{Role: "user", Content: fmt.Sprintf("msg-%d", i),
+			Timestamp: start.Add(time.Duration(i) * 10 * time.Minute).UnixMilli()}
+		if err := store.Append(context.Background(), "parent", "agent", "", msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// queue.go is edited after msg-0 and again after msg-2; worker.go is
+	// first edited after msg-2.
+	write := func(rel, data string, at time.Duration) {
+		t.Helper()
+		path := filepath.Join(parentDir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, start.Add(at), start.Add(at)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("files/q/.path", "/work/api/queue.go", 5*time.Minute)
+	write("files/q/v1", "v1", 5*time.Minute)
+	write("files/q/v2", "v2", 25*time.Minute)
+	write("files/w/.path", "/work/api/worker.go", 25*time.Minute)
+	write("files/w/v1", "v1", 25*time.Minute)
+	write("backup/queue.go.1", "before first edit", 5*time.Minute)
+	write("backup/queue.go.2", "before second edit", 25*time.Minute)
+	if err := store.SaveModifyFiles("parent", []string{"/work/api/queue.go", "/work/api/worker.go"}); err != nil {
+		t.Fatal(err)
+	}
+
+	child, err := store.Fork(context.Background(), "parent", 1)
+	if err != nil {
+		t.Fatalf("Fork: %v", err)
+	}
+	exists := func(rel string) bool {
+		_, err := os.Stat(filepath.Join(child.SessionDir, rel))
+		return err == nil
+	}
+	for rel, want := range map[string]bool{
+		"files/q/.path": true, "files/q/v1": true, "files/q/v2": false,
+		"files/w/.path": false, "files/w/v1": false,
+		"backup/queue.go.1": true, "backup/queue.go.2": false,
+	} {
+		if got := exists(rel); got != want {
+			t.Errorf("fork has %s = %v, want %v", rel, got, want)
+		}
+	}
+	if files, _ := store.GetModifyFiles(child.SessionID); len(files) != 1 || files[0] != "/work/api/queue.go" {
+		t.Errorf("fork modify files = %v, want only queue.go", files)
+	}
+
+	// Forking at the last message keeps everything.
+	child, err = store.Fork(context.Background(), "parent", 3)
+	if err != nil {
+		t.Fatalf("Fork: %v", err)
+	}
+	if !exists("files/q/v2") || !exists("files/w/v1") || !exists("backup/queue.go.2") {
+		t.Error("fork at the last message lost file versions")
+	}
+}
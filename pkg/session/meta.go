@@ -9,6 +9,7 @@ import (
 	"time"
 
 	goharnesssession "github.com/DotNetAge/goharness/session"
+	"github.com/DotNetAge/mindx/pkg/storage"
 	"github.com/oklog/ulid/v2"
 )
 
@@ -27,7 +28,7 @@ func generateSessionID() string {
 func LoadSessionMeta(sessionDirPath string) (*goharnesssession.SessionInfo, error) {
 	metaPath := filepath.Join(sessionDirPath, "meta.json")
 
-	data, err := os.ReadFile(metaPath)
+	data, err := storage.ReadFile(metaPath)
 	if err != nil {
 		return nil, fmt.Errorf("read session meta: %w", err)
 	}
@@ -63,19 +64,144 @@ func LoadSessionMeta(sessionDirPath string) (*goharnesssession.SessionInfo, erro
 	return &info, nil
 }
 
-// SaveSessionMeta persists session metadata to meta.json in the given session directory.
+// SaveSessionMeta atomically persists session metadata to meta.json in the
+// given session directory. Lineage and star keys already in meta.json are
+// kept.
 func SaveSessionMeta(sessionDirPath string, info *goharnesssession.SessionInfo) error {
 	info.UpdatedAt = time.Now()
 
-	data, err := json.MarshalIndent(info, "", "  ")
+	data, err := json.Marshal(info)
 	if err != nil {
 		return fmt.Errorf("marshal session meta: %w", err)
 	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("marshal session meta: %w", err)
+	}
 
 	metaPath := filepath.Join(sessionDirPath, "meta.json")
+	if old, err := readMetaMap(metaPath); err == nil {
+		for _, k := range extraMetaKeys {
+			if v, ok := old[k]; ok {
+				raw[k] = v
+			}
+		}
+	}
+	return writeMetaMap(sessionDirPath, raw)
+}
+
+// SessionLineage links a forked session to the session it was forked
+// from. SessionInfo has no fields for it, so it is kept in meta.json under
+// keys of its own.
+type SessionLineage struct {
+	ParentID string `json:"parent_session_id,omitempty"`
+	// ForkIndex is the index of the last parent message copied into the
+	// fork; it is meaningful only when ParentID is set.
+	ForkIndex int      `json:"fork_message_index,omitempty"`
+	Children  []string `json:"child_session_ids,omitempty"`
+}
+
+var lineageKeys = []string{"parent_session_id", "fork_message_index", "child_session_ids"}
+
+// extraMetaKeys are the meta.json keys that SessionInfo does not cover.
+var extraMetaKeys = []string{"parent_session_id", "fork_message_index", "child_session_ids", starredKey}
+
+// LoadSessionLineage reads the lineage of the session in sessionDirPath.
+// A session that was never forked has a zero lineage.
+func LoadSessionLineage(sessionDirPath string) (SessionLineage, error) {
+	var l SessionLineage
+	data, err := storage.ReadFile(filepath.Join(sessionDirPath, "meta.json"))
+	if err != nil {
+		return l, fmt.Errorf("read session meta: %w", err)
+	}
+	if err := json.Unmarshal(data, &l); err != nil {
+		return l, fmt.Errorf("unmarshal session lineage: %w", err)
+	}
+	return l, nil
+}
+
+// SaveSessionLineage replaces the lineage keys of meta.json in
+// sessionDirPath, leaving the other metadata untouched.
+func SaveSessionLineage(sessionDirPath string, l SessionLineage) error {
+	raw, err := readMetaMap(filepath.Join(sessionDirPath, "meta.json"))
+	if err != nil {
+		return err
+	}
+	for _, k := range lineageKeys {
+		delete(raw, k)
+	}
+	if l.ParentID != "" {
+		raw["parent_session_id"] = l.ParentID
+		raw["fork_message_index"] = l.ForkIndex
+	}
+	if len(l.Children) > 0 {
+		raw["child_session_ids"] = l.Children
+	}
+	return writeMetaMap(sessionDirPath, raw)
+}
+
+// starredKey marks a session in meta.json as starred by the user.
+const starredKey = "starred"
+
+// LoadSessionStarred reports whether the session in sessionDirPath is
+// starred.
+func LoadSessionStarred(sessionDirPath string) bool {
+	raw, err := readMetaMap(filepath.Join(sessionDirPath, "meta.json"))
+	if err != nil {
+		return false
+	}
+	starred, _ := raw[starredKey].(bool)
+	return starred
+}
+
+// SetStarred stars or unstars a session. Session retention can be told to
+// never collect starred sessions.
+func (s *FileSessionStore) SetStarred(sessionID string, starred bool) error {
+	defer s.lockSession(sessionID)()
+
+	dir := s.findSessionDir(sessionID)
+	if dir == "" {
+		return goharnesssession.ErrSessionNotFound
+	}
+	raw, err := readMetaMap(filepath.Join(dir, "meta.json"))
+	if err != nil {
+		if _, statErr := statSessionInfo(sessionAgent(dir), sessionID, dir); statErr != nil {
+			return err
+		}
+		if raw, err = readMetaMap(filepath.Join(dir, "meta.json")); err != nil {
+			return err
+		}
+	}
+	if starred {
+		raw[starredKey] = true
+	} else {
+		delete(raw, starredKey)
+	}
+	return writeMetaMap(dir, raw)
+}
+
+func readMetaMap(metaPath string) (map[string]any, error) {
+	data, err := storage.ReadFile(metaPath)
+	if err != nil {
+		return nil, fmt.Errorf("read session meta: %w", err)
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("unmarshal session meta: %w", err)
+	}
+	return raw, nil
+}
+
+func writeMetaMap(sessionDirPath string, raw map[string]any) error {
+	data, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal session meta: %w", err)
+	}
+	if data, err = storage.Seal(data); err != nil {
+		return fmt.Errorf("seal session meta: %w", err)
+	}
 	if err := os.MkdirAll(sessionDirPath, 0755); err != nil {
 		return fmt.Errorf("create session dir: %w", err)
 	}
-
-	return os.WriteFile(metaPath, data, 0600)
+	return writeFileAtomic(filepath.Join(sessionDirPath, "meta.json"), data, 0600)
 }
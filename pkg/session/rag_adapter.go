@@ -14,14 +14,27 @@ type RAGMemoryAdapter struct {
 	rag        *memory.RAGMemory
 	agentName  string
 	projectDir string
+	// policy limits the session's memory to the scopes its agent declares.
+	policy memory.ScopePolicy
 }
 
-func NewRAGMemoryAdapter(rag *memory.RAGMemory, agentName, projectDir string) *RAGMemoryAdapter {
-	return &RAGMemoryAdapter{rag: rag, agentName: agentName, projectDir: projectDir}
+func NewRAGMemoryAdapter(rag *memory.RAGMemory, agentName, projectDir string, policy memory.ScopePolicy) *RAGMemoryAdapter {
+	return &RAGMemoryAdapter{rag: rag, agentName: agentName, projectDir: projectDir, policy: policy}
+}
+
+// access returns ctx limited to the agent's scopes within sessionID.
+func (a *RAGMemoryAdapter) access(ctx context.Context, sessionID string) context.Context {
+	return memory.WithAccess(ctx, memory.Access{
+		AgentName:  a.agentName,
+		ProjectDir: a.projectDir,
+		SessionID:  sessionID,
+		Policy:     a.policy,
+	})
 }
 
 func (a *RAGMemoryAdapter) StoreChunks(ctx context.Context, sessionID string, chunks []goharnessmemory.MemoryChunk) error {
-	if a.rag == nil || len(chunks) == 0 {
+	// An agent that may write no scope keeps no compaction summaries.
+	if a.rag == nil || len(chunks) == 0 || len(a.policy.Write) == 0 {
 		return nil
 	}
 	// Ensure each chunk gets all required metadata fields.
@@ -36,7 +49,7 @@ func (a *RAGMemoryAdapter) StoreChunks(ctx context.Context, sessionID string, ch
 			chunks[i].ProjectDir = a.projectDir
 		}
 	}
-	return a.rag.StoreMemoryChunks(ctx, chunks)
+	return a.rag.StoreMemoryChunks(a.access(ctx, sessionID), chunks)
 }
 
 func (a *RAGMemoryAdapter) Retrieve(ctx context.Context, query, sessionID string, limit int) ([]goharnessmemory.MemoryChunk, error) {
@@ -48,7 +61,7 @@ func (a *RAGMemoryAdapter) Retrieve(ctx context.Context, query, sessionID string
 		goharnessmemory.WithMemorySessionID(sessionID),
 		goharnessmemory.WithMemoryLimit(limit),
 	}
-	chunks, err := a.rag.Retrieve(ctx, query, opts...)
+	chunks, err := a.rag.Retrieve(a.access(ctx, sessionID), query, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,100 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/DotNetAge/mindx/pkg/storage"
+)
+
+// marshalLine encodes v as one JSONL line, newline included, sealed when
+// storage encryption is on.
+func marshalLine(v any) ([]byte, error) {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if line, err = storage.SealLine(line); err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+// Reseal rewrites every session file, and the search journal, in the
+// current storage form: sealed with the primary key when encryption is on,
+// plaintext when it is off. It reports how many files were rewritten.
+// index.json of the message logs holds only segment names and stays
+// plaintext; so do the backups under backup/, which goharness reads
+// directly.
+func (s *FileSessionStore) Reseal() (int, error) {
+	agents, err := os.ReadDir(s.rootDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("read session root: %w", err)
+	}
+	n := 0
+	for _, a := range agents {
+		if !a.IsDir() {
+			continue
+		}
+		sessions, err := os.ReadDir(s.agentDir(a.Name()))
+		if err != nil {
+			continue
+		}
+		for _, e := range sessions {
+			if !e.IsDir() {
+				continue
+			}
+			unlock := s.lockSession(e.Name())
+			changed, err := resealSessionDir(s.sessionDir(a.Name(), e.Name()))
+			unlock()
+			n += changed
+			if err != nil {
+				return n, fmt.Errorf("reseal session %s: %w", e.Name(), err)
+			}
+		}
+	}
+	if s.search != nil {
+		changed, err := s.search.Reseal()
+		if err != nil {
+			return n, fmt.Errorf("reseal search journal: %w", err)
+		}
+		if changed {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// resealSessionDir reseals the files of one session. The caller holds the
+// session lock.
+func resealSessionDir(dir string) (int, error) {
+	n := 0
+	for _, name := range []string{"meta.json", "modify_files.yml", legacySessionFile, legacySessionFile + ".migrated"} {
+		changed, err := storage.ResealFile(filepath.Join(dir, name))
+		if err != nil {
+			return n, err
+		}
+		if changed {
+			n++
+		}
+	}
+	segments, err := filepath.Glob(filepath.Join(logDirPath(dir), "*.jsonl"))
+	if err != nil {
+		return n, err
+	}
+	for _, path := range segments {
+		changed, err := storage.ResealLines(path)
+		if err != nil {
+			return n, err
+		}
+		if changed {
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,126 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	goharnesssession "github.com/DotNetAge/goharness/session"
+	"github.com/DotNetAge/mindx/pkg/storage"
+)
+
+func useTestKey(t *testing.T) *storage.Keyring {
+	t.Helper()
+	key, err := storage.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring, err := storage.NewKeyring("k1", map[string][]byte{"k1": key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage.Use(ring, true)
+	t.Cleanup(func() { storage.Use(nil, false) })
+	return ring
+}
+
+// filesContaining lists the files under root whose raw bytes contain s.
+func filesContaining(t *testing.T, root, s string) []string {
+	t.Helper()
+	var out []string
+	_ = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		if data, _ := os.ReadFile(path); bytes.Contains(data, []byte(s)) {
+			out = append(out, path)
+		}
+		return nil
+	})
+	return out
+}
+
+func TestFileStoreEncryptedAtRest(t *testing.T) {
+	ring := useTestKey(t)
+	ctx := context.Background()
+	root := t.TempDir()
+	store, err := NewFileSessionStore(filepath.Join(root, "sessions"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.EnableSearch(filepath.Join(root, "search")); err != nil {
+		t.Fatal(err)
+	}
+	info, err := store.Create(ctx, "agent", goharnesssession.WithProjectDirOption("/work/secretproject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Append(ctx, info.SessionID, "agent", "", goharnesssession.Message{Role: "user", Content: "the launch codes are hunter2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveModifyFiles(info.SessionID, []string{"/work/secretproject/main.go"}); err != nil {
+		t.Fatal(err)
+	}
+	usage := NewFileTokenUsageStore(filepath.Join(root, "data"))
+	if err := usage.Append(ctx, goharnesssession.TokenUsageRecord{ID: "u1", SessionID: info.SessionID, ModelName: "secret-model", TotalTokens: 7, Timestamp: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []string{"hunter2", "secretproject", "secret-model"} {
+		if files := filesContaining(t, root, s); len(files) > 0 {
+			t.Errorf("%q stored in plaintext in %v", s, files)
+		}
+	}
+
+	msgs, err := store.Get(ctx, info.SessionID)
+	if err != nil || len(msgs) != 1 || msgs[0].Content != "the launch codes are hunter2" {
+		t.Fatalf("Get = %+v, %v", msgs, err)
+	}
+	if meta, err := store.GetMeta(ctx, info.SessionID); err != nil || meta.ProjectDir != "/work/secretproject" {
+		t.Errorf("GetMeta = %+v, %v", meta, err)
+	}
+	if files, err := store.GetModifyFiles(info.SessionID); err != nil || len(files) != 1 {
+		t.Errorf("GetModifyFiles = %v, %v", files, err)
+	}
+	if hits, err := store.Search(ctx, SearchQuery{Text: "hunter2"}); err != nil || len(hits) != 1 {
+		t.Errorf("Search = %+v, %v", hits, err)
+	}
+	if recs, err := usage.Query(ctx, goharnesssession.TokenUsageFilter{}); err != nil || len(recs) != 1 || recs[0].ModelName != "secret-model" {
+		t.Errorf("usage = %+v, %v", recs, err)
+	}
+
+	// Without the key the log cannot be read, and a rewrite fails rather
+	// than dropping the messages it could not read.
+	storage.Use(nil, false)
+	if msgs, _ := store.Get(ctx, info.SessionID); len(msgs) != 0 {
+		t.Errorf("Get without the storage key = %+v", msgs)
+	}
+	if err := store.Truncate(ctx, info.SessionID, 0); err == nil {
+		t.Error("Truncate without the storage key should fail")
+	}
+
+	// Turning encryption off and resealing writes everything in plaintext.
+	storage.Use(ring, false)
+	if n, err := store.Reseal(); err != nil || n == 0 {
+		t.Fatalf("Reseal = %d, %v", n, err)
+	}
+	if n, err := usage.Reseal(); err != nil || n != 1 {
+		t.Fatalf("usage Reseal = %d, %v", n, err)
+	}
+	storage.Use(nil, false)
+	if msgs, err := store.Get(ctx, info.SessionID); err != nil || len(msgs) != 1 {
+		t.Errorf("Get after decrypting = %+v, %v", msgs, err)
+	}
+	if recs, err := usage.Query(ctx, goharnesssession.TokenUsageFilter{}); err != nil || len(recs) != 1 {
+		t.Errorf("usage after decrypting = %+v, %v", recs, err)
+	}
+	if files := filesContaining(t, root, "hunter2"); len(files) == 0 {
+		t.Error("decrypted session log should hold the plaintext")
+	}
+	if n, err := store.Reseal(); err != nil || n != 0 {
+		t.Errorf("second Reseal = %d, %v, want nothing to do", n, err)
+	}
+}
@@ -0,0 +1,385 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+
+	goharnesssession "github.com/DotNetAge/goharness/session"
+	"github.com/DotNetAge/mindx/pkg/storage"
+)
+
+// SearchIndex is an inverted full-text index over session messages. Each
+// message is a document keyed by session and message offset; the index
+// keeps only terms and per-document attributes, message text is read back
+// from the session log for snippets.
+//
+// It is persisted as a journal (journal.jsonl) of add and drop records,
+// replayed on open. Appending a message appends one add record; rewriting
+// a session drops it and re-adds its messages. The journal is compacted
+// once dropped documents outnumber live ones. Losing it is harmless: a
+// missing journal makes EnableSearch rebuild the index from the sessions.
+type SearchIndex struct {
+	mu      sync.RWMutex
+	path    string
+	docs    []searchDoc
+	live    int
+	terms   map[string]map[int]int // term -> doc -> term frequency
+	session map[string][]int       // session ID -> docs in offset order
+}
+
+// searchDoc is one indexed message.
+type searchDoc struct {
+	SessionID string         `json:"session"`
+	AgentName string         `json:"agent"`
+	Offset    int            `json:"offset"`
+	Role      string         `json:"role"`
+	Timestamp int64          `json:"ts"`
+	Terms     map[string]int `json:"terms,omitempty"`
+
+	dropped bool
+}
+
+// searchRecord is one journal line.
+type searchRecord struct {
+	Op string `json:"op"` // "add" or "drop"
+	searchDoc
+}
+
+const (
+	searchJournalName = "journal.jsonl"
+	// searchCompactMin is the number of dropped documents below which the
+	// journal is never compacted.
+	searchCompactMin = 1000
+)
+
+// OpenSearchIndex loads the index stored in dir. The second result
+// reports whether the index was newly created and so needs building.
+func OpenSearchIndex(dir string) (*SearchIndex, bool, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, false, fmt.Errorf("create search index dir: %w", err)
+	}
+	idx := &SearchIndex{
+		path:    filepath.Join(dir, searchJournalName),
+		terms:   make(map[string]map[int]int),
+		session: make(map[string][]int),
+	}
+
+	f, err := os.Open(idx.path)
+	if os.IsNotExist(err) {
+		return idx, true, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("open search journal: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("read search journal: %w", err)
+		}
+		plain, err := storage.OpenLine(line)
+		if err != nil {
+			return nil, false, fmt.Errorf("read search journal: %w", err)
+		}
+		var rec searchRecord
+		if json.Unmarshal(plain, &rec) != nil {
+			continue
+		}
+		switch rec.Op {
+		case "add":
+			idx.insert(rec.searchDoc)
+		case "drop":
+			idx.drop(rec.SessionID)
+		}
+	}
+	return idx, false, nil
+}
+
+// Add indexes the message at offset of a session.
+func (x *SearchIndex) Add(sessionID, agentName string, offset int, msg goharnesssession.Message) error {
+	doc := newSearchDoc(sessionID, agentName, offset, msg)
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.insert(doc)
+	return x.journal(searchRecord{Op: "add", searchDoc: doc})
+}
+
+// Replace reindexes a session whose messages were rewritten; nil msgs
+// removes it from the index.
+func (x *SearchIndex) Replace(sessionID, agentName string, msgs []goharnesssession.Message) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	var buf bytes.Buffer
+	encode := func(rec searchRecord) {
+		if line, err := marshalLine(rec); err == nil {
+			buf.Write(line)
+		}
+	}
+	if len(x.session[sessionID]) > 0 {
+		x.drop(sessionID)
+		encode(searchRecord{Op: "drop", searchDoc: searchDoc{SessionID: sessionID}})
+	}
+	for i, m := range msgs {
+		doc := newSearchDoc(sessionID, agentName, i, m)
+		x.insert(doc)
+		encode(searchRecord{Op: "add", searchDoc: doc})
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+	if dead := len(x.docs) - x.live; dead >= searchCompactMin && dead > x.live {
+		return x.compact()
+	}
+	return x.appendJournal(buf.Bytes())
+}
+
+// Next returns the offset the next message of a session is indexed at.
+func (x *SearchIndex) Next(sessionID string) int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	docs := x.session[sessionID]
+	if len(docs) == 0 {
+		return 0
+	}
+	return x.docs[docs[len(docs)-1]].Offset + 1
+}
+
+// searchMatch is a document matching every term of a query.
+type searchMatch struct {
+	doc   searchDoc
+	score float64
+}
+
+// match returns the documents containing every query term that pass keep,
+// scored by tf-idf.
+func (x *SearchIndex) match(query string, keep func(*searchDoc) bool) []searchMatch {
+	terms := queryTerms(query)
+	if len(terms) == 0 {
+		return nil
+	}
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	// Walk the rarest term's postings and probe the others.
+	postings := make([]map[int]int, len(terms))
+	for i, t := range terms {
+		postings[i] = x.terms[t]
+		if len(postings[i]) == 0 {
+			return nil
+		}
+		if len(postings[i]) < len(postings[0]) {
+			postings[0], postings[i] = postings[i], postings[0]
+		}
+	}
+	var out []searchMatch
+	for id := range postings[0] {
+		doc := &x.docs[id]
+		if !keep(doc) {
+			continue
+		}
+		score := 0.0
+		for _, p := range postings {
+			tf, ok := p[id]
+			if !ok {
+				score = -1
+				break
+			}
+			score += (1 + math.Log(float64(tf))) * math.Log(1+float64(x.live)/float64(len(p)))
+		}
+		if score >= 0 {
+			out = append(out, searchMatch{doc: *doc, score: score})
+		}
+	}
+	return out
+}
+
+func (x *SearchIndex) insert(doc searchDoc) {
+	id := len(x.docs)
+	x.docs = append(x.docs, doc)
+	x.live++
+	for t, tf := range doc.Terms {
+		p := x.terms[t]
+		if p == nil {
+			p = make(map[int]int)
+			x.terms[t] = p
+		}
+		p[id] = tf
+	}
+	x.session[doc.SessionID] = append(x.session[doc.SessionID], id)
+}
+
+func (x *SearchIndex) drop(sessionID string) {
+	for _, id := range x.session[sessionID] {
+		for t := range x.docs[id].Terms {
+			if p := x.terms[t]; p != nil {
+				delete(p, id)
+				if len(p) == 0 {
+					delete(x.terms, t)
+				}
+			}
+		}
+		x.docs[id].dropped = true
+		x.live--
+	}
+	delete(x.session, sessionID)
+}
+
+func (x *SearchIndex) journal(rec searchRecord) error {
+	line, err := marshalLine(rec)
+	if err != nil {
+		return fmt.Errorf("marshal search record: %w", err)
+	}
+	return x.appendJournal(line)
+}
+
+func (x *SearchIndex) appendJournal(data []byte) error {
+	f, err := os.OpenFile(x.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("open search journal: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("append search journal: %w", err)
+	}
+	return nil
+}
+
+// compact rewrites the journal and the in-memory tables with live
+// documents only.
+func (x *SearchIndex) compact() error {
+	var buf bytes.Buffer
+	docs := x.docs
+	x.docs, x.live = nil, 0
+	x.terms = make(map[string]map[int]int)
+	x.session = make(map[string][]int)
+	for _, doc := range docs {
+		if doc.dropped {
+			continue
+		}
+		x.insert(doc)
+		line, err := marshalLine(searchRecord{Op: "add", searchDoc: doc})
+		if err != nil {
+			return fmt.Errorf("compact search journal: %w", err)
+		}
+		buf.Write(line)
+	}
+	if err := writeFileAtomic(x.path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("compact search journal: %w", err)
+	}
+	return nil
+}
+
+// Reseal rewrites the journal in the current storage form. It reports
+// whether anything changed.
+func (x *SearchIndex) Reseal() (bool, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return storage.ResealLines(x.path)
+}
+
+func newSearchDoc(sessionID, agentName string, offset int, msg goharnesssession.Message) searchDoc {
+	doc := searchDoc{
+		SessionID: sessionID,
+		AgentName: agentName,
+		Offset:    offset,
+		Role:      msg.Role,
+		Timestamp: msg.Timestamp,
+		Terms:     make(map[string]int),
+	}
+	for _, t := range indexTerms(msg.Content) {
+		doc.Terms[t]++
+	}
+	return doc
+}
+
+// indexTerms splits text into index terms: lowercased letter and digit
+// runs, and for CJK text, which has no word breaks, every character and
+// every pair of adjacent characters.
+func indexTerms(text string) []string {
+	var terms []string
+	scanTerms(text, func(word string, cjk bool) {
+		if !cjk {
+			terms = append(terms, word)
+			return
+		}
+		rs := []rune(word)
+		for i := range rs {
+			terms = append(terms, string(rs[i]))
+			if i+1 < len(rs) {
+				terms = append(terms, string(rs[i:i+2]))
+			}
+		}
+	})
+	return terms
+}
+
+// queryTerms splits a query like indexTerms, but a CJK run of two or more
+// characters only yields its character pairs.
+func queryTerms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	add := func(t string) {
+		if !seen[t] {
+			seen[t] = true
+			terms = append(terms, t)
+		}
+	}
+	scanTerms(query, func(word string, cjk bool) {
+		rs := []rune(word)
+		if !cjk || len(rs) == 1 {
+			add(word)
+			return
+		}
+		for i := 0; i+1 < len(rs); i++ {
+			add(string(rs[i : i+2]))
+		}
+	})
+	return terms
+}
+
+// scanTerms calls fn for each lowercased run of CJK characters or of other
+// letters and digits in text.
+func scanTerms(text string, fn func(word string, cjk bool)) {
+	var b strings.Builder
+	runCJK := false
+	flush := func() {
+		if b.Len() > 0 {
+			fn(b.String(), runCJK)
+			b.Reset()
+		}
+	}
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			if !runCJK {
+				flush()
+				runCJK = true
+			}
+			b.WriteRune(r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if runCJK {
+				flush()
+				runCJK = false
+			}
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			flush()
+		}
+	}
+	flush()
+}
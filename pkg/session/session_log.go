@@ -0,0 +1,325 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	goharnesssession "github.com/DotNetAge/goharness/session"
+	"github.com/DotNetAge/mindx/pkg/storage"
+)
+
+// Session messages are stored as an append-only log under
+// <session>/log: JSONL segments of one message per line, plus index.json
+// naming the live segments in order. Appending writes a single line to the
+// last segment; only Delete, Truncate and UpdateMessages rewrite the log,
+// by compacting the surviving messages into a fresh segment.
+//
+// Crash safety: a segment line is written with one append and synced, so a
+// crash can at worst leave a torn last line, which readers skip and the
+// next append truncates away. index.json is replaced atomically and is the
+// commit point of rolls, rewrites and the migration from session.yml;
+// segment files it does not name are leftovers and get removed.
+const (
+	logDirName        = "log"
+	logIndexName      = "index.json"
+	legacySessionFile = "session.yml"
+	logIndexVersion   = 1
+
+	// segmentMaxBytes is the size at which the active segment is sealed and
+	// a new one started.
+	segmentMaxBytes = 4 << 20
+	// maxSegments is the number of segments above which the log is
+	// compacted into one.
+	maxSegments = 8
+)
+
+// logIndex is the content of index.json.
+type logIndex struct {
+	Version     int      `json:"version"`
+	NextSegment int      `json:"next_segment"`
+	Segments    []string `json:"segments"`
+}
+
+func logDirPath(sessionDir string) string {
+	return filepath.Join(sessionDir, logDirName)
+}
+
+// hasSessionData reports whether dir holds a message log or a legacy
+// session.yml.
+func hasSessionData(dir string) bool {
+	if _, err := os.Stat(filepath.Join(logDirPath(dir), logIndexName)); err == nil {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(dir, legacySessionFile))
+	return err == nil
+}
+
+func (idx *logIndex) save(sessionDir string) error {
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal log index: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(logDirPath(sessionDir), logIndexName), data, 0600); err != nil {
+		return fmt.Errorf("write log index: %w", err)
+	}
+	return nil
+}
+
+func (idx *logIndex) newSegment() string {
+	idx.NextSegment++
+	return fmt.Sprintf("%06d.jsonl", idx.NextSegment)
+}
+
+// openLogIndex loads the log index of a session, migrating a legacy
+// session.yml on first use. A session without messages has an empty index.
+func openLogIndex(sessionDir string) (*logIndex, error) {
+	if err := os.MkdirAll(logDirPath(sessionDir), 0755); err != nil {
+		return nil, fmt.Errorf("create session log dir: %w", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(logDirPath(sessionDir), logIndexName))
+	switch {
+	case err == nil:
+		var idx logIndex
+		if err := json.Unmarshal(data, &idx); err != nil {
+			return nil, fmt.Errorf("parse log index: %w", err)
+		}
+		retireLegacyFile(sessionDir)
+		return &idx, nil
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("read log index: %w", err)
+	}
+
+	idx := &logIndex{Version: logIndexVersion}
+	legacy := filepath.Join(sessionDir, legacySessionFile)
+	if _, err := os.Stat(legacy); err != nil {
+		return idx, nil
+	}
+	msgs, err := parseMessagesFromFile(legacy)
+	if err != nil {
+		return nil, fmt.Errorf("migrate %s: %w", legacy, err)
+	}
+	if err := idx.rewrite(sessionDir, msgs); err != nil {
+		return nil, fmt.Errorf("migrate %s: %w", legacy, err)
+	}
+	retireLegacyFile(sessionDir)
+	return idx, nil
+}
+
+// retireLegacyFile renames a migrated session.yml out of the way. It is
+// kept, as session.yml.migrated, so a downgrade can be done by hand.
+func retireLegacyFile(sessionDir string) {
+	legacy := filepath.Join(sessionDir, legacySessionFile)
+	if _, err := os.Stat(legacy); err != nil {
+		return
+	}
+	if err := os.Rename(legacy, legacy+".migrated"); err != nil {
+		log.Printf("[WARN] session: failed to retire migrated %s: %v", legacy, err)
+	}
+}
+
+// readLog returns every message of the log in append order.
+func (idx *logIndex) readLog(sessionDir string) ([]goharnesssession.Message, error) {
+	var msgs []goharnesssession.Message
+	for _, name := range idx.Segments {
+		segMsgs, _, err := readSegment(filepath.Join(logDirPath(sessionDir), name))
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, segMsgs...)
+	}
+	return msgs, nil
+}
+
+// readSegment parses a segment and returns its messages and the length of
+// its intact prefix. A torn last line (no trailing newline) ends the
+// intact prefix; a corrupt complete line is skipped with a warning. A
+// sealed line that cannot be opened fails the read instead, so a missing
+// storage key never causes a rewrite to drop messages.
+func readSegment(path string) ([]goharnesssession.Message, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, nil
+		}
+		return nil, 0, fmt.Errorf("open session segment: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var msgs []goharnesssession.Message
+	var valid int64
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			return msgs, valid, nil
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("read session segment: %w", err)
+		}
+		valid += int64(len(line))
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		plain, err := storage.OpenLine(line)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", path, err)
+		}
+		var msg goharnesssession.Message
+		if err := json.Unmarshal(plain, &msg); err != nil {
+			log.Printf("[WARN] session: skipping corrupt line in %s: %v", path, err)
+			continue
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+// append adds one message to the active segment, sealing it and starting a
+// new one once it is full, and compacts the log once it has too many
+// segments.
+func (idx *logIndex) append(sessionDir string, msg goharnesssession.Message) error {
+	line, err := marshalLine(msg)
+	if err != nil {
+		return fmt.Errorf("marshal message: %w", err)
+	}
+
+	dir := logDirPath(sessionDir)
+	if len(idx.Segments) == 0 || segmentFull(filepath.Join(dir, idx.Segments[len(idx.Segments)-1])) {
+		idx.Segments = append(idx.Segments, idx.newSegment())
+		if err := idx.save(sessionDir); err != nil {
+			return err
+		}
+	}
+	path := filepath.Join(dir, idx.Segments[len(idx.Segments)-1])
+	if err := repairSegment(path); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("open session segment: %w", err)
+	}
+	if _, err := f.Write(line); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("append message: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("sync session segment: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close session segment: %w", err)
+	}
+
+	if len(idx.Segments) > maxSegments {
+		return idx.compact(sessionDir)
+	}
+	return nil
+}
+
+func segmentFull(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Size() >= segmentMaxBytes
+}
+
+// repairSegment truncates a torn last line left by a crash mid-append, so
+// the next line starts on a line boundary.
+func repairSegment(path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("open session segment: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+	last := make([]byte, 1)
+	if _, err := f.ReadAt(last, info.Size()-1); err != nil {
+		return fmt.Errorf("read session segment: %w", err)
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+	_, valid, err := readSegment(path)
+	if err != nil {
+		return err
+	}
+	log.Printf("[WARN] session: truncating torn line in %s (%d bytes)", path, info.Size()-valid)
+	return f.Truncate(valid)
+}
+
+// compact rewrites the log as a single segment holding its current
+// messages.
+func (idx *logIndex) compact(sessionDir string) error {
+	msgs, err := idx.readLog(sessionDir)
+	if err != nil {
+		return err
+	}
+	return idx.rewrite(sessionDir, msgs)
+}
+
+// rewrite replaces the log with msgs: they are written to a new segment,
+// index.json is switched to it, and only then are the old segments
+// removed.
+func (idx *logIndex) rewrite(sessionDir string, msgs []goharnesssession.Message) error {
+	var buf bytes.Buffer
+	for _, m := range msgs {
+		line, err := marshalLine(m)
+		if err != nil {
+			return fmt.Errorf("marshal message: %w", err)
+		}
+		buf.Write(line)
+	}
+
+	dir := logDirPath(sessionDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create session log dir: %w", err)
+	}
+	old := idx.Segments
+	name := idx.newSegment()
+	if err := writeFileAtomic(filepath.Join(dir, name), buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("write session segment: %w", err)
+	}
+	idx.Version = logIndexVersion
+	idx.Segments = []string{name}
+	if err := idx.save(sessionDir); err != nil {
+		idx.Segments = old
+		return err
+	}
+	removeStaleSegments(dir, idx.Segments)
+	return nil
+}
+
+// removeStaleSegments deletes segment files the index does not name:
+// segments replaced by a rewrite, and leftovers of one interrupted by a
+// crash.
+func removeStaleSegments(dir string, live []string) {
+	keep := make(map[string]bool, len(live))
+	for _, name := range live {
+		keep[name] = true
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".jsonl" || keep[e.Name()] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, e.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("[WARN] session: failed to remove stale segment %s: %v", e.Name(), err)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	goharnesssession "github.com/DotNetAge/goharness/session"
+	"gopkg.in/yaml.v3"
+)
+
+func appendN(t *testing.T, store *FileSessionStore, sessionID string, from, to int) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		msg := goharnesssession.Message{Role: "user", Content: fmt.Sprintf("msg-%d", i), Timestamp: int64(1000 + i)}
+		if err := store.Append(context.Background(), sessionID, "agent", "", msg); err != nil {
+			t.Fatalf("Append %d: %v", i, err)
+		}
+	}
+}
+
+func contents(msgs []goharnesssession.Message) string {
+	parts := make([]string, len(msgs))
+	for i, m := range msgs {
+		parts[i] = m.Content
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestSessionLogMigratesLegacyYAML(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "agent", "legacy")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	legacy := []goharnesssession.Message{
+		{Role: "user", Content: "aGVsbG8=", Timestamp: 1},      // "hello"
+		{Role: "assistant", Content: "d29ybGQ=", Timestamp: 2}, // "world"
+	}
+	data, _ := yaml.Marshal(legacy)
+	if err := os.WriteFile(filepath.Join(dir, legacySessionFile), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewFileSessionStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	msgs, err := store.Get(ctx, "legacy")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got := contents(msgs); got != "hello,world" {
+		t.Fatalf("migrated messages = %q, want hello,world", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, legacySessionFile)); !os.IsNotExist(err) {
+		t.Errorf("session.yml should be retired after migration")
+	}
+	if _, err := os.Stat(filepath.Join(dir, legacySessionFile+".migrated")); err != nil {
+		t.Errorf("session.yml.migrated missing: %v", err)
+	}
+
+	if err := store.Append(ctx, "legacy", "agent", "", goharnesssession.Message{Role: "user", Content: "again", Timestamp: 3}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	msgs, _ = store.Get(ctx, "legacy")
+	if got := contents(msgs); got != "hello,world,again" {
+		t.Errorf("messages after append = %q", got)
+	}
+}
+
+func TestSessionLogRecoversTornLine(t *testing.T) {
+	root := t.TempDir()
+	store, _ := NewFileSessionStore(root)
+	appendN(t, store, "torn", 0, 2)
+
+	seg := filepath.Join(root, "agent", "torn", logDirName, "000001.jsonl")
+	f, err := os.OpenFile(seg, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"role":"user","content":"half`)
+	_ = f.Close()
+
+	msgs, _ := store.Get(context.Background(), "torn")
+	if got := contents(msgs); got != "msg-0,msg-1" {
+		t.Fatalf("messages with torn tail = %q", got)
+	}
+
+	appendN(t, store, "torn", 2, 3)
+	msgs, _ = store.Get(context.Background(), "torn")
+	if got := contents(msgs); got != "msg-0,msg-1,msg-2" {
+		t.Errorf("messages after repair = %q", got)
+	}
+}
+
+func TestSessionLogRewriteAndCompact(t *testing.T) {
+	root := t.TempDir()
+	store, _ := NewFileSessionStore(root)
+	ctx := context.Background()
+	dir := filepath.Join(root, "agent", "rw")
+
+	// Force one segment per message so the log rolls and then compacts.
+	idx, err := openLogIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxSegments; i++ {
+		idx.Segments = append(idx.Segments, idx.newSegment())
+		line, _ := json.Marshal(goharnesssession.Message{Role: "user", Content: fmt.Sprintf("msg-%d", i), Timestamp: int64(1000 + i)})
+		if err := os.WriteFile(filepath.Join(logDirPath(dir), idx.Segments[i]), append(line, '\n'), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := idx.save(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveSessionMeta(dir, &goharnesssession.SessionInfo{SessionID: "rw"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(logDirPath(dir), "999999.jsonl"), []byte("stale\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Compact(ctx, "rw"); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	entries, _ := os.ReadDir(logDirPath(dir))
+	var segs []string
+	for _, e := range entries {
+		if filepath.Ext(e.Name()) == ".jsonl" {
+			segs = append(segs, e.Name())
+		}
+	}
+	if len(segs) != 1 {
+		t.Errorf("segments after compact = %v, want one", segs)
+	}
+
+	if err := store.Truncate(ctx, "rw", 3); err != nil {
+		t.Fatalf("Truncate: %v", err)
+	}
+	msgs, _ := store.Get(ctx, "rw")
+	if got := contents(msgs); got != "msg-0,msg-1,msg-2" {
+		t.Errorf("after Truncate = %q", got)
+	}
+
+	if err := store.Delete(ctx, 1001, "rw"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	msgs, _ = store.Get(ctx, "rw")
+	if got := contents(msgs); got != "msg-0,msg-2" {
+		t.Errorf("after Delete = %q", got)
+	}
+
+	if err := store.UpdateMessages(ctx, "rw", 1, msgs[1:]); err != nil {
+		t.Fatalf("UpdateMessages: %v", err)
+	}
+	msgs, _ = store.Get(ctx, "rw")
+	if got := contents(msgs); got != "msg-2" {
+		t.Errorf("after UpdateMessages = %q", got)
+	}
+	if cursor, _ := store.GetCursor(ctx, "rw"); cursor != 1 {
+		t.Errorf("cursor = %d, want 1", cursor)
+	}
+}
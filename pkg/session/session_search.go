@@ -0,0 +1,269 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	goharnesssession "github.com/DotNetAge/goharness/session"
+)
+
+// SearchQuery selects messages for FileSessionStore.Search. Every term of
+// Text must occur in a message; the other fields are optional filters.
+type SearchQuery struct {
+	Text       string
+	Agent      string
+	ProjectDir string // matches the directory and its subdirectories
+	Since      time.Time
+	Until      time.Time // exclusive
+	Roles      []string
+	Limit      int
+}
+
+// SearchHit is a message matching a SearchQuery. Offset is the message's
+// position in the session's full message list.
+type SearchHit struct {
+	SessionID  string  `json:"session_id"`
+	AgentName  string  `json:"agent_name"`
+	ProjectDir string  `json:"project_dir,omitempty"`
+	Title      string  `json:"title,omitempty"`
+	Offset     int     `json:"offset"`
+	Role       string  `json:"role"`
+	Timestamp  int64   `json:"timestamp"`
+	Score      float64 `json:"score"`
+	Snippet    string  `json:"snippet"`
+}
+
+const (
+	defaultSearchLimit = 20
+	// snippetRadius is the number of characters of context kept on each
+	// side of the first matched term.
+	snippetRadius = 60
+)
+
+// EnableSearch attaches the full-text index stored in dir, which Append
+// then maintains. A new index is built from the existing sessions.
+func (s *FileSessionStore) EnableSearch(dir string) error {
+	idx, fresh, err := OpenSearchIndex(dir)
+	if err != nil {
+		return err
+	}
+	if fresh {
+		if err := s.buildSearchIndex(idx); err != nil {
+			return err
+		}
+	}
+	s.search = idx
+	return nil
+}
+
+// buildSearchIndex indexes every session with a message log.
+func (s *FileSessionStore) buildSearchIndex(idx *SearchIndex) error {
+	agents, err := os.ReadDir(s.rootDir)
+	if err != nil {
+		return fmt.Errorf("read session root: %w", err)
+	}
+	for _, a := range agents {
+		if !a.IsDir() {
+			continue
+		}
+		sessions, err := os.ReadDir(s.agentDir(a.Name()))
+		if err != nil {
+			continue
+		}
+		for _, e := range sessions {
+			dir := s.sessionDir(a.Name(), e.Name())
+			if !e.IsDir() || !hasSessionData(dir) {
+				continue
+			}
+			unlock := s.lockSession(e.Name())
+			msgs, err := s.readMessages(dir)
+			unlock()
+			if err != nil {
+				log.Printf("[WARN] session: skipping %s while building search index: %v", dir, err)
+				continue
+			}
+			if err := idx.Replace(e.Name(), a.Name(), msgs); err != nil {
+				return fmt.Errorf("build search index: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// indexAppend indexes a message just appended to the session in dir. The
+// caller holds the session lock.
+func (s *FileSessionStore) indexAppend(dir string, msg goharnesssession.Message) {
+	if s.search == nil {
+		return
+	}
+	sessionID := filepath.Base(dir)
+	if err := s.search.Add(sessionID, sessionAgent(dir), s.search.Next(sessionID), msg); err != nil {
+		log.Printf("[WARN] session: failed to index message of %s: %v", sessionID, err)
+	}
+}
+
+// indexReplace reindexes the session in dir after its messages were
+// rewritten or removed (nil msgs). The caller holds the session lock.
+func (s *FileSessionStore) indexReplace(dir string, msgs []goharnesssession.Message) {
+	if s.search == nil {
+		return
+	}
+	sessionID := filepath.Base(dir)
+	if err := s.search.Replace(sessionID, sessionAgent(dir), msgs); err != nil {
+		log.Printf("[WARN] session: failed to reindex %s: %v", sessionID, err)
+	}
+}
+
+// sessionAgent returns the agent owning a session directory.
+func sessionAgent(dir string) string {
+	return filepath.Base(filepath.Dir(dir))
+}
+
+// Search returns the messages matching q, best match first. It fails if
+// search is not enabled.
+func (s *FileSessionStore) Search(ctx context.Context, q SearchQuery) ([]SearchHit, error) {
+	if s.search == nil {
+		return nil, fmt.Errorf("session search is not enabled")
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	roles := make(map[string]bool, len(q.Roles))
+	for _, r := range q.Roles {
+		roles[r] = true
+	}
+	since, until := q.Since.UnixMilli(), q.Until.UnixMilli()
+
+	matches := s.search.match(q.Text, func(d *searchDoc) bool {
+		return (q.Agent == "" || d.AgentName == q.Agent) &&
+			(len(roles) == 0 || roles[d.Role]) &&
+			(q.Since.IsZero() || d.Timestamp >= since) &&
+			(q.Until.IsZero() || d.Timestamp < until)
+	})
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].doc.Timestamp > matches[j].doc.Timestamp
+	})
+
+	projectDir := ""
+	if q.ProjectDir != "" {
+		projectDir = filepath.Clean(q.ProjectDir)
+	}
+	metas := make(map[string]*goharnesssession.SessionInfo)
+	logs := make(map[string][]goharnesssession.Message)
+	terms := queryTerms(q.Text)
+
+	hits := make([]SearchHit, 0, limit)
+	for _, m := range matches {
+		if len(hits) == limit {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		id := m.doc.SessionID
+		meta, ok := metas[id]
+		if !ok {
+			meta, _ = s.GetSessionMeta(id)
+			metas[id] = meta
+		}
+		if projectDir != "" && (meta == nil || !underDir(meta.ProjectDir, projectDir)) {
+			continue
+		}
+		msgs, ok := logs[id]
+		if !ok {
+			msgs = s.logMessages(id)
+			logs[id] = msgs
+		}
+		if m.doc.Offset >= len(msgs) {
+			continue
+		}
+
+		hit := SearchHit{
+			SessionID: id,
+			AgentName: m.doc.AgentName,
+			Offset:    m.doc.Offset,
+			Role:      m.doc.Role,
+			Timestamp: m.doc.Timestamp,
+			Score:     m.score,
+			Snippet:   snippet(msgs[m.doc.Offset].Content, terms),
+		}
+		if meta != nil {
+			hit.ProjectDir = meta.ProjectDir
+			hit.Title = meta.Title
+		}
+		hits = append(hits, hit)
+	}
+	return hits, nil
+}
+
+// logMessages returns a session's messages in log order, the order search
+// offsets refer to.
+func (s *FileSessionStore) logMessages(sessionID string) []goharnesssession.Message {
+	defer s.lockSession(sessionID)()
+	dir := s.findSessionDir(sessionID)
+	if dir == "" || !hasSessionData(dir) {
+		return nil
+	}
+	msgs, err := s.readMessages(dir)
+	if err != nil {
+		log.Printf("[WARN] session: failed to read session log %s: %v", dir, err)
+	}
+	return msgs
+}
+
+// underDir reports whether path is dir or lies below it.
+func underDir(path, dir string) bool {
+	path = filepath.Clean(path)
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// snippet cuts the part of text around the first occurrence of any term,
+// collapsing whitespace and marking cut ends with "…".
+func snippet(text string, terms []string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	lower := strings.ToLower(text)
+	at := -1
+	for _, t := range terms {
+		if i := strings.Index(lower, t); i >= 0 && (at < 0 || i < at) {
+			at = i
+		}
+	}
+	if at < 0 || len(lower) != len(text) {
+		// Lowercasing changed byte offsets; fall back to the head.
+		at = 0
+	}
+
+	runes := []rune(text)
+	center := utf8.RuneCountInString(text[:at])
+	start, end := center-snippetRadius, center+snippetRadius
+	if start < 0 {
+		end -= start
+		start = 0
+	}
+	if end > len(runes) {
+		start -= end - len(runes)
+		end = len(runes)
+		if start < 0 {
+			start = 0
+		}
+	}
+	out := string(runes[start:end])
+	if start > 0 {
+		out = "…" + out
+	}
+	if end < len(runes) {
+		out += "…"
+	}
+	return out
+}
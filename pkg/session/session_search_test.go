@@ -0,0 +1,174 @@
+package session
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	goharnesssession "github.com/DotNetAge/goharness/session"
+)
+
+func newSearchStore(t *testing.T, root, indexDir string) *FileSessionStore {
+	t.Helper()
+	store, err := NewFileSessionStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.EnableSearch(indexDir); err != nil {
+		t.Fatalf("EnableSearch: %v", err)
+	}
+	return store
+}
+
+func seedSearch(t *testing.T, store *FileSessionStore) {
+	t.Helper()
+	ctx := context.Background()
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC).UnixMilli()
+	if err := SaveSessionMeta(filepath.Join(store.rootDir, "coder", "s1"), &goharnesssession.SessionInfo{
+		SessionID: "s1", AgentName: "coder", ProjectDir: "/work/api",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	msgs := []struct {
+		agent, session, role, content string
+		day                           int
+	}{
+		{"coder", "s1", "user", "Should we use Postgres or SQLite for the queue?", 0},
+		{"coder", "s1", "assistant", "We decided to use SQLite: the queue is local and small.", 0},
+		{"coder", "s1", "user", "ok, ship it", 0},
+		{"writer", "s2", "user", "我们决定使用 SQLite 作为本地存储", 5},
+		{"writer", "s2", "assistant", "好的，已记录这个决定。", 5},
+	}
+	for i, m := range msgs {
+		ts := base + int64(m.day)*24*3600*1000 + int64(i)
+		if err := store.Append(ctx, m.session, m.agent, "", goharnesssession.Message{Role: m.role, Content: m.content, Timestamp: ts}); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+}
+
+func hitKeys(hits []SearchHit) string {
+	keys := make([]string, len(hits))
+	for i, h := range hits {
+		keys[i] = h.SessionID + "#" + strconv.Itoa(h.Offset)
+	}
+	return strings.Join(keys, ",")
+}
+
+func TestSessionSearch(t *testing.T) {
+	root := t.TempDir()
+	store := newSearchStore(t, root, t.TempDir())
+	seedSearch(t, store)
+	ctx := context.Background()
+
+	hits, err := store.Search(ctx, SearchQuery{Text: "decided sqlite"})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if hitKeys(hits) != "s1#1" {
+		t.Fatalf("hits = %s, want s1#1", hitKeys(hits))
+	}
+	if h := hits[0]; h.AgentName != "coder" || h.Role != "assistant" || h.ProjectDir != "/work/api" || !strings.Contains(h.Snippet, "decided to use SQLite") {
+		t.Errorf("hit = %+v", h)
+	}
+
+	tests := []struct {
+		name string
+		q    SearchQuery
+		want string
+	}{
+		{"cjk phrase", SearchQuery{Text: "决定"}, "s2#0,s2#1"},
+		{"cjk single char", SearchQuery{Text: "录"}, "s2#1"},
+		{"agent", SearchQuery{Text: "sqlite", Agent: "writer"}, "s2#0"},
+		{"role", SearchQuery{Text: "sqlite", Roles: []string{"user"}, Agent: "coder"}, "s1#0"},
+		{"project dir", SearchQuery{Text: "sqlite", ProjectDir: "/work"}, "s1#0,s1#1"},
+		{"since", SearchQuery{Text: "sqlite", Since: time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC)}, "s2#0"},
+		{"until", SearchQuery{Text: "sqlite", Until: time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC)}, "s1#0,s1#1"},
+		{"no match", SearchQuery{Text: "mysql"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, err := store.Search(ctx, tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := strings.Split(hitKeys(hits), ",")
+			want := strings.Split(tt.want, ",")
+			if len(got) != len(want) {
+				t.Fatalf("hits = %v, want %v", got, want)
+			}
+			seen := make(map[string]bool)
+			for _, k := range got {
+				seen[k] = true
+			}
+			for _, k := range want {
+				if !seen[k] {
+					t.Errorf("hits = %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSessionSearchPersistsAndReindexes(t *testing.T) {
+	root, indexDir := t.TempDir(), t.TempDir()
+	store := newSearchStore(t, root, indexDir)
+	seedSearch(t, store)
+	ctx := context.Background()
+
+	if err := store.Truncate(ctx, "s1", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeleteSession(ctx, "s2"); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := newSearchStore(t, root, indexDir)
+	hits, err := reopened.Search(ctx, SearchQuery{Text: "sqlite"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hitKeys(hits) != "s1#0" {
+		t.Errorf("hits after reopen = %s, want s1#0", hitKeys(hits))
+	}
+
+	if err := reopened.Append(ctx, "s1", "coder", "", goharnesssession.Message{Role: "assistant", Content: "SQLite it is"}); err != nil {
+		t.Fatal(err)
+	}
+	hits, _ = reopened.Search(ctx, SearchQuery{Text: "sqlite", Roles: []string{"assistant"}})
+	if hitKeys(hits) != "s1#1" {
+		t.Errorf("hits after append = %s, want s1#1", hitKeys(hits))
+	}
+}
+
+func TestSessionSearchBuildsFromExistingSessions(t *testing.T) {
+	root := t.TempDir()
+	plain, err := NewFileSessionStore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seedSearch(t, plain)
+
+	store := newSearchStore(t, root, t.TempDir())
+	hits, err := store.Search(context.Background(), SearchQuery{Text: "postgres"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hitKeys(hits) != "s1#0" {
+		t.Errorf("hits = %s, want s1#0", hitKeys(hits))
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	text := strings.Repeat("lorem ", 30) + "the DECISION was made\n\nhere " + strings.Repeat("ipsum ", 30)
+	got := snippet(text, []string{"decision"})
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") || !strings.Contains(got, "the DECISION was made here") {
+		t.Errorf("snippet = %q", got)
+	}
+	if got := snippet("short text", []string{"text"}); got != "short text" {
+		t.Errorf("snippet = %q, want whole text", got)
+	}
+}
@@ -0,0 +1,139 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// calibrationMinTokens is the smallest estimate a sample is taken
+	// from; below it per-message overhead dominates the ratio.
+	calibrationMinTokens = 200
+	// calibrationMinSamples is how many samples a family needs before its
+	// factor is applied.
+	calibrationMinSamples = 3
+	// calibrationAlpha weights a new sample in the moving average.
+	calibrationAlpha = 0.1
+	// calibrationMaxFactor bounds the factor to [1/max, max], so one
+	// outlier cannot make estimates useless.
+	calibrationMaxFactor = 4.0
+)
+
+// CalibrationState is what a family's calibration has learned so far.
+type CalibrationState struct {
+	Factor    float64   `json:"factor"`
+	Samples   int       `json:"samples"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TokenCalibration learns, per model family, a correction factor for a
+// TokenEstimator: the moving average of actual prompt tokens reported by
+// the provider over the estimate for the same text. It is persisted as
+// JSON so the factor survives restarts.
+type TokenCalibration struct {
+	path string
+
+	mu       sync.Mutex
+	families map[string]*CalibrationState
+}
+
+// NewTokenCalibration loads the calibration stored at path. A missing file
+// starts empty.
+func NewTokenCalibration(path string) (*TokenCalibration, error) {
+	c := &TokenCalibration{path: path, families: make(map[string]*CalibrationState)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+		return nil, fmt.Errorf("read token calibration: %w", err)
+	}
+	if err := json.Unmarshal(data, &c.families); err != nil {
+		return nil, fmt.Errorf("parse token calibration: %w", err)
+	}
+	return c, nil
+}
+
+// Observe records that text estimated at estimated tokens was billed as
+// actual prompt tokens. Samples too small to be meaningful are ignored.
+func (c *TokenCalibration) Observe(family string, estimated, actual int) error {
+	if estimated < calibrationMinTokens || actual <= 0 {
+		return nil
+	}
+	ratio := math.Min(math.Max(float64(actual)/float64(estimated), 1/calibrationMaxFactor), calibrationMaxFactor)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := c.families[family]
+	if st == nil {
+		st = &CalibrationState{Factor: ratio}
+		c.families[family] = st
+	} else {
+		st.Factor += calibrationAlpha * (ratio - st.Factor)
+	}
+	st.Samples++
+	st.UpdatedAt = time.Now()
+	return c.saveLocked()
+}
+
+// Factor returns the correction factor of a family, or 1 until it has
+// enough samples.
+func (c *TokenCalibration) Factor(family string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if st := c.families[family]; st != nil && st.Samples >= calibrationMinSamples {
+		return st.Factor
+	}
+	return 1
+}
+
+// States returns a copy of every family's calibration.
+func (c *TokenCalibration) States() map[string]CalibrationState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string]CalibrationState, len(c.families))
+	for k, v := range c.families {
+		out[k] = *v
+	}
+	return out
+}
+
+// Estimator wraps inner so its estimates are scaled by the family's
+// current factor.
+func (c *TokenCalibration) Estimator(family string, inner TokenEstimator) TokenEstimator {
+	return &calibratedEstimator{cal: c, family: family, inner: inner}
+}
+
+func (c *TokenCalibration) saveLocked() error {
+	data, err := json.MarshalIndent(c.families, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal token calibration: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("create token calibration dir: %w", err)
+	}
+	tmpPath := c.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("write token calibration: %w", err)
+	}
+	return os.Rename(tmpPath, c.path)
+}
+
+type calibratedEstimator struct {
+	cal    *TokenCalibration
+	family string
+	inner  TokenEstimator
+}
+
+func (e *calibratedEstimator) Estimate(text string) int {
+	n := e.inner.Estimate(text)
+	if n == 0 {
+		return 0
+	}
+	return int(math.Round(float64(n) * e.cal.Factor(e.family)))
+}
@@ -1,5 +1,10 @@
 package session
 
+import (
+	"math"
+	"unicode"
+)
+
 // TokenEstimator estimates token counts for text content.
 type TokenEstimator interface {
 	Estimate(text string) int
@@ -16,3 +21,56 @@ func (e *charCountEstimator) Estimate(text string) int {
 	// Rough estimate: ~4 characters per token for Chinese/English mixed text.
 	return len(text) / 4
 }
+
+// cjkTokensPerRune is the average cost of a Han, Kana or Hangul character
+// in the common BPE vocabularies, which mostly split them into one or two
+// tokens.
+const cjkTokensPerRune = 1.25
+
+// NewHeuristicEstimator creates a script-aware estimator for models without
+// a BPE vocabulary. Unlike the char-count estimator it charges CJK text per
+// character rather than per UTF-8 byte, and punctuation and line breaks —
+// dense in code — as a token each.
+func NewHeuristicEstimator() TokenEstimator {
+	return heuristicEstimator{}
+}
+
+type heuristicEstimator struct{}
+
+func (heuristicEstimator) Estimate(text string) int {
+	var tokens float64
+	word := 0 // UTF-8 bytes of the current letter/digit run
+	flush := func() {
+		if word > 0 {
+			tokens += math.Ceil(float64(word) / 4)
+			word = 0
+		}
+	}
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flush()
+			tokens += cjkTokensPerRune
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word += len(string(r))
+		case r == '\n':
+			flush()
+			tokens++
+		case unicode.IsSpace(r):
+			// A space merges into the token that follows it.
+			flush()
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+	return int(math.Ceil(tokens))
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r)
+}
@@ -0,0 +1,170 @@
+package session
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/rand/v2"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testVocabulary builds a tiktoken-format vocabulary: every single byte,
+// then the given merges in rank order.
+func testVocabulary(merges ...string) string {
+	var b strings.Builder
+	rank := 0
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), rank)
+		rank++
+	}
+	for _, m := range merges {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(m)), rank)
+		rank++
+	}
+	return b.String()
+}
+
+func TestBPEEncodingMergesByRank(t *testing.T) {
+	enc, err := ReadBPE("test", strings.NewReader(testVocabulary("he", "ll", "hell", "hello", " w", "or", " wor", "ld", " world")))
+	if err != nil {
+		t.Fatalf("ReadBPE: %v", err)
+	}
+
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"hello", 1},
+		{"hello world", 2},
+		{"help", 3}, // "he" "l" "p"
+		// "hello", "  ", "world"; without the leading space "world"
+		// only merges to "w" "or" "ld".
+		{"hello  world", 6},
+		{"", 0},
+	}
+	for _, tc := range tests {
+		if got := enc.Estimate(tc.text); got != tc.want {
+			t.Errorf("Estimate(%q) = %d, want %d", tc.text, got, tc.want)
+		}
+	}
+
+	// Cached pieces count the same; " hello" has no merge for its
+	// leading space and costs two tokens.
+	if got := enc.Estimate("hello world hello world"); got != 5 {
+		t.Errorf("Estimate(repeated) = %d, want 5", got)
+	}
+}
+
+func TestBPEMergeCountMatchesScan(t *testing.T) {
+	enc, err := ReadBPE("test", strings.NewReader(testVocabulary("ab", "ba", "aa", "abab", "aab", "bab", "aaaa")))
+	if err != nil {
+		t.Fatalf("ReadBPE: %v", err)
+	}
+	// scan is the plain algorithm: merge the leftmost lowest-ranked pair
+	// until none is left.
+	scan := func(piece string) int {
+		bounds := make([]int, len(piece)+1)
+		for i := range bounds {
+			bounds[i] = i
+		}
+		for len(bounds) > 2 {
+			best, bestRank := -1, 0
+			for i := 0; i+2 < len(bounds); i++ {
+				if rank, ok := enc.ranks[piece[bounds[i]:bounds[i+2]]]; ok && (best < 0 || rank < bestRank) {
+					best, bestRank = i, rank
+				}
+			}
+			if best < 0 {
+				break
+			}
+			bounds = append(bounds[:best+1], bounds[best+2:]...)
+		}
+		return len(bounds) - 1
+	}
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	for range 500 {
+		b := make([]byte, rng.IntN(24))
+		for i := range b {
+			b[i] = "abc"[rng.IntN(3)]
+		}
+		if got, want := enc.mergeCount(string(b)), scan(string(b)); got != want {
+			t.Fatalf("mergeCount(%q) = %d, want %d", b, got, want)
+		}
+	}
+
+	// A long piece, such as a run of one character, stays fast.
+	if got := enc.mergeCount(strings.Repeat("a", 1<<16)); got != 1<<14 {
+		t.Errorf("mergeCount(long run) = %d, want %d", got, 1<<14)
+	}
+}
+
+func TestReadBPERejectsMalformedLines(t *testing.T) {
+	if _, err := ReadBPE("bad", strings.NewReader("aGk=\n")); err == nil {
+		t.Error("missing rank should fail")
+	}
+	if _, err := ReadBPE("bad", strings.NewReader("")); err == nil {
+		t.Error("empty vocabulary should fail")
+	}
+}
+
+func TestHeuristicEstimatorChargesCJKPerCharacter(t *testing.T) {
+	est := NewHeuristicEstimator()
+
+	// 12 Han characters are 36 UTF-8 bytes: len/4 says 9 tokens, BPE
+	// vocabularies produce 12 or more.
+	zh := "今天天气很好我们去公园散步"
+	if got := est.Estimate(zh); got < 12 {
+		t.Errorf("Estimate(zh) = %d, want >= 12", got)
+	}
+	if got := est.Estimate("hello world"); got != 4 {
+		t.Errorf("Estimate(en) = %d, want 4", got)
+	}
+	// Punctuation and line breaks are a token each.
+	if got := est.Estimate("f(x);\n"); got != 6 {
+		t.Errorf("Estimate(code) = %d, want 6", got)
+	}
+}
+
+func TestTokenCalibrationLearnsFactor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calibration.json")
+	cal, err := NewTokenCalibration(path)
+	if err != nil {
+		t.Fatalf("NewTokenCalibration: %v", err)
+	}
+
+	if err := cal.Observe("qwen", 50, 100); err != nil {
+		t.Fatalf("Observe: %v", err)
+	}
+	if n := cal.States()["qwen"].Samples; n != 0 {
+		t.Errorf("small sample recorded: %d samples", n)
+	}
+
+	for i := 0; i < calibrationMinSamples-1; i++ {
+		_ = cal.Observe("qwen", 1000, 2000)
+	}
+	if f := cal.Factor("qwen"); f != 1 {
+		t.Errorf("factor before enough samples = %v, want 1", f)
+	}
+	_ = cal.Observe("qwen", 1000, 2000)
+	if f := cal.Factor("qwen"); f != 2 {
+		t.Errorf("factor = %v, want 2", f)
+	}
+
+	est := cal.Estimator("qwen", NewTokenEstimator())
+	if got := est.Estimate(strings.Repeat("a", 400)); got != 200 {
+		t.Errorf("calibrated Estimate = %d, want 200", got)
+	}
+
+	reloaded, err := NewTokenCalibration(path)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if f := reloaded.Factor("qwen"); f != 2 {
+		t.Errorf("reloaded factor = %v, want 2", f)
+	}
+	if f := reloaded.Factor("gpt"); f != 1 {
+		t.Errorf("unknown family factor = %v, want 1", f)
+	}
+}
@@ -2,7 +2,7 @@ package session
 
 // UsageSource identifies where a token usage record originated.
 //
-// Sources are stored alongside the record in token_usages.yml and
+// Sources are stored alongside the record in the token usage log and
 // exposed via the token.usage.overview RPC response.
 type UsageSource string
 
@@ -15,4 +15,12 @@ const (
 
 	// UsageSourceTranslation indicates the token usage came from translation.
 	UsageSourceTranslation UsageSource = "translation"
+
+	// UsageSourceMemory indicates the token usage came from memory
+	// consolidation.
+	UsageSourceMemory UsageSource = "memory"
+
+	// UsageSourceImport marks usage that came with an imported session. It
+	// was spent elsewhere, so budgets do not count it.
+	UsageSourceImport UsageSource = "import"
 )
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ── keyring shared between processes ─────────────────────────
+
+// The daemon, the TUI and the CLI each hold a keyring for the same data
+// directory. A rekey in one of them publishes its new primary key ID to
+// the primary file of the shared storage directory; the others reload
+// their keyring before sealing once it changes, and when they meet data
+// sealed with a key they do not hold yet.
+
+// primaryFile is the file in the storage directory naming the primary
+// key ID.
+const primaryFile = "primary"
+
+// clientsDir is the directory in the storage directory holding one lease
+// file per process attached to the data.
+const clientsDir = "clients"
+
+// AttachInterval is how often an attached process renews its lease; a
+// lease not renewed for three intervals is stale.
+const AttachInterval = 10 * time.Second
+
+type watcher struct {
+	dir    string
+	reload func() (*Keyring, error)
+
+	mu    sync.Mutex
+	mtime time.Time
+	size  int64
+}
+
+var watching atomic.Pointer[watcher]
+
+// attached holds the storage directories the process has attached to.
+var attached sync.Map
+
+// Watch makes the process keyring follow key rotations published to the
+// storage directory dir: reload returns the keyring from where the keys
+// are kept. It replaces any earlier Watch; a nil reload stops watching.
+func Watch(dir string, reload func() (*Keyring, error)) {
+	if reload == nil {
+		watching.Store(nil)
+		return
+	}
+	w := &watcher{dir: dir, reload: reload}
+	if info, err := os.Stat(filepath.Join(dir, primaryFile)); err == nil {
+		w.mtime, w.size = info.ModTime(), info.Size()
+	}
+	watching.Store(w)
+}
+
+// Publish records primary as the primary key ID of the storage directory
+// dir, for the processes watching it.
+func Publish(dir, primary string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	return writeAtomic(filepath.Join(dir, primaryFile), []byte(primary+"\n"), 0600)
+}
+
+// sealing returns the state new data is sealed with, after picking up a
+// primary key published by another process.
+func sealing() state {
+	if w := watching.Load(); w != nil {
+		w.follow()
+	}
+	return current()
+}
+
+// follow reloads the keyring when the published primary key differs from
+// the one in use.
+func (w *watcher) follow() {
+	path := filepath.Join(w.dir, primaryFile)
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if info.ModTime().Equal(w.mtime) && info.Size() == w.size {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	w.mtime, w.size = info.ModTime(), info.Size()
+	primary := string(bytes.TrimSpace(data))
+	if ring := current().ring; ring != nil && ring.Primary() == primary {
+		return
+	}
+	w.load()
+}
+
+// load installs the keyring reload returns. Caller holds mu.
+func (w *watcher) load() bool {
+	ring, err := w.reload()
+	if err != nil || ring == nil {
+		return false
+	}
+	Use(ring, current().want)
+	return true
+}
+
+// reloadRing reloads the watched keyring, reporting whether one was
+// installed.
+func reloadRing() bool {
+	w := watching.Load()
+	if w == nil {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.load()
+}
+
+// Attach registers the process as using the data of the storage
+// directory dir until it exits, renewing its lease every AttachInterval.
+// A rekey refuses to run while other processes are attached.
+func Attach(dir string) error {
+	dir = filepath.Join(dir, clientsDir)
+	if _, loaded := attached.LoadOrStore(dir, true); loaded {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		attached.Delete(dir)
+		return err
+	}
+	lease := filepath.Join(dir, strconv.Itoa(os.Getpid()))
+	if err := os.WriteFile(lease, nil, 0600); err != nil {
+		return err
+	}
+	go func() {
+		for range time.Tick(AttachInterval) {
+			now := time.Now()
+			if err := os.Chtimes(lease, now, now); os.IsNotExist(err) {
+				_ = os.WriteFile(lease, nil, 0600)
+			}
+		}
+	}()
+	return nil
+}
+
+// Attached returns the IDs of the other processes attached to the
+// storage directory dir, removing stale leases.
+func Attached(dir string) ([]int, error) {
+	dir = filepath.Join(dir, clientsDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("storage: list attached processes: %w", err)
+	}
+	self := os.Getpid()
+	var pids []int
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || pid == self {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		if time.Since(info.ModTime()) > 3*AttachInterval {
+			_ = os.Remove(filepath.Join(dir, e.Name()))
+			continue
+		}
+		pids = append(pids, pid)
+	}
+	return pids, nil
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWatchFollowsRekey(t *testing.T) {
+	dir := t.TempDir()
+	t.Cleanup(func() { Watch("", nil) })
+
+	// The keys as kept in the credential store, shared by both processes.
+	keys := map[string][]byte{}
+	add := func(id string) {
+		key, err := GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[id] = key
+	}
+	primary := "k1"
+	add(primary)
+	load := func() (*Keyring, error) { return NewKeyring(primary, keys) }
+
+	// This process, a client, and the daemon both start on k1.
+	ring, _ := load()
+	use(t, ring, true)
+	Watch(dir, load)
+	daemon, _ := load()
+
+	before, err := Seal([]byte("before"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The daemon rekeys: k2 becomes primary and k1 is kept to decrypt.
+	add("k2")
+	primary = "k2"
+	daemon, _ = load()
+	if err := Publish(dir, primary); err != nil {
+		t.Fatal(err)
+	}
+	fromDaemon, err := daemon.Seal([]byte("from daemon"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := Open(fromDaemon); err != nil || string(got) != "from daemon" {
+		t.Errorf("client Open of data sealed with the new key = %q, %v", got, err)
+	}
+	after, err := Seal([]byte("after"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _, _ := splitHeader(after); id != "k2" {
+		t.Errorf("client seals with %q after the rekey, want k2", id)
+	}
+	for _, sealed := range [][]byte{before, after} {
+		if _, err := daemon.Open(sealed); err != nil {
+			t.Errorf("daemon Open of client data: %v", err)
+		}
+	}
+}
+
+func TestOpenReloadsOnUnknownKey(t *testing.T) {
+	t.Cleanup(func() { Watch("", nil) })
+	other := testRing(t, "k9", "k9")
+	sealed, _ := other.Seal([]byte("x"))
+
+	use(t, testRing(t, "k1", "k1"), true)
+	if _, err := Open(sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("Open without a watcher = %v, want ErrUnknownKey", err)
+	}
+	Watch(t.TempDir(), func() (*Keyring, error) { return other, nil })
+	if got, err := Open(sealed); err != nil || !bytes.Equal(got, []byte("x")) {
+		t.Errorf("Open after reload = %q, %v", got, err)
+	}
+}
+
+func TestAttached(t *testing.T) {
+	dir := t.TempDir()
+	if err := Attach(dir); err != nil {
+		t.Fatal(err)
+	}
+	leases := filepath.Join(dir, clientsDir)
+	for _, pid := range []int{1 << 20, 1<<20 + 1} {
+		if err := os.WriteFile(filepath.Join(leases, strconv.Itoa(pid)), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stale := time.Now().Add(-4 * AttachInterval)
+	_ = os.Chtimes(filepath.Join(leases, strconv.Itoa(1<<20+1)), stale, stale)
+
+	pids, err := Attached(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pids) != 1 || pids[0] != 1<<20 {
+		t.Errorf("Attached = %v, want only the live other process", pids)
+	}
+	if _, err := os.Stat(filepath.Join(leases, strconv.Itoa(1<<20+1))); !os.IsNotExist(err) {
+		t.Error("stale lease not removed")
+	}
+}
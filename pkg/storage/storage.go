@@ -0,0 +1,428 @@
+// Package storage seals mindx data at rest with AES-256-GCM.
+//
+// Whole files are sealed as
+//
+//	"\x00MXS\x01" | key ID length (1 byte) | key ID | nonce (12 bytes) | ciphertext
+//
+// with the header as additional data, and JSONL lines as "mxs1:" followed
+// by the base64 of a sealed blob, so append-only logs stay line-oriented
+// and sealed and plain lines can sit in the same file. Data without the
+// marker is plaintext and passes through Open unchanged, which lets
+// encryption be turned on for an existing data directory: new writes are
+// sealed and a rekey seals the rest.
+//
+// The active keyring is process-wide and set at startup with Use; stores
+// call Seal, Open, SealLine and OpenLine around their own I/O. Processes
+// sharing a data directory follow each other's key rotations through
+// Watch and Publish.
+package storage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+)
+
+// KeySize is the size of a data key: AES-256.
+const KeySize = 32
+
+var (
+	fileMagic  = []byte("\x00MXS\x01")
+	linePrefix = []byte("mxs1:")
+)
+
+var (
+	// ErrNoKey is returned when opening sealed data without a keyring.
+	ErrNoKey = errors.New("storage: data is encrypted but no storage key is available")
+	// ErrUnknownKey is returned when sealed data names a key the keyring
+	// does not hold.
+	ErrUnknownKey = errors.New("storage: data is encrypted with an unknown storage key")
+)
+
+// Keyring holds the data keys by ID. New data is sealed with the primary
+// key; data sealed with any of the keys can be opened, so retired keys are
+// kept until a rekey has resealed everything.
+type Keyring struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// NewKeyring returns a keyring of keys, sealing with keys[primary].
+func NewKeyring(primary string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[primary]; !ok {
+		return nil, fmt.Errorf("storage: primary key %q not in keyring", primary)
+	}
+	k := &Keyring{primary: primary, keys: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		if id == "" || len(id) > 255 {
+			return nil, fmt.Errorf("storage: invalid key ID %q", id)
+		}
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("storage: key %q is %d bytes, want %d", id, len(key), KeySize)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("storage: key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("storage: key %q: %w", id, err)
+		}
+		k.keys[id] = aead
+	}
+	return k, nil
+}
+
+// GenerateKey returns a new random data key.
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("storage: generate key: %w", err)
+	}
+	return key, nil
+}
+
+// Primary returns the ID of the key new data is sealed with.
+func (k *Keyring) Primary() string {
+	return k.primary
+}
+
+// IDs returns the IDs of all keys, sorted.
+func (k *Keyring) IDs() []string {
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Seal encrypts plaintext with the primary key.
+func (k *Keyring) Seal(plaintext []byte) ([]byte, error) {
+	aead := k.keys[k.primary]
+	header := make([]byte, 0, len(fileMagic)+1+len(k.primary))
+	header = append(header, fileMagic...)
+	header = append(header, byte(len(k.primary)))
+	header = append(header, k.primary...)
+
+	out := make([]byte, len(header)+aead.NonceSize(), len(header)+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	copy(out, header)
+	if _, err := io.ReadFull(rand.Reader, out[len(header):]); err != nil {
+		return nil, fmt.Errorf("storage: generate nonce: %w", err)
+	}
+	return aead.Seal(out, out[len(header):], plaintext, header), nil
+}
+
+// Open decrypts data sealed with any key of the keyring.
+func (k *Keyring) Open(data []byte) ([]byte, error) {
+	id, rest, ok := splitHeader(data)
+	if !ok {
+		return nil, errors.New("storage: malformed sealed data")
+	}
+	aead, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	if len(rest) < aead.NonceSize() {
+		return nil, errors.New("storage: malformed sealed data")
+	}
+	header := data[:len(data)-len(rest)]
+	plaintext, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], header)
+	if err != nil {
+		return nil, fmt.Errorf("storage: decrypt with key %q: %w", id, err)
+	}
+	return plaintext, nil
+}
+
+// splitHeader returns the key ID of sealed data and what follows the
+// header.
+func splitHeader(data []byte) (id string, rest []byte, ok bool) {
+	if !IsSealed(data) || len(data) < len(fileMagic)+1 {
+		return "", nil, false
+	}
+	n := int(data[len(fileMagic)])
+	start := len(fileMagic) + 1
+	if len(data) < start+n {
+		return "", nil, false
+	}
+	return string(data[start : start+n]), data[start+n:], true
+}
+
+// IsSealed reports whether data is a sealed blob.
+func IsSealed(data []byte) bool {
+	return bytes.HasPrefix(data, fileMagic)
+}
+
+// IsSealedLine reports whether line is a sealed JSONL line.
+func IsSealedLine(line []byte) bool {
+	return bytes.HasPrefix(line, linePrefix)
+}
+
+// ── process-wide keyring ─────────────────────────────────────
+
+type state struct {
+	ring    *Keyring
+	encrypt bool
+	// want is the encrypt setting Use was given, kept for a keyring
+	// reloaded by Watch.
+	want bool
+}
+
+var active atomic.Pointer[state]
+
+// Use installs ring as the process keyring. With encrypt set new data is
+// sealed; otherwise it is written in plaintext and ring only opens data
+// sealed earlier. A nil ring turns sealing off and leaves sealed data
+// unreadable.
+func Use(ring *Keyring, encrypt bool) {
+	active.Store(&state{ring: ring, encrypt: encrypt && ring != nil, want: encrypt})
+}
+
+func current() state {
+	if s := active.Load(); s != nil {
+		return *s
+	}
+	return state{}
+}
+
+// Active returns the process keyring, or nil.
+func Active() *Keyring {
+	return current().ring
+}
+
+// Encrypting reports whether new data is sealed.
+func Encrypting() bool {
+	return current().encrypt
+}
+
+// Seal seals data when encryption is on and returns it unchanged
+// otherwise.
+func Seal(data []byte) ([]byte, error) {
+	s := sealing()
+	if !s.encrypt {
+		return data, nil
+	}
+	return s.ring.Seal(data)
+}
+
+// Open returns the plaintext of data, sealed or not.
+func Open(data []byte) ([]byte, error) {
+	if !IsSealed(data) {
+		return data, nil
+	}
+	ring := current().ring
+	if ring == nil {
+		if !reloadRing() {
+			return nil, ErrNoKey
+		}
+		ring = current().ring
+	}
+	plain, err := ring.Open(data)
+	if errors.Is(err, ErrUnknownKey) && reloadRing() {
+		// Sealed by another process with a key added since.
+		return current().ring.Open(data)
+	}
+	return plain, err
+}
+
+// SealLine seals one JSONL line (without its newline) when encryption is
+// on.
+func SealLine(line []byte) ([]byte, error) {
+	s := sealing()
+	if !s.encrypt {
+		return line, nil
+	}
+	sealed, err := s.ring.Seal(line)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(linePrefix)+base64.StdEncoding.EncodedLen(len(sealed)))
+	copy(out, linePrefix)
+	base64.StdEncoding.Encode(out[len(linePrefix):], sealed)
+	return out, nil
+}
+
+// OpenLine returns the plaintext of a JSONL line, sealed or not. A
+// plaintext line is returned unchanged; white space around a sealed one,
+// its newline included, is ignored.
+func OpenLine(line []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(line)
+	if !IsSealedLine(trimmed) {
+		return line, nil
+	}
+	sealed, err := decodeLine(trimmed)
+	if err != nil {
+		return nil, err
+	}
+	return Open(sealed)
+}
+
+func decodeLine(line []byte) ([]byte, error) {
+	sealed := make([]byte, base64.StdEncoding.DecodedLen(len(line)-len(linePrefix)))
+	n, err := base64.StdEncoding.Decode(sealed, line[len(linePrefix):])
+	if err != nil {
+		return nil, fmt.Errorf("storage: malformed sealed line: %w", err)
+	}
+	return sealed[:n], nil
+}
+
+// SealText seals a string field as text, in the line form, when
+// encryption is on.
+func SealText(s string) (string, error) {
+	out, err := SealLine([]byte(s))
+	return string(out), err
+}
+
+// OpenText returns the plaintext of a string sealed by SealText, or s
+// itself when it is not sealed.
+func OpenText(s string) (string, error) {
+	out, err := OpenLine([]byte(s))
+	return string(out), err
+}
+
+// ReadFile reads path and opens its content.
+func ReadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err = Open(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return data, nil
+}
+
+// Current reports whether data, a whole file or a single line, is already
+// in the form new writes take: sealed with the primary key when
+// encryption is on, plaintext when it is off. A rekey rewrites data that
+// is not.
+func Current(data []byte) bool {
+	if trimmed := bytes.TrimSpace(data); IsSealedLine(trimmed) {
+		sealed, err := decodeLine(trimmed)
+		if err != nil {
+			return false
+		}
+		data = sealed
+	}
+	s := current()
+	if !IsSealed(data) {
+		return !s.encrypt
+	}
+	id, _, ok := splitHeader(data)
+	return ok && s.encrypt && id == s.ring.Primary()
+}
+
+// ── rekey ────────────────────────────────────────────────────
+
+// ResealFile rewrites the file at path in the current form (see Current)
+// unless it already is. It reports whether the file was rewritten; a
+// missing file is not an error. The caller must hold whatever lock guards
+// the file.
+func ResealFile(path string) (bool, error) {
+	data, info, err := readForReseal(path)
+	if err != nil || info == nil || Current(data) {
+		return false, err
+	}
+	plain, err := Open(data)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+	sealed, err := Seal(plain)
+	if err != nil {
+		return false, err
+	}
+	return true, writeAtomic(path, sealed, info.Mode().Perm())
+}
+
+// ResealLines rewrites the JSONL file at path so every line is in the
+// current form, leaving lines that already are untouched. A torn last
+// line, without its newline, is kept as it is.
+func ResealLines(path string) (bool, error) {
+	data, info, err := readForReseal(path)
+	if err != nil || info == nil {
+		return false, err
+	}
+	var out bytes.Buffer
+	changed := false
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			out.Write(data)
+			break
+		}
+		line := data[:i]
+		data = data[i+1:]
+		if len(bytes.TrimSpace(line)) == 0 || Current(line) {
+			out.Write(line)
+			out.WriteByte('\n')
+			continue
+		}
+		plain, err := OpenLine(line)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", path, err)
+		}
+		sealed, err := SealLine(plain)
+		if err != nil {
+			return false, err
+		}
+		out.Write(sealed)
+		out.WriteByte('\n')
+		changed = true
+	}
+	if !changed {
+		return false, nil
+	}
+	return true, writeAtomic(path, out.Bytes(), info.Mode().Perm())
+}
+
+func readForReseal(path string) ([]byte, os.FileInfo, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, info, nil
+}
+
+// writeAtomic replaces path with data through a synced temporary file in
+// the same directory.
+func writeAtomic(path string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer func() { _ = os.Remove(tmp) }()
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testRing(t *testing.T, primary string, ids ...string) *Keyring {
+	t.Helper()
+	keys := make(map[string][]byte)
+	for _, id := range ids {
+		key, err := GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[id] = key
+	}
+	ring, err := NewKeyring(primary, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ring
+}
+
+// use installs ring for the test and restores plaintext mode after it.
+func use(t *testing.T, ring *Keyring, encrypt bool) {
+	t.Helper()
+	Use(ring, encrypt)
+	t.Cleanup(func() { Use(nil, false) })
+}
+
+func TestSealOpen(t *testing.T) {
+	use(t, testRing(t, "a", "a"), true)
+	plain := []byte(`{"role":"user","content":"secret"}`)
+
+	sealed, err := Seal(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSealed(sealed) || bytes.Contains(sealed, []byte("secret")) {
+		t.Fatalf("sealed = %q", sealed)
+	}
+	if got, err := Open(sealed); err != nil || !bytes.Equal(got, plain) {
+		t.Errorf("Open = %q, %v", got, err)
+	}
+	if got, err := Open(plain); err != nil || !bytes.Equal(got, plain) {
+		t.Errorf("Open of plaintext = %q, %v", got, err)
+	}
+
+	sealed[len(sealed)-1] ^= 1
+	if _, err := Open(sealed); err == nil {
+		t.Error("Open of tampered data should fail")
+	}
+}
+
+func TestSealLine(t *testing.T) {
+	use(t, testRing(t, "a", "a"), true)
+	plain := []byte(`{"n":1}`)
+
+	line, err := SealLine(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSealedLine(line) || bytes.ContainsAny(line, "\n") {
+		t.Fatalf("line = %q", line)
+	}
+	if got, err := OpenLine(append(line, '\n')); err != nil || !bytes.Equal(got, plain) {
+		t.Errorf("OpenLine = %q, %v", got, err)
+	}
+	if got, _ := OpenLine([]byte("  {\"n\":2}\n")); string(got) != "  {\"n\":2}\n" {
+		t.Errorf("OpenLine of plaintext = %q, want it unchanged", got)
+	}
+	if text, err := SealText("hello"); err != nil || text == "hello" {
+		t.Errorf("SealText = %q, %v", text, err)
+	} else if got, err := OpenText(text); err != nil || got != "hello" {
+		t.Errorf("OpenText = %q, %v", got, err)
+	}
+}
+
+func TestPlaintextMode(t *testing.T) {
+	old := testRing(t, "a", "a")
+	use(t, old, true)
+	sealed, _ := Seal([]byte("x"))
+
+	// With encryption off the keyring still opens old data.
+	Use(old, false)
+	if got, err := Seal([]byte("y")); err != nil || string(got) != "y" {
+		t.Errorf("Seal with encryption off = %q, %v", got, err)
+	}
+	if got, err := Open(sealed); err != nil || string(got) != "x" {
+		t.Errorf("Open = %q, %v", got, err)
+	}
+
+	Use(nil, false)
+	if _, err := Open(sealed); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Open without keyring = %v, want ErrNoKey", err)
+	}
+	Use(testRing(t, "b", "b"), true)
+	if _, err := Open(sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Open with another keyring = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestReseal(t *testing.T) {
+	dir := t.TempDir()
+	keyA, _ := GenerateKey()
+	keyB, _ := GenerateKey()
+	ring := func(primary string, keys map[string][]byte) *Keyring {
+		k, err := NewKeyring(primary, keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+
+	use(t, ring("a", map[string][]byte{"a": keyA}), true)
+	file := filepath.Join(dir, "meta.json")
+	sealed, _ := Seal([]byte(`{"title":"t"}`))
+	if err := os.WriteFile(file, sealed, 0600); err != nil {
+		t.Fatal(err)
+	}
+	line, _ := SealLine([]byte(`{"n":1}`))
+	lines := filepath.Join(dir, "log.jsonl")
+	if err := os.WriteFile(lines, []byte(string(line)+"\n{\"n\":2}\n{\"n\":3"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if changed, err := ResealFile(file); err != nil || changed {
+		t.Errorf("ResealFile of current file = %v, %v", changed, err)
+	}
+
+	// Rotate: "b" is primary, "a" still opens old data.
+	Use(ring("b", map[string][]byte{"a": keyA, "b": keyB}), true)
+	if changed, err := ResealFile(file); err != nil || !changed {
+		t.Fatalf("ResealFile = %v, %v", changed, err)
+	}
+	if changed, err := ResealLines(lines); err != nil || !changed {
+		t.Fatalf("ResealLines = %v, %v", changed, err)
+	}
+	data, _ := os.ReadFile(file)
+	if !Current(data) {
+		t.Error("file not sealed with the new primary key")
+	}
+	data, _ = os.ReadFile(lines)
+	parts := bytes.Split(data, []byte("\n"))
+	if len(parts) != 3 || !Current(parts[0]) || !Current(parts[1]) || string(parts[2]) != `{"n":3` {
+		t.Errorf("lines = %q", data)
+	}
+
+	// Back to plaintext; the retired key "a" is no longer needed.
+	Use(ring("b", map[string][]byte{"b": keyB}), false)
+	if _, err := ResealFile(file); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ResealLines(lines); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(file); string(got) != `{"title":"t"}` {
+		t.Errorf("file = %q", got)
+	}
+	if got, _ := os.ReadFile(lines); string(got) != "{\"n\":1}\n{\"n\":2}\n{\"n\":3" {
+		t.Errorf("lines = %q", got)
+	}
+	if info, _ := os.Stat(file); info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+}